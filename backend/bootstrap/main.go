@@ -1,13 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -38,135 +41,329 @@ type UserEntry struct {
 	DirectAddress string    `json:"direct_address,omitempty"`
 	RegisteredAt  time.Time `json:"registered_at"`
 	LastSeen      time.Time `json:"last_seen"`
+	// Revoked and RevokedAt turn an entry into a tombstone: once set, the
+	// username resolves to no usable key and RotateUser refuses to revive
+	// it, but the entry (and its AuditLog history) is kept rather than
+	// deleted so LookupUser can still tell a caller why a once-valid
+	// username no longer works.
+	Revoked   bool      `json:"revoked,omitempty"`
+	RevokedAt time.Time `json:"revoked_at,omitempty"`
 }
 
-// TokenRegistry manages transfer tokens
+// TokenRegistry manages transfer tokens. It's a thin layer over a Store,
+// giving RegisterToken/LookupToken/CleanupExpired optimistic-concurrency
+// semantics so multiple bootstrap replicas can share one backend (a
+// BoltStore) without split-brain, while a single replica can still run the
+// default MemStore with no extra setup.
 type TokenRegistry struct {
-	entries map[string]*TokenEntry
-	mu      sync.RWMutex
+	store Store
 }
 
-// UsernameRegistry manages username registrations
+// UsernameRegistry manages username registrations, mirroring TokenRegistry's
+// Store-backed shape.
 type UsernameRegistry struct {
-	entries map[string]*UserEntry
-	mu      sync.RWMutex
+	store Store
 }
 
 // BootstrapService manages the bootstrap discovery service
 type BootstrapService struct {
-	tokens    *TokenRegistry
-	usernames *UsernameRegistry
-	limiters  map[string]*rate.Limiter
-	limiterMu sync.RWMutex
-	maxTTL    time.Duration
+	tokens     *TokenRegistry
+	usernames  *UsernameRegistry
+	limiters   map[string]*rate.Limiter
+	limiterMu  sync.RWMutex
+	maxTTL     time.Duration
+	challenges *ChallengeRegistry
+	// powDifficulty is the leading-zero-bit target handleRegisterToken and
+	// handleRegisterUser require of a submitted challenge/nonce pair,
+	// independent of and on top of per-IP rate limiting: it makes each
+	// registration attempt costly regardless of how many IPs a spammer
+	// spreads across.
+	powDifficulty int
+	// regNonces rejects a replayed (username, nonce) pair across
+	// registration, rotation, and revocation envelopes, on top of each
+	// envelope's own timestamp freshness check.
+	regNonces *regNonceCache
+	// audit records every registration, rotation, and revocation a
+	// username goes through, so a client can fetch its own key history.
+	audit *AuditLog
 }
 
+// NewTokenRegistry creates a registry backed by an in-memory MemStore,
+// suitable for a single bootstrap replica.
 func NewTokenRegistry() *TokenRegistry {
-	return &TokenRegistry{
-		entries: make(map[string]*TokenEntry),
-	}
+	return NewTokenRegistryWithStore(NewMemStore())
+}
+
+// NewTokenRegistryWithStore creates a registry backed by store, e.g. a
+// BoltStore shared across replicas behind a load balancer.
+func NewTokenRegistryWithStore(store Store) *TokenRegistry {
+	return &TokenRegistry{store: store}
 }
 
+// NewUsernameRegistry creates a registry backed by an in-memory MemStore,
+// suitable for a single bootstrap replica.
 func NewUsernameRegistry() *UsernameRegistry {
-	return &UsernameRegistry{
-		entries: make(map[string]*UserEntry),
-	}
+	return NewUsernameRegistryWithStore(NewMemStore())
+}
+
+// NewUsernameRegistryWithStore creates a registry backed by store, e.g. a
+// BoltStore shared across replicas behind a load balancer.
+func NewUsernameRegistryWithStore(store Store) *UsernameRegistry {
+	return &UsernameRegistry{store: store}
 }
 
 func NewBootstrapService(maxTTL time.Duration) *BootstrapService {
 	return &BootstrapService{
-		tokens:    NewTokenRegistry(),
-		usernames: NewUsernameRegistry(),
-		limiters:  make(map[string]*rate.Limiter),
-		maxTTL:    maxTTL,
+		tokens:        NewTokenRegistry(),
+		usernames:     NewUsernameRegistry(),
+		limiters:      make(map[string]*rate.Limiter),
+		maxTTL:        maxTTL,
+		challenges:    NewChallengeRegistry(defaultChallengeTTL),
+		powDifficulty: defaultPowDifficultyBits,
+		regNonces:     newRegNonceCache(defaultRegNonceCacheSize),
+		audit:         NewAuditLog(NewMemStore()),
 	}
 }
 
-// RegisterToken registers a new transfer token
+// RegisterToken registers a new transfer token, using the etcd-style
+// read-compute-CAS-retry pattern against the registry's Store: it reads the
+// current revision for entry.Token, then CASes its encoded form in,
+// retrying if another writer's CAS landed first. An identical retry of an
+// already-registered entry (e.g. a client retrying after a dropped
+// response) succeeds as a no-op; only a genuinely different stored entry
+// is reported as a duplicate.
 func (tr *TokenRegistry) RegisterToken(entry *TokenEntry) error {
-	tr.mu.Lock()
-	defer tr.mu.Unlock()
-
-	if _, exists := tr.entries[entry.Token]; exists {
-		return fmt.Errorf("token already exists")
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
 	}
 
-	tr.entries[entry.Token] = entry
-	return nil
+	var lastErr error
+	for attempt := 0; attempt < casMaxRetries; attempt++ {
+		existing, rev, found, err := tr.store.Get(entry.Token)
+		if err != nil {
+			return err
+		}
+		if found {
+			if bytes.Equal(existing.Data, data) {
+				return nil
+			}
+			return fmt.Errorf("token already exists")
+		}
+
+		if _, err := tr.store.CompareAndSwap(entry.Token, rev, StoredValue{Data: data, ExpiresAt: entry.ExpiresAt}); err == nil {
+			return nil
+		} else if errors.Is(err, ErrRevisionMismatch) {
+			lastErr = err
+			continue
+		} else {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to register token after %d attempts: %w", casMaxRetries, lastErr)
 }
 
 // LookupToken retrieves a token entry
 func (tr *TokenRegistry) LookupToken(token string) (*TokenEntry, error) {
-	tr.mu.RLock()
-	defer tr.mu.RUnlock()
-
-	entry, exists := tr.entries[token]
-	if !exists {
+	sv, _, found, err := tr.store.Get(token)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
 		return nil, fmt.Errorf("token not found")
 	}
 
+	var entry TokenEntry
+	if err := json.Unmarshal(sv.Data, &entry); err != nil {
+		return nil, err
+	}
+
 	// Check if expired
 	if time.Now().After(entry.ExpiresAt) {
 		return nil, fmt.Errorf("token expired")
 	}
 
-	return entry, nil
+	return &entry, nil
 }
 
-// CleanupExpired removes expired tokens
+// CleanupExpired removes expired tokens. It lists expired keys via
+// RangeExpired, then deletes each with a read-then-CAS-delete: if another
+// writer touched the key in between (re-registered it, or another cleanup
+// pass already removed it), that key is simply skipped rather than retried
+// as an error, since "no longer expired" or "already gone" both mean there's
+// nothing left to clean up.
 func (tr *TokenRegistry) CleanupExpired() int {
-	tr.mu.Lock()
-	defer tr.mu.Unlock()
-
-	count := 0
-	now := time.Now()
-	for token, entry := range tr.entries {
-		if now.After(entry.ExpiresAt) {
-			delete(tr.entries, token)
-			count++
+	var keys []string
+	_ = tr.store.RangeExpired(time.Now(), func(key string, _ StoredValue) {
+		keys = append(keys, key)
+	})
+
+	removed := 0
+	for _, key := range keys {
+		for attempt := 0; attempt < casMaxRetries; attempt++ {
+			_, rev, found, err := tr.store.Get(key)
+			if err != nil || !found {
+				break
+			}
+			if err := tr.store.Delete(key, rev); err == nil {
+				removed++
+				break
+			} else if errors.Is(err, ErrRevisionMismatch) {
+				continue
+			} else {
+				break
+			}
 		}
 	}
-	return count
+	return removed
 }
 
 // Count returns the number of active tokens
 func (tr *TokenRegistry) Count() int {
-	tr.mu.RLock()
-	defer tr.mu.RUnlock()
-	return len(tr.entries)
+	n, _ := tr.store.Count()
+	return n
 }
 
-// RegisterUser registers a new username
+// RegisterUser registers a new username, using the same etcd-style
+// read-compute-CAS-retry pattern as RegisterToken.
 func (ur *UsernameRegistry) RegisterUser(entry *UserEntry) error {
-	ur.mu.Lock()
-	defer ur.mu.Unlock()
-
-	if _, exists := ur.entries[entry.Username]; exists {
-		return fmt.Errorf("username already taken")
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
 	}
 
-	ur.entries[entry.Username] = entry
-	return nil
+	var lastErr error
+	for attempt := 0; attempt < casMaxRetries; attempt++ {
+		existing, rev, found, err := ur.store.Get(entry.Username)
+		if err != nil {
+			return err
+		}
+		if found {
+			if bytes.Equal(existing.Data, data) {
+				return nil
+			}
+			return fmt.Errorf("username already taken")
+		}
+
+		if _, err := ur.store.CompareAndSwap(entry.Username, rev, StoredValue{Data: data}); err == nil {
+			return nil
+		} else if errors.Is(err, ErrRevisionMismatch) {
+			lastErr = err
+			continue
+		} else {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to register username after %d attempts: %w", casMaxRetries, lastErr)
 }
 
 // LookupUser retrieves a user entry
 func (ur *UsernameRegistry) LookupUser(username string) (*UserEntry, error) {
-	ur.mu.RLock()
-	defer ur.mu.RUnlock()
-
-	entry, exists := ur.entries[username]
-	if !exists {
+	sv, _, found, err := ur.store.Get(username)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
 		return nil, fmt.Errorf("username not found")
 	}
 
-	return entry, nil
+	var entry UserEntry
+	if err := json.Unmarshal(sv.Data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
 }
 
 // Count returns the number of registered users
 func (ur *UsernameRegistry) Count() int {
-	ur.mu.RLock()
-	defer ur.mu.RUnlock()
-	return len(ur.entries)
+	n, _ := ur.store.Count()
+	return n
+}
+
+// RotateUser replaces username's existing entry with newEntry, using the
+// same CAS retry loop as RegisterUser but requiring an existing, non-
+// revoked entry to replace rather than refusing because one already
+// exists.
+func (ur *UsernameRegistry) RotateUser(username string, newEntry *UserEntry) error {
+	data, err := json.Marshal(newEntry)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < casMaxRetries; attempt++ {
+		current, rev, found, err := ur.currentEntry(username)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("username not found")
+		}
+		if current.Revoked {
+			return fmt.Errorf("username has been revoked")
+		}
+
+		if _, err := ur.store.CompareAndSwap(username, rev, StoredValue{Data: data}); err == nil {
+			return nil
+		} else if errors.Is(err, ErrRevisionMismatch) {
+			lastErr = err
+			continue
+		} else {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to rotate username after %d attempts: %w", casMaxRetries, lastErr)
+}
+
+// RevokeUser marks username's entry as a tombstone: future LookupUser
+// calls still find it (so a client can learn it was revoked rather than
+// never existing), but RotateUser refuses to rotate it back to life.
+// Revoking an already-revoked username is a no-op, not an error.
+func (ur *UsernameRegistry) RevokeUser(username string) error {
+	var lastErr error
+	for attempt := 0; attempt < casMaxRetries; attempt++ {
+		current, rev, found, err := ur.currentEntry(username)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("username not found")
+		}
+		if current.Revoked {
+			return nil
+		}
+
+		current.Revoked = true
+		current.RevokedAt = time.Now()
+		data, err := json.Marshal(current)
+		if err != nil {
+			return err
+		}
+
+		if _, err := ur.store.CompareAndSwap(username, rev, StoredValue{Data: data}); err == nil {
+			return nil
+		} else if errors.Is(err, ErrRevisionMismatch) {
+			lastErr = err
+			continue
+		} else {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to revoke username after %d attempts: %w", casMaxRetries, lastErr)
+}
+
+// currentEntry reads username's entry and its revision, decoded, for
+// RotateUser/RevokeUser's read-compute-CAS step.
+func (ur *UsernameRegistry) currentEntry(username string) (*UserEntry, uint64, bool, error) {
+	sv, rev, found, err := ur.store.Get(username)
+	if err != nil || !found {
+		return nil, rev, found, err
+	}
+	var entry UserEntry
+	if err := json.Unmarshal(sv.Data, &entry); err != nil {
+		return nil, 0, false, err
+	}
+	return &entry, rev, true, nil
 }
 
 // Rate limiter
@@ -184,6 +381,57 @@ func (bs *BootstrapService) getRateLimiter(ip string, limit rate.Limit, burst in
 
 // HTTP Handlers
 
+// handleIssueChallenge mints a proof-of-work challenge a client must solve
+// before handleRegisterToken or handleRegisterUser will accept its request.
+func (bs *BootstrapService) handleIssueChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Rate limiting (same budget as token registration: a client fetches
+	// one challenge per registration attempt)
+	ip := getClientIP(r)
+	limiter := bs.getRateLimiter(ip, rate.Limit(20.0/60.0), 20) // 20 per minute
+	if !limiter.Allow() {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	entry, err := bs.challenges.Issue()
+	if err != nil {
+		http.Error(w, "Failed to issue challenge", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"challenge":  entry.Challenge,
+		"difficulty": bs.powDifficulty,
+		"expires_at": entry.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+// checkProofOfWork validates and redeems the challenge/nonce pair a
+// registration request submitted, writing an error response and reporting
+// false if it doesn't hold up.
+func (bs *BootstrapService) checkProofOfWork(w http.ResponseWriter, challenge, nonce string) bool {
+	if challenge == "" || nonce == "" {
+		http.Error(w, "Proof-of-work challenge and nonce required", http.StatusBadRequest)
+		return false
+	}
+	if !verifyProofOfWork(challenge, nonce, bs.powDifficulty) {
+		http.Error(w, "Proof-of-work does not meet required difficulty", http.StatusBadRequest)
+		return false
+	}
+	if err := bs.challenges.Redeem(challenge); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
 func (bs *BootstrapService) handleRegisterToken(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -206,6 +454,8 @@ func (bs *BootstrapService) handleRegisterToken(w http.ResponseWriter, r *http.R
 		RelayHints         []string `json:"relay_hints"`
 		SenderAddress      string   `json:"sender_address"`
 		TTLSeconds         int      `json:"ttl_seconds"`
+		Challenge          string   `json:"challenge"`
+		Nonce              string   `json:"nonce"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -219,6 +469,10 @@ func (bs *BootstrapService) handleRegisterToken(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if !bs.checkProofOfWork(w, req.Challenge, req.Nonce) {
+		return
+	}
+
 	// Set default TTL
 	if req.TTLSeconds == 0 {
 		req.TTLSeconds = 3600 // 1 hour default
@@ -308,6 +562,16 @@ func (bs *BootstrapService) handleRegisterUser(w http.ResponseWriter, r *http.Re
 		PublicKey     string   `json:"public_key"`
 		RelayHints    []string `json:"relay_hints"`
 		DirectAddress string   `json:"direct_address"`
+		Challenge     string   `json:"challenge"`
+		Nonce         string   `json:"nonce"`
+		// Timestamp, SigNonce, and Signature are the proof-of-possession
+		// envelope: Signature is the Ed25519 signature by PublicKey over
+		// registrationCanonicalBytes(Username, PublicKey, Timestamp,
+		// SigNonce), proving the requester holds the private key rather
+		// than just squatting someone else's public key.
+		Timestamp int64  `json:"timestamp"`
+		SigNonce  string `json:"nonce_sig"`
+		Signature string `json:"signature"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -326,6 +590,28 @@ func (bs *BootstrapService) handleRegisterUser(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if !bs.checkProofOfWork(w, req.Challenge, req.Nonce) {
+		return
+	}
+
+	pub, err := decodeEd25519PublicKey(req.PublicKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkEnvelopeFreshness(req.Timestamp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !bs.regNonces.checkAndStore(req.Username+":"+req.SigNonce, time.Unix(req.Timestamp, 0).Add(registrationClockSkew)) {
+		http.Error(w, "Registration envelope nonce already used", http.StatusBadRequest)
+		return
+	}
+	if err := verifyEnvelopeSignature(pub, registrationCanonicalBytes(req.Username, req.PublicKey, req.Timestamp, req.SigNonce), req.Signature); err != nil {
+		http.Error(w, "Proof of key possession failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	entry := &UserEntry{
 		Username:      req.Username,
 		PublicKey:     req.PublicKey,
@@ -340,6 +626,7 @@ func (bs *BootstrapService) handleRegisterUser(w http.ResponseWriter, r *http.Re
 		http.Error(w, err.Error(), http.StatusConflict)
 		return
 	}
+	_ = bs.audit.Append(entry.Username, AuditEntry{Action: "register", Fingerprint: entry.Fingerprint, Timestamp: entry.RegisteredAt})
 
 	log.Printf("User registered: %s (fingerprint: %s)", entry.Username, entry.Fingerprint)
 
@@ -383,6 +670,216 @@ func (bs *BootstrapService) handleLookupUser(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(entry)
 }
 
+// handleRegisterUserByUsername serves everything under
+// /api/v1/register/{username}: PUT rotates the key, DELETE revokes it,
+// and GET .../history fetches its AuditLog. All three need the username
+// out of the same path, so rather than three mux entries with duplicated
+// path-parsing they share this one and switch on method (and the
+// "/history" suffix) instead.
+func (bs *BootstrapService) handleRegisterUserByUsername(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path[len("/api/v1/register/"):]
+	if path == "" {
+		http.Error(w, "Username required", http.StatusBadRequest)
+		return
+	}
+
+	if username := strings.TrimSuffix(path, "/history"); username != path {
+		bs.handleUserHistory(w, r, username)
+		return
+	}
+	username := path
+
+	switch r.Method {
+	case http.MethodPut:
+		bs.rotateUser(w, r, username)
+	case http.MethodDelete:
+		bs.revokeUser(w, r, username)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// rotateUser handles PUT /api/v1/register/{username}: the client submits
+// a new public key plus two signatures over the same envelope bytes, one
+// from the username's current key (proving it's authorized to hand the
+// name off) and one from the new key (proving possession of it), so a
+// rotation can't be forged by someone who only has one half of the pair.
+func (bs *BootstrapService) rotateUser(w http.ResponseWriter, r *http.Request, username string) {
+	ip := getClientIP(r)
+	limiter := bs.getRateLimiter(ip, rate.Limit(5.0/3600.0), 5) // 5 per hour
+	if !limiter.Allow() {
+		w.Header().Set("Retry-After", "3600")
+		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	var req struct {
+		NewPublicKey  string   `json:"new_public_key"`
+		Timestamp     int64    `json:"timestamp"`
+		Nonce         string   `json:"nonce"`
+		OldSignature  string   `json:"old_signature"`
+		NewSignature  string   `json:"new_signature"`
+		RelayHints    []string `json:"relay_hints"`
+		DirectAddress string   `json:"direct_address"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.NewPublicKey == "" {
+		http.Error(w, "New public key required", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := bs.usernames.LookupUser(username)
+	if err != nil {
+		http.Error(w, "Username not found", http.StatusNotFound)
+		return
+	}
+	if existing.Revoked {
+		http.Error(w, "Username has been revoked", http.StatusGone)
+		return
+	}
+
+	oldPub, err := decodeEd25519PublicKey(existing.PublicKey)
+	if err != nil {
+		http.Error(w, "Stored public key is no longer valid: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	newPub, err := decodeEd25519PublicKey(req.NewPublicKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkEnvelopeFreshness(req.Timestamp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !bs.regNonces.checkAndStore(username+":"+req.Nonce, time.Unix(req.Timestamp, 0).Add(registrationClockSkew)) {
+		http.Error(w, "Rotation envelope nonce already used", http.StatusBadRequest)
+		return
+	}
+
+	canonical := registrationCanonicalBytes(username, req.NewPublicKey, req.Timestamp, req.Nonce)
+	if err := verifyEnvelopeSignature(oldPub, canonical, req.OldSignature); err != nil {
+		http.Error(w, "Previous key did not authorize this rotation: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := verifyEnvelopeSignature(newPub, canonical, req.NewSignature); err != nil {
+		http.Error(w, "Proof of new key possession failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newEntry := &UserEntry{
+		Username:      username,
+		PublicKey:     req.NewPublicKey,
+		Fingerprint:   computeFingerprint(req.NewPublicKey),
+		RelayHints:    req.RelayHints,
+		DirectAddress: req.DirectAddress,
+		RegisteredAt:  existing.RegisteredAt,
+		LastSeen:      time.Now(),
+	}
+	if len(newEntry.RelayHints) == 0 {
+		newEntry.RelayHints = existing.RelayHints
+	}
+	if newEntry.DirectAddress == "" {
+		newEntry.DirectAddress = existing.DirectAddress
+	}
+
+	if err := bs.usernames.RotateUser(username, newEntry); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	_ = bs.audit.Append(username, AuditEntry{Action: "rotate", Fingerprint: newEntry.Fingerprint, Timestamp: newEntry.LastSeen})
+
+	log.Printf("User rotated: %s (fingerprint: %s)", username, newEntry.Fingerprint)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"username":    newEntry.Username,
+		"fingerprint": newEntry.Fingerprint,
+	})
+}
+
+// revokeUser handles DELETE /api/v1/register/{username}: the client signs
+// a revocation statement with the username's current key, and the entry
+// becomes a tombstone rather than being removed outright, so LookupUser
+// and the audit log both keep remembering that the name once resolved
+// somewhere and was deliberately retired.
+func (bs *BootstrapService) revokeUser(w http.ResponseWriter, r *http.Request, username string) {
+	ip := getClientIP(r)
+	limiter := bs.getRateLimiter(ip, rate.Limit(5.0/3600.0), 5) // 5 per hour
+	if !limiter.Allow() {
+		w.Header().Set("Retry-After", "3600")
+		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	var req struct {
+		Timestamp int64  `json:"timestamp"`
+		Nonce     string `json:"nonce"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := bs.usernames.LookupUser(username)
+	if err != nil {
+		http.Error(w, "Username not found", http.StatusNotFound)
+		return
+	}
+	pub, err := decodeEd25519PublicKey(existing.PublicKey)
+	if err != nil {
+		http.Error(w, "Stored public key is no longer valid: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := checkEnvelopeFreshness(req.Timestamp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !bs.regNonces.checkAndStore(username+":"+req.Nonce, time.Unix(req.Timestamp, 0).Add(registrationClockSkew)) {
+		http.Error(w, "Revocation envelope nonce already used", http.StatusBadRequest)
+		return
+	}
+	if err := verifyEnvelopeSignature(pub, revocationCanonicalBytes(username, req.Timestamp, req.Nonce), req.Signature); err != nil {
+		http.Error(w, "Revocation signature invalid: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := bs.usernames.RevokeUser(username); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	_ = bs.audit.Append(username, AuditEntry{Action: "revoke", Timestamp: time.Now()})
+
+	log.Printf("User revoked: %s", username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleUserHistory serves GET /api/v1/register/{username}/history,
+// returning the append-only AuditLog a client can use to confirm its own
+// (or a peer's) key history hasn't been tampered with out from under it.
+func (bs *BootstrapService) handleUserHistory(w http.ResponseWriter, r *http.Request, username string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	history, err := bs.audit.History(username)
+	if err != nil {
+		http.Error(w, "Failed to load history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"username": username,
+		"history":  history,
+	})
+}
+
 func (bs *BootstrapService) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -426,19 +923,15 @@ func isValidUsername(username string) bool {
 	return true
 }
 
-func computeFingerprint(publicKey string) string {
-	// Simple hash for fingerprint (first 16 chars)
-	if len(publicKey) > 16 {
-		return publicKey[:16]
-	}
-	return publicKey
-}
-
 func main() {
 	listen := flag.String("listen", ":8082", "HTTP listen address")
 	logLevel := flag.String("log-level", "info", "Logging level")
 	maxTTL := flag.Duration("token-ttl-max", 24*time.Hour, "Maximum token TTL")
 	cleanupInterval := flag.Duration("cleanup-interval", 60*time.Second, "Cleanup interval")
+	powDifficulty := flag.Int("pow-difficulty", defaultPowDifficultyBits, "Required leading-zero bits for registration proof-of-work")
+	storeKind := flag.String("store", "memory", "Registry storage backend: memory, bolt, or etcd")
+	storePath := flag.String("store-path", "", "BoltDB file to persist token/username registries in (--store=bolt); ignored otherwise")
+	etcdEndpoints := flag.String("etcd-endpoints", "", "Comma-separated etcd v3 endpoints (--store=etcd)")
 	flag.Parse()
 
 	log.Printf("QuantaraX Bootstrap Service starting...")
@@ -446,9 +939,60 @@ func main() {
 	log.Printf("Max token TTL: %s", *maxTTL)
 	log.Printf("Cleanup interval: %s", *cleanupInterval)
 
-// Basic address validation
-if *listen == "" { log.Fatalf("listen address cannot be empty") }
+	// Basic address validation
+	if *listen == "" {
+		log.Fatalf("listen address cannot be empty")
+	}
 	service := NewBootstrapService(*maxTTL)
+	service.powDifficulty = *powDifficulty
+
+	switch *storeKind {
+	case "memory":
+		// service already defaults to MemStore-backed registries.
+	case "bolt":
+		if *storePath == "" {
+			log.Fatalf("--store=bolt requires --store-path")
+		}
+		boltDB, err := OpenBoltDB(*storePath)
+		if err != nil {
+			log.Fatalf("Failed to open store at %s: %v", *storePath, err)
+		}
+		defer boltDB.Close()
+
+		tokenStore, err := OpenBoltStore(boltDB, []byte("tokens"))
+		if err != nil {
+			log.Fatalf("Failed to open token store: %v", err)
+		}
+		usernameStore, err := OpenBoltStore(boltDB, []byte("usernames"))
+		if err != nil {
+			log.Fatalf("Failed to open username store: %v", err)
+		}
+		auditStore, err := OpenBoltStore(boltDB, []byte("audit"))
+		if err != nil {
+			log.Fatalf("Failed to open audit store: %v", err)
+		}
+
+		service.tokens = NewTokenRegistryWithStore(tokenStore)
+		service.usernames = NewUsernameRegistryWithStore(usernameStore)
+		service.audit = NewAuditLog(auditStore)
+		log.Printf("Using BoltDB-backed registries at %s", *storePath)
+	case "etcd":
+		if *etcdEndpoints == "" {
+			log.Fatalf("--store=etcd requires --etcd-endpoints")
+		}
+		etcdClient, err := OpenEtcdClient(strings.Split(*etcdEndpoints, ","))
+		if err != nil {
+			log.Fatalf("Failed to connect to etcd at %s: %v", *etcdEndpoints, err)
+		}
+		defer etcdClient.Close()
+
+		service.tokens = NewTokenRegistryWithStore(OpenEtcdStore(etcdClient, "tokens/"))
+		service.usernames = NewUsernameRegistryWithStore(OpenEtcdStore(etcdClient, "usernames/"))
+		service.audit = NewAuditLog(OpenEtcdStore(etcdClient, "audit/"))
+		log.Printf("Using etcd-backed registries at %s", *etcdEndpoints)
+	default:
+		log.Fatalf("Unknown --store %q: must be memory, bolt, or etcd", *storeKind)
+	}
 
 	// Start cleanup goroutine
 	go func() {
@@ -459,6 +1003,9 @@ if *listen == "" { log.Fatalf("listen address cannot be empty") }
 			if count > 0 {
 				log.Printf("Cleaned up %d expired tokens", count)
 			}
+			if n := service.challenges.CleanupExpired(); n > 0 {
+				log.Printf("Cleaned up %d expired PoW challenges", n)
+			}
 		}
 	}()
 
@@ -466,8 +1013,10 @@ if *listen == "" { log.Fatalf("listen address cannot be empty") }
 	http.HandleFunc("/api/v1/token", service.handleRegisterToken)
 	http.HandleFunc("/api/v1/token/", service.handleLookupToken)
 	http.HandleFunc("/api/v1/register", service.handleRegisterUser)
+	http.HandleFunc("/api/v1/register/", service.handleRegisterUserByUsername)
 	http.HandleFunc("/api/v1/lookup/", service.handleLookupUser)
-http.HandleFunc("/health", service.handleHealth)
+	http.HandleFunc("/api/v1/pow/challenge", service.handleIssueChallenge)
+	http.HandleFunc("/health", service.handleHealth)
 	// pprof endpoints
 	http.HandleFunc("/debug/pprof/", pprof.Index)
 	http.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -2,22 +2,62 @@ package main
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 )
 
+// solvePow brute-forces a nonce satisfying service's current PoW
+// difficulty for challenge, for tests that need a valid registration
+// request.
+func solvePow(service *BootstrapService, challenge string) string {
+	for i := 0; ; i++ {
+		nonce := fmt.Sprintf("%d", i)
+		if verifyProofOfWork(challenge, nonce, service.powDifficulty) {
+			return nonce
+		}
+	}
+}
+
+// signedRegistrationEnvelope generates an Ed25519 keypair and the
+// timestamp/nonce/signature fields handleRegisterUser requires as proof
+// the caller holds the private key for publicKeyB64.
+func signedRegistrationEnvelope(t *testing.T, username string, nonceSuffix string) (pub ed25519.PublicKey, priv ed25519.PrivateKey, pubB64 string, timestamp int64, nonce string, signatureB64 string) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	pubB64 = base64.StdEncoding.EncodeToString(pub)
+	timestamp = time.Now().Unix()
+	nonce = "nonce-" + nonceSuffix
+	sig := ed25519.Sign(priv, registrationCanonicalBytes(username, pubB64, timestamp, nonce))
+	signatureB64 = base64.StdEncoding.EncodeToString(sig)
+	return pub, priv, pubB64, timestamp, nonce, signatureB64
+}
+
 func TestTokenRegistration(t *testing.T) {
 	service := NewBootstrapService(24 * time.Hour)
 
+	entry, err := service.challenges.Issue()
+	if err != nil {
+		t.Fatalf("issue challenge: %v", err)
+	}
+
 	reqBody := map[string]interface{}{
 		"token":                "test-token-123",
 		"ephemeral_public_key": "test-key",
 		"manifest_hash":        "test-hash",
 		"relay_hints":          []string{"relay1.test:4433"},
 		"ttl_seconds":          3600,
+		"challenge":            entry.Challenge,
+		"nonce":                solvePow(service, entry.Challenge),
 	}
 
 	body, _ := json.Marshal(reqBody)
@@ -116,9 +156,66 @@ func TestDuplicateToken(t *testing.T) {
 func TestUsernameRegistration(t *testing.T) {
 	service := NewBootstrapService(24 * time.Hour)
 
+	entry, err := service.challenges.Issue()
+	if err != nil {
+		t.Fatalf("issue challenge: %v", err)
+	}
+
+	_, _, pubB64, timestamp, nonce, signatureB64 := signedRegistrationEnvelope(t, "testuser", "register")
+
+	reqBody := map[string]interface{}{
+		"username":   "testuser",
+		"public_key": pubB64,
+		"challenge":  entry.Challenge,
+		"nonce":      solvePow(service, entry.Challenge),
+		"timestamp":  timestamp,
+		"nonce_sig":  nonce,
+		"signature":  signatureB64,
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/register", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	service.handleRegisterUser(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	stored, err := service.usernames.LookupUser("testuser")
+	if err != nil {
+		t.Fatalf("lookup registered user: %v", err)
+	}
+	if stored.PublicKey != pubB64 {
+		t.Errorf("Expected stored public key %s, got %s", pubB64, stored.PublicKey)
+	}
+
+	history, err := service.audit.History("testuser")
+	if err != nil {
+		t.Fatalf("load history: %v", err)
+	}
+	if len(history) != 1 || history[0].Action != "register" {
+		t.Errorf("Expected a single register audit entry, got %+v", history)
+	}
+}
+
+func TestUsernameRegistrationRejectsInvalidPublicKey(t *testing.T) {
+	service := NewBootstrapService(24 * time.Hour)
+
+	entry, err := service.challenges.Issue()
+	if err != nil {
+		t.Fatalf("issue challenge: %v", err)
+	}
+
 	reqBody := map[string]interface{}{
 		"username":   "testuser",
 		"public_key": "pubkey123",
+		"challenge":  entry.Challenge,
+		"nonce":      solvePow(service, entry.Challenge),
+		"timestamp":  time.Now().Unix(),
+		"nonce_sig":  "nonce-reject",
+		"signature":  "bm90YXNpZw==",
 	}
 
 	body, _ := json.Marshal(reqBody)
@@ -127,8 +224,271 @@ func TestUsernameRegistration(t *testing.T) {
 
 	service.handleRegisterUser(w, req)
 
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for non-base64 public key, got %d", w.Code)
+	}
+}
+
+func TestUsernameRegistrationRejectsBadSignature(t *testing.T) {
+	service := NewBootstrapService(24 * time.Hour)
+
+	entry, err := service.challenges.Issue()
+	if err != nil {
+		t.Fatalf("issue challenge: %v", err)
+	}
+
+	_, _, pubB64, timestamp, nonce, _ := signedRegistrationEnvelope(t, "testuser", "forged")
+	forgedPriv := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	forgedSig := ed25519.Sign(forgedPriv, registrationCanonicalBytes("testuser", pubB64, timestamp, nonce))
+
+	reqBody := map[string]interface{}{
+		"username":   "testuser",
+		"public_key": pubB64,
+		"challenge":  entry.Challenge,
+		"nonce":      solvePow(service, entry.Challenge),
+		"timestamp":  timestamp,
+		"nonce_sig":  nonce,
+		"signature":  base64.StdEncoding.EncodeToString(forgedSig),
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/register", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	service.handleRegisterUser(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for signature not matching the claimed public key, got %d", w.Code)
+	}
+}
+
+func registerSignedUser(t *testing.T, service *BootstrapService, username string) (ed25519.PrivateKey, string) {
+	t.Helper()
+
+	entry, err := service.challenges.Issue()
+	if err != nil {
+		t.Fatalf("issue challenge: %v", err)
+	}
+	_, priv, pubB64, timestamp, nonce, signatureB64 := signedRegistrationEnvelope(t, username, username)
+
+	reqBody := map[string]interface{}{
+		"username":   username,
+		"public_key": pubB64,
+		"challenge":  entry.Challenge,
+		"nonce":      solvePow(service, entry.Challenge),
+		"timestamp":  timestamp,
+		"nonce_sig":  nonce,
+		"signature":  signatureB64,
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/register", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	service.handleRegisterUser(w, req)
 	if w.Code != http.StatusCreated {
-		t.Errorf("Expected status 201, got %d", w.Code)
+		t.Fatalf("registering %s: expected 201, got %d: %s", username, w.Code, w.Body.String())
+	}
+	return priv, pubB64
+}
+
+func TestUsernameRotation(t *testing.T) {
+	service := NewBootstrapService(24 * time.Hour)
+	oldPriv, oldPubB64 := registerSignedUser(t, service, "rotateuser")
+
+	newPub, newPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	newPubB64 := base64.StdEncoding.EncodeToString(newPub)
+	timestamp := time.Now().Unix()
+	nonce := "rotate-nonce"
+	canonical := registrationCanonicalBytes("rotateuser", newPubB64, timestamp, nonce)
+	oldSig := ed25519.Sign(oldPriv, canonical)
+	newSig := ed25519.Sign(newPriv, canonical)
+
+	reqBody := map[string]interface{}{
+		"new_public_key": newPubB64,
+		"timestamp":      timestamp,
+		"nonce":          nonce,
+		"old_signature":  base64.StdEncoding.EncodeToString(oldSig),
+		"new_signature":  base64.StdEncoding.EncodeToString(newSig),
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/register/rotateuser", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	service.handleRegisterUserByUsername(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	stored, err := service.usernames.LookupUser("rotateuser")
+	if err != nil {
+		t.Fatalf("lookup rotated user: %v", err)
+	}
+	if stored.PublicKey != newPubB64 {
+		t.Errorf("Expected rotated public key %s, got %s", newPubB64, stored.PublicKey)
+	}
+	if stored.PublicKey == oldPubB64 {
+		t.Errorf("Expected public key to change after rotation")
+	}
+
+	history, err := service.audit.History("rotateuser")
+	if err != nil {
+		t.Fatalf("load history: %v", err)
+	}
+	if len(history) != 2 || history[1].Action != "rotate" {
+		t.Errorf("Expected register+rotate audit entries, got %+v", history)
+	}
+}
+
+func TestUsernameRotationRejectsWithoutOldKeyAuthorization(t *testing.T) {
+	service := NewBootstrapService(24 * time.Hour)
+	registerSignedUser(t, service, "rotateuser")
+
+	newPub, newPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	newPubB64 := base64.StdEncoding.EncodeToString(newPub)
+	timestamp := time.Now().Unix()
+	nonce := "rotate-nonce-unauthorized"
+	canonical := registrationCanonicalBytes("rotateuser", newPubB64, timestamp, nonce)
+	newSig := ed25519.Sign(newPriv, canonical)
+
+	reqBody := map[string]interface{}{
+		"new_public_key": newPubB64,
+		"timestamp":      timestamp,
+		"nonce":          nonce,
+		"old_signature":  base64.StdEncoding.EncodeToString(newSig), // wrong key
+		"new_signature":  base64.StdEncoding.EncodeToString(newSig),
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/register/rotateuser", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	service.handleRegisterUserByUsername(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 when old_signature doesn't authorize the rotation, got %d", w.Code)
+	}
+}
+
+func TestUsernameRevocation(t *testing.T) {
+	service := NewBootstrapService(24 * time.Hour)
+	priv, _ := registerSignedUser(t, service, "revokeuser")
+
+	timestamp := time.Now().Unix()
+	nonce := "revoke-nonce"
+	sig := ed25519.Sign(priv, revocationCanonicalBytes("revokeuser", timestamp, nonce))
+
+	reqBody := map[string]interface{}{
+		"timestamp": timestamp,
+		"nonce":     nonce,
+		"signature": base64.StdEncoding.EncodeToString(sig),
+	}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/register/revokeuser", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	service.handleRegisterUserByUsername(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	stored, err := service.usernames.LookupUser("revokeuser")
+	if err != nil {
+		t.Fatalf("lookup revoked user: %v", err)
+	}
+	if !stored.Revoked {
+		t.Errorf("Expected entry to be marked revoked")
+	}
+
+	// A revoked username must refuse to rotate back to life.
+	newPub, newPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	newPubB64 := base64.StdEncoding.EncodeToString(newPub)
+	rotateTimestamp := time.Now().Unix()
+	rotateNonce := "revoke-then-rotate"
+	canonical := registrationCanonicalBytes("revokeuser", newPubB64, rotateTimestamp, rotateNonce)
+	oldSig := ed25519.Sign(priv, canonical)
+	newSig := ed25519.Sign(newPriv, canonical)
+	rotateBody := map[string]interface{}{
+		"new_public_key": newPubB64,
+		"timestamp":      rotateTimestamp,
+		"nonce":          rotateNonce,
+		"old_signature":  base64.StdEncoding.EncodeToString(oldSig),
+		"new_signature":  base64.StdEncoding.EncodeToString(newSig),
+	}
+	rotateReqBody, _ := json.Marshal(rotateBody)
+	rotateReq := httptest.NewRequest(http.MethodPut, "/api/v1/register/revokeuser", bytes.NewReader(rotateReqBody))
+	rotateW := httptest.NewRecorder()
+
+	service.handleRegisterUserByUsername(rotateW, rotateReq)
+
+	if rotateW.Code != http.StatusGone {
+		t.Errorf("Expected status 410 rotating a revoked username, got %d", rotateW.Code)
+	}
+}
+
+func TestUserHistory(t *testing.T) {
+	service := NewBootstrapService(24 * time.Hour)
+	registerSignedUser(t, service, "historyuser")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/register/historyuser/history", nil)
+	w := httptest.NewRecorder()
+
+	service.handleRegisterUserByUsername(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Username string       `json:"username"`
+		History  []AuditEntry `json:"history"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Username != "historyuser" {
+		t.Errorf("Expected username historyuser, got %s", resp.Username)
+	}
+	if len(resp.History) != 1 || resp.History[0].Action != "register" {
+		t.Errorf("Expected a single register audit entry, got %+v", resp.History)
+	}
+}
+
+func TestVerifyEnvelopeSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	message := registrationCanonicalBytes("sigtestuser", base64.StdEncoding.EncodeToString(pub), 1234, "sig-nonce")
+	sig := ed25519.Sign(priv, message)
+	sigB64 := base64.StdEncoding.EncodeToString(sig)
+
+	if err := verifyEnvelopeSignature(pub, message, sigB64); err != nil {
+		t.Errorf("Expected valid signature to verify, got %v", err)
+	}
+
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	if err := verifyEnvelopeSignature(otherPub, message, sigB64); err == nil {
+		t.Error("Expected signature verification to fail against a different public key")
+	}
+
+	if err := verifyEnvelopeSignature(pub, append(append([]byte{}, message...), 'x'), sigB64); err == nil {
+		t.Error("Expected signature verification to fail against a tampered message")
+	}
+
+	if err := verifyEnvelopeSignature(pub, message, "not-base64!!"); err == nil {
+		t.Error("Expected an error for a non-base64 signature")
 	}
 }
 
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultPowDifficultyBits is how many leading zero bits verifyProofOfWork
+// requires by default: solving costs a client roughly 2^18 SHA-256
+// attempts (well under a second on a single core), enough to make scripted
+// mass registration noticeably more expensive without punishing a single
+// legitimate client retrying by hand.
+const defaultPowDifficultyBits = 18
+
+// defaultChallengeTTL bounds how long an issued challenge stays redeemable,
+// so a scraped-and-hoarded challenge can't be solved and cashed in later.
+const defaultChallengeTTL = 5 * time.Minute
+
+// ChallengeEntry is one outstanding hashcash-style proof-of-work challenge.
+type ChallengeEntry struct {
+	Challenge string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// ChallengeRegistry issues and redeems proof-of-work challenges for the
+// registration endpoints. Unlike the Store-backed TokenRegistry and
+// UsernameRegistry, it keeps its challenges in an in-process map guarded by
+// a mutex.
+type ChallengeRegistry struct {
+	entries map[string]*ChallengeEntry
+	mu      sync.Mutex
+	ttl     time.Duration
+}
+
+// NewChallengeRegistry creates a registry whose issued challenges are
+// redeemable for ttl.
+func NewChallengeRegistry(ttl time.Duration) *ChallengeRegistry {
+	return &ChallengeRegistry{
+		entries: make(map[string]*ChallengeEntry),
+		ttl:     ttl,
+	}
+}
+
+// Issue mints a new random challenge good until ttl elapses.
+func (cr *ChallengeRegistry) Issue() (*ChallengeEntry, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry := &ChallengeEntry{
+		Challenge: hex.EncodeToString(raw),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(cr.ttl),
+	}
+
+	cr.mu.Lock()
+	cr.entries[entry.Challenge] = entry
+	cr.mu.Unlock()
+
+	return entry, nil
+}
+
+// Redeem consumes challenge so it can't be used again, returning an error
+// if it's unknown, already redeemed, or expired.
+func (cr *ChallengeRegistry) Redeem(challenge string) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	entry, exists := cr.entries[challenge]
+	if !exists {
+		return fmt.Errorf("unknown or already-used challenge")
+	}
+	delete(cr.entries, challenge)
+
+	if time.Now().After(entry.ExpiresAt) {
+		return fmt.Errorf("challenge expired")
+	}
+	return nil
+}
+
+// CleanupExpired removes challenges that were issued but never redeemed.
+func (cr *ChallengeRegistry) CleanupExpired() int {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	count := 0
+	now := time.Now()
+	for c, entry := range cr.entries {
+		if now.After(entry.ExpiresAt) {
+			delete(cr.entries, c)
+			count++
+		}
+	}
+	return count
+}
+
+// leadingZeroBits counts how many leading bits of b are zero.
+func leadingZeroBits(b []byte) int {
+	n := 0
+	for _, by := range b {
+		if by == 0 {
+			n += 8
+			continue
+		}
+		for mask := byte(0x80); mask > 0 && by&mask == 0; mask >>= 1 {
+			n++
+		}
+		break
+	}
+	return n
+}
+
+// verifyProofOfWork reports whether sha256(challenge || nonce) has at
+// least difficulty leading zero bits: the standard hashcash construction,
+// where solving costs the client on average 2^difficulty hash attempts but
+// verifying costs the server exactly one.
+func verifyProofOfWork(challenge, nonce string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + nonce))
+	return leadingZeroBits(sum[:]) >= difficulty
+}
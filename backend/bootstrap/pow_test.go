@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifyProofOfWork(t *testing.T) {
+	// Difficulty 0 is satisfied by anything.
+	if !verifyProofOfWork("chal", "0", 0) {
+		t.Error("difficulty 0 should accept any nonce")
+	}
+
+	nonce := solvePow(&BootstrapService{powDifficulty: 12}, "chal")
+	if !verifyProofOfWork("chal", nonce, 12) {
+		t.Error("solved nonce should satisfy the difficulty it was solved for")
+	}
+	if verifyProofOfWork("chal", nonce, 64) {
+		t.Error("a nonce solved for a low difficulty shouldn't satisfy a much higher one")
+	}
+}
+
+func TestChallengeRedeemOnce(t *testing.T) {
+	registry := NewChallengeRegistry(time.Minute)
+
+	entry, err := registry.Issue()
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	if err := registry.Redeem(entry.Challenge); err != nil {
+		t.Fatalf("first redeem should succeed: %v", err)
+	}
+	if err := registry.Redeem(entry.Challenge); err == nil {
+		t.Error("second redeem of the same challenge should fail")
+	}
+}
+
+func TestChallengeExpiry(t *testing.T) {
+	registry := NewChallengeRegistry(-time.Second) // already expired on issue
+
+	entry, err := registry.Issue()
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	if err := registry.Redeem(entry.Challenge); err == nil {
+		t.Error("expected error redeeming an expired challenge")
+	}
+}
+
+func TestChallengeCleanupExpired(t *testing.T) {
+	registry := NewChallengeRegistry(-time.Second)
+
+	if _, err := registry.Issue(); err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if _, err := registry.Issue(); err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	if n := registry.CleanupExpired(); n != 2 {
+		t.Errorf("expected 2 expired challenges cleaned up, got %d", n)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zeebo/blake3"
+)
+
+// registrationClockSkew bounds how far a registration/rotation/revocation
+// envelope's Timestamp may drift from this server's clock before it's
+// rejected, the same role ClockSkew plays for relayauth tokens.
+const registrationClockSkew = 5 * time.Minute
+
+// defaultRegNonceCacheSize bounds regNonceCache's replay set.
+const defaultRegNonceCacheSize = 10000
+
+// regNonceCache is a bounded, TTL-expiring set of consumed (username,
+// nonce) pairs, identical in shape to authn's nonceCache: an entry stays
+// rejected until its own expiry, after which it's safe to evict since the
+// timestamp-skew check would reject a replay of that age anyway.
+type regNonceCache struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	maxSize int
+}
+
+func newRegNonceCache(maxSize int) *regNonceCache {
+	return &regNonceCache{seen: make(map[string]time.Time), maxSize: maxSize}
+}
+
+func (c *regNonceCache) checkAndStore(key string, expiry time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if exp, ok := c.seen[key]; ok && exp.After(now) {
+		return false
+	}
+
+	for k, exp := range c.seen {
+		if exp.Before(now) {
+			delete(c.seen, k)
+		}
+	}
+	if len(c.seen) >= c.maxSize {
+		var oldestKey string
+		var oldest time.Time
+		for k, exp := range c.seen {
+			if oldest.IsZero() || exp.Before(oldest) {
+				oldestKey, oldest = k, exp
+			}
+		}
+		if oldestKey != "" {
+			delete(c.seen, oldestKey)
+		}
+	}
+	c.seen[key] = expiry
+	return true
+}
+
+// registrationCanonicalBytes is the message a registration or rotation
+// envelope's signature(s) cover: the username, the public key being
+// attested (base64, as submitted), and a timestamp+nonce pair that pins
+// the signature to one specific request. A rotation's old-key signature
+// and new-key signature both cover the same bytes — "this old/new key
+// attests that username should now resolve to publicKeyB64 as of
+// timestamp" — just from two different signers.
+func registrationCanonicalBytes(username, publicKeyB64 string, timestamp int64, nonce string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(username)
+	buf.WriteString(publicKeyB64)
+	_ = binary.Write(&buf, binary.BigEndian, timestamp)
+	buf.WriteString(nonce)
+	return buf.Bytes()
+}
+
+// revocationCanonicalBytes is the message a revocation envelope's
+// signature covers: unlike registration/rotation there's no new key to
+// attest, just "username's current key revokes it as of timestamp".
+func revocationCanonicalBytes(username string, timestamp int64, nonce string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(username)
+	buf.WriteString("revoke")
+	_ = binary.Write(&buf, binary.BigEndian, timestamp)
+	buf.WriteString(nonce)
+	return buf.Bytes()
+}
+
+// decodeEd25519PublicKey decodes a base64-encoded Ed25519 public key,
+// rejecting anything that isn't exactly ed25519.PublicKeySize bytes.
+func decodeEd25519PublicKey(publicKeyB64 string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(publicKeyB64)
+	if err != nil {
+		return nil, fmt.Errorf("public key is not valid base64: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// verifyEnvelopeSignature decodes signatureB64 and reports whether it's a
+// valid Ed25519 signature by pub over message.
+func verifyEnvelopeSignature(pub ed25519.PublicKey, message []byte, signatureB64 string) error {
+	sig, err := base64.StdEncoding.DecodeString(signatureB64)
+	if err != nil {
+		return fmt.Errorf("signature is not valid base64: %w", err)
+	}
+	if !ed25519.Verify(pub, message, sig) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
+
+// checkEnvelopeFreshness rejects a timestamp too far from this server's
+// clock in either direction, bounding how long a captured, validly signed
+// envelope stays replayable before checkAndStore's own expiry would catch
+// it anyway.
+func checkEnvelopeFreshness(timestamp int64) error {
+	skew := time.Since(time.Unix(timestamp, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > registrationClockSkew {
+		return fmt.Errorf("timestamp outside the %s acceptance window", registrationClockSkew)
+	}
+	return nil
+}
+
+// computeFingerprint returns a BLAKE3 fingerprint of publicKeyB64's decoded
+// key bytes, falling back to hashing the raw string if it isn't valid
+// base64 (an entry registered before signed envelopes were required may
+// still hold an opaque, non-base64 key string).
+func computeFingerprint(publicKeyB64 string) string {
+	keyBytes, err := base64.StdEncoding.DecodeString(publicKeyB64)
+	if err != nil {
+		keyBytes = []byte(publicKeyB64)
+	}
+	hasher := blake3.New()
+	hasher.Write(keyBytes)
+	return "BLAKE3:" + hex.EncodeToString(hasher.Sum(nil))
+}
+
+// AuditEntry is one event in a username's append-only key history: every
+// registration, rotation, and revocation it has ever gone through.
+type AuditEntry struct {
+	Action      string    `json:"action"` // "register", "rotate", or "revoke"
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// auditKeyPrefix namespaces AuditLog's entries in the shared Store so they
+// can't collide with a UsernameRegistry key of the same username.
+const auditKeyPrefix = "audit:"
+
+// AuditLog is a small append-only per-username event log, backed by the
+// same Store abstraction as TokenRegistry/UsernameRegistry so it shares
+// whichever backend (memory, Bolt, etcd) the bootstrap replica was
+// configured with.
+type AuditLog struct {
+	store Store
+}
+
+// NewAuditLog creates an AuditLog backed by store.
+func NewAuditLog(store Store) *AuditLog {
+	return &AuditLog{store: store}
+}
+
+// Append records entry in username's history, using the same etcd-style
+// read-compute-CAS-retry pattern as UsernameRegistry.RegisterUser.
+func (al *AuditLog) Append(username string, entry AuditEntry) error {
+	key := auditKeyPrefix + username
+
+	var lastErr error
+	for attempt := 0; attempt < casMaxRetries; attempt++ {
+		history, rev, err := al.history(key)
+		if err != nil {
+			return err
+		}
+		history = append(history, entry)
+
+		data, err := json.Marshal(history)
+		if err != nil {
+			return err
+		}
+
+		if _, err := al.store.CompareAndSwap(key, rev, StoredValue{Data: data}); err == nil {
+			return nil
+		} else if errors.Is(err, ErrRevisionMismatch) {
+			lastErr = err
+			continue
+		} else {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to append audit entry for %q after %d attempts: %w", username, casMaxRetries, lastErr)
+}
+
+// History returns username's recorded events, oldest first, or an empty
+// slice if it has none yet.
+func (al *AuditLog) History(username string) ([]AuditEntry, error) {
+	history, _, err := al.history(auditKeyPrefix + username)
+	return history, err
+}
+
+func (al *AuditLog) history(key string) ([]AuditEntry, uint64, error) {
+	sv, rev, found, err := al.store.Get(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !found {
+		return nil, rev, nil
+	}
+	var history []AuditEntry
+	if err := json.Unmarshal(sv.Data, &history); err != nil {
+		return nil, 0, err
+	}
+	return history, rev, nil
+}
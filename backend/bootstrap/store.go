@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRevisionMismatch means a CompareAndSwap or Delete's expectedRev
+	// no longer matches the key's current revision: either another writer
+	// won a race, or (for CompareAndSwap with expectedRev 0) the key
+	// already exists.
+	ErrRevisionMismatch = errors.New("revision mismatch")
+
+	// ErrKeyNotFound means Delete was called against a key the Store has
+	// no record of at all.
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// casMaxRetries bounds how many times RegisterToken, RegisterUser, and
+// CleanupExpired retry a losing CompareAndSwap/Delete before giving up,
+// the etcd-style "read, compute, CAS, retry on conflict" loop.
+const casMaxRetries = 5
+
+// StoredValue is the unit a Store persists: an opaque caller-encoded
+// payload plus the expiry RangeExpired keys off, so a Store implementation
+// doesn't need to know how to parse a TokenEntry or UserEntry to garbage
+// collect them. A zero ExpiresAt means the entry never expires.
+type StoredValue struct {
+	Data      []byte
+	ExpiresAt time.Time
+}
+
+// Store is a revisioned key-value abstraction TokenRegistry and
+// UsernameRegistry are built on, so bootstrap can run multiple replicas
+// behind a load balancer sharing one backing store instead of each holding
+// its own in-memory map: every write is a compare-and-swap against the
+// revision the caller last observed, so two replicas racing to register
+// the same key can't both believe they won.
+type Store interface {
+	// Get returns key's current value and revision. found is false (and
+	// revision 0) if key doesn't exist.
+	Get(key string) (value StoredValue, revision uint64, found bool, err error)
+
+	// CompareAndSwap writes value for key if its current revision equals
+	// expectedRev (0 meaning "key must not exist yet"), returning the new
+	// revision on success or ErrRevisionMismatch if another writer's CAS
+	// landed first.
+	CompareAndSwap(key string, expectedRev uint64, value StoredValue) (newRev uint64, err error)
+
+	// Delete removes key if its current revision equals expectedRev,
+	// returning ErrRevisionMismatch on conflict or ErrKeyNotFound if
+	// there's no such key at all.
+	Delete(key string, expectedRev uint64) error
+
+	// RangeExpired calls fn once for every stored entry whose ExpiresAt is
+	// non-zero and before cutoff. fn runs after RangeExpired has finished
+	// reading, so it's safe for fn to turn around and call CompareAndSwap
+	// or Delete on the same Store.
+	RangeExpired(before time.Time, fn func(key string, value StoredValue)) error
+
+	// Count returns the total number of keys currently stored, expired or
+	// not, for registry stats (BootstrapService.handleHealth).
+	Count() (int, error)
+}
+
+// memEntry is one key's current value and revision in a MemStore.
+type memEntry struct {
+	value    StoredValue
+	revision uint64
+}
+
+// MemStore is the default, non-persistent Store backend: a map guarded by
+// a mutex with a single monotonic revision counter shared across all keys.
+// It behaves identically to the registries' old bespoke in-memory maps,
+// just routed through the Store interface, and remains the right choice
+// for a single bootstrap replica.
+type MemStore struct {
+	mu      sync.Mutex
+	entries map[string]memEntry
+	nextRev uint64
+}
+
+// NewMemStore creates an empty in-memory Store.
+func NewMemStore() *MemStore {
+	return &MemStore{entries: make(map[string]memEntry)}
+}
+
+func (m *MemStore) Get(key string) (StoredValue, uint64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return StoredValue{}, 0, false, nil
+	}
+	return e.value, e.revision, true, nil
+}
+
+func (m *MemStore) CompareAndSwap(key string, expectedRev uint64, value StoredValue) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var current uint64
+	if e, ok := m.entries[key]; ok {
+		current = e.revision
+	}
+	if current != expectedRev {
+		return 0, ErrRevisionMismatch
+	}
+
+	m.nextRev++
+	m.entries[key] = memEntry{value: value, revision: m.nextRev}
+	return m.nextRev, nil
+}
+
+func (m *MemStore) Delete(key string, expectedRev uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	if e.revision != expectedRev {
+		return ErrRevisionMismatch
+	}
+
+	delete(m.entries, key)
+	return nil
+}
+
+func (m *MemStore) RangeExpired(before time.Time, fn func(key string, value StoredValue)) error {
+	type kv struct {
+		key   string
+		value StoredValue
+	}
+
+	m.mu.Lock()
+	var expired []kv
+	for key, e := range m.entries {
+		if !e.value.ExpiresAt.IsZero() && e.value.ExpiresAt.Before(before) {
+			expired = append(expired, kv{key, e.value})
+		}
+	}
+	m.mu.Unlock()
+
+	for _, e := range expired {
+		fn(e.key, e.value)
+	}
+	return nil
+}
+
+func (m *MemStore) Count() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries), nil
+}
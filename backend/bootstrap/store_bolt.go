@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// boltStoredValue is how a StoredValue and its revision are serialized into
+// a single Bolt value: keeping the revision alongside the payload means a
+// read only needs one bucket lookup instead of a second counter key.
+type boltStoredValue struct {
+	Data      []byte    `json:"data"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Revision  uint64    `json:"revision"`
+}
+
+// BoltStore is a Store backed by a bucket in a BoltDB file, for running
+// bootstrap nodes behind a load balancer where an in-memory MemStore per
+// replica would split-brain: every replica CASes against the same on-disk
+// revision. Each BoltStore owns one bucket, so TokenRegistry and
+// UsernameRegistry can share a single underlying file without their keys
+// colliding.
+type BoltStore struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// OpenBoltStore opens (creating if necessary) db's bucket as a Store.
+// Closing the returned BoltStore closes db itself, so callers sharing one
+// *bolt.DB across multiple buckets should open it once and wrap each
+// bucket separately rather than calling Close on every BoltStore.
+func OpenBoltStore(db *bolt.DB, bucket []byte) (*BoltStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error { _, e := tx.CreateBucketIfNotExists(bucket); return e })
+	if err != nil {
+		return nil, err
+	}
+	return &BoltStore{db: db, bucket: bucket}, nil
+}
+
+// OpenBoltDB opens (creating if necessary) a BoltDB file at path, for
+// passing to OpenBoltStore.
+func OpenBoltDB(path string) (*bolt.DB, error) {
+	return bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+}
+
+func (b *BoltStore) Close() error { return b.db.Close() }
+
+func (b *BoltStore) Get(key string) (StoredValue, uint64, bool, error) {
+	var sv StoredValue
+	var rev uint64
+	var found bool
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(b.bucket)
+		raw := bk.Get([]byte(key))
+		if raw == nil {
+			return nil
+		}
+
+		var bsv boltStoredValue
+		if err := json.Unmarshal(raw, &bsv); err != nil {
+			return err
+		}
+		sv = StoredValue{Data: bsv.Data, ExpiresAt: bsv.ExpiresAt}
+		rev = bsv.Revision
+		found = true
+		return nil
+	})
+	if err != nil {
+		return StoredValue{}, 0, false, err
+	}
+	return sv, rev, found, nil
+}
+
+func (b *BoltStore) CompareAndSwap(key string, expectedRev uint64, value StoredValue) (uint64, error) {
+	var newRev uint64
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(b.bucket)
+
+		var current uint64
+		if raw := bk.Get([]byte(key)); raw != nil {
+			var bsv boltStoredValue
+			if err := json.Unmarshal(raw, &bsv); err != nil {
+				return err
+			}
+			current = bsv.Revision
+		}
+		if current != expectedRev {
+			return ErrRevisionMismatch
+		}
+
+		seq, err := bk.NextSequence()
+		if err != nil {
+			return err
+		}
+		newRev = seq
+
+		encoded, err := json.Marshal(boltStoredValue{Data: value.Data, ExpiresAt: value.ExpiresAt, Revision: newRev})
+		if err != nil {
+			return err
+		}
+		return bk.Put([]byte(key), encoded)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newRev, nil
+}
+
+func (b *BoltStore) Delete(key string, expectedRev uint64) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(b.bucket)
+
+		raw := bk.Get([]byte(key))
+		if raw == nil {
+			return ErrKeyNotFound
+		}
+
+		var bsv boltStoredValue
+		if err := json.Unmarshal(raw, &bsv); err != nil {
+			return err
+		}
+		if bsv.Revision != expectedRev {
+			return ErrRevisionMismatch
+		}
+
+		return bk.Delete([]byte(key))
+	})
+}
+
+func (b *BoltStore) RangeExpired(before time.Time, fn func(key string, value StoredValue)) error {
+	type kv struct {
+		key   string
+		value StoredValue
+	}
+
+	var expired []kv
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(b.bucket)
+		return bk.ForEach(func(k, raw []byte) error {
+			var bsv boltStoredValue
+			if err := json.Unmarshal(raw, &bsv); err != nil {
+				return err
+			}
+			if !bsv.ExpiresAt.IsZero() && bsv.ExpiresAt.Before(before) {
+				expired = append(expired, kv{string(k), StoredValue{Data: bsv.Data, ExpiresAt: bsv.ExpiresAt}})
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range expired {
+		fn(e.key, e.value)
+	}
+	return nil
+}
+
+func (b *BoltStore) Count() (int, error) {
+	count := 0
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(b.bucket)
+		return bk.ForEach(func(k, v []byte) error {
+			count++
+			return nil
+		})
+	})
+	return count, err
+}
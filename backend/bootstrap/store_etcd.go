@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcdRequestTimeout bounds every individual etcd RPC this driver issues,
+// so a partitioned or unreachable cluster fails a single Get/CompareAndSwap/
+// Delete call instead of hanging the bootstrap service's request handlers.
+const etcdRequestTimeout = 5 * time.Second
+
+// etcdStoredValue is how a StoredValue is serialized into an etcd value.
+// Unlike boltStoredValue, it carries no revision: etcd already tracks a
+// per-key ModRevision itself, so CompareAndSwap and Get use that instead
+// of maintaining a parallel counter.
+type etcdStoredValue struct {
+	Data      []byte    `json:"data"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EtcdStore is a Store backed by etcd v3, for running bootstrap as
+// multiple replicas across machines or availability zones rather than
+// BoltStore's single shared file: every write CASes against etcd's own
+// ModRevision via a Txn, and any entry with a non-zero ExpiresAt is
+// attached to a lease so etcd auto-expires it cluster-wide even if this
+// particular replica's cleanup goroutine (RangeExpired) never runs.
+type EtcdStore struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// OpenEtcdStore wraps client as a Store, namespacing every key under
+// prefix so TokenRegistry and UsernameRegistry can share one etcd
+// cluster/client without their keys colliding.
+func OpenEtcdStore(client *clientv3.Client, prefix string) *EtcdStore {
+	return &EtcdStore{client: client, prefix: prefix}
+}
+
+// OpenEtcdClient dials an etcd v3 client against endpoints, for passing to
+// OpenEtcdStore.
+func OpenEtcdClient(endpoints []string) (*clientv3.Client, error) {
+	return clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: etcdRequestTimeout,
+	})
+}
+
+func (e *EtcdStore) key(k string) string { return e.prefix + k }
+
+func (e *EtcdStore) Get(key string) (StoredValue, uint64, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, e.key(key))
+	if err != nil {
+		return StoredValue{}, 0, false, err
+	}
+	if len(resp.Kvs) == 0 {
+		return StoredValue{}, 0, false, nil
+	}
+
+	var sv etcdStoredValue
+	if err := json.Unmarshal(resp.Kvs[0].Value, &sv); err != nil {
+		return StoredValue{}, 0, false, err
+	}
+	return StoredValue{Data: sv.Data, ExpiresAt: sv.ExpiresAt}, uint64(resp.Kvs[0].ModRevision), true, nil
+}
+
+func (e *EtcdStore) CompareAndSwap(key string, expectedRev uint64, value StoredValue) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	encoded, err := json.Marshal(etcdStoredValue{Data: value.Data, ExpiresAt: value.ExpiresAt})
+	if err != nil {
+		return 0, err
+	}
+
+	put := clientv3.OpPut(e.key(key), string(encoded))
+	if !value.ExpiresAt.IsZero() {
+		ttl := int64(time.Until(value.ExpiresAt).Seconds())
+		if ttl < 1 {
+			ttl = 1
+		}
+		lease, err := e.client.Grant(ctx, ttl)
+		if err != nil {
+			return 0, err
+		}
+		put = clientv3.OpPut(e.key(key), string(encoded), clientv3.WithLease(lease.ID))
+	}
+
+	var cmp clientv3.Cmp
+	if expectedRev == 0 {
+		cmp = clientv3.Compare(clientv3.CreateRevision(e.key(key)), "=", 0)
+	} else {
+		cmp = clientv3.Compare(clientv3.ModRevision(e.key(key)), "=", int64(expectedRev))
+	}
+
+	resp, err := e.client.Txn(ctx).If(cmp).Then(put).Commit()
+	if err != nil {
+		return 0, err
+	}
+	if !resp.Succeeded {
+		return 0, ErrRevisionMismatch
+	}
+	// Every op in a successful txn commits at the same cluster revision,
+	// which becomes the written key's new ModRevision.
+	return uint64(resp.Header.Revision), nil
+}
+
+func (e *EtcdStore) Delete(key string, expectedRev uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	getResp, err := e.client.Get(ctx, e.key(key))
+	if err != nil {
+		return err
+	}
+	if len(getResp.Kvs) == 0 {
+		return ErrKeyNotFound
+	}
+
+	cmp := clientv3.Compare(clientv3.ModRevision(e.key(key)), "=", int64(expectedRev))
+	del := clientv3.OpDelete(e.key(key))
+	resp, err := e.client.Txn(ctx).If(cmp).Then(del).Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return ErrRevisionMismatch
+	}
+	return nil
+}
+
+func (e *EtcdStore) RangeExpired(before time.Time, fn func(key string, value StoredValue)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, e.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	type kv struct {
+		key   string
+		value StoredValue
+	}
+	var expired []kv
+	for _, item := range resp.Kvs {
+		var sv etcdStoredValue
+		if err := json.Unmarshal(item.Value, &sv); err != nil {
+			return err
+		}
+		if !sv.ExpiresAt.IsZero() && sv.ExpiresAt.Before(before) {
+			expired = append(expired, kv{
+				key:   strings.TrimPrefix(string(item.Key), e.prefix),
+				value: StoredValue{Data: sv.Data, ExpiresAt: sv.ExpiresAt},
+			})
+		}
+	}
+
+	for _, item := range expired {
+		fn(item.key, item.value)
+	}
+	return nil
+}
+
+func (e *EtcdStore) Count() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, e.prefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.Count), nil
+}
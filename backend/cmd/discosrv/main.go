@@ -0,0 +1,215 @@
+// Command discosrv is a device discovery service modeled on Syncthing's
+// stdiscosrv: peers announce the addresses they're reachable at (signed
+// with their ed25519 identity key) and other peers look those addresses
+// up by device ID. It's a standalone replacement for relying solely on
+// bootstrap's token/username rendezvous when what's needed is "where is
+// device X reachable right now."
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/quantarax/backend/internal/discovery"
+	"golang.org/x/time/rate"
+)
+
+// maxAnnounceTTL bounds how far in the future an announcement's
+// ExpiresAt may be, the same role bootstrap's maxTTL plays for token TTLs:
+// it keeps a single announcement from squatting on a device ID forever.
+const maxAnnounceTTL = 1 * time.Hour
+
+// DiscoveryService wires a discovery.Registry up to the HTTP API, adding
+// per-IP rate limiting the same shape bootstrap.BootstrapService uses.
+type DiscoveryService struct {
+	registry  *discovery.Registry
+	limiters  map[string]*rate.Limiter
+	limiterMu sync.RWMutex
+}
+
+func NewDiscoveryService() *DiscoveryService {
+	return &DiscoveryService{
+		registry: discovery.NewRegistry(),
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+func (ds *DiscoveryService) getRateLimiter(ip string, limit rate.Limit, burst int) *rate.Limiter {
+	ds.limiterMu.Lock()
+	defer ds.limiterMu.Unlock()
+
+	limiter, exists := ds.limiters[ip]
+	if !exists {
+		limiter = rate.NewLimiter(limit, burst)
+		ds.limiters[ip] = limiter
+	}
+	return limiter
+}
+
+func getClientIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		return xff
+	}
+	if xri := r.Header.Get("X-Real-IP"); xri != "" {
+		return xri
+	}
+	return r.RemoteAddr
+}
+
+// announceRequest is the wire shape of POST /v1/announce; PublicKey and
+// Sig travel base64-encoded since an Announcement's fields are raw bytes.
+type announceRequest struct {
+	DeviceID  string    `json:"device_id"`
+	PublicKey string    `json:"public_key"`
+	Addresses []string  `json:"addresses"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Sig       string    `json:"sig"`
+}
+
+func (ds *DiscoveryService) handleAnnounce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ip := getClientIP(r)
+	limiter := ds.getRateLimiter(ip, rate.Limit(20.0/60.0), 20) // 20 per minute
+	if !limiter.Allow() {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	var req announceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	pub, err := base64.StdEncoding.DecodeString(req.PublicKey)
+	if err != nil {
+		http.Error(w, "Invalid public_key encoding", http.StatusBadRequest)
+		return
+	}
+	sig, err := base64.StdEncoding.DecodeString(req.Sig)
+	if err != nil {
+		http.Error(w, "Invalid sig encoding", http.StatusBadRequest)
+		return
+	}
+
+	if req.ExpiresAt.After(time.Now().Add(maxAnnounceTTL)) {
+		req.ExpiresAt = time.Now().Add(maxAnnounceTTL)
+	}
+
+	ann := discovery.Announcement{
+		DeviceID:  req.DeviceID,
+		PublicKey: pub,
+		Addresses: req.Addresses,
+		ExpiresAt: req.ExpiresAt,
+		Sig:       sig,
+	}
+	if err := ds.registry.Announce(ann); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Announcement stored: %s (%d addresses, expires %s)", ann.DeviceID, len(ann.Addresses), ann.ExpiresAt.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"device_id":  ann.DeviceID,
+		"expires_at": ann.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+func (ds *DiscoveryService) handleLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ip := getClientIP(r)
+	limiter := ds.getRateLimiter(ip, rate.Limit(200.0/60.0), 200) // 200 per minute
+	if !limiter.Allow() {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	deviceID := strings.TrimPrefix(r.URL.Path, "/v1/lookup/")
+	if deviceID == "" {
+		http.Error(w, "device_id required", http.StatusBadRequest)
+		return
+	}
+
+	result, found := ds.registry.Lookup(deviceID, ip)
+	if !found {
+		http.Error(w, "Device not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (ds *DiscoveryService) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       "healthy",
+		"device_count": ds.registry.Count(),
+	})
+}
+
+func main() {
+	listen := flag.String("listen", ":8083", "HTTP listen address")
+	cleanupInterval := flag.Duration("cleanup-interval", 60*time.Second, "Cleanup interval")
+	flag.Parse()
+
+	log.Printf("QuantaraX Discovery Service starting...")
+	log.Printf("Cleanup interval: %s", *cleanupInterval)
+
+	service := NewDiscoveryService()
+
+	go func() {
+		ticker := time.NewTicker(*cleanupInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if count := service.registry.CleanupExpired(); count > 0 {
+				log.Printf("Cleaned up %d expired announcements", count)
+			}
+		}
+	}()
+
+	http.HandleFunc("/v1/announce", service.handleAnnounce)
+	http.HandleFunc("/v1/lookup/", service.handleLookup)
+	http.HandleFunc("/health", service.handleHealth)
+
+	server := &http.Server{
+		Addr:         *listen,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Printf("Discovery service listening on %s", *listen)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("HTTP server error: %v", err)
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	log.Println("Shutting down gracefully...")
+	log.Printf("Final stats - Devices: %d", service.registry.Count())
+}
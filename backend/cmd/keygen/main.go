@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,10 +22,14 @@ const (
 
 var (
 	// Global flags
-	outputDir     string
-	noPassphrase  bool
-	force         bool
+	outputDir      string
+	noPassphrase   bool
+	force          bool
 	includePrivate bool
+	exportFormat   string
+	exportFile     string
+	recipients     string
+	identityFile   string
 )
 
 func main() {
@@ -43,6 +48,8 @@ func main() {
 		showCmd(args)
 	case "export":
 		exportCmd(args)
+	case "import":
+		importCmd(args)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		printUsage()
@@ -57,6 +64,7 @@ func printUsage() {
 	fmt.Println("  keygen generate [flags]  - Generate new identity keypair")
 	fmt.Println("  keygen show              - Display public key information")
 	fmt.Println("  keygen export [flags]    - Export keys for backup")
+	fmt.Println("  keygen import [flags]    - Import a previously exported key")
 	fmt.Println()
 	fmt.Println("Run 'keygen <command> -h' for command-specific help")
 }
@@ -212,6 +220,9 @@ func exportCmd(args []string) {
 	fs := flag.NewFlagSet("export", flag.ExitOnError)
 	fs.StringVar(&outputDir, "keys-dir", crypto.GetDefaultKeystorePath(), "Key storage directory")
 	fs.BoolVar(&includePrivate, "include-private", false, "Include private key in export")
+	fs.StringVar(&exportFormat, "format", "keystore-v1", "Private key export format: keystore-v1 or age")
+	fs.StringVar(&exportFile, "out", "", "Output file for the private key export (required with -include-private)")
+	fs.StringVar(&recipients, "recipients", "", "Comma-separated age1... recipients (format=age only)")
 	fs.Parse(args)
 
 	pubPath := filepath.Join(outputDir, identityPubFile)
@@ -226,10 +237,187 @@ func exportCmd(args []string) {
 	fmt.Println("Public Key:")
 	fmt.Print(string(pubKeyData))
 
-	if includePrivate {
+	if !includePrivate {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("WARNING: Exporting private key is a sensitive operation")
+
+	if exportFile == "" {
+		fmt.Fprintln(os.Stderr, "-out is required with -include-private")
+		os.Exit(1)
+	}
+
+	priv, err := loadIdentityPrivateKey(outputDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	var data []byte
+	switch exportFormat {
+	case "keystore-v1":
+		passphrase := promptNewPassphrase("Enter export passphrase: ")
+		data, err = crypto.ExportKeystore(priv, passphrase)
+	case "age":
+		if recipients == "" {
+			fmt.Fprintln(os.Stderr, "-recipients is required with -format=age")
+			os.Exit(1)
+		}
+		data, err = crypto.ExportKeystoreAge(priv, strings.Split(recipients, ","))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown export format: %s\n", exportFormat)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(exportFile, data, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write export file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Private key exported (%s) to %s\n", exportFormat, exportFile)
+}
+
+func importCmd(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.StringVar(&outputDir, "output-dir", crypto.GetDefaultKeystorePath(), "Key storage directory")
+	fs.BoolVar(&force, "force", false, "Overwrite existing keys")
+	fs.StringVar(&exportFormat, "format", "keystore-v1", "Import format: keystore-v1 or age")
+	fs.StringVar(&exportFile, "in", "", "Exported key file to import (required)")
+	fs.StringVar(&identityFile, "identity-file", "", "Path to an age identity file (format=age only)")
+	fs.Parse(args)
+
+	if exportFile == "" {
+		fmt.Fprintln(os.Stderr, "-in is required")
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(exportFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read export file: %v\n", err)
+		os.Exit(1)
+	}
+
+	var priv []byte
+	switch exportFormat {
+	case "keystore-v1":
+		fmt.Print("Enter export passphrase: ")
+		passphraseBytes, rerr := term.ReadPassword(int(syscall.Stdin))
 		fmt.Println()
-		fmt.Println("WARNING: Exporting private key is sensitive operation")
-		fmt.Println("Private key export not yet implemented in this version")
-		fmt.Println("Use the keystore file directly for backup")
+		if rerr != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read passphrase: %v\n", rerr)
+			os.Exit(1)
+		}
+		priv, err = crypto.ImportKeystore(data, string(passphraseBytes))
+	case "age":
+		if identityFile == "" {
+			fmt.Fprintln(os.Stderr, "-identity-file is required with -format=age")
+			os.Exit(1)
+		}
+		identityData, rerr := os.ReadFile(identityFile)
+		if rerr != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read identity file: %v\n", rerr)
+			os.Exit(1)
+		}
+		priv, err = crypto.ImportKeystoreAge(data, string(identityData))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown import format: %s\n", exportFormat)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to import private key: %v\n", err)
+		os.Exit(1)
 	}
+
+	if err := os.MkdirAll(outputDir, 0700); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
+		os.Exit(1)
+	}
+	keyPath := filepath.Join(outputDir, identityKeyFile)
+	if !force {
+		if _, err := os.Stat(keyPath); !os.IsNotExist(err) {
+			fmt.Println("Identity keys already exist.")
+			fmt.Print("Overwrite existing keys? [y/N]: ")
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Println("Aborted.")
+				return
+			}
+		}
+	}
+
+	passphrase := promptNewPassphrase("Enter a new keystore passphrase (leave empty for no encryption): ")
+	if err := crypto.SaveKey(priv, keyPath, passphrase); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save private key: %v\n", err)
+		os.Exit(1)
+	}
+
+	pubKey := ed25519PublicFromPrivate(priv)
+	pubKeyB64 := base64.StdEncoding.EncodeToString(pubKey)
+	pubPath := filepath.Join(outputDir, identityPubFile)
+	if err := os.WriteFile(pubPath, []byte(pubKeyB64+"\n"), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save public key: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Private key imported successfully!")
+	fmt.Printf("Keys stored in:\n  %s\n", outputDir)
+}
+
+// loadIdentityPrivateKey prompts for the local keystore's passphrase (if
+// any) and loads the identity private key stored under keysDir.
+func loadIdentityPrivateKey(keysDir string) ([]byte, error) {
+	keyPath := filepath.Join(keysDir, identityKeyFile)
+	if _, err := os.Stat(keyPath + ".insecure"); err == nil {
+		return crypto.LoadKey(keyPath+".insecure", "")
+	}
+
+	fmt.Print("Enter keystore passphrase: ")
+	passphraseBytes, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return crypto.LoadKey(keyPath, string(passphraseBytes))
+}
+
+// promptNewPassphrase reads and confirms a new passphrase, exiting the
+// process on mismatch or read failure. An empty passphrase is allowed
+// through unconfirmed, matching generateCmd's existing convention.
+func promptNewPassphrase(prompt string) string {
+	fmt.Print(prompt)
+	passphraseBytes, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read passphrase: %v\n", err)
+		os.Exit(1)
+	}
+	passphrase := string(passphraseBytes)
+	if passphrase == "" {
+		return passphrase
+	}
+
+	fmt.Print("Confirm passphrase: ")
+	confirmBytes, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read passphrase: %v\n", err)
+		os.Exit(1)
+	}
+	if passphrase != string(confirmBytes) {
+		fmt.Fprintln(os.Stderr, "Passphrases do not match.")
+		os.Exit(1)
+	}
+	return passphrase
+}
+
+// ed25519PublicFromPrivate extracts the public half from an Ed25519
+// private key (the last 32 of its 64 bytes, per crypto/ed25519's seed+pub
+// concatenation).
+func ed25519PublicFromPrivate(priv []byte) []byte {
+	return priv[32:]
 }
\ No newline at end of file
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/quantarax/backend/daemon/manager/migrations"
+	"github.com/quantarax/backend/internal/crypto"
+	"github.com/quantarax/backend/internal/crypto/handshake"
+	"github.com/quantarax/backend/internal/mount"
+	"github.com/quantarax/backend/internal/quicutil"
+	"github.com/quic-go/quic-go"
+	_ "modernc.org/sqlite"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	command := os.Args[1]
+	args := os.Args[2:]
+
+	switch command {
+	case "db":
+		dbCmd(args)
+	case "mount":
+		mountCmd(args)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Println("quantarax - QuantaraX daemon maintenance tool")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  quantarax db migrate [flags]             - Apply pending schema migrations")
+	fmt.Println("  quantarax db version [flags]              - Print current and known schema versions")
+	fmt.Println("  quantarax mount <peer> <remote-path> <local-path> - Mount a peer's directory tree over QUIC")
+	fmt.Println()
+	fmt.Println("Run 'quantarax db <command> -h' for command-specific help")
+}
+
+// mountCmd implements `quantarax mount <peer> <remote-path> <local-path>`:
+// it dials peer, negotiates session keys via the handshake package, then
+// attaches remote-path at local-path for as long as the process runs,
+// unmounting cleanly on SIGINT/SIGTERM.
+func mountCmd(args []string) {
+	fs := flag.NewFlagSet("mount", flag.ExitOnError)
+	fs.Parse(args)
+	rest := fs.Args()
+	if len(rest) != 3 {
+		fmt.Fprintln(os.Stderr, "Usage: quantarax mount <peer> <remote-path> <local-path>")
+		os.Exit(1)
+	}
+	peer, remotePath, localPath := rest[0], rest[1], rest[2]
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tlsConfig := quicutil.MakeClientTLSConfig()
+	tlsConfig.NextProtos = []string{"quantarax-mount"}
+	conn, err := quic.DialAddr(ctx, peer, tlsConfig, &quic.Config{EnableDatagrams: false})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to %s: %v\n", peer, err)
+		os.Exit(1)
+	}
+	defer conn.CloseWithError(0, "mount closed")
+
+	handshakeStream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open handshake stream: %v\n", err)
+		os.Exit(1)
+	}
+	clientIDPub, clientIDPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate identity key: %v\n", err)
+		os.Exit(1)
+	}
+	hsKeys, err := handshake.ClientHandshake(handshakeStream, remotePath, clientIDPriv, clientIDPub, nil, handshake.KEMX25519)
+	handshakeStream.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Handshake failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	session := &mount.Session{
+		Conn: conn,
+		SessionKeys: &crypto.SessionKeys{
+			PayloadKey: hsKeys.PayloadKey,
+			IVBase:     hsKeys.IVBase,
+		},
+	}
+
+	fmt.Printf("Mounting %s:%s at %s\n", peer, remotePath, localPath)
+	if err := mount.Attach(ctx, session, localPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Mount failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func dbCmd(args []string) {
+	if len(args) < 1 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "migrate":
+		dbMigrateCmd(args[1:])
+	case "version":
+		dbVersionCmd(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown db command: %s\n", args[0])
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func dbMigrateCmd(args []string) {
+	fs := flag.NewFlagSet("db migrate", flag.ExitOnError)
+	dbPath := fs.String("db", "quantarax.db", "Path to the daemon's SQLite database")
+	to := fs.Int("to", migrations.MaxKnownVersion(migrations.Registry), "Target schema version (defaults to the newest known migration)")
+	fs.Parse(args)
+
+	db, err := sql.Open("sqlite", *dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err := migrations.RunTo(db, migrations.Registry, *to); err != nil {
+		fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Database %s migrated to version %d\n", *dbPath, *to)
+}
+
+func dbVersionCmd(args []string) {
+	fs := flag.NewFlagSet("db version", flag.ExitOnError)
+	dbPath := fs.String("db", "quantarax.db", "Path to the daemon's SQLite database")
+	fs.Parse(args)
+
+	db, err := sql.Open("sqlite", *dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open database: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	current, err := migrations.CurrentVersion(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read schema version: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Current schema version: %d\n", current)
+	fmt.Printf("Known schema version:   %d\n", migrations.MaxKnownVersion(migrations.Registry))
+}
@@ -10,8 +10,11 @@ import (
 	"path/filepath"
 	"time"
 
+	"strings"
+
 	"github.com/google/uuid"
 	"github.com/quantarax/backend/internal/crypto"
+	"github.com/quantarax/backend/internal/nattraversal"
 	"github.com/quantarax/backend/internal/observability"
 	"github.com/quantarax/backend/internal/quicutil"
 	"github.com/quic-go/quic-go"
@@ -26,13 +29,15 @@ const (
 )
 
 var (
-	listen    string
-	outputDir string
+	listen      string
+	outputDir   string
+	stunServers string // comma-separated STUN servers for NAT candidate gathering
 )
 
 func main() {
 	flag.StringVar(&listen, "listen", ":4433", "Listen address (host:port)")
 	flag.StringVar(&outputDir, "output-dir", "./received", "Output directory for chunks")
+	flag.StringVar(&stunServers, "stun", "", "Comma-separated STUN servers (host:port) for NAT traversal candidate gathering")
 	flag.Parse()
 
 	// Init tracing if configured
@@ -84,6 +89,22 @@ func receiveChunks() error {
 		return fmt.Errorf("failed to derive session keys: %w", err)
 	}
 
+	// Gather NAT traversal candidates when --stun is set. As on the sender
+	// side, this demo binary has no signaling channel to hand these to a
+	// peer yet, so it only reports what Connect would have to work with;
+	// the listener below still binds to --listen as before.
+	if stunServers != "" {
+		candidates, err := nattraversal.Gather(ctx, strings.Split(stunServers, ","), "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "NAT candidate gathering failed: %v\n", err)
+		} else {
+			fmt.Println("Gathered NAT traversal candidates:")
+			for _, c := range candidates {
+				fmt.Printf("  %s\n", c.String())
+			}
+		}
+	}
+
 	listener, err := quic.ListenAddr(listen, tlsConfig, &quic.Config{EnableDatagrams: false})
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
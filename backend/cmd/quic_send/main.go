@@ -13,10 +13,12 @@ import (
 	"github.com/quantarax/backend/internal/chunker"
 	"github.com/quantarax/backend/internal/crypto"
 	"github.com/quantarax/backend/internal/introspect"
+	"github.com/quantarax/backend/internal/nattraversal"
 	"encoding/binary"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/curve25519"
 	"io"
+	"strings"
 )
 
 const (
@@ -32,6 +34,7 @@ var (
 	chunkIndex int
 	chunkSize  int
 	offset     int
+	stunServers string // comma-separated STUN servers for NAT candidate gathering
 )
 
 func main() {
@@ -42,6 +45,7 @@ func main() {
 	flag.IntVar(&chunkIndex, "chunk-index", 0, "Chunk index to send")
 	flag.IntVar(&chunkSize, "chunk-size", 1<<20, "Chunk size in bytes (default 1MiB)")
 	flag.IntVar(&offset, "offset", 0, "Byte offset in file")
+	flag.StringVar(&stunServers, "stun", "", "Comma-separated STUN servers (host:port) for NAT traversal candidate gathering")
 	flag.Parse()
 
 	// Init tracing if configured
@@ -122,6 +126,22 @@ func sendChunk() error {
 
 	_ = buildChunkMessage(sessionID, uint32(chunkIndex), ciphertext)
 
+	// Gather NAT traversal candidates when --stun is set. There's no
+	// signaling channel in this demo binary to exchange candidates with the
+	// receiver yet, so this only reports what Connect would have to work
+	// with; dialing below still goes straight to --addr/--relay.
+	if stunServers != "" {
+		candidates, err := nattraversal.Gather(context.Background(), strings.Split(stunServers, ","), relayAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "NAT candidate gathering failed: %v\n", err)
+		} else {
+			fmt.Println("Gathered NAT traversal candidates:")
+			for _, c := range candidates {
+				fmt.Printf("  %s\n", c.String())
+			}
+		}
+	}
+
 	// Connect
 	tlsConfig := quicutil.MakeClientTLSConfig()
 	var dialAddr string
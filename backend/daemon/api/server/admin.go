@@ -0,0 +1,62 @@
+package server
+
+import "net/http"
+
+// AdminController lets main.go wire process-lifecycle behavior (restarting
+// or reloading the daemon) into the REST admin endpoints without
+// DaemonAPIServer needing to own that lifecycle itself, the same decoupling
+// ChunkWorkerPool.SetScheduler uses to keep scheduling policy out of the
+// pool.
+type AdminController interface {
+	// Restart persists in-flight transfers' resume state and re-execs the
+	// daemon binary.
+	Restart() error
+	// Reload re-parses configuration in place, without dropping active
+	// sessions.
+	Reload() error
+}
+
+// SetAdminController wires ctrl into /api/v1/admin/restart and
+// /api/v1/admin/reload. Left unset (nil), both endpoints answer 501 Not
+// Implemented.
+func (s *DaemonAPIServer) SetAdminController(ctrl AdminController) {
+	s.admin = ctrl
+}
+
+// handleAdminRestart drains in-flight transfers' resume state and re-execs
+// the daemon via AdminController.Restart. Guarded, like every other route,
+// by the authn.Chain middleware StartAPIServers installs ahead of the whole
+// mux (no authentication at all when that chain is empty).
+func (s *DaemonAPIServer) handleAdminRestart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.admin == nil {
+		writeJSONError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "admin controller not configured")
+		return
+	}
+	if err := s.admin.Restart(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "restarting"})
+}
+
+// handleAdminReload re-parses configuration via AdminController.Reload
+// without restarting the process or dropping active QUIC sessions.
+func (s *DaemonAPIServer) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.admin == nil {
+		writeJSONError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "admin controller not configured")
+		return
+	}
+	if err := s.admin.Reload(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+}
@@ -0,0 +1,42 @@
+package authn
+
+// ChainOptions mirrors daemon/config.Config.Auth's fields without this
+// package importing daemon/config: config is low-level enough that lots of
+// packages import it, and importing it back from authn would invert that
+// dependency direction for no benefit — daemon/main.go just copies the
+// fields across when calling BuildChain.
+type ChainOptions struct {
+	StaticToken    string
+	HMACKey        string
+	OIDCJWKSURL    string
+	OIDCIssuer     string
+	OIDCAudience   string
+	MTLSEnabled    bool
+	MTLSAllowedOUs []string
+}
+
+// BuildChain constructs the Chain opts describes, in the fixed
+// static/HMAC/OIDC/mTLS order every Chain in this codebase uses so a
+// request presenting more than one credential form is resolved the same
+// way regardless of which daemon built the chain. A ChainOptions with every
+// field at its zero value produces an empty Chain, which disables auth.
+func BuildChain(opts ChainOptions) Chain {
+	var chain Chain
+	if opts.StaticToken != "" {
+		chain = append(chain, &StaticTokenAuthenticator{Token: opts.StaticToken})
+	}
+	if opts.HMACKey != "" {
+		chain = append(chain, &HMACBearerAuthenticator{Key: []byte(opts.HMACKey)})
+	}
+	if opts.OIDCJWKSURL != "" {
+		chain = append(chain, &OIDCAuthenticator{
+			JWKSURL:  opts.OIDCJWKSURL,
+			Issuer:   opts.OIDCIssuer,
+			Audience: opts.OIDCAudience,
+		})
+	}
+	if opts.MTLSEnabled {
+		chain = append(chain, &MTLSAuthenticator{AllowedOUs: opts.MTLSAllowedOUs})
+	}
+	return chain
+}
@@ -0,0 +1,166 @@
+package authn
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hmacClaims is the JSON payload an HMACBearerAuthenticator token signs.
+// Nonce exists purely to make two tokens minted for the same subject at the
+// same second distinguishable in the replay cache — it isn't otherwise
+// meaningful.
+type hmacClaims struct {
+	Subject string `json:"sub"`
+	Expiry  int64  `json:"exp"`
+	Nonce   string `json:"nonce"`
+}
+
+// HMACToken signs claims with key using HMAC-SHA256 and returns the bearer
+// token string callers (bootstrap flows, CLI login) hand to a client:
+// base64url(claimsJSON) + "." + base64url(mac).
+func HMACToken(key []byte, subject string, expiry time.Time, nonce string) (string, error) {
+	claims := hmacClaims{Subject: subject, Expiry: expiry.Unix(), Nonce: nonce}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	claimsB64 := base64.RawURLEncoding.EncodeToString(claimsJSON)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(claimsB64))
+	sigB64 := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	return claimsB64 + "." + sigB64, nil
+}
+
+// nonceCache is a bounded, TTL-expiring set of consumed nonces, preventing
+// a captured HMAC bearer token from being replayed until its own expiry —
+// after that it's rejected on the expiry check anyway, so entries older
+// than the longest token TTL in use are safe to evict.
+type nonceCache struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	maxSize int
+}
+
+func newNonceCache(maxSize int) *nonceCache {
+	return &nonceCache{seen: make(map[string]time.Time), maxSize: maxSize}
+}
+
+// checkAndStore returns false if key has already been seen and hasn't
+// expired; otherwise it records key (evicting expired entries first, and
+// the single oldest entry if still over maxSize) and returns true.
+func (c *nonceCache) checkAndStore(key string, expiry time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if exp, ok := c.seen[key]; ok && exp.After(now) {
+		return false
+	}
+
+	for k, exp := range c.seen {
+		if exp.Before(now) {
+			delete(c.seen, k)
+		}
+	}
+	if len(c.seen) >= c.maxSize {
+		var oldestKey string
+		var oldest time.Time
+		for k, exp := range c.seen {
+			if oldest.IsZero() || exp.Before(oldest) {
+				oldestKey, oldest = k, exp
+			}
+		}
+		if oldestKey != "" {
+			delete(c.seen, oldestKey)
+		}
+	}
+	c.seen[key] = expiry
+	return true
+}
+
+// defaultNonceCacheSize bounds HMACBearerAuthenticator's replay cache when
+// MaxReplayCacheSize is left at zero.
+const defaultNonceCacheSize = 10000
+
+// HMACBearerAuthenticator verifies an `Authorization: Bearer <token>`
+// header whose token is HMACToken's `base64url(claims).base64url(mac)`
+// form, rejecting expired claims and replayed (subject, nonce) pairs.
+type HMACBearerAuthenticator struct {
+	Key                []byte
+	MaxReplayCacheSize int
+
+	once  sync.Once
+	nonce *nonceCache
+}
+
+func (h *HMACBearerAuthenticator) cache() *nonceCache {
+	h.once.Do(func() {
+		size := h.MaxReplayCacheSize
+		if size <= 0 {
+			size = defaultNonceCacheSize
+		}
+		h.nonce = newNonceCache(size)
+	})
+	return h.nonce
+}
+
+// Authenticate implements Authenticator.
+func (h *HMACBearerAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, ErrNoCredentials
+	}
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return nil, ErrNoCredentials
+	}
+	token := strings.TrimPrefix(header, prefix)
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		// Not HMACToken's two-part form (e.g. a three-part OIDC JWT): let
+		// the next Chain entry try it.
+		return nil, ErrNoCredentials
+	}
+	claimsB64, sigB64 := parts[0], parts[1]
+
+	mac := hmac.New(sha256.New, h.Key)
+	mac.Write([]byte(claimsB64))
+	wantSig := mac.Sum(nil)
+	gotSig, err := base64.RawURLEncoding.DecodeString(sigB64)
+	if err != nil || !hmac.Equal(gotSig, wantSig) {
+		return nil, errUnauthenticated("bearer token signature mismatch")
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(claimsB64)
+	if err != nil {
+		return nil, errUnauthenticated("malformed bearer token claims")
+	}
+	var claims hmacClaims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, errUnauthenticated("malformed bearer token claims")
+	}
+
+	expiry := time.Unix(claims.Expiry, 0)
+	if time.Now().After(expiry) {
+		return nil, errUnauthenticated("bearer token expired")
+	}
+
+	replayKey := claims.Subject + ":" + claims.Nonce
+	if !h.cache().checkAndStore(replayKey, expiry) {
+		return nil, errUnauthenticated("bearer token nonce already used")
+	}
+
+	return &Principal{
+		Subject: claims.Subject,
+		Method:  "hmac",
+		Claims:  map[string]string{"exp": strconv.FormatInt(claims.Expiry, 10)},
+	}, nil
+}
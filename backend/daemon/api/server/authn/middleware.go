@@ -0,0 +1,52 @@
+package authn
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+)
+
+// HTTPMiddleware wraps next so every request is authenticated by chain
+// before reaching it: a resolved Principal is attached to the request's
+// context (retrievable with FromContext) and the request proceeds; a
+// rejected or absent credential gets a JSON error body shaped like
+// JSONErrorHandler's, so a REST client sees the same {"code","message"}
+// envelope whether the rejection came from this middleware or a gRPC
+// status further down the stack. A nil chain (the default, same as
+// StartAPIServers' old auth-disabled-when-token-unset posture) disables
+// auth entirely — next is called unconditionally.
+func HTTPMiddleware(chain Chain) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(chain) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := chain.Authenticate(r)
+			if err != nil {
+				writeAuthError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+func writeAuthError(w http.ResponseWriter, err error) {
+	code := codes.Unauthenticated
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		code = authErr.GRPCCode()
+	}
+	status := http.StatusUnauthorized
+	codeStr := "UNAUTHENTICATED"
+	if code == codes.PermissionDenied {
+		status = http.StatusForbidden
+		codeStr = "PERMISSION_DENIED"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	b, _ := json.Marshal(map[string]string{"code": codeStr, "message": err.Error()})
+	_, _ = w.Write(b)
+}
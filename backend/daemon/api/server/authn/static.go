@@ -0,0 +1,36 @@
+package authn
+
+import "net/http"
+
+// StaticTokenAuthenticator reproduces StartAPIServers' original behavior —
+// a single shared-secret token compared against a fixed header — as one
+// Authenticator in a Chain rather than the only option. Header defaults to
+// "X-Auth-Token" when empty.
+type StaticTokenAuthenticator struct {
+	Header string
+	Token  string
+	// Subject is the Principal.Subject a successful match resolves to,
+	// since a shared static token has no notion of "who" beyond "holds the
+	// token" — defaults to "static" when empty.
+	Subject string
+}
+
+// Authenticate implements Authenticator.
+func (s *StaticTokenAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	header := s.Header
+	if header == "" {
+		header = "X-Auth-Token"
+	}
+	got := r.Header.Get(header)
+	if got == "" {
+		return nil, ErrNoCredentials
+	}
+	if got != s.Token {
+		return nil, errUnauthenticated("static token mismatch")
+	}
+	subject := s.Subject
+	if subject == "" {
+		subject = "static"
+	}
+	return &Principal{Subject: subject, Method: "static"}, nil
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HTTPFaultConfig tunes HTTPFaultInjector's chaos behavior for the REST
+// control plane, mirroring transport.FaultInjectorConfig's shape but for
+// whole HTTP responses instead of QUIC stream writes. A zero-value
+// HTTPFaultConfig (Enabled == false) disables it entirely.
+type HTTPFaultConfig struct {
+	Enabled bool
+
+	// Probability is the chance [0, 1] a matching request is faulted.
+	Probability float64
+
+	// StatusCodes are the candidate responses a fault returns; one is
+	// chosen at random on each hit. http.StatusServiceUnavailable if empty.
+	StatusCodes []int
+
+	// LatencyMin/LatencyMax add a random delay, uniformly distributed
+	// between them, before a faulted request's response is written.
+	LatencyMin time.Duration
+	LatencyMax time.Duration
+
+	// PathFilter, if set, restricts faults to requests whose path has this
+	// prefix; empty matches every request.
+	PathFilter string
+}
+
+// HTTPFaultInjector wraps RegisterHTTP's routes with reproducible response
+// chaos, configurable at runtime via POST /api/v1/admin/fault, so
+// integration tests can validate a client's retry/backoff recovery path
+// against the REST control plane without OS-level network chaos.
+type HTTPFaultInjector struct {
+	mu  sync.RWMutex
+	cfg HTTPFaultConfig
+
+	hits int64
+}
+
+// NewHTTPFaultInjector creates a disabled injector; Configure enables it.
+func NewHTTPFaultInjector() *HTTPFaultInjector {
+	return &HTTPFaultInjector{}
+}
+
+// Configure replaces the injector's active configuration.
+func (f *HTTPFaultInjector) Configure(cfg HTTPFaultConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cfg = cfg
+}
+
+// Hits returns how many requests this injector has faulted so far.
+func (f *HTTPFaultInjector) Hits() int64 {
+	return atomic.LoadInt64(&f.hits)
+}
+
+// Middleware wraps next so requests matching the injector's current
+// configuration are, with probability cfg.Probability, answered with a
+// simulated failure (one of cfg.StatusCodes, after an optional simulated
+// latency) instead of reaching next.
+func (f *HTTPFaultInjector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.RLock()
+		cfg := f.cfg
+		f.mu.RUnlock()
+
+		if !cfg.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if cfg.PathFilter != "" && !strings.HasPrefix(r.URL.Path, cfg.PathFilter) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if cfg.Probability <= 0 || rand.Float64() >= cfg.Probability {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		atomic.AddInt64(&f.hits, 1)
+		if cfg.LatencyMax > 0 {
+			d := cfg.LatencyMin
+			if cfg.LatencyMax > cfg.LatencyMin {
+				d += time.Duration(rand.Int63n(int64(cfg.LatencyMax - cfg.LatencyMin)))
+			}
+			time.Sleep(d)
+		}
+
+		codes := cfg.StatusCodes
+		if len(codes) == 0 {
+			codes = []int{http.StatusServiceUnavailable}
+		}
+		writeJSONError(w, codes[rand.Intn(len(codes))], "FAULT_INJECTED", "fault injector simulated failure")
+	})
+}
@@ -2,31 +2,72 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/crypto/acme/autocert"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/status"
 	// Fallback to native HTTP handlers if protobuf stubs are not present
+
+	"github.com/quantarax/backend/daemon/api/server/authn"
+	"github.com/quantarax/backend/internal/quicutil"
 )
 
 // StartAPIServers starts the gRPC server, HTTP gateway, and an SSE endpoint.
 // grpcAddr: address for gRPC (e.g., 127.0.0.1:9090)
 // restAddr: address for REST (e.g., 127.0.0.1:8080)
-func StartAPIServers(ctx context.Context, grpcAddr, restAddr string, impl *DaemonAPIServer) (grpcStop func(), restStop func(), err error) {
+// The returned *health.Server is the grpc.health.v1.Health service
+// registered on the gRPC server; wrap it in a ComponentHealthBridge to keep
+// it in sync with a observability.HealthChecker's HTTP /health body.
+func StartAPIServers(ctx context.Context, grpcAddr, restAddr string, impl *DaemonAPIServer) (grpcStop func(), restStop func(), healthSrv *health.Server, err error) {
+	return StartAPIServersWithAuth(ctx, grpcAddr, restAddr, impl, buildAuthChainFromEnv())
+}
+
+// StartAPIServersWithAuth is StartAPIServers with an explicit authn.Chain
+// instead of the one buildAuthChainFromEnv derives from
+// QUANTARAX_AUTH_TOKEN/QUANTARAX_AUTH_HMAC_KEY/QUANTARAX_AUTH_OIDC_*, for a
+// caller (daemon/main.go) that builds its chain from daemon/config.Config
+// instead. An empty chain disables auth on both the gRPC and REST/gateway
+// listeners, same as StartAPIServers' old behavior when
+// QUANTARAX_AUTH_TOKEN was unset.
+func StartAPIServersWithAuth(ctx context.Context, grpcAddr, restAddr string, impl *DaemonAPIServer, chain authn.Chain) (grpcStop func(), restStop func(), healthSrv *health.Server, err error) {
+	return StartAPIServersWithListeners(ctx, grpcAddr, restAddr, impl, chain, nil, nil)
+}
+
+// StartAPIServersWithListeners is StartAPIServersWithAuth, but adopts
+// grpcListener/restListener instead of binding grpcAddr/restAddr itself
+// when they're non-nil - the socket-activation path daemon/main.go takes
+// when systemd.ListenFDs hands it pre-bound sockets named "grpc"/"rest",
+// so a restart under systemd doesn't drop connections queued on those
+// sockets the way an unbind-then-rebind would. grpcAddr/restAddr are still
+// used for logging and the grpc-gateway's own dial-back to grpcAddr.
+func StartAPIServersWithListeners(ctx context.Context, grpcAddr, restAddr string, impl *DaemonAPIServer, chain authn.Chain, grpcListener, restListener net.Listener) (grpcStop func(), restStop func(), healthSrv *health.Server, err error) {
 	// Start gRPC server
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(authn.UnaryServerInterceptor(chain)),
+		grpc.StreamInterceptor(authn.StreamServerInterceptor(chain)),
+	)
 	// Attempt to register gRPC service if generated stubs exist; otherwise skip
 	// RegisterGRPC is a no-op in native HTTP mode
 	RegisterGRPC(grpcServer, impl)
-	l, err := net.Listen("tcp", grpcAddr)
-	if err != nil {
-		return nil, nil, err
+	healthSrv = newHealthServer()
+	registerHealth(grpcServer, healthSrv)
+	l := grpcListener
+	if l == nil {
+		l, err = net.Listen("tcp", grpcAddr)
+		if err != nil {
+			return nil, nil, healthSrv, err
+		}
 	}
 	go func() { _ = grpcServer.Serve(l) }()
 	grpcStop = func() { grpcServer.GracefulStop(); _ = l.Close() }
@@ -47,22 +88,112 @@ func StartAPIServers(ctx context.Context, grpcAddr, restAddr string, impl *Daemo
 	root := http.NewServeMux()
 	root.Handle("/api/v1/events", SSEHandler(impl.events))
 	root.Handle("/", gwMux)
-	// Optional auth: enforce X-Auth-Token if QUANTARAX_AUTH_TOKEN is set
-	authToken := os.Getenv("QUANTARAX_AUTH_TOKEN")
-	var handler http.Handler = root
-	if authToken != "" {
-		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.Header.Get("X-Auth-Token") != authToken {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-			root.ServeHTTP(w, r)
-		})
-	}
+	// Wrap every route with the configurable fault injector so integration
+	// tests can exercise client-side retry/backoff without OS-level chaos,
+	// then with chain's authentication (a no-op wrapper when chain is
+	// empty, the same auth-disabled-by-default posture the old
+	// X-Auth-Token check had).
+	handler := impl.faultInjector.Middleware(root)
+	handler = authn.HTTPMiddleware(chain)(handler)
 	server := &http.Server{Addr: restAddr, Handler: handler}
-	go func() { _ = server.ListenAndServe() }()
+	if restListener != nil {
+		go func() { _ = server.Serve(restListener) }()
+	} else {
+		go func() { _ = server.ListenAndServe() }()
+	}
 	restStop = func() { _ = server.Close() }
-	return grpcStop, restStop, nil
+
+	// Optionally serve the same handler over HTTP/3 (QUIC). Disabled unless
+	// QUANTARAX_QUIC_GATEWAY_ADDR is set, since it requires a real or
+	// autocert-provisioned TLS certificate.
+	if quicAddr := os.Getenv("QUANTARAX_QUIC_GATEWAY_ADDR"); quicAddr != "" {
+		quicStop, err := startQUICGateway(quicAddr, handler)
+		if err != nil {
+			return grpcStop, restStop, healthSrv, fmt.Errorf("failed to start QUIC gateway: %w", err)
+		}
+		prevStop := restStop
+		restStop = func() { prevStop(); quicStop() }
+	}
+
+	return grpcStop, restStop, healthSrv, nil
+}
+
+// buildAuthChainFromEnv builds the authn.Chain StartAPIServers uses from
+// environment variables, for a caller that hasn't migrated to
+// StartAPIServersWithAuth/daemon/config.Config.AuthConfig yet:
+//   - QUANTARAX_AUTH_TOKEN: enables StaticTokenAuthenticator on X-Auth-Token,
+//     reproducing the original single-token check.
+//   - QUANTARAX_AUTH_HMAC_KEY: enables HMACBearerAuthenticator.
+//   - QUANTARAX_AUTH_OIDC_JWKS_URL (+ _ISSUER/_AUDIENCE): enables
+//     OIDCAuthenticator.
+//
+// Every set method is tried in that order; an empty chain (nothing set)
+// disables auth entirely, matching the pre-authn.Chain default.
+func buildAuthChainFromEnv() authn.Chain {
+	var chain authn.Chain
+	if token := os.Getenv("QUANTARAX_AUTH_TOKEN"); token != "" {
+		chain = append(chain, &authn.StaticTokenAuthenticator{Token: token})
+	}
+	if key := os.Getenv("QUANTARAX_AUTH_HMAC_KEY"); key != "" {
+		chain = append(chain, &authn.HMACBearerAuthenticator{Key: []byte(key)})
+	}
+	if jwksURL := os.Getenv("QUANTARAX_AUTH_OIDC_JWKS_URL"); jwksURL != "" {
+		chain = append(chain, &authn.OIDCAuthenticator{
+			JWKSURL:  jwksURL,
+			Issuer:   os.Getenv("QUANTARAX_AUTH_OIDC_ISSUER"),
+			Audience: os.Getenv("QUANTARAX_AUTH_OIDC_AUDIENCE"),
+		})
+	}
+	return chain
+}
+
+// startQUICGateway serves handler over HTTP/3 on addr. If
+// QUANTARAX_AUTOCERT_DOMAIN is set, certificates are obtained and renewed
+// automatically via ACME (Let's Encrypt); otherwise a self-signed
+// development certificate is generated, matching the non-QUIC listener's
+// development fallback.
+func startQUICGateway(addr string, handler http.Handler) (stop func(), err error) {
+	tlsConfig, err := gatewayTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	h3 := &http3.Server{
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}
+	go func() { _ = h3.ListenAndServe() }()
+	return func() { _ = h3.Close() }, nil
+}
+
+// gatewayTLSConfig builds the TLS config used by the HTTP/3 gateway listener.
+func gatewayTLSConfig() (*tls.Config, error) {
+	if domain := os.Getenv("QUANTARAX_AUTOCERT_DOMAIN"); domain != "" {
+		cacheDir := os.Getenv("QUANTARAX_AUTOCERT_CACHE_DIR")
+		if cacheDir == "" {
+			cacheDir = "autocert-cache"
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(domain),
+			Cache:      autocert.DirCache(cacheDir),
+		}
+		tlsConfig := m.TLSConfig()
+		tlsConfig.NextProtos = append([]string{http3.NextProtoH3}, tlsConfig.NextProtos...)
+		return tlsConfig, nil
+	}
+
+	certPEM, keyPEM, err := quicutil.GenerateSelfSignedCert()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate development certificate: %w", err)
+	}
+	tlsConfig, err := quicutil.MakeTLSConfig(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.NextProtos = []string{http3.NextProtoH3}
+	return tlsConfig, nil
 }
 
 // JSONErrorHandler converts gateway errors to a normalized JSON model
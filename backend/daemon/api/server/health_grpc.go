@@ -0,0 +1,72 @@
+package server
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/quantarax/backend/internal/observability"
+)
+
+// Health service names reported by the daemon's grpc.health.v1.Health
+// service, one per subsystem a ComponentHealthBridge can flip independently
+// via SetServing. The empty-string service name (HealthServiceOverall) is
+// the blanket status a plain Check("") or Watch("") call sees, same as the
+// top-level "status" field in the HTTP /health JSON body.
+const (
+	HealthServiceOverall      = ""
+	HealthServiceDaemonAPI    = "quantarax.daemon.api"
+	HealthServiceQUICTransfer = "quantarax.daemon.quic"
+	HealthServiceBitmapStore  = "quantarax.daemon.bitmapstore"
+)
+
+// newHealthServer creates the grpc.health.v1.Health implementation
+// StartAPIServers registers on the gRPC server. The overall status and the
+// daemon API itself are marked SERVING as soon as the gRPC server starts
+// accepting connections; QUIC and the bitmap store are left for main.go to
+// report via a ComponentHealthBridge once their own state is known.
+func newHealthServer() *health.Server {
+	h := health.NewServer()
+	h.SetServingStatus(HealthServiceOverall, healthpb.HealthCheckResponse_SERVING)
+	h.SetServingStatus(HealthServiceDaemonAPI, healthpb.HealthCheckResponse_SERVING)
+	return h
+}
+
+// ComponentHealthBridge keeps the gRPC health.v1.Health service and the HTTP
+// /health JSON body (observability.HealthChecker) in sync, so a component
+// flips one status and both surfaces agree immediately rather than only the
+// next time something polls it. It's the wiring daemon/main.go uses to
+// report the QUIC listener and bitmap store's live status, the same
+// decoupled-hook pattern AdminController lets main.go plug process
+// lifecycle into without DaemonAPIServer owning it.
+type ComponentHealthBridge struct {
+	checker *observability.HealthChecker
+	grpc    *health.Server
+}
+
+// NewComponentHealthBridge wires checker and grpcHealth together. Either may
+// be nil, in which case SetServing updates only the non-nil side.
+func NewComponentHealthBridge(checker *observability.HealthChecker, grpcHealth *health.Server) *ComponentHealthBridge {
+	return &ComponentHealthBridge{checker: checker, grpc: grpcHealth}
+}
+
+// SetServing reports status for both grpcService (a HealthService* constant)
+// and checkName (the key it appears under in the HTTP /health JSON body's
+// "checks" map).
+func (b *ComponentHealthBridge) SetServing(grpcService, checkName string, status observability.HealthStatus, message string) {
+	if b.checker != nil {
+		b.checker.SetStatus(checkName, observability.ComponentHealth{Status: status, Message: message})
+	}
+	if b.grpc != nil {
+		servingStatus := healthpb.HealthCheckResponse_NOT_SERVING
+		if status == observability.HealthStatusOK {
+			servingStatus = healthpb.HealthCheckResponse_SERVING
+		}
+		b.grpc.SetServingStatus(grpcService, servingStatus)
+	}
+}
+
+// registerHealth registers h with s under grpc.health.v1.Health.
+func registerHealth(s *grpc.Server, h *health.Server) {
+	healthpb.RegisterHealthServer(s, h)
+}
@@ -0,0 +1,32 @@
+package server
+
+// SessionResumer exposes session.SessionManager.ResumeSession and
+// AcquireLease to handleTransferPrefix's "resume" action without
+// daemon/api/server importing daemon/session directly, the same
+// decoupling AdminController uses to keep process lifecycle out of this
+// package.
+type SessionResumer interface {
+	ResumeSession(sessionID string) (missing []int64, totalChunks int64, err error)
+
+	// AcquireLease takes out a refreshable manager.LeaseStore lease on
+	// sessionID so the resume computation below can't race a concurrent
+	// handleConnection for the same session. The returned cancel func
+	// must always be invoked - see session.SessionManager.AcquireLease.
+	AcquireLease(sessionID, holder string) (cancel func(), err error)
+}
+
+// ResumeSessionResponse is the HTTP contract for GET
+// /api/v1/transfer/{session_id}/resume: the chunk indices a resuming
+// sender still needs to (re)send, in place of requiring
+// --chunk-index/--offset on the command line.
+type ResumeSessionResponse struct {
+	MissingChunks []int64 `json:"missing_chunks"`
+	TotalChunks   int64   `json:"total_chunks"`
+}
+
+// SetSessionResumer wires resumer into GET
+// /api/v1/transfer/{session_id}/resume. Left unset (nil), the endpoint
+// answers 501 Not Implemented.
+func (s *DaemonAPIServer) SetSessionResumer(resumer SessionResumer) {
+	s.resumer = resumer
+}
@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/quantarax/backend/daemon/manager"
 	"github.com/quantarax/backend/daemon/service"
 	"github.com/quantarax/backend/internal/chunker"
@@ -20,6 +22,26 @@ type (
 		RecipientID       string            `json:"recipient_id"`
 		ChunkSizeOverride int64             `json:"chunk_size_override"`
 		Metadata          map[string]string `json:"metadata"`
+		FecScheme         *FecSchemeRequest `json:"fec_scheme,omitempty"`
+		RetryPolicy       *RetryPolicyRequest `json:"retry_policy,omitempty"`
+	}
+	// FecSchemeRequest opts a transfer into Reed-Solomon erasure coding: k
+	// data chunks plus m parity chunks per stripe of stripe_chunks (k when
+	// omitted) consecutive chunks.
+	FecSchemeRequest struct {
+		K            int `json:"k"`
+		M            int `json:"m"`
+		StripeChunks int `json:"stripe_chunks,omitempty"`
+	}
+	// RetryPolicyRequest mirrors service.RetryPolicy for the HTTP contract,
+	// tuning how the transfer service retries a transient control-plane
+	// failure while creating or accepting a transfer.
+	RetryPolicyRequest struct {
+		InitialDelayMs int64   `json:"initial_delay_ms"`
+		Multiplier     float64 `json:"multiplier"`
+		MaxDelayMs     int64   `json:"max_delay_ms"`
+		MaxAttempts    int     `json:"max_attempts"`
+		JitterMs       int64   `json:"jitter_ms"`
 	}
 	CreateTransferResponse struct {
 		SessionID         string        `json:"session_id"`
@@ -30,9 +52,10 @@ type (
 	}
 
 	AcceptTransferRequest struct {
-		TransferToken   string `json:"transfer_token"`
-		OutputPath      string `json:"output_path"`
-		ResumeSessionID string `json:"resume_session_id"`
+		TransferToken   string               `json:"transfer_token"`
+		OutputPath      string               `json:"output_path"`
+		ResumeSessionID string               `json:"resume_session_id"`
+		RetryPolicy     *RetryPolicyRequest  `json:"retry_policy,omitempty"`
 	}
 	AcceptTransferResponse struct {
 		SessionID       string        `json:"session_id"`
@@ -53,7 +76,11 @@ type (
 		RttMs                  float64 `json:"rtt_ms,omitempty"`
 		Streams                int     `json:"streams,omitempty"`
 		LossRatePct            float64 `json:"loss_rate_pct,omitempty"`
+		RawBytesSent           int64   `json:"raw_bytes_sent,omitempty"`
+		RawBytesRecv           int64   `json:"raw_bytes_recv,omitempty"`
+		RateHistogram          manager.RateHistogram `json:"rate_histogram,omitempty"`
 		ErrorMessage           string  `json:"error_message,omitempty"`
+		Metadata               map[string]string `json:"metadata,omitempty"`
 	}
 
 	TransferSummary struct {
@@ -85,25 +112,69 @@ type (
 	}
 
 	ManifestJSON struct {
-		FileName    string   `json:"file_name"`
-		FileSize    int64    `json:"file_size"`
-		ChunkSize   int64    `json:"chunk_size"`
-		TotalChunks int64    `json:"total_chunks"`
-		MerkleRoot  string   `json:"merkle_root"`
-		ChunkHashes []string `json:"chunk_hashes,omitempty"`
+		FileName    string          `json:"file_name"`
+		FileSize    int64           `json:"file_size"`
+		ChunkSize   int64           `json:"chunk_size"`
+		TotalChunks int64           `json:"total_chunks"`
+		MerkleRoot  string          `json:"merkle_root"`
+		ChunkHashes []string        `json:"chunk_hashes,omitempty"`
+		FecStripes  []FecStripeJSON `json:"fec_stripes,omitempty"`
+	}
+	// FecStripeJSON mirrors chunker.FecStripe for the HTTP contract.
+	FecStripeJSON struct {
+		StripeID string `json:"stripe_id"`
+		Start    int    `json:"start"`
+		K        int    `json:"k"`
+		M        int    `json:"m"`
+	}
+
+	RegisterWebhookRequest struct {
+		URL             string   `json:"url"`
+		Secret          string   `json:"secret"`
+		EventTypes      []string `json:"event_types"`
+		SessionIDFilter string   `json:"session_id_filter"`
+		AuthToken       string   `json:"auth_token"`
+	}
+	RegisterWebhookResponse struct {
+		ID string `json:"id"`
+	}
+	WebhookStatusResponse struct {
+		ID               string `json:"id"`
+		URL              string `json:"url"`
+		DeliveredCount   int64  `json:"delivered_count"`
+		FailedCount      int64  `json:"failed_count"`
+		DeadLetterCount  int64  `json:"dead_letter_count"`
+		LastError        string `json:"last_error,omitempty"`
+		LastDeliveryTime int64  `json:"last_delivery_time,omitempty"`
+	}
+
+	// AdminFaultRequest configures the HTTPFaultInjector mounted in front of
+	// every route this server registers, for integration tests to exercise
+	// client-side recovery paths without OS-level network chaos.
+	AdminFaultRequest struct {
+		Enabled      bool  `json:"enabled"`
+		Probability  float64 `json:"probability"`
+		StatusCodes  []int   `json:"status_codes"`
+		LatencyMinMs int64   `json:"latency_min_ms"`
+		LatencyMaxMs int64   `json:"latency_max_ms"`
+		PathFilter   string  `json:"path_filter"`
 	}
 )
 
 // DaemonAPIServer wires services to HTTP handlers
 
 type DaemonAPIServer struct {
-	transfer *service.TransferService
-	sessions *manager.SessionStore
-	events   *service.EventPublisher
+	transfer      *service.TransferService
+	sessions      *manager.SessionStore
+	events        *service.EventPublisher
+	webhooks      *manager.WebhookStore
+	faultInjector *HTTPFaultInjector
+	admin         AdminController
+	resumer       SessionResumer
 }
 
-func NewDaemonAPIServer(ts *service.TransferService, store *manager.SessionStore, events *service.EventPublisher) *DaemonAPIServer {
-	return &DaemonAPIServer{transfer: ts, sessions: store, events: events}
+func NewDaemonAPIServer(ts *service.TransferService, store *manager.SessionStore, events *service.EventPublisher, webhooks *manager.WebhookStore) *DaemonAPIServer {
+	return &DaemonAPIServer{transfer: ts, sessions: store, events: events, webhooks: webhooks, faultInjector: NewHTTPFaultInjector()}
 }
 
 // RegisterHTTP registers REST routes on mux
@@ -113,6 +184,17 @@ func (s *DaemonAPIServer) RegisterHTTP(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/transfer/", s.handleTransferPrefix)
 	mux.HandleFunc("/api/v1/transfers", s.handleListTransfers)
 	mux.HandleFunc("/api/v1/keys", s.handleGetKeys)
+	mux.HandleFunc("/api/v1/webhooks", s.handleRegisterWebhook)
+	mux.HandleFunc("/api/v1/webhooks/", s.handleWebhookPrefix)
+	mux.HandleFunc("/api/v1/admin/fault", s.handleAdminFault)
+	mux.HandleFunc("/api/v1/admin/restart", s.handleAdminRestart)
+	mux.HandleFunc("/api/v1/admin/reload", s.handleAdminReload)
+}
+
+// FaultInjector returns the fault injector this server's routes are wrapped
+// with, e.g. for gateway.go to mount its Middleware around the full mux.
+func (s *DaemonAPIServer) FaultInjector() *HTTPFaultInjector {
+	return s.faultInjector
 }
 
 func (s *DaemonAPIServer) handleCreateTransfer(w http.ResponseWriter, r *http.Request) {
@@ -125,7 +207,11 @@ func (s *DaemonAPIServer) handleCreateTransfer(w http.ResponseWriter, r *http.Re
 		writeJSONError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "invalid JSON body")
 		return
 	}
-	sessionID, token, manifest, err := s.transfer.CreateTransfer(req.FilePath, req.RecipientID, req.ChunkSizeOverride, req.Metadata)
+	var fecScheme *chunker.FecScheme
+	if req.FecScheme != nil {
+		fecScheme = &chunker.FecScheme{K: req.FecScheme.K, M: req.FecScheme.M, StripeChunks: req.FecScheme.StripeChunks}
+	}
+	sessionID, token, manifest, err := s.transfer.CreateTransfer(req.FilePath, req.RecipientID, req.ChunkSizeOverride, req.Metadata, fecScheme, toRetryPolicy(req.RetryPolicy))
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
 		return
@@ -153,7 +239,7 @@ func (s *DaemonAPIServer) handleAcceptTransfer(w http.ResponseWriter, r *http.Re
 		writeJSONError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "invalid JSON body")
 		return
 	}
-	sid, manifest, err := s.transfer.AcceptTransfer(req.TransferToken, req.OutputPath, req.ResumeSessionID)
+	sid, manifest, err := s.transfer.AcceptTransfer(req.TransferToken, req.OutputPath, req.ResumeSessionID, toRetryPolicy(req.RetryPolicy))
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
 		return
@@ -206,10 +292,39 @@ func (s *DaemonAPIServer) handleTransferPrefix(w http.ResponseWriter, r *http.Re
 					resp.LossRatePct = f
 				}
 			}
+			resp.RawBytesSent, resp.RawBytesRecv = sess.GetRawBandwidth()
+			resp.RateHistogram = sess.GetRateHistogram()
+		}
+		resp.Metadata = map[string]string{
+			"fault_injector_hits": strconv.FormatInt(s.faultInjector.Hits(), 10),
 		}
 		writeJSON(w, http.StatusOK, resp)
 		return
 	}
+	if action == "resume" {
+		if s.resumer == nil {
+			writeJSONError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "session resumer not configured")
+			return
+		}
+		cancel, err := s.resumer.AcquireLease(sessionID, r.RemoteAddr)
+		if err != nil {
+			writeJSONError(w, http.StatusConflict, "LEASE_HELD", err.Error())
+			return
+		}
+		defer cancel()
+
+		missing, total, err := s.resumer.ResumeSession(sessionID)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, "NOT_FOUND", err.Error())
+			return
+		}
+		if sess, sErr := s.sessions.Get(sessionID); sErr == nil && sess.GetState() == manager.StateResuming {
+			_ = sess.TransitionTo(manager.StateActive, "")
+		}
+		writeJSON(w, http.StatusOK, &ResumeSessionResponse{MissingChunks: missing, TotalChunks: total})
+		return
+	}
+	http.NotFound(w, r)
 }
 
 func (s *DaemonAPIServer) handleListTransfers(w http.ResponseWriter, r *http.Request) {
@@ -242,6 +357,104 @@ func (s *DaemonAPIServer) handleGetKeys(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, http.StatusOK, &GetKeysResponse{PublicKeyBase64: pub, Fingerprint: fp})
 }
 
+// handleRegisterWebhook registers a new outbound webhook subscriber, to be
+// delivered by service.WebhookDispatcher alongside the existing SSE stream.
+func (s *DaemonAPIServer) handleRegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req RegisterWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "invalid JSON body")
+		return
+	}
+	if req.URL == "" || req.Secret == "" {
+		writeJSONError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "url and secret are required")
+		return
+	}
+	sub := &manager.WebhookSubscriber{
+		ID:              uuid.New().String(),
+		URL:             req.URL,
+		Secret:          req.Secret,
+		EventTypes:      req.EventTypes,
+		SessionIDFilter: req.SessionIDFilter,
+		AuthToken:       req.AuthToken,
+		CreateTime:      time.Now(),
+	}
+	if err := s.webhooks.Add(sub); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, &RegisterWebhookResponse{ID: sub.ID})
+}
+
+// handleWebhookPrefix serves /api/v1/webhooks/{id}/status.
+func (s *DaemonAPIServer) handleWebhookPrefix(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/webhooks/"), "/")
+	webhookID := parts[0]
+	if len(parts) < 2 || parts[1] != "status" {
+		http.NotFound(w, r)
+		return
+	}
+	sub, err := s.webhooks.Get(webhookID)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, "NOT_FOUND", err.Error())
+		return
+	}
+	status := sub.Status()
+	resp := &WebhookStatusResponse{
+		ID:              status.ID,
+		URL:             status.URL,
+		DeliveredCount:  status.DeliveredCount,
+		FailedCount:     status.FailedCount,
+		DeadLetterCount: status.DeadLetterCount,
+		LastError:       status.LastError,
+	}
+	if !status.LastDeliveryTime.IsZero() {
+		resp.LastDeliveryTime = status.LastDeliveryTime.UnixMilli()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleAdminFault (re)configures this server's HTTPFaultInjector. Posting
+// {"enabled": false} (the zero value for everything else) turns it back off.
+func (s *DaemonAPIServer) handleAdminFault(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req AdminFaultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "invalid JSON body")
+		return
+	}
+	s.faultInjector.Configure(HTTPFaultConfig{
+		Enabled:     req.Enabled,
+		Probability: req.Probability,
+		StatusCodes: req.StatusCodes,
+		LatencyMin:  time.Duration(req.LatencyMinMs) * time.Millisecond,
+		LatencyMax:  time.Duration(req.LatencyMaxMs) * time.Millisecond,
+		PathFilter:  req.PathFilter,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// toRetryPolicy converts the HTTP contract's RetryPolicyRequest to
+// service.RetryPolicy, returning nil (no retries) when req is nil.
+func toRetryPolicy(req *RetryPolicyRequest) *service.RetryPolicy {
+	if req == nil {
+		return nil
+	}
+	return &service.RetryPolicy{
+		InitialDelay: time.Duration(req.InitialDelayMs) * time.Millisecond,
+		Multiplier:   req.Multiplier,
+		MaxDelay:     time.Duration(req.MaxDelayMs) * time.Millisecond,
+		MaxAttempts:  req.MaxAttempts,
+		Jitter:       time.Duration(req.JitterMs) * time.Millisecond,
+	}
+}
+
 // SSE handler remains for event streaming
 func SSEHandler(events *service.EventPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -253,8 +466,8 @@ func SSEHandler(events *service.EventPublisher) http.HandlerFunc {
 			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
 			return
 		}
-		filter := r.URL.Query().Get("session_id")
-		sub := events.Subscribe(filter)
+		sessionID := r.URL.Query().Get("session_id")
+		sub := events.Subscribe(service.EventFilter{SessionID: sessionID})
 		defer events.Unsubscribe(sub.ID)
 		ctx := r.Context()
 		for {
@@ -325,6 +538,9 @@ func toHTTPManifest(m *chunker.Manifest) *ManifestJSON {
 		TotalChunks: int64(m.ChunkCount),
 		MerkleRoot:  base64.StdEncoding.EncodeToString([]byte(m.MerkleRoot)),
 	}
+	for _, stripe := range m.FecStripes {
+		pm.FecStripes = append(pm.FecStripes, FecStripeJSON{StripeID: stripe.StripeID, Start: stripe.Start, K: stripe.K, M: stripe.M})
+	}
 	// Optional: include chunk hashes
 	// for _, ch := range m.Chunks { pm.ChunkHashes = append(pm.ChunkHashes, ch.Hash) }
 	return pm
@@ -342,6 +558,8 @@ func toHTTPState(s manager.TransferState) string {
 		return "COMPLETED"
 	case manager.StateFailed:
 		return "FAILED"
+	case manager.StateResuming:
+		return "RESUMING"
 	default:
 		return "UNSPECIFIED"
 	}
@@ -360,6 +578,8 @@ func fromHTTPState(s string) manager.TransferState {
 		return manager.StateCompleted
 	case "FAILED":
 		return manager.StateFailed
+	case "RESUMING":
+		return manager.StateResuming
 	default:
 		return manager.StatePending
 	}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 )
@@ -17,6 +18,79 @@ type Config struct {
 	EventBufferSize        int
 	WorkerCount            int
 	QueueDepth             int
+
+	// BackoffInitialMs, BackoffMaxMs, BackoffMultiplier, BackoffJitter, and
+	// BackoffMaxAttempts are the scalar fields of a transport.BackoffPolicy
+	// (kept as plain types here the same way ChunkSize/TokenTTL are, rather
+	// than importing daemon/transport into this package), used as the
+	// daemon-wide default for DTN retry backoff where a domain profile
+	// doesn't set its own.
+	BackoffInitialMs   int64
+	BackoffMaxMs       int64
+	BackoffMultiplier  float64
+	BackoffJitter      float64
+	BackoffMaxAttempts int
+
+	// FECMinR and FECMaxR are the daemon-wide default FEC parity-shard
+	// bounds (transport.FECController.SetBounds takes the equivalent
+	// per-transfer pair); a live transfer's own FECController isn't
+	// re-bounded when these change, the same limitation BackoffInitialMs
+	// and friends have against an in-flight DTNWorker.
+	FECMinR int
+	FECMaxR int
+
+	// AdminPubKeys lists the ed25519 public keys (raw 32-byte form)
+	// authorized to send a signed transport.AdminCommand over a transfer's
+	// control stream (see transport.VerifyAdminCommandSignature and
+	// IsAdminKey). Empty means no key is trusted, so the admin
+	// control-stream channel is disabled by default.
+	AdminPubKeys [][]byte
+
+	// Auth configures the daemon's API authentication chain (see
+	// daemon/api/server/authn). Each non-empty field enables one more
+	// Authenticator in the chain, tried in the same static/HMAC/OIDC/mTLS
+	// order daemon/main.go builds them in; every field left at its zero
+	// value leaves the chain empty, which disables auth entirely (the same
+	// default StartAPIServers always had before this field existed).
+	Auth AuthConfig
+}
+
+// AuthConfig holds daemon flags/env for the API auth chain.
+type AuthConfig struct {
+	// StaticToken, if set, enables authn.StaticTokenAuthenticator on the
+	// X-Auth-Token header — the original single-token check.
+	StaticToken string
+
+	// HMACKey, if set, enables authn.HMACBearerAuthenticator on bearer
+	// tokens minted with authn.HMACToken.
+	HMACKey string
+
+	// OIDCJWKSURL, if set, enables authn.OIDCAuthenticator against that
+	// JWKS endpoint, optionally constrained to OIDCIssuer/OIDCAudience.
+	OIDCJWKSURL  string
+	OIDCIssuer   string
+	OIDCAudience string
+
+	// MTLSEnabled, if true, enables authn.MTLSAuthenticator, optionally
+	// restricted to certificates whose Organizational Unit is in
+	// MTLSAllowedOUs. Only takes effect on a listener that actually
+	// requests/verifies client certificates.
+	MTLSEnabled    bool
+	MTLSAllowedOUs []string
+}
+
+// IsAdminKey reports whether pubKey matches one of c's configured admin
+// public keys. Callers must already have verified pubKey actually signed
+// the message it came with (see transport.VerifyAdminCommandSignature) —
+// this only answers whether that key is trusted, not whether it signed
+// anything.
+func (c *Config) IsAdminKey(pubKey []byte) bool {
+	for _, k := range c.AdminPubKeys {
+		if bytes.Equal(k, pubKey) {
+			return true
+		}
+	}
+	return false
 }
 
 // DefaultConfig returns default configuration
@@ -35,6 +109,11 @@ func DefaultConfig() *Config {
 		EventBufferSize:        100,
 		WorkerCount:            8,
 		QueueDepth:             32,
+		BackoffInitialMs:       500,
+		BackoffMaxMs:           30000,
+		BackoffMultiplier:      2.0,
+		BackoffJitter:          0.2,
+		BackoffMaxAttempts:     10,
 	}
 }
 
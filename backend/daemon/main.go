@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/quantarax/backend/internal/chunker"
 	"github.com/quantarax/backend/internal/crypto"
@@ -12,17 +14,25 @@ import (
 	"net/http/pprof"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/quantarax/backend/daemon/api/server"
+	"github.com/quantarax/backend/daemon/api/server/authn"
 	"github.com/quantarax/backend/daemon/config"
 	"github.com/quantarax/backend/daemon/manager"
+	"github.com/quantarax/backend/daemon/manager/migrations"
 	"github.com/quantarax/backend/daemon/service"
+	"github.com/quantarax/backend/daemon/session"
+	"github.com/quantarax/backend/daemon/systemd"
 	"github.com/quantarax/backend/daemon/transport"
 	"github.com/quantarax/backend/internal/observability"
 	"github.com/quantarax/backend/internal/quicutil"
 	"github.com/quantarax/backend/internal/ratelimit"
+	_ "modernc.org/sqlite"
 )
 
 func main() {
@@ -32,6 +42,27 @@ func main() {
 	quicAddr := flag.String("quic-addr", ":4433", "QUIC listener address")
 	observAddr := flag.String("observ-addr", "127.0.0.1:8081", "Observability server address")
 	mode := flag.String("mode", "", "Run mode (e.g., test)")
+	authStaticToken := flag.String("auth-static-token", os.Getenv("QUANTARAX_AUTH_TOKEN"), "Static bearer token required on X-Auth-Token (disabled if empty)")
+	authHMACKey := flag.String("auth-hmac-key", os.Getenv("QUANTARAX_AUTH_HMAC_KEY"), "HMAC key for bearer tokens minted with authn.HMACToken (disabled if empty)")
+	authOIDCJWKSURL := flag.String("auth-oidc-jwks-url", os.Getenv("QUANTARAX_AUTH_OIDC_JWKS_URL"), "JWKS URL for OIDC bearer token verification (disabled if empty)")
+	authOIDCIssuer := flag.String("auth-oidc-issuer", os.Getenv("QUANTARAX_AUTH_OIDC_ISSUER"), "Required issuer for OIDC bearer tokens")
+	authOIDCAudience := flag.String("auth-oidc-audience", os.Getenv("QUANTARAX_AUTH_OIDC_AUDIENCE"), "Required audience for OIDC bearer tokens")
+	authMTLSEnabled := flag.Bool("auth-mtls-enabled", os.Getenv("QUANTARAX_AUTH_MTLS_ENABLED") == "true", "Authenticate requests by client certificate (requires a listener that verifies client certs)")
+	authMTLSAllowedOUs := flag.String("auth-mtls-allowed-ous", os.Getenv("QUANTARAX_AUTH_MTLS_ALLOWED_OUS"), "Comma-separated client certificate Organizational Units allowed by auth-mtls-enabled (empty allows any)")
+	defaultBitmapDBPath := ""
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		defaultBitmapDBPath = filepath.Join(homeDir, ".local", "share", "quantarax", "bitmaps.db")
+	}
+	bitmapDBPath := flag.String("bitmap-db-path", defaultBitmapDBPath, "SQLite database for resumable transfer chunk bitmaps (manager.BitmapStore)")
+	defaultLeaseDBPath := ""
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		defaultLeaseDBPath = filepath.Join(homeDir, ".local", "share", "quantarax", "leases.db")
+	}
+	leaseDBPath := flag.String("lease-db-path", defaultLeaseDBPath, "BoltDB database for refreshable session leases (manager.LeaseStore)")
+	generateUnit := flag.String("generate-unit", "", "Print a hardened systemd unit for the current config and exit: 'service', 'sockets', or 'all'")
+	systemdDynamicUser := flag.Bool("systemd-dynamic-user", false, "Have -generate-unit emit DynamicUser=yes instead of a fixed User=/Group=quantarax")
+	systemdSockets := flag.Bool("systemd-sockets", false, "Have -generate-unit's service unit Requires=/expect quantarax-daemon.socket instead of binding its own addresses at startup")
+	dtnRelays := flag.String("dtn-relays", os.Getenv("QUANTARAX_DTN_RELAYS"), "Comma-separated relay base URLs (e.g. https://relay.example.com:8082) SendWithOrchestration's DTN bundle-mode fallback forwards spooled bundles to; empty disables bundle-mode entirely even when a transfer's DTNProfile is set")
 	flag.Parse()
 
 	// Initialize observability
@@ -42,6 +73,12 @@ func main() {
 	service.StartCASGCLoop(24*time.Hour, 1*time.Hour)
 	// Initialize DTN queue/worker
 	_ = service.InitDTN("/tmp/quantarax_dtn.db")
+	// Initialize DTN bundle-mode fallback's spool/forwarder; harmless to
+	// start with an empty relay list, it'll just never have anywhere to
+	// forward a spooled bundle to.
+	if *dtnRelays != "" {
+		_ = service.InitDTNBundles("/tmp/quantarax_dtn_bundles.db", strings.Split(*dtnRelays, ","))
+	}
 	metrics := observability.NewMetrics()
 	healthChecker := observability.NewHealthChecker("1.0.0")
 	// Init tracing if configured
@@ -60,11 +97,32 @@ func main() {
 	cfg.GRPCAddress = *grpcAddr
 	cfg.RESTAddress = *restAddr
 	cfg.QUICAddress = *quicAddr
+	cfg.Auth = config.AuthConfig{
+		StaticToken:    *authStaticToken,
+		HMACKey:        *authHMACKey,
+		OIDCJWKSURL:    *authOIDCJWKSURL,
+		OIDCIssuer:     *authOIDCIssuer,
+		OIDCAudience:   *authOIDCAudience,
+		MTLSEnabled:    *authMTLSEnabled,
+		MTLSAllowedOUs: splitAndTrim(*authMTLSAllowedOUs),
+	}
 	// For test mode, adjust config if needed
 	if *mode == "test" {
 		// Test-specific config
 	}
 
+	// -generate-unit prints unit file(s) derived from the config above and
+	// exits without binding anything, so it can be piped straight to
+	// systemctl/systemd-analyze verify without a running daemon.
+	if *generateUnit != "" {
+		printGeneratedUnits(cfg, *generateUnit, systemd.UnitOptions{
+			KeystoreDir: cfg.KeysDirectory,
+			DynamicUser: *systemdDynamicUser,
+			Sockets:     *systemdSockets,
+		})
+		return
+	}
+
 	logger.Info("Configuration loaded")
 	log.Printf("  QUIC Address: %s", cfg.QUICAddress)
 	log.Printf("  Chunk Size: %d bytes", cfg.ChunkSize)
@@ -73,11 +131,23 @@ func main() {
 	// Initialize session store
 	sessionStore := manager.NewSessionStore()
 	logger.Info("Session store initialized")
+	// Restore resume state from a prior admin-triggered restart, if any.
+	if err := sessionStore.RestoreSnapshot(sessionSnapshotPath); err != nil {
+		logger.Error(err, "Failed to restore session snapshot")
+	}
 
 	// Initialize event publisher
 	eventPublisher := service.NewEventPublisher(cfg.EventBufferSize)
+	eventPublisher.SetSessionStore(sessionStore)
 	log.Printf("Event publisher initialized (buffer size: %d)", cfg.EventBufferSize)
 
+	// Initialize webhook dispatcher: pushes the same TransferEvent stream
+	// SSE clients see to any registered outbound webhook subscribers.
+	webhookStore := manager.NewWebhookStore()
+	webhookDispatcher := service.NewWebhookDispatcher(webhookStore, eventPublisher)
+	webhookDispatcher.Start(context.Background())
+	logger.Info("Webhook dispatcher initialized")
+
 	// Initialize transfer service
 	transferService, err := service.NewTransferService(
 		sessionStore,
@@ -90,6 +160,47 @@ func main() {
 	}
 	logger.Info("Transfer service initialized")
 
+	// Initialize the bitmap database and session/keepalive subsystem: a
+	// SQLite-backed manager.BitmapStore plus the session.SessionManager that
+	// reconciles it with sessionStore on startup, pings active peers, and
+	// prunes bitmaps no session has touched in bitmapTTL.
+	if err := os.MkdirAll(filepath.Dir(*bitmapDBPath), 0o700); err != nil {
+		logger.Fatal(err, "Failed to create bitmap database directory")
+	}
+	bitmapDB, err := sql.Open("sqlite", *bitmapDBPath)
+	if err != nil {
+		logger.Fatal(err, "Failed to open bitmap database")
+	}
+	if err := migrations.Run(bitmapDB, migrations.Registry); err != nil {
+		logger.Fatal(err, "Failed to initialize bitmap database schema")
+	}
+	bitmapStore := manager.NewBitmapStore(bitmapDB)
+
+	// Lease store: gates mutation of a session across restarts. A lease
+	// whose holder died without releasing it is reclaimed at startup, by
+	// sessionManager.Start -> reconcile, and its session rehydrated to
+	// StateResuming rather than left looking falsely StateActive forever.
+	if err := os.MkdirAll(filepath.Dir(*leaseDBPath), 0o700); err != nil {
+		logger.Fatal(err, "Failed to create lease database directory")
+	}
+	leaseStore, err := manager.OpenLeaseStore(*leaseDBPath)
+	if err != nil {
+		logger.Fatal(err, "Failed to open lease database")
+	}
+	defer leaseStore.Close()
+
+	sessionManager := session.NewSessionManager(sessionStore, bitmapStore, leaseStore, session.DefaultConfig(), nil, eventPublisher)
+	if err := sessionManager.Start(context.Background()); err != nil {
+		logger.Fatal(err, "Failed to start session manager")
+	}
+	logger.Info("Session manager initialized (bitmap store at " + *bitmapDBPath + ", lease store at " + *leaseDBPath + ")")
+
+	// Admin controller backs both the REST admin endpoints (below) and the
+	// control-stream AdminCommand channel (handleConnection), so a restart
+	// triggered either way goes through the same snapshot-then-re-exec path.
+	adminController := &daemonAdminController{sessionStore: sessionStore, cfg: cfg, logger: logger}
+	adminHandler := service.NewAdminHandler(cfg, logger, adminController.Restart)
+
 	// Register health checks
 	if *mode != "test" {
 		healthChecker.RegisterCheck("quic_listener", observability.QUICListenerCheck(cfg.QUICAddress))
@@ -109,14 +220,61 @@ func main() {
 		logger.Fatal(err, "Failed to create TLS config")
 	}
 
-	// Start QUIC listener
-	quicListener, err := transport.ListenQUIC(cfg.QUICAddress, tlsConfig)
+	// systemdListenFiles is non-empty only when systemd (or an equivalent
+	// supervisor) socket-activated this process per LISTEN_PID/LISTEN_FDS:
+	// every address below prefers the matching pre-bound fd over binding
+	// its own, so a restart managed by the grpc/rest/quic socket units
+	// GenerateSocketUnits emits doesn't drop connections queued on them.
+	systemdListenFiles, err := systemd.ListenFDs(true)
 	if err != nil {
-		logger.Fatal(err, "Failed to start QUIC listener")
+		logger.Error(err, "Failed to inspect LISTEN_FDS")
+	}
+	if len(systemdListenFiles) > 0 {
+		logger.Info(fmt.Sprintf("Adopted %d socket-activated listener(s) from systemd", len(systemdListenFiles)))
 	}
-	defer quicListener.Close()
 
-	logger.Info("QUIC listener started on " + cfg.QUICAddress)
+	// Start QUIC listener. If systemd handed us a "quic" datagram socket,
+	// or QUANTARAX_LISTEN_FD is set because this process was re-exec'd by
+	// a prior admin-triggered restart (see daemonAdminController.Restart),
+	// rebuild the listener around the inherited socket instead of
+	// rebinding cfg.QUICAddress, so in-flight QUIC connections migrate to
+	// the new process rather than being dropped. listenFile is nil
+	// whenever none of that handoff is in play (first start, or a
+	// platform ListenQUICReusable doesn't support), meaning a future
+	// restart falls back to the old rebind-with-a-gap behavior
+	// daemonAdminController.Restart already documented.
+	var quicListener *transport.QUICListener
+	var listenFile *os.File
+	if f, ok := systemdListenFiles["quic"]; ok {
+		quicListener, listenFile, err = transport.ListenQUICFromInheritedFD(f.Fd(), tlsConfig)
+		if err != nil {
+			logger.Fatal(err, "Failed to rebuild QUIC listener from systemd socket")
+		}
+		logger.Info("QUIC listener adopted from systemd socket activation on " + cfg.QUICAddress)
+	} else if fdStr := os.Getenv(inheritedListenFDEnv); fdStr != "" {
+		fd, perr := strconv.Atoi(fdStr)
+		if perr != nil {
+			logger.Fatal(perr, "Invalid "+inheritedListenFDEnv)
+		}
+		quicListener, listenFile, err = transport.ListenQUICFromInheritedFD(uintptr(fd), tlsConfig)
+		if err != nil {
+			logger.Fatal(err, "Failed to rebuild QUIC listener from inherited fd")
+		}
+		logger.Info("QUIC listener resumed from inherited socket on " + cfg.QUICAddress)
+	} else {
+		quicListener, listenFile, err = transport.ListenQUICReusable(cfg.QUICAddress, tlsConfig)
+		if err != nil {
+			// SO_REUSEPORT handoff unsupported on this platform: fall back
+			// to a plain listener, same as before this capability existed.
+			quicListener, err = transport.ListenQUIC(cfg.QUICAddress, tlsConfig)
+			if err != nil {
+				logger.Fatal(err, "Failed to start QUIC listener")
+			}
+		}
+		logger.Info("QUIC listener started on " + cfg.QUICAddress)
+	}
+	defer quicListener.Close()
+	adminController.listenFile = listenFile
 
 	// Start metrics and health HTTP server
 	go startObservabilityServer(*observAddr, metrics, healthChecker, logger) // exposes /metrics, /health, /debug/pprof
@@ -152,30 +310,82 @@ func main() {
 				metrics.RecordQUICConnection(true)
 
 				// Handle connection in goroutine
-				go handleConnection(ctx, conn, transferService, eventPublisher, sessionStore, cfg, logger, metrics)
+				go handleConnection(ctx, conn, transferService, eventPublisher, sessionStore, sessionManager, cfg, logger, metrics, adminHandler)
 			}
 		}
 	}()
 
 	// Start API servers (gRPC + REST gateway + SSE)
-	grpcStop, restStop, err := server.StartAPIServers(context.Background(), cfg.GRPCAddress, cfg.RESTAddress, server.NewDaemonAPIServer(transferService, sessionStore, eventPublisher))
+	apiServer := server.NewDaemonAPIServer(transferService, sessionStore, eventPublisher, webhookStore)
+	apiServer.SetAdminController(adminController)
+	apiServer.SetSessionResumer(sessionManager)
+	authChain := authn.BuildChain(authn.ChainOptions{
+		StaticToken:    cfg.Auth.StaticToken,
+		HMACKey:        cfg.Auth.HMACKey,
+		OIDCJWKSURL:    cfg.Auth.OIDCJWKSURL,
+		OIDCIssuer:     cfg.Auth.OIDCIssuer,
+		OIDCAudience:   cfg.Auth.OIDCAudience,
+		MTLSEnabled:    cfg.Auth.MTLSEnabled,
+		MTLSAllowedOUs: cfg.Auth.MTLSAllowedOUs,
+	})
+	grpcListener, err := systemd.TCPListener(systemdListenFiles, "grpc")
+	if err != nil {
+		logger.Fatal(err, "Failed to adopt systemd grpc socket")
+	}
+	restListener, err := systemd.TCPListener(systemdListenFiles, "rest")
+	if err != nil {
+		logger.Fatal(err, "Failed to adopt systemd rest socket")
+	}
+	grpcStop, restStop, grpcHealth, err := server.StartAPIServersWithListeners(context.Background(), cfg.GRPCAddress, cfg.RESTAddress, apiServer, authChain, grpcListener, restListener)
 	if err != nil {
 		logger.Fatal(err, "Failed to start API servers")
 	}
 	logger.Info("API servers started: gRPC on " + cfg.GRPCAddress + ", REST on " + cfg.RESTAddress)
 
+	// Tell systemd (Type=notify units only; a no-op otherwise) that
+	// startup is done, so ExecStartPost-style ordering and
+	// Requires=quantarax-daemon.service dependents unblock now rather
+	// than immediately after fork.
+	if _, err := systemd.Notify(false, "READY=1"); err != nil {
+		logger.Error(err, "sd_notify READY=1 failed")
+	}
+
+	// healthBridge keeps the gRPC grpc.health.v1.Health service (what
+	// DaemonRunner.waitForReady now prefers) and the HTTP /health JSON body
+	// in sync for subsystems that can fail independently of the HTTP
+	// server itself.
+	healthBridge := server.NewComponentHealthBridge(healthChecker, grpcHealth)
+	healthBridge.SetServing(server.HealthServiceQUICTransfer, "quic_listener", observability.HealthStatusOK, "QUIC listener bound on "+cfg.QUICAddress)
+	healthBridge.SetServing(server.HealthServiceBitmapStore, "bitmapstore", observability.HealthStatusOK, "bitmap store at "+*bitmapDBPath)
+
 	logger.Info("QuantaraX Daemon running")
 	logger.Info("Press Ctrl+C to stop")
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal, reloading in place on SIGHUP (systemd's
+	// ExecReload=/bin/kill -HUP $MAINPID) instead of exiting.
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := <-sigChan; sig == syscall.SIGHUP; sig = <-sigChan {
+		if _, nErr := systemd.Notify(false, "RELOADING=1"); nErr != nil {
+			logger.Error(nErr, "sd_notify RELOADING=1 failed")
+		}
+		if err := adminController.Reload(); err != nil {
+			logger.Error(err, "SIGHUP reload failed")
+		}
+		if _, nErr := systemd.Notify(false, "READY=1"); nErr != nil {
+			logger.Error(nErr, "sd_notify READY=1 failed")
+		}
+	}
 
+	if _, err := systemd.Notify(false, "STOPPING=1"); err != nil {
+		logger.Error(err, "sd_notify STOPPING=1 failed")
+	}
 	logger.Info("Shutting down gracefully...")
 	cancel()
 	grpcStop()
 	restStop()
+	sessionManager.Stop()
+	_ = bitmapDB.Close()
 
 	// Cleanup old sessions
 	cleanedUp := sessionStore.CleanupOldSessions(24 * time.Hour)
@@ -184,6 +394,125 @@ func main() {
 	logger.Info("Daemon stopped")
 }
 
+// sessionSnapshotPath is where an admin-triggered restart persists in-flight
+// sessions for the re-exec'd process to pick back up, alongside the other
+// hardcoded data-file paths main already uses (e.g. the DTN queue's).
+const sessionSnapshotPath = "/tmp/quantarax_sessions.json"
+
+// inheritedListenFDEnv carries the listening QUIC socket's file descriptor
+// number across an admin-triggered restart's syscall.Exec, so the
+// re-exec'd process can rebuild its listener with
+// transport.ListenQUICFromInheritedFD instead of rebinding cfg.QUICAddress.
+const inheritedListenFDEnv = "QUANTARAX_LISTEN_FD"
+
+// daemonAdminController implements server.AdminController, giving the REST
+// admin endpoints a way to persist resume state and re-exec this binary, or
+// reload configuration, without DaemonAPIServer owning process lifecycle
+// itself.
+type daemonAdminController struct {
+	sessionStore *manager.SessionStore
+	cfg          *config.Config
+	logger       *observability.Logger
+
+	// listenFile, if non-nil, is the inheritable dup of the bound QUIC
+	// socket's fd (see transport.ListenQUICReusable). Restart passes its
+	// fd number to the re-exec'd process via inheritedListenFDEnv so it
+	// can pick the same socket back up instead of rebinding
+	// cfg.QUICAddress. nil on platforms ListenQUICReusable doesn't
+	// support, in which case Restart falls back to the rebind-with-a-gap
+	// behavior this type had before SO_REUSEPORT handoff existed.
+	listenFile *os.File
+}
+
+// Restart drains every registered transfer sender (service.lookupSenders'
+// pause, not a hard stop — queued chunks aren't lost), persists every
+// in-flight session to sessionSnapshotPath, then re-execs the current
+// binary with its original arguments. DTN queue and CAS entries need no
+// separate snapshot step: both are BoltDB-backed and already fsync each
+// write as it happens (see service.OpenDTNQueue, manager's BoltCAS), so
+// there's nothing buffered in memory to flush here. If listenFile is set,
+// its fd number is passed to the new process via inheritedListenFDEnv so
+// the QUIC listener migrates with it instead of leaving the gap this
+// method used to have before ListenQUICReusable existed.
+func (a *daemonAdminController) Restart() error {
+	service.DrainAllSenders()
+	if err := a.sessionStore.PersistSnapshot(sessionSnapshotPath); err != nil {
+		return err
+	}
+	execPath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	env := os.Environ()
+	if a.listenFile != nil {
+		env = append(env, fmt.Sprintf("%s=%d", inheritedListenFDEnv, a.listenFile.Fd()))
+	}
+	a.logger.Info("Admin restart requested; re-executing daemon binary")
+	go func() {
+		time.Sleep(200 * time.Millisecond) // let the HTTP response flush first
+		if err := syscall.Exec(execPath, os.Args, env); err != nil {
+			a.logger.Error(err, "Failed to re-exec daemon binary")
+		}
+	}()
+	return nil
+}
+
+// Reload re-parses configuration in place. Address fields are left as they
+// are: changing a listener address without rebinding would desync the
+// running servers from cfg, so only the rest of what LoadConfig produces is
+// swapped in live.
+func (a *daemonAdminController) Reload() error {
+	fresh, err := config.LoadConfig("")
+	if err != nil {
+		return err
+	}
+	fresh.GRPCAddress = a.cfg.GRPCAddress
+	fresh.RESTAddress = a.cfg.RESTAddress
+	fresh.QUICAddress = a.cfg.QUICAddress
+	*a.cfg = *fresh
+	a.logger.Info("Admin reload requested; configuration re-parsed")
+	return nil
+}
+
+// printGeneratedUnits implements -generate-unit: "service" prints just
+// quantarax-daemon.service, "sockets" prints the grpc/rest/quic socket
+// units GenerateSocketUnits derives from cfg, and "all" prints both.
+func printGeneratedUnits(cfg *config.Config, which string, opts systemd.UnitOptions) {
+	switch which {
+	case "service":
+		fmt.Print(systemd.GenerateUnit(cfg, opts))
+	case "sockets":
+		for name, unit := range systemd.GenerateSocketUnits(cfg) {
+			fmt.Printf("# %s\n%s\n", name, unit)
+		}
+	case "all":
+		fmt.Printf("# quantarax-daemon.service\n%s\n", systemd.GenerateUnit(cfg, opts))
+		for name, unit := range systemd.GenerateSocketUnits(cfg) {
+			fmt.Printf("# %s\n%s\n", name, unit)
+		}
+	default:
+		log.Fatalf("-generate-unit: unknown target %q (want service, sockets, or all)", which)
+	}
+}
+
+// splitAndTrim splits a comma-separated flag/env value into its trimmed,
+// non-empty elements, returning nil for an empty input so a zero-valued
+// -auth-mtls-allowed-ous produces a nil AuthConfig.MTLSAllowedOUs rather than
+// a one-element slice containing "".
+func splitAndTrim(s string) []string {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
 func startObservabilityServer(addr string, metrics *observability.Metrics, health *observability.HealthChecker, logger *observability.Logger) {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", metrics.Handler())
@@ -208,9 +537,11 @@ func handleConnection(
 	transferService *service.TransferService,
 	eventPublisher *service.EventPublisher,
 	sessionStore *manager.SessionStore,
+	sessionManager *session.SessionManager,
 	cfg *config.Config,
 	logger *observability.Logger,
 	metrics *observability.Metrics,
+	adminHandler *service.AdminHandler,
 ) {
 	defer conn.Close()
 
@@ -220,11 +551,13 @@ func handleConnection(
 		logger.Error(err, "failed to accept control stream")
 		return
 	}
+	ctrl.SetMetrics(metrics)
 	signed, err := ctrl.ReceiveSignedManifest()
 	if err != nil {
 		logger.Error(err, "failed to receive manifest")
 		return
 	}
+	ctx = transport.ExtractTraceContext(ctx, signed.TraceContext)
 	logger.Info("Manifest received")
 	// Parse manifest JSON
 	var manifest chunker.Manifest
@@ -232,6 +565,19 @@ func handleConnection(
 		logger.Error(err, "failed to parse manifest JSON")
 		return
 	}
+
+	// Acquire (and keep refreshing) a lease on this session before
+	// mutating it below, so a concurrent admin action or a second
+	// connection racing for the same session ID can't interleave updates,
+	// and so a crash mid-transfer leaves a reclaimable lease behind
+	// instead of one that looks held forever.
+	holder := conn.GetConnection().RemoteAddr().String()
+	releaseLease, err := sessionManager.AcquireLease(manifest.SessionID, holder)
+	if err != nil {
+		logger.Error(err, "failed to acquire session lease")
+		return
+	}
+	defer releaseLease()
 	// Build basic session keys placeholder (real key exchange omitted here)
 	var sk crypto.SessionKeys
 	// Orchestrate sending using domain profile
@@ -248,11 +594,11 @@ func handleConnection(
 			bytes := sentChunks * int64(manifest.ChunkSize)
 			sess.UpdateProgress(bytes, sentChunks)
 			// Publish progress event
-			eventPublisher.PublishProgress(manifest.SessionID, sess.GetProgressPercent(), sess.GetTransferRate(), sess.GetEstimatedTimeRemaining())
+			eventPublisher.PublishProgress(manifest.SessionID, sess.GetProgressPercent(), bytes, sentChunks, sess.GetTransferRate(), sess.GetEstimatedTimeRemaining())
 		}
 		metrics.RecordChunkSent(int(idx))
 	}
-	if err := service.SendWithOrchestration(ctx, conn, &manifest, &sk, sessionUUID, filePath, onChunkSent); err != nil {
+	if err := service.SendWithOrchestration(ctx, conn, &manifest, &sk, sessionUUID, filePath, onChunkSent, eventPublisher, metrics, logger, adminHandler); err != nil {
 		logger.Error(err, "send orchestration failed")
 		return
 	}
@@ -2,6 +2,7 @@ package manager
 
 import (
 	"database/sql"
+	"encoding/binary"
 	"fmt"
 	"sync"
 	"time"
@@ -53,6 +54,30 @@ func (cb *ChunkBitmap) SetChunk(chunkIndex int64) error {
 	return nil
 }
 
+// ClearChunk marks a chunk as not received, e.g. after verification finds
+// its on-disk bytes don't match the expected hash and it needs to be
+// re-fetched.
+func (cb *ChunkBitmap) ClearChunk(chunkIndex int64) error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if chunkIndex < 0 || chunkIndex >= cb.totalChunks {
+		return fmt.Errorf("chunk index out of range: %d", chunkIndex)
+	}
+
+	byteIndex := chunkIndex / 8
+	bitIndex := chunkIndex % 8
+
+	if cb.bitmap[byteIndex]&(1<<bitIndex) == 0 {
+		return nil // Already clear
+	}
+
+	cb.bitmap[byteIndex] &^= 1 << bitIndex
+	cb.chunksReceived--
+
+	return nil
+}
+
 // HasChunk checks if a chunk has been received
 func (cb *ChunkBitmap) HasChunk(chunkIndex int64) bool {
 	cb.mu.RLock()
@@ -162,6 +187,112 @@ func (cb *ChunkBitmap) Deserialize(data []byte) error {
 	return nil
 }
 
+// EncodeRanges produces a compact run-length-encoded snapshot of cb's
+// missing ranges, suitable for piggybacking on a QUIC datagram (see
+// transport.DatagramHaveBitmap): `sessionID || baseIndex || RLE pairs`,
+// where baseIndex is the lowest missing index so every (start, len) pair
+// can be varint-encoded relative to it instead of carrying cb's full
+// absolute range. Each pair is `varint(start-baseIndex) || varint(len)`.
+// Typical near-complete bitmaps (a handful of scattered misses) fit this in
+// well under MaxDatagramSize; a caller can compare the encoded length
+// against that limit before sending and fall back to Serialize() over a
+// reliable control stream instead.
+func (cb *ChunkBitmap) EncodeRanges() []byte {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	var ranges [][2]int64 // [start, len]
+	var start int64 = -1
+	for i := int64(0); i < cb.totalChunks; i++ {
+		byteIndex := i / 8
+		bitIndex := i % 8
+		missing := cb.bitmap[byteIndex]&(1<<bitIndex) == 0
+		if missing && start < 0 {
+			start = i
+		} else if !missing && start >= 0 {
+			ranges = append(ranges, [2]int64{start, i - start})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		ranges = append(ranges, [2]int64{start, cb.totalChunks - start})
+	}
+
+	var baseIndex int64
+	if len(ranges) > 0 {
+		baseIndex = ranges[0][0]
+	}
+
+	idBytes := []byte(cb.sessionID)
+	buf := make([]byte, 0, 16+len(idBytes)+len(ranges)*10)
+	var varintBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(varintBuf[:], uint64(len(idBytes)))
+	buf = append(buf, varintBuf[:n]...)
+	buf = append(buf, idBytes...)
+	n = binary.PutVarint(varintBuf[:], baseIndex)
+	buf = append(buf, varintBuf[:n]...)
+	for _, r := range ranges {
+		n = binary.PutUvarint(varintBuf[:], uint64(r[0]-baseIndex))
+		buf = append(buf, varintBuf[:n]...)
+		n = binary.PutUvarint(varintBuf[:], uint64(r[1]))
+		buf = append(buf, varintBuf[:n]...)
+	}
+	return buf
+}
+
+// DecodeRanges is the inverse of (*ChunkBitmap).EncodeRanges: it returns
+// the session ID the snapshot was taken for and the missing ranges as
+// absolute [start, len) pairs, without needing a ChunkBitmap to decode
+// into (the receiving side of a have-bitmap datagram only wants to know
+// which chunks to retransmit, not to reconstruct the sender's bitmap).
+func DecodeRanges(data []byte) (sessionID string, ranges [][2]int64, err error) {
+	buf := data
+	idLen, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return "", nil, fmt.Errorf("malformed ranges: missing session id length")
+	}
+	buf = buf[n:]
+	if uint64(len(buf)) < idLen {
+		return "", nil, fmt.Errorf("malformed ranges: truncated session id")
+	}
+	sessionID = string(buf[:idLen])
+	buf = buf[idLen:]
+
+	baseIndex, n := binary.Varint(buf)
+	if n <= 0 {
+		return "", nil, fmt.Errorf("malformed ranges: missing base index")
+	}
+	buf = buf[n:]
+
+	for len(buf) > 0 {
+		offset, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return "", nil, fmt.Errorf("malformed ranges: truncated range start")
+		}
+		buf = buf[n:]
+		length, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return "", nil, fmt.Errorf("malformed ranges: truncated range length")
+		}
+		buf = buf[n:]
+		ranges = append(ranges, [2]int64{baseIndex + int64(offset), int64(length)})
+	}
+	return sessionID, ranges, nil
+}
+
+// ExpandRanges flattens a [start, len) range list (as returned by
+// DecodeRanges) into individual chunk indices, the form a sender's
+// retransmit path wants.
+func ExpandRanges(ranges [][2]int64) []int64 {
+	var out []int64
+	for _, r := range ranges {
+		for i := int64(0); i < r[1]; i++ {
+			out = append(out, r[0]+i)
+		}
+	}
+	return out
+}
+
 // BitmapStore manages persistent chunk bitmaps
 type BitmapStore struct {
 	db *sql.DB
@@ -263,3 +394,27 @@ func (bs *BitmapStore) DeleteBitmap(sessionID string) error {
 
 	return nil
 }
+
+// PruneStale deletes every bitmap whose last_updated is older than ttl,
+// using the idx_bitmaps_updated index the initial migration already
+// creates for this, and returns how many rows were removed. It does not
+// check whether the owning session completed first, the same way
+// SessionStore.CleanupOldSessions only looks at age for already-terminal
+// sessions: a caller (session.SessionManager's pruning loop) is expected to
+// pick a ttl long enough that an in-progress transfer's bitmap is never
+// still the oldest thing in the table.
+func (bs *BitmapStore) PruneStale(ttl time.Duration) (int, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	cutoff := time.Now().Add(-ttl)
+	result, err := bs.db.Exec("DELETE FROM chunk_bitmaps WHERE last_updated < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune stale bitmaps: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rows), nil
+}
@@ -107,6 +107,57 @@ func TestChunkBitmap_GetProgress(t *testing.T) {
 	}
 }
 
+func TestChunkBitmap_EncodeDecodeRanges(t *testing.T) {
+	bitmap := NewChunkBitmap("session-with-digits-123", 20)
+
+	// Set everything except 3-5 and 17-19, so the missing set is two ranges.
+	for i := int64(0); i < 20; i++ {
+		if (i >= 3 && i <= 5) || i >= 17 {
+			continue
+		}
+		bitmap.SetChunk(i)
+	}
+
+	encoded := bitmap.EncodeRanges()
+	sessionID, ranges, err := DecodeRanges(encoded)
+	if err != nil {
+		t.Fatalf("DecodeRanges failed: %v", err)
+	}
+	if sessionID != "session-with-digits-123" {
+		t.Errorf("expected session id %q, got %q", "session-with-digits-123", sessionID)
+	}
+
+	missing := ExpandRanges(ranges)
+	expected := []int64{3, 4, 5, 17, 18, 19}
+	if len(missing) != len(expected) {
+		t.Fatalf("expected %d missing chunks, got %d (%v)", len(expected), len(missing), missing)
+	}
+	for i, idx := range expected {
+		if missing[i] != idx {
+			t.Errorf("expected missing chunk %d at position %d, got %d", idx, i, missing[i])
+		}
+	}
+}
+
+func TestChunkBitmap_EncodeRanges_Complete(t *testing.T) {
+	bitmap := NewChunkBitmap("test-session", 10)
+	for i := int64(0); i < 10; i++ {
+		bitmap.SetChunk(i)
+	}
+
+	encoded := bitmap.EncodeRanges()
+	sessionID, ranges, err := DecodeRanges(encoded)
+	if err != nil {
+		t.Fatalf("DecodeRanges failed: %v", err)
+	}
+	if sessionID != "test-session" {
+		t.Errorf("expected session id %q, got %q", "test-session", sessionID)
+	}
+	if len(ranges) != 0 {
+		t.Errorf("expected no missing ranges for a complete bitmap, got %v", ranges)
+	}
+}
+
 func TestChunkBitmap_OutOfRange(t *testing.T) {
 	bitmap := NewChunkBitmap("test-session", 10)
 
@@ -5,6 +5,7 @@ import (
 	"time"
 	"encoding/binary"
 	"github.com/boltdb/bolt"
+	"github.com/quantarax/backend/internal/observability"
 )
 
 type BoltCAS struct { db *bolt.DB }
@@ -68,5 +69,6 @@ func (b *BoltCAS) GC(maxAge time.Duration) (int, error) {
 		}
 		return nil
 	})
+	observability.TraceGlobal(observability.FacetCAS, "cas gc", "removed", removed, "max_age_seconds", maxAge.Seconds())
 	return removed, err
 }
@@ -0,0 +1,169 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/quantarax/backend/internal/chunker"
+	"github.com/zeebo/blake3"
+)
+
+// CASBackend is a minimal content-addressed chunk reader VerifyReceivedFile
+// can fall back to when a chunk's on-disk bytes don't hash-match, in case
+// the sender's chunks were also cached in CAS. No CAS implementation in
+// this repo currently retains chunk bytes (BoltCAS only tracks
+// hash->last-seen timestamps for GC), so passing nil is the common case and
+// simply skips the fallback.
+type CASBackend interface {
+	HasChunk(hash string) bool
+	GetChunk(hash string) ([]byte, error)
+}
+
+// VerifyReceivedFile re-derives the Merkle root from the bytes actually
+// written to disk at sess.FilePath, re-chunked at sess.ChunkSize and hashed
+// with BLAKE3 exactly like the sender did, and compares it against
+// manifest's signed MerkleRoot. This closes the gap where end-of-transfer
+// verification only checked per-chunk hashes against the manifest as they
+// arrived, which can't catch a corrupt write() to the reassembled file.
+//
+// On a root mismatch, it bisects the expected and actual Merkle trees
+// level by level to locate the specific chunk indices responsible, rather
+// than re-hashing and diff-checking every chunk, and returns them in
+// VerificationResult.CorruptChunks so the caller can mark just those
+// chunks unreceived (ChunkBitmap.ClearChunk) and hand them back to the
+// retry/request-missing path instead of redownloading the whole file.
+func VerifyReceivedFile(sess *Session, manifest *chunker.Manifest, cas CASBackend) (*VerificationResult, error) {
+	if manifest == nil {
+		return nil, fmt.Errorf("verify received file: manifest is required")
+	}
+
+	computedHashes, err := hashFileChunks(sess.FilePath, sess.ChunkSize, manifest, cas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash received file: %w", err)
+	}
+
+	computedRoot, err := chunker.ComputeMerkleRoot(computedHashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute merkle root: %w", err)
+	}
+
+	mv := NewMerkleVerifier()
+	result := mv.CreateVerificationResult(sess.ID, []byte(computedRoot), []byte(manifest.MerkleRoot))
+
+	if result.Status != VerificationSuccess {
+		expectedHashes := extractManifestChunkHashes(manifest)
+		if corrupt, bisectErr := locateCorruptChunks(expectedHashes, computedHashes); bisectErr == nil {
+			result.CorruptChunks = corrupt
+		}
+	}
+
+	if err := mv.SignVerificationResult(result); err != nil {
+		return result, fmt.Errorf("failed to sign verification result: %w", err)
+	}
+
+	return result, nil
+}
+
+// hashFileChunks reads filePath in chunkSize windows and returns the
+// base64-encoded BLAKE3 hash of each chunk, in index order. If a window's
+// on-disk bytes don't hash-match the manifest's expected hash for that
+// index and cas is non-nil, it retries the chunk from CAS before accepting
+// the (possibly still-mismatched) on-disk hash.
+func hashFileChunks(filePath string, chunkSize int64, manifest *chunker.Manifest, cas CASBackend) ([]string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Only the original file's chunks are hashed here, never any FEC parity
+	// chunks a manifest's FecStripes appended: MerkleRoot was computed over
+	// just the data chunks, and parity chunks were never written to
+	// sess.FilePath in the first place.
+	dataChunkCount := manifest.DataChunkCount()
+	hashes := make([]string, dataChunkCount)
+	buf := make([]byte, chunkSize)
+	for i := 0; i < dataChunkCount; i++ {
+		n := manifest.Chunks[i].Length
+		if n <= 0 || int64(n) > chunkSize {
+			n = int(chunkSize)
+		}
+		b := buf[:n]
+
+		if _, err := f.Seek(int64(i)*chunkSize, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek to chunk %d: %w", i, err)
+		}
+		if _, err := io.ReadFull(f, b); err != nil {
+			return nil, fmt.Errorf("failed to read chunk %d: %w", i, err)
+		}
+
+		h := blake3.Sum256(b)
+		computed := base64.StdEncoding.EncodeToString(h[:])
+
+		if cas != nil && computed != manifest.Chunks[i].Hash && cas.HasChunk(manifest.Chunks[i].Hash) {
+			if casBytes, err := cas.GetChunk(manifest.Chunks[i].Hash); err == nil {
+				casHash := blake3.Sum256(casBytes)
+				computed = base64.StdEncoding.EncodeToString(casHash[:])
+			}
+		}
+
+		hashes[i] = computed
+	}
+
+	return hashes, nil
+}
+
+func extractManifestChunkHashes(manifest *chunker.Manifest) []string {
+	dataChunkCount := manifest.DataChunkCount()
+	hashes := make([]string, dataChunkCount)
+	for i := 0; i < dataChunkCount; i++ {
+		hashes[i] = manifest.Chunks[i].Hash
+	}
+	return hashes
+}
+
+// locateCorruptChunks builds the expected and actual Merkle trees from
+// their respective leaf hash lists and walks down from the root, at each
+// level only descending into children whose hash differs between the two
+// trees, until it reaches the leaves responsible for the root mismatch.
+// This is O(divergent chunks * tree height) instead of diffing every chunk.
+func locateCorruptChunks(expectedHashes, actualHashes []string) ([]int64, error) {
+	if len(expectedHashes) != len(actualHashes) {
+		return nil, fmt.Errorf("chunk count mismatch: expected %d, got %d", len(expectedHashes), len(actualHashes))
+	}
+
+	expected, err := chunker.BuildMerkleTree(expectedHashes)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := chunker.BuildMerkleTree(actualHashes)
+	if err != nil {
+		return nil, err
+	}
+	if len(expected.Levels) != len(actual.Levels) {
+		return nil, fmt.Errorf("merkle tree height mismatch")
+	}
+
+	var corrupt []int64
+	var walk func(level, index int)
+	walk = func(level, index int) {
+		if level == 0 {
+			corrupt = append(corrupt, int64(index))
+			return
+		}
+		children := expected.Levels[level-1]
+		leftIdx, rightIdx := 2*index, 2*index+1
+		if leftIdx < len(children) && !bytes.Equal(expected.Levels[level-1][leftIdx], actual.Levels[level-1][leftIdx]) {
+			walk(level-1, leftIdx)
+		}
+		if rightIdx < len(children) && !bytes.Equal(expected.Levels[level-1][rightIdx], actual.Levels[level-1][rightIdx]) {
+			walk(level-1, rightIdx)
+		}
+	}
+	walk(len(expected.Levels)-1, 0)
+
+	return corrupt, nil
+}
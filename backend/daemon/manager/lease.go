@@ -0,0 +1,199 @@
+package manager
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+var (
+	// ErrLeaseHeld is returned by Acquire when sessionID's lease is already
+	// held by a different holder and hasn't passed its deadline yet.
+	ErrLeaseHeld = errors.New("session lease held by another holder")
+	// ErrLeaseNotFound is returned by Refresh/Release when the lease has
+	// already been released, reclaimed, or never existed.
+	ErrLeaseNotFound = errors.New("session lease not found")
+	// ErrLeaseMismatch is returned by Refresh/Release when token.Secret
+	// doesn't match the record on disk - e.g. a cancel fired after the
+	// lease was already reclaimed and re-acquired by someone else.
+	ErrLeaseMismatch = errors.New("session lease token does not match current holder")
+)
+
+var bucketLeases = []byte("session_leases")
+
+// LeaseToken is the handle Acquire hands back to a caller. Refresh and
+// Release take it by value rather than a bare session ID, so a stale
+// caller (e.g. a goroutine still holding a reference after its lease was
+// reclaimed and re-acquired by someone else) can't accidentally mutate or
+// release a newer holder's lease: Secret must match the on-disk record.
+type LeaseToken struct {
+	SessionID string
+	Holder    string
+	Secret    string
+	TTL       time.Duration
+	Deadline  time.Time
+}
+
+// leaseRecord is the on-disk encoding of a LeaseToken, keyed by SessionID.
+type leaseRecord struct {
+	Holder     string    `json:"holder"`
+	Secret     string    `json:"secret"`
+	TTLSeconds float64   `json:"ttl_seconds"`
+	Deadline   time.Time `json:"deadline"`
+}
+
+// LeaseStore persists session leases in BoltDB, following the same
+// single-bucket open/close shape as BoltCAS and BoltRevocationStore. A
+// lease gates mutation of one manager.Session across process restarts: as
+// long as its deadline hasn't passed, only the holder recorded in Acquire
+// may Refresh or Release it.
+type LeaseStore struct{ db *bolt.DB }
+
+// OpenLeaseStore opens (creating if necessary) a BoltDB file at path for
+// session lease bookkeeping.
+func OpenLeaseStore(path string) (*LeaseStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error { _, e := tx.CreateBucketIfNotExists(bucketLeases); return e })
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &LeaseStore{db: db}, nil
+}
+
+func (l *LeaseStore) Close() error { return l.db.Close() }
+
+// Acquire takes out a lease on sessionID for holder, valid until ttl
+// elapses. If an unexpired lease already exists under a different holder,
+// Acquire returns ErrLeaseHeld. The returned cancel func releases the
+// lease and must always be deferred by the caller - including on error
+// paths - so a lease is never left dangling past its own Release.
+func (l *LeaseStore) Acquire(sessionID, holder string, ttl time.Duration) (LeaseToken, func(), error) {
+	secret, err := randomSecret()
+	if err != nil {
+		return LeaseToken{}, func() {}, err
+	}
+	now := time.Now()
+	rec := leaseRecord{Holder: holder, Secret: secret, TTLSeconds: ttl.Seconds(), Deadline: now.Add(ttl)}
+
+	err = l.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucketLeases)
+		if existing := bk.Get([]byte(sessionID)); existing != nil {
+			var cur leaseRecord
+			if jsonErr := json.Unmarshal(existing, &cur); jsonErr == nil {
+				if cur.Holder != holder && now.Before(cur.Deadline) {
+					return ErrLeaseHeld
+				}
+			}
+		}
+		data, mErr := json.Marshal(rec)
+		if mErr != nil {
+			return mErr
+		}
+		return bk.Put([]byte(sessionID), data)
+	})
+	if err != nil {
+		return LeaseToken{}, func() {}, err
+	}
+
+	token := LeaseToken{SessionID: sessionID, Holder: holder, Secret: secret, TTL: ttl, Deadline: rec.Deadline}
+	cancel := func() { _ = l.Release(token) }
+	return token, cancel, nil
+}
+
+// Refresh extends token's deadline by its original TTL from now, so a
+// background goroutine calling Refresh every ttl/3 keeps a long-running
+// operation's lease alive without ever holding it continuously.
+func (l *LeaseStore) Refresh(token LeaseToken) (LeaseToken, error) {
+	next := token
+	err := l.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucketLeases)
+		existing := bk.Get([]byte(token.SessionID))
+		if existing == nil {
+			return ErrLeaseNotFound
+		}
+		var cur leaseRecord
+		if err := json.Unmarshal(existing, &cur); err != nil {
+			return err
+		}
+		if cur.Secret != token.Secret {
+			return ErrLeaseMismatch
+		}
+		cur.Deadline = time.Now().Add(token.TTL)
+		next.Deadline = cur.Deadline
+		data, err := json.Marshal(cur)
+		if err != nil {
+			return err
+		}
+		return bk.Put([]byte(token.SessionID), data)
+	})
+	if err != nil {
+		return LeaseToken{}, err
+	}
+	return next, nil
+}
+
+// Release removes token's lease if it's still the current holder's. It's
+// not an error for the lease to already be gone (the usual case is it was
+// reclaimed as expired before Release ran), so callers can unconditionally
+// defer the cancel func Acquire returns.
+func (l *LeaseStore) Release(token LeaseToken) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucketLeases)
+		existing := bk.Get([]byte(token.SessionID))
+		if existing == nil {
+			return nil
+		}
+		var cur leaseRecord
+		if err := json.Unmarshal(existing, &cur); err != nil {
+			return err
+		}
+		if cur.Secret != token.Secret {
+			return nil
+		}
+		return bk.Delete([]byte(token.SessionID))
+	})
+}
+
+// ReclaimExpired deletes every lease whose deadline has passed and returns
+// the session IDs it reclaimed, so a daemon starting back up can decide
+// what to do with the sessions those leases used to protect (see
+// session.SessionManager.reconcile, which moves a reclaimed StateActive
+// session to StateResuming).
+func (l *LeaseStore) ReclaimExpired() ([]string, error) {
+	var reclaimed []string
+	now := time.Now()
+	err := l.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucketLeases)
+		c := bk.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var rec leaseRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				continue
+			}
+			if now.After(rec.Deadline) {
+				reclaimed = append(reclaimed, string(k))
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return reclaimed, err
+}
+
+func randomSecret() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,121 @@
+// Package migrations implements a minimal forward-only schema migration
+// framework for PersistentStore's SQLite database. Each Migration owns its
+// own SQL (for checksumming and auditing) and a Go closure that applies it;
+// Run/RunTo record every applied version in schema_version so a restart
+// never re-applies a migration and a binary can detect a database that is
+// newer than the schema it knows how to speak.
+package migrations
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Migration is one forward step in the schema's history. SQL is the
+// statement(s) Up executes; it exists as its own field (rather than being
+// parsed out of the closure) purely so Run can checksum what actually ran
+// and record that alongside the version.
+type Migration struct {
+	Version     int
+	Description string
+	SQL         string
+	Up          func(*sql.Tx) error
+	Down        func(*sql.Tx) error
+}
+
+func ensureVersionTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`)
+	return err
+}
+
+// CurrentVersion returns the highest version recorded in schema_version, or
+// 0 for a database that has never been migrated.
+func CurrentVersion(db *sql.DB) (int, error) {
+	if err := ensureVersionTable(db); err != nil {
+		return 0, fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+	var version int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// MaxKnownVersion returns the highest version present in registry, i.e. the
+// schema version this binary expects an up-to-date database to be at.
+func MaxKnownVersion(registry []Migration) int {
+	max := 0
+	for _, m := range registry {
+		if m.Version > max {
+			max = m.Version
+		}
+	}
+	return max
+}
+
+// Run applies every pending migration in registry, in ascending version
+// order.
+func Run(db *sql.DB, registry []Migration) error {
+	return RunTo(db, registry, MaxKnownVersion(registry))
+}
+
+// RunTo applies every migration in registry whose version is greater than
+// the database's current version and less than or equal to target, each
+// inside its own transaction. It records the applied version and a checksum
+// of the executed SQL in schema_version, and stops at the first error,
+// leaving the database at the last successfully applied version.
+func RunTo(db *sql.DB, registry []Migration, target int) error {
+	if err := ensureVersionTable(db); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	sorted := append([]Migration(nil), registry...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range sorted {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", m.Version, err)
+		}
+
+		if err := m.Up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+
+		checksum := sha256.Sum256([]byte(m.SQL))
+		_, err = tx.Exec(
+			"INSERT INTO schema_version (version, checksum, applied_at) VALUES (?, ?, ?)",
+			m.Version, hex.EncodeToString(checksum[:]), time.Now(),
+		)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+		}
+	}
+
+	return nil
+}
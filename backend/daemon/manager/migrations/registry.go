@@ -0,0 +1,97 @@
+package migrations
+
+import "database/sql"
+
+const migration1SQL = `
+	CREATE TABLE IF NOT EXISTS transfer_sessions (
+		session_id TEXT PRIMARY KEY,
+		file_path TEXT NOT NULL,
+		file_name TEXT NOT NULL,
+		file_size INTEGER NOT NULL,
+		chunk_size INTEGER NOT NULL,
+		total_chunks INTEGER NOT NULL,
+		direction TEXT NOT NULL,
+		state TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		metadata TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS chunk_bitmaps (
+		session_id TEXT PRIMARY KEY,
+		bitmap_data BLOB NOT NULL,
+		chunks_received INTEGER NOT NULL DEFAULT 0,
+		last_updated TIMESTAMP NOT NULL,
+		FOREIGN KEY (session_id) REFERENCES transfer_sessions(session_id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_sessions_state ON transfer_sessions(state);
+	CREATE INDEX IF NOT EXISTS idx_bitmaps_updated ON chunk_bitmaps(last_updated);
+`
+
+const migration2SQL = `
+	CREATE TABLE IF NOT EXISTS chunk_proofs (
+		session_id TEXT NOT NULL,
+		chunk_index INTEGER NOT NULL,
+		leaf_hash BLOB NOT NULL,
+		proof_json TEXT NOT NULL,
+		root_hash BLOB NOT NULL,
+		status TEXT NOT NULL,
+		verified_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (session_id, chunk_index)
+	);
+`
+
+// migration3SQL adds the columns PersistentStore needs to store session
+// metadata encrypted and tamper-evident instead of as plain JSON. SQLite
+// only allows one ADD COLUMN per ALTER TABLE statement. The pre-existing
+// "metadata" column is left in place (nullable going forward) so old rows
+// stay readable until LoadSession lazily re-encrypts them; see
+// PersistentStore.LoadSession.
+const migration3SQL = `
+	ALTER TABLE transfer_sessions ADD COLUMN metadata_nonce BLOB;
+	ALTER TABLE transfer_sessions ADD COLUMN metadata_ciphertext BLOB;
+	ALTER TABLE transfer_sessions ADD COLUMN row_signature BLOB;
+`
+
+// Registry is the ordered history of PersistentStore's SQLite schema.
+// Append new migrations with a strictly increasing Version; never edit a
+// migration that has already shipped, since its checksum is recorded in
+// deployed databases' schema_version tables.
+var Registry = []Migration{
+	{
+		Version:     1,
+		Description: "initial schema: transfer_sessions, chunk_bitmaps",
+		SQL:         migration1SQL,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration1SQL)
+			return err
+		},
+	},
+	{
+		Version:     2,
+		Description: "chunk_proofs table for persisted per-chunk Merkle proof verification",
+		SQL:         migration2SQL,
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(migration2SQL)
+			return err
+		},
+	},
+	{
+		Version:     3,
+		Description: "encrypted/signed session metadata columns on transfer_sessions",
+		SQL:         migration3SQL,
+		Up: func(tx *sql.Tx) error {
+			for _, stmt := range []string{
+				`ALTER TABLE transfer_sessions ADD COLUMN metadata_nonce BLOB`,
+				`ALTER TABLE transfer_sessions ADD COLUMN metadata_ciphertext BLOB`,
+				`ALTER TABLE transfer_sessions ADD COLUMN row_signature BLOB`,
+			} {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
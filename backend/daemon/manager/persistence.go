@@ -1,21 +1,57 @@
 package manager
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"database/sql"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/quantarax/backend/daemon/manager/migrations"
+	"github.com/quantarax/backend/internal/crypto"
+	"github.com/quantarax/backend/internal/crypto/identity"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	_ "modernc.org/sqlite"
 )
 
 var (
 	ErrDatabaseNotInitialized = errors.New("database not initialized")
 	ErrBitmapNotFound         = errors.New("bitmap not found")
+	ErrChunkProofNotFound     = errors.New("chunk proof not found")
+	ErrSchemaTooNew           = errors.New("database schema is newer than this binary's migration registry")
+	ErrSchemaTooOld           = errors.New("database schema is behind the migration registry and store is read-only")
+
+	// ErrStoreTampered is returned by LoadSession when a row's signature
+	// doesn't match its contents, meaning the database was modified outside
+	// of PersistentStore since the row was last saved.
+	ErrStoreTampered = errors.New("session row signature invalid: database may have been tampered with")
 )
 
+// metadataStorageLabel is the HKDF label used to derive the AES-256-GCM key
+// that protects transfer_sessions.metadata_ciphertext.
+const metadataStorageLabel = "session-metadata"
+
+// canonicalSessionRow returns the bytes signed/verified for a session row:
+// everything an attacker with filesystem access could alter that SaveSession
+// itself doesn't re-derive from the encrypted metadata, so a bad signature
+// always means the row actually changed.
+func canonicalSessionRow(sessionID string, fileSize, totalChunks int64, state string, metadataCiphertext []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(sessionID)
+	_ = binary.Write(&buf, binary.BigEndian, fileSize)
+	_ = binary.Write(&buf, binary.BigEndian, totalChunks)
+	buf.WriteString(state)
+	buf.Write(metadataCiphertext)
+	return buf.Bytes()
+}
+
 // PersistentStore manages SQLite-backed session and bitmap storage
 type PersistentStore struct {
 	db   *sql.DB
@@ -23,8 +59,37 @@ type PersistentStore struct {
 	mu   sync.RWMutex
 }
 
+// Option configures NewPersistentStore.
+type Option func(*storeConfig)
+
+type storeConfig struct {
+	registry []migrations.Migration
+	readOnly bool
+}
+
+// WithMigrationRegistry overrides the set of migrations NewPersistentStore
+// applies; callers embedding PersistentStore in a larger schema can append
+// their own migrations to migrations.Registry and pass the result here.
+// Defaults to migrations.Registry.
+func WithMigrationRegistry(registry []migrations.Migration) Option {
+	return func(c *storeConfig) { c.registry = registry }
+}
+
+// WithReadOnly opens the store without applying any pending migrations. If
+// the database's current schema version doesn't exactly match the
+// registry's newest known version, NewPersistentStore fails fast instead of
+// running against a schema it doesn't understand.
+func WithReadOnly(readOnly bool) Option {
+	return func(c *storeConfig) { c.readOnly = readOnly }
+}
+
 // NewPersistentStore creates a new persistent store with SQLite backend
-func NewPersistentStore(dbPath string) (*PersistentStore, error) {
+func NewPersistentStore(dbPath string, opts ...Option) (*PersistentStore, error) {
+	cfg := storeConfig{registry: migrations.Registry}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -40,8 +105,7 @@ func NewPersistentStore(dbPath string) (*PersistentStore, error) {
 		path: dbPath,
 	}
 
-	// Initialize schema
-	if err := store.initSchema(); err != nil {
+	if err := store.initSchema(cfg); err != nil {
 		db.Close()
 		return nil, err
 	}
@@ -49,60 +113,42 @@ func NewPersistentStore(dbPath string) (*PersistentStore, error) {
 	return store, nil
 }
 
-// initSchema creates the database schema if it doesn't exist
-func (ps *PersistentStore) initSchema() error {
-	schema := `
-		CREATE TABLE IF NOT EXISTS schema_version (
-			version INTEGER PRIMARY KEY,
-			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS transfer_sessions (
-			session_id TEXT PRIMARY KEY,
-			file_path TEXT NOT NULL,
-			file_name TEXT NOT NULL,
-			file_size INTEGER NOT NULL,
-			chunk_size INTEGER NOT NULL,
-			total_chunks INTEGER NOT NULL,
-			direction TEXT NOT NULL,
-			state TEXT NOT NULL,
-			created_at TIMESTAMP NOT NULL,
-			updated_at TIMESTAMP NOT NULL,
-			metadata TEXT
-		);
-
-		CREATE TABLE IF NOT EXISTS chunk_bitmaps (
-			session_id TEXT PRIMARY KEY,
-			bitmap_data BLOB NOT NULL,
-			chunks_received INTEGER NOT NULL DEFAULT 0,
-			last_updated TIMESTAMP NOT NULL,
-			FOREIGN KEY (session_id) REFERENCES transfer_sessions(session_id) ON DELETE CASCADE
-		);
-
-		CREATE INDEX IF NOT EXISTS idx_sessions_state ON transfer_sessions(state);
-		CREATE INDEX IF NOT EXISTS idx_bitmaps_updated ON chunk_bitmaps(last_updated);
-	`
-
-	if _, err := ps.db.Exec(schema); err != nil {
-		return fmt.Errorf("failed to initialize schema: %w", err)
-	}
+// initSchema brings the database up to date using the migration registry in
+// cfg, or (in read-only mode) verifies it's already at the expected
+// version without writing anything.
+func (ps *PersistentStore) initSchema(cfg storeConfig) error {
+	known := migrations.MaxKnownVersion(cfg.registry)
 
-	// Insert schema version if not exists
-	var version int
-	err := ps.db.QueryRow("SELECT version FROM schema_version ORDER BY version DESC LIMIT 1").Scan(&version)
-	if err == sql.ErrNoRows {
-		if _, err := ps.db.Exec("INSERT INTO schema_version (version) VALUES (1)"); err != nil {
-			return fmt.Errorf("failed to set schema version: %w", err)
+	if cfg.readOnly {
+		current, err := migrations.CurrentVersion(ps.db)
+		if err != nil {
+			return err
 		}
-	} else if err != nil {
-		return fmt.Errorf("failed to query schema version: %w", err)
+		if current > known {
+			return fmt.Errorf("%w: database at version %d, binary knows up to %d", ErrSchemaTooNew, current, known)
+		}
+		if current < known {
+			return fmt.Errorf("%w: database at version %d, registry expects %d", ErrSchemaTooOld, current, known)
+		}
+		return nil
 	}
 
+	if err := migrations.Run(ps.db, cfg.registry); err != nil {
+		return fmt.Errorf("failed to initialize schema: %w", err)
+	}
 	return nil
 }
 
-// SaveSession persists a session to the database
+// SaveSession persists a session to the database. Metadata is encrypted with
+// AES-256-GCM under a key derived from the service's own identity key, and
+// the row is signed with that same key, so a filesystem-level attacker can't
+// read or silently alter a session's recorded file path, size, or state
+// between daemon runs.
 func (ps *PersistentStore) SaveSession(session *Session) error {
+	_, span := otel.Tracer("quantarax-manager").Start(context.Background(), "persistence.save_session")
+	span.SetAttributes(attribute.String("session_id", session.ID))
+	defer span.End()
+
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
@@ -111,11 +157,29 @@ func (ps *PersistentStore) SaveSession(session *Session) error {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
+	priv, _, err := identity.LoadOrCreate("", "")
+	if err != nil {
+		return fmt.Errorf("failed to load identity keys: %w", err)
+	}
+	key := crypto.DeriveStorageKey(priv, metadataStorageLabel)
+
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate metadata nonce: %w", err)
+	}
+	ciphertext, err := crypto.Seal(key[:], nonce, []byte(session.ID), metadataJSON)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt metadata: %w", err)
+	}
+
+	signature := ed25519.Sign(priv, canonicalSessionRow(session.ID, session.FileSize, session.TotalChunks, session.State.String(), ciphertext))
+
 	query := `
-		INSERT OR REPLACE INTO transfer_sessions 
-		(session_id, file_path, file_name, file_size, chunk_size, total_chunks, 
-		 direction, state, created_at, updated_at, metadata)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT OR REPLACE INTO transfer_sessions
+		(session_id, file_path, file_name, file_size, chunk_size, total_chunks,
+		 direction, state, created_at, updated_at, metadata,
+		 metadata_nonce, metadata_ciphertext, row_signature)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, ?, ?, ?)
 	`
 
 	_, err = ps.db.Exec(query,
@@ -129,7 +193,9 @@ func (ps *PersistentStore) SaveSession(session *Session) error {
 		session.State.String(),
 		session.StartTime,
 		session.UpdateTime,
-		string(metadataJSON),
+		nonce,
+		ciphertext,
+		signature,
 	)
 
 	if err != nil {
@@ -139,35 +205,51 @@ func (ps *PersistentStore) SaveSession(session *Session) error {
 	return nil
 }
 
-// LoadSession retrieves a session from the database
+// LoadSession retrieves a session from the database, verifying its row
+// signature and decrypting its metadata. Rows written before metadata
+// encryption was introduced carry plaintext in the legacy "metadata" column
+// and no signature; LoadSession accepts those once, then transparently
+// re-saves the row through SaveSession so it's encrypted and signed from
+// then on (the one-shot "upgrade on first open" the migration framework
+// itself can't do, since Up only has a *sql.Tx, not the service's identity
+// key).
 func (ps *PersistentStore) LoadSession(sessionID string) (*Session, error) {
+	_, span := otel.Tracer("quantarax-manager").Start(context.Background(), "persistence.load_session")
+	span.SetAttributes(attribute.String("session_id", sessionID))
+	defer span.End()
+
 	ps.mu.RLock()
-	defer ps.mu.RUnlock()
 
 	var (
-		filePath     string
-		fileName     string
-		fileSize     int64
-		chunkSize    int64
-		totalChunks  int64
-		directionStr string
-		stateStr     string
-		createdAt    time.Time
-		updatedAt    time.Time
-		metadataJSON string
+		filePath           string
+		fileName           string
+		fileSize           int64
+		chunkSize          int64
+		totalChunks        int64
+		directionStr       string
+		stateStr           string
+		createdAt          time.Time
+		updatedAt          time.Time
+		legacyMetadata     sql.NullString
+		metadataNonce      []byte
+		metadataCiphertext []byte
+		rowSignature       []byte
 	)
 
 	query := `
 		SELECT file_path, file_name, file_size, chunk_size, total_chunks,
-		       direction, state, created_at, updated_at, metadata
+		       direction, state, created_at, updated_at, metadata,
+		       metadata_nonce, metadata_ciphertext, row_signature
 		FROM transfer_sessions
 		WHERE session_id = ?
 	`
 
 	err := ps.db.QueryRow(query, sessionID).Scan(
 		&filePath, &fileName, &fileSize, &chunkSize, &totalChunks,
-		&directionStr, &stateStr, &createdAt, &updatedAt, &metadataJSON,
+		&directionStr, &stateStr, &createdAt, &updatedAt, &legacyMetadata,
+		&metadataNonce, &metadataCiphertext, &rowSignature,
 	)
+	ps.mu.RUnlock()
 
 	if err == sql.ErrNoRows {
 		return nil, ErrSessionNotFound
@@ -175,6 +257,31 @@ func (ps *PersistentStore) LoadSession(sessionID string) (*Session, error) {
 		return nil, fmt.Errorf("failed to load session: %w", err)
 	}
 
+	var metadataJSON string
+	needsUpgrade := false
+
+	if len(metadataCiphertext) > 0 {
+		priv, _, err := identity.LoadOrCreate("", "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load identity keys: %w", err)
+		}
+
+		expectedSig := canonicalSessionRow(sessionID, fileSize, totalChunks, stateStr, metadataCiphertext)
+		if len(rowSignature) == 0 || !ed25519.Verify(priv.Public().(ed25519.PublicKey), expectedSig, rowSignature) {
+			return nil, ErrStoreTampered
+		}
+
+		key := crypto.DeriveStorageKey(priv, metadataStorageLabel)
+		plaintext, err := crypto.Open(key[:], metadataNonce, []byte(sessionID), metadataCiphertext)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrStoreTampered, err)
+		}
+		metadataJSON = string(plaintext)
+	} else if legacyMetadata.Valid {
+		metadataJSON = legacyMetadata.String
+		needsUpgrade = true
+	}
+
 	// Parse direction
 	var direction TransferDirection
 	switch directionStr {
@@ -224,16 +331,47 @@ func (ps *PersistentStore) LoadSession(sessionID string) (*Session, error) {
 		}
 	}
 
+	if needsUpgrade {
+		if err := ps.SaveSession(session); err != nil {
+			return nil, fmt.Errorf("failed to upgrade legacy plaintext session row: %w", err)
+		}
+	}
+
 	return session, nil
 }
 
-// UpdateSessionState updates only the session state
+// UpdateSessionState updates only the session state. Since state is part of
+// what row_signature covers, the signature is recomputed over the existing
+// file_size/total_chunks/metadata_ciphertext with the new state rather than
+// just updating the state column, so a later LoadSession doesn't mistake
+// this legitimate update for tampering.
 func (ps *PersistentStore) UpdateSessionState(sessionID string, newState TransferState) error {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	query := `UPDATE transfer_sessions SET state = ?, updated_at = ? WHERE session_id = ?`
-	result, err := ps.db.Exec(query, newState.String(), time.Now(), sessionID)
+	var fileSize, totalChunks int64
+	var metadataCiphertext []byte
+	err := ps.db.QueryRow(
+		"SELECT file_size, total_chunks, metadata_ciphertext FROM transfer_sessions WHERE session_id = ?",
+		sessionID,
+	).Scan(&fileSize, &totalChunks, &metadataCiphertext)
+	if err == sql.ErrNoRows {
+		return ErrSessionNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to read session for state update: %w", err)
+	}
+
+	var signature []byte
+	if len(metadataCiphertext) > 0 {
+		priv, _, err := identity.LoadOrCreate("", "")
+		if err != nil {
+			return fmt.Errorf("failed to load identity keys: %w", err)
+		}
+		signature = ed25519.Sign(priv, canonicalSessionRow(sessionID, fileSize, totalChunks, newState.String(), metadataCiphertext))
+	}
+
+	query := `UPDATE transfer_sessions SET state = ?, updated_at = ?, row_signature = ? WHERE session_id = ?`
+	result, err := ps.db.Exec(query, newState.String(), time.Now(), signature, sessionID)
 	if err != nil {
 		return fmt.Errorf("failed to update session state: %w", err)
 	}
@@ -288,10 +426,6 @@ func (ps *PersistentStore) DeleteSession(sessionID string) error {
 
 // ListSessions returns all sessions matching the filter
 func (ps *PersistentStore) ListSessions(filterState *TransferState, limit, offset int) ([]*Session, int, error) {
-	ps.mu.RLock()
-	defer ps.mu.RUnlock()
-
-	var sessions []*Session
 	var query string
 	var args []interface{}
 
@@ -304,19 +438,30 @@ func (ps *PersistentStore) ListSessions(filterState *TransferState, limit, offse
 		args = []interface{}{limit, offset}
 	}
 
+	ps.mu.RLock()
 	rows, err := ps.db.Query(query, args...)
 	if err != nil {
+		ps.mu.RUnlock()
 		return nil, 0, fmt.Errorf("failed to query sessions: %w", err)
 	}
-	defer rows.Close()
-
+	var sessionIDs []string
 	for rows.Next() {
 		var sessionID string
 		if err := rows.Scan(&sessionID); err != nil {
+			rows.Close()
+			ps.mu.RUnlock()
 			return nil, 0, fmt.Errorf("failed to scan session ID: %w", err)
 		}
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	rows.Close()
+	ps.mu.RUnlock()
 
-		// Load full session (inefficient but simple for now)
+	// LoadSession (called below, without ps.mu held) may itself need to take
+	// ps.mu.Lock() to upgrade a legacy plaintext row, so the session ID scan
+	// above must fully release the lock before this loop runs.
+	var sessions []*Session
+	for _, sessionID := range sessionIDs {
 		session, err := ps.LoadSession(sessionID)
 		if err != nil {
 			continue
@@ -341,6 +486,93 @@ func (ps *PersistentStore) ListSessions(filterState *TransferState, limit, offse
 	return sessions, total, nil
 }
 
+// ChunkProofRecord is a persisted per-chunk Merkle proof verification,
+// re-servable without recomputation after a daemon restart.
+type ChunkProofRecord struct {
+	SessionID  string
+	ChunkIndex int64
+	LeafHash   []byte
+	Proof      [][]byte
+	RootHash   []byte
+	Status     VerificationStatus
+	VerifiedAt time.Time
+}
+
+// SaveChunkProof persists (or replaces) the verification outcome for one
+// chunk's Merkle proof, keyed by (session_id, chunk_index).
+func (ps *PersistentStore) SaveChunkProof(sessionID string, chunkIndex int64, leaf []byte, proof [][]byte, root []byte, status VerificationStatus) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		return fmt.Errorf("failed to marshal proof: %w", err)
+	}
+
+	query := `
+		INSERT OR REPLACE INTO chunk_proofs
+		(session_id, chunk_index, leaf_hash, proof_json, root_hash, status, verified_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	if _, err := ps.db.Exec(query, sessionID, chunkIndex, leaf, string(proofJSON), root, status.String(), time.Now()); err != nil {
+		return fmt.Errorf("failed to save chunk proof: %w", err)
+	}
+	return nil
+}
+
+// GetChunkProof retrieves a previously persisted chunk proof verification.
+func (ps *PersistentStore) GetChunkProof(sessionID string, chunkIndex int64) (*ChunkProofRecord, error) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	var (
+		leafHash   []byte
+		proofJSON  string
+		rootHash   []byte
+		statusStr  string
+		verifiedAt time.Time
+	)
+
+	query := `
+		SELECT leaf_hash, proof_json, root_hash, status, verified_at
+		FROM chunk_proofs
+		WHERE session_id = ? AND chunk_index = ?
+	`
+	err := ps.db.QueryRow(query, sessionID, chunkIndex).Scan(&leafHash, &proofJSON, &rootHash, &statusStr, &verifiedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrChunkProofNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load chunk proof: %w", err)
+	}
+
+	var proof [][]byte
+	if err := json.Unmarshal([]byte(proofJSON), &proof); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal proof: %w", err)
+	}
+
+	var status VerificationStatus
+	switch statusStr {
+	case "SUCCESS":
+		status = VerificationSuccess
+	case "HASH_MISMATCH":
+		status = VerificationHashMismatch
+	case "CORRUPTION_DETECTED":
+		status = VerificationCorruptionDetected
+	default:
+		return nil, fmt.Errorf("invalid chunk proof status: %s", statusStr)
+	}
+
+	return &ChunkProofRecord{
+		SessionID:  sessionID,
+		ChunkIndex: chunkIndex,
+		LeafHash:   leafHash,
+		Proof:      proof,
+		RootHash:   rootHash,
+		Status:     status,
+		VerifiedAt: verifiedAt,
+	}, nil
+}
+
 // Close closes the database connection
 func (ps *PersistentStore) Close() error {
 	if ps.db != nil {
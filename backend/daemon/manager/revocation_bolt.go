@@ -0,0 +1,53 @@
+package manager
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// BoltRevocationStore persists revoked transfer session IDs so a revoked
+// token is rejected even after the daemon restarts.
+type BoltRevocationStore struct{ db *bolt.DB }
+
+var bucketRevocations = []byte("revoked_sessions")
+
+func OpenBoltRevocationStore(path string) (*BoltRevocationStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error { _, e := tx.CreateBucketIfNotExists(bucketRevocations); return e })
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltRevocationStore{db: db}, nil
+}
+
+func (b *BoltRevocationStore) Close() error { return b.db.Close() }
+
+// Revoke marks sessionID's outstanding transfer tokens as no longer acceptable.
+func (b *BoltRevocationStore) Revoke(sessionID string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucketRevocations)
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, uint64(time.Now().Unix()))
+		return bk.Put([]byte(sessionID), buf)
+	})
+}
+
+// IsRevoked reports whether sessionID's transfer tokens have been revoked.
+func (b *BoltRevocationStore) IsRevoked(sessionID string) bool {
+	var revoked bool
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucketRevocations)
+		if bk == nil {
+			return nil
+		}
+		revoked = bk.Get([]byte(sessionID)) != nil
+		return nil
+	})
+	return revoked
+}
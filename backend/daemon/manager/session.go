@@ -14,6 +14,14 @@ const (
 	StatePaused
 	StateCompleted
 	StateFailed
+	// StateResuming is the state a session lands in when the daemon starts
+	// up and finds its lease expired (i.e. the process died) while it was
+	// StateActive: the client's already-persisted chunk bitmap is still
+	// good, so the session is resumable rather than failed, but it isn't
+	// "active" again until a peer actually reconnects and re-acquires the
+	// lease. See manager.LeaseStore.ReclaimExpired and
+	// session.SessionManager.reconcile.
+	StateResuming
 )
 
 func (s TransferState) String() string {
@@ -28,6 +36,8 @@ func (s TransferState) String() string {
 		return "COMPLETED"
 	case StateFailed:
 		return "FAILED"
+	case StateResuming:
+		return "RESUMING"
 	default:
 		return "UNKNOWN"
 	}
@@ -69,11 +79,34 @@ type Session struct {
 	ErrorMessage      string
 	Metadata          map[string]string
 
+	// ParentBundleID is set on child sessions created by a directory/
+	// multi-file bundle transfer, pointing at the bundle-level session's ID
+	// so SessionStore.BundleProgress can aggregate their progress.
+	ParentBundleID string
+
 	// Transfer metrics
 	transferRateSamples  []float64
 	lastUpdateTime       time.Time
 	lastBytesTransferred int64
 
+	// Raw bandwidth accounting, reported by a transport.BandwidthMeter via
+	// UpdateBandwidth: rawBytesSent/Recv include protocol overhead and
+	// retransmits that BytesTransferred (payload only) never does.
+	// lastUsefulBytesOut is the previous snapshot's useful-byte count, so
+	// each UpdateBandwidth call can derive a per-interval throughput sample.
+	rawBytesSent       int64
+	rawBytesRecv       int64
+	lastUsefulBytesOut int64
+	rateSamples        []float64
+
+	// OnTransition, if set, is called after a successful TransitionTo with
+	// the old and new state. It lets other packages (e.g. statesync) react
+	// to state changes - a StatePaused->StateActive move triggering a
+	// snapshot-based resume, for instance - without Session or this package
+	// depending on them. Invoked outside s.mu, so a hook is free to call
+	// back into the session (e.g. GetState) without deadlocking.
+	OnTransition func(from, to TransferState)
+
 	mu sync.RWMutex
 }
 
@@ -171,13 +204,13 @@ func (s *Session) GetEstimatedTimeRemaining() int64 {
 // TransitionTo transitions the session to a new state
 func (s *Session) TransitionTo(newState TransferState, errorMsg string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	// Validate state transition
 	validTransitions := map[TransferState][]TransferState{
 		StatePending:   {StateActive, StateFailed},
 		StateActive:    {StatePaused, StateCompleted, StateFailed},
 		StatePaused:    {StateActive, StateFailed},
+		StateResuming:  {StateActive, StateFailed},
 		StateCompleted: {},
 		StateFailed:    {},
 	}
@@ -192,18 +225,39 @@ func (s *Session) TransitionTo(newState TransferState, errorMsg string) error {
 	}
 
 	if !isValid {
+		s.mu.Unlock()
 		return ErrInvalidStateTransition
 	}
 
+	oldState := s.State
 	s.State = newState
 	s.UpdateTime = time.Now()
 	if errorMsg != "" {
 		s.ErrorMessage = errorMsg
 	}
+	hook := s.OnTransition
+	s.mu.Unlock()
+
+	if hook != nil {
+		hook(oldState, newState)
+	}
 
 	return nil
 }
 
+// RehydrateState force-sets the session's state without going through
+// TransitionTo's validTransitions check or firing OnTransition. It exists
+// for crash-recovery paths (see session.SessionManager.reclaimLeases)
+// that need to move a session into StateResuming from whatever state a
+// killed process left it in, which by definition isn't one of the states
+// TransitionTo's table would normally allow moving out of freely.
+func (s *Session) RehydrateState(newState TransferState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.State = newState
+	s.UpdateTime = time.Now()
+}
+
 // GetState returns current state (thread-safe)
 func (s *Session) GetState() TransferState {
 	s.mu.RLock()
@@ -0,0 +1,73 @@
+package manager
+
+// bandwidthRateSampleCap bounds how many 1-second throughput samples
+// RateHistogram considers, keeping it a rolling window rather than an
+// unbounded history.
+const bandwidthRateSampleCap = 60
+
+// RateHistogram buckets a session's recent throughput samples (useful
+// bytes/sec) by log10-ish magnitude, so operators can see the shape of a
+// transfer's rate distribution (steady vs. bursty) at a glance instead of
+// just its current instantaneous rate.
+type RateHistogram struct {
+	Buckets map[string]int `json:"buckets"`
+}
+
+// bandwidthHistogramBucket labels a throughput sample by its log-scale
+// magnitude.
+func bandwidthHistogramBucket(bytesPerSec float64) string {
+	switch {
+	case bytesPerSec <= 0:
+		return "0"
+	case bytesPerSec < 1<<10:
+		return "<1KB/s"
+	case bytesPerSec < 1<<20:
+		return "<1MB/s"
+	case bytesPerSec < 10*(1<<20):
+		return "<10MB/s"
+	case bytesPerSec < 100*(1<<20):
+		return "<100MB/s"
+	default:
+		return ">=100MB/s"
+	}
+}
+
+// UpdateBandwidth records the latest raw/useful byte counters reported by a
+// transport.BandwidthMeter snapshot (raw bytes include protocol overhead and
+// retransmits; BytesTransferred, updated separately by UpdateProgress, never
+// does) and appends the throughput delta since the last snapshot as a
+// RateHistogram sample.
+func (s *Session) UpdateBandwidth(rawOut, rawIn, usefulOut int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delta := usefulOut - s.lastUsefulBytesOut
+	s.lastUsefulBytesOut = usefulOut
+	s.rawBytesSent = rawOut
+	s.rawBytesRecv = rawIn
+
+	s.rateSamples = append(s.rateSamples, float64(delta))
+	if len(s.rateSamples) > bandwidthRateSampleCap {
+		s.rateSamples = s.rateSamples[len(s.rateSamples)-bandwidthRateSampleCap:]
+	}
+}
+
+// GetRawBandwidth returns the most recent raw bytes sent/received a
+// BandwidthMeter has reported for this session.
+func (s *Session) GetRawBandwidth() (rawOut, rawIn int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rawBytesSent, s.rawBytesRecv
+}
+
+// GetRateHistogram buckets the last bandwidthRateSampleCap throughput
+// samples log-scale.
+func (s *Session) GetRateHistogram() RateHistogram {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h := RateHistogram{Buckets: make(map[string]int)}
+	for _, sample := range s.rateSamples {
+		h.Buckets[bandwidthHistogramBucket(sample)]++
+	}
+	return h
+}
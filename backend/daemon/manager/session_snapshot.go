@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// sessionSnapshot is the on-disk resume-state representation of a Session:
+// every exported field except the non-serializable OnTransition hook.
+type sessionSnapshot struct {
+	ID                string            `json:"id"`
+	FilePath          string            `json:"file_path"`
+	FileName          string            `json:"file_name"`
+	FileSize          int64             `json:"file_size"`
+	ChunkSize         int64             `json:"chunk_size"`
+	TotalChunks       int64             `json:"total_chunks"`
+	State             TransferState     `json:"state"`
+	Direction         TransferDirection `json:"direction"`
+	BytesTransferred  int64             `json:"bytes_transferred"`
+	ChunksTransferred int64             `json:"chunks_transferred"`
+	StartTime         time.Time         `json:"start_time"`
+	UpdateTime        time.Time         `json:"update_time"`
+	ErrorMessage      string            `json:"error_message"`
+	Metadata          map[string]string `json:"metadata"`
+	ParentBundleID    string            `json:"parent_bundle_id"`
+}
+
+// PersistSnapshot writes every session currently in the store to path as
+// JSON, so an admin-triggered restart can restore resume state in the new
+// process via RestoreSnapshot.
+func (s *SessionStore) PersistSnapshot(path string) error {
+	s.mu.RLock()
+	snaps := make([]sessionSnapshot, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sess.mu.RLock()
+		snaps = append(snaps, sessionSnapshot{
+			ID:                sess.ID,
+			FilePath:          sess.FilePath,
+			FileName:          sess.FileName,
+			FileSize:          sess.FileSize,
+			ChunkSize:         sess.ChunkSize,
+			TotalChunks:       sess.TotalChunks,
+			State:             sess.State,
+			Direction:         sess.Direction,
+			BytesTransferred:  sess.BytesTransferred,
+			ChunksTransferred: sess.ChunksTransferred,
+			StartTime:         sess.StartTime,
+			UpdateTime:        sess.UpdateTime,
+			ErrorMessage:      sess.ErrorMessage,
+			Metadata:          sess.Metadata,
+			ParentBundleID:    sess.ParentBundleID,
+		})
+		sess.mu.RUnlock()
+	}
+	s.mu.RUnlock()
+
+	data, err := json.Marshal(snaps)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// RestoreSnapshot loads sessions previously written by PersistSnapshot into
+// s, skipping any ID already present. A missing file is not an error, since
+// a daemon with nothing to resume starts clean.
+func (s *SessionStore) RestoreSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var snaps []sessionSnapshot
+	if err := json.Unmarshal(data, &snaps); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, snap := range snaps {
+		if _, exists := s.sessions[snap.ID]; exists {
+			continue
+		}
+		s.sessions[snap.ID] = &Session{
+			ID:                snap.ID,
+			FilePath:          snap.FilePath,
+			FileName:          snap.FileName,
+			FileSize:          snap.FileSize,
+			ChunkSize:         snap.ChunkSize,
+			TotalChunks:       snap.TotalChunks,
+			State:             snap.State,
+			Direction:         snap.Direction,
+			BytesTransferred:  snap.BytesTransferred,
+			ChunksTransferred: snap.ChunksTransferred,
+			StartTime:         snap.StartTime,
+			UpdateTime:        snap.UpdateTime,
+			ErrorMessage:      snap.ErrorMessage,
+			Metadata:          snap.Metadata,
+			ParentBundleID:    snap.ParentBundleID,
+		}
+	}
+	return nil
+}
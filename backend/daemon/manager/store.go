@@ -125,6 +125,33 @@ func (s *SessionStore) CleanupOldSessions(maxAge time.Duration) int {
 	return removed
 }
 
+// ByParentBundle returns every session whose ParentBundleID matches bundleID,
+// i.e. the per-file child sessions of a directory/multi-file bundle transfer.
+func (s *SessionStore) ByParentBundle(bundleID string) []*Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var children []*Session
+	for _, session := range s.sessions {
+		if session.ParentBundleID == bundleID {
+			children = append(children, session)
+		}
+	}
+	return children
+}
+
+// BundleProgress aggregates the bytes/chunks transferred and totals across
+// every child session of the bundle transfer identified by bundleID.
+func (s *SessionStore) BundleProgress(bundleID string) (bytesTransferred, totalBytes, chunksTransferred, totalChunks int64) {
+	for _, child := range s.ByParentBundle(bundleID) {
+		bytesTransferred += child.BytesTransferred
+		totalBytes += child.FileSize
+		chunksTransferred += child.ChunksTransferred
+		totalChunks += child.TotalChunks
+	}
+	return
+}
+
 // Count returns the total number of sessions
 func (s *SessionStore) Count() int {
 	s.mu.RLock()
@@ -1,10 +1,19 @@
 package manager
 
 import (
+	"bytes"
+	"context"
 	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
+
+	"github.com/quantarax/backend/internal/chunker"
+	"github.com/quantarax/backend/internal/crypto/identity"
+	"github.com/zeebo/blake3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // VerificationStatus represents the result of Merkle root verification
@@ -38,18 +47,82 @@ type VerificationResult struct {
 	Timestamp          time.Time
 	Signature          []byte
 	PublicKey          []byte
+
+	// CorruptChunks is populated by VerifyReceivedFile on a mismatch: the
+	// chunk indices whose on-disk bytes were bisected as the source of the
+	// root divergence, so a caller can mark just those unreceived and
+	// re-request them instead of redownloading the whole file.
+	CorruptChunks []int64
 }
 
 // MerkleVerifier handles Merkle root verification for transfers
-type MerkleVerifier struct{}
+type MerkleVerifier struct {
+	store *PersistentStore
+
+	mu            sync.RWMutex
+	expectedRoots map[string][]byte // sessionID -> manifest Merkle root
+}
 
-// NewMerkleVerifier creates a new Merkle verifier
+// NewMerkleVerifier creates a new Merkle verifier with no persistence; chunk
+// proofs verified through it are not re-servable after a restart.
 func NewMerkleVerifier() *MerkleVerifier {
-	return &MerkleVerifier{}
+	return &MerkleVerifier{expectedRoots: make(map[string][]byte)}
+}
+
+// NewMerkleVerifierWithStore creates a Merkle verifier that persists every
+// chunk proof it verifies to store's chunk_proofs table, so chunk-level
+// verification survives a daemon restart.
+func NewMerkleVerifierWithStore(store *PersistentStore) *MerkleVerifier {
+	return &MerkleVerifier{store: store, expectedRoots: make(map[string][]byte)}
+}
+
+// SetExpectedRoot records the manifest Merkle root sessionID's chunk proofs
+// should be checked against. Callers (e.g. ChunkReceiver) set this once the
+// signed manifest is known, before any VerifyChunkProof call for that session.
+func (mv *MerkleVerifier) SetExpectedRoot(sessionID string, root []byte) {
+	mv.mu.Lock()
+	defer mv.mu.Unlock()
+	mv.expectedRoots[sessionID] = root
+}
+
+// VerifyChunkProof checks leaf and its authentication path proof against the
+// Merkle root recorded for sessionID (via SetExpectedRoot), letting a
+// receiver reject a corrupted chunk as soon as its proof arrives rather than
+// waiting for the whole file. If a PersistentStore was supplied, the result
+// is saved to chunk_proofs so it can be re-served without re-verifying after
+// a restart.
+func (mv *MerkleVerifier) VerifyChunkProof(sessionID string, chunkIndex int64, leaf []byte, proof [][]byte) VerificationStatus {
+	mv.mu.RLock()
+	root, ok := mv.expectedRoots[sessionID]
+	mv.mu.RUnlock()
+
+	var status VerificationStatus
+	if !ok {
+		status = VerificationHashMismatch
+	} else if chunker.VerifyProof(leaf, int(chunkIndex), proof, root) {
+		status = VerificationSuccess
+	} else {
+		status = VerificationHashMismatch
+	}
+
+	if mv.store != nil {
+		_ = mv.store.SaveChunkProof(sessionID, chunkIndex, leaf, proof, root, status)
+	}
+
+	return status
 }
 
 // VerifyMerkleRoot verifies that computed Merkle root matches expected
 func (mv *MerkleVerifier) VerifyMerkleRoot(computed, expected []byte) VerificationStatus {
+	_, span := otel.Tracer("quantarax-manager").Start(context.Background(), "merkle.verify_root")
+	defer span.End()
+
+	status := mv.verifyMerkleRoot(computed, expected)
+	span.SetAttributes(attribute.String("status", status.String()))
+	return status
+}
+
+func (mv *MerkleVerifier) verifyMerkleRoot(computed, expected []byte) VerificationStatus {
 	if len(computed) != len(expected) {
 		return VerificationCorruptionDetected
 	}
@@ -64,11 +137,38 @@ func (mv *MerkleVerifier) VerifyMerkleRoot(computed, expected []byte) Verificati
 	return VerificationSuccess
 }
 
-// SignVerificationResult signs the verification result with Ed25519
-func (mv *MerkleVerifier) SignVerificationResult(
-	result *VerificationResult,
-	privateKey ed25519.PrivateKey,
-) error {
+// VerifyMerkleProof recomputes the Merkle root from leaf by folding in path,
+// the sibling-hash authentication path produced by chunker.BuildMerkleProof,
+// and reports whether it matches root. index is the leaf's position among
+// the manifest's chunk hashes and determines left/right ordering at each
+// level, mirroring chunker.ComputeMerkleRoot's pairing.
+func VerifyMerkleProof(leaf, root []byte, path [][]byte, index int) bool {
+	current := leaf
+	idx := index
+	for _, sibling := range path {
+		var combined []byte
+		if idx%2 == 0 {
+			combined = append(append([]byte{}, current...), sibling...)
+		} else {
+			combined = append(append([]byte{}, sibling...), current...)
+		}
+		h := blake3.Sum256(combined)
+		current = h[:]
+		idx /= 2
+	}
+	return bytes.Equal(current, root)
+}
+
+// SignVerificationResult signs result with this service's own identity
+// keypair (loaded or created via identity.LoadOrCreate), so a verification
+// result is always signed by the instance that actually performed the
+// verification rather than whatever key a caller happens to pass in.
+func (mv *MerkleVerifier) SignVerificationResult(result *VerificationResult) error {
+	privateKey, _, err := identity.LoadOrCreate("", "")
+	if err != nil {
+		return fmt.Errorf("failed to load identity keys: %w", err)
+	}
+
 	// Create canonical JSON for signing
 	canonical, err := json.Marshal(map[string]interface{}{
 		"session_id":           result.SessionID,
@@ -0,0 +1,141 @@
+package manager
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook subscriber not found")
+)
+
+// WebhookSubscriber is an outbound HTTP push target registered against the
+// TransferEvent stream: every event matching EventTypes (all events, if
+// empty) and SessionIDFilter (every session, if empty) is POSTed to URL,
+// signed with Secret. AuthToken, if set, is sent as a Bearer token so
+// Splunk-HEC-style endpoints that require it can be targeted directly.
+type WebhookSubscriber struct {
+	ID              string
+	URL             string
+	Secret          string
+	EventTypes      []string
+	SessionIDFilter string
+	AuthToken       string
+	CreateTime      time.Time
+
+	mu               sync.RWMutex
+	deliveredCount   int64
+	failedCount      int64
+	deadLetterCount  int64
+	lastError        string
+	lastDeliveryTime time.Time
+}
+
+// WebhookStatus is a point-in-time snapshot of a subscriber's delivery
+// counters, safe to read without holding the subscriber's lock.
+type WebhookStatus struct {
+	ID               string
+	URL              string
+	DeliveredCount   int64
+	FailedCount      int64
+	DeadLetterCount  int64
+	LastError        string
+	LastDeliveryTime time.Time
+}
+
+// RecordDelivered marks one successful delivery attempt.
+func (w *WebhookSubscriber) RecordDelivered() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deliveredCount++
+	w.lastDeliveryTime = time.Now()
+	w.lastError = ""
+}
+
+// RecordFailed marks one failed delivery attempt (about to be retried).
+func (w *WebhookSubscriber) RecordFailed(errMsg string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.failedCount++
+	w.lastError = errMsg
+}
+
+// RecordDeadLetter marks an event as permanently undeliverable after
+// exhausting retries.
+func (w *WebhookSubscriber) RecordDeadLetter(errMsg string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deadLetterCount++
+	w.lastError = errMsg
+}
+
+// Status returns a snapshot of w's current delivery counters.
+func (w *WebhookSubscriber) Status() WebhookStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return WebhookStatus{
+		ID:               w.ID,
+		URL:              w.URL,
+		DeliveredCount:   w.deliveredCount,
+		FailedCount:      w.failedCount,
+		DeadLetterCount:  w.deadLetterCount,
+		LastError:        w.lastError,
+		LastDeliveryTime: w.lastDeliveryTime,
+	}
+}
+
+// WebhookStore manages in-memory webhook subscriber registrations, mirroring
+// SessionStore's map-plus-mutex shape.
+type WebhookStore struct {
+	subscribers map[string]*WebhookSubscriber
+	mu          sync.RWMutex
+}
+
+// NewWebhookStore creates a new, empty webhook store.
+func NewWebhookStore() *WebhookStore {
+	return &WebhookStore{
+		subscribers: make(map[string]*WebhookSubscriber),
+	}
+}
+
+// Add registers a new webhook subscriber.
+func (s *WebhookStore) Add(sub *WebhookSubscriber) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers[sub.ID] = sub
+	return nil
+}
+
+// Get retrieves a webhook subscriber by ID.
+func (s *WebhookStore) Get(id string) (*WebhookSubscriber, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sub, exists := s.subscribers[id]
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+	return sub, nil
+}
+
+// Delete removes a webhook subscriber.
+func (s *WebhookStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.subscribers[id]; !exists {
+		return ErrWebhookNotFound
+	}
+	delete(s.subscribers, id)
+	return nil
+}
+
+// List returns every registered webhook subscriber.
+func (s *WebhookStore) List() []*WebhookSubscriber {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subs := make([]*WebhookSubscriber, 0, len(s.subscribers))
+	for _, sub := range s.subscribers {
+		subs = append(subs, sub)
+	}
+	return subs
+}
@@ -0,0 +1,31 @@
+package reassembler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrAADMismatch means an incoming chunk's associated data doesn't encode
+// the (session_id, chunk_index) pair it claims to be for.
+var ErrAADMismatch = errors.New("reassembler: chunk AAD does not match session/chunk index")
+
+// ValidateAAD checks that aad is exactly the session-ID-plus-chunk-index
+// construction ChunkReceiver builds before calling crypto.Open (16-byte
+// session ID followed by an 8-byte big-endian chunk index), so a
+// misaddressed or replayed chunk is rejected before the more expensive
+// decrypt call rather than after.
+func ValidateAAD(aad []byte, sessionID uuid.UUID, chunkIndex int64) error {
+	if len(aad) != 16+8 {
+		return ErrAADMismatch
+	}
+	if !bytes.Equal(aad[0:16], sessionID[:]) {
+		return ErrAADMismatch
+	}
+	if int64(binary.BigEndian.Uint64(aad[16:24])) != chunkIndex {
+		return ErrAADMismatch
+	}
+	return nil
+}
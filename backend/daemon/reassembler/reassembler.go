@@ -0,0 +1,275 @@
+// Package reassembler provides receiver-side bookkeeping for
+// partially-received transfer sessions. The sender side pipelines chunks
+// across several worker pools with no ordering guarantee, so a receiver
+// needs to track which chunk indices have arrived, buffer ones that showed
+// up out of order, and bound how much of that buffering it's willing to do
+// per session and across all sessions combined.
+package reassembler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quantarax/backend/daemon/manager"
+	"github.com/quantarax/backend/daemon/service"
+)
+
+var _ EventPublisher = (*service.EventPublisher)(nil)
+
+var (
+	ErrUnknownSession        = errors.New("reassembler: chunk for unregistered session")
+	ErrChunkIndexOutOfRange  = errors.New("reassembler: chunk index exceeds session's total chunks")
+	ErrPendingChunksExceeded = errors.New("reassembler: session exceeded MaxPendingChunks")
+)
+
+// EventPublisher is the subset of service.EventPublisher the reassembler
+// needs to report a session's reassembly failure, mirroring the interface
+// statesync.EventPublisher uses, so a nil publisher or a test double can
+// stand in for it. The assertion above checks that service.EventPublisher
+// satisfies it.
+type EventPublisher interface {
+	PublishFailed(sessionID, errorMessage string)
+}
+
+// Config tunes SessionReassembler's memory and time budgets, borrowed from
+// Pulsar's chunked-message design: a per-session cap on chunks buffered
+// ahead of the next one due to be written, a global cap on bytes buffered
+// across every session, and a TTL after which a stalled session is
+// garbage-collected.
+type Config struct {
+	MaxPendingChunks int
+	MaxPendingBytes  int64
+	TTL              time.Duration
+}
+
+// ReadyChunk is one chunk that's now contiguous with its session's
+// last-written chunk and safe to write to disk.
+type ReadyChunk struct {
+	Index int64
+	Data  []byte
+}
+
+// Stats summarizes the reassembler's current load, for metrics.
+type Stats struct {
+	PendingSessions int
+	PendingBytes    int64
+	DroppedChunks   int64
+}
+
+// pendingSession is one session's out-of-order chunk buffer.
+type pendingSession struct {
+	session   *manager.Session
+	nextWrite int64
+	buffered  map[int64][]byte
+	bytes     int64
+	lastSeen  time.Time
+}
+
+// SessionReassembler tracks, per session, which chunk indices have arrived,
+// buffering chunks that arrive ahead of the one the receiver is waiting to
+// write next until the gap closes, subject to MaxPendingChunks per session
+// and MaxPendingBytes across all sessions combined.
+type SessionReassembler struct {
+	cfg    Config
+	events EventPublisher
+
+	mu            sync.Mutex
+	sessions      map[string]*pendingSession
+	totalBytes    int64
+	droppedChunks int64
+}
+
+// NewSessionReassembler creates a reassembler. events may be nil, in which
+// case reassembly failures still transition the affected Session to
+// StateFailed but nothing is published.
+func NewSessionReassembler(cfg Config, events EventPublisher) *SessionReassembler {
+	return &SessionReassembler{
+		cfg:      cfg,
+		events:   events,
+		sessions: make(map[string]*pendingSession),
+	}
+}
+
+// Register starts tracking sess, giving subsequent Submit calls for its ID
+// somewhere to buffer out-of-order chunks and a Session to transition on a
+// budget violation or timeout.
+func (r *SessionReassembler) Register(sess *manager.Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[sess.ID] = &pendingSession{
+		session:  sess,
+		buffered: make(map[int64][]byte),
+		lastSeen: time.Now(),
+	}
+}
+
+// Forget stops tracking sessionID, releasing any bytes it had buffered. Call
+// it once a session completes normally, so its budget is freed without
+// waiting for TTL expiry.
+func (r *SessionReassembler) Forget(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.forgetLocked(sessionID)
+}
+
+func (r *SessionReassembler) forgetLocked(sessionID string) {
+	if ps, ok := r.sessions[sessionID]; ok {
+		r.totalBytes -= ps.bytes
+		delete(r.sessions, sessionID)
+	}
+}
+
+// Submit records that chunkIndex (whose AAD has already been validated by
+// the caller via ValidateAAD, and whose payload has already been decrypted)
+// arrived for sessionID. It returns, in increasing index order, every chunk
+// that is now contiguous with the session's last-written chunk and can be
+// flushed to disk; chunks that arrived ahead of a gap are buffered and
+// returned by a later Submit call once the gap closes.
+func (r *SessionReassembler) Submit(sessionID uuid.UUID, chunkIndex int64, payload []byte) ([]ReadyChunk, error) {
+	key := sessionID.String()
+
+	r.mu.Lock()
+
+	ps, ok := r.sessions[key]
+	if !ok {
+		r.mu.Unlock()
+		return nil, ErrUnknownSession
+	}
+
+	if total := ps.session.TotalChunks; total > 0 && chunkIndex >= total {
+		r.mu.Unlock()
+		return nil, ErrChunkIndexOutOfRange
+	}
+
+	ps.lastSeen = time.Now()
+
+	if chunkIndex < ps.nextWrite {
+		// Duplicate of a chunk already written; nothing new to buffer.
+		r.mu.Unlock()
+		return nil, nil
+	}
+
+	if _, dup := ps.buffered[chunkIndex]; !dup {
+		if chunkIndex != ps.nextWrite && r.cfg.MaxPendingChunks > 0 && len(ps.buffered) >= r.cfg.MaxPendingChunks {
+			r.droppedChunks++
+			r.mu.Unlock()
+			return nil, ErrPendingChunksExceeded
+		}
+		ps.buffered[chunkIndex] = payload
+		ps.bytes += int64(len(payload))
+		r.totalBytes += int64(len(payload))
+	}
+
+	var ready []ReadyChunk
+	for {
+		data, ok := ps.buffered[ps.nextWrite]
+		if !ok {
+			break
+		}
+		ready = append(ready, ReadyChunk{Index: ps.nextWrite, Data: data})
+		delete(ps.buffered, ps.nextWrite)
+		ps.bytes -= int64(len(data))
+		r.totalBytes -= int64(len(data))
+		ps.nextWrite++
+	}
+
+	var evictID string
+	if r.cfg.MaxPendingBytes > 0 && r.totalBytes > r.cfg.MaxPendingBytes {
+		evictID = r.oldestSessionLocked()
+	}
+
+	r.mu.Unlock()
+
+	if evictID != "" {
+		r.failSession(evictID, "reassembly memory budget exceeded")
+	}
+
+	return ready, nil
+}
+
+// oldestSessionLocked returns the tracked session ID with the oldest
+// lastSeen timestamp - the one a MaxPendingBytes violation evicts first, per
+// Pulsar's oldest-incomplete-session-first policy. Caller must hold r.mu.
+func (r *SessionReassembler) oldestSessionLocked() string {
+	var oldestID string
+	var oldestTime time.Time
+	for id, ps := range r.sessions {
+		if oldestID == "" || ps.lastSeen.Before(oldestTime) {
+			oldestID = id
+			oldestTime = ps.lastSeen
+		}
+	}
+	return oldestID
+}
+
+// failSession transitions sessionID's Session to StateFailed, forgets its
+// buffered chunks, and publishes EventFailed with reason.
+func (r *SessionReassembler) failSession(sessionID, reason string) {
+	r.mu.Lock()
+	ps, ok := r.sessions[sessionID]
+	if ok {
+		r.forgetLocked(sessionID)
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	_ = ps.session.TransitionTo(manager.StateFailed, reason)
+	if r.events != nil {
+		r.events.PublishFailed(sessionID, reason)
+	}
+}
+
+// GC fails and forgets every tracked session whose lastSeen is older than
+// cfg.TTL, publishing EventFailed with reason "reassembly timeout". A
+// zero TTL disables expiry.
+func (r *SessionReassembler) GC() {
+	if r.cfg.TTL <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-r.cfg.TTL)
+
+	r.mu.Lock()
+	var expired []string
+	for id, ps := range r.sessions {
+		if ps.lastSeen.Before(cutoff) {
+			expired = append(expired, id)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, id := range expired {
+		r.failSession(id, "reassembly timeout")
+	}
+}
+
+// StartGC runs GC every interval until ctx is canceled.
+func (r *SessionReassembler) StartGC(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.GC()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stats reports the reassembler's current load.
+func (r *SessionReassembler) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Stats{
+		PendingSessions: len(r.sessions),
+		PendingBytes:    r.totalBytes,
+		DroppedChunks:   r.droppedChunks,
+	}
+}
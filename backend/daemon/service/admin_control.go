@@ -0,0 +1,165 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/quantarax/backend/daemon/config"
+	"github.com/quantarax/backend/daemon/transport"
+	"github.com/quantarax/backend/internal/crypto/identity"
+	"github.com/quantarax/backend/internal/observability"
+)
+
+// activeSenders tracks the OrchestratedSender for each in-flight transfer
+// session, keyed by SessionID, so an AdminCommand naming SessionIDs can
+// reach the right sender without SendWithOrchestration needing to hand one
+// back to its caller. RegisterSender/UnregisterSender bracket
+// SendWithOrchestration's NewOrchestratedSender/orch.Close the same way
+// InitCAS/InitDTN bracket their own package-level state.
+var (
+	activeSendersMu sync.RWMutex
+	activeSenders   = make(map[string]*transport.OrchestratedSender)
+)
+
+// RegisterSender makes orch reachable by sessionID for AdminActionPause/
+// AdminActionResume.
+func RegisterSender(sessionID string, orch *transport.OrchestratedSender) {
+	activeSendersMu.Lock()
+	defer activeSendersMu.Unlock()
+	activeSenders[sessionID] = orch
+}
+
+// UnregisterSender removes sessionID's entry, once its transfer's
+// SendWithOrchestration call returns.
+func UnregisterSender(sessionID string) {
+	activeSendersMu.Lock()
+	defer activeSendersMu.Unlock()
+	delete(activeSenders, sessionID)
+}
+
+// lookupSenders resolves sessionIDs to their registered senders, skipping
+// any session that isn't (or is no longer) active. An empty sessionIDs
+// selects every currently active sender, so AdminActionPause/
+// AdminActionResume with no SessionIDs applies to the whole daemon.
+func lookupSenders(sessionIDs []string) []*transport.OrchestratedSender {
+	activeSendersMu.RLock()
+	defer activeSendersMu.RUnlock()
+	if len(sessionIDs) == 0 {
+		out := make([]*transport.OrchestratedSender, 0, len(activeSenders))
+		for _, s := range activeSenders {
+			out = append(out, s)
+		}
+		return out
+	}
+	var out []*transport.OrchestratedSender
+	for _, id := range sessionIDs {
+		if s, ok := activeSenders[id]; ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// DrainAllSenders pauses every currently registered transfer sender, same
+// selection as AdminActionPause with an empty SessionIDs. Used by an
+// admin-triggered restart so in-flight sends stop cleanly without losing
+// queued progress, ahead of persisting the session snapshot those paused
+// transfers resume from after the restart.
+func DrainAllSenders() {
+	for _, s := range lookupSenders(nil) {
+		s.Pause()
+	}
+}
+
+// AdminHandler verifies and applies transport.AdminCommand messages
+// received over a transfer's control stream. It's the control-stream
+// counterpart to server.AdminController (which drives the same restart/
+// reload over REST, see main.go's daemonAdminController), plus the pause,
+// resume, and set-config actions that REST channel doesn't expose.
+type AdminHandler struct {
+	cfg     *config.Config
+	logger  *observability.Logger
+	restart func() error
+}
+
+// NewAdminHandler creates an AdminHandler. restart is called for
+// AdminActionRestart, e.g. daemonAdminController.Restart; a nil restart
+// makes AdminActionRestart always fail with "restart not configured",
+// the same as AdminController left unset makes the REST restart endpoint
+// answer 501.
+func NewAdminHandler(cfg *config.Config, logger *observability.Logger, restart func() error) *AdminHandler {
+	return &AdminHandler{cfg: cfg, logger: logger, restart: restart}
+}
+
+// Handle verifies cmd's signature against h.cfg's admin allowlist and, only
+// if that passes, applies it — a command that fails verification is never
+// applied, regardless of what Action it names.
+func (h *AdminHandler) Handle(cmd *transport.AdminCommand) *transport.AdminAck {
+	ack := &transport.AdminAck{Action: cmd.Action, Timestamp: time.Now().Unix()}
+
+	if !transport.VerifyAdminCommandSignature(cmd) || !h.cfg.IsAdminKey(cmd.PublicKey) {
+		ack.Error = "unauthorized"
+		if h.logger != nil {
+			h.logger.Warn("rejected admin command: signature invalid or public key not in admin allowlist")
+		}
+		return ack
+	}
+
+	switch cmd.Action {
+	case transport.AdminActionPause:
+		for _, s := range lookupSenders(cmd.SessionIDs) {
+			s.Pause()
+		}
+	case transport.AdminActionResume:
+		for _, s := range lookupSenders(cmd.SessionIDs) {
+			s.Resume()
+		}
+	case transport.AdminActionSetConfig:
+		h.applyConfigPatch(cmd.Config)
+	case transport.AdminActionRotateKeys:
+		privPath, pubPath, err := identity.DefaultPaths()
+		if err == nil {
+			_, _, err = identity.Rotate(privPath, pubPath)
+		}
+		if err != nil {
+			ack.Error = err.Error()
+			return ack
+		}
+	case transport.AdminActionRestart:
+		if h.restart == nil {
+			ack.Error = "restart not configured"
+			return ack
+		}
+		if err := h.restart(); err != nil {
+			ack.Error = err.Error()
+			return ack
+		}
+	default:
+		ack.Error = "unknown admin action"
+		return ack
+	}
+
+	ack.OK = true
+	return ack
+}
+
+// applyConfigPatch copies patch's non-nil fields onto h.cfg in place, the
+// same partial-update shape daemonAdminController.Reload uses for the
+// address fields it preserves across a reload.
+func (h *AdminHandler) applyConfigPatch(patch *transport.AdminConfigPatch) {
+	if patch == nil {
+		return
+	}
+	if patch.ChunkSize != nil {
+		h.cfg.ChunkSize = *patch.ChunkSize
+	}
+	if patch.WorkerCount != nil {
+		h.cfg.WorkerCount = *patch.WorkerCount
+	}
+	if patch.FECMinR != nil {
+		h.cfg.FECMinR = *patch.FECMinR
+	}
+	if patch.FECMaxR != nil {
+		h.cfg.FECMaxR = *patch.FECMaxR
+	}
+}
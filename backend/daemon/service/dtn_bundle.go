@@ -0,0 +1,37 @@
+package service
+
+import (
+	"github.com/quantarax/backend/daemon/transport/dtn"
+)
+
+// defaultDTNSpool and defaultDTNForwarder back the bundle-mode fallback
+// SendWithOrchestration switches a transfer into once its direct QUIC
+// attempts keep failing (see dtnBundleFailureThreshold): chunks are spooled
+// here instead of retried over the connection, and defaultDTNForwarder
+// opportunistically forwards them to one of the configured relays,
+// honoring custody semantics along the way.
+var (
+	defaultDTNSpool     *dtn.Spool
+	defaultDTNForwarder *dtn.Forwarder
+)
+
+// InitDTNBundles opens a bundle spool at path and starts a Forwarder
+// against relays, using dtn.HTTPRelaySender as the delivery mechanism. It's
+// the bundle-mode counterpart to InitDTN's DTNQueue/DTNWorker pair, kept as
+// a separate subsystem since a bundle is handed off to a relay for
+// store-and-forward custody rather than simply retried on this daemon's own
+// schedule.
+func InitDTNBundles(path string, relays []string) error {
+	spool, err := dtn.OpenSpool(path)
+	if err != nil {
+		return err
+	}
+	defaultDTNSpool = spool
+	defaultDTNForwarder = dtn.NewForwarder(spool, relays, dtn.HTTPRelaySender(nil))
+	defaultDTNForwarder.Start()
+	return nil
+}
+
+// GetDTNSpool returns the default bundle spool, or nil if InitDTNBundles
+// hasn't been called (e.g. no relays configured for this daemon).
+func GetDTNSpool() *dtn.Spool { return defaultDTNSpool }
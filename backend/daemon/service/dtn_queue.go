@@ -1,59 +1,237 @@
 package service
 
 import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
 	"time"
-	"strconv"
+
 	"github.com/boltdb/bolt"
+	"github.com/quantarax/backend/internal/observability"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// DTNItem is one chunk queued for delayed (store-and-forward) delivery.
 type DTNItem struct {
 	SessionID string
 	ChunkIdx  int64
 	Priority  int
 	ExpireAt  int64
+
+	// Attempts counts how many delivery attempts this item has already
+	// used, incremented by DTNWorker each time sendFunc fails. DTNWorker
+	// drops the item once Attempts reaches its BackoffPolicy.MaxAttempts,
+	// rather than requeuing forever against a peer that keeps failing.
+	Attempts int
 }
 
-type DTNQueue struct { db *bolt.DB }
+// DTNQueue is a BoltDB-backed priority/TTL queue for DTN delivery. Items
+// live in bucketDTN keyed by a fixed 17-byte big-endian layout
+// [priority:1][expireAt:8][enqueuedSeq:8] (priority stored inverted so
+// "higher priority" sorts first under BoltDB's ascending cursor order),
+// so a plain forward cursor walk already yields items in
+// priority-then-expiry-then-insertion order. bucketDTNIndex maps
+// "sessionID:primaryKey" to nothing, giving an O(log n) way to find and
+// drop a session's entries without scanning bucketDTN.
+type DTNQueue struct {
+	db  *bolt.DB
+	seq uint64
+}
 
-var bucketDTN = []byte("dtn_queue")
+var (
+	bucketDTN      = []byte("dtn_queue")
+	bucketDTNIndex = []byte("dtn_queue_session_index")
+)
+
+const dtnKeySize = 1 + 8 + 8 // priority + expireAt + enqueuedSeq
 
 func OpenDTNQueue(path string) (*DTNQueue, error) {
 	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
-	if err != nil { return nil, err }
-	err = db.Update(func(tx *bolt.Tx) error { _, e := tx.CreateBucketIfNotExists(bucketDTN); return e })
-	if err != nil { db.Close(); return nil, err }
-	return &DTNQueue{db: db}, nil
+	if err != nil {
+		return nil, err
+	}
+	var maxSeq uint64
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, e := tx.CreateBucketIfNotExists(bucketDTN)
+		if e != nil {
+			return e
+		}
+		if _, e := tx.CreateBucketIfNotExists(bucketDTNIndex); e != nil {
+			return e
+		}
+
+		// Resume the insertion-order counter from whatever's already on
+		// disk: a fresh q.seq starting at 0 would reassign seq values a
+		// prior process already used, and since the primary key is
+		// [priority][expireAt][seq], an item left over from before this
+		// reopen with the same priority and expiry (ExpireAt unset is the
+		// common case) would collide with one enqueued after restart and
+		// get silently overwritten.
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			if len(k) != dtnKeySize {
+				continue
+			}
+			if seq := binary.BigEndian.Uint64(k[9:17]); seq > maxSeq {
+				maxSeq = seq
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DTNQueue{db: db, seq: maxSeq}, nil
+}
+
+// dtnKey builds the fixed-width primary key for item, using seq to break
+// ties between same-priority, same-expiry items in enqueue order.
+func dtnKey(priority int, expireAt int64, seq uint64) []byte {
+	key := make([]byte, dtnKeySize)
+	key[0] = byte(255 - clampPriority(priority))
+	binary.BigEndian.PutUint64(key[1:9], uint64(expireAt))
+	binary.BigEndian.PutUint64(key[9:17], seq)
+	return key
+}
+
+func clampPriority(p int) int {
+	if p < 0 {
+		return 0
+	}
+	if p > 255 {
+		return 255
+	}
+	return p
+}
+
+// dtnIndexKey namespaces the session index bucket by session ID (which may
+// itself contain ':' or digits; the primary key's fixed width means the
+// separator position is unambiguous when parsing it back out) so a
+// session's entries can be found without touching bucketDTN.
+func dtnIndexKey(sessionID string, primaryKey []byte) []byte {
+	key := make([]byte, 0, len(sessionID)+1+len(primaryKey))
+	key = append(key, []byte(sessionID)...)
+	key = append(key, ':')
+	key = append(key, primaryKey...)
+	return key
 }
 
 func (q *DTNQueue) Enqueue(item *DTNItem) error {
+	_, span := otel.Tracer("quantarax-dtn").Start(context.Background(), "dtn.enqueue")
+	span.SetAttributes(
+		attribute.String("session_id", item.SessionID),
+		attribute.Int64("chunk_index", item.ChunkIdx),
+	)
+	defer span.End()
+
+	observability.TraceGlobal(observability.FacetDTN, "dtn enqueue", "session_id", item.SessionID, "chunk_index", item.ChunkIdx, "priority", item.Priority, "expire_at", item.ExpireAt)
+
 	return q.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(bucketDTN)
-		key := []byte(item.SessionID + ":" + strconv.FormatInt(item.ChunkIdx, 10))
-		val := []byte{byte(item.Priority)}
-		return b.Put(key, val)
+		idx := tx.Bucket(bucketDTNIndex)
+
+		q.seq++
+		key := dtnKey(item.Priority, item.ExpireAt, q.seq)
+
+		val, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(key, val); err != nil {
+			return err
+		}
+		return idx.Put(dtnIndexKey(item.SessionID, key), nil)
 	})
 }
 
+// DequeueBatch removes and returns up to n items in priority/TTL order
+// (highest priority first, then earliest expiry, then insertion order).
+// Expired entries encountered along the way are garbage-collected rather
+// than returned.
 func (q *DTNQueue) DequeueBatch(n int) ([]DTNItem, error) {
+	_, span := otel.Tracer("quantarax-dtn").Start(context.Background(), "dtn.dequeue_batch")
+	defer span.End()
+
+	now := time.Now().Unix()
 	var out []DTNItem
 	err := q.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(bucketDTN)
+		idx := tx.Bucket(bucketDTNIndex)
 		c := b.Cursor()
+
 		for k, v := c.First(); k != nil && len(out) < n; k, v = c.Next() {
-			// simplistic parse: find ':'
-			var sess string; var idx int64
-			for i := range k {
-				if k[i] == ':' { sess = string(k[:i]); break }
+			item, err := decodeDTNItem(v)
+			if err != nil {
+				// Corrupt entry: drop it, nothing in the index to clean up.
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := idx.Delete(dtnIndexKey(item.SessionID, k)); err != nil {
+				return err
+			}
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+
+			if item.ExpireAt > 0 && item.ExpireAt <= now {
+				continue
+			}
+			out = append(out, *item)
+		}
+		return nil
+	})
+	span.SetAttributes(attribute.Int("items_returned", len(out)))
+	return out, err
+}
+
+// PeekDue returns every item already past its ExpireAt without removing
+// them, so callers (e.g. a retry scan) can act on expiry without racing a
+// concurrent DequeueBatch.
+func (q *DTNQueue) PeekDue(now time.Time) ([]DTNItem, error) {
+	cutoff := now.Unix()
+	var out []DTNItem
+	err := q.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketDTN)
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			item, err := decodeDTNItem(v)
+			if err != nil {
+				continue
+			}
+			if item.ExpireAt > 0 && item.ExpireAt <= cutoff {
+				out = append(out, *item)
 			}
-			// naive: parse idx from suffix
-			var mul int64 = 1
-			for i := len(k)-1; i >= 0; i-- { if k[i] == ':' { break }; idx += int64(k[i]-'0') * mul; mul *= 10 }
-			out = append(out, DTNItem{SessionID: sess, ChunkIdx: idx, Priority: int(v[0])})
-			_ = b.Delete(k)
 		}
 		return nil
 	})
 	return out, err
 }
 
+// Requeue re-enqueues item after backoff, pushing its ExpireAt out so it
+// isn't immediately treated as expired, and assigning it a fresh insertion
+// sequence so it's ordered after items already waiting at the same
+// priority/expiry.
+func (q *DTNQueue) Requeue(item *DTNItem, backoff time.Duration) error {
+	requeued := *item
+	if requeued.ExpireAt > 0 {
+		if pushed := time.Now().Add(backoff).Unix(); pushed > requeued.ExpireAt {
+			requeued.ExpireAt = pushed
+		}
+	}
+	return q.Enqueue(&requeued)
+}
+
+func decodeDTNItem(val []byte) (*DTNItem, error) {
+	var item DTNItem
+	if err := json.Unmarshal(val, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
 func (q *DTNQueue) Close() error { return q.db.Close() }
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestDTNQueue(t *testing.T) *DTNQueue {
+	t.Helper()
+	q, err := OpenDTNQueue(filepath.Join(t.TempDir(), "dtn.db"))
+	if err != nil {
+		t.Fatalf("OpenDTNQueue failed: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func TestDTNQueue_PriorityOrder(t *testing.T) {
+	q := openTestDTNQueue(t)
+
+	future := time.Now().Add(time.Hour).Unix()
+	if err := q.Enqueue(&DTNItem{SessionID: "sess-a", ChunkIdx: 1, Priority: 1, ExpireAt: future}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := q.Enqueue(&DTNItem{SessionID: "sess-b", ChunkIdx: 2, Priority: 5, ExpireAt: future}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := q.Enqueue(&DTNItem{SessionID: "sess-c", ChunkIdx: 3, Priority: 3, ExpireAt: future}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	items, err := q.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch failed: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(items))
+	}
+	if items[0].SessionID != "sess-b" || items[1].SessionID != "sess-c" || items[2].SessionID != "sess-a" {
+		t.Fatalf("expected priority-descending order [sess-b, sess-c, sess-a], got %v", items)
+	}
+}
+
+func TestDTNQueue_ExpiryGC(t *testing.T) {
+	q := openTestDTNQueue(t)
+
+	past := time.Now().Add(-time.Minute).Unix()
+	future := time.Now().Add(time.Hour).Unix()
+
+	if err := q.Enqueue(&DTNItem{SessionID: "sess-expired", ChunkIdx: 1, Priority: 5, ExpireAt: past}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := q.Enqueue(&DTNItem{SessionID: "sess-live", ChunkIdx: 2, Priority: 1, ExpireAt: future}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	items, err := q.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch failed: %v", err)
+	}
+	if len(items) != 1 || items[0].SessionID != "sess-live" {
+		t.Fatalf("expected only the live item to be returned, got %v", items)
+	}
+
+	// The expired item must have been deleted outright, not just skipped.
+	remaining, err := q.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch failed: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Fatalf("expected queue to be empty after GC, got %v", remaining)
+	}
+}
+
+func TestDTNQueue_SessionIDsWithColonsAndDigits(t *testing.T) {
+	q := openTestDTNQueue(t)
+
+	future := time.Now().Add(time.Hour).Unix()
+	tricky := []string{"sess:123", "42", "a:b:c:99", "00042"}
+	for i, sess := range tricky {
+		if err := q.Enqueue(&DTNItem{SessionID: sess, ChunkIdx: int64(i), Priority: 1, ExpireAt: future}); err != nil {
+			t.Fatalf("Enqueue(%q) failed: %v", sess, err)
+		}
+	}
+
+	items, err := q.DequeueBatch(len(tricky))
+	if err != nil {
+		t.Fatalf("DequeueBatch failed: %v", err)
+	}
+	if len(items) != len(tricky) {
+		t.Fatalf("expected %d items, got %d", len(tricky), len(items))
+	}
+
+	gotBySession := make(map[string]int64, len(items))
+	for _, it := range items {
+		gotBySession[it.SessionID] = it.ChunkIdx
+	}
+	for i, sess := range tricky {
+		if idx, ok := gotBySession[sess]; !ok || idx != int64(i) {
+			t.Errorf("session %q: expected chunk index %d, got %d (found=%v)", sess, i, idx, ok)
+		}
+	}
+}
+
+func TestDTNQueue_Requeue(t *testing.T) {
+	q := openTestDTNQueue(t)
+
+	item := &DTNItem{SessionID: "sess-retry", ChunkIdx: 7, Priority: 2, ExpireAt: time.Now().Add(time.Minute).Unix()}
+	if err := q.Requeue(item, 0); err != nil {
+		t.Fatalf("Requeue failed: %v", err)
+	}
+
+	items, err := q.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch failed: %v", err)
+	}
+	if len(items) != 1 || items[0].SessionID != "sess-retry" || items[0].ChunkIdx != 7 {
+		t.Fatalf("expected requeued item back, got %v", items)
+	}
+}
+
+func TestDTNQueue_ReopenDoesNotCollideWithExistingItems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dtn.db")
+
+	q, err := OpenDTNQueue(path)
+	if err != nil {
+		t.Fatalf("OpenDTNQueue failed: %v", err)
+	}
+	// Same priority and ExpireAt (the common case: unset) as the item
+	// enqueued after reopen below, so only the insertion-sequence part of
+	// the primary key can tell them apart.
+	if err := q.Enqueue(&DTNItem{SessionID: "sess-before-restart", ChunkIdx: 1, Priority: 1}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	q, err = OpenDTNQueue(path)
+	if err != nil {
+		t.Fatalf("reopen OpenDTNQueue failed: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	if err := q.Enqueue(&DTNItem{SessionID: "sess-after-restart", ChunkIdx: 2, Priority: 1}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	items, err := q.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected both the pre-restart and post-restart items to survive, got %v", items)
+	}
+	bySession := make(map[string]bool, len(items))
+	for _, it := range items {
+		bySession[it.SessionID] = true
+	}
+	if !bySession["sess-before-restart"] || !bySession["sess-after-restart"] {
+		t.Fatalf("expected both sessions present, got %v", items)
+	}
+}
+
+func TestDTNQueue_PeekDue(t *testing.T) {
+	q := openTestDTNQueue(t)
+
+	past := time.Now().Add(-time.Minute).Unix()
+	future := time.Now().Add(time.Hour).Unix()
+	if err := q.Enqueue(&DTNItem{SessionID: "sess-due", ChunkIdx: 1, Priority: 1, ExpireAt: past}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if err := q.Enqueue(&DTNItem{SessionID: "sess-not-due", ChunkIdx: 2, Priority: 1, ExpireAt: future}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	due, err := q.PeekDue(time.Now())
+	if err != nil {
+		t.Fatalf("PeekDue failed: %v", err)
+	}
+	if len(due) != 1 || due[0].SessionID != "sess-due" {
+		t.Fatalf("expected only sess-due, got %v", due)
+	}
+
+	// PeekDue must not remove anything.
+	remaining, err := q.DequeueBatch(10)
+	if err != nil {
+		t.Fatalf("DequeueBatch failed: %v", err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("expected both items still present after PeekDue, got %d", len(remaining))
+	}
+}
@@ -2,6 +2,9 @@ package service
 
 import (
 	"time"
+
+	"github.com/quantarax/backend/daemon/transport"
+	"github.com/quantarax/backend/internal/observability"
 )
 
 type DTNWorker struct {
@@ -9,10 +12,28 @@ type DTNWorker struct {
 	stop  chan struct{}
 	// hooks to send chunks
 	sendFunc func(sess string, idx int64) error
+
+	// backoff paces re-attempts against a peer that just failed, keyed off
+	// each DTNItem's own Attempts count rather than a shared timer, so one
+	// item retrying doesn't throttle the rest of the batch.
+	backoff transport.BackoffPolicy
+	logger  *observability.Logger
 }
 
 func NewDTNWorker(q *DTNQueue, send func(string, int64) error) *DTNWorker {
-	return &DTNWorker{queue: q, stop: make(chan struct{}), sendFunc: send}
+	return &DTNWorker{queue: q, stop: make(chan struct{}), sendFunc: send, backoff: transport.DefaultBackoffPolicy()}
+}
+
+// SetBackoff overrides the default retry backoff used when sendFunc fails.
+func (w *DTNWorker) SetBackoff(policy transport.BackoffPolicy) {
+	w.backoff = policy
+}
+
+// SetLogger attaches a logger for DTNItemDropped events. Left nil, dropped
+// items are silently discarded, same as an unset onChunkFailed callback
+// elsewhere in this package.
+func (w *DTNWorker) SetLogger(logger *observability.Logger) {
+	w.logger = logger
 }
 
 func (w *DTNWorker) Start() {
@@ -26,11 +47,36 @@ func (w *DTNWorker) Start() {
 			case <-ticker.C:
 				items, _ := w.queue.DequeueBatch(128)
 				for _, it := range items {
-					_ = w.sendFunc(it.SessionID, it.ChunkIdx)
+					w.attempt(it)
 				}
 			}
 		}
 	}()
 }
 
+// attempt sends one DTNItem and, on failure, either requeues it after
+// w.backoff.Duration(it.Attempts) or drops it, if it's already past its
+// ExpireAt or exhausted w.backoff's MaxAttempts.
+func (w *DTNWorker) attempt(it DTNItem) {
+	err := w.sendFunc(it.SessionID, it.ChunkIdx)
+	if err == nil {
+		return
+	}
+
+	it.Attempts++
+	expired := it.ExpireAt > 0 && time.Now().Unix() >= it.ExpireAt
+	if expired || w.backoff.Exhausted(it.Attempts) {
+		reason := "max_attempts_exceeded"
+		if expired {
+			reason = "expired"
+		}
+		if w.logger != nil {
+			w.logger.DTNItemDropped(it.SessionID, it.ChunkIdx, reason, it.Attempts)
+		}
+		return
+	}
+
+	_ = w.queue.Requeue(&it, w.backoff.Duration(it.Attempts-1))
+}
+
 func (w *DTNWorker) Stop() { close(w.stop) }
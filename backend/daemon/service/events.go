@@ -5,6 +5,9 @@ import (
 	"strconv"
 	"sync"
 	"time"
+
+	"github.com/quantarax/backend/daemon/manager"
+	"github.com/quantarax/backend/internal/observability"
 )
 
 // EventType represents different event classifications
@@ -19,6 +22,15 @@ const (
 	EventFailed
 	EventChunkSent
 	EventChunkReceived
+	EventManifestOffered
+	EventManifestFetched
+	EventChunkVerified
+	EventChunkFailedVerification
+	EventSnapshotRequested
+	EventSnapshotApplied
+	EventBandwidth
+	EventBadEncodingProof
+	EventResumeProgress
 )
 
 func (e EventType) String() string {
@@ -39,6 +51,24 @@ func (e EventType) String() string {
 		return "CHUNK_SENT"
 	case EventChunkReceived:
 		return "CHUNK_RECEIVED"
+	case EventManifestOffered:
+		return "MANIFEST_OFFERED"
+	case EventManifestFetched:
+		return "MANIFEST_FETCHED"
+	case EventChunkVerified:
+		return "CHUNK_VERIFIED"
+	case EventChunkFailedVerification:
+		return "CHUNK_FAILED_VERIFICATION"
+	case EventSnapshotRequested:
+		return "SNAPSHOT_REQUESTED"
+	case EventSnapshotApplied:
+		return "SNAPSHOT_APPLIED"
+	case EventBandwidth:
+		return "BANDWIDTH"
+	case EventBadEncodingProof:
+		return "BAD_ENCODING_PROOF"
+	case EventResumeProgress:
+		return "RESUME_PROGRESS"
 	default:
 		return "UNKNOWN"
 	}
@@ -54,37 +84,102 @@ type TransferEvent struct {
 	Metadata        map[string]string
 }
 
+// EventFilter selects which events a subscriber receives. An empty
+// SessionID or nil Types matches everything along that axis.
+type EventFilter struct {
+	SessionID string
+	Types     []EventType
+}
+
+func (f EventFilter) matches(event *TransferEvent) bool {
+	if f.SessionID != "" && f.SessionID != event.SessionID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == event.EventType {
+			return true
+		}
+	}
+	return false
+}
+
 // EventSubscription represents an active event subscription
 type EventSubscription struct {
-	ID              string
-	SessionIDFilter string
-	Channel         chan *TransferEvent
+	ID      string
+	Filter  EventFilter
+	Channel chan *TransferEvent
 }
 
+// recentEventBufferSize is how many past events per session are replayed to
+// a newly-opened subscription, so a late subscriber (e.g. a UI reattaching
+// mid-transfer) can recover recent history instead of only future events.
+const recentEventBufferSize = 32
+
+// chunkVerifiedThrottleInterval caps how often EventChunkVerified events
+// reach subscribers per session. Chunk verification happens once per chunk,
+// which on a fast local transfer can be hundreds of times a second — far
+// more than any UI needs to redraw, so it is throttled to 4 Hz.
+const chunkVerifiedThrottleInterval = 250 * time.Millisecond
+
 // EventPublisher manages event subscriptions and broadcasting
 type EventPublisher struct {
 	subscriptions map[string]*EventSubscription
 	mu            sync.RWMutex
 	bufferSize    int
+	recent        map[string][]*TransferEvent // sessionID -> ring buffer
+
+	throttleMu        sync.Mutex
+	lastChunkVerified map[string]time.Time // sessionID -> last emit time
+
+	// sessionStore, if set via SetSessionStore, lets Publish feed
+	// EventKindBandwidth samples straight into the matching Session's raw
+	// bandwidth accounting instead of subscribers needing to parse Metadata.
+	sessionStore *manager.SessionStore
+}
+
+// SetSessionStore wires store into Publish's EventKindBandwidth handling, so
+// bandwidth samples reported through the observability.EventBus pipeline
+// update the originating Session directly. Left unset (nil), bandwidth
+// events still reach subscribers via Metadata but no Session is updated.
+func (p *EventPublisher) SetSessionStore(store *manager.SessionStore) {
+	p.sessionStore = store
 }
 
 // NewEventPublisher creates a new event publisher
 func NewEventPublisher(bufferSize int) *EventPublisher {
 	return &EventPublisher{
-		subscriptions: make(map[string]*EventSubscription),
-		bufferSize:    bufferSize,
+		subscriptions:     make(map[string]*EventSubscription),
+		bufferSize:        bufferSize,
+		recent:            make(map[string][]*TransferEvent),
+		lastChunkVerified: make(map[string]time.Time),
 	}
 }
 
-// Subscribe creates a new event subscription
-func (p *EventPublisher) Subscribe(sessionIDFilter string) *EventSubscription {
+// Subscribe creates a new event subscription matching filter. Any buffered
+// recent events for a specific session (filter.SessionID) are replayed to
+// the new subscriber immediately, best-effort.
+func (p *EventPublisher) Subscribe(filter EventFilter) *EventSubscription {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	sub := &EventSubscription{
-		ID:              generateSubscriptionID(),
-		SessionIDFilter: sessionIDFilter,
-		Channel:         make(chan *TransferEvent, p.bufferSize),
+		ID:      generateSubscriptionID(),
+		Filter:  filter,
+		Channel: make(chan *TransferEvent, p.bufferSize),
+	}
+
+	if filter.SessionID != "" {
+		for _, ev := range p.recent[filter.SessionID] {
+			if filter.matches(ev) {
+				select {
+				case sub.Channel <- ev:
+				default:
+				}
+			}
+		}
 	}
 
 	p.subscriptions[sub.ID] = sub
@@ -102,17 +197,27 @@ func (p *EventPublisher) Unsubscribe(subscriptionID string) {
 	}
 }
 
-// Publish broadcasts an event to all matching subscribers
-func (p *EventPublisher) Publish(event *TransferEvent) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
+// publish broadcasts an event to all matching subscribers and records it in
+// that session's recent-event ring buffer for late subscribers.
+func (p *EventPublisher) publish(event *TransferEvent) {
+	p.mu.Lock()
+	if event.SessionID != "" {
+		buf := append(p.recent[event.SessionID], event)
+		if len(buf) > recentEventBufferSize {
+			buf = buf[len(buf)-recentEventBufferSize:]
+		}
+		p.recent[event.SessionID] = buf
+	}
+	subs := make([]*EventSubscription, 0, len(p.subscriptions))
 	for _, sub := range p.subscriptions {
-		// Apply session ID filter
-		if sub.SessionIDFilter != "" && sub.SessionIDFilter != event.SessionID {
+		subs = append(subs, sub)
+	}
+	p.mu.Unlock()
+
+	for _, sub := range subs {
+		if !sub.Filter.matches(event) {
 			continue
 		}
-
 		// Non-blocking send to prevent slow consumers from blocking
 		select {
 		case sub.Channel <- event:
@@ -123,9 +228,96 @@ func (p *EventPublisher) Publish(event *TransferEvent) {
 	}
 }
 
+// Publish implements observability.EventBus, letting transport-layer
+// components (e.g. ChunkReceiver) feed chunk lifecycle events into this same
+// subscriber pipeline without the transport package importing service.
+func (p *EventPublisher) Publish(ev observability.Event) error {
+	var eventType EventType
+	switch ev.Kind {
+	case observability.EventKindChunkReceived:
+		eventType = EventChunkVerified
+	case observability.EventKindHashMismatch, observability.EventKindMerkleProofFailed:
+		eventType = EventChunkFailedVerification
+	case observability.EventKindDecryptFailed:
+		eventType = EventChunkFailedVerification
+	case observability.EventKindVerificationCompleted:
+		eventType = EventCompleted
+	case observability.EventKindSnapshotRequested:
+		eventType = EventSnapshotRequested
+	case observability.EventKindSnapshotApplied:
+		eventType = EventSnapshotApplied
+	case observability.EventKindBandwidth:
+		eventType = EventBandwidth
+	case observability.EventKindBadEncodingProof:
+		eventType = EventBadEncodingProof
+	default:
+		return nil
+	}
+
+	if eventType == EventChunkVerified && !p.allowChunkVerified(ev.SessionID) {
+		return nil
+	}
+
+	if eventType == EventBandwidth && p.sessionStore != nil {
+		p.applyBandwidthSample(ev)
+	}
+
+	te := &TransferEvent{
+		SessionID: ev.SessionID,
+		EventType: eventType,
+		Timestamp: ev.Timestamp,
+		Metadata:  ev.Fields,
+	}
+	if ev.ChunkIndex != nil {
+		if te.Metadata == nil {
+			te.Metadata = map[string]string{}
+		}
+		te.Metadata["chunk_index"] = strconv.FormatInt(*ev.ChunkIndex, 10)
+	}
+	p.publish(te)
+	return nil
+}
+
+// applyBandwidthSample parses the raw/useful byte counters a
+// BandwidthMeter.StartPeriodicPublish tick reported in ev.Fields and records
+// them on the matching Session, best-effort: a missing session or an
+// unparseable field just skips the update, since subscribers still get the
+// event via Metadata regardless.
+func (p *EventPublisher) applyBandwidthSample(ev observability.Event) {
+	sess, err := p.sessionStore.Get(ev.SessionID)
+	if err != nil {
+		return
+	}
+	rawOut, err1 := strconv.ParseInt(ev.Fields["raw_bytes_out"], 10, 64)
+	rawIn, err2 := strconv.ParseInt(ev.Fields["raw_bytes_in"], 10, 64)
+	usefulOut, err3 := strconv.ParseInt(ev.Fields["useful_bytes_out"], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return
+	}
+	sess.UpdateBandwidth(rawOut, rawIn, usefulOut)
+}
+
+// allowChunkVerified reports whether enough time has passed since the last
+// EventChunkVerified emission for sessionID to emit another one, enforcing
+// chunkVerifiedThrottleInterval (4 Hz).
+func (p *EventPublisher) allowChunkVerified(sessionID string) bool {
+	now := time.Now()
+	p.throttleMu.Lock()
+	defer p.throttleMu.Unlock()
+	if last, ok := p.lastChunkVerified[sessionID]; ok && now.Sub(last) < chunkVerifiedThrottleInterval {
+		return false
+	}
+	p.lastChunkVerified[sessionID] = now
+	return true
+}
+
+// Close implements observability.EventBus; EventPublisher holds no external
+// resources to release.
+func (p *EventPublisher) Close() error { return nil }
+
 // PublishStarted publishes a transfer started event
 func (p *EventPublisher) PublishStarted(sessionID, fileName string, totalSize int64) {
-	p.Publish(&TransferEvent{
+	p.publish(&TransferEvent{
 		SessionID:       sessionID,
 		EventType:       EventStarted,
 		Timestamp:       time.Now(),
@@ -138,23 +330,28 @@ func (p *EventPublisher) PublishStarted(sessionID, fileName string, totalSize in
 	})
 }
 
-// PublishProgress publishes a progress update event
-func (p *EventPublisher) PublishProgress(sessionID string, progressPercent float64, transferRateMbps float64) {
-	p.Publish(&TransferEvent{
+// PublishProgress publishes a progress update event carrying the fields a
+// progress bar / ETA display needs: bytes and chunks transferred so far,
+// the current transfer rate, and the estimated time remaining.
+func (p *EventPublisher) PublishProgress(sessionID string, progressPercent float64, bytesTransferred, chunksTransferred int64, transferRateMbps float64, etaSeconds int64) {
+	p.publish(&TransferEvent{
 		SessionID:       sessionID,
 		EventType:       EventProgress,
 		Timestamp:       time.Now(),
 		ProgressPercent: progressPercent,
 		Message:         "Transfer in progress",
 		Metadata: map[string]string{
-			"transfer_rate_mbps": formatFloat(transferRateMbps),
+			"bytes_transferred":  strconv.FormatInt(bytesTransferred, 10),
+			"chunks_transferred": strconv.FormatInt(chunksTransferred, 10),
+			"rate_mbps":          formatFloat(transferRateMbps),
+			"eta_s":              strconv.FormatInt(etaSeconds, 10),
 		},
 	})
 }
 
 // PublishCompleted publishes a transfer completed event
 func (p *EventPublisher) PublishCompleted(sessionID string, totalTime time.Duration, avgSpeed float64) {
-	p.Publish(&TransferEvent{
+	p.publish(&TransferEvent{
 		SessionID:       sessionID,
 		EventType:       EventCompleted,
 		Timestamp:       time.Now(),
@@ -169,7 +366,7 @@ func (p *EventPublisher) PublishCompleted(sessionID string, totalTime time.Durat
 
 // PublishFailed publishes a transfer failed event
 func (p *EventPublisher) PublishFailed(sessionID, errorMessage string) {
-	p.Publish(&TransferEvent{
+	p.publish(&TransferEvent{
 		SessionID:       sessionID,
 		EventType:       EventFailed,
 		Timestamp:       time.Now(),
@@ -180,7 +377,7 @@ func (p *EventPublisher) PublishFailed(sessionID, errorMessage string) {
 
 // PublishChunkSent publishes a chunk sent event
 func (p *EventPublisher) PublishChunkSent(sessionID string, chunkIndex int64) {
-	p.Publish(&TransferEvent{
+	p.publish(&TransferEvent{
 		SessionID: sessionID,
 		EventType: EventChunkSent,
 		Timestamp: time.Now(),
@@ -192,7 +389,7 @@ func (p *EventPublisher) PublishChunkSent(sessionID string, chunkIndex int64) {
 
 // PublishChunkReceived publishes a chunk received event
 func (p *EventPublisher) PublishChunkReceived(sessionID string, chunkIndex int64) {
-	p.Publish(&TransferEvent{
+	p.publish(&TransferEvent{
 		SessionID: sessionID,
 		EventType: EventChunkReceived,
 		Timestamp: time.Now(),
@@ -202,6 +399,108 @@ func (p *EventPublisher) PublishChunkReceived(sessionID string, chunkIndex int64
 	})
 }
 
+// PublishManifestOffered publishes an event recording that a manifest was
+// offered to a recipient (i.e. a transfer token was issued for fileKey).
+func (p *EventPublisher) PublishManifestOffered(sessionID, fileKey string) {
+	p.publish(&TransferEvent{
+		SessionID: sessionID,
+		EventType: EventManifestOffered,
+		Timestamp: time.Now(),
+		Message:   "Manifest offered",
+		Metadata: map[string]string{
+			"file_key": fileKey,
+		},
+	})
+}
+
+// PublishManifestFetched publishes an event recording that a recipient
+// fetched the manifest for fileKey.
+func (p *EventPublisher) PublishManifestFetched(sessionID, fileKey string) {
+	p.publish(&TransferEvent{
+		SessionID: sessionID,
+		EventType: EventManifestFetched,
+		Timestamp: time.Now(),
+		Message:   "Manifest fetched",
+		Metadata: map[string]string{
+			"file_key": fileKey,
+		},
+	})
+}
+
+// PublishChunkVerified publishes an event recording that chunkIndex passed
+// its Merkle proof / hash verification.
+func (p *EventPublisher) PublishChunkVerified(sessionID string, chunkIndex int64) {
+	p.publish(&TransferEvent{
+		SessionID: sessionID,
+		EventType: EventChunkVerified,
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			"chunk_index": strconv.FormatInt(chunkIndex, 10),
+		},
+	})
+}
+
+// PublishChunkFailedVerification publishes an event recording that
+// chunkIndex failed verification for the given reason.
+func (p *EventPublisher) PublishChunkFailedVerification(sessionID string, chunkIndex int64, reason string) {
+	p.publish(&TransferEvent{
+		SessionID: sessionID,
+		EventType: EventChunkFailedVerification,
+		Timestamp: time.Now(),
+		Message:   reason,
+		Metadata: map[string]string{
+			"chunk_index": strconv.FormatInt(chunkIndex, 10),
+			"reason":      reason,
+		},
+	})
+}
+
+// PublishSnapshotRequested publishes an event recording that a receiver
+// asked the sender for a resumable-transfer snapshot of sessionID.
+func (p *EventPublisher) PublishSnapshotRequested(sessionID string) {
+	p.publish(&TransferEvent{
+		SessionID: sessionID,
+		EventType: EventSnapshotRequested,
+		Timestamp: time.Now(),
+		Message:   "Snapshot requested",
+	})
+}
+
+// PublishSnapshotApplied publishes an event recording that a snapshot was
+// validated and its missing chunks were enqueued, with appliedChunks the
+// number of chunk indices that were re-requested as a result.
+func (p *EventPublisher) PublishSnapshotApplied(sessionID string, appliedChunks int) {
+	p.publish(&TransferEvent{
+		SessionID: sessionID,
+		EventType: EventSnapshotApplied,
+		Timestamp: time.Now(),
+		Message:   "Snapshot applied",
+		Metadata: map[string]string{
+			"applied_chunks": strconv.Itoa(appliedChunks),
+		},
+	})
+}
+
+// PublishResumeProgress publishes a keepalive/resume progress update for a
+// session: how many of its chunks are accounted for so far, who it last
+// heard from, and how long it's gone without hearing from anyone. It
+// implements session.ProgressSink so session.SessionManager's keepalive
+// loop can feed this straight into the SSE /api/v1/events channel without
+// that package importing this one.
+func (p *EventPublisher) PublishResumeProgress(sessionID string, receivedChunks, totalChunks int64, lastPeer string, stalledFor time.Duration) {
+	p.publish(&TransferEvent{
+		SessionID: sessionID,
+		EventType: EventResumeProgress,
+		Timestamp: time.Now(),
+		Metadata: map[string]string{
+			"received_chunks": strconv.FormatInt(receivedChunks, 10),
+			"total_chunks":    strconv.FormatInt(totalChunks, 10),
+			"last_peer":       lastPeer,
+			"stalled_seconds": strconv.FormatInt(int64(stalledFor.Seconds()), 10),
+		},
+	})
+}
+
 // GetSubscriptionCount returns the number of active subscriptions
 func (p *EventPublisher) GetSubscriptionCount() int {
 	p.mu.RLock()
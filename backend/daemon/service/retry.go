@@ -0,0 +1,56 @@
+package service
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy tunes how many times, and with what backoff, the transfer
+// service retries a transient control-plane failure (e.g. a manifest fetch
+// that hasn't landed yet) while creating or accepting a transfer. A
+// zero-value RetryPolicy (MaxAttempts == 0) disables retries, mirroring
+// transport.BackoffConfig's zero-value-disables convention.
+type RetryPolicy struct {
+	InitialDelay time.Duration
+	Multiplier   float64
+	MaxDelay     time.Duration
+	MaxAttempts  int
+	Jitter       time.Duration
+}
+
+// delay returns how long to wait before retry number attempt (0-indexed),
+// capped at MaxDelay.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	if p.InitialDelay <= 0 {
+		return 0
+	}
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = 1
+	}
+	d := time.Duration(float64(p.InitialDelay) * math.Pow(mult, float64(attempt)))
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(p.Jitter)))
+	}
+	return d
+}
+
+// Do calls fn, retrying up to MaxAttempts additional times with delay()
+// backoff between attempts until fn returns a nil error or attempts are
+// exhausted. The zero-value RetryPolicy calls fn exactly once.
+func (p RetryPolicy) Do(fn func() error) error {
+	var err error
+	for attempt := 0; attempt <= p.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(p.delay(attempt - 1))
+		}
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -4,23 +4,80 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/quantarax/backend/daemon/transport"
+	"github.com/quantarax/backend/daemon/transport/dtn"
 	"github.com/quantarax/backend/internal/chunker"
 	"github.com/quantarax/backend/internal/crypto"
+	"github.com/quantarax/backend/internal/crypto/identity"
+	"github.com/quantarax/backend/internal/observability"
+	"go.opentelemetry.io/otel"
 )
 
+// bandwidthPublishInterval is how often SendWithOrchestration has the
+// transfer's BandwidthMeter publish a raw/useful-bytes snapshot to bus.
+const bandwidthPublishInterval = time.Second
+
 // SendWithOrchestration demonstrates routing control/preview/bulk via OrchestratedSender.
 // This is a scaffold for the daemon's send pipeline to call after QUIC is established.
-func SendWithOrchestration(ctx context.Context, conn *transport.QUICConnection, manifest *chunker.Manifest, sessionKeys *crypto.SessionKeys, sessionID uuid.UUID, filePath string, onChunkSent func(int64)) error {
+func SendWithOrchestration(ctx context.Context, conn *transport.QUICConnection, manifest *chunker.Manifest, sessionKeys *crypto.SessionKeys, sessionID uuid.UUID, filePath string, onChunkSent func(int64), bus observability.EventBus, metrics *observability.Metrics, logger *observability.Logger, admin *AdminHandler) error {
+	tr := otel.Tracer("quantarax-daemon")
+	ctx, sessionSpan := tr.Start(ctx, "session.start")
+	defer func() {
+		_, completeSpan := tr.Start(ctx, "session.complete")
+		completeSpan.End()
+		sessionSpan.End()
+	}()
+
 	profile := transport.ProfileForDomain(manifest.Domain, manifest)
-	// onFailed enqueues DTN retry if configured
+	// bundleFailures counts consecutive EnqueueBulk/Preview/Repair failures
+	// for this transfer; once it crosses dtnBundleFailureThreshold with a
+	// DTNProfile configured, onFailed latches bundleMode so the scheduling
+	// loop below stops handing chunks to orch's own pools (which keep
+	// proving they can't reach the peer directly) and spools every later
+	// chunk as a DTN bundle instead. bundleMode is never unlatched: once a
+	// transfer's fallen back to bundles, it stays there rather than
+	// flapping between modes.
+	var bundleFailures int64
+	var bundleMode int32
+	var orch *transport.OrchestratedSender
+	spoolChunkAsBundle := func(idx int64) {
+		if orch == nil || manifest.DTNProfile == nil {
+			return
+		}
+		spool := GetDTNSpool()
+		if spool == nil {
+			return
+		}
+		ciphertext, err := orch.EncryptChunkForDTN(idx)
+		if err != nil {
+			return
+		}
+		_ = spool.Put(&dtn.Bundle{
+			SessionID:  manifest.SessionID,
+			ChunkIndex: idx,
+			TTL:        time.Now().Add(time.Duration(manifest.DTNProfile.TTLSeconds) * time.Second).Unix(),
+			Custody:    manifest.DTNProfile.Custody,
+			CreatedAt:  time.Now().Unix(),
+			Ciphertext: ciphertext,
+		})
+	}
+	// onFailed enqueues DTN retry if configured; once enough consecutive
+	// failures accumulate it also latches bundleMode (see above) and
+	// spools this chunk as a bundle right away instead of waiting for
+	// DTNWorker's in-connection retry to exhaust itself.
 	onFailed := func(idx int64, err error) {
 		if manifest.DTNProfile == nil {
 			return
 		}
+		if atomic.AddInt64(&bundleFailures, 1) >= dtnBundleFailureThreshold {
+			atomic.StoreInt32(&bundleMode, 1)
+			spoolChunkAsBundle(idx)
+			return
+		}
 		q := GetDTNQueue()
 		if q == nil {
 			return
@@ -28,8 +85,31 @@ func SendWithOrchestration(ctx context.Context, conn *transport.QUICConnection,
 		expire := time.Now().Add(time.Duration(manifest.DTNProfile.TTLSeconds) * time.Second).Unix()
 		_ = q.Enqueue(&DTNItem{SessionID: manifest.SessionID, ChunkIdx: idx, Priority: 1, ExpireAt: expire})
 	}
-	orch := transport.NewOrchestratedSender(conn, profile, sessionKeys, sessionID, filePath, int64(manifest.ChunkSize), onChunkSent, onFailed)
+	var chunksSent int64
+	trackedOnChunkSent := func(idx int64) {
+		atomic.AddInt64(&chunksSent, 1)
+		atomic.StoreInt64(&bundleFailures, 0)
+		onChunkSent(idx)
+	}
+	orch = transport.NewOrchestratedSender(conn, profile, sessionKeys, sessionID, filePath, int64(manifest.ChunkSize), trackedOnChunkSent, onFailed)
+	RegisterSender(manifest.SessionID, orch)
+	defer UnregisterSender(manifest.SessionID)
 	defer orch.Close()
+	orch.SetMetrics(metrics)
+	orch.SetDatagramThreshold(defaultDatagramChunkThreshold)
+	orch.SetRepairDatagramThreshold(defaultRepairDatagramThreshold)
+	if admin != nil {
+		orch.SetAdminHandler(admin.Handle)
+	}
+	if bus != nil {
+		orch.BandwidthMeter().StartPeriodicPublish(ctx, bandwidthPublishInterval, manifest.SessionID, bus)
+	}
+	if logger != nil {
+		orch.BandwidthMeter().StartPeriodicLogging(ctx, bandwidthPublishInterval, manifest.SessionID, logger)
+	}
+	if err := orch.EnableFEC(manifest, chunker.FecParityPath(filePath)); err != nil {
+		fmt.Printf("fec: parity sidecar unavailable, sending without it: %v\n", err)
+	}
 	// Start autotuner for chunk size and streams
 	auto := transport.NewAutoTuner(orch, manifest)
 	auto.Start()
@@ -40,10 +120,44 @@ func SendWithOrchestration(ctx context.Context, conn *transport.QUICConnection,
 			_ = conn.GetControlStream().SendFECUpdate(&transport.FECUpdateMessage{SessionID: manifest.SessionID, K: k, R: r, Reason: reason, Timestamp: time.Now().Unix()})
 		}
 	})
+	fecCtl.SetMetrics(metrics)
+	// Publish the controller's loss/RTT estimate over the unreliable
+	// datagram path on every Tick, not just when the parity ratio
+	// changes, so the receiver's own view of the path updates every
+	// interval without waiting behind the bulk streams' backlog. Falls
+	// back to doing nothing when the peer didn't negotiate datagram
+	// support (max_datagram_frame_size = 0): a send failure here isn't
+	// surfaced anywhere since FECUpdateMessage on the control stream
+	// already carries the ratio change reliably.
+	if conn.SupportsDatagrams() {
+		go conn.Datagrams().Run(ctx)
+		fecCtl.SetTelemetryPublisher(func(lossMean, rttMean float64, k, r int) {
+			sample, err := json.Marshal(transport.TelemetrySample{
+				SessionID: manifest.SessionID,
+				LossMean:  lossMean,
+				RTTMillis: rttMean,
+				K:         k,
+				R:         r,
+				Timestamp: time.Now().Unix(),
+			})
+			if err != nil {
+				return
+			}
+			_ = conn.Datagrams().Send(transport.DatagramTelemetry, sample, transport.PriorityP0)
+		})
+	}
 	go func() {
 		Ticker := time.NewTicker(5 * time.Second)
 		defer Ticker.Stop()
+		var lastSent, lastNacked int64
 		for range Ticker.C {
+			sent := atomic.LoadInt64(&chunksSent)
+			nacked := orch.NackedCount()
+			// quic-go's public Conn API doesn't currently expose a
+			// per-connection RTT estimate, so rtt_spike never fires yet;
+			// Observe still tracks loss via NACKed chunk counts.
+			fecCtl.Observe(int(sent-lastSent), int(nacked-lastNacked), 0)
+			lastSent, lastNacked = sent, nacked
 			fecCtl.Tick()
 		}
 	}()
@@ -54,9 +168,9 @@ func SendWithOrchestration(ctx context.Context, conn *transport.QUICConnection,
 		// Best-effort receive response (non-blocking in production)
 		if t, data, err := conn.GetControlStream().ReceiveAny(); err == nil && t == transport.MessageTypeChunkHaveResponse {
 			var resp transport.ChunkHaveResponse
-			if json.Unmarshal(data, &resp) == nil {
+			if conn.GetControlStream().DecodeMessage(data, &resp) == nil {
 				var decomp transport.ChunkRangeCompressor
-				idxs, _ := decomp.Decompress(resp.HaveRanges)
+				idxs, _ := decomp.DecodeRanges(resp.HaveRanges, resp.Encoding)
 				for _, id := range idxs {
 					have[id] = true
 				}
@@ -67,41 +181,110 @@ func SendWithOrchestration(ctx context.Context, conn *transport.QUICConnection,
 	orch.EnqueueControl(func(ctx context.Context) {
 		fmt.Println("control: preflight complete")
 	})
-	// Spawn a control listener to handle NACK and retransmit missing chunks
-	go func() {
-		for {
-			if conn.GetControlStream() == nil {
-				return
-			}
-			t, data, err := conn.GetControlStream().ReceiveAny()
-			if err != nil {
-				return
+	// Stream a signed Merkle proof for each chunk ahead of its data, so the
+	// receiver can verify a chunk as soon as it arrives rather than waiting
+	// for the final whole-file verification pass.
+	if conn.GetControlStream() != nil {
+		if priv, _, err := identity.LoadOrCreate("", ""); err == nil {
+			hashes := make([]string, len(manifest.Chunks))
+			for i, ch := range manifest.Chunks {
+				hashes[i] = ch.Hash
 			}
-			if t == transport.MessageTypeNack {
-				var nack transport.NackMessage
-				if json.Unmarshal(data, &nack) == nil {
-					var decomp transport.ChunkRangeCompressor
-					idxs, _ := decomp.Decompress(nack.MissingRanges)
-					for _, id := range idxs {
-						_ = orch.EnqueueBulk(id)
+			go func() {
+				for i := range manifest.Chunks {
+					path, err := chunker.BuildMerkleProof(hashes, i)
+					if err != nil {
+						continue
 					}
+					chunkCtx, chunkSpan := tr.Start(ctx, "chunk.send")
+					proof := &transport.ChunkProofMessage{
+						SessionID:    manifest.SessionID,
+						ChunkIndex:   int64(i),
+						ChunkHash:    hashes[i],
+						ProofPath:    path,
+						Timestamp:    time.Now().Unix(),
+						TraceContext: transport.InjectTraceContext(chunkCtx),
+					}
+					transport.SignChunkProof(proof, priv)
+					_ = conn.GetControlStream().SendChunkProof(proof)
+					chunkSpan.End()
 				}
-			}
+			}()
 		}
-	}()
+	}
+	// Single control-stream listener for the rest of the transfer: retransmits
+	// on NACK (push path) and serves pull-mode ChunkRequest batches.
+	go orch.ServeChunkRequests(ctx)
+	parity := map[int64]bool{}
+	for _, idx := range manifest.ParityChunkIndices() {
+		parity[idx] = true
+	}
+	dataChunkCount := int64(manifest.DataChunkCount())
+
 	// Preview/header scheduling example (first 3 chunks)
-	for i := int64(0); i < 3 && i < int64(manifest.ChunkCount); i++ {
+	for i := int64(0); i < 3 && i < dataChunkCount; i++ {
 		if have[i] {
 			continue
 		}
 		_ = orch.EnqueuePreview(i)
 	}
-	// Bulk scheduling example (rest chunks)
-	for i := int64(3); i < int64(manifest.ChunkCount); i++ {
+	// Bulk scheduling example (rest of the data chunks). Once bundleMode
+	// has latched (dtnBundleFailureThreshold consecutive failures with a
+	// DTNProfile configured), later iterations of this loop stop handing
+	// chunks to orch's pools at all and spool them as DTN bundles instead,
+	// rather than paying for a direct attempt that's already shown it won't
+	// reach the peer.
+	for i := int64(3); i < dataChunkCount; i++ {
 		if have[i] {
 			continue
 		}
+		if atomic.LoadInt32(&bundleMode) == 1 {
+			spoolChunkAsBundle(i)
+			continue
+		}
 		_ = orch.EnqueueBulk(i)
 	}
+	// Parity chunks go last and only when loss indicators (manifest.Network's
+	// loss_rate_pct estimate) cross fecParityLossThresholdPct — below that,
+	// the receiver's own retransmit path is cheaper than sending redundancy
+	// nobody needs.
+	if len(parity) > 0 && manifest.Network != nil && manifest.Network.LossPct >= fecParityLossThresholdPct {
+		for i := dataChunkCount; i < int64(manifest.ChunkCount); i++ {
+			if have[i] || !parity[i] {
+				continue
+			}
+			_ = orch.EnqueueRepair(i)
+		}
+	}
 	return nil
 }
+
+// fecParityLossThresholdPct is the measured-loss-percentage floor above
+// which SendWithOrchestration bothers sending a transfer's FEC parity
+// chunks at all, rather than relying solely on the receiver's normal
+// hash-mismatch/missing-chunk NACK path to fix up the rare dropped chunk.
+const fecParityLossThresholdPct = 2.0
+
+// defaultDatagramChunkThreshold is the largest per-chunk segment
+// SendWithOrchestration risks over the unreliable QUIC datagram fast path
+// rather than opening a stream for it. It's set well under
+// maxDatagramPayloadSize to leave headroom for the JSON/base64 inflation
+// and GCM tag ChunkDataMessage's ciphertext field picks up once encoded,
+// not just the plaintext segment size being compared against it.
+const defaultDatagramChunkThreshold = 512
+
+// defaultRepairDatagramThreshold is defaultDatagramChunkThreshold's
+// counterpart for FEC parity/repair chunks (see orch.EnqueueRepair): larger
+// because losing one over the datagram path costs nothing — it's spare
+// redundancy, never retransmitted or waited on — where a data chunk that
+// size would rather pay for a reliable stream. Still comfortably under
+// maxDatagramPayloadSize once the same JSON/base64/GCM-tag inflation is
+// accounted for.
+const defaultRepairDatagramThreshold = 768
+
+// dtnBundleFailureThreshold is how many consecutive direct-send failures
+// SendWithOrchestration tolerates (for a transfer with a DTNProfile
+// configured) before giving up on reaching the peer over this QUIC
+// connection at all and latching bundle-mode fallback: every later chunk is
+// spooled as a DTN bundle for opportunistic relay delivery instead.
+const dtnBundleFailureThreshold = 3
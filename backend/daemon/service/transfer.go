@@ -2,28 +2,93 @@ package service
 
 import (
 	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/quantarax/backend/daemon/manager"
+	"github.com/quantarax/backend/daemon/transport"
 	"github.com/quantarax/backend/internal/chunker"
 	"github.com/quantarax/backend/internal/crypto"
 	"github.com/quantarax/backend/internal/engineering"
 	"github.com/quantarax/backend/internal/introspect"
 	"github.com/quantarax/backend/internal/media"
+	_ "github.com/quantarax/backend/internal/media/dpxplugin"
+	_ "github.com/quantarax/backend/internal/media/exrplugin"
+	"github.com/quantarax/backend/internal/medical"
+	_ "golang.org/x/image/tiff"
 	"strings"
 )
 
 var (
-	ErrSessionNotFound = errors.New("session not found")
-	ErrInvalidToken    = errors.New("invalid transfer token")
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrInvalidToken      = errors.New("invalid transfer token")
+	ErrTokenExpired      = errors.New("transfer token expired")
+	ErrTokenRevoked      = errors.New("transfer token revoked")
+	ErrWrongRecipient    = errors.New("transfer token issued for a different recipient")
+	ErrManifestNotFetched = errors.New("manifest not yet fetched for this file key")
 )
 
+// deltaResumeCacheSuffix names the sibling file a receiver's last-synced
+// copy of an engineering-domain file is cached under, the same
+// append-a-suffix convention thumbPath uses for media previews.
+const deltaResumeCacheSuffix = ".quantarax-prev"
+
+// deltaResumeBlockSize is the fixed block size chunker.ComputeDeltaSignatures
+// and EmitDeltaInstructions use for rsync-style delta resume, independent of
+// the transfer's own ChunkSize since the two serve different purposes (one
+// sizes network segments, the other sizes the unit a single byte edit can
+// invalidate).
+const deltaResumeBlockSize = 128 * 1024
+
+// fileExists reports whether path names a regular, readable file.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+const defaultTokenTTL = 24 * time.Hour
+
+// tokenHeader is the fixed, unsigned preamble of a transfer token.
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	V   int    `json:"v"`
+}
+
+// TransferTokenClaims is the signed payload embedded in a transfer token.
+// It deliberately omits the manifest itself: the receiver fetches the full
+// manifest over the control stream via FileKey once the token is accepted,
+// so an intercepted URL leaks neither file contents nor an unrevocable,
+// manifest-sized ticket.
+type TransferTokenClaims struct {
+	SessionID       string `json:"session_id"`
+	FileKey         string `json:"file_key"`
+	ManifestHash    string `json:"manifest_hash"`
+	RecipientPubKey string `json:"recipient_pubkey,omitempty"`
+	Exp             int64  `json:"exp"`
+	Nonce           string `json:"nonce"`
+}
+
+// BundleTokenClaims is the signed payload for a directory/multi-file
+// transfer token. Like TransferTokenClaims it omits the bundle descriptor
+// itself: the receiver fetches it over the control stream via BundleKey,
+// then fetches each selected child manifest via the existing FileKey flow.
+type BundleTokenClaims struct {
+	SessionID       string `json:"session_id"`
+	BundleKey       string `json:"bundle_key"`
+	BundleHash      string `json:"bundle_hash"`
+	RecipientPubKey string `json:"recipient_pubkey,omitempty"`
+	Exp             int64  `json:"exp"`
+	Nonce           string `json:"nonce"`
+}
+
 // TransferService manages file transfer operations
 type TransferService struct {
 	store          *manager.SessionStore
@@ -32,6 +97,13 @@ type TransferService struct {
 	chunkSize      int64
 	privateKey     ed25519.PrivateKey
 	publicKey      ed25519.PublicKey
+	revocations    *manager.BoltRevocationStore
+
+	manifestsMu sync.RWMutex
+	manifests   map[string]*chunker.Manifest // keyed by FileKey
+
+	bundlesMu sync.RWMutex
+	bundles   map[string]*chunker.Bundle // keyed by BundleKey
 }
 
 // NewTransferService creates a new transfer service
@@ -47,6 +119,11 @@ func NewTransferService(
 		return nil, err
 	}
 
+	revocations, err := manager.OpenBoltRevocationStore(filepath.Join(keysDir, "revocations.db"))
+	if err != nil {
+		return nil, err
+	}
+
 	ts := &TransferService{
 		store:          store,
 		eventPublisher: eventPublisher,
@@ -54,18 +131,26 @@ func NewTransferService(
 		chunkSize:      chunkSize,
 		privateKey:     privateKey,
 		publicKey:      publicKey,
+		revocations:    revocations,
+		manifests:      make(map[string]*chunker.Manifest),
+		bundles:        make(map[string]*chunker.Bundle),
 	}
 	// Initialize DTN queue/worker
 	_ = InitDTN(filepath.Join(keysDir, "dtn_queue.db"))
 	return ts, nil
 }
 
-// CreateTransfer initiates a new file transfer
+// CreateTransfer initiates a new file transfer. fecScheme is optional (nil
+// means no erasure coding, the common case); when set, the manifest gains
+// FecStripes and a parity sidecar is written alongside filePath via
+// chunker.FecParityPath for the transport layer to pick up.
 func (s *TransferService) CreateTransfer(
 	filePath string,
 	recipientID string,
 	chunkSizeOverride int64,
 	metadata map[string]string,
+	fecScheme *chunker.FecScheme,
+	retryPolicy *RetryPolicy,
 ) (sessionID string, token string, manifest *chunker.Manifest, err error) {
 	// Validate file exists
 	fileInfo, err := os.Stat(filePath)
@@ -105,16 +190,35 @@ func (s *TransferService) CreateTransfer(
 		}
 	}
 
-	// Generate manifest
-	manifest, err = chunker.ComputeManifest(filePath, chunker.ChunkOptions{ChunkSize: chunkSizeToUse})
+	// CSV telemetry sources (racetrack_factory) get re-encoded through
+	// chunker.CodecTelemetry's double-delta/dictionary-RLE columnar
+	// scheme instead of shipped as raw rows; other extensions (MDF, BLF,
+	// ...) aren't parseable by it yet, so they fall through unchanged.
+	compression := chunker.CodecNone
+	if decision.Domain == introspect.DomainTelemetry && strings.EqualFold(filepath.Ext(filePath), ".csv") {
+		compression = chunker.CodecTelemetry
+	}
+
+	// Generate manifest, retrying per retryPolicy if this transiently fails
+	// (e.g. the file is still being written when the transfer is created).
+	computeManifest := func() error {
+		var cmErr error
+		manifest, cmErr = chunker.ComputeManifest(filePath, chunker.ChunkOptions{ChunkSize: chunkSizeToUse, Compression: compression})
+		return cmErr
+	}
+	if retryPolicy != nil {
+		err = retryPolicy.Do(computeManifest)
+	} else {
+		err = computeManifest()
+	}
 	if err != nil {
 		return "", "", nil, err
 	}
 	// Domain-specific pre-processing (placeholders kept safe by default)
 	if decision.Domain == introspect.DomainMedia {
-		// Try moov relocation (non-destructive placeholder)
-		// newPath, _ := media.RelocateMoovToFront(filePath) // disabled until full rewrite support
-		_ = filePath
+		// Best-effort faststart relocation, covering classic MP4, fragmented
+		// MP4, and Matroska/WebM without container-specific branching here.
+		_, _ = media.OptimizeForStreaming(filePath)
 	}
 	// Enrich manifest with domain decision and minimal policy/FEC defaults
 	manifest.Domain = decision.Domain
@@ -152,6 +256,21 @@ func (s *TransferService) CreateTransfer(
 	}
 	manifest.Network = np
 
+	if fecScheme != nil {
+		srcFile, err := os.Open(filePath)
+		if err != nil {
+			return "", "", nil, err
+		}
+		parity, err := chunker.ApplyFECScheme(manifest, srcFile, *fecScheme)
+		srcFile.Close()
+		if err != nil {
+			return "", "", nil, err
+		}
+		if err := os.WriteFile(chunker.FecParityPath(filePath), parity, 0644); err != nil {
+			return "", "", nil, err
+		}
+	}
+
 	// Generate session ID
 	sessionID = uuid.New().String()
 
@@ -210,6 +329,24 @@ func (s *TransferService) CreateTransfer(
 				BlockCount: len(blocks),
 			})
 		}
+		// Real rsync-style delta resume: if a receiver's last-synced copy
+		// of this file is cached alongside it (the same sibling-file
+		// convention thumbPath above uses for media previews), diff the
+		// current version against it so a resumed transfer sends only the
+		// changed blocks instead of every chunk, which the content-defined
+		// ComputeDeltaBlocks call above only sizes a sender-side checkpoint
+		// for rather than actually driving.
+		if prevPath := filePath + deltaResumeCacheSuffix; fileExists(prevPath) {
+			if sigs, err := chunker.ComputeDeltaSignatures(prevPath, deltaResumeBlockSize); err == nil {
+				if instructions, err := chunker.EmitDeltaInstructions(filePath, deltaResumeBlockSize, sigs); err == nil {
+					manifest.EngineeringProfile.DeltaCheckpoints = append(manifest.EngineeringProfile.DeltaCheckpoints, chunker.DeltaCheckpoint{
+						Path:       prevPath,
+						BlockSize:  deltaResumeBlockSize,
+						BlockCount: len(instructions),
+					})
+				}
+			}
+		}
 	case introspect.DomainMedical:
 		if manifest.MedicalProfile == nil {
 			manifest.MedicalProfile = &chunker.MedicalProfile{}
@@ -217,7 +354,19 @@ func (s *TransferService) CreateTransfer(
 		manifest.MedicalProfile.StrictMode = true
 		manifest.MedicalProfile.E2E = true
 		manifest.MedicalProfile.AtRest = true
-		// Fill minimal metadata if available in future extractor (kept empty for now)
+		// Use the file's own DICOM UIDs as content grouping keys when
+		// available; a non-DICOM medical file (NIfTI/NRRD, or one the
+		// parser couldn't make sense of) just keeps the flags above with no
+		// per-study metadata.
+		if dicomMeta, ok := medical.DetectAndExtract(filePath); ok && len(dicomMeta.Studies) > 0 {
+			study := dicomMeta.Studies[0]
+			manifest.MedicalProfile.PatientID = study.PatientID
+			manifest.MedicalProfile.StudyUID = study.StudyInstanceUID
+			manifest.MedicalProfile.SeriesCount = study.SeriesCount
+			if len(study.Series) > 0 {
+				manifest.MedicalProfile.Modality = study.Series[0].Modality
+			}
+		}
 	}
 
 	// Publish started event
@@ -226,17 +375,136 @@ func (s *TransferService) CreateTransfer(
 	return sessionID, token, manifest, nil
 }
 
+// CreateBundleTransfer chunks every file under root and issues a single
+// token referencing the resulting chunker.Bundle, for directory/multi-file
+// transfers. Each child file gets its own manager.Session (ParentBundleID
+// set to the bundle session's ID) and its own registered FileKey, so the
+// receiver can fetch and accept child manifests individually via
+// AcceptBundleTransfer.
+func (s *TransferService) CreateBundleTransfer(
+	root string,
+	recipientID string,
+	metadata map[string]string,
+) (sessionID string, token string, bundle *chunker.Bundle, err error) {
+	bundle, childManifests, err := chunker.ComputeBundle(root, chunker.ChunkOptions{ChunkSize: int(s.chunkSize)})
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	// Honor engineering dependency graphs: any child whose directory holds a
+	// BOM/manifest.txt gets its dependency list recorded the same way a
+	// single-file engineering transfer does, so AcceptBundleTransfer can
+	// fetch dependees before dependents.
+	if deps, derr := engineering.DiscoverDependencies(root); derr == nil && len(deps) > 0 {
+		for relPath, manifest := range childManifests {
+			decision := introspect.Decide(filepath.Join(root, relPath))
+			if decision.Domain != introspect.DomainEngineering {
+				continue
+			}
+			manifest.Domain = decision.Domain
+			if manifest.EngineeringProfile == nil {
+				manifest.EngineeringProfile = &chunker.EngineeringProfile{}
+			}
+			for _, d := range deps {
+				dependsOn := d
+				if rel, rerr := filepath.Rel(root, d); rerr == nil {
+					dependsOn = rel
+				}
+				manifest.EngineeringProfile.Dependencies = append(manifest.EngineeringProfile.Dependencies, chunker.Dependency{Node: relPath, DependsOn: []string{dependsOn}})
+			}
+		}
+	}
+
+	sessionID = uuid.New().String()
+	var totalSize int64
+	for _, entry := range bundle.Entries {
+		totalSize += entry.Size
+	}
+
+	bundleSession := manager.NewSession(sessionID, root, bundle.RootName, totalSize, s.chunkSize, manager.DirectionSend)
+	bundleSession.Metadata = metadata
+	if err := s.store.Add(bundleSession); err != nil {
+		return "", "", nil, err
+	}
+
+	bundleKey, err := randomTokenComponent()
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	s.manifestsMu.Lock()
+	for i := range bundle.Entries {
+		manifest, ok := childManifests[bundle.Entries[i].RelativePath]
+		if !ok {
+			continue
+		}
+		fileKey, ferr := randomTokenComponent()
+		if ferr != nil {
+			s.manifestsMu.Unlock()
+			return "", "", nil, ferr
+		}
+		bundle.Entries[i].FileKey = fileKey
+		s.manifests[fileKey] = manifest
+	}
+	s.manifestsMu.Unlock()
+
+	s.bundlesMu.Lock()
+	s.bundles[bundleKey] = bundle
+	s.bundlesMu.Unlock()
+
+	token, err = s.issueBundleToken(sessionID, recipientID, defaultTokenTTL, bundleKey, bundle)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	s.eventPublisher.PublishManifestOffered(sessionID, bundleKey)
+
+	return sessionID, token, bundle, nil
+}
+
 // AcceptTransfer accepts an incoming transfer
 func (s *TransferService) AcceptTransfer(
 	token string,
 	outputPath string,
 	resumeSessionID string,
+	retryPolicy *RetryPolicy,
 ) (sessionID string, manifest *chunker.Manifest, err error) {
-	// Parse token
-	sessionID, manifest, err = s.parseToken(token)
+	// Parse and verify the signed token claims
+	claims, err := s.parseToken(token)
+	if err != nil {
+		return "", nil, err
+	}
+	sessionID = claims.SessionID
+	if resumeSessionID != "" {
+		// A resuming receiver already has a session row with its prior
+		// BytesTransferred/ChunksTransferred; reuse it instead of creating a
+		// fresh zeroed one below, so session.SessionManager.ResumeSession
+		// still sees the bitmap this sessionID accumulated before whatever
+		// interrupted the transfer (a daemon restart, a dropped connection).
+		sessionID = resumeSessionID
+	}
+
+	// Resolve the full manifest by FileKey. In production this is fetched
+	// from the sender over the control stream's ManifestRequest handler, a
+	// call retryPolicy (when set) retries on transient failure; same-process
+	// callers (and tests) can resolve it directly here.
+	fetchManifest := func() error {
+		m, ok := s.GetManifestByFileKey(claims.FileKey)
+		if !ok {
+			return ErrManifestNotFetched
+		}
+		manifest = m
+		return nil
+	}
+	if retryPolicy != nil {
+		err = retryPolicy.Do(fetchManifest)
+	} else {
+		err = fetchManifest()
+	}
 	if err != nil {
 		return "", nil, err
 	}
+	s.eventPublisher.PublishManifestFetched(sessionID, claims.FileKey)
 
 	// Enforce medical strict gating
 	if manifest.Domain == "medical" {
@@ -245,6 +513,15 @@ func (s *TransferService) AcceptTransfer(
 		}
 	}
 
+	if resumeSessionID != "" {
+		if _, err := s.store.Get(resumeSessionID); err == nil {
+			// Already have this session (and its progress) in the store;
+			// nothing left to do but hand the manifest back so the caller
+			// can resolve its missing chunks via SessionManager.ResumeSession.
+			return sessionID, manifest, nil
+		}
+	}
+
 	// Create session
 	session := manager.NewSession(
 		sessionID,
@@ -263,6 +540,121 @@ func (s *TransferService) AcceptTransfer(
 	return sessionID, manifest, nil
 }
 
+// AcceptBundleTransfer accepts a directory/multi-file transfer, creating one
+// child manager.Session per selected chunker.BundleEntry (selector returning
+// false skips that entry entirely, e.g. to accept only files matching a
+// glob). Entries are processed in dependency order - an entry whose
+// EngineeringProfile.Dependencies names another bundle entry as DependsOn is
+// only accepted after that dependee - falling back to the bundle's own
+// ordering for entries with no recorded dependencies.
+func (s *TransferService) AcceptBundleTransfer(
+	token string,
+	selector func(entry chunker.BundleEntry) bool,
+	outDir string,
+) (sessionID string, bundle *chunker.Bundle, accepted map[string]*chunker.Manifest, err error) {
+	claims, err := s.parseBundleToken(token)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	sessionID = claims.SessionID
+
+	bundle, ok := s.GetBundleByKey(claims.BundleKey)
+	if !ok {
+		return "", nil, nil, ErrManifestNotFetched
+	}
+	s.eventPublisher.PublishManifestFetched(sessionID, claims.BundleKey)
+
+	var totalSize int64
+	for _, entry := range bundle.Entries {
+		totalSize += entry.Size
+	}
+	bundleSession := manager.NewSession(sessionID, outDir, bundle.RootName, totalSize, s.chunkSize, manager.DirectionReceive)
+	if err := s.store.Add(bundleSession); err != nil {
+		return "", nil, nil, err
+	}
+
+	accepted = make(map[string]*chunker.Manifest)
+	for _, entry := range s.orderBundleEntries(bundle.Entries) {
+		if selector != nil && !selector(entry) {
+			continue
+		}
+		manifest, ok := s.GetManifestByFileKey(entry.FileKey)
+		if !ok {
+			continue
+		}
+
+		childSession := manager.NewSession(
+			uuid.New().String(),
+			filepath.Join(outDir, entry.RelativePath),
+			filepath.Base(entry.RelativePath),
+			manifest.FileSize,
+			int64(manifest.ChunkSize),
+			manager.DirectionReceive,
+		)
+		childSession.ParentBundleID = sessionID
+		if err := s.store.Add(childSession); err != nil {
+			return "", nil, nil, err
+		}
+
+		accepted[entry.RelativePath] = manifest
+	}
+
+	return sessionID, bundle, accepted, nil
+}
+
+// orderBundleEntries returns entries ordered so that any entry named as a
+// DependsOn target in another entry's EngineeringProfile.Dependencies is
+// visited first (a depth-first post-order walk), preserving
+// the bundle's own ordering for entries with no recorded dependencies.
+func (s *TransferService) orderBundleEntries(entries []chunker.BundleEntry) []chunker.BundleEntry {
+	byPath := make(map[string]chunker.BundleEntry, len(entries))
+	for _, e := range entries {
+		byPath[e.RelativePath] = e
+	}
+
+	dependsOn := make(map[string]map[string]bool)
+	for _, e := range entries {
+		manifest, ok := s.GetManifestByFileKey(e.FileKey)
+		if !ok || manifest.EngineeringProfile == nil {
+			continue
+		}
+		for _, dep := range manifest.EngineeringProfile.Dependencies {
+			if _, isBundleMember := byPath[dep.Node]; !isBundleMember {
+				continue
+			}
+			for _, on := range dep.DependsOn {
+				if _, isBundleMember := byPath[on]; !isBundleMember {
+					continue
+				}
+				if dependsOn[dep.Node] == nil {
+					dependsOn[dep.Node] = make(map[string]bool)
+				}
+				dependsOn[dep.Node][on] = true
+			}
+		}
+	}
+
+	var ordered []chunker.BundleEntry
+	visited := make(map[string]bool)
+	var visit func(path string)
+	visit = func(path string) {
+		if visited[path] {
+			return
+		}
+		visited[path] = true
+		for dep := range dependsOn[path] {
+			visit(dep)
+		}
+		if e, ok := byPath[path]; ok {
+			ordered = append(ordered, e)
+		}
+	}
+	for _, e := range entries {
+		visit(e.RelativePath)
+	}
+	return ordered
+}
+
 // GetTransferStatus retrieves transfer status
 func (s *TransferService) GetTransferStatus(sessionID string) (*TransferStatus, error) {
 	session, err := s.store.Get(sessionID)
@@ -296,56 +688,269 @@ func (s *TransferService) GetPublicKey() (string, string) {
 	return pubKeyB64, fingerprint
 }
 
-// generateToken creates a transfer token
+// generateToken creates a transfer token with no specific recipient, for the
+// common case where any holder of the link may accept.
 func (s *TransferService) generateToken(sessionID string, manifest *chunker.Manifest) (string, error) {
-	tokenData := map[string]interface{}{
-		"session_id": sessionID,
-		"manifest":   manifest,
-		"created_at": time.Now().Unix(),
+	return s.IssueTokenFor(sessionID, "", defaultTokenTTL, manifest)
+}
+
+// IssueTokenFor issues a compact, Ed25519-signed transfer token scoped to
+// recipientPub (leave empty for no recipient restriction) that expires after
+// ttl. Distinct tokens for the same session can be issued to different
+// recipients; RevokeToken invalidates all of them at once, since revocation
+// is keyed by SessionID rather than by token.
+//
+// The token is three base64url segments separated by '.': header, claims,
+// signature - the signature covers "header.claims". The full manifest is
+// not embedded; it is registered under claims.FileKey and fetched lazily
+// via ManifestRequest/ManifestResponse on the control stream.
+func (s *TransferService) IssueTokenFor(sessionID string, recipientPub string, ttl time.Duration, manifest *chunker.Manifest) (string, error) {
+	fileKey, err := randomTokenComponent()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomTokenComponent()
+	if err != nil {
+		return "", err
+	}
+
+	claims := TransferTokenClaims{
+		SessionID:       sessionID,
+		FileKey:         fileKey,
+		ManifestHash:    manifest.MerkleRoot,
+		RecipientPubKey: recipientPub,
+		Exp:             time.Now().Add(ttl).Unix(),
+		Nonce:           nonce,
 	}
 
-	data, err := json.Marshal(tokenData)
+	header, err := json.Marshal(tokenHeader{Alg: "ed25519", V: 1})
 	if err != nil {
 		return "", err
 	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	headerB64 := base64.URLEncoding.EncodeToString(header)
+	claimsB64 := base64.URLEncoding.EncodeToString(claimsJSON)
+	signature := ed25519.Sign(s.privateKey, []byte(headerB64+"."+claimsB64))
+	sigB64 := base64.URLEncoding.EncodeToString(signature)
+
+	s.manifestsMu.Lock()
+	s.manifests[fileKey] = manifest
+	s.manifestsMu.Unlock()
 
-	token := base64.URLEncoding.EncodeToString(data)
-	return "quantarax://xfer?t=" + token, nil
+	s.eventPublisher.PublishManifestOffered(sessionID, fileKey)
+
+	return "quantarax://xfer?t=" + headerB64 + "." + claimsB64 + "." + sigB64, nil
 }
 
-// parseToken parses a transfer token
-func (s *TransferService) parseToken(token string) (string, *chunker.Manifest, error) {
-	// Remove protocol prefix
-	const prefix = "quantarax://xfer?t="
+// RevokeToken invalidates every outstanding token for sessionID; subsequent
+// AcceptTransfer calls against it fail with ErrTokenRevoked.
+func (s *TransferService) RevokeToken(sessionID string) error {
+	return s.revocations.Revoke(sessionID)
+}
+
+// GetManifestByFileKey resolves a manifest previously registered by
+// IssueTokenFor, for the control stream's ManifestRequest handler.
+func (s *TransferService) GetManifestByFileKey(fileKey string) (*chunker.Manifest, bool) {
+	s.manifestsMu.RLock()
+	defer s.manifestsMu.RUnlock()
+	m, ok := s.manifests[fileKey]
+	return m, ok
+}
+
+// ServeManifestRequests is the sender-side ManifestRequest handler:
+// it answers ManifestRequest messages on ctrl with the manifest registered
+// under the requested FileKey until the control stream errors, so callers
+// should run it in a goroutine. It is meant to run during the token
+// handshake, before SendWithOrchestration's ServeChunkRequests becomes the
+// control stream's sole reader for the rest of the transfer.
+func (s *TransferService) ServeManifestRequests(ctrl *transport.ControlStream) {
+	for {
+		req, err := ctrl.ReceiveManifestRequest()
+		if err != nil {
+			return
+		}
+		resp := &transport.ManifestResponse{FileKey: req.FileKey}
+		if manifest, ok := s.GetManifestByFileKey(req.FileKey); ok {
+			if data, err := json.Marshal(manifest); err == nil {
+				resp.ManifestJSON = data
+				resp.Found = true
+			}
+		}
+		_ = ctrl.SendManifestResponse(resp)
+	}
+}
+
+// GetBundleByKey resolves a bundle descriptor previously registered by
+// CreateBundleTransfer, for the control stream's BundleRequest handler.
+func (s *TransferService) GetBundleByKey(bundleKey string) (*chunker.Bundle, bool) {
+	s.bundlesMu.RLock()
+	defer s.bundlesMu.RUnlock()
+	b, ok := s.bundles[bundleKey]
+	return b, ok
+}
+
+// ServeBundleRequests is the sender-side BundleRequest handler: it answers
+// BundleRequest messages on ctrl with the bundle descriptor registered under
+// the requested BundleKey, until the control stream errors. Like
+// ServeManifestRequests, run it in a goroutine during the token handshake.
+func (s *TransferService) ServeBundleRequests(ctrl *transport.ControlStream) {
+	for {
+		req, err := ctrl.ReceiveBundleRequest()
+		if err != nil {
+			return
+		}
+		resp := &transport.BundleResponse{BundleKey: req.BundleKey}
+		if bundle, ok := s.GetBundleByKey(req.BundleKey); ok {
+			if data, err := json.Marshal(bundle); err == nil {
+				resp.BundleJSON = data
+				resp.Found = true
+			}
+		}
+		_ = ctrl.SendBundleResponse(resp)
+	}
+}
+
+// issueBundleToken issues a compact, Ed25519-signed token referencing
+// bundleKey, mirroring IssueTokenFor's format and signing scheme.
+func (s *TransferService) issueBundleToken(sessionID string, recipientPub string, ttl time.Duration, bundleKey string, bundle *chunker.Bundle) (string, error) {
+	nonce, err := randomTokenComponent()
+	if err != nil {
+		return "", err
+	}
+
+	claims := BundleTokenClaims{
+		SessionID:       sessionID,
+		BundleKey:       bundleKey,
+		BundleHash:      bundle.MerkleRoot,
+		RecipientPubKey: recipientPub,
+		Exp:             time.Now().Add(ttl).Unix(),
+		Nonce:           nonce,
+	}
+
+	header, err := json.Marshal(tokenHeader{Alg: "ed25519", V: 1})
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	headerB64 := base64.URLEncoding.EncodeToString(header)
+	claimsB64 := base64.URLEncoding.EncodeToString(claimsJSON)
+	signature := ed25519.Sign(s.privateKey, []byte(headerB64+"."+claimsB64))
+	sigB64 := base64.URLEncoding.EncodeToString(signature)
+
+	return "quantarax://xfer-bundle?t=" + headerB64 + "." + claimsB64 + "." + sigB64, nil
+}
+
+// parseBundleToken parses and verifies a bundle transfer token, returning its
+// claims. It does not resolve the bundle descriptor; callers fetch it
+// separately by BundleKey.
+func (s *TransferService) parseBundleToken(token string) (*BundleTokenClaims, error) {
+	const prefix = "quantarax://xfer-bundle?t="
 	if len(token) < len(prefix) {
-		return "", nil, ErrInvalidToken
+		return nil, ErrInvalidToken
+	}
+
+	parts := strings.Split(token[len(prefix):], ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
 	}
+	headerB64, claimsB64, sigB64 := parts[0], parts[1], parts[2]
 
-	encoded := token[len(prefix):]
-	data, err := base64.URLEncoding.DecodeString(encoded)
+	signature, err := base64.URLEncoding.DecodeString(sigB64)
 	if err != nil {
-		return "", nil, ErrInvalidToken
+		return nil, ErrInvalidToken
+	}
+	if !ed25519.Verify(s.publicKey, []byte(headerB64+"."+claimsB64), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	claimsData, err := base64.URLEncoding.DecodeString(claimsB64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims BundleTokenClaims
+	if err := json.Unmarshal(claimsData, &claims); err != nil {
+		return nil, ErrInvalidToken
 	}
 
-	var tokenData map[string]interface{}
-	if err := json.Unmarshal(data, &tokenData); err != nil {
-		return "", nil, ErrInvalidToken
+	if time.Now().Unix() > claims.Exp {
+		return nil, ErrTokenExpired
+	}
+	if s.revocations.IsRevoked(claims.SessionID) {
+		return nil, ErrTokenRevoked
+	}
+	if claims.RecipientPubKey != "" {
+		ownPub := base64.StdEncoding.EncodeToString(s.publicKey)
+		if claims.RecipientPubKey != ownPub {
+			return nil, ErrWrongRecipient
+		}
+	}
+
+	return &claims, nil
+}
+
+// parseToken parses and verifies a transfer token, returning its claims.
+// It does not resolve the manifest; callers fetch it separately by FileKey.
+func (s *TransferService) parseToken(token string) (*TransferTokenClaims, error) {
+	const prefix = "quantarax://xfer?t="
+	if len(token) < len(prefix) {
+		return nil, ErrInvalidToken
 	}
 
-	sessionID := tokenData["session_id"].(string)
+	parts := strings.Split(token[len(prefix):], ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	headerB64, claimsB64, sigB64 := parts[0], parts[1], parts[2]
 
-	// Parse manifest
-	manifestData, err := json.Marshal(tokenData["manifest"])
+	signature, err := base64.URLEncoding.DecodeString(sigB64)
 	if err != nil {
-		return "", nil, err
+		return nil, ErrInvalidToken
+	}
+	if !ed25519.Verify(s.publicKey, []byte(headerB64+"."+claimsB64), signature) {
+		return nil, ErrInvalidToken
 	}
 
-	var manifest chunker.Manifest
-	if err := json.Unmarshal(manifestData, &manifest); err != nil {
-		return "", nil, err
+	claimsData, err := base64.URLEncoding.DecodeString(claimsB64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims TransferTokenClaims
+	if err := json.Unmarshal(claimsData, &claims); err != nil {
+		return nil, ErrInvalidToken
 	}
 
-	return sessionID, &manifest, nil
+	if time.Now().Unix() > claims.Exp {
+		return nil, ErrTokenExpired
+	}
+	if s.revocations.IsRevoked(claims.SessionID) {
+		return nil, ErrTokenRevoked
+	}
+	if claims.RecipientPubKey != "" {
+		ownPub := base64.StdEncoding.EncodeToString(s.publicKey)
+		if claims.RecipientPubKey != ownPub {
+			return nil, ErrWrongRecipient
+		}
+	}
+
+	return &claims, nil
+}
+
+// randomTokenComponent returns a URL-safe random identifier suitable for use
+// as a FileKey or claim nonce.
+func randomTokenComponent() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate token component: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
 
 // TransferStatus represents transfer status information
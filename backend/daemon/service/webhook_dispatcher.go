@@ -0,0 +1,214 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/quantarax/backend/daemon/manager"
+)
+
+// webhookQueueDepth bounds how many pending events a single subscriber's
+// delivery queue holds; once full, new events for that subscriber are
+// dropped, the same non-blocking slow-consumer policy EventPublisher.publish
+// applies to its own subscription channels.
+const webhookQueueDepth = 256
+
+// webhookMaxRetries is how many delivery attempts (beyond the first) a
+// dispatcher makes before dead-lettering an event for one subscriber.
+const webhookMaxRetries = 5
+
+// webhookBackoffBase/Factor/Jitter tune the exponential-backoff delay
+// between retries, mirroring transport.BackoffConfig's shape.
+const (
+	webhookBackoffBase   = 500 * time.Millisecond
+	webhookBackoffFactor = 2.0
+	webhookBackoffJitter = 250 * time.Millisecond
+)
+
+// webhookEventPayload mirrors server.TransferEventJSON's wire shape. It's
+// redefined here rather than imported because daemon/api/server imports
+// this package, not the other way around.
+type webhookEventPayload struct {
+	SessionID       string            `json:"session_id"`
+	EventType       string            `json:"event_type"`
+	Timestamp       int64             `json:"timestamp"`
+	ProgressPercent float64           `json:"progress_percent"`
+	Message         string            `json:"message,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+// WebhookDispatcher consumes the same TransferEvent stream SSEHandler does
+// and pushes matching events to each registered manager.WebhookSubscriber
+// over HTTP: a bounded per-subscriber queue feeds a dedicated delivery
+// goroutine that retries with exponential backoff and jitter, dead-lettering
+// an event (via WebhookSubscriber.RecordDeadLetter) once webhookMaxRetries
+// is exhausted.
+type WebhookDispatcher struct {
+	store     *manager.WebhookStore
+	publisher *EventPublisher
+	client    *http.Client
+
+	mu     sync.Mutex
+	queues map[string]chan *TransferEvent
+}
+
+// NewWebhookDispatcher creates a dispatcher backed by store. Call Start to
+// begin consuming publisher's event stream.
+func NewWebhookDispatcher(store *manager.WebhookStore, publisher *EventPublisher) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		store:     store,
+		publisher: publisher,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		queues:    make(map[string]chan *TransferEvent),
+	}
+}
+
+// Start subscribes to the publisher's unfiltered event stream and fans
+// matching events out to registered subscribers until ctx is done.
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	sub := d.publisher.Subscribe(EventFilter{})
+	go func() {
+		defer d.publisher.Unsubscribe(sub.ID)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-sub.Channel:
+				if !ok {
+					return
+				}
+				d.fanOut(ev)
+			}
+		}
+	}()
+}
+
+// fanOut enqueues ev for delivery to every subscriber whose filter matches
+// it, lazily starting that subscriber's delivery worker on first use.
+func (d *WebhookDispatcher) fanOut(ev *TransferEvent) {
+	for _, sub := range d.store.List() {
+		if !webhookMatches(sub, ev) {
+			continue
+		}
+		d.enqueue(sub, ev)
+	}
+}
+
+func webhookMatches(sub *manager.WebhookSubscriber, ev *TransferEvent) bool {
+	if sub.SessionIDFilter != "" && sub.SessionIDFilter != ev.SessionID {
+		return false
+	}
+	if len(sub.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range sub.EventTypes {
+		if t == ev.EventType.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *WebhookDispatcher) enqueue(sub *manager.WebhookSubscriber, ev *TransferEvent) {
+	d.mu.Lock()
+	q, ok := d.queues[sub.ID]
+	if !ok {
+		q = make(chan *TransferEvent, webhookQueueDepth)
+		d.queues[sub.ID] = q
+		go d.worker(sub, q)
+	}
+	d.mu.Unlock()
+
+	select {
+	case q <- ev:
+	default:
+		// Subscriber's queue is full; drop rather than block the fan-out
+		// loop for every other subscriber.
+	}
+}
+
+func (d *WebhookDispatcher) worker(sub *manager.WebhookSubscriber, q chan *TransferEvent) {
+	for ev := range q {
+		d.deliver(sub, ev)
+	}
+}
+
+// deliver POSTs ev to sub.URL, retrying with backoff up to webhookMaxRetries
+// times before dead-lettering it.
+func (d *WebhookDispatcher) deliver(sub *manager.WebhookSubscriber, ev *TransferEvent) {
+	body, err := json.Marshal(webhookEventPayload{
+		SessionID:       ev.SessionID,
+		EventType:       ev.EventType.String(),
+		Timestamp:       ev.Timestamp.UnixMilli(),
+		ProgressPercent: ev.ProgressPercent,
+		Message:         ev.Message,
+		Metadata:        ev.Metadata,
+	})
+	if err != nil {
+		sub.RecordDeadLetter(err.Error())
+		return
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= webhookMaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(webhookBackoffDelay(attempt))
+		}
+		if err := d.post(sub, body); err != nil {
+			lastErr = err
+			sub.RecordFailed(err.Error())
+			continue
+		}
+		sub.RecordDelivered()
+		return
+	}
+	sub.RecordDeadLetter(lastErr.Error())
+}
+
+func (d *WebhookDispatcher) post(sub *manager.WebhookSubscriber, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sub.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+sub.AuthToken)
+	}
+	req.Header.Set("X-Quantarax-Signature", "sha256="+signWebhookBody(sub.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook delivery to %s failed: status %d", sub.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoffDelay returns the delay before retry number attempt
+// (1-indexed, since attempt 0 is the first, non-retried send).
+func webhookBackoffDelay(attempt int) time.Duration {
+	d := time.Duration(float64(webhookBackoffBase) * math.Pow(webhookBackoffFactor, float64(attempt-1)))
+	if webhookBackoffJitter > 0 {
+		d += time.Duration(rand.Int63n(int64(webhookBackoffJitter)))
+	}
+	return d
+}
@@ -0,0 +1,308 @@
+// Package session ties a manager.Session, its manager.ChunkBitmap, and the
+// originating manifest together as one resumable unit, so a transfer can
+// survive a daemon restart without the caller requiring --chunk-index or
+// --offset flags to pick back up where it left off.
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/quantarax/backend/daemon/manager"
+)
+
+// Config tunes SessionManager's background loops.
+type Config struct {
+	// KeepaliveInterval is how often active sessions are pinged and their
+	// progress reported to ProgressSink.
+	KeepaliveInterval time.Duration
+	// PruneInterval is how often the pruning goroutine sweeps BitmapTTL.
+	PruneInterval time.Duration
+	// BitmapTTL is how stale a bitmap's last_updated must be before
+	// BitmapStore.PruneStale deletes it. Pick something comfortably longer
+	// than any expected stall, since a pruned bitmap can't be resumed from.
+	BitmapTTL time.Duration
+	// LeaseTTL is how long a manager.LeaseStore lease granted by
+	// AcquireLease stays valid before it must be refreshed. Refresh runs
+	// every LeaseTTL/3, so a holder gets two missed refreshes of slack
+	// before a crash-recovery daemon would consider the lease expired.
+	LeaseTTL time.Duration
+}
+
+// DefaultConfig returns the tuning SessionManager uses when the daemon
+// doesn't override it.
+func DefaultConfig() Config {
+	return Config{
+		KeepaliveInterval: 15 * time.Second,
+		PruneInterval:     5 * time.Minute,
+		BitmapTTL:         72 * time.Hour,
+		LeaseTTL:          30 * time.Second,
+	}
+}
+
+// Pinger sends a keepalive to the peer sessionID is talking to, over
+// whatever transport owns that connection (typically a QUIC control
+// stream's SendStatus). A nil Pinger disables keepalives without disabling
+// resume: this package doesn't import daemon/transport, the same way authn
+// doesn't import daemon/config, so a caller that owns a live connection
+// wires it in instead.
+type Pinger interface {
+	Ping(sessionID string) error
+}
+
+// ProgressSink publishes per-session resume/keepalive progress onto the
+// daemon's event pipeline (see service.EventPublisher.PublishResumeProgress)
+// without this package importing daemon/service, which itself needs to call
+// SessionManager.ResumeSession from TransferService.AcceptTransfer - the
+// same inverted-dependency concern authn.BuildChain's doc comment explains.
+type ProgressSink interface {
+	PublishResumeProgress(sessionID string, receivedChunks, totalChunks int64, lastPeer string, stalledFor time.Duration)
+}
+
+// peerState is SessionManager's last-seen bookkeeping for one session, used
+// to compute the "stalled duration" ProgressSink reports.
+type peerState struct {
+	lastPeer string
+	lastSeen time.Time
+}
+
+// SessionManager reconciles manager.SessionStore rows with their
+// manager.BitmapStore bitmaps, exposes ResumeSession for the gRPC/REST API,
+// and runs the keepalive and bitmap-pruning background loops.
+type SessionManager struct {
+	sessions *manager.SessionStore
+	bitmaps  *manager.BitmapStore
+	leases   *manager.LeaseStore
+	cfg      Config
+	pinger   Pinger
+	sink     ProgressSink
+
+	mu    sync.Mutex
+	peers map[string]*peerState
+
+	cancel context.CancelFunc
+}
+
+// NewSessionManager creates a SessionManager over sessions/bitmaps. pinger
+// and sink may both be nil, disabling keepalive pings and progress
+// reporting respectively while resume and pruning still work. leases may
+// also be nil, in which case AcquireLease is a no-op that always succeeds
+// and crash recovery can't distinguish a clean shutdown from a crash - the
+// daemon should only run without a LeaseStore in tests.
+func NewSessionManager(sessions *manager.SessionStore, bitmaps *manager.BitmapStore, leases *manager.LeaseStore, cfg Config, pinger Pinger, sink ProgressSink) *SessionManager {
+	return &SessionManager{
+		sessions: sessions,
+		bitmaps:  bitmaps,
+		leases:   leases,
+		cfg:      cfg,
+		pinger:   pinger,
+		sink:     sink,
+		peers:    make(map[string]*peerState),
+	}
+}
+
+// Start reconciles every session already in the store against its
+// persisted bitmap, then launches the keepalive and pruning goroutines.
+// Start returns once reconciliation completes; the background loops run
+// until ctx is cancelled or Stop is called.
+func (m *SessionManager) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+
+	if err := m.reconcile(); err != nil {
+		cancel()
+		return err
+	}
+
+	go m.keepaliveLoop(ctx)
+	go m.pruneLoop(ctx)
+	return nil
+}
+
+// Stop cancels the background loops started by Start. Safe to call even if
+// Start was never called.
+func (m *SessionManager) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// reconcile makes sure every incomplete receive session already in the
+// store has a corresponding chunk_bitmaps row, so ResumeSession works for
+// sessions that existed before this SessionManager did - e.g. one restored
+// by SessionStore.RestoreSnapshot after an admin-triggered restart. It also
+// reclaims any session lease left over from a process that died without
+// releasing it: a session still StateActive under a reclaimed lease is
+// rehydrated to StateResuming, since its bitmap is intact but nothing is
+// actively feeding it chunks until a peer reconnects.
+func (m *SessionManager) reconcile() error {
+	if err := m.reclaimLeases(); err != nil {
+		return err
+	}
+
+	sessions, _ := m.sessions.List(nil, 0, 0)
+	for _, sess := range sessions {
+		if sess.Direction != manager.DirectionReceive || sess.TotalChunks == 0 {
+			continue
+		}
+		if state := sess.GetState(); state == manager.StateCompleted || state == manager.StateFailed {
+			continue
+		}
+		if _, err := m.bitmaps.LoadBitmap(sess.ID, sess.TotalChunks); err == manager.ErrBitmapNotFound {
+			if err := m.bitmaps.SaveBitmap(manager.NewChunkBitmap(sess.ID, sess.TotalChunks)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reclaimLeases drops every expired lease in m.leases and, for each
+// session that was StateActive under a reclaimed lease, rehydrates it
+// into StateResuming directly rather than through TransitionTo: this runs
+// once at startup before any peer has reconnected, so there's no
+// OnTransition hook side effect to worry about triggering prematurely.
+func (m *SessionManager) reclaimLeases() error {
+	if m.leases == nil {
+		return nil
+	}
+	reclaimed, err := m.leases.ReclaimExpired()
+	if err != nil {
+		return err
+	}
+	for _, sessionID := range reclaimed {
+		sess, err := m.sessions.Get(sessionID)
+		if err != nil {
+			continue
+		}
+		if sess.GetState() == manager.StateActive {
+			sess.RehydrateState(manager.StateResuming)
+		}
+	}
+	return nil
+}
+
+// AcquireLease takes out a manager.LeaseStore lease on sessionID under
+// holder and starts a background goroutine that refreshes it every
+// cfg.LeaseTTL/3 until the returned cancel func is called. Callers -
+// handleConnection and the REST handlers that mutate a session - must
+// defer cancel() so the lease (and its refresh goroutine) never outlives
+// the operation that acquired it, even on an error path. If no LeaseStore
+// was configured, AcquireLease always succeeds and cancel is a no-op.
+func (m *SessionManager) AcquireLease(sessionID, holder string) (cancel func(), err error) {
+	if m.leases == nil {
+		return func() {}, nil
+	}
+	token, release, err := m.leases.Acquire(sessionID, holder, m.cfg.LeaseTTL)
+	if err != nil {
+		return func() {}, err
+	}
+
+	ctx, cancelRefresh := context.WithCancel(context.Background())
+	go m.refreshLeaseLoop(ctx, token)
+	return func() {
+		cancelRefresh()
+		release()
+	}, nil
+}
+
+func (m *SessionManager) refreshLeaseLoop(ctx context.Context, token manager.LeaseToken) {
+	interval := token.TTL / 3
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if refreshed, err := m.leases.Refresh(token); err == nil {
+				token = refreshed
+			}
+		}
+	}
+}
+
+// ResumeSession returns the chunk indices sessionID is still missing,
+// reconciled against its persisted bitmap, so a sender can re-request only
+// those chunks instead of requiring --chunk-index/--offset on the command
+// line.
+func (m *SessionManager) ResumeSession(sessionID string) (missing []int64, totalChunks int64, err error) {
+	sess, err := m.sessions.Get(sessionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	bitmap, err := m.bitmaps.LoadBitmap(sessionID, sess.TotalChunks)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bitmap.GetMissing(), sess.TotalChunks, nil
+}
+
+// Touch records that sessionID just heard from peer, resetting the stalled
+// timer ProgressSink reports. Transport code calls this on every received
+// chunk or control message.
+func (m *SessionManager) Touch(sessionID, peer string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.peers[sessionID] = &peerState{lastPeer: peer, lastSeen: time.Now()}
+}
+
+func (m *SessionManager) keepaliveLoop(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.KeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sendKeepalives()
+		}
+	}
+}
+
+func (m *SessionManager) sendKeepalives() {
+	sessions, _ := m.sessions.List(nil, 0, 0)
+	for _, sess := range sessions {
+		if sess.GetState() != manager.StateActive {
+			continue
+		}
+		if m.pinger != nil {
+			_ = m.pinger.Ping(sess.ID)
+		}
+		m.reportProgress(sess)
+	}
+}
+
+func (m *SessionManager) reportProgress(sess *manager.Session) {
+	if m.sink == nil {
+		return
+	}
+	m.mu.Lock()
+	state, ok := m.peers[sess.ID]
+	m.mu.Unlock()
+
+	var lastPeer string
+	var stalledFor time.Duration
+	if ok {
+		lastPeer = state.lastPeer
+		stalledFor = time.Since(state.lastSeen)
+	}
+	m.sink.PublishResumeProgress(sess.ID, sess.ChunksTransferred, sess.TotalChunks, lastPeer, stalledFor)
+}
+
+func (m *SessionManager) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.PruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = m.bitmaps.PruneStale(m.cfg.BitmapTTL)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/quantarax/backend/daemon/manager"
+	"github.com/quantarax/backend/daemon/manager/migrations"
+	_ "modernc.org/sqlite"
+)
+
+func newTestStores(t *testing.T) (*manager.SessionStore, *manager.BitmapStore) {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open in-memory database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migrations.Run(db, migrations.Registry); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return manager.NewSessionStore(), manager.NewBitmapStore(db)
+}
+
+func TestSessionManager_StartReconcilesMissingBitmap(t *testing.T) {
+	sessions, bitmaps := newTestStores(t)
+	sess := manager.NewSession("sess-1", "/tmp/out", "out.bin", 1000, 100, manager.DirectionReceive)
+	if err := sessions.Add(sess); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	mgr := NewSessionManager(sessions, bitmaps, nil, DefaultConfig(), nil, nil)
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer mgr.Stop()
+
+	missing, total, err := mgr.ResumeSession("sess-1")
+	if err != nil {
+		t.Fatalf("ResumeSession failed: %v", err)
+	}
+	if total != sess.TotalChunks {
+		t.Errorf("expected total chunks %d, got %d", sess.TotalChunks, total)
+	}
+	if len(missing) != int(total) {
+		t.Errorf("expected every chunk missing for a freshly-reconciled session, got %d missing of %d", len(missing), total)
+	}
+}
+
+func TestSessionManager_ResumeSessionReflectsReceivedChunks(t *testing.T) {
+	sessions, bitmaps := newTestStores(t)
+	sess := manager.NewSession("sess-2", "/tmp/out", "out.bin", 500, 100, manager.DirectionReceive)
+	if err := sessions.Add(sess); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	bitmap := manager.NewChunkBitmap("sess-2", sess.TotalChunks)
+	if err := bitmap.SetChunk(0); err != nil {
+		t.Fatalf("SetChunk failed: %v", err)
+	}
+	if err := bitmaps.SaveBitmap(bitmap); err != nil {
+		t.Fatalf("SaveBitmap failed: %v", err)
+	}
+
+	mgr := NewSessionManager(sessions, bitmaps, nil, DefaultConfig(), nil, nil)
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer mgr.Stop()
+
+	missing, _, err := mgr.ResumeSession("sess-2")
+	if err != nil {
+		t.Fatalf("ResumeSession failed: %v", err)
+	}
+	for _, idx := range missing {
+		if idx == 0 {
+			t.Errorf("chunk 0 was already received and should not be in the missing list")
+		}
+	}
+	if len(missing) != int(sess.TotalChunks)-1 {
+		t.Errorf("expected %d missing chunks, got %d", sess.TotalChunks-1, len(missing))
+	}
+}
+
+type fakeSink struct {
+	sessionID      string
+	receivedChunks int64
+	totalChunks    int64
+	lastPeer       string
+	stalledFor     time.Duration
+	calls          int
+}
+
+func (f *fakeSink) PublishResumeProgress(sessionID string, receivedChunks, totalChunks int64, lastPeer string, stalledFor time.Duration) {
+	f.sessionID = sessionID
+	f.receivedChunks = receivedChunks
+	f.totalChunks = totalChunks
+	f.lastPeer = lastPeer
+	f.stalledFor = stalledFor
+	f.calls++
+}
+
+func TestSessionManager_ReportProgressUsesTouchedPeer(t *testing.T) {
+	sessions, bitmaps := newTestStores(t)
+	sess := manager.NewSession("sess-3", "/tmp/out", "out.bin", 500, 100, manager.DirectionReceive)
+	if err := sess.TransitionTo(manager.StateActive, ""); err != nil {
+		t.Fatalf("TransitionTo failed: %v", err)
+	}
+	if err := sessions.Add(sess); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	sink := &fakeSink{}
+	mgr := NewSessionManager(sessions, bitmaps, nil, DefaultConfig(), nil, sink)
+	mgr.Touch("sess-3", "peer-42")
+	mgr.reportProgress(sess)
+
+	if sink.calls != 1 {
+		t.Fatalf("expected PublishResumeProgress to be called once, got %d", sink.calls)
+	}
+	if sink.sessionID != "sess-3" || sink.lastPeer != "peer-42" {
+		t.Errorf("unexpected sink state: %+v", sink)
+	}
+}
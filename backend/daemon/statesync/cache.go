@@ -0,0 +1,37 @@
+package statesync
+
+import (
+	"encoding/hex"
+	"sync"
+
+	"github.com/quantarax/backend/daemon/transport"
+)
+
+// SnapshotCache caches snapshots by content hash. Snapshots are immutable
+// once issued, so a cache hit always returns the exact response previously
+// stored for that hash rather than letting a later, differently-constructed
+// SnapshotResponse silently replace it.
+type SnapshotCache struct {
+	mu    sync.RWMutex
+	byKey map[string]*transport.SnapshotResponse
+}
+
+// NewSnapshotCache creates an empty snapshot cache.
+func NewSnapshotCache() *SnapshotCache {
+	return &SnapshotCache{byKey: make(map[string]*transport.SnapshotResponse)}
+}
+
+// GetOrStore returns the cached snapshot for resp's content hash if one
+// already exists, otherwise it stores resp and returns it. The second
+// return value reports whether resp was already cached.
+func (c *SnapshotCache) GetOrStore(resp *transport.SnapshotResponse) (*transport.SnapshotResponse, bool) {
+	key := hex.EncodeToString(Hash(resp))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.byKey[key]; ok {
+		return existing, true
+	}
+	c.byKey[key] = resp
+	return resp, false
+}
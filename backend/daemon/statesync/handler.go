@@ -0,0 +1,28 @@
+package statesync
+
+import (
+	"crypto/ed25519"
+	"errors"
+
+	"github.com/quantarax/backend/daemon/transport"
+)
+
+// ErrUnknownSession is returned by a handler built with NewSnapshotHandler
+// when a SnapshotRequest names a session other than the one it was built
+// for.
+var ErrUnknownSession = errors.New("statesync: snapshot requested for unknown session")
+
+// NewSnapshotHandler returns a function suitable for
+// transport.OrchestratedSender.SetSnapshotHandler: it answers every
+// SnapshotRequest for sessionID with a freshly signed snapshot built from
+// the sender's own view of the transfer (manifestRoot, chunkHashes and
+// committedChunks are fixed at construction time, matching the sender-side
+// values recorded when the manifest was offered).
+func NewSnapshotHandler(sessionID string, manifestRoot []byte, chunkSize, totalChunks int64, committedChunks []int64, chunkHashes []string, privateKey ed25519.PrivateKey) func(*transport.SnapshotRequest) (*transport.SnapshotResponse, error) {
+	return func(req *transport.SnapshotRequest) (*transport.SnapshotResponse, error) {
+		if req.SessionID != sessionID {
+			return nil, ErrUnknownSession
+		}
+		return BuildSnapshot(sessionID, manifestRoot, chunkSize, totalChunks, committedChunks, chunkHashes, privateKey)
+	}
+}
@@ -0,0 +1,121 @@
+package statesync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/quantarax/backend/daemon/manager"
+	"github.com/quantarax/backend/daemon/service"
+	"github.com/quantarax/backend/daemon/transport"
+)
+
+// EventPublisher is the subset of service.EventPublisher's API the reactor
+// needs. Declared as an interface so a Reactor can be constructed with a nil
+// publisher (or a test double) without this package forcing one in.
+type EventPublisher interface {
+	PublishSnapshotRequested(sessionID string)
+	PublishSnapshotApplied(sessionID string, appliedChunks int)
+}
+
+var _ EventPublisher = (*service.EventPublisher)(nil)
+
+// Reactor drives the receiver side of a snapshot-based resume: on request it
+// pulls a signed snapshot from the sender, verifies it against the manifest
+// root pinned for the session, diffs the sender's committed-chunk bitmap
+// against the receiver's own, and re-requests whatever is still missing.
+type Reactor struct {
+	sessionID          string
+	pinnedManifestRoot []byte
+	control            *transport.ControlStream
+	bitmap             *manager.ChunkBitmap
+	cache              *SnapshotCache
+	events             EventPublisher
+}
+
+// NewReactor creates a Reactor for sessionID. pinnedManifestRoot is the
+// manifest root the receiver originally accepted for this session - every
+// snapshot pulled through this Reactor is checked against it, never against
+// whatever root the snapshot itself claims. bitmap tracks chunks the
+// receiver already has; events may be nil to disable event publication.
+func NewReactor(sessionID string, pinnedManifestRoot []byte, control *transport.ControlStream, bitmap *manager.ChunkBitmap, cache *SnapshotCache, events EventPublisher) *Reactor {
+	return &Reactor{
+		sessionID:          sessionID,
+		pinnedManifestRoot: pinnedManifestRoot,
+		control:            control,
+		bitmap:             bitmap,
+		cache:              cache,
+		events:             events,
+	}
+}
+
+// Resume requests a snapshot for the reactor's session, validates it, and
+// re-requests (via a ChunkRequest pull, the same mechanism a NACK uses)
+// every chunk the snapshot reports as committed on the sender but that the
+// receiver's own bitmap doesn't have yet. It returns the number of chunk
+// indices re-requested.
+func (r *Reactor) Resume(ctx context.Context) (int, error) {
+	if r.events != nil {
+		r.events.PublishSnapshotRequested(r.sessionID)
+	}
+
+	if err := r.control.SendSnapshotRequest(&transport.SnapshotRequest{SessionID: r.sessionID}); err != nil {
+		return 0, fmt.Errorf("statesync: send snapshot request: %w", err)
+	}
+
+	resp, err := r.control.ReceiveSnapshotResponse()
+	if err != nil {
+		return 0, fmt.Errorf("statesync: receive snapshot response: %w", err)
+	}
+
+	if err := VerifySnapshot(resp, r.pinnedManifestRoot); err != nil {
+		return 0, err
+	}
+
+	resp, _ = r.cache.GetOrStore(resp)
+
+	var comp transport.ChunkRangeCompressor
+	committed, err := comp.Decompress(resp.CommittedRanges)
+	if err != nil {
+		return 0, fmt.Errorf("statesync: decompress committed ranges: %w", err)
+	}
+
+	missing := make([]int64, 0, len(committed))
+	for _, idx := range committed {
+		if !r.bitmap.HasChunk(idx) {
+			missing = append(missing, idx)
+		}
+	}
+
+	if len(missing) > 0 {
+		if err := r.control.SendChunkRequest(&transport.ChunkRequest{
+			SessionID: r.sessionID,
+			Indices:   missing,
+			Timestamp: time.Now().Unix(),
+		}); err != nil {
+			return 0, fmt.Errorf("statesync: send chunk request: %w", err)
+		}
+	}
+
+	if r.events != nil {
+		r.events.PublishSnapshotApplied(r.sessionID, len(missing))
+	}
+	return len(missing), nil
+}
+
+// AttachToSession registers the Reactor on sess's OnTransition hook so that
+// moving from StatePaused back to StateActive triggers a snapshot-based
+// resume instead of sess's owner re-scanning the whole transfer from
+// scratch. Resume runs in its own goroutine since OnTransition fires
+// synchronously from within TransitionTo and a network round-trip has no
+// place blocking that call.
+func (r *Reactor) AttachToSession(sess *manager.Session) {
+	sess.OnTransition = func(from, to manager.TransferState) {
+		if from != manager.StatePaused || to != manager.StateActive {
+			return
+		}
+		go func() {
+			_, _ = r.Resume(context.Background())
+		}()
+	}
+}
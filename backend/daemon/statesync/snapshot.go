@@ -0,0 +1,131 @@
+// Package statesync lets a receiver resume a partially completed transfer
+// by pulling a compact, signed snapshot of session state from the sender
+// instead of re-scanning everything. It sits parallel to manager and
+// transport, depending on both, so that neither of those packages needs to
+// know this package exists.
+package statesync
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/quantarax/backend/daemon/transport"
+	"github.com/quantarax/backend/internal/chunker"
+	"github.com/zeebo/blake3"
+)
+
+// proofSampleSize is the fixed number of chunk indices a snapshot carries
+// Merkle proofs for. Spreading these evenly across the file (rather than,
+// say, just the first few chunks) means a snapshot that passes verification
+// gives some confidence about the sender's claimed bitmap across the whole
+// transfer, not just its beginning.
+const proofSampleSize = 8
+
+// sampleProofIndices picks up to proofSampleSize indices evenly spaced
+// across [0, totalChunks), always including the first and last index.
+func sampleProofIndices(totalChunks int64) []int64 {
+	if totalChunks <= 0 {
+		return nil
+	}
+	if totalChunks <= proofSampleSize {
+		indices := make([]int64, totalChunks)
+		for i := range indices {
+			indices[i] = int64(i)
+		}
+		return indices
+	}
+
+	indices := make([]int64, 0, proofSampleSize)
+	step := float64(totalChunks-1) / float64(proofSampleSize-1)
+	seen := make(map[int64]bool, proofSampleSize)
+	for i := 0; i < proofSampleSize; i++ {
+		idx := int64(float64(i) * step)
+		if !seen[idx] {
+			seen[idx] = true
+			indices = append(indices, idx)
+		}
+	}
+	return indices
+}
+
+// BuildSnapshot assembles a signed snapshot of a sender's transfer state for
+// sessionID: manifestRoot and chunkHashes describe the manifest the sender
+// is serving, committedChunks is the set of chunk indices it currently has
+// available to send, and privateKey is the sender's own identity key used
+// to sign the result (mirroring manager.MerkleVerifier.SignVerificationResult).
+func BuildSnapshot(sessionID string, manifestRoot []byte, chunkSize, totalChunks int64, committedChunks []int64, chunkHashes []string, privateKey ed25519.PrivateKey) (*transport.SnapshotResponse, error) {
+	var comp transport.ChunkRangeCompressor
+
+	sampleIndices := sampleProofIndices(totalChunks)
+	proof := make([]transport.SnapshotProofEntry, 0, len(sampleIndices))
+	for _, idx := range sampleIndices {
+		if idx < 0 || int(idx) >= len(chunkHashes) {
+			return nil, fmt.Errorf("statesync: proof index %d out of range for %d chunk hashes", idx, len(chunkHashes))
+		}
+		leaf, err := base64.StdEncoding.DecodeString(chunkHashes[idx])
+		if err != nil {
+			return nil, fmt.Errorf("statesync: decode chunk hash %d: %w", idx, err)
+		}
+		path, err := chunker.BuildMerkleProof(chunkHashes, int(idx))
+		if err != nil {
+			return nil, fmt.Errorf("statesync: build proof for chunk %d: %w", idx, err)
+		}
+		proof = append(proof, transport.SnapshotProofEntry{ChunkIndex: idx, LeafHash: leaf, Path: path})
+	}
+
+	resp := &transport.SnapshotResponse{
+		SessionID:       sessionID,
+		ManifestRoot:    manifestRoot,
+		ChunkSize:       chunkSize,
+		TotalChunks:     totalChunks,
+		CommittedRanges: comp.Compress(committedChunks),
+		Proof:           proof,
+	}
+
+	if err := signSnapshot(resp, privateKey); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// canonicalSnapshotBytes returns the bytes signed/verified for resp: every
+// field except the signature and public key themselves, in a fixed order,
+// mirroring control_stream.go's chunkProofCanonicalBytes.
+func canonicalSnapshotBytes(resp *transport.SnapshotResponse) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(resp.SessionID)
+	buf.Write(resp.ManifestRoot)
+	_ = binary.Write(&buf, binary.BigEndian, resp.ChunkSize)
+	_ = binary.Write(&buf, binary.BigEndian, resp.TotalChunks)
+	buf.WriteString(resp.CommittedRanges)
+	_ = binary.Write(&buf, binary.BigEndian, resp.Timestamp)
+	for _, entry := range resp.Proof {
+		_ = binary.Write(&buf, binary.BigEndian, entry.ChunkIndex)
+		buf.Write(entry.LeafHash)
+		for _, sibling := range entry.Path {
+			buf.Write(sibling)
+		}
+	}
+	return buf.Bytes()
+}
+
+// signSnapshot signs resp in place with privateKey, stamping Timestamp first
+// so the signature covers it.
+func signSnapshot(resp *transport.SnapshotResponse, privateKey ed25519.PrivateKey) error {
+	resp.Timestamp = time.Now().Unix()
+	resp.PublicKey = privateKey.Public().(ed25519.PublicKey)
+	resp.Signature = ed25519.Sign(privateKey, canonicalSnapshotBytes(resp))
+	return nil
+}
+
+// Hash returns a content-derived identifier for resp, used to cache
+// snapshots by content: since a snapshot is immutable once issued, two
+// responses with the same Hash are the same snapshot.
+func Hash(resp *transport.SnapshotResponse) []byte {
+	sum := blake3.Sum256(append(canonicalSnapshotBytes(resp), resp.Signature...))
+	return sum[:]
+}
@@ -0,0 +1,45 @@
+package statesync
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+
+	"github.com/quantarax/backend/daemon/transport"
+	"github.com/quantarax/backend/internal/chunker"
+)
+
+var (
+	// ErrManifestRootMismatch is returned when a snapshot's ManifestRoot
+	// disagrees with the root the receiver originally accepted for the
+	// session. A resuming receiver must reject it outright rather than
+	// trust a snapshot for what may be a different file under the same
+	// session ID - this is the key invariant statesync enforces.
+	ErrManifestRootMismatch = errors.New("statesync: snapshot manifest root does not match pinned root")
+	ErrInvalidSnapshotSignature = errors.New("statesync: invalid snapshot signature")
+	ErrInvalidSnapshotProof     = errors.New("statesync: snapshot Merkle proof failed")
+)
+
+// VerifySnapshot checks resp's signature and Merkle proof sample against
+// pinnedManifestRoot, the manifest root the receiver originally accepted for
+// this session. It returns ErrManifestRootMismatch without even checking the
+// signature if resp.ManifestRoot disagrees, since a snapshot for the wrong
+// manifest is never acceptable regardless of who signed it.
+func VerifySnapshot(resp *transport.SnapshotResponse, pinnedManifestRoot []byte) error {
+	if !bytes.Equal(resp.ManifestRoot, pinnedManifestRoot) {
+		return ErrManifestRootMismatch
+	}
+
+	if !ed25519.Verify(resp.PublicKey, canonicalSnapshotBytes(resp), resp.Signature) {
+		return ErrInvalidSnapshotSignature
+	}
+
+	for _, entry := range resp.Proof {
+		if !chunker.VerifyProof(entry.LeafHash, int(entry.ChunkIndex), entry.Path, resp.ManifestRoot) {
+			return fmt.Errorf("%w: chunk %d", ErrInvalidSnapshotProof, entry.ChunkIndex)
+		}
+	}
+
+	return nil
+}
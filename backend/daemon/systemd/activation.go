@@ -0,0 +1,132 @@
+// Package systemd lets the daemon be managed as a native systemd service:
+// GenerateUnit/GenerateSocketUnits emit unit files for `quantarax-daemon
+// --generate-unit`, and ListenFDs/Notify implement the systemd socket
+// activation and sd_notify protocols respectively, without a cgo
+// dependency on libsystemd.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first inherited file descriptor systemd hands a
+// socket-activated process; fd 0-2 are still stdin/stdout/stderr.
+const listenFDsStart = 3
+
+// ListenFDs inspects LISTEN_PID/LISTEN_FDS/LISTEN_FDNAMES and returns the
+// file descriptors systemd pre-bound for this process, keyed by the name
+// given in the unit's FileDescriptorName= (or "LISTEN_FDNAMES" entry,
+// which socket units default to their unit name). It returns an empty map
+// and no error when LISTEN_FDS isn't set or doesn't target this PID - the
+// ordinary "not socket-activated" case a plain `quantarax-daemon` launch
+// hits every time.
+//
+// If unsetEnv is true, the LISTEN_* environment variables are cleared
+// after reading so a child process this daemon might exec (e.g. the
+// re-exec'd binary in daemonAdminController.Restart) doesn't also try to
+// adopt the same descriptors.
+func ListenFDs(unsetEnv bool) (map[string]*os.File, error) {
+	if unsetEnv {
+		defer func() {
+			os.Unsetenv("LISTEN_PID")
+			os.Unsetenv("LISTEN_FDS")
+			os.Unsetenv("LISTEN_FDNAMES")
+		}()
+	}
+
+	pidStr := os.Getenv("LISTEN_PID")
+	fdsStr := os.Getenv("LISTEN_FDS")
+	if pidStr == "" || fdsStr == "" {
+		return map[string]*os.File{}, nil
+	}
+	pid, err := strconv.Atoi(pidStr)
+	if err != nil {
+		return nil, fmt.Errorf("systemd: invalid LISTEN_PID %q: %w", pidStr, err)
+	}
+	if pid != os.Getpid() {
+		// Not meant for us - e.g. inherited across a fork systemd didn't
+		// initiate for this process.
+		return map[string]*os.File{}, nil
+	}
+	n, err := strconv.Atoi(fdsStr)
+	if err != nil {
+		return nil, fmt.Errorf("systemd: invalid LISTEN_FDS %q: %w", fdsStr, err)
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	files := make(map[string]*os.File, n)
+	for i := 0; i < n; i++ {
+		fd := listenFDsStart + i
+		name := fmt.Sprintf("fd%d", fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		setCloexec(uintptr(fd))
+		files[name] = os.NewFile(uintptr(fd), name)
+	}
+	return files, nil
+}
+
+// TCPListener adopts files[name] as a *net.TCPListener, for a named socket
+// unit (e.g. "quantarax-grpc.socket") whose ListenStream= binds the same
+// address as the corresponding -grpc-addr/-rest-addr flag. It returns nil
+// if name isn't present, so a caller can fall back to binding the address
+// itself.
+func TCPListener(files map[string]*os.File, name string) (net.Listener, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, nil
+	}
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("systemd: adopting listener %q: %w", name, err)
+	}
+	return l, nil
+}
+
+// UDPConn adopts files[name] as a *net.UDPConn, for the QUIC socket unit's
+// ListenDatagram=. Returns nil if name isn't present.
+func UDPConn(files map[string]*os.File, name string) (*net.UDPConn, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, nil
+	}
+	conn, err := net.FilePacketConn(f)
+	if err != nil {
+		return nil, fmt.Errorf("systemd: adopting datagram socket %q: %w", name, err)
+	}
+	udpConn, ok := conn.(*net.UDPConn)
+	if !ok {
+		return nil, fmt.Errorf("systemd: socket %q is not a UDP datagram socket", name)
+	}
+	return udpConn, nil
+}
+
+// Notify sends state to the supervisor named in NOTIFY_SOCKET (sd_notify
+// protocol), the usual "READY=1"/"RELOADING=1"/"STOPPING=1"/"STATUS=..."
+// strings systemd's service manager understands. It's a no-op returning
+// (false, nil) when NOTIFY_SOCKET isn't set - i.e. the daemon isn't
+// running under systemd (or an equivalent that sets it) at all.
+func Notify(unsetEnv bool, state string) (bool, error) {
+	if unsetEnv {
+		defer os.Unsetenv("NOTIFY_SOCKET")
+	}
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return false, nil
+	}
+
+	conn, err := net.Dial("unixgram", addr)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package systemd
+
+import "syscall"
+
+// setCloexec marks fd close-on-exec, so a re-exec'd process (see
+// daemonAdminController.Restart) doesn't inherit a systemd-provided
+// listener a second time via the new process's own LISTEN_FDS - it gets
+// the fd explicitly passed via QUANTARAX_LISTEN_FD instead, same as the
+// SO_REUSEPORT handoff path.
+func setCloexec(fd uintptr) {
+	syscall.CloseOnExec(int(fd))
+}
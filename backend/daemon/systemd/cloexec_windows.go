@@ -0,0 +1,7 @@
+//go:build windows
+
+package systemd
+
+// setCloexec is a no-op on windows, which has no LISTEN_FDS-based socket
+// activation for this daemon to support in the first place.
+func setCloexec(fd uintptr) {}
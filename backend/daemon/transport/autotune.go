@@ -37,12 +37,28 @@ func computeBDPChunk(network *chunker.NetworkProfile, streams int) int {
 	return clampChunkBytes(perStream)
 }
 
+// overheadBackoffThreshold is the fraction of raw bytes beyond useful
+// payload bytes (retransmits, headers, failed writes) past which
+// applyOverheadBackoff trims the probe-phase streams/chunkBytes decision
+// back down, on the theory that a path already paying heavy retransmit
+// overhead won't benefit from more parallel streams piling on more loss.
+const overheadBackoffThreshold = 0.25
+
 // AutoTuner periodically adjusts worker streams (8..16) and chunk sizes (256KiB..8MiB)
-// based on coarse network profile. Hooks can be extended to use live metrics.
+// based on coarse network profile, tempered each tick by how much raw
+// on-wire bandwidth is going to overhead rather than useful payload (see
+// applyOverheadBackoff).
 type AutoTuner struct {
 	orch     *OrchestratedSender
 	manifest *chunker.Manifest
 	quit     chan struct{}
+
+	// lastRawOut and lastUsefulOut are the orchestrator's BandwidthMeter
+	// totals as of the previous tick, so applyOverheadBackoff can look at
+	// this interval's overhead ratio rather than the transfer's cumulative
+	// one, which would react far too slowly to a path degrading mid-transfer.
+	lastRawOut    int64
+	lastUsefulOut int64
 }
 
 func NewAutoTuner(orch *OrchestratedSender, manifest *chunker.Manifest) *AutoTuner {
@@ -74,10 +90,40 @@ func (a *AutoTuner) Start() {
 					}
 				}
 				chunkBytes := computeBDPChunk(a.manifest.Network, streams)
+				streams, chunkBytes = a.applyOverheadBackoff(streams, chunkBytes)
 				a.orch.Adjust(chunkBytes, streams)
 			}
 		}
 	}()
 }
 
+// applyOverheadBackoff compares this interval's raw bytes out against useful
+// bytes out from the orchestrator's shared BandwidthMeter and, if overhead
+// (retransmits, failed writes, headers) exceeds overheadBackoffThreshold of
+// useful throughput, halves the streams count and chunk size the BDP/network
+// heuristic would otherwise pick — more streams and bigger chunks on a path
+// already spending a quarter of its bandwidth on overhead just buys more
+// loss, not more goodput. Returns streams and chunkBytes unchanged if there
+// isn't enough useful throughput yet this interval to judge the ratio.
+func (a *AutoTuner) applyOverheadBackoff(streams, chunkBytes int) (int, int) {
+	rawOut, _, usefulOut := a.orch.BandwidthMeter().Snapshot()
+	deltaRaw := rawOut - a.lastRawOut
+	deltaUseful := usefulOut - a.lastUsefulOut
+	a.lastRawOut, a.lastUsefulOut = rawOut, usefulOut
+
+	if deltaUseful <= 0 {
+		return streams, chunkBytes
+	}
+	overhead := float64(deltaRaw-deltaUseful) / float64(deltaUseful)
+	if overhead <= overheadBackoffThreshold {
+		return streams, chunkBytes
+	}
+
+	streams = streams / 2
+	if streams < 1 {
+		streams = 1
+	}
+	return streams, clampChunkBytes(chunkBytes / 2)
+}
+
 func (a *AutoTuner) Stop() { close(a.quit) }
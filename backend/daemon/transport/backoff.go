@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffConfig tunes the delay a ChunkWorkerPool waits before re-enqueueing
+// a chunk whose send failed, instead of calling onChunkFailed immediately. A
+// zero-value BackoffConfig (MaxRetries == 0) disables in-pool retries
+// entirely, preserving the old immediate-callback behavior.
+type BackoffConfig struct {
+	// Base is the delay before the first retry. Factor scales it
+	// exponentially for each subsequent attempt.
+	Base   time.Duration
+	Factor float64
+
+	// JitterCap adds up to this much extra random delay, to keep many
+	// simultaneously failing chunks from retrying in lockstep.
+	JitterCap time.Duration
+
+	// MaxRetries is how many times a chunk is retried in-pool before
+	// onChunkFailed is finally called.
+	MaxRetries int
+}
+
+// delay returns how long to wait before retry number attempt (0-indexed).
+func (b BackoffConfig) delay(attempt int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+	factor := b.Factor
+	if factor <= 0 {
+		factor = 1
+	}
+	d := time.Duration(float64(b.Base) * math.Pow(factor, float64(attempt)))
+	if b.JitterCap > 0 {
+		d += time.Duration(rand.Int63n(int64(b.JitterCap)))
+	}
+	return d
+}
+
+// BackoffPolicy configures exponential retry backoff for operations above
+// the per-chunk send path BackoffConfig already covers: the control
+// listener's NACK re-enqueue, DTN's dequeue-retry loop, and DialQUIC's
+// reconnect attempts. Each of those tracks its own attempt counter (chunk
+// index, DTNItem.Attempts, dial attempt) rather than a running timer, so
+// Duration only needs an attempt number.
+type BackoffPolicy struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+
+	// Jitter is the fraction of the computed delay to randomize by in
+	// either direction (0.2 means +/-20%), so many callers backing off in
+	// lockstep (e.g. every NACKed chunk in one NackMessage) don't all
+	// retry on the same tick.
+	Jitter float64
+
+	// MaxAttempts caps how many times a caller should retry before giving
+	// up; 0 means unlimited. Exhausted treats attempt as 0-indexed.
+	MaxAttempts int
+}
+
+// DefaultBackoffPolicy is used wherever a zero-value BackoffPolicy is seen
+// (Multiplier <= 0), so a profile or Config that doesn't set one still
+// retries sensibly instead of busy-looping or retrying forever.
+func DefaultBackoffPolicy() BackoffPolicy {
+	return BackoffPolicy{
+		Initial:     500 * time.Millisecond,
+		Max:         30 * time.Second,
+		Multiplier:  2.0,
+		Jitter:      0.2,
+		MaxAttempts: 10,
+	}
+}
+
+// Duration returns the delay to wait before retry number attempt (0-indexed:
+// attempt 0 is the delay before the first retry), clamped to Max and
+// randomized by +/-Jitter.
+func (p BackoffPolicy) Duration(attempt int) time.Duration {
+	if p.Multiplier <= 0 {
+		p = DefaultBackoffPolicy()
+	}
+	if attempt < 0 {
+		attempt = 0
+	}
+	d := float64(p.Initial) * math.Pow(p.Multiplier, float64(attempt))
+	if p.Max > 0 && d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+	if p.Jitter > 0 {
+		delta := d * p.Jitter
+		d += (rand.Float64()*2 - 1) * delta
+		if d < 0 {
+			d = 0
+		}
+	}
+	return time.Duration(d)
+}
+
+// Exhausted reports whether attempt has used up this policy's MaxAttempts.
+// 0 means unlimited and is never exhausted.
+func (p BackoffPolicy) Exhausted(attempt int) bool {
+	return p.MaxAttempts > 0 && attempt >= p.MaxAttempts
+}
@@ -0,0 +1,143 @@
+package transport
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/quantarax/backend/internal/observability"
+)
+
+// numPriorityClasses bounds the array BandwidthMeter indexes by
+// PriorityClass; it only needs to match the number of classes actually
+// declared in priorities.go (P0/P1/P2 today).
+const numPriorityClasses = 3
+
+// ClassBandwidth is one PriorityClass's running byte totals, returned by
+// SnapshotByClass.
+type ClassBandwidth struct {
+	RawOut    int64
+	RawIn     int64
+	UsefulOut int64
+}
+
+// BandwidthMeter counts raw and useful bytes moved over a chunk sender's
+// connection, including bytes spent on retransmits and failed writes, so
+// operators can see protocol overhead and retry amplification alongside the
+// "useful" (payload) throughput a transfer is actually making. Counts are
+// broken down per PriorityClass (Control/Preview/Bulk) since retransmit
+// overhead on a lossy path concentrates on whichever class carries bulk
+// payload, not evenly across all three.
+type BandwidthMeter struct {
+	byClass [numPriorityClasses]struct {
+		rawOut    int64
+		rawIn     int64
+		usefulOut int64
+	}
+}
+
+// NewBandwidthMeter creates a zeroed meter.
+func NewBandwidthMeter() *BandwidthMeter {
+	return &BandwidthMeter{}
+}
+
+// AddRawOut records n bytes written to the wire for class, whether or not
+// the write ultimately succeeded.
+func (m *BandwidthMeter) AddRawOut(class PriorityClass, n int64) {
+	atomic.AddInt64(&m.byClass[class].rawOut, n)
+}
+
+// AddRawIn records n bytes read off the wire for class.
+func (m *BandwidthMeter) AddRawIn(class PriorityClass, n int64) {
+	atomic.AddInt64(&m.byClass[class].rawIn, n)
+}
+
+// AddUsefulOut records n payload bytes that were part of a chunk fragment
+// actually delivered for class, as opposed to headers, retries, or failed
+// writes.
+func (m *BandwidthMeter) AddUsefulOut(class PriorityClass, n int64) {
+	atomic.AddInt64(&m.byClass[class].usefulOut, n)
+}
+
+// Snapshot returns the running totals summed across every class.
+func (m *BandwidthMeter) Snapshot() (rawOut, rawIn, usefulOut int64) {
+	for _, c := range m.byClass {
+		rawOut += atomic.LoadInt64(&c.rawOut)
+		rawIn += atomic.LoadInt64(&c.rawIn)
+		usefulOut += atomic.LoadInt64(&c.usefulOut)
+	}
+	return
+}
+
+// SnapshotByClass returns the running totals for every PriorityClass this
+// meter has recorded against, so a caller can compare goodput vs. raw
+// throughput per stream class rather than only in aggregate.
+func (m *BandwidthMeter) SnapshotByClass() map[PriorityClass]ClassBandwidth {
+	out := make(map[PriorityClass]ClassBandwidth, numPriorityClasses)
+	for i, c := range m.byClass {
+		out[PriorityClass(i)] = ClassBandwidth{
+			RawOut:    atomic.LoadInt64(&c.rawOut),
+			RawIn:     atomic.LoadInt64(&c.rawIn),
+			UsefulOut: atomic.LoadInt64(&c.usefulOut),
+		}
+	}
+	return out
+}
+
+// StartPeriodicPublish publishes an EventKindBandwidth snapshot to bus every
+// interval until ctx is canceled. A nil bus is allowed (the publish is
+// skipped each tick), mirroring the nil-bus-disables-publishing convention
+// used by ChunkReceiver.SetEventBus.
+func (m *BandwidthMeter) StartPeriodicPublish(ctx context.Context, interval time.Duration, sessionID string, bus observability.EventBus) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if bus == nil {
+					continue
+				}
+				rawOut, rawIn, usefulOut := m.Snapshot()
+				_ = bus.Publish(observability.Event{
+					Kind:      observability.EventKindBandwidth,
+					SessionID: sessionID,
+					Timestamp: time.Now(),
+					Fields: map[string]string{
+						"raw_bytes_out":    strconv.FormatInt(rawOut, 10),
+						"raw_bytes_in":     strconv.FormatInt(rawIn, 10),
+						"useful_bytes_out": strconv.FormatInt(usefulOut, 10),
+					},
+				})
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// StartPeriodicLogging reports a BandwidthSample to logger every interval
+// until ctx is canceled, with sent/recvd as this window's delta (not the
+// running total) so BandwidthSample's window argument is meaningful. A nil
+// logger is allowed and simply skips every tick, the same convention
+// StartPeriodicPublish uses for a nil bus.
+func (m *BandwidthMeter) StartPeriodicLogging(ctx context.Context, interval time.Duration, sessionID string, logger *observability.Logger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var lastOut, lastIn int64
+		for {
+			select {
+			case <-ticker.C:
+				rawOut, rawIn, _ := m.Snapshot()
+				if logger != nil {
+					logger.BandwidthSample(sessionID, rawOut-lastOut, rawIn-lastIn, interval)
+				}
+				lastOut, lastIn = rawOut, rawIn
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
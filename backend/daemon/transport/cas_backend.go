@@ -1,5 +1,7 @@
 package transport
 
+import "github.com/quantarax/backend/internal/observability"
+
 type CASBackend interface {
 	HasChunk(hash string) bool
 	PutChunk(hash string, length int) error
@@ -13,12 +15,15 @@ func casHas(hash string) bool {
 	if casBackend == nil {
 		return false
 	}
-	return casBackend.HasChunk(hash)
+	ok := casBackend.HasChunk(hash)
+	observability.TraceGlobal(observability.FacetCAS, "cas lookup", "hash", hash, "hit", ok)
+	return ok
 }
 
 func casPut(hash string, length int) {
 	if casBackend == nil {
 		return
 	}
+	observability.TraceGlobal(observability.FacetCAS, "cas put", "hash", hash, "length", length)
 	_ = casBackend.PutChunk(hash, length)
 }
@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quantarax/backend/internal/crypto"
+)
+
+// TestChunkCrypto_XChaCha20Poly1305RoundTrip negotiates
+// AlgorithmXChaCha20Poly1305 (24-byte nonce) on both sides and confirms
+// encryptChunk/decryptChunk and encryptFragment/decryptFragment round-trip
+// correctly — guarding against nonce derivation that only works for the
+// fixed 12-byte nonce algorithms.
+func TestChunkCrypto_XChaCha20Poly1305RoundTrip(t *testing.T) {
+	var sessionKeys crypto.SessionKeys
+	if _, err := rand.Read(sessionKeys.PayloadKey[:]); err != nil {
+		t.Fatalf("generate payload key: %v", err)
+	}
+	if _, err := rand.Read(sessionKeys.IVBase[:]); err != nil {
+		t.Fatalf("generate IV base: %v", err)
+	}
+	sessionID := uuid.New()
+
+	senderControl := &ControlStream{preferredAEAD: crypto.AlgorithmXChaCha20Poly1305}
+	pool := NewChunkWorkerPool(1, 1, nil, &sessionKeys, sessionID, nil, 1<<20, nil, nil)
+	pool.SetControlStream(senderControl)
+
+	receiverControl := &ControlStream{peerAEAD: crypto.AlgorithmXChaCha20Poly1305}
+	receiver := NewChunkReceiver(nil, &sessionKeys, sessionID, t.TempDir(), 1<<20, nil, receiverControl, nil, nil, nil)
+
+	chunkPlaintext := []byte("quantarax chunk payload")
+	ciphertext, err := pool.encryptChunk(7, chunkPlaintext)
+	if err != nil {
+		t.Fatalf("encryptChunk failed: %v", err)
+	}
+	decrypted, err := receiver.decryptChunk(7, ciphertext)
+	if err != nil {
+		t.Fatalf("decryptChunk failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, chunkPlaintext) {
+		t.Fatalf("decryptChunk = %q, want %q", decrypted, chunkPlaintext)
+	}
+
+	fragmentPlaintext := []byte("quantarax fragment payload")
+	fragCiphertext, err := pool.encryptFragment(3, 2, fragmentPlaintext)
+	if err != nil {
+		t.Fatalf("encryptFragment failed: %v", err)
+	}
+	fragDecrypted, err := receiver.decryptFragment(3, 2, fragCiphertext)
+	if err != nil {
+		t.Fatalf("decryptFragment failed: %v", err)
+	}
+	if !bytes.Equal(fragDecrypted, fragmentPlaintext) {
+		t.Fatalf("decryptFragment = %q, want %q", fragDecrypted, fragmentPlaintext)
+	}
+}
@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// presentChunks returns the indices present in a bitmap of totalChunks
+// chunks at the given presence fraction (0..1), deterministically seeded so
+// repeated benchmark runs compare against the same bitmap.
+func presentChunks(totalChunks int, presence float64) []int64 {
+	r := rand.New(rand.NewSource(1))
+	chunks := make([]int64, 0, int(float64(totalChunks)*presence))
+	for i := 0; i < totalChunks; i++ {
+		if r.Float64() < presence {
+			chunks = append(chunks, int64(i))
+		}
+	}
+	return chunks
+}
+
+func benchmarkEncodeRanges(b *testing.B, presence float64, useRoaring bool) {
+	chunks := presentChunks(1_000_000, presence)
+	var comp ChunkRangeCompressor
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		encoded, _, err := comp.EncodeRanges(chunks, useRoaring)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(encoded)))
+	}
+}
+
+func BenchmarkEncodeRanges_Ranges_10pct(b *testing.B)  { benchmarkEncodeRanges(b, 0.10, false) }
+func BenchmarkEncodeRanges_Ranges_50pct(b *testing.B)  { benchmarkEncodeRanges(b, 0.50, false) }
+func BenchmarkEncodeRanges_Ranges_90pct(b *testing.B)  { benchmarkEncodeRanges(b, 0.90, false) }
+func BenchmarkEncodeRanges_Roaring_10pct(b *testing.B) { benchmarkEncodeRanges(b, 0.10, true) }
+func BenchmarkEncodeRanges_Roaring_50pct(b *testing.B) { benchmarkEncodeRanges(b, 0.50, true) }
+func BenchmarkEncodeRanges_Roaring_90pct(b *testing.B) { benchmarkEncodeRanges(b, 0.90, true) }
+
+func benchmarkDecodeRanges(b *testing.B, presence float64, useRoaring bool) {
+	chunks := presentChunks(1_000_000, presence)
+	var comp ChunkRangeCompressor
+	encoded, encoding, err := comp.EncodeRanges(chunks, useRoaring)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := comp.DecodeRanges(encoded, encoding); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeRanges_Ranges_10pct(b *testing.B)  { benchmarkDecodeRanges(b, 0.10, false) }
+func BenchmarkDecodeRanges_Ranges_50pct(b *testing.B)  { benchmarkDecodeRanges(b, 0.50, false) }
+func BenchmarkDecodeRanges_Ranges_90pct(b *testing.B)  { benchmarkDecodeRanges(b, 0.90, false) }
+func BenchmarkDecodeRanges_Roaring_10pct(b *testing.B) { benchmarkDecodeRanges(b, 0.10, true) }
+func BenchmarkDecodeRanges_Roaring_50pct(b *testing.B) { benchmarkDecodeRanges(b, 0.50, true) }
+func BenchmarkDecodeRanges_Roaring_90pct(b *testing.B) { benchmarkDecodeRanges(b, 0.90, true) }
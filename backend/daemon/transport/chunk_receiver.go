@@ -1,24 +1,27 @@
 package transport
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
-	"encoding/json"
 	"encoding/base64"
 
 	"github.com/google/uuid"
 	"github.com/quic-go/quic-go"
 	"github.com/quantarax/backend/internal/crypto"
 	"github.com/quantarax/backend/internal/chunker"
-	"github.com/quantarax/backend/internal/crypto/identity"
 	"github.com/quantarax/backend/internal/fec"
 	"github.com/quantarax/backend/daemon/manager"
 	"github.com/quantarax/backend/internal/observability"
 	"github.com/zeebo/blake3"
+	"go.opentelemetry.io/otel"
 )
 
 
@@ -27,6 +30,25 @@ const (
 	ChunkMagic      = 0x514E5458 // "QNTX"
 	ChunkVersion    = 0x01
 	ChunkHeaderSize = 32
+
+	// ChunkVersionFragmented marks a stream using the segment/fragment wire
+	// format (FragmentHeader, ChunkHeaderSize+4 bytes) instead of the
+	// single-shot ChunkHeader: each ChunkIndex becomes a "segment" streamed
+	// as one or more ordered fragments over the same QUIC stream.
+	ChunkVersionFragmented = 0x02
+
+	// FragmentHeaderSize is FragmentHeader's wire size: ChunkHeader's layout
+	// up through SessionID, with the chunk-index slot reused as SegmentSeq
+	// and a FragmentSeq appended after it.
+	FragmentHeaderSize = 36
+
+	// fragmentFlagFinal marks the last fragment of a segment, stored in the
+	// byte ChunkHeader leaves reserved.
+	fragmentFlagFinal = 1 << 0
+
+	// defaultFragmentSize is how much segment data each fragment carries
+	// when a sender hasn't set a different size via SetFragmentSize.
+	defaultFragmentSize = 64 * 1024
 )
 
 var (
@@ -34,6 +56,24 @@ var (
 	ErrInvalidVersion = errors.New("unsupported chunk version")
 )
 
+// FragmentHeader is the parsed form of one segment/fragment wire header.
+type FragmentHeader struct {
+	SessionID   uuid.UUID
+	SegmentSeq  uint32
+	FragmentSeq uint32
+	PayloadLen  int
+	Final       bool
+}
+
+// segmentAssembly accumulates a segment's fragments, in any arrival order,
+// until the one marked final has been seen and every fragment up to it has
+// arrived.
+type segmentAssembly struct {
+	mu        sync.Mutex
+	fragments map[uint32][]byte
+	total     int // 0 until the final fragment has been seen
+}
+
 // ChunkReceiver handles incoming chunk streams
 type ChunkReceiver struct {
 	connection  *quic.Conn
@@ -49,7 +89,49 @@ type ChunkReceiver struct {
 	receivedCnt int64
 	manifest    *chunker.Manifest
 	fecDec      *fec.Decoder
+	fecAsm      *fecReassembler
 	lastFECUpdate time.Time
+	eventBus    observability.EventBus
+	merkleVerifier *manager.MerkleVerifier
+
+	// haveBitmap tracks which data chunks this receiver has assembled, so
+	// finishDataChunk can push periodic have-bitmap snapshots (see
+	// haveBitmapDatagramInterval) to the sender over the unreliable
+	// datagram path, letting it retransmit only what's still missing
+	// instead of waiting for a per-chunk ACK/NACK round trip. Left nil
+	// (manifest == nil, chunk count unknown) disables the feature.
+	haveBitmap *manager.ChunkBitmap
+
+	segmentsMu sync.Mutex
+	segments   map[int64]*segmentAssembly
+}
+
+// haveBitmapDatagramInterval is how many data chunks finishDataChunk
+// assembles between have-bitmap datagram pushes. Frequent enough that a
+// sender's retransmits stay close behind real gaps, rare enough that it
+// doesn't compete with chunk data for datagram bandwidth.
+const haveBitmapDatagramInterval = 32
+
+// SetEventBus attaches an observability.EventBus that receives lifecycle
+// events (chunk_received, decrypt_failed, hash_mismatch, retransmit,
+// verification_completed) as they happen. A nil bus (the default) disables
+// event publishing without affecting logging or metrics.
+func (r *ChunkReceiver) SetEventBus(bus observability.EventBus) {
+	r.eventBus = bus
+}
+
+// publishEvent is a no-op when no event bus is attached.
+func (r *ChunkReceiver) publishEvent(kind observability.EventKind, chunkIndex *int64, fields map[string]string) {
+	if r.eventBus == nil {
+		return
+	}
+	_ = r.eventBus.Publish(observability.Event{
+		Kind:       kind,
+		SessionID:  r.sessionID.String(),
+		ChunkIndex: chunkIndex,
+		Timestamp:  time.Now(),
+		Fields:     fields,
+	})
 }
 
 // NewChunkReceiver creates a new chunk receiver
@@ -76,10 +158,23 @@ func NewChunkReceiver(
 		manifest:        manifest,
 		logger:          logger,
 		metrics:         metrics,
+		merkleVerifier:  manager.NewMerkleVerifier(),
+		segments:        make(map[int64]*segmentAssembly),
 	}
 	if manifest != nil && manifest.FEC != nil {
 		if dec, err := fec.NewDecoder(manifest.FEC.K, manifest.FEC.R); err == nil { cr.fecDec = dec }
 	}
+	if manifest != nil && len(manifest.FecStripes) > 0 {
+		cr.fecAsm = newFECReassembler()
+	}
+	if manifest != nil && manifest.DataChunkCount() > 0 {
+		cr.haveBitmap = manager.NewChunkBitmap(sessionID.String(), int64(manifest.DataChunkCount()))
+	}
+	if manifest != nil {
+		if root, err := base64.StdEncoding.DecodeString(manifest.MerkleRoot); err == nil {
+			cr.merkleVerifier.SetExpectedRoot(sessionID.String(), root)
+		}
+	}
 	return cr
 }
 
@@ -90,53 +185,184 @@ func (r *ChunkReceiver) AcceptAndProcessStreams() error {
 		if err != nil {
 			return err
 		}
-		
+
 		// Process stream in goroutine
-		go r.processChunkStream(stream)
+		go r.dispatchStream(stream)
 	}
 }
 
-// processChunkStream reads and processes a single chunk stream
-func (r *ChunkReceiver) processChunkStream(stream *quic.Stream) {
-	defer stream.Close()
-	
-	// Read chunk header
-	header := make([]byte, ChunkHeaderSize)
-	if _, err := io.ReadFull(stream, header); err != nil {
-		fmt.Printf("Failed to read chunk header: %v\n", err)
+// dispatchStream peeks a stream's version byte to route it to the legacy
+// single-shot chunk path or the segment/fragment reassembly path. Both
+// header formats share the same Magic(4)+Version(1) prefix, so this is the
+// only byte that needs inspecting before committing to a parser.
+func (r *ChunkReceiver) dispatchStream(stream *quic.Stream) {
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(stream, prefix); err != nil {
+		fmt.Printf("Failed to read stream header prefix: %v\n", err)
+		stream.Close()
 		return
 	}
-	
+
+	switch prefix[4] {
+	case ChunkVersionFragmented:
+		header := make([]byte, FragmentHeaderSize)
+		copy(header, prefix)
+		if _, err := io.ReadFull(stream, header[len(prefix):]); err != nil {
+			fmt.Printf("Failed to read fragment header: %v\n", err)
+			stream.Close()
+			return
+		}
+		r.processFragmentedStream(stream, header)
+	default:
+		header := make([]byte, ChunkHeaderSize)
+		copy(header, prefix)
+		if _, err := io.ReadFull(stream, header[len(prefix):]); err != nil {
+			fmt.Printf("Failed to read chunk header: %v\n", err)
+			stream.Close()
+			return
+		}
+		r.processChunkStream(stream, header)
+	}
+}
+
+// processChunkStream reads and processes a single legacy (non-fragmented)
+// chunk stream, given its already-read header.
+func (r *ChunkReceiver) processChunkStream(stream *quic.Stream, header []byte) {
+	defer stream.Close()
+
 	// Parse header
 	chunkIndex, payloadLen, err := r.parseChunkHeader(header)
 	if err != nil {
 		fmt.Printf("Failed to parse chunk header: %v\n", err)
 		return
 	}
-	
+
 	// Read encrypted payload
 	encryptedPayload := make([]byte, payloadLen)
 	if _, err := io.ReadFull(stream, encryptedPayload); err != nil {
 		fmt.Printf("Failed to read chunk payload: %v\n", err)
 		return
 	}
-	
+
 	// Decrypt chunk
 	plaintext, err := r.decryptChunk(chunkIndex, encryptedPayload)
 	if err != nil {
 		fmt.Printf("Failed to decrypt chunk %d: %v\n", chunkIndex, err)
-// Metrics and logging for decrypt failure
 		// Metrics and logging for decrypt failure
 		if r.metrics != nil { r.metrics.RecordChunkRetransmit("decrypt_failed") }
 		if r.logger != nil { r.logger.ChunkDecryptFailed(r.sessionID.String(), int(chunkIndex), "decrypt_failed", err.Error(), 0) }
 		// Send NACK to request retransmission
 		if r.control != nil {
 			var comp ChunkRangeCompressor
-			rangeStr := comp.Compress([]int64{chunkIndex})
-			_ = r.control.SendNack(&NackMessage{MissingRanges: rangeStr, Reason: "decrypt_failed", SessionID: r.sessionID.String(), Timestamp: time.Now().Unix()})
+			rangeStr, encoding, _ := comp.EncodeRanges([]int64{chunkIndex}, r.control.PeerSupportsRoaring())
+			_ = r.control.SendNack(&NackMessage{MissingRanges: rangeStr, Encoding: encoding, Reason: "decrypt_failed", SessionID: r.sessionID.String(), Timestamp: time.Now().Unix()})
 		}
+		idx := chunkIndex
+		r.publishEvent(observability.EventKindDecryptFailed, &idx, map[string]string{"error": err.Error()})
+		r.publishEvent(observability.EventKindRetransmit, &idx, map[string]string{"reason": "decrypt_failed"})
 		return
 	}
+
+	r.handleAssembledChunk(chunkIndex, plaintext)
+}
+
+// processFragmentedStream reads a sequence of (FragmentHeader, ciphertext)
+// frames for one segment off stream, decrypting each fragment as it arrives
+// and handing it to the session's segment reassembler, so the receiver can
+// start decrypting a segment before all of its fragments are in. The full
+// chunk only runs through handleAssembledChunk — the same verification,
+// write, and ACK pipeline the legacy path uses — once the reassembler
+// reports the segment complete.
+func (r *ChunkReceiver) processFragmentedStream(stream *quic.Stream, firstHeader []byte) {
+	defer stream.Close()
+
+	header := firstHeader
+	for {
+		fh, err := parseFragmentHeader(header)
+		if err != nil {
+			fmt.Printf("Failed to parse fragment header: %v\n", err)
+			return
+		}
+
+		ciphertext := make([]byte, fh.PayloadLen)
+		if _, err := io.ReadFull(stream, ciphertext); err != nil {
+			fmt.Printf("Failed to read fragment payload: %v\n", err)
+			return
+		}
+
+		plaintext, err := r.decryptFragment(fh.SegmentSeq, fh.FragmentSeq, ciphertext)
+		if err != nil {
+			fmt.Printf("Failed to decrypt segment %d fragment %d: %v\n", fh.SegmentSeq, fh.FragmentSeq, err)
+			if r.metrics != nil { r.metrics.RecordChunkRetransmit("decrypt_failed") }
+			idx := int64(fh.SegmentSeq)
+			r.publishEvent(observability.EventKindDecryptFailed, &idx, map[string]string{"error": err.Error(), "fragment": fmt.Sprintf("%d", fh.FragmentSeq)})
+			return
+		}
+
+		if complete, assembled := r.assembleFragment(int64(fh.SegmentSeq), fh.FragmentSeq, fh.Final, plaintext); complete {
+			r.handleAssembledChunk(int64(fh.SegmentSeq), assembled)
+		}
+
+		if fh.Final {
+			return
+		}
+
+		next := make([]byte, FragmentHeaderSize)
+		if _, err := io.ReadFull(stream, next); err != nil {
+			if err != io.EOF {
+				fmt.Printf("Failed to read next fragment header: %v\n", err)
+			}
+			return
+		}
+		header = next
+	}
+}
+
+// assembleFragment records one fragment of segment's reassembly and reports
+// whether the segment is now complete — every fragment up to and including
+// the one marked final has arrived, regardless of arrival order — returning
+// its concatenated plaintext when it is.
+func (r *ChunkReceiver) assembleFragment(segment int64, fragmentSeq uint32, final bool, plaintext []byte) (bool, []byte) {
+	r.segmentsMu.Lock()
+	asm, ok := r.segments[segment]
+	if !ok {
+		asm = &segmentAssembly{fragments: make(map[uint32][]byte)}
+		r.segments[segment] = asm
+	}
+	r.segmentsMu.Unlock()
+
+	asm.mu.Lock()
+	defer asm.mu.Unlock()
+	asm.fragments[fragmentSeq] = plaintext
+	if final {
+		asm.total = int(fragmentSeq) + 1
+	}
+	if asm.total == 0 || len(asm.fragments) != asm.total {
+		return false, nil
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < asm.total; i++ {
+		part, ok := asm.fragments[uint32(i)]
+		if !ok {
+			return false, nil
+		}
+		buf.Write(part)
+	}
+
+	r.segmentsMu.Lock()
+	delete(r.segments, segment)
+	r.segmentsMu.Unlock()
+
+	return true, buf.Bytes()
+}
+
+// handleAssembledChunk runs the shared post-decrypt pipeline for a complete
+// chunk's plaintext: per-chunk hash verification against the manifest, CAS
+// storage, writing to the output file, ACKing, and — once every chunk has
+// been received — the final whole-file verification pass. Used by both the
+// legacy single-shot path and the fragmented segment reassembler.
+func (r *ChunkReceiver) handleAssembledChunk(chunkIndex int64, plaintext []byte) {
 	// Per-chunk integrity: verify against manifest hash if available
 	if r.manifest != nil && chunkIndex >= 0 && int(chunkIndex) < len(r.manifest.Chunks) {
 		expected := r.manifest.Chunks[chunkIndex].Hash
@@ -153,12 +379,142 @@ func (r *ChunkReceiver) processChunkStream(stream *quic.Stream) {
 			// Send NACK to request retransmission
 			if r.control != nil {
 				var comp ChunkRangeCompressor
-				rangeStr := comp.Compress([]int64{chunkIndex})
-				_ = r.control.SendNack(&NackMessage{MissingRanges: rangeStr, Reason: "hash_mismatch", SessionID: r.sessionID.String(), Timestamp: time.Now().Unix()})
+				rangeStr, encoding, _ := comp.EncodeRanges([]int64{chunkIndex}, r.control.PeerSupportsRoaring())
+				_ = r.control.SendNack(&NackMessage{MissingRanges: rangeStr, Encoding: encoding, Reason: "hash_mismatch", SessionID: r.sessionID.String(), Timestamp: time.Now().Unix()})
 			}
+			idx := chunkIndex
+			r.publishEvent(observability.EventKindHashMismatch, &idx, map[string]string{"expected": expected, "computed": computed})
+			r.publishEvent(observability.EventKindRetransmit, &idx, map[string]string{"reason": "hash_mismatch"})
+			return
+		}
+	}
+	// A verified parity chunk only ever feeds fecAsm's stripe reconstruction;
+	// it has no place of its own in the output file.
+	if r.manifest != nil && r.fecAsm != nil {
+		if stripe, ok := r.manifest.StripeForChunk(chunkIndex); ok {
+			r.handleFECStripeChunk(stripe, chunkIndex, plaintext)
 			return
 		}
 	}
+	r.finishDataChunk(chunkIndex, plaintext)
+}
+
+// handleFECStripeChunk records chunkIndex's verified plaintext against its
+// FEC stripe and, for a parity chunk, stops there. A data chunk still gets
+// its normal finishDataChunk treatment; the only difference from a non-FEC
+// chunk is that arriving here may also complete its stripe and recover
+// sibling data chunks the network dropped, which get finishDataChunk'd too.
+func (r *ChunkReceiver) handleFECStripeChunk(stripe chunker.FecStripe, chunkIndex int64, plaintext []byte) {
+	padded := plaintext
+	if len(padded) < int(r.chunkSize) {
+		padded = make([]byte, r.chunkSize)
+		copy(padded, plaintext)
+	}
+
+	recovered, err := r.fecAsm.observe(stripe, chunkIndex, padded)
+	if err != nil && r.logger != nil {
+		r.logger.WithSession(r.sessionID.String()).Warn(fmt.Sprintf("fec stripe %s: %v", stripe.StripeID, err))
+	}
+
+	if int(chunkIndex) < stripe.Start+stripe.K {
+		r.finishDataChunk(chunkIndex, plaintext)
+	}
+
+	for _, idx := range recovered {
+		data := r.fecAsm.shardData(stripe, idx)
+		if data == nil {
+			continue
+		}
+		if length := r.manifest.Chunks[idx].Length; length > 0 && length <= len(data) {
+			data = data[:length]
+		}
+		if !r.verifyRecoveredChunk(stripe, idx, data) {
+			return
+		}
+		r.finishDataChunk(idx, data)
+	}
+}
+
+// verifyRecoveredChunk checks a chunk FEC just reconstructed against its
+// manifest hash. A mismatch here — unlike handleAssembledChunk's check on a
+// chunk straight off the wire — means the sender's own erasure coding is
+// inconsistent with what it signed, so instead of NACKing for a retransmit
+// this reports a BadEncodingProof and disconnects. Returns false when the
+// caller should stop processing the stripe.
+func (r *ChunkReceiver) verifyRecoveredChunk(stripe chunker.FecStripe, chunkIndex int64, data []byte) bool {
+	if r.manifest == nil || int(chunkIndex) >= len(r.manifest.Chunks) {
+		return true
+	}
+	expected := r.manifest.Chunks[chunkIndex].Hash
+	h := blake3.Sum256(data)
+	if base64.StdEncoding.EncodeToString(h[:]) == expected {
+		return true
+	}
+	r.reportBadEncoding(stripe, chunkIndex, expected)
+	return false
+}
+
+// reportBadEncoding builds a chunker.BadEncodingProof for chunkIndex's
+// stripe from only the shards genuinely received over the wire, records the
+// quantarax_fec_bad_encoding_proofs_total metric, publishes an
+// EventKindBadEncodingProof, sends the proof to the peer over the control
+// stream, and disconnects — the sender's erasure coding is provably
+// inconsistent with its signed manifest, so retrying would just repeat the
+// same bad data.
+func (r *ChunkReceiver) reportBadEncoding(stripe chunker.FecStripe, chunkIndex int64, expectedHash string) {
+	shards, ok := r.fecAsm.ReceivedOnlyShards(stripe.StripeID)
+	if !ok {
+		return
+	}
+	hashes := make([]string, len(r.manifest.Chunks))
+	for i, c := range r.manifest.Chunks {
+		hashes[i] = c.Hash
+	}
+	mproof, err := chunker.BuildMerkleProof(hashes, int(chunkIndex))
+	if err != nil {
+		if r.logger != nil {
+			r.logger.WithSession(r.sessionID.String()).Error(err, "bad encoding: failed to build merkle proof")
+		}
+		return
+	}
+	shardIndex := int(chunkIndex) - stripe.Start
+	proof, err := chunker.ProveBadEncoding(shardIndex, stripe.K, stripe.M, shards, chunkIndex, expectedHash, mproof)
+	if err != nil {
+		if r.logger != nil {
+			r.logger.WithSession(r.sessionID.String()).Error(err, "bad encoding: failed to build fraud proof")
+		}
+		return
+	}
+
+	if r.metrics != nil {
+		r.metrics.RecordFECBadEncodingProof()
+	}
+	idx := chunkIndex
+	r.publishEvent(observability.EventKindBadEncodingProof, &idx, map[string]string{
+		"stripe_id":   stripe.StripeID,
+		"shard_index": fmt.Sprintf("%d", shardIndex),
+	})
+	if r.control != nil {
+		_ = r.control.SendBadEncodingProof(&BadEncodingProofMessage{
+			SessionID:  r.sessionID.String(),
+			ChunkIndex: chunkIndex,
+			Proof:      proof,
+			Timestamp:  time.Now().Unix(),
+		})
+	}
+	if r.logger != nil {
+		r.logger.WithSession(r.sessionID.String()).Error(fmt.Errorf("bad encoding proof"), fmt.Sprintf("chunk %d: sender's FEC shards reconstruct inconsistent with the manifest; disconnecting", chunkIndex))
+	}
+	if r.connection != nil {
+		_ = r.connection.CloseWithError(0, "fec bad encoding proof")
+	}
+}
+
+// finishDataChunk is handleAssembledChunk's tail for one already
+// hash-verified data chunk (received normally or recovered via FEC): CAS
+// storage, writing to the output file, ACKing, and — once every data chunk
+// has been received — the final whole-file verification pass.
+func (r *ChunkReceiver) finishDataChunk(chunkIndex int64, plaintext []byte) {
 	// Compute and store CAS entry (after validation)
 	h := blake3.Sum256(plaintext)
 	chunkHash := base64.StdEncoding.EncodeToString(h[:])
@@ -168,77 +524,320 @@ func (r *ChunkReceiver) processChunkStream(stream *quic.Stream) {
 		fmt.Printf("Failed to write chunk %d to file: %v\n", chunkIndex, err)
 		return
 	}
-	
+
 	// Notify chunk received
 	if r.onChunkReceived != nil {
 		r.onChunkReceived(chunkIndex)
 	}
+	idx := chunkIndex
+	r.publishEvent(observability.EventKindChunkReceived, &idx, nil)
+	if r.haveBitmap != nil && chunkIndex >= 0 && chunkIndex < int64(r.manifest.DataChunkCount()) {
+		_ = r.haveBitmap.SetChunk(chunkIndex)
+		if received, _ := r.haveBitmap.GetProgress(); received%haveBitmapDatagramInterval == 0 {
+			r.sendHaveBitmapDatagram()
+		}
+	}
 	// Send immediate ACK for this chunk (simple per-chunk ACK). In production, batch every ~250ms.
 	if r.control != nil {
 		r.receivedCnt++
-		ranges := r.ackComp.Compress([]int64{chunkIndex})
-		_ = r.control.SendAck(&AckMessage{ChunkRanges: ranges, TotalReceived: r.receivedCnt, Timestamp: time.Now().Unix(), SessionID: r.sessionID.String()})
-		// If transfer complete, compute Merkle root and send VerificationMessage
-		if r.manifest != nil && r.receivedCnt >= int64(r.manifest.ChunkCount) {
-			computedRoot, _ := r.computeFileMerkleRoot()
-			mv := manager.NewMerkleVerifier()
-			vr := mv.CreateVerificationResult(r.sessionID.String(), []byte(computedRoot), []byte(r.manifest.MerkleRoot))
-			// Record metrics for Merkle verification
-			if r.metrics != nil { r.metrics.RecordMerkleVerification(vr.Status == manager.VerificationSuccess) }
-			// Structured log for verification outcome
-			if r.logger != nil {
-				l := r.logger.WithSession(r.sessionID.String())
-				msg := fmt.Sprintf("verification completed: status=%s", vr.Status.String())
-				if vr.Status == manager.VerificationSuccess { l.Info(msg) } else { l.Warn(msg) }
-			}
-			// Sign the verification result using local identity keys
-			if priv, pub, err := identity.LoadOrCreate("", ""); err == nil {
-				if err := mv.SignVerificationResult(vr, priv); err == nil {
-					fmt.Printf("Verification signed (pub=%d bytes)\n", len(pub))
-				} else {
-					fmt.Printf("Verification signing failed: %v\n", err)
+		ranges, encoding, _ := r.ackComp.EncodeRanges([]int64{chunkIndex}, r.control.PeerSupportsRoaring())
+		_ = r.control.SendAck(&AckMessage{ChunkRanges: ranges, Encoding: encoding, TotalReceived: r.receivedCnt, Timestamp: time.Now().Unix(), SessionID: r.sessionID.String()})
+			// If every data chunk (parity excluded — FEC parity is a bonus,
+			// not something the receiver waits around for) has arrived,
+			// verify the bytes actually on disk and send a
+			// VerificationMessage.
+			if r.manifest != nil && r.receivedCnt >= int64(r.manifest.DataChunkCount()) {
+				sess := &manager.Session{ID: r.sessionID.String(), FilePath: r.outputPath, ChunkSize: r.chunkSize}
+				vr, err := manager.VerifyReceivedFile(sess, r.manifest, nil)
+				if err != nil {
+					fmt.Printf("Verification failed: %v\n", err)
+					return
 				}
-			} else {
-				fmt.Printf("Identity load failed: %v\n", err)
+				// Record metrics for Merkle verification
+				if r.metrics != nil { r.metrics.RecordMerkleVerification(vr.Status == manager.VerificationSuccess) }
+				// Structured log for verification outcome
+				if r.logger != nil {
+					l := r.logger.WithSession(r.sessionID.String())
+					msg := fmt.Sprintf("verification completed: status=%s", vr.Status.String())
+					if vr.Status == manager.VerificationSuccess { l.Info(msg) } else { l.Warn(msg) }
+				}
+				if vr.Status != manager.VerificationSuccess && len(vr.CorruptChunks) > 0 {
+					if r.logger != nil {
+						r.logger.WithSession(r.sessionID.String()).
+							Warn(fmt.Sprintf("on-disk verification found %d corrupt chunk(s), requesting retransmission", len(vr.CorruptChunks)))
+					}
+					go r.RequestMissing()
+				}
+				_ = r.control.SendVerification(&VerificationMessage{SessionID: r.sessionID.String(), Status: vr.Status.String(), MerkleRootComputed: vr.MerkleRootComputed, MerkleRootExpected: vr.MerkleRootExpected, Timestamp: time.Now().Unix(), Signature: vr.Signature, PublicKey: vr.PublicKey})
+				r.publishEvent(observability.EventKindVerificationCompleted, nil, map[string]string{"status": vr.Status.String()})
 			}
-			_ = r.control.SendVerification(&VerificationMessage{SessionID: r.sessionID.String(), Status: vr.Status.String(), MerkleRootComputed: []byte(computedRoot), MerkleRootExpected: []byte(r.manifest.MerkleRoot), Timestamp: time.Now().Unix(), Signature: vr.Signature, PublicKey: vr.PublicKey})
+	}
+}
+
+// sendHaveBitmapDatagram pushes a have-bitmap snapshot (see
+// haveBitmapDatagramInterval) over the unreliable datagram path, tagged the
+// same way DatagramMux tags its categories so a sender reading the
+// connection via QUICConnection.Datagrams().Subscribe(DatagramHaveBitmap)
+// picks it up without a second reader racing ChunkReceiver for datagrams.
+// If the RLE-encoded form wouldn't fit a single datagram (or the send
+// fails — most likely because the peer didn't negotiate datagram support),
+// it falls back to the reliable control stream, carrying the full
+// Serialize()d bitmap instead of the RLE-encoded ranges.
+func (r *ChunkReceiver) sendHaveBitmapDatagram() {
+	encoded := r.haveBitmap.EncodeRanges()
+	tagged := make([]byte, 1+len(encoded))
+	tagged[0] = byte(DatagramHaveBitmap)
+	copy(tagged[1:], encoded)
+	if len(tagged) <= maxDatagramPayloadSize && r.connection != nil {
+		if err := r.connection.SendDatagram(tagged); err == nil {
+			return
 		}
 	}
+	if r.control == nil {
+		return
+	}
+	_ = r.control.SendChunkHaveResponse(&ChunkHaveResponse{
+		SessionID:  r.sessionID.String(),
+		HaveRanges: base64.StdEncoding.EncodeToString(r.haveBitmap.Serialize()),
+		Encoding:   RangeEncodingBitmap,
+		ChunkCount: r.manifest.DataChunkCount(),
+		Timestamp:  time.Now().Unix(),
+	})
 }
 
-// extractChunkHashes returns the list of chunk hashes from manifest in index order.
-func extractChunkHashes(m *chunker.Manifest) []string {
-	if m == nil || len(m.Chunks) == 0 { return nil }
-	h := make([]string, len(m.Chunks))
-	for i, ch := range m.Chunks { h[i] = ch.Hash }
-	return h
+// RequestMissing scans the partial output file on disk, finds chunk indices
+// that are absent or whose bytes don't hash-match the manifest, and asks the
+// sender for just those over the control stream instead of waiting for a
+// blind push. Requests are batched into windows sized by the manifest's
+// measured bandwidth and retried with exponential backoff, re-checking disk
+// state between rounds, up to DTNProfile.MaxRetries.
+func (r *ChunkReceiver) RequestMissing() error {
+	if r.manifest == nil || r.control == nil {
+		return fmt.Errorf("request missing: no manifest or control stream")
+	}
+
+	window := requestWindowSize(r.manifest.Network)
+	maxRetries := 3
+	backoffMs := 500
+	if r.manifest.DTNProfile != nil {
+		if r.manifest.DTNProfile.MaxRetries > 0 {
+			maxRetries = r.manifest.DTNProfile.MaxRetries
+		}
+		if r.manifest.DTNProfile.BackoffMs > 0 {
+			backoffMs = r.manifest.DTNProfile.BackoffMs
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		missing := r.missingChunkIndices()
+		if len(missing) == 0 {
+			return nil
+		}
+		if attempt > 0 {
+			time.Sleep(time.Duration(backoffMs) * time.Millisecond * time.Duration(int64(1)<<uint(attempt-1)))
+		}
+		for start := 0; start < len(missing); start += window {
+			end := start + window
+			if end > len(missing) {
+				end = len(missing)
+			}
+			req := &ChunkRequest{SessionID: r.sessionID.String(), Indices: missing[start:end], Timestamp: time.Now().Unix()}
+			if err := r.control.SendChunkRequest(req); err != nil {
+				lastErr = err
+			}
+		}
+	}
+	if lastErr != nil {
+		return fmt.Errorf("request missing: %w", lastErr)
+	}
+	return fmt.Errorf("request missing: chunks still missing after %d retries", maxRetries)
 }
 
-// computeFileMerkleRoot computes the Merkle root from the actual file bytes on disk in chunk order.
-func (r *ChunkReceiver) computeFileMerkleRoot() (string, error) {
-	if r.manifest == nil { return "", nil }
+// missingChunkIndices scans the partial output file and returns indices
+// whose on-disk bytes don't hash-match the manifest, including indices the
+// file is too short to contain yet. FEC parity chunks are never considered
+// missing here — they're optional redundancy, not file content, so a
+// receiver that never got them (or doesn't need them) has nothing to chase.
+func (r *ChunkReceiver) missingChunkIndices() []int64 {
+	dataChunkCount := r.manifest.DataChunkCount()
 	f, err := os.Open(r.outputPath)
-	if err != nil { return "", err }
+	if err != nil {
+		missing := make([]int64, dataChunkCount)
+		for i := range missing {
+			missing[i] = int64(i)
+		}
+		return missing
+	}
 	defer f.Close()
-	// Build base64 BLAKE3 per-chunk hashes compatible with manifest
-	hashes := make([]string, 0, r.manifest.ChunkCount)
+
+	var missing []int64
 	buf := make([]byte, r.chunkSize)
-	for i := 0; i < int(r.manifest.ChunkCount); i++ {
-		// Seek and read exact chunk length from manifest
-		if _, err := f.Seek(int64(i)*r.chunkSize, 0); err != nil { return "", err }
+	for i := 0; i < dataChunkCount; i++ {
+		expected := r.manifest.Chunks[i].Hash
 		n := r.manifest.Chunks[i].Length
-		if n <= 0 || int64(n) > int64(len(buf)) { n = int(r.chunkSize) }
+		if n <= 0 || int64(n) > int64(len(buf)) {
+			n = int(r.chunkSize)
+		}
 		b := buf[:n]
-		if _, err := io.ReadFull(f, b); err != nil && err != io.EOF && err != io.ErrUnexpectedEOF { return "", err }
-		// Hash and encode base64
+		if _, err := f.Seek(int64(i)*r.chunkSize, 0); err != nil {
+			missing = append(missing, int64(i))
+			continue
+		}
+		if _, err := io.ReadFull(f, b); err != nil {
+			missing = append(missing, int64(i))
+			continue
+		}
 		h := blake3.Sum256(b)
-		hashes = append(hashes, base64.StdEncoding.EncodeToString(h[:]))
+		if base64.StdEncoding.EncodeToString(h[:]) != expected {
+			missing = append(missing, int64(i))
+		}
+	}
+	return missing
+}
+
+// requestWindowSize sizes the pull-request batch from measured bandwidth, so
+// low-bandwidth/DTN profiles request fewer chunks per round trip.
+func requestWindowSize(net *chunker.NetworkProfile) int {
+	const defaultWindow = 16
+	if net == nil || net.Bandwidth <= 0 {
+		return defaultWindow
 	}
-	return chunker.ComputeMerkleRoot(hashes)
+	window := int(net.Bandwidth)
+	if window < 1 {
+		window = 1
+	}
+	if window > 256 {
+		window = 256
+	}
+	return window
+}
+
+// extractChunkHashes returns the list of chunk hashes from manifest in index order.
+func extractChunkHashes(m *chunker.Manifest) []string {
+	if m == nil || len(m.Chunks) == 0 { return nil }
+	h := make([]string, len(m.Chunks))
+	for i, ch := range m.Chunks { h[i] = ch.Hash }
+	return h
+}
+
+// handleChunkProof checks a sender-signed Merkle proof for one chunk against
+// the signed manifest's root, letting the receiver flag a bad chunk as soon
+// as its proof arrives instead of waiting for the final whole-file
+// verification pass.
+func (r *ChunkReceiver) handleChunkProof(proof *ChunkProofMessage) {
+	ctx := ExtractTraceContext(context.Background(), proof.TraceContext)
+	_, span := otel.Tracer("quantarax-daemon").Start(ctx, "chunk.verify")
+	defer span.End()
+
+	if !VerifyChunkProofSignature(proof) {
+		if r.logger != nil {
+			r.logger.WithSession(r.sessionID.String()).
+				Error(fmt.Errorf("invalid chunk proof signature"), fmt.Sprintf("chunk %d proof signature invalid", proof.ChunkIndex))
+		}
+		return
+	}
+	if r.manifest == nil {
+		return
+	}
+	leaf, err := base64.StdEncoding.DecodeString(proof.ChunkHash)
+	if err != nil {
+		return
+	}
+	if r.merkleVerifier.VerifyChunkProof(r.sessionID.String(), proof.ChunkIndex, leaf, proof.ProofPath) == manager.VerificationSuccess {
+		return
+	}
+
+	fmt.Printf("Chunk %d failed Merkle proof verification\n", proof.ChunkIndex)
+	if r.metrics != nil {
+		r.metrics.RecordChunkRetransmit("merkle_proof_failed")
+	}
+	if r.logger != nil {
+		r.logger.WithSession(r.sessionID.String()).
+			Error(fmt.Errorf("merkle proof mismatch"), fmt.Sprintf("chunk %d failed merkle proof verification", proof.ChunkIndex))
+	}
+	if r.control != nil {
+		var comp ChunkRangeCompressor
+		rangeStr, encoding, _ := comp.EncodeRanges([]int64{proof.ChunkIndex}, r.control.PeerSupportsRoaring())
+		_ = r.control.SendNack(&NackMessage{MissingRanges: rangeStr, Encoding: encoding, Reason: "merkle_proof_failed", SessionID: r.sessionID.String(), Timestamp: time.Now().Unix()})
+	}
+	idx := proof.ChunkIndex
+	r.publishEvent(observability.EventKindMerkleProofFailed, &idx, map[string]string{"chunk_hash": proof.ChunkHash})
+	r.publishEvent(observability.EventKindRetransmit, &idx, map[string]string{"reason": "merkle_proof_failed"})
+}
+
+// applyFECUpdate debounces and applies an FECUpdateMessage, whether it
+// arrived over the control stream proper or its datagram fast path: at most
+// once per 500ms, and only at group boundaries (when receivedCnt % K == 0),
+// so a burst of quick changes doesn't churn the decoder mid-group.
+func (r *ChunkReceiver) applyFECUpdate(u *FECUpdateMessage) {
+	if r.fecDec == nil || u.K <= 0 || u.R <= 0 {
+		return
+	}
+	k, _ := r.fecDec.GetParameters()
+	apply := true
+	if time.Since(r.lastFECUpdate) < 500*time.Millisecond { apply = false }
+	if k > 0 && r.receivedCnt%int64(k) != 0 { apply = false }
+	if !apply {
+		return
+	}
+	if dec, err := fec.NewDecoder(u.K, u.R); err == nil {
+		r.fecDec = dec
+		r.lastFECUpdate = time.Now()
+		r.publishEvent(observability.EventKindFECUpdated, nil, map[string]string{"k": fmt.Sprintf("%d", u.K), "r": fmt.Sprintf("%d", u.R)})
+	}
+}
+
+// serveChunkDatagrams consumes ChunkData and FECUpdate messages arriving
+// over the control stream's unreliable datagram fast path, as a sibling to
+// ServeControlUpdates' stream-based loop below (SendChunkData/SendFECUpdate
+// prefer the datagram path once the peer supports datagrams). Datagram
+// payloads are always plain JSON, never the stream's negotiated codec, so
+// this unmarshals directly rather than going through r.control.codec.
+func (r *ChunkReceiver) serveChunkDatagrams() {
+	if r.control == nil {
+		return
+	}
+	for dgram := range r.control.ReceiveDatagrams(r.connection.Context()) {
+		switch dgram.Type {
+		case MessageTypeChunkData:
+			var msg ChunkDataMessage
+			if json.Unmarshal(dgram.Payload, &msg) == nil {
+				r.processChunkDatagram(&msg)
+			}
+		case MessageTypeFECUpdate:
+			var u FECUpdateMessage
+			if json.Unmarshal(dgram.Payload, &u) == nil {
+				r.applyFECUpdate(&u)
+			}
+		}
+	}
+}
+
+// processChunkDatagram decrypts and assembles a chunk that arrived whole
+// over the datagram fast path, mirroring processChunkStream's decrypt/NACK/
+// assemble pipeline for a chunk that never opened a stream at all.
+func (r *ChunkReceiver) processChunkDatagram(msg *ChunkDataMessage) {
+	plaintext, err := r.decryptChunk(msg.ChunkIndex, msg.Ciphertext)
+	if err != nil {
+		if r.metrics != nil { r.metrics.RecordChunkRetransmit("decrypt_failed") }
+		if r.logger != nil { r.logger.ChunkDecryptFailed(r.sessionID.String(), int(msg.ChunkIndex), "decrypt_failed", err.Error(), 0) }
+		if r.control != nil {
+			var comp ChunkRangeCompressor
+			rangeStr, encoding, _ := comp.EncodeRanges([]int64{msg.ChunkIndex}, r.control.PeerSupportsRoaring())
+			_ = r.control.SendNack(&NackMessage{MissingRanges: rangeStr, Encoding: encoding, Reason: "decrypt_failed", SessionID: r.sessionID.String(), Timestamp: time.Now().Unix()})
+		}
+		idx := msg.ChunkIndex
+		r.publishEvent(observability.EventKindDecryptFailed, &idx, map[string]string{"error": err.Error()})
+		r.publishEvent(observability.EventKindRetransmit, &idx, map[string]string{"reason": "decrypt_failed"})
+		return
+	}
+	r.handleAssembledChunk(msg.ChunkIndex, plaintext)
 }
 
 // ServeControlUpdates listens for FEC updates and CHUNK_HAVE requests and responds appropriately.
 func (r *ChunkReceiver) ServeControlUpdates() {
+	go r.serveChunkDatagrams()
 	go func(){
 		for {
 			if r.control == nil { return }
@@ -247,22 +846,17 @@ func (r *ChunkReceiver) ServeControlUpdates() {
 			switch t {
 			case MessageTypeFECUpdate:
 				var u FECUpdateMessage
-				if json.Unmarshal(data, &u) == nil {
-					// Debounce quick changes: apply at most once per 500ms
-					// and only at group boundaries (when receivedCnt % K == 0)
-					if r.fecDec != nil && (u.K > 0 && u.R > 0) {
-						k, _ := r.fecDec.GetParameters()
-						apply := true
-						if time.Since(r.lastFECUpdate) < 500*time.Millisecond { apply = false }
-						if k > 0 && r.receivedCnt%int64(k) != 0 { apply = false }
-						if apply {
-							if dec, err := fec.NewDecoder(u.K, u.R); err == nil { r.fecDec = dec; r.lastFECUpdate = time.Now() }
-						}
-					}
+				if r.control.codec.Unmarshal(data, &u) == nil {
+					r.applyFECUpdate(&u)
+				}
+			case MessageTypeChunkProof:
+				var proof ChunkProofMessage
+				if r.control.codec.Unmarshal(data, &proof) == nil {
+					r.handleChunkProof(&proof)
 				}
 			case MessageTypeChunkHaveRequest:
 				var req ChunkHaveRequest
-				if json.Unmarshal(data, &req) == nil {
+				if r.control.codec.Unmarshal(data, &req) == nil {
 					// Build CAS bitmap from manifest chunk hashes
 					var idxs []int64
 					if r.manifest != nil {
@@ -271,8 +865,8 @@ func (r *ChunkReceiver) ServeControlUpdates() {
 						}
 					}
 					var comp ChunkRangeCompressor
-					ranges := comp.Compress(idxs)
-					_ = r.control.SendChunkHaveResponse(&ChunkHaveResponse{SessionID: req.SessionID, ChunkCount: req.ChunkCount, HaveRanges: ranges, Timestamp: time.Now().Unix()})
+					ranges, encoding, _ := comp.EncodeRanges(idxs, r.control.PeerSupportsRoaring())
+					_ = r.control.SendChunkHaveResponse(&ChunkHaveResponse{SessionID: req.SessionID, ChunkCount: req.ChunkCount, HaveRanges: ranges, Encoding: encoding, Timestamp: time.Now().Unix()})
 				}
 			}
 		}
@@ -311,22 +905,81 @@ func (r *ChunkReceiver) parseChunkHeader(header []byte) (int64, int, error) {
 	return chunkIndex, payloadLen, nil
 }
 
+// parseFragmentHeader parses a FragmentHeaderSize-byte wire header. Unlike
+// parseChunkHeader it doesn't check the session ID against the receiver's
+// own, since processFragmentedStream/dispatchStream accept a stream (and
+// thus a session) before any header is read; a mismatched session ID here
+// would indicate a routing bug elsewhere rather than a per-fragment concern.
+func parseFragmentHeader(header []byte) (FragmentHeader, error) {
+	if len(header) != FragmentHeaderSize {
+		return FragmentHeader{}, fmt.Errorf("fragment header: expected %d bytes, got %d", FragmentHeaderSize, len(header))
+	}
+
+	magic := binary.BigEndian.Uint32(header[0:4])
+	if magic != ChunkMagic {
+		return FragmentHeader{}, ErrInvalidMagic
+	}
+	if header[4] != ChunkVersionFragmented {
+		return FragmentHeader{}, ErrInvalidVersion
+	}
+
+	var fh FragmentHeader
+	fh.Final = header[5]&fragmentFlagFinal != 0
+	copy(fh.SessionID[:], header[8:24])
+	fh.SegmentSeq = binary.BigEndian.Uint32(header[24:28])
+	fh.FragmentSeq = binary.BigEndian.Uint32(header[28:32])
+	fh.PayloadLen = int(binary.BigEndian.Uint32(header[32:36]))
+
+	return fh, nil
+}
+
+// aead resolves the crypto.AEAD this receiver decrypts chunk/fragment
+// payloads with: whatever the peer declared as its preferredAEAD in the
+// most recently received SignedManifest (control.PeerAEAD), or AES-256-GCM
+// if no control stream is attached yet.
+func (r *ChunkReceiver) aead() crypto.AEAD {
+	if r.control == nil {
+		return crypto.NewAEAD(crypto.AlgorithmAES256GCM)
+	}
+	return crypto.NewAEAD(r.control.PeerAEAD())
+}
+
+// decryptFragment decrypts one fragment's ciphertext, using the same
+// session key as whole-chunk decryption but a nonce and AAD derived from
+// both the segment and fragment sequence, mirroring the sender's
+// encryptFragment.
+func (r *ChunkReceiver) decryptFragment(segmentSeq, fragmentSeq uint32, ciphertext []byte) ([]byte, error) {
+	aead := r.aead()
+	counter := uint64(segmentSeq)<<32 | uint64(fragmentSeq)
+	nonce := crypto.DeriveNonceN(r.sessionKeys.IVBase[:], counter, aead.NonceSize())
+
+	aad := make([]byte, 16+4+4)
+	copy(aad[0:16], r.sessionID[:])
+	binary.BigEndian.PutUint32(aad[16:20], segmentSeq)
+	binary.BigEndian.PutUint32(aad[20:24], fragmentSeq)
+
+	return aead.Open(r.sessionKeys.PayloadKey[:], nonce, aad, ciphertext)
+}
+
 // decryptChunk decrypts chunk data using session keys
 func (r *ChunkReceiver) decryptChunk(chunkIndex int64, ciphertext []byte) ([]byte, error) {
-	// Derive nonce from chunk index
-	nonce := crypto.DeriveNonce(r.sessionKeys.IVBase, uint64(chunkIndex))
-	
+	aead := r.aead()
+
+	// Derive nonce from chunk index, sized to the negotiated AEAD (e.g. 24
+	// bytes for XChaCha20-Poly1305, not the fixed 12 DeriveNonce assumes)
+	nonce := crypto.DeriveNonceN(r.sessionKeys.IVBase[:], uint64(chunkIndex), aead.NonceSize())
+
 	// Construct AAD from session ID and chunk index
 	aad := make([]byte, 16+8)
 	copy(aad[0:16], r.sessionID[:])
 	binary.BigEndian.PutUint64(aad[16:24], uint64(chunkIndex))
-	
-	// Decrypt using AES-256-GCM
-	plaintext, err := crypto.Open(r.sessionKeys.PayloadKey[:], nonce[:], aad, ciphertext)
+
+	// Decrypt using the negotiated AEAD algorithm
+	plaintext, err := aead.Open(r.sessionKeys.PayloadKey[:], nonce, aad, ciphertext)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	return plaintext, nil
 }
 
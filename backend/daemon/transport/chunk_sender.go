@@ -6,10 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/quantarax/backend/internal/chunkreader"
 	"github.com/quantarax/backend/internal/crypto"
 	"github.com/quic-go/quic-go"
 )
@@ -27,43 +28,131 @@ type ChunkWorkerPool struct {
 	class         PriorityClass
 	sessionKeys   *crypto.SessionKeys
 	sessionID     uuid.UUID
-	filePath      string
+	reader        chunkreader.RangeReader
+	bufPool       *chunkreader.BufferPool
 	chunkSize     int64
+	fragmentSize  int64
+	fragmentTTL   time.Duration
 	ctx           context.Context
 	cancel        context.CancelFunc
 	wg            sync.WaitGroup
 	workerCancels []context.CancelFunc
 	onChunkSent   func(chunkIndex int64)
 	onChunkFailed func(chunkIndex int64, err error)
+
+	faultInjector *FaultInjector
+	bwMeter       *BandwidthMeter
+
+	backoff      BackoffConfig
+	retryBackoff RetryBackoff
+	retryMu      sync.Mutex
+	retryCount   map[int64]int
+
+	// leaseTTL/stopTimeout/workerLeases/leaseWG bound each in-flight
+	// sendChunk call with a refreshable Lease (see lease.go), so a worker
+	// cancellation during ScaleWorkers or Stop can abort a stuck stream
+	// write deterministically instead of leaking the goroutine.
+	leaseTTL     time.Duration
+	stopTimeout  time.Duration
+	leaseMu      sync.Mutex
+	workerLeases map[int]*Lease
+	leaseWG      sync.WaitGroup
+
+	// controlStream and datagramThreshold, set via SetControlStream/
+	// SetDatagramThreshold, let sendChunk send a chunk whose whole segment
+	// is datagramThreshold bytes or smaller as a single unreliable QUIC
+	// datagram instead of opening a stream for it. datagramThreshold's
+	// zero value disables the fast path, same as an unset faultInjector.
+	controlStream     *ControlStream
+	datagramThreshold int64
+
+	// repairMu guards repairIndices, the set of chunk indices
+	// EnqueueRepairChunk has marked as FEC parity/repair shards rather than
+	// ordinary data chunks. sendChunk consults it to decide whether to
+	// apply repairDatagramThreshold instead of datagramThreshold: losing a
+	// repair shard costs nothing (it's spare redundancy nobody's waiting
+	// on, recovered the same way a dropped parity chunk over a stream
+	// would be — simply not sent), so it's fine to risk a bigger datagram
+	// for one than for a data chunk of the same size.
+	repairMu                sync.Mutex
+	repairIndices           map[int64]bool
+	repairDatagramThreshold int64
+
+	// pauseMu guards pauseCh, which workers block on between dequeuing a
+	// chunk and sending it: open (default) means running, closed means
+	// paused. Pause/Resume swap it rather than stopping workers outright,
+	// so a paused pool can resume mid-transfer without losing queued chunks
+	// or worker goroutines the way Stop's one-shot context cancellation
+	// would.
+	pauseMu sync.RWMutex
+	pauseCh chan struct{}
 }
 
-// NewChunkWorkerPool creates a new worker pool
+// NewChunkWorkerPool creates a new worker pool that reads chunk data from
+// reader (a chunkreader.RangeReader shared across every worker and, when
+// the caller sets one up front, across every pool in an OrchestratedSender)
+// instead of each worker opening filePath itself.
 func NewChunkWorkerPool(
 	workerCount int,
 	queueDepth int,
 	connection *quic.Conn,
 	sessionKeys *crypto.SessionKeys,
 	sessionID uuid.UUID,
-	filePath string,
+	reader chunkreader.RangeReader,
 	chunkSize int64,
 	onChunkSent func(chunkIndex int64),
 	onChunkFailed func(chunkIndex int64, err error),
 ) *ChunkWorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	fragmentSize := int64(defaultFragmentSize)
+	running := make(chan struct{})
+	close(running)
 	return &ChunkWorkerPool{
 		workerCount:   workerCount,
 		chunkQueue:    make(chan int64, queueDepth),
 		connection:    connection,
 		sessionKeys:   sessionKeys,
 		sessionID:     sessionID,
-		filePath:      filePath,
+		reader:        reader,
+		bufPool:       chunkreader.NewBufferPool(int(fragmentSize)),
 		chunkSize:     chunkSize,
+		fragmentSize:  fragmentSize,
 		ctx:           ctx,
 		cancel:        cancel,
 		onChunkSent:   onChunkSent,
 		onChunkFailed: onChunkFailed,
 		class:         PriorityP2,
+		retryCount:    make(map[int64]int),
+		leaseTTL:      defaultLeaseTTL,
+		stopTimeout:   defaultStopTimeout,
+		workerLeases:  make(map[int]*Lease),
+		pauseCh:       running,
+	}
+}
+
+// Pause blocks every worker between dequeuing a chunk and sending it, until
+// Resume is called. Unlike Stop, queued chunks and worker goroutines are
+// left intact. Calling Pause while already paused is a no-op.
+func (p *ChunkWorkerPool) Pause() {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+	select {
+	case <-p.pauseCh:
+		p.pauseCh = make(chan struct{})
+	default:
+	}
+}
+
+// Resume releases workers blocked by a prior Pause. Calling Resume while
+// not paused is a no-op.
+func (p *ChunkWorkerPool) Resume() {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+	select {
+	case <-p.pauseCh:
+	default:
+		close(p.pauseCh)
 	}
 }
 
@@ -92,17 +181,69 @@ func (p *ChunkWorkerPool) EnqueueChunk(chunkIndex int64) error {
 	}
 }
 
-// Stop stops the worker pool gracefully
+// EnqueueRepairChunk adds an FEC parity/repair chunk to the transmission
+// queue, same as EnqueueChunk, but marks chunkIndex so sendChunk applies
+// repairDatagramThreshold instead of the ordinary datagramThreshold when
+// deciding whether it fits the unreliable datagram fast path — see
+// SetRepairDatagramThreshold.
+func (p *ChunkWorkerPool) EnqueueRepairChunk(chunkIndex int64) error {
+	p.repairMu.Lock()
+	if p.repairIndices == nil {
+		p.repairIndices = make(map[int64]bool)
+	}
+	p.repairIndices[chunkIndex] = true
+	p.repairMu.Unlock()
+	return p.EnqueueChunk(chunkIndex)
+}
+
+// isRepairChunk reports whether chunkIndex was enqueued via
+// EnqueueRepairChunk, and clears its entry: each index is only ever sent
+// once per call to sendChunk, so there's nothing to keep it around for
+// afterward.
+func (p *ChunkWorkerPool) isRepairChunk(chunkIndex int64) bool {
+	p.repairMu.Lock()
+	defer p.repairMu.Unlock()
+	repair := p.repairIndices[chunkIndex]
+	delete(p.repairIndices, chunkIndex)
+	return repair
+}
+
+// Stop stops the worker pool gracefully. It gives outstanding leases
+// p.stopTimeout to finish on their own (the normal case: a worker notices
+// its context is done and returns between chunks); if any are still
+// in-flight past that deadline, it revokes every lease, which aborts their
+// stream writes via CancelWrite so the blocked workers return promptly.
 func (p *ChunkWorkerPool) Stop() {
 	// Stop workers
 	for _, c := range p.workerCancels {
 		c()
 	}
 	close(p.chunkQueue)
-	p.wg.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(p.stopTimeout):
+		p.revokeAllLeases()
+		<-done
+	}
+
 	p.cancel()
 }
 
+// SetReader swaps the pool's RangeReader, e.g. to layer FEC parity chunks on
+// top of the original source file via OrchestratedSender.EnableFEC. Like
+// SetChunkSize, it's meant to be called once during setup, before any chunk
+// is enqueued — not while sends are already in flight.
+func (p *ChunkWorkerPool) SetReader(reader chunkreader.RangeReader) {
+	p.reader = reader
+}
+
 // SetChunkSize updates the chunk size used by workers
 func (p *ChunkWorkerPool) SetChunkSize(bytes int64) {
 	if bytes > 0 {
@@ -110,6 +251,22 @@ func (p *ChunkWorkerPool) SetChunkSize(bytes int64) {
 	}
 }
 
+// SetFragmentSize sets how many bytes of a segment each fragment carries.
+// Zero or unset falls back to defaultFragmentSize.
+func (p *ChunkWorkerPool) SetFragmentSize(bytes int64) {
+	if bytes > 0 {
+		p.fragmentSize = bytes
+		p.bufPool = chunkreader.NewBufferPool(int(bytes))
+	}
+}
+
+// SetFragmentTTL sets how long a fragment is allowed to wait in the
+// PriorityScheduler before it's dropped as stale rather than sent late. Zero
+// (the default) means fragments never expire.
+func (p *ChunkWorkerPool) SetFragmentTTL(ttl time.Duration) {
+	p.fragmentTTL = ttl
+}
+
 // ScaleWorkers adjusts the number of active workers. It can scale up or down.
 func (p *ChunkWorkerPool) ScaleWorkers(target int) {
 	if target <= 0 {
@@ -119,10 +276,15 @@ func (p *ChunkWorkerPool) ScaleWorkers(target int) {
 	for len(p.workerCancels) < target {
 		p.addWorker()
 	}
-	// Scale down
+	// Scale down. Canceling workerCancels[idx] alone only stops that worker
+	// from picking up its *next* chunk — a chunk it's already sending opened
+	// its stream against its lease's context, not this one, so it keeps
+	// writing unless that lease is also revoked here.
 	for len(p.workerCancels) > target {
 		idx := len(p.workerCancels) - 1
+		id := idx + 1
 		p.workerCancels[idx]()
+		p.revokeWorkerLease(id)
 		p.workerCancels = p.workerCancels[:idx]
 	}
 }
@@ -139,33 +301,36 @@ func (p *ChunkWorkerPool) workerWithCtx(workerID int, wctx context.Context) {
 				return
 			}
 
-			// If a scheduler is present, enqueue by priority class
-			if p.scheduler != nil {
-				ci := chunkIndex
-				p.scheduler.Enqueue(p.class, func(ctx context.Context) {
-					if err := p.sendChunk(ci); err != nil {
-						fmt.Printf("Worker %d: failed to send chunk %d: %v\n", workerID, ci, err)
-						if p.onChunkFailed != nil {
-							p.onChunkFailed(ci, err)
-						}
-						return
-					}
-					if p.onChunkSent != nil {
-						p.onChunkSent(ci)
-					}
-				})
-				continue
+			p.pauseMu.RLock()
+			pauseCh := p.pauseCh
+			p.pauseMu.RUnlock()
+			select {
+			case <-pauseCh:
+			case <-p.ctx.Done():
+				return
+			case <-wctx.Done():
+				return
 			}
 
-			if err := p.sendChunk(chunkIndex); err != nil {
-				// Log error and enqueue DTN retry via callback
+			// sendChunk itself routes each fragment's write through the
+			// scheduler (if one is configured), so priority/expiry is
+			// honored at fragment granularity rather than gating the whole
+			// segment behind one scheduler slot.
+			if err := p.sendChunk(workerID, chunkIndex); err != nil {
 				fmt.Printf("Worker %d: failed to send chunk %d: %v\n", workerID, chunkIndex, err)
+				if p.scheduleRetry(chunkIndex, err) {
+					continue
+				}
+				// Retries exhausted (or none configured): fall back to the
+				// caller's own recovery path, e.g. DTN re-enqueue.
 				if p.onChunkFailed != nil {
 					p.onChunkFailed(chunkIndex, err)
 				}
 				continue
 			}
 
+			p.clearRetry(chunkIndex)
+
 			// Notify chunk sent
 			if p.onChunkSent != nil {
 				p.onChunkSent(chunkIndex)
@@ -179,103 +344,540 @@ func (p *ChunkWorkerPool) workerWithCtx(workerID int, wctx context.Context) {
 	}
 }
 
-// sendChunk sends a single chunk over a QUIC stream
-func (p *ChunkWorkerPool) sendChunk(chunkIndex int64) error {
-	// Open new QUIC stream for this chunk
-	stream, err := p.connection.OpenStreamSync(p.ctx)
+// scheduleRetry decides whether chunkIndex should be retried in-pool after a
+// failed send (sendErr). If p.retryBackoff is set, it takes over entirely:
+// its returned delay re-enqueues chunkIndex (a <= 0 delay means stop, per
+// RetryBackoff's permanent-failure convention). Otherwise p.backoff governs
+// as before — if it allows another attempt, chunkIndex is re-enqueued after
+// the configured delay and this returns true; if not, it returns false so
+// the caller falls back to onChunkFailed. Either way the delay is waited
+// out on a separate timer rather than blocking the worker goroutine, so one
+// chunk's backoff doesn't stall the rest of the queue.
+func (p *ChunkWorkerPool) scheduleRetry(chunkIndex int64, sendErr error) bool {
+	if p.retryBackoff != nil {
+		p.retryMu.Lock()
+		attempt := p.retryCount[chunkIndex]
+		p.retryCount[chunkIndex] = attempt + 1
+		p.retryMu.Unlock()
+
+		delay := p.retryBackoff(attempt, sendErr, 0)
+		if delay <= 0 {
+			return false
+		}
+		time.AfterFunc(delay, func() {
+			_ = p.EnqueueChunk(chunkIndex)
+		})
+		return true
+	}
+
+	if p.backoff.MaxRetries <= 0 {
+		return false
+	}
+
+	p.retryMu.Lock()
+	attempt := p.retryCount[chunkIndex]
+	if attempt >= p.backoff.MaxRetries {
+		p.retryMu.Unlock()
+		return false
+	}
+	p.retryCount[chunkIndex] = attempt + 1
+	p.retryMu.Unlock()
+
+	time.AfterFunc(p.backoff.delay(attempt), func() {
+		_ = p.EnqueueChunk(chunkIndex)
+	})
+	return true
+}
+
+// clearRetry forgets chunkIndex's retry count after a successful send.
+func (p *ChunkWorkerPool) clearRetry(chunkIndex int64) {
+	p.retryMu.Lock()
+	delete(p.retryCount, chunkIndex)
+	p.retryMu.Unlock()
+}
+
+// setWorkerLease records (or, with lease nil, forgets) the Lease currently
+// bounding workerID's in-flight sendChunk call, so ScaleWorkers and Stop can
+// find and revoke it later.
+func (p *ChunkWorkerPool) setWorkerLease(workerID int, lease *Lease) {
+	p.leaseMu.Lock()
+	defer p.leaseMu.Unlock()
+	if lease == nil {
+		delete(p.workerLeases, workerID)
+		return
+	}
+	p.workerLeases[workerID] = lease
+}
+
+// revokeWorkerLease revokes workerID's current lease, if it has one
+// in-flight. It's a no-op if the worker isn't between leases or has none.
+func (p *ChunkWorkerPool) revokeWorkerLease(workerID int) {
+	p.leaseMu.Lock()
+	lease, ok := p.workerLeases[workerID]
+	p.leaseMu.Unlock()
+	if ok {
+		lease.Revoke()
+	}
+}
+
+// revokeAllLeases revokes every lease currently in flight, aborting every
+// worker's in-progress stream write so Stop's bounded wait can complete.
+func (p *ChunkWorkerPool) revokeAllLeases() {
+	p.leaseMu.Lock()
+	leases := make([]*Lease, 0, len(p.workerLeases))
+	for _, lease := range p.workerLeases {
+		leases = append(leases, lease)
+	}
+	p.leaseMu.Unlock()
+	for _, lease := range leases {
+		lease.Revoke()
+	}
+}
+
+// fragmentPlan is one fragment's slice of a segment: offset/length relative
+// to the segment's start, its sequence number, and whether it's the last
+// fragment of the segment.
+type fragmentPlan struct {
+	seq    uint32
+	offset int64
+	length int64
+	final  bool
+}
+
+// planFragments splits a segmentLen-byte segment into ordered fragments of
+// at most fragmentSize bytes each. A zero-length segment still produces one
+// (empty, final) fragment so the receiver sees a complete, if trivial,
+// segment rather than nothing at all.
+func planFragments(segmentLen, fragmentSize int64) []fragmentPlan {
+	if fragmentSize <= 0 {
+		fragmentSize = defaultFragmentSize
+	}
+	if segmentLen == 0 {
+		return []fragmentPlan{{final: true}}
+	}
+
+	var plans []fragmentPlan
+	var seq uint32
+	for offset := int64(0); offset < segmentLen; offset += fragmentSize {
+		length := fragmentSize
+		if offset+length > segmentLen {
+			length = segmentLen - offset
+		}
+		plans = append(plans, fragmentPlan{seq: seq, offset: offset, length: length})
+		seq++
+	}
+	plans[len(plans)-1].final = true
+	return plans
+}
+
+// segmentByteLength returns how many bytes of the reader's content actually
+// belong to the segment starting at offset, which is less than chunkSize
+// for the file's final (possibly partial) segment.
+func segmentByteLength(reader chunkreader.RangeReader, offset, chunkSize int64) (int64, error) {
+	size, err := reader.Size()
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer stream.Close()
+	if offset >= size {
+		return 0, io.EOF
+	}
+	if remaining := size - offset; remaining < chunkSize {
+		return remaining, nil
+	}
+	return chunkSize, nil
+}
 
-	// Read chunk data from file
-	chunkData, err := p.readChunkFromFile(chunkIndex)
+// sendChunk sends one chunk ("segment") over a dedicated QUIC stream,
+// pipelining the three stages that used to run back-to-back: one goroutine
+// streams fragments off disk via io.ReaderAt, a second encrypts each
+// fragment as it arrives, and this goroutine writes the encrypted fragments
+// to the stream as they become ready — so, for multi-fragment segments,
+// disk I/O for fragment N+1 overlaps encryption of fragment N and the wire
+// write of fragment N-1 instead of all three running strictly in sequence.
+func (p *ChunkWorkerPool) sendChunk(workerID int, chunkIndex int64) error {
+	if p.reader == nil {
+		return fmt.Errorf("chunk %d: no reader available (source file failed to open)", chunkIndex)
+	}
+
+	if p.faultInjector != nil {
+		if err := p.faultInjector.BeforeOpenStream(); err != nil {
+			return fmt.Errorf("chunk %d: %w", chunkIndex, err)
+		}
+	}
+
+	if pf, ok := p.reader.(*chunkreader.PrefetchReader); ok {
+		pf.Advance(chunkIndex)
+	}
+
+	segmentOffset := chunkIndex * p.chunkSize
+	segmentLen, err := segmentByteLength(p.reader, segmentOffset, p.chunkSize)
 	if err != nil {
 		return err
 	}
 
-	// Encrypt chunk
-	encryptedChunk, err := p.encryptChunk(chunkIndex, chunkData)
+	// Below DatagramThreshold (or RepairDatagramThreshold for a chunk
+	// EnqueueRepairChunk marked), try the unreliable datagram fast path
+	// before paying for a stream open at all. Any failure here (oversize
+	// once framed, a transient SendDatagram error, or the peer dropping
+	// datagram support mid-transfer) falls through to the ordinary
+	// fragmented-stream pipeline below rather than failing the chunk
+	// outright.
+	threshold := p.datagramThreshold
+	if p.isRepairChunk(chunkIndex) && p.repairDatagramThreshold > threshold {
+		threshold = p.repairDatagramThreshold
+	}
+	if threshold > 0 && segmentLen <= threshold &&
+		p.controlStream != nil && p.controlStream.SupportsDatagrams() {
+		if err := p.sendChunkDatagram(chunkIndex, segmentOffset, segmentLen); err == nil {
+			return nil
+		}
+	}
+
+	// lease bounds this call so ScaleWorkers/Stop can abort it deterministically
+	// even though it's running on a worker goroutine whose own wctx cancellation
+	// only stops it from picking up its *next* chunk, not this one.
+	lease := NewLease(p.ctx, p.leaseTTL)
+	p.setWorkerLease(workerID, lease)
+	p.leaseWG.Add(1)
+	defer func() {
+		p.setWorkerLease(workerID, nil)
+		lease.Release()
+		p.leaseWG.Done()
+	}()
+
+	stream, err := p.connection.OpenStreamSync(lease.Context())
 	if err != nil {
 		return err
 	}
+	defer stream.Close()
 
-	// Build chunk message header
-	header := p.buildChunkHeader(chunkIndex, len(encryptedChunk))
+	// watchDone stops the watcher goroutine once sendChunk returns normally;
+	// otherwise it would outlive this call waiting on a lease.Context() that
+	// may never fire.
+	watchDone := make(chan struct{})
+	defer close(watchDone)
+	go func() {
+		select {
+		case <-lease.Context().Done():
+			stream.CancelWrite(0)
+			stream.CancelRead(0)
+		case <-watchDone:
+		}
+	}()
 
-	// Write header and encrypted payload to stream
-	if _, err := stream.Write(header); err != nil {
-		return err
+	fragments := planFragments(segmentLen, p.fragmentSize)
+
+	// segCtx bounds just this segment's pipeline: canceling it on return (success
+	// or failure) unblocks the reader/encrypt goroutines if the writer stage
+	// below exits early, instead of leaving them parked on a full channel until
+	// the whole pool eventually stops.
+	segCtx, cancelSeg := context.WithCancel(p.ctx)
+	defer cancelSeg()
+
+	type rawFragment struct {
+		seq   uint32
+		data  []byte
+		final bool
+	}
+	type encFragment struct {
+		seq   uint32
+		data  []byte
+		final bool
+	}
+
+	const pipelineDepth = 4
+	rawCh := make(chan rawFragment, pipelineDepth)
+	encCh := make(chan encFragment, pipelineDepth)
+	errCh := make(chan error, 2)
+
+	var stages sync.WaitGroup
+	stages.Add(2)
+
+	// Stage 1: read fragments off disk.
+	go func() {
+		defer stages.Done()
+		defer close(rawCh)
+		for _, fr := range fragments {
+			buf := p.fragmentBuffer(fr.length)
+			if _, err := p.reader.ReadAt(buf, segmentOffset+fr.offset); err != nil && err != io.EOF {
+				select {
+				case errCh <- fmt.Errorf("read fragment %d: %w", fr.seq, err):
+				default:
+				}
+				return
+			}
+			select {
+			case rawCh <- rawFragment{seq: fr.seq, data: buf, final: fr.final}:
+			case <-segCtx.Done():
+				return
+			}
+		}
+	}()
+
+	// Stage 2: encrypt each fragment with a nonce derived from segment+fragment.
+	go func() {
+		defer stages.Done()
+		defer close(encCh)
+		for rf := range rawCh {
+			ciphertext, err := p.encryptFragment(uint32(chunkIndex), rf.seq, rf.data)
+			p.releaseFragmentBuffer(rf.data)
+			if err != nil {
+				select {
+				case errCh <- fmt.Errorf("encrypt fragment %d: %w", rf.seq, err):
+				default:
+				}
+				return
+			}
+			select {
+			case encCh <- encFragment{seq: rf.seq, data: ciphertext, final: rf.final}:
+			case <-segCtx.Done():
+				return
+			}
+		}
+	}()
+
+	// Stage 3: write each fragment to the stream as it becomes ready.
+	for ef := range encCh {
+		if err := p.writeFragment(stream, lease, uint32(chunkIndex), ef.seq, ef.data, ef.final); err != nil {
+			return err
+		}
 	}
 
-	if _, err := stream.Write(encryptedChunk); err != nil {
+	stages.Wait()
+	select {
+	case err := <-errCh:
 		return err
+	default:
+		return nil
 	}
+}
 
-	return nil
+// fragmentBuffer returns a buffer of exactly length bytes, drawn from
+// p.bufPool when length matches the pool's fixed size (true for every
+// fragment except a segment's final, possibly shorter one) and freshly
+// allocated otherwise.
+func (p *ChunkWorkerPool) fragmentBuffer(length int64) []byte {
+	if length == p.fragmentSize {
+		return p.bufPool.Get()
+	}
+	return make([]byte, length)
 }
 
-// readChunkFromFile reads chunk data from file at the appropriate offset
-func (p *ChunkWorkerPool) readChunkFromFile(chunkIndex int64) ([]byte, error) {
-	file, err := os.Open(p.filePath)
-	if err != nil {
-		return nil, err
+// releaseFragmentBuffer returns buf to p.bufPool once its plaintext bytes
+// have been consumed (i.e. after encryption), so the next fragment read can
+// reuse the backing array instead of allocating a new one.
+func (p *ChunkWorkerPool) releaseFragmentBuffer(buf []byte) {
+	p.bufPool.Put(buf)
+}
+
+// writeFragment writes one fragment's header and ciphertext to stream. When
+// the pool has a PriorityScheduler attached, the write itself is routed
+// through it under the pool's priority class with a TTL-based expiry, so a
+// fragment that's gone stale waiting behind other classes' work is dropped
+// instead of sent late, and higher-priority fragments from other in-flight
+// segments can still interleave between this segment's own fragment writes.
+// lease is refreshed between the header and payload writes, so a segment
+// with many fragments keeps extending its own deadline as long as it's
+// still making progress, rather than expiring mid-segment on a fixed clock
+// started back when sendChunk opened the stream.
+func (p *ChunkWorkerPool) writeFragment(stream *quic.Stream, lease *Lease, segmentSeq, fragmentSeq uint32, payload []byte, final bool) error {
+	header := p.buildFragmentHeader(segmentSeq, fragmentSeq, len(payload), final)
+
+	if p.scheduler == nil {
+		if _, err := p.writeWire(stream, header); err != nil {
+			return err
+		}
+		lease.Refresh()
+		_, err := p.writeWire(stream, payload)
+		if err == nil && p.bwMeter != nil {
+			p.bwMeter.AddUsefulOut(p.class, int64(len(payload)))
+		}
+		return err
 	}
-	defer file.Close()
 
-	offset := chunkIndex * p.chunkSize
-	if _, err := file.Seek(offset, 0); err != nil {
-		return nil, err
+	var expires time.Time
+	if p.fragmentTTL > 0 {
+		expires = time.Now().Add(p.fragmentTTL)
 	}
 
-	chunkData := make([]byte, p.chunkSize)
-	n, err := file.Read(chunkData)
-	if err != nil && err != io.EOF {
-		return nil, err
+	done := make(chan error, 1)
+	p.scheduler.EnqueueWithExpiry(p.class, expires, func(ctx context.Context) {
+		if _, err := p.writeWire(stream, header); err != nil {
+			done <- err
+			return
+		}
+		lease.Refresh()
+		_, err := p.writeWire(stream, payload)
+		if err == nil && p.bwMeter != nil {
+			p.bwMeter.AddUsefulOut(p.class, int64(len(payload)))
+		}
+		done <- err
+	})
+
+	select {
+	case err := <-done:
+		return err
+	case <-p.ctx.Done():
+		return ErrWorkerPoolStopped
 	}
+}
+
+// writeWire writes data to stream, routing through the fault injector (if
+// one is configured) before the real write so a simulated failure never
+// touches the actual QUIC stream, and recording raw bytes on the bandwidth
+// meter (if one is configured) regardless of outcome.
+func (p *ChunkWorkerPool) writeWire(stream *quic.Stream, data []byte) (int, error) {
+	if p.faultInjector != nil {
+		if n, err := p.faultInjector.BeforeWrite(data); err != nil {
+			if p.bwMeter != nil {
+				p.bwMeter.AddRawOut(p.class, int64(n))
+			}
+			return n, err
+		}
+	}
+	n, err := stream.Write(data)
+	if p.bwMeter != nil {
+		p.bwMeter.AddRawOut(p.class, int64(n))
+	}
+	return n, err
+}
+
+// aead resolves the crypto.AEAD this pool encrypts chunk/fragment payloads
+// with: whatever controlStream.SetPreferredAEAD declared for this side in
+// the manifest handshake (see crypto.AEADAlgorithm), or AES-256-GCM if no
+// control stream is attached yet.
+func (p *ChunkWorkerPool) aead() crypto.AEAD {
+	if p.controlStream == nil {
+		return crypto.NewAEAD(crypto.AlgorithmAES256GCM)
+	}
+	return crypto.NewAEAD(p.controlStream.PreferredAEAD())
+}
+
+// encryptFragment encrypts one fragment of segmentSeq using a nonce and AAD
+// derived from both the segment and fragment sequence, so every fragment —
+// unlike whole-chunk encryption, which only varied by chunk index — gets a
+// distinct nonce even within the same segment. The nonce is sized to the
+// negotiated AEAD via DeriveNonceN rather than the fixed 12-byte
+// DeriveNonce, since XChaCha20-Poly1305 needs a 24-byte nonce.
+func (p *ChunkWorkerPool) encryptFragment(segmentSeq, fragmentSeq uint32, plaintext []byte) ([]byte, error) {
+	aead := p.aead()
+	counter := uint64(segmentSeq)<<32 | uint64(fragmentSeq)
+	nonce := crypto.DeriveNonceN(p.sessionKeys.IVBase[:], counter, aead.NonceSize())
+
+	aad := make([]byte, 16+4+4)
+	copy(aad[0:16], p.sessionID[:])
+	binary.BigEndian.PutUint32(aad[16:20], segmentSeq)
+	binary.BigEndian.PutUint32(aad[20:24], fragmentSeq)
 
-	return chunkData[:n], nil
+	return aead.Seal(p.sessionKeys.PayloadKey[:], nonce, aad, plaintext)
 }
 
-// encryptChunk encrypts chunk data using session keys
+// encryptChunk encrypts a whole chunk for the datagram fast path, using the
+// same nonce and AAD derivation as the receiver's decryptChunk: a nonce
+// from the chunk index alone (crypto.DeriveNonceN, sized to the negotiated
+// AEAD), unlike encryptFragment's segment+fragment derivation, since a
+// datagram chunk is never split into fragments.
 func (p *ChunkWorkerPool) encryptChunk(chunkIndex int64, plaintext []byte) ([]byte, error) {
-	// Derive nonce from chunk index
-	nonce := crypto.DeriveNonce(p.sessionKeys.IVBase, uint64(chunkIndex))
+	aead := p.aead()
+	nonce := crypto.DeriveNonceN(p.sessionKeys.IVBase[:], uint64(chunkIndex), aead.NonceSize())
 
-	// Construct AAD from session ID and chunk index
 	aad := make([]byte, 16+8)
 	copy(aad[0:16], p.sessionID[:])
 	binary.BigEndian.PutUint64(aad[16:24], uint64(chunkIndex))
 
-	// Encrypt using AES-256-GCM
-	ciphertext, err := crypto.Seal(p.sessionKeys.PayloadKey[:], nonce[:], aad, plaintext)
+	return aead.Seal(p.sessionKeys.PayloadKey[:], nonce, aad, plaintext)
+}
+
+// EncryptChunkForBundle reads chunkIndex's whole segment and seals it with
+// the same whole-chunk nonce/AAD derivation as encryptChunk (and hence
+// sendChunkDatagram), so a DTN bundle's ciphertext is decryptable by the
+// same receiver-side decryptChunk a datagram-delivered chunk would use. Used
+// by service.SendWithOrchestration's DTN bundle-mode fallback rather than
+// the normal stream/datagram send path.
+func (p *ChunkWorkerPool) EncryptChunkForBundle(chunkIndex int64) ([]byte, error) {
+	if p.reader == nil {
+		return nil, fmt.Errorf("chunk %d: no reader available (source file failed to open)", chunkIndex)
+	}
+	segmentOffset := chunkIndex * p.chunkSize
+	segmentLen, err := segmentByteLength(p.reader, segmentOffset, p.chunkSize)
 	if err != nil {
 		return nil, err
 	}
+	plaintext := p.fragmentBuffer(segmentLen)
+	defer p.releaseFragmentBuffer(plaintext)
+	if _, err := p.reader.ReadAt(plaintext, segmentOffset); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("chunk %d: read for bundle send: %w", chunkIndex, err)
+	}
+	return p.encryptChunk(chunkIndex, plaintext)
+}
+
+// sendChunkDatagram reads chunkIndex's whole segment (segmentOffset,
+// segmentLen bytes), encrypts it as one piece, and sends it as a single
+// ChunkDataMessage over the unreliable datagram path instead of opening a
+// stream. Called only once sendChunk has confirmed segmentLen fits under
+// DatagramThreshold and the peer supports datagrams.
+func (p *ChunkWorkerPool) sendChunkDatagram(chunkIndex, segmentOffset, segmentLen int64) error {
+	plaintext := p.fragmentBuffer(segmentLen)
+	defer p.releaseFragmentBuffer(plaintext)
+	if _, err := p.reader.ReadAt(plaintext, segmentOffset); err != nil && err != io.EOF {
+		return fmt.Errorf("chunk %d: read for datagram send: %w", chunkIndex, err)
+	}
 
-	return ciphertext, nil
+	ciphertext, err := p.encryptChunk(chunkIndex, plaintext)
+	if err != nil {
+		return fmt.Errorf("chunk %d: encrypt for datagram send: %w", chunkIndex, err)
+	}
+
+	// SendChunkData (via SendDatagramMessage) accounts the attempt on
+	// whatever observability.Metrics the session's ControlStream carries,
+	// same as SendAck/SendNack/SendFECUpdate's datagram fast path.
+	if err := p.controlStream.SendChunkData(&ChunkDataMessage{
+		SessionID:  p.sessionID.String(),
+		ChunkIndex: chunkIndex,
+		Ciphertext: ciphertext,
+		Timestamp:  time.Now().Unix(),
+	}); err != nil {
+		return err
+	}
+
+	if p.bwMeter != nil {
+		p.bwMeter.AddUsefulOut(p.class, int64(len(plaintext)))
+	}
+	return nil
 }
 
-// buildChunkHeader constructs the chunk message header
-func (p *ChunkWorkerPool) buildChunkHeader(chunkIndex int64, payloadLen int) []byte {
-	header := make([]byte, ChunkHeaderSize)
+// buildFragmentHeader constructs the wire header for one fragment of
+// segment segmentSeq, extending ChunkHeader's layout: SessionID keeps its
+// offset, the chunk-index slot becomes SegmentSeq, and FragmentSeq is
+// appended after it. The final fragment flag lives in the byte ChunkHeader
+// left reserved.
+func (p *ChunkWorkerPool) buildFragmentHeader(segmentSeq, fragmentSeq uint32, payloadLen int, final bool) []byte {
+	header := make([]byte, FragmentHeaderSize)
 
 	// Magic (4 bytes)
 	binary.BigEndian.PutUint32(header[0:4], ChunkMagic)
 
 	// Version (1 byte)
-	header[4] = ChunkVersion
+	header[4] = ChunkVersionFragmented
+
+	// Flags (1 byte): bit0 = final fragment
+	if final {
+		header[5] = fragmentFlagFinal
+	}
 
-	// Reserved (3 bytes) - zeros
+	// Reserved (2 bytes) - zeros
 
 	// SessionID (16 bytes)
 	copy(header[8:24], p.sessionID[:])
 
-	// ChunkIndex (4 bytes)
-	binary.BigEndian.PutUint32(header[24:28], uint32(chunkIndex))
+	// SegmentSeq (4 bytes)
+	binary.BigEndian.PutUint32(header[24:28], segmentSeq)
+
+	// FragmentSeq (4 bytes)
+	binary.BigEndian.PutUint32(header[28:32], fragmentSeq)
 
 	// PayloadLength (4 bytes)
-	binary.BigEndian.PutUint32(header[28:32], uint32(payloadLen))
+	binary.BigEndian.PutUint32(header[32:36], uint32(payloadLen))
 
 	return header
 }
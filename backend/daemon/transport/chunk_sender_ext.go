@@ -1,7 +1,80 @@
 package transport
 
+import (
+	"time"
+)
+
 // SetScheduler configures the worker pool with a priority scheduler and class.
 func (p *ChunkWorkerPool) SetScheduler(s *PriorityScheduler, class PriorityClass) {
 	p.scheduler = s
 	p.class = class
 }
+
+// SetFaultInjector attaches chaos behavior to every stream open and write
+// this pool performs. A nil injector (the default) disables it.
+func (p *ChunkWorkerPool) SetFaultInjector(f *FaultInjector) {
+	p.faultInjector = f
+}
+
+// SetBandwidthMeter attaches a meter that accumulates raw and useful bytes
+// for every write this pool performs. A nil meter (the default) disables
+// accounting.
+func (p *ChunkWorkerPool) SetBandwidthMeter(m *BandwidthMeter) {
+	p.bwMeter = m
+}
+
+// SetBackoff configures in-pool retry backoff for failed chunk sends. The
+// zero value disables retries, so every failure goes straight to
+// onChunkFailed as before.
+func (p *ChunkWorkerPool) SetBackoff(cfg BackoffConfig) {
+	p.backoff = cfg
+}
+
+// SetRetryBackoff overrides scheduleRetry's delay policy with fn (see
+// RetryBackoff), taking over from BackoffConfig entirely for as long as fn
+// is non-nil. A nil fn (the default) leaves SetBackoff's BackoffConfig in
+// charge.
+func (p *ChunkWorkerPool) SetRetryBackoff(fn RetryBackoff) {
+	p.retryBackoff = fn
+}
+
+// SetLeaseTTL overrides how long a chunk send's Lease gets before it expires
+// if nothing refreshes it. Zero or negative leaves the default in place.
+func (p *ChunkWorkerPool) SetLeaseTTL(ttl time.Duration) {
+	if ttl > 0 {
+		p.leaseTTL = ttl
+	}
+}
+
+// SetStopTimeout overrides how long Stop waits for in-flight leases to
+// finish on their own before revoking them outright. Zero or negative
+// leaves the default in place.
+func (p *ChunkWorkerPool) SetStopTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		p.stopTimeout = timeout
+	}
+}
+
+// SetControlStream gives the pool the session's ControlStream, so sendChunk
+// can check SupportsDatagrams and send via SendChunkData once
+// SetDatagramThreshold has enabled the fast path. A nil stream (the
+// default) leaves every chunk going out over its own stream, as before.
+func (p *ChunkWorkerPool) SetControlStream(cs *ControlStream) {
+	p.controlStream = cs
+}
+
+// SetDatagramThreshold enables sendChunk's unreliable-datagram fast path
+// for chunks whose whole segment is bytes or smaller, bypassing the
+// per-chunk stream open entirely. Zero (the default) disables the fast
+// path; every chunk uses the normal fragmented-stream pipeline.
+func (p *ChunkWorkerPool) SetDatagramThreshold(bytes int64) {
+	p.datagramThreshold = bytes
+}
+
+// SetRepairDatagramThreshold sets a separate, usually larger, datagram
+// fast-path ceiling applied only to chunks enqueued via EnqueueRepairChunk
+// (FEC parity/repair shards). Zero (the default) leaves those chunks
+// subject to the same DatagramThreshold as everything else.
+func (p *ChunkWorkerPool) SetRepairDatagramThreshold(bytes int64) {
+	p.repairDatagramThreshold = bytes
+}
@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quantarax/backend/internal/chunkreader"
+	"github.com/quantarax/backend/internal/crypto"
+	"go.uber.org/goleak"
+)
+
+// selfSignedTLSConfig returns a minimal TLS config for a loopback QUIC pair,
+// good only for this test process.
+func selfSignedTLSConfig(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+
+	server = &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"quantarax-test"}}
+	client = &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quantarax-test"}}
+	return server, client
+}
+
+// TestChunkWorkerPool_ScaleDown_NoLeaks scales a pool from 16 workers down to
+// 1 while a chunk send is in flight, then stops it, and asserts via goleak
+// that no goroutines are left behind: exactly the leak ScaleWorkers used
+// to cause before per-chunk sends carried their own revocable Lease.
+func TestChunkWorkerPool_ScaleDown_NoLeaks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	serverTLS, clientTLS := selfSignedTLSConfig(t)
+	listener, err := ListenQUIC("127.0.0.1:0", serverTLS)
+	if err != nil {
+		t.Fatalf("ListenQUIC: %v", err)
+	}
+	defer listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	acceptCh := make(chan *QUICConnection, 1)
+	go func() {
+		conn, err := listener.Accept(ctx)
+		if err != nil {
+			return
+		}
+		acceptCh <- conn
+	}()
+
+	clientConn, err := DialQUIC(ctx, listener.listener.Addr().String(), clientTLS)
+	if err != nil {
+		t.Fatalf("DialQUIC: %v", err)
+	}
+	defer clientConn.Close()
+
+	serverConn := <-acceptCh
+	defer serverConn.Close()
+
+	reader, err := chunkreader.New("pread", sourceFileForTest(t), 1<<20, 0)
+	if err != nil {
+		t.Fatalf("chunkreader.New: %v", err)
+	}
+	defer reader.Close()
+
+	sessionKeys := &crypto.SessionKeys{}
+	pool := NewChunkWorkerPool(16, 256, clientConn.GetConnection(), sessionKeys, uuid.New(), reader, 1<<20, nil, nil)
+	pool.SetLeaseTTL(200 * time.Millisecond)
+	pool.SetStopTimeout(2 * time.Second)
+	pool.Start()
+
+	for i := int64(0); i < 16; i++ {
+		_ = pool.EnqueueChunk(i)
+	}
+
+	// Let sends get under way before yanking workers out from under them.
+	time.Sleep(50 * time.Millisecond)
+	pool.ScaleWorkers(1)
+	time.Sleep(50 * time.Millisecond)
+
+	pool.Stop()
+}
+
+// sourceFileForTest returns a path chunkreader can open and read a few
+// fragments from; the test doesn't care about its contents.
+func sourceFileForTest(t *testing.T) string {
+	t.Helper()
+	f := t.TempDir() + "/source.bin"
+	data := make([]byte, 4<<20)
+	if err := os.WriteFile(f, data, 0o600); err != nil {
+		t.Fatalf("write source file: %v", err)
+	}
+	return f
+}
@@ -2,24 +2,132 @@ package transport
 
 import (
 	"bytes"
+	"context"
 	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"sync"
+	"sync/atomic"
 
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/fxamacker/cbor/v2"
 	"github.com/quic-go/quic-go"
+	"github.com/quantarax/backend/internal/chunker"
+	"github.com/quantarax/backend/internal/crypto"
+	"github.com/quantarax/backend/internal/observability"
+	"go.opentelemetry.io/otel"
 )
 
 var (
 	ErrInvalidSignature       = errors.New("invalid manifest signature")
 	ErrInvalidProtocolVersion = errors.New("unsupported protocol version")
+
+	// ErrDatagramsNotSupported means the peer didn't negotiate QUIC DATAGRAM
+	// support (RFC 9221), so SendDatagramMessage has no unreliable path to
+	// use and the caller should fall back to a reliable stream message.
+	ErrDatagramsNotSupported = errors.New("peer does not support QUIC datagrams")
+
+	// ErrDatagramTooLarge means a message, once JSON-encoded and framed,
+	// exceeds MaxDatagramSize and must be sent over the stream path (or
+	// split) instead.
+	ErrDatagramTooLarge = errors.New("control message too large for a datagram")
 )
 
 const (
 	ProtocolVersion = 1
 	ControlStreamID = 0
+
+	// ProtocolVersionRoaring is the version a peer declares in its
+	// SignedManifest once it's willing to receive Roaring-encoded chunk
+	// bitmaps (see RangeEncodingRoaring) instead of only the legacy
+	// range-string format. ReceiveSignedManifest accepts either this or
+	// the original ProtocolVersion, so a mixed-version pair of peers still
+	// interoperates: they just negotiate down to range strings.
+	ProtocolVersionRoaring = 2
+)
+
+// ControlCodec wire format names, carried in SignedManifest.Codec.
+const (
+	// CodecJSON is the original wire format: every message field goes
+	// through encoding/json, which forces binary fields (Merkle roots,
+	// signatures, public keys) through base64. It's the default for a
+	// ControlStream that hasn't negotiated anything else, so an older
+	// peer that doesn't know about Codec still interoperates.
+	CodecJSON = "json"
+
+	// CodecCBOR encodes the same struct fields as CBOR (RFC 8949), which
+	// carries []byte fields as native binary strings instead of
+	// base64-inflated JSON strings, at roughly the same CPU cost as JSON.
+	CodecCBOR = "cbor"
+
+	// CodecProtobuf names the schema pinned in proto/control/v1/control.proto.
+	// resolveCodec currently falls back to CodecJSON for this name: this
+	// tree has no protoc/protoc-gen-go available to generate and verify
+	// bindings from the .proto file, and hand-writing wire-compatible
+	// Protobuf marshaling for eight message types with no way to build or
+	// test it is a worse bet than an honest fallback.
+	CodecProtobuf = "protobuf"
+)
+
+// ControlCodec encodes and decodes control-message payloads for the wire
+// format a ControlStream negotiates with its peer during the
+// SignedManifest handshake (see SignedManifest.Codec). It mirrors the
+// (Marshal, Unmarshal) shape of encoding/json and encoding/cbor so either
+// can be dropped in directly.
+type ControlCodec interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonControlCodec struct{}
+
+func (jsonControlCodec) Name() string                          { return CodecJSON }
+func (jsonControlCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+func (jsonControlCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type cborControlCodec struct{}
+
+func (cborControlCodec) Name() string                          { return CodecCBOR }
+func (cborControlCodec) Marshal(v interface{}) ([]byte, error) { return cbor.Marshal(v) }
+func (cborControlCodec) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
+
+// resolveCodec maps a SignedManifest.Codec name to the ControlCodec that
+// implements it, falling back to JSON for "", CodecProtobuf (not yet
+// implemented — see CodecProtobuf), and any name it doesn't recognize, so a
+// future codec name from a newer peer degrades to the always-supported
+// fallback instead of failing the handshake.
+func resolveCodec(name string) ControlCodec {
+	switch name {
+	case CodecCBOR:
+		return cborControlCodec{}
+	default:
+		return jsonControlCodec{}
+	}
+}
+
+// Range-bitmap wire encodings for AckMessage.ChunkRanges,
+// NackMessage.MissingRanges, and ChunkHaveResponse.HaveRanges. An empty
+// Encoding is treated as RangeEncodingRanges, since it's what a peer
+// predating this field (and ProtocolVersionRoaring) always sends.
+const (
+	RangeEncodingRanges  = "ranges"
+	RangeEncodingRoaring = "roaring"
+	// RangeEncodingBitmap marks ChunkHaveResponse.HaveRanges as a
+	// base64-encoded full ChunkBitmap.Serialize() rather than a range or
+	// Roaring encoding of present/missing indices. Used only as the
+	// control-stream fallback when a have-bitmap datagram's RLE form (see
+	// ChunkReceiver.sendHaveBitmapDatagram) would exceed a single
+	// datagram's path MTU or its datagram send fails.
+	RangeEncodingBitmap = "bitmap"
 )
 
 // ControlMessageType represents control message types
@@ -34,6 +142,19 @@ const (
 	MessageTypeFECUpdate
 	MessageTypeChunkHaveRequest
 	MessageTypeChunkHaveResponse
+	MessageTypeChunkProof
+	MessageTypeChunkRequest
+	MessageTypeChunkPayloadAck
+	MessageTypeManifestRequest
+	MessageTypeManifestResponse
+	MessageTypeBundleRequest
+	MessageTypeBundleResponse
+	MessageTypeSnapshotRequest
+	MessageTypeSnapshotResponse
+	MessageTypeBadEncodingProof
+	MessageTypeChunkData
+	MessageTypeAdminCommand
+	MessageTypeAdminAck
 )
 
 // SignedManifest represents a cryptographically signed file manifest
@@ -42,11 +163,33 @@ type SignedManifest struct {
 	Signature       []byte
 	PublicKey       []byte
 	ProtocolVersion int32
+
+	// Codec names the ControlCodec the sender will use to encode every
+	// control message it sends after this one (see resolveCodec). It is
+	// always carried in plain JSON regardless of the negotiated codec,
+	// since this message is what bootstraps the negotiation in the first
+	// place. "" is treated as CodecJSON, for a peer predating this field.
+	Codec string
+
+	// Algorithm is the crypto.AEADAlgorithm (as its single wire byte) the
+	// sender will use to encrypt the chunk and control payloads that
+	// follow (see crypto.ResolveAEADAlgorithm). 0 is
+	// crypto.AlgorithmAES256GCM, for a peer predating this field.
+	Algorithm byte
+
+	// TraceContext is the W3C traceparent string for the sender's
+	// transfer span at the moment SendSignedManifest was called, the
+	// same convention ChunkProofMessage.TraceContext uses. "" means the
+	// sender had no active span, or predates this field; either way
+	// ExtractTraceContext hands ReceiveSignedManifest's caller back the
+	// context it passed in.
+	TraceContext string
 }
 
 // AckMessage represents chunk acknowledgment
 type AckMessage struct {
 	ChunkRanges   string
+	Encoding      string // RangeEncodingRanges or RangeEncodingRoaring; "" means RangeEncodingRanges
 	TotalReceived int64
 	Timestamp     int64
 	SessionID     string
@@ -55,9 +198,17 @@ type AckMessage struct {
 // NackMessage represents missing chunk request
 type NackMessage struct {
 	MissingRanges string
+	Encoding      string // RangeEncodingRanges or RangeEncodingRoaring; "" means RangeEncodingRanges
 	Reason        string
 	SessionID     string
 	Timestamp     int64
+
+	// RetryAfterMs, if set, is a Retry-After-style hint (e.g. from
+	// congestion control or a "bad nonce" rekey request) for how long the
+	// sender should wait before re-enqueueing the missing chunks — see
+	// OrchestratedSender.reenqueueNacked and RetryBackoff. Zero leaves the
+	// backoff policy's own computed delay in charge.
+	RetryAfterMs int64
 }
 
 // StatusMessage represents transfer status update
@@ -88,6 +239,19 @@ type FECUpdateMessage struct {
 	Timestamp int64
 }
 
+// ChunkDataMessage carries one small chunk's full ciphertext over the
+// unreliable datagram path instead of opening a stream for it (see
+// ChunkWorkerPool.sendChunkDatagram, gated by DatagramThreshold). Ciphertext
+// is sized and nonced the same way the legacy (pre-fragmentation)
+// ChunkHeader stream format is, so a receiver decrypts it with the same
+// decryptChunk used for that format.
+type ChunkDataMessage struct {
+	SessionID  string
+	ChunkIndex int64
+	Ciphertext []byte
+	Timestamp  int64
+}
+
 // ChunkHaveRequest asks the receiver to provide a bitmap of chunks present in CAS.
 type ChunkHaveRequest struct {
 	SessionID  string
@@ -98,24 +262,193 @@ type ChunkHaveRequest struct {
 type ChunkHaveResponse struct {
 	SessionID  string
 	HaveRanges string
+	Encoding   string // RangeEncodingRanges or RangeEncodingRoaring; "" means RangeEncodingRanges
 	ChunkCount int
 	Timestamp  int64
 }
 
+// ChunkProofMessage carries the sibling-hash Merkle authentication path for
+// one chunk, signed by the sender's identity key, so the receiver can check
+// a chunk against the signed manifest root before the transfer completes
+// rather than only at the final verification pass.
+type ChunkProofMessage struct {
+	SessionID  string
+	ChunkIndex int64
+	ChunkHash  string // base64 BLAKE3 leaf hash, as recorded in the manifest
+	ProofPath  [][]byte
+	Timestamp  int64
+	Signature  []byte
+	PublicKey  []byte
+
+	// TraceContext is the W3C traceparent string (always ≤55 bytes) for the
+	// sender's "chunk.send" span covering this chunk, if tracing is active.
+	// The receiver extracts it to continue the same trace into its own
+	// verification span instead of starting an unlinked one.
+	TraceContext string
+}
+
+// BadEncodingProofMessage carries a chunker.BadEncodingProof to the sender
+// (and, via the observability pipeline, to operators) when a receiver's FEC
+// reconstruction disagrees with the signed manifest. Unlike ChunkProofMessage
+// it needs no signature: Proof is self-verifying offline via
+// chunker.VerifyBadEncoding against the manifest root both sides already
+// hold.
+type BadEncodingProofMessage struct {
+	SessionID  string
+	ChunkIndex int64
+	Proof      *chunker.BadEncodingProof
+	Timestamp  int64
+}
+
+// ChunkRequest asks the sender to (re-)send a batch of chunk indices, used by
+// a receiver pulling missing chunks instead of waiting for a blind push.
+type ChunkRequest struct {
+	SessionID string
+	Indices   []int64
+	Timestamp int64
+}
+
+// ChunkPayloadAck confirms (or denies) that a pull-requested chunk index was
+// accepted for sending, so the requester can distinguish "queued" from
+// "sender has no such session/chunk" without waiting for the data stream.
+type ChunkPayloadAck struct {
+	SessionID string
+	Index     int64
+	OK        bool
+	Timestamp int64
+}
+
+// ManifestRequest fetches the full manifest a compact signed transfer token
+// only references by FileKey, rather than embedding it in the token itself.
+type ManifestRequest struct {
+	FileKey string
+}
+
+// ManifestResponse carries the manifest JSON for a previously requested
+// FileKey. Found is false when the sender has no manifest registered under
+// that key (e.g. expired or unknown).
+type ManifestResponse struct {
+	FileKey      string
+	ManifestJSON []byte
+	Found        bool
+}
+
+// BundleRequest fetches the full bundle descriptor a bundle transfer token
+// only references by BundleKey, analogous to ManifestRequest for a single file.
+type BundleRequest struct {
+	BundleKey string
+}
+
+// BundleResponse carries the bundle JSON for a previously requested
+// BundleKey. Found is false when the sender has no bundle registered under
+// that key (e.g. expired or unknown).
+type BundleResponse struct {
+	BundleKey  string
+	BundleJSON []byte
+	Found      bool
+}
+
+// SnapshotRequest asks the sender for a compact, signed snapshot of
+// SessionID's transfer state, so a receiver resuming a paused transfer can
+// diff against its local bitmap instead of re-scanning the whole transfer.
+type SnapshotRequest struct {
+	SessionID string
+}
+
+// SnapshotProofEntry is one leaf of the fixed-size authentication path set
+// a SnapshotResponse carries, letting the receiver check a handful of chunk
+// hashes against the pinned manifest root without trusting the sender's
+// bitmap outright.
+type SnapshotProofEntry struct {
+	ChunkIndex int64
+	LeafHash   []byte
+	Path       [][]byte
+}
+
+// SnapshotResponse carries a point-in-time, sender-signed snapshot of a
+// session's progress: a range-compressed bitmap of chunks committed on the
+// sender side, plus Merkle proofs for a fixed sample of chunk indices so the
+// receiver can authenticate it against the manifest root it originally
+// accepted for the session, rather than trusting the sender's bitmap claim.
+type SnapshotResponse struct {
+	SessionID       string
+	ManifestRoot    []byte
+	ChunkSize       int64
+	TotalChunks     int64
+	CommittedRanges string
+	Proof           []SnapshotProofEntry
+	Timestamp       int64
+	Signature       []byte
+	PublicKey       []byte
+}
+
 // ControlStream manages the control protocol stream
 type ControlStream struct {
 	stream *quic.Stream
+
+	// conn is used for the parallel unreliable datagram path
+	// (SendDatagramMessage/ReceiveDatagrams); nil for a ControlStream
+	// built without one, in which case datagram methods report
+	// ErrDatagramsNotSupported the same as a peer that never negotiated
+	// RFC 9221 support.
+	conn *quic.Conn
+
+	// datagramSeq is a monotonic counter stamped on every datagram this
+	// side sends, so the receiving side can recognize and drop an exact
+	// duplicate of one it's already delivered.
+	datagramSeq uint64
+
+	dedupMu    sync.Mutex
+	dedupSeen  map[uint64]struct{}
+	dedupOrder []uint64
+
+	// peerProtocolVersion is the ProtocolVersion the peer declared in its
+	// SignedManifest, set by ReceiveSignedManifest and read by
+	// PeerSupportsRoaring. Zero until a manifest has been received.
+	peerProtocolVersion int32
+
+	// codec encodes/decodes every control message sendControlMessage and
+	// receiveControlMessage's Receive* callers exchange after the initial
+	// SignedManifest handshake (which always stays plain JSON to bootstrap
+	// the negotiation). Defaults to jsonControlCodec{} and is overwritten
+	// by ReceiveSignedManifest to match the peer's declared Codec, or by
+	// SetPreferredCodec to declare this side's own preference before sending one.
+	codec ControlCodec
+
+	// preferredAEAD is the crypto.AEADAlgorithm this side advertises in
+	// the next SendSignedManifest call, set via SetPreferredAEAD.
+	// Defaults to crypto.AlgorithmAES256GCM.
+	preferredAEAD crypto.AEADAlgorithm
+
+	// metrics, set via SetMetrics, records DatagramsSentTotal/
+	// DatagramsReceivedTotal/DatagramsDroppedTotal for every datagram this
+	// stream sends or receives. nil (the default) disables that
+	// accounting without affecting the datagram path itself.
+	metrics *observability.Metrics
+
+	// peerAEAD is the crypto.AEADAlgorithm the peer declared in its most
+	// recently received SignedManifest, set by ReceiveSignedManifest and
+	// read by PeerAEAD. crypto.AlgorithmAES256GCM until a manifest has
+	// been received.
+	peerAEAD crypto.AEADAlgorithm
 }
 
-// NewControlStream creates a new control stream wrapper
-func NewControlStream(stream *quic.Stream) *ControlStream {
+// NewControlStream creates a new control stream wrapper. conn enables the
+// unreliable datagram path (SendDatagramMessage/ReceiveDatagrams) alongside
+// the reliable stream path stream already provides; pass nil to disable it.
+func NewControlStream(stream *quic.Stream, conn *quic.Conn) *ControlStream {
 	return &ControlStream{
-		stream: stream,
+		stream:    stream,
+		conn:      conn,
+		dedupSeen: make(map[uint64]struct{}),
+		codec:     jsonControlCodec{},
 	}
 }
 
-// SendSignedManifest sends a signed manifest over the control stream
-func (cs *ControlStream) SendSignedManifest(manifestJSON []byte, privateKey ed25519.PrivateKey) error {
+// SendSignedManifest sends a signed manifest over the control stream. ctx's
+// current span, if any, is propagated to the peer as TraceContext so its
+// ReceiveSignedManifest can continue the same trace.
+func (cs *ControlStream) SendSignedManifest(ctx context.Context, manifestJSON []byte, privateKey ed25519.PrivateKey) error {
 	signature := ed25519.Sign(privateKey, manifestJSON)
 	publicKey := privateKey.Public().(ed25519.PublicKey)
 
@@ -123,10 +456,19 @@ func (cs *ControlStream) SendSignedManifest(manifestJSON []byte, privateKey ed25
 		ManifestJSON:    manifestJSON,
 		Signature:       signature,
 		PublicKey:       publicKey,
-		ProtocolVersion: ProtocolVersion,
+		ProtocolVersion: ProtocolVersionRoaring,
+		Codec:           cs.codec.Name(),
+		Algorithm:       byte(cs.preferredAEAD),
+		TraceContext:    InjectTraceContext(ctx),
 	}
 
-	return cs.sendControlMessage(MessageTypeManifest, sm)
+	// The manifest itself is always plain JSON: it's what bootstraps the
+	// codec negotiation, so it can't assume the negotiated codec yet.
+	data, err := json.Marshal(sm)
+	if err != nil {
+		return err
+	}
+	return cs.sendRaw(MessageTypeManifest, data)
 }
 
 // ReceiveSignedManifest receives and verifies a signed manifest
@@ -145,7 +487,7 @@ func (cs *ControlStream) ReceiveSignedManifest() (*SignedManifest, error) {
 		return nil, err
 	}
 
-	if sm.ProtocolVersion != ProtocolVersion {
+	if sm.ProtocolVersion != ProtocolVersion && sm.ProtocolVersion != ProtocolVersionRoaring {
 		return nil, ErrInvalidProtocolVersion
 	}
 
@@ -153,12 +495,44 @@ func (cs *ControlStream) ReceiveSignedManifest() (*SignedManifest, error) {
 		return nil, ErrInvalidSignature
 	}
 
+	atomic.StoreInt32(&cs.peerProtocolVersion, sm.ProtocolVersion)
+	cs.codec = resolveCodec(sm.Codec)
+	cs.peerAEAD = crypto.ResolveAEADAlgorithm(sm.Algorithm)
+
 	return &sm, nil
 }
 
-// SendAck sends an acknowledgment message
+// PeerSupportsRoaring reports whether the peer's most recently received
+// SignedManifest declared ProtocolVersionRoaring or later, so a caller
+// building an outgoing AckMessage/NackMessage/ChunkHaveResponse knows
+// whether it's safe to use RangeEncodingRoaring instead of falling back to
+// the legacy RangeEncodingRanges. Returns false until a manifest has been
+// received.
+func (cs *ControlStream) PeerSupportsRoaring() bool {
+	return atomic.LoadInt32(&cs.peerProtocolVersion) >= ProtocolVersionRoaring
+}
+
+// sendMessageWithDatagramFastPath sends payload as an unreliable datagram
+// when the peer negotiated datagram support and payload fits within
+// MaxDatagramSize, falling back to the reliable stream otherwise (a
+// too-large payload or an unsupported/failed datagram send is not itself an
+// error to the caller — it just means the slower, reliable path runs
+// instead). Every message type here is safe to lose occasionally: the next
+// Ack/Nack/FECUpdate supersedes whatever came before it.
+func (cs *ControlStream) sendMessageWithDatagramFastPath(msgType ControlMessageType, payload interface{}) error {
+	if cs.SupportsDatagrams() {
+		if err := cs.SendDatagramMessage(msgType, payload); err == nil {
+			return nil
+		}
+	}
+	return cs.sendControlMessage(msgType, payload)
+}
+
+// SendAck sends an acknowledgment message, over the unreliable datagram
+// path when the peer supports it (an occasional dropped Ack is harmless;
+// the next one supersedes it) and over the reliable stream otherwise.
 func (cs *ControlStream) SendAck(ack *AckMessage) error {
-	return cs.sendControlMessage(MessageTypeAck, ack)
+	return cs.sendMessageWithDatagramFastPath(MessageTypeAck, ack)
 }
 
 // ReceiveAck receives an acknowledgment message
@@ -173,16 +547,19 @@ func (cs *ControlStream) ReceiveAck() (*AckMessage, error) {
 	}
 
 	var ack AckMessage
-	if err := json.Unmarshal(data, &ack); err != nil {
+	if err := cs.codec.Unmarshal(data, &ack); err != nil {
 		return nil, err
 	}
 
 	return &ack, nil
 }
 
-// SendNack sends a negative acknowledgment message
+// SendNack sends a negative acknowledgment message, over the unreliable
+// datagram path when the peer supports it and over the reliable stream
+// otherwise. A dropped Nack is harmless: RequestMissing asks for the chunk
+// again with a ChunkRequest as long as it is still missing.
 func (cs *ControlStream) SendNack(nack *NackMessage) error {
-	return cs.sendControlMessage(MessageTypeNack, nack)
+	return cs.sendMessageWithDatagramFastPath(MessageTypeNack, nack)
 }
 
 // ReceiveNack receives a negative acknowledgment message
@@ -197,14 +574,18 @@ func (cs *ControlStream) ReceiveNack() (*NackMessage, error) {
 	}
 
 	var nack NackMessage
-	if err := json.Unmarshal(data, &nack); err != nil {
+	if err := cs.codec.Unmarshal(data, &nack); err != nil {
 		return nil, err
 	}
 
 	return &nack, nil
 }
 
-// SendStatus sends a status update message
+// SendStatus sends a status update message over the reliable stream. A
+// caller polling status as a heartbeat rather than a one-shot update can
+// use SendDatagramMessage(MessageTypeStatus, status) instead when
+// SupportsDatagrams() is true, since losing one heartbeat doesn't matter as
+// long as the next one arrives.
 func (cs *ControlStream) SendStatus(status *StatusMessage) error {
 	return cs.sendControlMessage(MessageTypeStatus, status)
 }
@@ -221,7 +602,7 @@ func (cs *ControlStream) ReceiveStatus() (*StatusMessage, error) {
 	}
 
 	var status StatusMessage
-	if err := json.Unmarshal(data, &status); err != nil {
+	if err := cs.codec.Unmarshal(data, &status); err != nil {
 		return nil, err
 	}
 
@@ -233,9 +614,12 @@ func (cs *ControlStream) SendVerification(verification *VerificationMessage) err
 	return cs.sendControlMessage(MessageTypeVerification, verification)
 }
 
-// SendFECUpdate sends FEC update
+// SendFECUpdate sends an FEC parameter update, over the unreliable datagram
+// path when the peer supports it and over the reliable stream otherwise. A
+// stale FEC parameter update is harmless since the next Tick's update
+// supersedes it.
 func (cs *ControlStream) SendFECUpdate(msg *FECUpdateMessage) error {
-	return cs.sendControlMessage(MessageTypeFECUpdate, msg)
+	return cs.sendMessageWithDatagramFastPath(MessageTypeFECUpdate, msg)
 }
 
 // ReceiveFECUpdate receives FEC update
@@ -248,7 +632,7 @@ func (cs *ControlStream) ReceiveFECUpdate() (*FECUpdateMessage, error) {
 		return nil, fmt.Errorf("expected FEC_UPDATE, got %d", msgType)
 	}
 	var m FECUpdateMessage
-	if err := json.Unmarshal(data, &m); err != nil {
+	if err := cs.codec.Unmarshal(data, &m); err != nil {
 		return nil, err
 	}
 	return &m, nil
@@ -269,7 +653,7 @@ func (cs *ControlStream) ReceiveChunkHaveRequest() (*ChunkHaveRequest, error) {
 		return nil, fmt.Errorf("expected CHUNK_HAVE_REQUEST, got %d", msgType)
 	}
 	var req ChunkHaveRequest
-	if err := json.Unmarshal(data, &req); err != nil {
+	if err := cs.codec.Unmarshal(data, &req); err != nil {
 		return nil, err
 	}
 	return &req, nil
@@ -290,12 +674,269 @@ func (cs *ControlStream) ReceiveChunkHaveResponse() (*ChunkHaveResponse, error)
 		return nil, fmt.Errorf("expected CHUNK_HAVE_RESPONSE, got %d", msgType)
 	}
 	var resp ChunkHaveResponse
-	if err := json.Unmarshal(data, &resp); err != nil {
+	if err := cs.codec.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendChunkRequest asks the sender to (re-)send a batch of chunk indices.
+func (cs *ControlStream) SendChunkRequest(req *ChunkRequest) error {
+	return cs.sendControlMessage(MessageTypeChunkRequest, req)
+}
+
+// ReceiveChunkRequest receives a pull request for a batch of chunk indices.
+func (cs *ControlStream) ReceiveChunkRequest() (*ChunkRequest, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeChunkRequest {
+		return nil, fmt.Errorf("expected CHUNK_REQUEST, got %d", msgType)
+	}
+	var req ChunkRequest
+	if err := cs.codec.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// SendChunkPayloadAck acknowledges (or rejects) a single pull-requested chunk index.
+func (cs *ControlStream) SendChunkPayloadAck(ack *ChunkPayloadAck) error {
+	return cs.sendControlMessage(MessageTypeChunkPayloadAck, ack)
+}
+
+// ReceiveChunkPayloadAck receives a pull-request acknowledgment.
+func (cs *ControlStream) ReceiveChunkPayloadAck() (*ChunkPayloadAck, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeChunkPayloadAck {
+		return nil, fmt.Errorf("expected CHUNK_PAYLOAD_ACK, got %d", msgType)
+	}
+	var ack ChunkPayloadAck
+	if err := cs.codec.Unmarshal(data, &ack); err != nil {
+		return nil, err
+	}
+	return &ack, nil
+}
+
+// SendManifestRequest asks the peer to resolve a FileKey to a full manifest.
+func (cs *ControlStream) SendManifestRequest(req *ManifestRequest) error {
+	return cs.sendControlMessage(MessageTypeManifestRequest, req)
+}
+
+// ReceiveManifestRequest receives a FileKey-to-manifest lookup request.
+func (cs *ControlStream) ReceiveManifestRequest() (*ManifestRequest, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeManifestRequest {
+		return nil, fmt.Errorf("expected MANIFEST_REQUEST, got %d", msgType)
+	}
+	var req ManifestRequest
+	if err := cs.codec.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// SendManifestResponse sends back the manifest resolved for a FileKey.
+func (cs *ControlStream) SendManifestResponse(resp *ManifestResponse) error {
+	return cs.sendControlMessage(MessageTypeManifestResponse, resp)
+}
+
+// ReceiveManifestResponse receives a resolved manifest.
+func (cs *ControlStream) ReceiveManifestResponse() (*ManifestResponse, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeManifestResponse {
+		return nil, fmt.Errorf("expected MANIFEST_RESPONSE, got %d", msgType)
+	}
+	var resp ManifestResponse
+	if err := cs.codec.Unmarshal(data, &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
 }
 
+// SendBundleRequest asks the peer to resolve a BundleKey to a full bundle descriptor.
+func (cs *ControlStream) SendBundleRequest(req *BundleRequest) error {
+	return cs.sendControlMessage(MessageTypeBundleRequest, req)
+}
+
+// ReceiveBundleRequest receives a BundleKey-to-bundle lookup request.
+func (cs *ControlStream) ReceiveBundleRequest() (*BundleRequest, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeBundleRequest {
+		return nil, fmt.Errorf("expected BUNDLE_REQUEST, got %d", msgType)
+	}
+	var req BundleRequest
+	if err := cs.codec.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// SendBundleResponse sends back the bundle descriptor resolved for a BundleKey.
+func (cs *ControlStream) SendBundleResponse(resp *BundleResponse) error {
+	return cs.sendControlMessage(MessageTypeBundleResponse, resp)
+}
+
+// ReceiveBundleResponse receives a resolved bundle descriptor.
+func (cs *ControlStream) ReceiveBundleResponse() (*BundleResponse, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeBundleResponse {
+		return nil, fmt.Errorf("expected BUNDLE_RESPONSE, got %d", msgType)
+	}
+	var resp BundleResponse
+	if err := cs.codec.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendSnapshotRequest asks the peer for a resumable-transfer snapshot.
+func (cs *ControlStream) SendSnapshotRequest(req *SnapshotRequest) error {
+	return cs.sendControlMessage(MessageTypeSnapshotRequest, req)
+}
+
+// ReceiveSnapshotRequest receives a snapshot request.
+func (cs *ControlStream) ReceiveSnapshotRequest() (*SnapshotRequest, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeSnapshotRequest {
+		return nil, fmt.Errorf("expected SNAPSHOT_REQUEST, got %d", msgType)
+	}
+	var req SnapshotRequest
+	if err := cs.codec.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// SendSnapshotResponse sends a signed resumable-transfer snapshot.
+func (cs *ControlStream) SendSnapshotResponse(resp *SnapshotResponse) error {
+	return cs.sendControlMessage(MessageTypeSnapshotResponse, resp)
+}
+
+// ReceiveSnapshotResponse receives a signed resumable-transfer snapshot.
+func (cs *ControlStream) ReceiveSnapshotResponse() (*SnapshotResponse, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeSnapshotResponse {
+		return nil, fmt.Errorf("expected SNAPSHOT_RESPONSE, got %d", msgType)
+	}
+	var resp SnapshotResponse
+	if err := cs.codec.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// chunkProofCanonicalBytes returns the bytes signed/verified for a
+// ChunkProofMessage: everything except the signature and public key
+// themselves.
+func chunkProofCanonicalBytes(proof *ChunkProofMessage) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(proof.SessionID)
+	_ = binary.Write(&buf, binary.BigEndian, proof.ChunkIndex)
+	buf.WriteString(proof.ChunkHash)
+	for _, sibling := range proof.ProofPath {
+		buf.Write(sibling)
+	}
+	_ = binary.Write(&buf, binary.BigEndian, proof.Timestamp)
+	return buf.Bytes()
+}
+
+// SignChunkProof signs proof in place with privateKey, filling in Signature
+// and PublicKey.
+func SignChunkProof(proof *ChunkProofMessage, privateKey ed25519.PrivateKey) {
+	proof.Signature = ed25519.Sign(privateKey, chunkProofCanonicalBytes(proof))
+	proof.PublicKey = privateKey.Public().(ed25519.PublicKey)
+}
+
+// VerifyChunkProofSignature reports whether proof's signature is valid for
+// its own embedded public key. Callers must separately confirm that public
+// key is the sender's authenticated identity key.
+func VerifyChunkProofSignature(proof *ChunkProofMessage) bool {
+	if len(proof.PublicKey) != ed25519.PublicKeySize || len(proof.Signature) == 0 {
+		return false
+	}
+	return ed25519.Verify(proof.PublicKey, chunkProofCanonicalBytes(proof), proof.Signature)
+}
+
+// SendChunkProof sends a signed Merkle proof for a single chunk.
+func (cs *ControlStream) SendChunkProof(proof *ChunkProofMessage) error {
+	return cs.sendControlMessage(MessageTypeChunkProof, proof)
+}
+
+// SendChunkData sends one whole chunk's ciphertext as an unreliable
+// datagram instead of opening a stream for it. Unlike SendAck/SendNack/
+// SendFECUpdate there is no reliable-stream fallback here: a chunk lost as
+// a datagram is recovered by the receiver's normal missing-chunk NACK path,
+// the same as a chunk lost any other way, so ChunkWorkerPool.sendChunk
+// falls back to its ordinary stream-based send on error instead of this
+// method retrying over the stream itself. Returns ErrDatagramsNotSupported
+// if the peer hasn't negotiated datagram support, or ErrDatagramTooLarge if
+// msg doesn't fit within MaxDatagramSize.
+func (cs *ControlStream) SendChunkData(msg *ChunkDataMessage) error {
+	return cs.SendDatagramMessage(MessageTypeChunkData, msg)
+}
+
+// ReceiveChunkProof receives a signed Merkle proof for a single chunk.
+func (cs *ControlStream) ReceiveChunkProof() (*ChunkProofMessage, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeChunkProof {
+		return nil, fmt.Errorf("expected CHUNK_PROOF, got %d", msgType)
+	}
+	var proof ChunkProofMessage
+	if err := cs.codec.Unmarshal(data, &proof); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// SendBadEncodingProof sends a chunker.BadEncodingProof to the peer, so a
+// sender (or anyone relaying the control stream) learns exactly which chunk
+// its FEC encoding was caught disagreeing with.
+func (cs *ControlStream) SendBadEncodingProof(msg *BadEncodingProofMessage) error {
+	return cs.sendControlMessage(MessageTypeBadEncodingProof, msg)
+}
+
+// ReceiveBadEncodingProof receives a BadEncodingProofMessage.
+func (cs *ControlStream) ReceiveBadEncodingProof() (*BadEncodingProofMessage, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeBadEncodingProof {
+		return nil, fmt.Errorf("expected BAD_ENCODING_PROOF, got %d", msgType)
+	}
+	var msg BadEncodingProofMessage
+	if err := cs.codec.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
 // ReceiveVerification receives a verification result message
 func (cs *ControlStream) ReceiveVerification() (*VerificationMessage, error) {
 	msgType, data, err := cs.receiveControlMessage()
@@ -308,20 +949,179 @@ func (cs *ControlStream) ReceiveVerification() (*VerificationMessage, error) {
 	}
 
 	var verification VerificationMessage
-	if err := json.Unmarshal(data, &verification); err != nil {
+	if err := cs.codec.Unmarshal(data, &verification); err != nil {
 		return nil, err
 	}
 
 	return &verification, nil
 }
 
-// sendControlMessage sends a control message with type and payload
+// AdminAction names the operation an AdminCommand requests. Unlike
+// ChunkProofMessage and friends, which flow between transfer peers,
+// AdminCommand is sent by an operator tool over the same control stream to
+// the daemon that opened it.
+type AdminAction string
+
+const (
+	// AdminActionRestart requests a graceful restart of the receiving
+	// daemon (see service.AdminHandler.Handle).
+	AdminActionRestart AdminAction = "restart"
+	// AdminActionPause halts AdminCommand.SessionIDs' transfers in place
+	// (ChunkWorkerPool.Pause); an empty SessionIDs pauses every active
+	// transfer.
+	AdminActionPause AdminAction = "pause"
+	// AdminActionResume reverses a prior AdminActionPause for the same
+	// SessionIDs selection.
+	AdminActionResume AdminAction = "resume"
+	// AdminActionSetConfig applies AdminCommand.Config's non-nil fields to
+	// the daemon's running config.Config.
+	AdminActionSetConfig AdminAction = "set_config"
+	// AdminActionRotateKeys replaces the daemon's identity keypair
+	// (internal/crypto/identity) with a freshly generated one.
+	AdminActionRotateKeys AdminAction = "rotate_keys"
+)
+
+// AdminConfigPatch carries optional overrides for AdminActionSetConfig. A
+// nil field leaves that setting unchanged, the same partial-update shape
+// daemonAdminController.Reload uses for the address fields it preserves
+// across a config reload.
+type AdminConfigPatch struct {
+	ChunkSize   *int64
+	WorkerCount *int
+	FECMinR     *int
+	FECMaxR     *int
+}
+
+// AdminCommand is a signed administrative instruction sent over the control
+// stream by an operator tool rather than a transfer peer: restart this
+// daemon, pause or resume specific (or all) transfer sessions, patch
+// runtime config, or rotate this daemon's identity key. It's verified the
+// same two-step way as ChunkProofMessage: VerifyAdminCommandSignature only
+// checks the signature against the command's own embedded PublicKey, so
+// the caller must separately confirm that PublicKey is one of the
+// daemon's configured admin keys (config.Config.AdminPubKeys) before
+// applying it.
+type AdminCommand struct {
+	Action     AdminAction
+	SessionIDs []string
+	Config     *AdminConfigPatch
+	Timestamp  int64
+	Signature  []byte
+	PublicKey  []byte
+}
+
+// AdminAck acknowledges an AdminCommand: OK is false and Error is set if
+// the command was rejected (unauthorized, or failed while applying) rather
+// than actually carried out.
+type AdminAck struct {
+	Action    AdminAction
+	OK        bool
+	Error     string
+	Timestamp int64
+}
+
+// adminCommandCanonicalBytes returns the bytes signed/verified for an
+// AdminCommand: everything except the signature and public key themselves.
+func adminCommandCanonicalBytes(cmd *AdminCommand) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(string(cmd.Action))
+	for _, id := range cmd.SessionIDs {
+		buf.WriteString(id)
+	}
+	if cmd.Config != nil {
+		if cmd.Config.ChunkSize != nil {
+			_ = binary.Write(&buf, binary.BigEndian, *cmd.Config.ChunkSize)
+		}
+		if cmd.Config.WorkerCount != nil {
+			_ = binary.Write(&buf, binary.BigEndian, int64(*cmd.Config.WorkerCount))
+		}
+		if cmd.Config.FECMinR != nil {
+			_ = binary.Write(&buf, binary.BigEndian, int64(*cmd.Config.FECMinR))
+		}
+		if cmd.Config.FECMaxR != nil {
+			_ = binary.Write(&buf, binary.BigEndian, int64(*cmd.Config.FECMaxR))
+		}
+	}
+	_ = binary.Write(&buf, binary.BigEndian, cmd.Timestamp)
+	return buf.Bytes()
+}
+
+// SignAdminCommand signs cmd in place with privateKey, filling in Signature
+// and PublicKey.
+func SignAdminCommand(cmd *AdminCommand, privateKey ed25519.PrivateKey) {
+	cmd.Signature = ed25519.Sign(privateKey, adminCommandCanonicalBytes(cmd))
+	cmd.PublicKey = privateKey.Public().(ed25519.PublicKey)
+}
+
+// VerifyAdminCommandSignature reports whether cmd's signature is valid for
+// its own embedded public key. Callers must separately confirm that public
+// key is in the daemon's admin allowlist.
+func VerifyAdminCommandSignature(cmd *AdminCommand) bool {
+	if len(cmd.PublicKey) != ed25519.PublicKeySize || len(cmd.Signature) == 0 {
+		return false
+	}
+	return ed25519.Verify(cmd.PublicKey, adminCommandCanonicalBytes(cmd), cmd.Signature)
+}
+
+// SendAdminCommand sends a signed administrative instruction.
+func (cs *ControlStream) SendAdminCommand(cmd *AdminCommand) error {
+	return cs.sendControlMessage(MessageTypeAdminCommand, cmd)
+}
+
+// ReceiveAdminCommand receives a signed administrative instruction. It does
+// not itself verify the signature or check the admin allowlist — see
+// VerifyAdminCommandSignature and service.AdminHandler.Handle.
+func (cs *ControlStream) ReceiveAdminCommand() (*AdminCommand, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeAdminCommand {
+		return nil, fmt.Errorf("expected ADMIN_COMMAND, got %d", msgType)
+	}
+	var cmd AdminCommand
+	if err := cs.codec.Unmarshal(data, &cmd); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// SendAdminAck acknowledges an AdminCommand.
+func (cs *ControlStream) SendAdminAck(ack *AdminAck) error {
+	return cs.sendControlMessage(MessageTypeAdminAck, ack)
+}
+
+// ReceiveAdminAck receives an AdminCommand acknowledgement.
+func (cs *ControlStream) ReceiveAdminAck() (*AdminAck, error) {
+	msgType, data, err := cs.receiveControlMessage()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MessageTypeAdminAck {
+		return nil, fmt.Errorf("expected ADMIN_ACK, got %d", msgType)
+	}
+	var ack AdminAck
+	if err := cs.codec.Unmarshal(data, &ack); err != nil {
+		return nil, err
+	}
+	return &ack, nil
+}
+
+// sendControlMessage encodes payload with the negotiated codec and frames
+// it with type and payload, the same length-prefixed framing regardless of
+// which codec produced the bytes.
 func (cs *ControlStream) sendControlMessage(msgType ControlMessageType, payload interface{}) error {
-	data, err := json.Marshal(payload)
+	data, err := cs.codec.Marshal(payload)
 	if err != nil {
 		return err
 	}
+	return cs.sendRaw(msgType, data)
+}
 
+// sendRaw frames already-encoded data with type + length, bypassing the
+// negotiated codec. Only SendSignedManifest uses this directly, since that
+// message must always be plain JSON to bootstrap the codec negotiation.
+func (cs *ControlStream) sendRaw(msgType ControlMessageType, data []byte) error {
 	if err := binary.Write(cs.stream, binary.BigEndian, msgType); err != nil {
 		return err
 	}
@@ -331,7 +1131,7 @@ func (cs *ControlStream) sendControlMessage(msgType ControlMessageType, payload
 		return err
 	}
 
-	_, err = cs.stream.Write(data)
+	_, err := cs.stream.Write(data)
 	return err
 }
 
@@ -365,6 +1165,176 @@ func (cs *ControlStream) Close() error {
 	return cs.stream.Close()
 }
 
+// datagramHeaderSize is the framing overhead SendDatagramMessage adds ahead
+// of a message's JSON encoding: msgType (1 byte) + payload length (4 bytes)
+// + monotonic sequence number (8 bytes). Datagrams carry their own framing,
+// unlike sendControlMessage's length-prefixed stream framing, because
+// there's no ordered byte stream underneath to delimit messages on.
+const datagramHeaderSize = 1 + 4 + 8
+
+// maxDatagramPayloadSize is a ceiling on the total encoded datagram size
+// (header + JSON payload). quic-go negotiates the peer's actual
+// max_datagram_frame_size internally but doesn't expose the negotiated
+// value, so this is a bet on the path rather than the 1200-byte minimum
+// QUIC guarantees without PMTU probing: 1350 comfortably fits a standard
+// 1500-byte Ethernet MTU's worth of QUIC/UDP/IP overhead, which is enough
+// headroom for a FEC repair shard (see ChunkWorkerPool.EnqueueRepairChunk)
+// alongside the small control messages this constant has always bounded.
+// Every caller already treats a too-big-for-the-path send as a dropped
+// datagram with an existing fallback (a stream resend, or simply the next
+// periodic sample), so raising this is an availability/reliability
+// tradeoff, not a correctness one.
+const maxDatagramPayloadSize = 1350
+
+// ControlDatagram is one decoded, de-duplicated datagram delivered by
+// ReceiveDatagrams.
+type ControlDatagram struct {
+	Type    ControlMessageType
+	Payload []byte
+}
+
+// encodeDatagramFrame lays out one datagram as msgType || length || seq ||
+// payload, all big-endian, so decodeDatagramFrame can validate the payload
+// length matches what actually arrived before handing it to json.Unmarshal.
+func encodeDatagramFrame(msgType ControlMessageType, seq uint64, payload []byte) []byte {
+	buf := make([]byte, datagramHeaderSize+len(payload))
+	buf[0] = byte(msgType)
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(payload)))
+	binary.BigEndian.PutUint64(buf[5:13], seq)
+	copy(buf[datagramHeaderSize:], payload)
+	return buf
+}
+
+func decodeDatagramFrame(b []byte) (msgType ControlMessageType, seq uint64, payload []byte, err error) {
+	if len(b) < datagramHeaderSize {
+		return 0, 0, nil, fmt.Errorf("datagram shorter than its %d-byte header", datagramHeaderSize)
+	}
+	msgType = ControlMessageType(b[0])
+	length := binary.BigEndian.Uint32(b[1:5])
+	seq = binary.BigEndian.Uint64(b[5:13])
+	payload = b[datagramHeaderSize:]
+	if int(length) != len(payload) {
+		return 0, 0, nil, fmt.Errorf("datagram length mismatch: header says %d, got %d", length, len(payload))
+	}
+	return msgType, seq, payload, nil
+}
+
+// MaxDatagramSize returns the largest JSON-encoded payload
+// SendDatagramMessage can carry, so a sender can split an oversize message
+// across multiple control messages or fall back to the stream path instead
+// of discovering the limit from a failed send.
+func (cs *ControlStream) MaxDatagramSize() int {
+	return maxDatagramPayloadSize - datagramHeaderSize
+}
+
+// SupportsDatagrams reports whether this connection negotiated QUIC
+// DATAGRAM (RFC 9221) support with its peer.
+func (cs *ControlStream) SupportsDatagrams() bool {
+	return cs.conn != nil && cs.conn.ConnectionState().SupportsDatagrams
+}
+
+// SendDatagramMessage sends payload as an unreliable, unordered QUIC
+// datagram tagged with msgType and a monotonic sequence number, for
+// latency-critical messages (AckMessage, NackMessage, FECUpdateMessage and
+// ChunkDataMessage are the callers in this package) where losing one
+// occasionally is fine as long as the next one gets through, and waiting
+// for the reliable stream's in-order delivery would add latency no
+// retransmission could recover anyway. It does not fall back to the stream
+// path itself — callers that want that should check SupportsDatagrams and
+// MaxDatagramSize and send via sendControlMessage when either check fails.
+func (cs *ControlStream) SendDatagramMessage(msgType ControlMessageType, payload interface{}) error {
+	if !cs.SupportsDatagrams() {
+		return ErrDatagramsNotSupported
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if len(data) > cs.MaxDatagramSize() {
+		if cs.metrics != nil {
+			cs.metrics.RecordDatagramDropped("too_large")
+		}
+		return fmt.Errorf("%w: %d bytes exceeds max %d", ErrDatagramTooLarge, len(data), cs.MaxDatagramSize())
+	}
+
+	seq := atomic.AddUint64(&cs.datagramSeq, 1)
+	if err := cs.conn.SendDatagram(encodeDatagramFrame(msgType, seq, data)); err != nil {
+		if cs.metrics != nil {
+			cs.metrics.RecordDatagramDropped("send_failed")
+		}
+		return err
+	}
+	if cs.metrics != nil {
+		cs.metrics.RecordDatagramSent()
+	}
+	return nil
+}
+
+// ReceiveDatagrams starts a goroutine that reads datagrams off the
+// connection until ctx is done or the connection errors, decoding each
+// frame and delivering (type, payload) pairs on the returned channel after
+// dropping malformed frames and exact-duplicate sequence numbers. The
+// channel is closed when the goroutine exits, so callers can range over it.
+func (cs *ControlStream) ReceiveDatagrams(ctx context.Context) <-chan ControlDatagram {
+	out := make(chan ControlDatagram)
+	go func() {
+		defer close(out)
+		for {
+			raw, err := cs.conn.ReceiveDatagram(ctx)
+			if err != nil {
+				return
+			}
+			msgType, seq, payload, err := decodeDatagramFrame(raw)
+			if err != nil {
+				if cs.metrics != nil {
+					cs.metrics.RecordDatagramDropped("malformed")
+				}
+				continue // malformed; there's no sender to retry with, just drop it
+			}
+			if cs.isDuplicateDatagram(seq) {
+				if cs.metrics != nil {
+					cs.metrics.RecordDatagramDropped("duplicate")
+				}
+				continue
+			}
+			if cs.metrics != nil {
+				cs.metrics.RecordDatagramReceived()
+			}
+			select {
+			case out <- ControlDatagram{Type: msgType, Payload: payload}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// datagramDedupWindow bounds how many recent sequence numbers
+// isDuplicateDatagram remembers, so a long-lived connection's dedup set
+// doesn't grow without bound.
+const datagramDedupWindow = 256
+
+// isDuplicateDatagram reports whether seq has already been delivered by a
+// prior ReceiveDatagrams call, recording it if not.
+func (cs *ControlStream) isDuplicateDatagram(seq uint64) bool {
+	cs.dedupMu.Lock()
+	defer cs.dedupMu.Unlock()
+
+	if _, ok := cs.dedupSeen[seq]; ok {
+		return true
+	}
+	cs.dedupSeen[seq] = struct{}{}
+	cs.dedupOrder = append(cs.dedupOrder, seq)
+	if len(cs.dedupOrder) > datagramDedupWindow {
+		oldest := cs.dedupOrder[0]
+		cs.dedupOrder = cs.dedupOrder[1:]
+		delete(cs.dedupSeen, oldest)
+	}
+	return false
+}
+
 // ChunkRangeCompressor compresses chunk indices into range notation
 type ChunkRangeCompressor struct{}
 
@@ -437,3 +1407,107 @@ func (c *ChunkRangeCompressor) Decompress(rangeStr string) ([]int64, error) {
 
 	return chunks, nil
 }
+
+// ToRoaring serializes chunks as a base64-encoded Roaring64 bitmap.
+// Compared to Compress's range-string format, a Roaring bitmap stays compact
+// even when the set doesn't fall into long contiguous runs (e.g. a scattered
+// 10%-present CAS bitmap over a million chunks), at the cost of not being
+// human-readable on the wire.
+func (c *ChunkRangeCompressor) ToRoaring(chunks []int64) (string, error) {
+	bm := roaring64.New()
+	for _, idx := range chunks {
+		bm.Add(uint64(idx))
+	}
+
+	var buf bytes.Buffer
+	if _, err := bm.WriteTo(&buf); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// FromRoaring is the inverse of ToRoaring.
+func (c *ChunkRangeCompressor) FromRoaring(encoded string) ([]int64, error) {
+	if encoded == "" {
+		return []int64{}, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	bm := roaring64.New()
+	if _, err := bm.ReadFrom(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+
+	chunks := make([]int64, 0, bm.GetCardinality())
+	it := bm.Iterator()
+	for it.HasNext() {
+		chunks = append(chunks, int64(it.Next()))
+	}
+	return chunks, nil
+}
+
+// EncodeRanges compresses chunks with ToRoaring when useRoaring is true
+// (typically cs.PeerSupportsRoaring() for whichever ControlStream the
+// message is headed out on), falling back to the legacy Compress format
+// otherwise, and reports which encoding it picked so the caller can stamp it
+// onto the message's Encoding field.
+func (c *ChunkRangeCompressor) EncodeRanges(chunks []int64, useRoaring bool) (encoded, encoding string, err error) {
+	if useRoaring {
+		encoded, err = c.ToRoaring(chunks)
+		return encoded, RangeEncodingRoaring, err
+	}
+	return c.Compress(chunks), RangeEncodingRanges, nil
+}
+
+// DecodeRanges is the inverse of EncodeRanges: it dispatches on encoding,
+// treating "" as RangeEncodingRanges for messages from a peer predating the
+// Encoding field.
+func (c *ChunkRangeCompressor) DecodeRanges(encoded, encoding string) ([]int64, error) {
+	if encoding == RangeEncodingRoaring {
+		return c.FromRoaring(encoded)
+	}
+	return c.Decompress(encoded)
+}
+
+// traceCarrier adapts a single traceparent string to otel's TextMapCarrier
+// so the W3C propagator can inject into / extract from it without pulling in
+// a full map just to hold one key.
+type traceCarrier struct{ traceparent string }
+
+func (c *traceCarrier) Get(key string) string {
+	if key == "traceparent" {
+		return c.traceparent
+	}
+	return ""
+}
+
+func (c *traceCarrier) Set(key, value string) {
+	if key == "traceparent" {
+		c.traceparent = value
+	}
+}
+
+func (c *traceCarrier) Keys() []string { return []string{"traceparent"} }
+
+// InjectTraceContext returns the W3C traceparent string for ctx's current
+// span, suitable for stamping onto a ChunkProofMessage.TraceContext before
+// sending it, or "" if ctx carries no active span.
+func InjectTraceContext(ctx context.Context) string {
+	carrier := &traceCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier.traceparent
+}
+
+// ExtractTraceContext returns a context continuing the trace named by
+// traceContext (a W3C traceparent string), parented to ctx. If
+// traceContext is empty or malformed, ctx is returned unchanged.
+func ExtractTraceContext(ctx context.Context, traceContext string) context.Context {
+	if traceContext == "" {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, &traceCarrier{traceparent: traceContext})
+}
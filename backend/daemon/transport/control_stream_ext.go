@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"github.com/quantarax/backend/internal/crypto"
+	"github.com/quantarax/backend/internal/observability"
+)
+
+// SetPreferredAEAD declares the crypto.AEADAlgorithm this side wants to
+// encrypt chunk and control payloads with, taking effect the next time
+// SendSignedManifest runs (the manifest handshake is what actually
+// advertises it to the peer). Callers with no particular preference can
+// pass crypto.PreferredAEADAlgorithm() to pick AES-256-GCM or
+// ChaCha20-Poly1305 based on the local CPU's AES-NI support.
+func (cs *ControlStream) SetPreferredAEAD(algo crypto.AEADAlgorithm) {
+	cs.preferredAEAD = algo
+}
+
+// PeerAEAD returns the crypto.AEADAlgorithm the peer declared in its most
+// recently received SignedManifest, or crypto.AlgorithmAES256GCM if none
+// has been received yet.
+func (cs *ControlStream) PeerAEAD() crypto.AEADAlgorithm {
+	return cs.peerAEAD
+}
+
+// PreferredAEAD returns the crypto.AEADAlgorithm this side currently
+// advertises via SetPreferredAEAD, or crypto.AlgorithmAES256GCM (the zero
+// value) if none has been set.
+func (cs *ControlStream) PreferredAEAD() crypto.AEADAlgorithm {
+	return cs.preferredAEAD
+}
+
+// SetPreferredCodec declares the ControlCodec this side wants to use for
+// every control message it sends, taking effect the next time
+// SendSignedManifest runs (the manifest handshake is what actually
+// advertises it to the peer). Unknown names resolve to CodecJSON via
+// resolveCodec, same as an unrecognized Codec received from a peer.
+func (cs *ControlStream) SetPreferredCodec(name string) {
+	cs.codec = resolveCodec(name)
+}
+
+// DecodeMessage decodes the raw payload bytes ReceiveAny returns into v
+// using the codec this stream negotiated with its peer. Callers outside
+// this package that inspect ReceiveAny's payload directly (rather than
+// using a typed Receive* method) must go through this instead of
+// json.Unmarshal, since the bytes may be CBOR once a codec other than
+// CodecJSON has been negotiated.
+func (cs *ControlStream) DecodeMessage(data []byte, v interface{}) error {
+	return cs.codec.Unmarshal(data, v)
+}
+
+// SetMetrics attaches the daemon's observability.Metrics so
+// SendDatagramMessage and ReceiveDatagrams can record
+// DatagramsSentTotal/DatagramsReceivedTotal/DatagramsDroppedTotal, and
+// publishes this stream's MaxDatagramSize as MaxDatagramFrameSize. A nil
+// Metrics (the default) disables that accounting without affecting sends
+// or receives.
+func (cs *ControlStream) SetMetrics(m *observability.Metrics) {
+	cs.metrics = m
+	if m != nil {
+		m.SetMaxDatagramFrameSize(cs.MaxDatagramSize())
+	}
+}
@@ -0,0 +1,137 @@
+package transport
+
+import (
+	"context"
+	"sync"
+)
+
+// DatagramCategory tags an unreliable QUIC datagram sent through a
+// QUICConnection's DatagramMux, so the receiving side can dispatch it to
+// the right consumer without decoding the payload first. This is a
+// separate, lower-level path from ControlStream's own
+// SendDatagramMessage/ReceiveDatagrams (which tags datagrams with a
+// ControlMessageType and JSON-encodes the payload): DatagramMux exists for
+// data that isn't part of the control-message protocol at all — periodic
+// telemetry samples and preview thumbnails in particular — tagged with
+// just a single byte instead of ControlStream's 13-byte type/length/sequence
+// header.
+type DatagramCategory uint8
+
+const (
+	// DatagramTelemetry carries periodic loss/RTT samples that feed a
+	// receiver's own LossEstimator, independent of whatever FECController
+	// the sender is running locally.
+	DatagramTelemetry DatagramCategory = iota
+	// DatagramNack carries the same NACK summaries ControlStream's
+	// datagram fast path already delivers; it's included here so a
+	// caller that's standardized on QUICConnection.Datagrams() for every
+	// category doesn't also need a second ControlStream-level datagram
+	// reader running in parallel.
+	DatagramNack
+	// DatagramHaveBitmap carries a CAS-have bitmap snapshot, for a peer
+	// to learn what's already reconstructable without a round trip on
+	// the control stream.
+	DatagramHaveBitmap
+	// DatagramPreviewFrame carries a preview thumbnail frame, small
+	// enough to fit a single datagram and latency-sensitive enough that
+	// waiting behind the bulk stream's backlog would defeat the point.
+	DatagramPreviewFrame
+)
+
+// TelemetrySample is the wire shape DatagramTelemetry datagrams carry: one
+// side's current view of the path, JSON-encoded (datagrams here are small
+// and infrequent enough that JSON's overhead doesn't matter the way it
+// would for, say, DatagramPreviewFrame's payload).
+type TelemetrySample struct {
+	SessionID string  `json:"session_id"`
+	LossMean  float64 `json:"loss_mean"`
+	RTTMillis float64 `json:"rtt_millis"`
+	K         int     `json:"k"`
+	R         int     `json:"r"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// DatagramMux demultiplexes a QUICConnection's unreliable datagrams by
+// their leading DatagramCategory byte, so several independent consumers
+// (a telemetry reporter, a have-bitmap updater, a preview renderer) can
+// each get just their own category's datagrams without racing each other
+// to read the connection directly — only Run reads off conn.
+type DatagramMux struct {
+	conn *QUICConnection
+
+	mu   sync.Mutex
+	subs map[DatagramCategory]chan []byte
+}
+
+// NewDatagramMux creates a DatagramMux over conn. Run must be started
+// (typically in its own goroutine) before Send's peer-side counterpart's
+// datagrams start arriving, or they're dropped same as any other unread
+// QUIC datagram.
+func NewDatagramMux(conn *QUICConnection) *DatagramMux {
+	return &DatagramMux{conn: conn, subs: make(map[DatagramCategory]chan []byte)}
+}
+
+// Subscribe returns the channel Run delivers category's datagrams on. The
+// channel is buffered and never closed; Run exiting just stops deliveries.
+// Calling Subscribe for the same category twice returns the same channel.
+func (m *DatagramMux) Subscribe(category DatagramCategory) <-chan []byte {
+	return m.subscribeChan(category)
+}
+
+func (m *DatagramMux) subscribeChan(category DatagramCategory) chan []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ch, ok := m.subs[category]
+	if !ok {
+		ch = make(chan []byte, 32)
+		m.subs[category] = ch
+	}
+	return ch
+}
+
+// Run reads datagrams off the connection until ctx is done or the
+// connection errors, dispatching each to its category's Subscribe channel.
+// A datagram with no subscriber, an empty payload, or a category byte this
+// build doesn't recognize is silently dropped — the same as a lost packet,
+// since there's no sender to retry with. Callers run Run in its own
+// goroutine; it blocks until ctx is done.
+func (m *DatagramMux) Run(ctx context.Context) {
+	for {
+		raw, err := m.conn.ReceiveDatagram(ctx)
+		if err != nil {
+			return
+		}
+		if len(raw) == 0 {
+			continue
+		}
+		category := DatagramCategory(raw[0])
+		m.mu.Lock()
+		ch, ok := m.subs[category]
+		m.mu.Unlock()
+		if !ok {
+			continue
+		}
+		payload := raw[1:]
+		select {
+		case ch <- payload:
+		default:
+			// The consumer is behind; every category here only ever cares
+			// about the latest sample (telemetry, have-bitmap) or tolerates
+			// the occasional drop (a NACK/preview frame, same as any other
+			// datagram), so dropping this one in favor of not blocking Run
+			// is the right tradeoff.
+		}
+	}
+}
+
+// Send tags payload with category and sends it as an unreliable QUIC
+// datagram at priority. priority is accepted for future congestion-aware
+// pacing (QUIC datagrams have no stream-level priority lanes the way
+// PriorityScheduler gives reliable streams, so there's nothing to act on
+// yet) and isn't consulted today.
+func (m *DatagramMux) Send(category DatagramCategory, payload []byte, priority PriorityClass) error {
+	tagged := make([]byte, 1+len(payload))
+	tagged[0] = byte(category)
+	copy(tagged[1:], payload)
+	return m.conn.SendDatagram(tagged, priority)
+}
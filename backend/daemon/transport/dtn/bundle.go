@@ -0,0 +1,37 @@
+// Package dtn implements a bundle-protocol-style store-and-forward
+// transport adapter for chunker.DTNProfile: each chunk that can't be
+// delivered directly over an active QUIC session is wrapped into a Bundle,
+// spooled to local disk, and opportunistically forwarded to one of a known
+// set of relays until that relay accepts custody of it.
+package dtn
+
+import "time"
+
+// Bundle is one DTN-style store-and-forward unit: an already-encrypted
+// chunk plus the header fields a relay needs to route, expire, and
+// acknowledge custody of it without ever decrypting the payload.
+type Bundle struct {
+	SessionID  string `json:"session_id"`
+	ChunkIndex int64  `json:"chunk_index"`
+
+	// TTL is the bundle's absolute expiry (unix seconds), carried over
+	// from chunker.DTNProfile.TTLSeconds at the point the bundle was
+	// created, the same "compute the deadline once at enqueue time"
+	// approach DTNItem.ExpireAt already uses.
+	TTL int64 `json:"ttl_unix"`
+
+	// Custody marks a bundle as requiring an explicit custody-accept
+	// signal from the next hop before Forwarder drops its spooled copy.
+	// A non-custody bundle is dropped as soon as delivery itself
+	// succeeds, the same best-effort semantics DTNWorker already has.
+	Custody bool `json:"custody"`
+
+	CreatedAt  int64  `json:"created_at"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Expired reports whether b is past its TTL as of now. A zero TTL never
+// expires, the same convention DTNItem.ExpireAt uses.
+func (b *Bundle) Expired(now time.Time) bool {
+	return b.TTL > 0 && now.Unix() >= b.TTL
+}
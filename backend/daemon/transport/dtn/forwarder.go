@@ -0,0 +1,176 @@
+package dtn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/quantarax/backend/daemon/transport"
+	"github.com/quantarax/backend/internal/observability"
+)
+
+// RelaySender delivers one bundle to relayAddr and reports whether that
+// relay accepted custody of it. Forwarder only drops its spooled copy of a
+// custody bundle once this returns custodyAccepted == true; a non-custody
+// bundle is dropped as soon as send itself succeeds (err == nil),
+// regardless of custodyAccepted.
+type RelaySender func(ctx context.Context, relayAddr string, b *Bundle) (custodyAccepted bool, err error)
+
+// Forwarder periodically walks a Spool and opportunistically forwards
+// whatever it holds to a known, static set of relays, round-robin, honoring
+// bundle-protocol custody semantics along the way.
+type Forwarder struct {
+	spool  *Spool
+	relays []string
+	send   RelaySender
+
+	backoff  transport.BackoffPolicy
+	interval time.Duration
+	logger   *observability.Logger
+
+	stop chan struct{}
+	next int
+}
+
+// NewForwarder creates a Forwarder that sweeps spool every 5 seconds,
+// trying relays in round-robin order via send.
+func NewForwarder(spool *Spool, relays []string, send RelaySender) *Forwarder {
+	return &Forwarder{
+		spool:    spool,
+		relays:   relays,
+		send:     send,
+		backoff:  transport.DefaultBackoffPolicy(),
+		interval: 5 * time.Second,
+	}
+}
+
+// SetBackoff overrides the BackoffPolicy whose Exhausted check decides when
+// a bundle that keeps failing is dropped from the spool. The policy's delays
+// are not used: every sweep retries each spooled bundle.
+func (f *Forwarder) SetBackoff(policy transport.BackoffPolicy) {
+	f.backoff = policy
+}
+
+// SetLogger attaches a logger for dropped/accepted bundle events. Left nil,
+// those events are silently discarded, the same as DTNWorker without
+// SetLogger.
+func (f *Forwarder) SetLogger(logger *observability.Logger) {
+	f.logger = logger
+}
+
+// Start begins the periodic sweep in a background goroutine.
+func (f *Forwarder) Start() {
+	f.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				f.sweep()
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic sweep.
+func (f *Forwarder) Stop() {
+	if f.stop != nil {
+		close(f.stop)
+	}
+}
+
+// sweep tries to forward every spooled bundle once, dropping it (deleting
+// from the spool) on success, expiry, or backoff exhaustion.
+func (f *Forwarder) sweep() {
+	entries, err := f.spool.List()
+	if err != nil || len(entries) == 0 {
+		return
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		f.attempt(entry, now)
+	}
+}
+
+// attempt tries every known relay, in round-robin starting order, for one
+// spooled entry, stopping at the first that either delivers it outright
+// (non-custody) or accepts custody of it (custody).
+func (f *Forwarder) attempt(entry SpoolEntry, now time.Time) {
+	if entry.Expired(now) {
+		_ = f.spool.Delete(entry.SessionID, entry.ChunkIndex)
+		if f.logger != nil {
+			f.logger.DTNItemDropped(entry.SessionID, entry.ChunkIndex, "expired", entry.Attempts)
+		}
+		return
+	}
+	if len(f.relays) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for i := 0; i < len(f.relays); i++ {
+		relay := f.relays[(f.next+i)%len(f.relays)]
+		accepted, err := f.send(ctx, relay, &entry.Bundle)
+		if err != nil {
+			continue
+		}
+		f.next = (f.next + i + 1) % len(f.relays)
+		if !entry.Custody || accepted {
+			_ = f.spool.Delete(entry.SessionID, entry.ChunkIndex)
+			observability.TraceGlobal(observability.FacetDTN, "dtn bundle delivered", "session_id", entry.SessionID, "chunk_index", entry.ChunkIndex, "relay", relay, "custody_accepted", accepted)
+			return
+		}
+		// Delivered but the relay didn't accept custody: keep the spooled
+		// copy and try the next relay, the same as after a failed send.
+	}
+
+	attempts, _ := f.spool.IncrementAttempts(entry.SessionID, entry.ChunkIndex)
+	if f.backoff.Exhausted(attempts) {
+		_ = f.spool.Delete(entry.SessionID, entry.ChunkIndex)
+		if f.logger != nil {
+			f.logger.DTNItemDropped(entry.SessionID, entry.ChunkIndex, "max_attempts_exceeded", attempts)
+		}
+	}
+}
+
+// HTTPRelaySender is the default RelaySender: it POSTs b as JSON to
+// relayAddr+"/dtn/bundle" and treats a 2xx response whose body is
+// {"custody_accepted":true} (the relay sends it with 202 Accepted) as
+// custody acceptance, and any other 2xx as plain delivery.
+func HTTPRelaySender(client *http.Client) RelaySender {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return func(ctx context.Context, relayAddr string, b *Bundle) (bool, error) {
+		body, err := json.Marshal(b)
+		if err != nil {
+			return false, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, relayAddr+"/dtn/bundle", bytes.NewReader(body))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return false, fmt.Errorf("dtn bundle delivery to %s failed: status %d", relayAddr, resp.StatusCode)
+		}
+		var ack struct {
+			CustodyAccepted bool `json:"custody_accepted"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&ack)
+		return ack.CustodyAccepted, nil
+	}
+}
@@ -0,0 +1,121 @@
+package dtn
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// SpoolEntry is one Bundle as held in the spool, plus the delivery-attempt
+// count Forwarder uses to back off and eventually give up on it.
+type SpoolEntry struct {
+	Bundle
+	Attempts int `json:"attempts"`
+}
+
+var bucketSpool = []byte("dtn_bundle_spool")
+
+// Spool is a BoltDB-backed store of Bundles awaiting delivery, keyed by
+// "sessionID:chunkIndex" so re-spooling the same chunk overwrites rather
+// than duplicates it, unlike DTNQueue's entries, which are keyed by
+// priority, expiry and a sequence number.
+type Spool struct {
+	db *bolt.DB
+}
+
+// OpenSpool opens (creating if necessary) a bundle spool at path.
+func OpenSpool(path string) (*Spool, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, e := tx.CreateBucketIfNotExists(bucketSpool)
+		return e
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Spool{db: db}, nil
+}
+
+// spoolKey builds the primary key for a session/chunk pair: the session ID
+// followed by ':' and the chunk index as a fixed 8-byte big-endian
+// integer, the same separator scheme dtnIndexKey in the DTNQueue uses, so
+// a session's spooled entries sort and prefix-scan together.
+func spoolKey(sessionID string, chunkIndex int64) []byte {
+	key := make([]byte, 0, len(sessionID)+1+8)
+	key = append(key, []byte(sessionID)...)
+	key = append(key, ':')
+	idx := make([]byte, 8)
+	binary.BigEndian.PutUint64(idx, uint64(chunkIndex))
+	return append(key, idx...)
+}
+
+// Put inserts or overwrites the spooled entry for b's session/chunk pair,
+// resetting Attempts to 0.
+func (s *Spool) Put(b *Bundle) error {
+	entry := SpoolEntry{Bundle: *b}
+	val, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSpool).Put(spoolKey(b.SessionID, b.ChunkIndex), val)
+	})
+}
+
+// Delete removes the spooled entry for sessionID/chunkIndex, called once a
+// bundle is delivered (or a custody bundle's custody has been accepted).
+func (s *Spool) Delete(sessionID string, chunkIndex int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSpool).Delete(spoolKey(sessionID, chunkIndex))
+	})
+}
+
+// IncrementAttempts bumps the attempt counter for an entry still in the
+// spool after a failed delivery try, returning the new count.
+func (s *Spool) IncrementAttempts(sessionID string, chunkIndex int64) (int, error) {
+	var attempts int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketSpool)
+		key := spoolKey(sessionID, chunkIndex)
+		val := b.Get(key)
+		if val == nil {
+			return nil
+		}
+		var entry SpoolEntry
+		if err := json.Unmarshal(val, &entry); err != nil {
+			return err
+		}
+		entry.Attempts++
+		attempts = entry.Attempts
+		out, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return b.Put(key, out)
+	})
+	return attempts, err
+}
+
+// List returns every entry currently in the spool, in no particular order.
+func (s *Spool) List() ([]SpoolEntry, error) {
+	var out []SpoolEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketSpool).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var entry SpoolEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				continue
+			}
+			out = append(out, entry)
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (s *Spool) Close() error { return s.db.Close() }
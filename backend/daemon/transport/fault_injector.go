@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// FaultErrorKind selects which flavor of error FaultInjector.BeforeWrite
+// simulates when it decides a write should fail.
+type FaultErrorKind int
+
+const (
+	FaultErrorTimeout FaultErrorKind = iota
+	FaultErrorReset
+	FaultErrorPartialWrite
+)
+
+var (
+	ErrFaultInjectedStreamOpen = errors.New("transport: fault injector simulated stream-open failure")
+	ErrFaultInjectedTimeout    = errors.New("transport: fault injector simulated write timeout")
+	ErrFaultInjectedReset      = errors.New("transport: fault injector simulated connection reset")
+	ErrFaultInjectedBlackout   = errors.New("transport: fault injector blackout window active")
+)
+
+// FaultInjectorConfig tunes FaultInjector's chaos behavior. Every rate is an
+// independent per-call probability in [0, 1]; a zero value disables that
+// particular fault.
+type FaultInjectorConfig struct {
+	// StreamOpenFailureRate is the chance BeforeOpenStream fails the open.
+	StreamOpenFailureRate float64
+
+	// WriteErrorRate is the chance BeforeWrite fails a write, choosing one of
+	// WriteErrorKinds (or FaultErrorTimeout if none are configured).
+	WriteErrorRate  float64
+	WriteErrorKinds []FaultErrorKind
+
+	// LatencyBase and LatencyJitter add constant + random delay before every
+	// injector decision, simulating network latency.
+	LatencyBase   time.Duration
+	LatencyJitter time.Duration
+
+	// BlackoutChance is rolled on every call once outside an active
+	// blackout window; on a hit, every call fails for BlackoutDuration
+	// before the injector recovers on its own.
+	BlackoutChance   float64
+	BlackoutDuration time.Duration
+}
+
+// FaultInjector wraps a ChunkWorkerPool's stream-open and write paths with
+// randomized chaos, so developing and testing against an unstable network
+// doesn't require an actual unstable network. A nil *FaultInjector (the
+// default) disables it entirely.
+type FaultInjector struct {
+	cfg FaultInjectorConfig
+	rng *rand.Rand
+
+	mu            sync.Mutex
+	blackoutUntil time.Time
+}
+
+// NewFaultInjector creates an injector seeded from the current time.
+func NewFaultInjector(cfg FaultInjectorConfig) *FaultInjector {
+	return &FaultInjector{
+		cfg: cfg,
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (f *FaultInjector) delay() {
+	if f.cfg.LatencyBase <= 0 && f.cfg.LatencyJitter <= 0 {
+		return
+	}
+	d := f.cfg.LatencyBase
+	if f.cfg.LatencyJitter > 0 {
+		f.mu.Lock()
+		jitter := time.Duration(f.rng.Int63n(int64(f.cfg.LatencyJitter)))
+		f.mu.Unlock()
+		d += jitter
+	}
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// inBlackout reports whether a blackout window is currently active, rolling
+// a new one if BlackoutChance fires and none is active yet.
+func (f *FaultInjector) inBlackout() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(f.blackoutUntil) {
+		return true
+	}
+	if f.cfg.BlackoutChance > 0 && f.rng.Float64() < f.cfg.BlackoutChance {
+		f.blackoutUntil = now.Add(f.cfg.BlackoutDuration)
+		return true
+	}
+	return false
+}
+
+// BeforeOpenStream stands in for OpenStreamSync's success path. A non-nil
+// error means the caller should treat the stream open as having failed,
+// without ever calling the real OpenStreamSync.
+func (f *FaultInjector) BeforeOpenStream() error {
+	f.delay()
+	if f.inBlackout() {
+		return ErrFaultInjectedBlackout
+	}
+	if f.cfg.StreamOpenFailureRate > 0 && f.rng.Float64() < f.cfg.StreamOpenFailureRate {
+		return ErrFaultInjectedStreamOpen
+	}
+	return nil
+}
+
+// BeforeWrite stands in for a real stream.Write(payload). A non-nil error
+// means the caller should treat the write as failed without touching the
+// real stream; n reports how many bytes should be counted as written (zero
+// for most faults, half of payload for a simulated partial write).
+func (f *FaultInjector) BeforeWrite(payload []byte) (n int, err error) {
+	f.delay()
+	if f.inBlackout() {
+		return 0, ErrFaultInjectedBlackout
+	}
+	if f.cfg.WriteErrorRate <= 0 || f.rng.Float64() >= f.cfg.WriteErrorRate {
+		return len(payload), nil
+	}
+
+	kinds := f.cfg.WriteErrorKinds
+	if len(kinds) == 0 {
+		kinds = []FaultErrorKind{FaultErrorTimeout}
+	}
+	switch kinds[f.rng.Intn(len(kinds))] {
+	case FaultErrorReset:
+		return 0, ErrFaultInjectedReset
+	case FaultErrorPartialWrite:
+		return len(payload) / 2, io.ErrShortWrite
+	default:
+		return 0, ErrFaultInjectedTimeout
+	}
+}
@@ -0,0 +1,35 @@
+package faultproxy
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/quantarax/backend/daemon/transport"
+)
+
+// WithFaultProxy starts a Proxy in front of addr and dials it through
+// transport.DialQUIC, returning the resulting connection alongside the
+// Proxy so the caller can Close it (which also tears down the relay) once
+// the test is done. The proxy's Run loop is started in its own goroutine
+// tied to ctx, so it stops relaying once ctx is done even if the caller
+// forgets to Close explicitly.
+//
+// This is the "dial option" DialQUIC itself doesn't need a variadic
+// parameter for: every other caller of transport.DialQUIC dials the real
+// address directly, and a fault-injecting scenario just needs to dial a
+// different address (the proxy's) instead — there's nothing DialQUIC's
+// own signature has to know about.
+func WithFaultProxy(ctx context.Context, cfg *Config, addr string, tlsConfig *tls.Config) (*transport.QUICConnection, *Proxy, error) {
+	proxy, err := NewProxy(cfg, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	go proxy.Run(ctx)
+
+	conn, err := transport.DialQUIC(ctx, proxy.ListenAddr(), tlsConfig)
+	if err != nil {
+		proxy.Close()
+		return nil, nil, err
+	}
+	return conn, proxy, nil
+}
@@ -0,0 +1,75 @@
+// Package faultproxy implements a chaos/fault-injection relay that sits
+// between a QUIC dial and the real UDP socket, deterministically dropping,
+// delaying, reordering, duplicating, and bit-flipping the datagrams that
+// pass through it according to a Config loaded from a JSON scenario file.
+// It exists so integration tests can gate on properties like "FEC recovers
+// at 15% loss" or "NACK retransmit completes within N seconds under a 2s
+// blackout" without an actually unstable network, the same role the
+// network-fault proxy layer plays in etcd's functional tester.
+//
+// faultproxy operates on raw UDP datagrams beneath QUIC's own packet
+// encryption, so it can't distinguish faults by QUIC stream — a drop
+// probability applies to every datagram in a direction, whichever streams'
+// frames happen to be coalesced into it. Scenarios that want one stream
+// hit harder than another need to drive that at the quic.Config/priority
+// level instead (see transport.PriorityScheduler).
+package faultproxy
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// BlackoutWindow blacks out every datagram in both directions for
+// Duration, starting AfterStart after the Proxy's Run began.
+type BlackoutWindow struct {
+	AfterStartMillis int64 `json:"after_start_millis"`
+	DurationMillis   int64 `json:"duration_millis"`
+}
+
+// Config tunes a Proxy's chaos behavior. Every probability is independent
+// and applied per datagram per direction; a zero value disables that
+// particular fault. Delay/jitter/bandwidth fields are milliseconds/bytes
+// rather than time.Duration so Config round-trips through JSON without a
+// custom (Un)marshaler.
+type Config struct {
+	// Seed makes a scenario's fault sequence reproducible across runs;
+	// 0 falls back to a time-seeded RNG (non-reproducible, for manual
+	// exploratory use rather than a CI-gated test).
+	Seed int64 `json:"seed"`
+
+	DropProbability      float64 `json:"drop_probability"`
+	DuplicateProbability float64 `json:"duplicate_probability"`
+	ReorderProbability   float64 `json:"reorder_probability"`
+	BitFlipProbability   float64 `json:"bit_flip_probability"`
+
+	// DelayMinMillis/DelayMaxMillis bound a uniformly distributed
+	// per-datagram delay; both zero disables added delay entirely.
+	DelayMinMillis int64 `json:"delay_min_millis"`
+	DelayMaxMillis int64 `json:"delay_max_millis"`
+
+	// BandwidthCapBytesPerSec, if positive, paces forwarded datagrams so
+	// the proxy never relays more than this many bytes per second in
+	// either direction, simulating a constrained link.
+	BandwidthCapBytesPerSec int64 `json:"bandwidth_cap_bytes_per_sec"`
+
+	Blackouts []BlackoutWindow `json:"blackouts"`
+}
+
+// LoadConfig reads and parses a JSON scenario file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) delayRange() (time.Duration, time.Duration) {
+	return time.Duration(c.DelayMinMillis) * time.Millisecond, time.Duration(c.DelayMaxMillis) * time.Millisecond
+}
@@ -0,0 +1,102 @@
+package faultproxy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pacedHoldback applies one direction's delay, reorder, and bandwidth-cap
+// behavior to the datagrams Proxy.deliver hands it, one instance per
+// direction so client->backend and backend->client don't share state.
+type pacedHoldback struct {
+	capBytesPerSec int64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	heldMu sync.Mutex
+	held   []byte
+}
+
+func newPacedHoldback(capBytesPerSec int64) *pacedHoldback {
+	return &pacedHoldback{capBytesPerSec: capBytesPerSec}
+}
+
+// send delivers data (after delay and the bandwidth cap's pacing) via
+// sendFn, unless reorder is set, in which case data is held back until the
+// next call to send — effectively swapping it with whatever comes after it
+// in this direction, a one-packet reorder.
+func (h *pacedHoldback) send(ctx context.Context, data []byte, delay time.Duration, reorder bool, sendFn func([]byte)) {
+	h.heldMu.Lock()
+	prevHeld := h.held
+	h.held = nil
+	if reorder {
+		h.held = data
+	}
+	h.heldMu.Unlock()
+
+	switch {
+	case prevHeld != nil && !reorder:
+		// data goes out first, then the datagram held back before it.
+		go func() {
+			h.dispatch(ctx, data, delay, sendFn)
+			h.dispatch(ctx, prevHeld, 0, sendFn)
+		}()
+	case prevHeld != nil:
+		go h.dispatch(ctx, prevHeld, delay, sendFn)
+	case !reorder:
+		go h.dispatch(ctx, data, delay, sendFn)
+	}
+}
+
+func (h *pacedHoldback) dispatch(ctx context.Context, data []byte, delay time.Duration, sendFn func([]byte)) {
+	h.pace(len(data))
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return
+	default:
+	}
+	sendFn(data)
+}
+
+// pace blocks until sending n more bytes would stay within capBytesPerSec,
+// a simple token bucket reset off wall-clock elapsed time since the last
+// call rather than a background ticker. A non-positive cap disables
+// pacing entirely.
+func (h *pacedHoldback) pace(n int) {
+	if h.capBytesPerSec <= 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if h.last.IsZero() {
+		h.last = now
+	}
+	h.tokens += now.Sub(h.last).Seconds() * float64(h.capBytesPerSec)
+	if h.tokens > float64(h.capBytesPerSec) {
+		h.tokens = float64(h.capBytesPerSec) // cap burst credit to one second's worth
+	}
+	h.last = now
+
+	if h.tokens < float64(n) {
+		wait := time.Duration((float64(n) - h.tokens) / float64(h.capBytesPerSec) * float64(time.Second))
+		time.Sleep(wait)
+		h.tokens = 0
+		h.last = time.Now()
+		return
+	}
+	h.tokens -= float64(n)
+}
@@ -0,0 +1,215 @@
+package faultproxy
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+const maxDatagramBytes = 65536
+
+// Proxy relays UDP datagrams between whatever dials its front address and
+// a fixed backend address, applying Config's faults to every datagram it
+// forwards in either direction.
+type Proxy struct {
+	cfg *Config
+
+	front *net.UDPConn // faces the dialer; ListenAddr() is what it should dial
+	back  *net.UDPConn // dialed to the real backend address
+
+	rng   *rand.Rand
+	rngMu sync.Mutex
+
+	startedAt time.Time
+
+	clientMu   sync.Mutex
+	clientAddr *net.UDPAddr // sender of the most recent datagram front received
+
+	fwdHoldback *pacedHoldback // client -> backend
+	revHoldback *pacedHoldback // backend -> client
+}
+
+// NewProxy binds an ephemeral local UDP socket to front datagrams and
+// dials backendAddr for the other leg. Run must be called to start
+// relaying; Close releases both sockets.
+func NewProxy(cfg *Config, backendAddr string) (*Proxy, error) {
+	front, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	if err != nil {
+		return nil, err
+	}
+	remote, err := net.ResolveUDPAddr("udp", backendAddr)
+	if err != nil {
+		front.Close()
+		return nil, err
+	}
+	back, err := net.DialUDP("udp", nil, remote)
+	if err != nil {
+		front.Close()
+		return nil, err
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	p := &Proxy{
+		cfg:   cfg,
+		front: front,
+		back:  back,
+		rng:   rand.New(rand.NewSource(seed)),
+	}
+	p.fwdHoldback = newPacedHoldback(cfg.BandwidthCapBytesPerSec)
+	p.revHoldback = newPacedHoldback(cfg.BandwidthCapBytesPerSec)
+	return p, nil
+}
+
+// ListenAddr returns the address a QUIC dial should target to reach
+// backendAddr through this proxy.
+func (p *Proxy) ListenAddr() string {
+	return p.front.LocalAddr().String()
+}
+
+// Close releases both of the proxy's sockets.
+func (p *Proxy) Close() error {
+	err1 := p.front.Close()
+	err2 := p.back.Close()
+	if err1 != nil {
+		return err1
+	}
+	return err2
+}
+
+// Run relays datagrams in both directions. Each direction stops when ctx is
+// done or its read socket errors, and Run returns once both have stopped.
+// Callers typically run it in its own goroutine.
+func (p *Proxy) Run(ctx context.Context) {
+	p.startedAt = time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.relayClientToBackend(ctx)
+	}()
+	p.relayBackendToClient(ctx)
+	<-done
+}
+
+func (p *Proxy) relayClientToBackend(ctx context.Context) {
+	buf := make([]byte, maxDatagramBytes)
+	for {
+		p.front.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, from, err := p.front.ReadFromUDP(buf)
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		if err != nil {
+			if isTimeout(err) {
+				continue
+			}
+			return
+		}
+		p.clientMu.Lock()
+		p.clientAddr = from
+		p.clientMu.Unlock()
+
+		pkt := append([]byte(nil), buf[:n]...)
+		p.deliver(ctx, p.fwdHoldback, pkt, func(data []byte) { p.back.Write(data) })
+	}
+}
+
+func (p *Proxy) relayBackendToClient(ctx context.Context) {
+	buf := make([]byte, maxDatagramBytes)
+	for {
+		p.back.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, err := p.back.Read(buf)
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		if err != nil {
+			if isTimeout(err) {
+				continue
+			}
+			return
+		}
+
+		p.clientMu.Lock()
+		client := p.clientAddr
+		p.clientMu.Unlock()
+		if client == nil {
+			continue // backend replied before the client ever sent anything; nothing to relay to
+		}
+
+		pkt := append([]byte(nil), buf[:n]...)
+		p.deliver(ctx, p.revHoldback, pkt, func(data []byte) { p.front.WriteToUDP(data, client) })
+	}
+}
+
+// deliver applies the configured faults to pkt and hands surviving copies
+// to send, each after hb's pacing/delay/reorder decision. A duplicated
+// datagram is sent twice (each independently delayed); a dropped one never
+// reaches send at all.
+func (p *Proxy) deliver(ctx context.Context, hb *pacedHoldback, pkt []byte, send func([]byte)) {
+	if p.inBlackout() || p.roll(p.cfg.DropProbability) {
+		return
+	}
+	if p.roll(p.cfg.BitFlipProbability) && len(pkt) > 0 {
+		pkt = append([]byte(nil), pkt...)
+		pkt[p.rng.Intn(len(pkt))] ^= 0xFF
+	}
+
+	copies := [][]byte{pkt}
+	if p.roll(p.cfg.DuplicateProbability) {
+		copies = append(copies, append([]byte(nil), pkt...))
+	}
+
+	for _, c := range copies {
+		delay := p.randomDelay()
+		reorder := p.roll(p.cfg.ReorderProbability)
+		hb.send(ctx, c, delay, reorder, send)
+	}
+}
+
+func (p *Proxy) roll(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	p.rngMu.Lock()
+	defer p.rngMu.Unlock()
+	return p.rng.Float64() < probability
+}
+
+func (p *Proxy) randomDelay() time.Duration {
+	min, max := p.cfg.delayRange()
+	if max <= min {
+		return min
+	}
+	p.rngMu.Lock()
+	defer p.rngMu.Unlock()
+	return min + time.Duration(p.rng.Int63n(int64(max-min)))
+}
+
+func (p *Proxy) inBlackout() bool {
+	if len(p.cfg.Blackouts) == 0 {
+		return false
+	}
+	elapsed := time.Since(p.startedAt)
+	for _, b := range p.cfg.Blackouts {
+		start := time.Duration(b.AfterStartMillis) * time.Millisecond
+		end := start + time.Duration(b.DurationMillis)*time.Millisecond
+		if elapsed >= start && elapsed < end {
+			return true
+		}
+	}
+	return false
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
@@ -1,45 +1,253 @@
 package transport
 
 import (
-	"time"
+	"math"
+
+	"github.com/quantarax/backend/internal/observability"
 )
 
+// LossEstimator maintains an exponentially weighted moving average of
+// packet loss rate plus its variance (via Welford's online algorithm), so
+// FECController can react to both how lossy the path currently is and how
+// noisy that estimate has been.
 type LossEstimator struct {
-	windowSent int64
-	windowLost int64
-	lastUpdate time.Time
+	// alpha weights each new sample against the running EWMA; higher
+	// reacts faster, lower smooths out noise. 0.2 matches common TCP RTO
+	// EWMA smoothing factors and is a reasonable default for loss too.
+	alpha float64
+
+	haveSample bool
+	mean       float64 // EWMA of loss rate
+	m2         float64 // Welford's running sum of squared deviations, for variance
+	count      int64
+}
+
+// NewLossEstimator creates a LossEstimator with the given EWMA weight.
+// alpha <= 0 falls back to 0.2.
+func NewLossEstimator(alpha float64) *LossEstimator {
+	if alpha <= 0 {
+		alpha = 0.2
+	}
+	return &LossEstimator{alpha: alpha}
 }
 
-func (le *LossEstimator) OnSent(n int64)   { le.windowSent += n }
-func (le *LossEstimator) OnLost(n int64)   { le.windowLost += n }
-func (le *LossEstimator) Estimate() float64 {
-	if le.windowSent == 0 { return 0 }
-	return float64(le.windowLost) / float64(le.windowSent)
+// Observe folds one (sent, lost) sample's loss rate into the EWMA and
+// variance estimate. sent <= 0 is ignored (nothing was observed).
+func (le *LossEstimator) Observe(sent, lost int) {
+	if sent <= 0 {
+		return
+	}
+	rate := float64(lost) / float64(sent)
+	le.count++
+	if !le.haveSample {
+		le.mean = rate
+		le.haveSample = true
+		return
+	}
+	// Welford's algorithm, adapted to weight the mean update by alpha
+	// (an EWMA) rather than 1/count (a plain running average), so the
+	// variance estimate tracks the same recency-weighted mean.
+	delta := rate - le.mean
+	le.mean += le.alpha * delta
+	delta2 := rate - le.mean
+	le.m2 = (1 - le.alpha) * (le.m2 + le.alpha*delta*delta2)
 }
 
-// FECController adapts K/R based on loss
+// Mean returns the current EWMA loss rate, 0 until the first sample.
+func (le *LossEstimator) Mean() float64 { return le.mean }
+
+// StdDev returns the current estimate of the loss rate's standard
+// deviation, 0 until at least two samples have been observed.
+func (le *LossEstimator) StdDev() float64 {
+	if le.m2 < 0 {
+		return 0
+	}
+	return math.Sqrt(le.m2)
+}
 
+// FECController adapts a transfer's FEC parity ratio (r/k) to the
+// measured loss rate and RTT on its path. Each Tick computes a target
+// ratio from the current LossEstimator state and only calls update once
+// the target has held steady for hysteresisTicks consecutive Ticks, to
+// avoid oscillating the ratio on every noisy sample.
 type FECController struct {
-	k, r   int
-	loss   *LossEstimator
-	lastMsg time.Time
-	update func(k, r int, reason string)
+	k, r int
+
+	rMin, rMax int
+
+	loss *LossEstimator
+	rtt  *LossEstimator // reused as a generic EWMA+variance estimator for RTT millis
+
+	// safetyFactor scales the loss standard deviation into the safety
+	// margin the target-ratio recurrence adds on top of the mean loss
+	// rate, so a noisier loss estimate asks for more parity headroom.
+	safetyFactor float64
+
+	// hysteresisTicks is how many consecutive Ticks a new target must
+	// hold before update fires.
+	hysteresisTicks int
+	pendingTarget   int
+	pendingReason   string
+	pendingStreak   int
+
+	// rttSpikeFactor*rtt.Mean() above the EWMA RTT mean that a sample must
+	// exceed to report "rtt_spike" as the adaptation's reason instead of
+	// the loss-driven one.
+	rttSpikeFactor float64
+	lastRTTSpike   bool
+
+	update  func(k, r int, reason string)
+	metrics *observability.Metrics
+
+	// telemetry, if set via SetTelemetryPublisher, is called on every
+	// Tick, before that Tick applies any change of r, with the
+	// controller's current loss/RTT estimate,
+	// independent of whether the parity ratio itself changed — unlike
+	// update, which only fires once the target has settled. A caller
+	// typically wires this to send a DatagramTelemetry datagram so the
+	// peer's own loss estimate can track this side's view of the path
+	// without waiting on an explicit FECUpdateMessage.
+	telemetry func(lossMean, rttMean float64, k, r int)
 }
 
+// NewFECController creates a controller starting at (initK, initR), with
+// rMin/rMax clamping the parity shard count it will ever ask for and
+// hysteresisTicks set to the package default of 3. update is called
+// whenever the controller settles on a new r.
 func NewFECController(initK, initR int, update func(k, r int, reason string)) *FECController {
-	return &FECController{k: initK, r: initR, loss: &LossEstimator{}, update: update}
+	rMin := 1
+	rMax := initK
+	if initR > rMax {
+		rMax = initR
+	}
+	return &FECController{
+		k:               initK,
+		r:               initR,
+		rMin:            rMin,
+		rMax:            rMax,
+		loss:            NewLossEstimator(0.2),
+		rtt:             NewLossEstimator(0.2),
+		safetyFactor:    1.0,
+		hysteresisTicks: 3,
+		rttSpikeFactor:  2.0,
+		pendingTarget:   initR,
+		update:          update,
+	}
+}
+
+// SetMetrics attaches metrics so every Tick reports the controller's
+// current loss rate and parity ratio, and every update adds to the
+// parity-shard counter. Left unset (nil), the controller still adapts,
+// it just doesn't publish to Prometheus.
+func (fc *FECController) SetMetrics(metrics *observability.Metrics) {
+	fc.metrics = metrics
+}
+
+// SetTelemetryPublisher registers fn to be called on every Tick, before
+// that Tick applies any change of r, with the controller's current loss/RTT
+// estimate and parity ratio,
+// for a caller to forward over a low-latency path (a DatagramTelemetry
+// datagram, typically) that doesn't wait for the parity ratio to actually
+// change the way FECUpdateMessage does. Left unset (nil), Tick behaves
+// exactly as before.
+func (fc *FECController) SetTelemetryPublisher(fn func(lossMean, rttMean float64, k, r int)) {
+	fc.telemetry = fn
 }
 
+// SetBounds overrides the default [1, max(initK, initR)] range Tick clamps its target
+// parity shard count to.
+func (fc *FECController) SetBounds(rMin, rMax int) {
+	fc.rMin = rMin
+	fc.rMax = rMax
+}
+
+// Observe folds one interval's (sent, lost) packet counts and the
+// interval's measured RTT into the controller's EWMA estimates, for the
+// next Tick to act on.
+func (fc *FECController) Observe(sent, lost, rttMillis int) {
+	fc.loss.Observe(sent, lost)
+	fc.observeRTT(rttMillis)
+}
+
+// observeRTT feeds rttMillis into fc.rtt's EWMA the same way Observe feeds
+// a loss rate into fc.loss, and flags whether this sample counts as a
+// spike (more than rttSpikeFactor times the prior EWMA mean) before the
+// mean is updated to include it.
+func (fc *FECController) observeRTT(rttMillis int) {
+	if rttMillis <= 0 {
+		return
+	}
+	sample := float64(rttMillis)
+	fc.lastRTTSpike = fc.rtt.haveSample && fc.rtt.mean > 0 && sample > fc.rttSpikeFactor*fc.rtt.mean
+
+	fc.rtt.count++
+	if !fc.rtt.haveSample {
+		fc.rtt.mean = sample
+		fc.rtt.haveSample = true
+		return
+	}
+	delta := sample - fc.rtt.mean
+	fc.rtt.mean += fc.rtt.alpha * delta
+	delta2 := sample - fc.rtt.mean
+	fc.rtt.m2 = (1 - fc.rtt.alpha) * (fc.rtt.m2 + fc.rtt.alpha*delta*delta2)
+}
+
+// Tick computes this interval's target parity ratio from the controller's
+// current loss/RTT estimates and, once the target has held for
+// hysteresisTicks consecutive Ticks, calls update with the new (k, r).
 func (fc *FECController) Tick() {
-	loss := fc.loss.Estimate()
-	if loss > 0.10 && fc.r < 12 {
-		fc.r += 2
-		fc.update(fc.k, fc.r, "loss>10%")
-	} else if loss > 0.03 && fc.r < 8 {
-		fc.r += 1
-		fc.update(fc.k, fc.r, "loss>3%")
-	} else if loss < 0.01 && fc.r > 2 {
-		fc.r -= 1
-		fc.update(fc.k, fc.r, "loss<1%")
+	lossMean := fc.loss.Mean()
+	safety := fc.safetyFactor * fc.loss.StdDev()
+	target := int(math.Ceil(float64(fc.k) * (lossMean + safety)))
+	if target < fc.rMin {
+		target = fc.rMin
+	}
+	if target > fc.rMax {
+		target = fc.rMax
+	}
+
+	reason := "loss_decrease"
+	switch {
+	case fc.lastRTTSpike:
+		reason = "rtt_spike"
+	case target > fc.r:
+		reason = "loss_increase"
+	case target < fc.r:
+		reason = "loss_decrease"
+	default:
+		reason = fc.pendingReason
+	}
+
+	if target == fc.pendingTarget {
+		fc.pendingStreak++
+	} else {
+		fc.pendingTarget = target
+		fc.pendingReason = reason
+		fc.pendingStreak = 1
+	}
+
+	if fc.metrics != nil {
+		fc.metrics.RecordFECAdaptation(lossMean, fc.rtt.Mean(), fc.k, fc.r, 0)
+	}
+	if fc.telemetry != nil {
+		fc.telemetry(lossMean, fc.rtt.Mean(), fc.k, fc.r)
+	}
+
+	if target == fc.r {
+		return
+	}
+	if fc.pendingStreak < fc.hysteresisTicks {
+		return
+	}
+
+	oldR := fc.r
+	fc.r = target
+	fc.pendingStreak = 0
+	if fc.metrics != nil {
+		fc.metrics.RecordFECAdaptation(lossMean, fc.rtt.Mean(), fc.k, fc.r, fc.r-oldR)
+	}
+	observability.TraceGlobal(observability.FacetFEC, "parity adapted", "k", fc.k, "old_r", oldR, "new_r", fc.r, "reason", reason, "loss_mean", lossMean)
+	if fc.update != nil {
+		fc.update(fc.k, fc.r, reason)
 	}
 }
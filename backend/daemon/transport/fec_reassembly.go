@@ -0,0 +1,125 @@
+package transport
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/quantarax/backend/internal/chunker"
+	"github.com/quantarax/backend/internal/fec"
+)
+
+// stripeBuffer accumulates one FEC stripe's shards (data and parity) as they
+// arrive, in whatever order the network delivers them.
+type stripeBuffer struct {
+	stripe chunker.FecStripe
+	shards [][]byte // len == stripe.K+stripe.M; index i<K is data chunk Start+i, i>=K is parity
+	have   int
+	// received marks which shards[i] arrived over the wire, as opposed to
+	// being filled in by a later Reconstruct call — ReceivedOnlyShards uses
+	// this to hand a fraud proof only evidence a verifier can independently
+	// re-derive from, never the disputed shard itself.
+	received []bool
+}
+
+// fecReassembler tracks one stripeBuffer per FecStripe a transfer's
+// receiver has seen at least one chunk for, so ChunkReceiver can recover
+// chunks a lossy path dropped without a retransmit round trip.
+type fecReassembler struct {
+	mu      sync.Mutex
+	stripes map[string]*stripeBuffer
+}
+
+func newFECReassembler() *fecReassembler {
+	return &fecReassembler{stripes: make(map[string]*stripeBuffer)}
+}
+
+// observe records chunkIndex's (already hash-verified, chunkSize-padded)
+// plaintext against its stripe and, once at least stripe.K shards have
+// arrived, attempts to reconstruct the rest. It returns the data chunk
+// indices (excluding chunkIndex itself) that were just recovered, so the
+// caller can run them through the normal verify/write/ACK path.
+func (a *fecReassembler) observe(stripe chunker.FecStripe, chunkIndex int64, paddedPlaintext []byte) ([]int64, error) {
+	a.mu.Lock()
+	sb, ok := a.stripes[stripe.StripeID]
+	if !ok {
+		sb = &stripeBuffer{stripe: stripe, shards: make([][]byte, stripe.K+stripe.M), received: make([]bool, stripe.K+stripe.M)}
+		a.stripes[stripe.StripeID] = sb
+	}
+	pos := int(chunkIndex) - stripe.Start
+	if pos < 0 || pos >= len(sb.shards) {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("fec stripe %s: chunk %d out of range", stripe.StripeID, chunkIndex)
+	}
+	if sb.shards[pos] == nil {
+		sb.shards[pos] = paddedPlaintext
+		sb.received[pos] = true
+		sb.have++
+	}
+	missing := len(sb.shards) - sb.have
+	if missing == 0 || sb.have < stripe.K {
+		a.mu.Unlock()
+		return nil, nil
+	}
+	shardsCopy := make([][]byte, len(sb.shards))
+	copy(shardsCopy, sb.shards)
+	a.mu.Unlock()
+
+	dec, err := fec.NewDecoder(stripe.K, stripe.M)
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.Reconstruct(shardsCopy); err != nil {
+		return nil, err
+	}
+
+	var recovered []int64
+	a.mu.Lock()
+	for i := 0; i < stripe.K; i++ {
+		if sb.shards[i] == nil && shardsCopy[i] != nil {
+			sb.shards[i] = shardsCopy[i]
+			sb.have++
+			if int(chunkIndex) != stripe.Start+i {
+				recovered = append(recovered, int64(stripe.Start+i))
+			}
+		}
+	}
+	a.mu.Unlock()
+	return recovered, nil
+}
+
+// ReceivedOnlyShards returns a copy of stripe's shard set with every slot
+// that was filled in by reconstruction (rather than genuinely received)
+// nulled back out, for use as chunker.ProveBadEncoding's receivedShards: a
+// fraud proof must rest only on bytes a third party can independently
+// re-derive, never on the disputed shard's own reconstructed value.
+func (a *fecReassembler) ReceivedOnlyShards(stripeID string) ([][]byte, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sb, ok := a.stripes[stripeID]
+	if !ok {
+		return nil, false
+	}
+	out := make([][]byte, len(sb.shards))
+	for i, s := range sb.shards {
+		if sb.received[i] {
+			out[i] = s
+		}
+	}
+	return out, true
+}
+
+// shardData returns the bytes stored for chunkIndex within stripe (arrived
+// or reconstructed), or nil if nothing is available yet.
+func (a *fecReassembler) shardData(stripe chunker.FecStripe, chunkIndex int64) []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sb, ok := a.stripes[stripe.StripeID]
+	if !ok {
+		return nil
+	}
+	pos := int(chunkIndex) - stripe.Start
+	if pos < 0 || pos >= len(sb.shards) {
+		return nil
+	}
+	return sb.shards[pos]
+}
@@ -0,0 +1,87 @@
+package transport
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultLeaseTTL is how long a chunk send gets before its lease
+	// expires if nothing refreshes it.
+	defaultLeaseTTL = 30 * time.Second
+
+	// defaultStopTimeout is how long Stop waits for in-flight leases to
+	// finish on their own before revoking them outright.
+	defaultStopTimeout = 10 * time.Second
+)
+
+// Lease bounds one in-flight sendChunk call with a refreshable deadline,
+// mirroring the renew-before-expiry pattern a distributed lock client uses
+// to hold a lock across a long operation: the holder calls Refresh
+// periodically to prove it's still making progress, and anyone else can
+// Revoke it to force an immediate, deterministic end instead of waiting for
+// a stuck peer to time out on its own.
+type Lease struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+// NewLease derives a cancelable context from parent and starts a timer that
+// cancels it after ttl unless Refresh is called first.
+func NewLease(parent context.Context, ttl time.Duration) *Lease {
+	if ttl <= 0 {
+		ttl = defaultLeaseTTL
+	}
+	ctx, cancel := context.WithCancel(parent)
+	l := &Lease{ctx: ctx, cancel: cancel, ttl: ttl}
+	l.timer = time.AfterFunc(ttl, cancel)
+	return l
+}
+
+// Context returns the lease's context, canceled on expiry, Revoke, or the
+// parent context's own cancellation.
+func (l *Lease) Context() context.Context {
+	return l.ctx
+}
+
+// Refresh extends the lease's deadline by ttl from now. It's a harmless
+// no-op once the lease has already expired, been revoked, or released.
+func (l *Lease) Refresh() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.timer == nil {
+		return
+	}
+	l.timer.Reset(l.ttl)
+}
+
+// Revoke cancels the lease's context immediately, stopping its timer first
+// so a concurrent Refresh can't resurrect it. Safe to call more than once.
+func (l *Lease) Revoke() {
+	l.stopTimer()
+	l.cancel()
+}
+
+// Release stops the lease's timer and cancels its context, for the common
+// case where the operation finished normally: nothing further needs to
+// observe Context().Done(), and canceling here (rather than leaving that to
+// the parent's own eventual cancellation) frees the context package's
+// bookkeeping for this lease right away.
+func (l *Lease) Release() {
+	l.stopTimer()
+	l.cancel()
+}
+
+func (l *Lease) stopTimer() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+}
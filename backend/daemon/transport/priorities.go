@@ -30,10 +30,24 @@ type ClassConfig struct {
 	Ack        AckStrategy
 	Streams    int // target parallel streams for this class
 	ChunkBytes int // preferred chunk size
+
+	// RetryBackoff, if set, overrides BackoffConfig/BackoffPolicy for this
+	// class's failed sends with an error/Retry-After-aware policy (see
+	// RetryBackoff's doc comment) — e.g. a rural/disaster profile plugging
+	// in a longer, more patient backoff without touching the core send
+	// loop. Left nil, pools fall back to the existing attempt-indexed
+	// BackoffConfig/BackoffPolicy unchanged.
+	RetryBackoff RetryBackoff
 }
 
 // DomainTransportProfile captures per-domain class configs
 
 type DomainTransportProfile struct {
 	P0, P1, P2 ClassConfig
+
+	// Backoff governs retry delay for NACKed bulk chunk re-enqueue on this
+	// domain's transfers (see OrchestratedSender.ServeChunkRequests). A
+	// zero-value Backoff (Multiplier <= 0) falls back to
+	// DefaultBackoffPolicy via BackoffPolicy.Duration.
+	Backoff BackoffPolicy
 }
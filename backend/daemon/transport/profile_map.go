@@ -1,40 +1,56 @@
 package transport
 
-import "github.com/quantarax/backend/internal/chunker"
+import (
+	"time"
+
+	"github.com/quantarax/backend/internal/chunker"
+)
 
 // ProfileForDomain returns transport class configs for a given domain.
 func ProfileForDomain(domain string, manifest *chunker.Manifest) DomainTransportProfile {
 	switch domain {
 	case "racetrack_factory":
-		// Telemetry highest priority, video headers next, bulk last
+		// Telemetry highest priority, video headers next, bulk last. Low,
+		// tight backoff: the path is assumed local/reliable, so a NACK is
+		// more likely transient congestion than sustained loss.
 		return DomainTransportProfile{
 			P0: ClassConfig{Ack: AckImmediate,  Streams: 4, ChunkBytes: 512*1024},  // telemetry
 			P1: ClassConfig{Ack: AckDelayed10ms, Streams: 2, ChunkBytes: 512*1024},  // headers/keyframes
 			P2: ClassConfig{Ack: AckDelayed25ms, Streams: 6, ChunkBytes: 1024*1024}, // bulk video
+			Backoff: BackoffPolicy{Initial: 100 * time.Millisecond, Max: 2 * time.Second, Multiplier: 2.0, Jitter: 0.2, MaxAttempts: 8},
 		}
 	case "media":
 		return DomainTransportProfile{
 			P0: ClassConfig{Ack: AckDelayed10ms, Streams: 1,  ChunkBytes: manifest.ChunkSize}, // control-like
 			P1: ClassConfig{Ack: AckDelayed10ms, Streams: 8,  ChunkBytes: 1024*1024},           // headers/keyframes
 			P2: ClassConfig{Ack: AckDelayed25ms, Streams: 8,  ChunkBytes: 4*1024*1024},         // bulk video
+			Backoff: BackoffPolicy{Initial: 250 * time.Millisecond, Max: 10 * time.Second, Multiplier: 2.0, Jitter: 0.2, MaxAttempts: 10},
 		}
 	case "engineering":
 		return DomainTransportProfile{
 			P0: ClassConfig{Ack: AckDelayed10ms, Streams: 1, ChunkBytes: manifest.ChunkSize},
 			P1: ClassConfig{Ack: AckDelayed25ms, Streams: 4, ChunkBytes: 256*1024},
 			P2: ClassConfig{Ack: AckDelayed25ms, Streams: 4, ChunkBytes: 256*1024},
+			Backoff: BackoffPolicy{Initial: 250 * time.Millisecond, Max: 10 * time.Second, Multiplier: 2.0, Jitter: 0.2, MaxAttempts: 10},
 		}
 	case "medical":
+		// Custody matters more than speed here: retry patiently rather
+		// than giving up and losing a chunk of a medical record.
 		return DomainTransportProfile{
 			P0: ClassConfig{Ack: AckImmediate,   Streams: 1, ChunkBytes: manifest.ChunkSize}, // control
 			P1: ClassConfig{Ack: AckImmediate,   Streams: 2, ChunkBytes: 256*1024},
 			P2: ClassConfig{Ack: AckDelayed10ms,  Streams: 2, ChunkBytes: 256*1024},
+			Backoff: BackoffPolicy{Initial: 500 * time.Millisecond, Max: 30 * time.Second, Multiplier: 2.0, Jitter: 0.2, MaxAttempts: 20},
 		}
 	case "disaster":
+		// Paths in this domain are assumed lossy and intermittent; back
+		// off further and more patiently than the default so retries
+		// don't pile onto an already-degraded link.
 		return DomainTransportProfile{
 			P0: ClassConfig{Ack: AckDelayed10ms, Streams: 1, ChunkBytes: 256*1024}, // thumbs/metadata
 			P1: ClassConfig{Ack: AckDelayed10ms, Streams: 1, ChunkBytes: 256*1024},
-			P2: ClassConfig{Ack: AckDelayed10ms, Streams: 2, ChunkBytes: 256*1024},
+			P2: ClassConfig{Ack: AckDelayed10ms, Streams: 2, ChunkBytes: 256*1024, RetryBackoff: PatientRetryBackoff},
+			Backoff: BackoffPolicy{Initial: 1 * time.Second, Max: 60 * time.Second, Multiplier: 2.0, Jitter: 0.3, MaxAttempts: 0},
 		}
 	case "rural":
 		fallthrough
@@ -42,7 +58,8 @@ func ProfileForDomain(domain string, manifest *chunker.Manifest) DomainTransport
 		return DomainTransportProfile{
 			P0: ClassConfig{Ack: AckDelayed10ms, Streams: 1, ChunkBytes: 384*1024},
 			P1: ClassConfig{Ack: AckDelayed10ms, Streams: 1, ChunkBytes: 384*1024},
-			P2: ClassConfig{Ack: AckDelayed10ms, Streams: 2, ChunkBytes: 384*1024},
+			P2: ClassConfig{Ack: AckDelayed10ms, Streams: 2, ChunkBytes: 384*1024, RetryBackoff: PatientRetryBackoff},
+			Backoff: DefaultBackoffPolicy(),
 		}
 	}
 }
@@ -3,7 +3,9 @@ package transport
 import (
 	"context"
 	"crypto/tls"
+	"time"
 
+	"github.com/quantarax/backend/internal/observability"
 	"github.com/quic-go/quic-go"
 )
 
@@ -12,6 +14,14 @@ type QUICConnection struct {
 	conn          *quic.Conn
 	controlStream *ControlStream
 	scheduler     *PriorityScheduler
+	datagramMux   *DatagramMux
+
+	// bwMeter and sessionID back Stats(); both are set by
+	// NewOrchestratedSender once a transfer starts, so a QUICConnection
+	// accepted or dialed before any send is attached reports a zeroed
+	// TransportStats rather than erroring.
+	bwMeter   *BandwidthMeter
+	sessionID string
 }
 
 // NewQUICConnection creates a new QUIC connection wrapper
@@ -31,7 +41,7 @@ func (q *QUICConnection) OpenControlStream(ctx context.Context) (*ControlStream,
 		return nil, err
 	}
 
-	q.controlStream = NewControlStream(stream)
+	q.controlStream = NewControlStream(stream, q.conn)
 	return q.controlStream, nil
 }
 
@@ -42,7 +52,7 @@ func (q *QUICConnection) AcceptControlStream(ctx context.Context) (*ControlStrea
 		return nil, err
 	}
 
-	q.controlStream = NewControlStream(stream)
+	q.controlStream = NewControlStream(stream, q.conn)
 	return q.controlStream, nil
 }
 
@@ -61,6 +71,97 @@ func (q *QUICConnection) Scheduler() *PriorityScheduler {
 	return q.scheduler
 }
 
+// SetBandwidthMeter attaches the meter Stats() reports from. Called by
+// NewOrchestratedSender with the meter it shares across every class's
+// pool, so Stats() reflects the same counters StartPeriodicPublish and
+// StartPeriodicLogging already draw from.
+func (q *QUICConnection) SetBandwidthMeter(m *BandwidthMeter) {
+	q.bwMeter = m
+}
+
+// SetSessionID attaches the session UUID string Stats() reports, for a
+// caller juggling several QUICConnections to tell their TransportStats
+// apart without threading the ID through separately.
+func (q *QUICConnection) SetSessionID(sessionID string) {
+	q.sessionID = sessionID
+}
+
+// TransportStats is a point-in-time snapshot of raw (on-wire) and useful
+// (payload) bytes moved over a QUICConnection, broken down by
+// PriorityClass, as reported by Stats.
+type TransportStats struct {
+	SessionID string
+	RawOut    int64
+	RawIn     int64
+	UsefulOut int64
+	ByClass   map[PriorityClass]ClassBandwidth
+}
+
+// Stats reports the connection's current bandwidth counters. It's a
+// zeroed TransportStats if no BandwidthMeter has been attached yet (see
+// SetBandwidthMeter) — most often because no transfer has started on this
+// connection.
+func (q *QUICConnection) Stats() TransportStats {
+	if q.bwMeter == nil {
+		return TransportStats{SessionID: q.sessionID}
+	}
+	rawOut, rawIn, usefulOut := q.bwMeter.Snapshot()
+	return TransportStats{
+		SessionID: q.sessionID,
+		RawOut:    rawOut,
+		RawIn:     rawIn,
+		UsefulOut: usefulOut,
+		ByClass:   q.bwMeter.SnapshotByClass(),
+	}
+}
+
+// SendDatagram sends payload as an unreliable, unordered QUIC datagram
+// (RFC 9221), bypassing every reliable stream's head-of-line blocking
+// entirely. priority is accepted for future congestion-aware pacing but
+// isn't consulted yet — see DatagramMux.Send, the usual way callers reach
+// this. Returns an error if the peer didn't negotiate datagram support or
+// payload exceeds MaxDatagramSize.
+func (q *QUICConnection) SendDatagram(payload []byte, priority PriorityClass) error {
+	return q.conn.SendDatagram(payload)
+}
+
+// ReceiveDatagram blocks for the next unreliable QUIC datagram the peer
+// sends, or until ctx is done. Most callers want Datagrams() instead: only
+// one reader can call ReceiveDatagram on a given connection, so every
+// consumer needs to come through the same DatagramMux.Run loop rather than
+// racing its own ReceiveDatagram call.
+func (q *QUICConnection) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	return q.conn.ReceiveDatagram(ctx)
+}
+
+// SupportsDatagrams reports whether this connection negotiated QUIC
+// DATAGRAM (RFC 9221) support with its peer — false if either side
+// declared max_datagram_frame_size = 0, in which case SendDatagram always
+// fails and callers should fall back to a reliable stream instead.
+func (q *QUICConnection) SupportsDatagrams() bool {
+	return q.conn.ConnectionState().SupportsDatagrams
+}
+
+// MaxDatagramSize returns the largest payload SendDatagram can carry,
+// matching ControlStream.MaxDatagramSize's conservative ceiling (a single
+// QUIC packet's worth, comfortably under typical path MTUs) since both
+// ultimately share the same connection's datagram frames.
+func (q *QUICConnection) MaxDatagramSize() int {
+	return maxDatagramPayloadSize
+}
+
+// Datagrams returns the connection's DatagramMux, creating it on first
+// use so every category's consumer shares the one Run loop reading off
+// the connection. Callers still need to start Run themselves (typically
+// `go conn.Datagrams().Run(ctx)` once per connection) before any
+// Subscribe channel starts receiving.
+func (q *QUICConnection) Datagrams() *DatagramMux {
+	if q.datagramMux == nil {
+		q.datagramMux = NewDatagramMux(q)
+	}
+	return q.datagramMux
+}
+
 // Close closes the QUIC connection
 func (q *QUICConnection) Close() error {
 	if q.controlStream != nil {
@@ -79,6 +180,7 @@ func DialQUIC(ctx context.Context, addr string, tlsConfig *tls.Config) (*QUICCon
 		MaxIdleTimeout:                 60 * 1e9,
 		InitialStreamReceiveWindow:     8 << 20,   // 8 MiB
 		InitialConnectionReceiveWindow: 128 << 20, // 128 MiB
+		EnableDatagrams:                true,
 	})
 	if err != nil {
 		return nil, err
@@ -87,6 +189,32 @@ func DialQUIC(ctx context.Context, addr string, tlsConfig *tls.Config) (*QUICCon
 	return NewQUICConnection(conn), nil
 }
 
+// DialQUICWithBackoff retries DialQUIC against addr, waiting
+// policy.Duration(attempt) between each failed attempt, until it succeeds,
+// ctx is done, or policy.Exhausted(attempt). It's a separate entry point
+// rather than a change to DialQUIC's own signature, the same way
+// faultproxy.WithFaultProxy wraps DialQUIC instead of adding a parameter
+// every existing caller would have to thread through.
+func DialQUICWithBackoff(ctx context.Context, addr string, tlsConfig *tls.Config, policy BackoffPolicy) (*QUICConnection, error) {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		conn, err := DialQUIC(ctx, addr, tlsConfig)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		observability.TraceGlobal(observability.FacetQUIC, "dial retry", "addr", addr, "attempt", attempt, "error", err.Error())
+		if policy.Exhausted(attempt) {
+			return nil, lastErr
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(policy.Duration(attempt)):
+		}
+	}
+}
+
 // ListenQUIC starts a QUIC listener
 func ListenQUIC(addr string, tlsConfig *tls.Config) (*QUICListener, error) {
 	listener, err := quic.ListenAddr(addr, tlsConfig, &quic.Config{
@@ -94,6 +222,7 @@ func ListenQUIC(addr string, tlsConfig *tls.Config) (*QUICListener, error) {
 		MaxIdleTimeout:                 60 * 1e9,
 		InitialStreamReceiveWindow:     8 << 20,
 		InitialConnectionReceiveWindow: 128 << 20,
+		EnableDatagrams:                true,
 	})
 	if err != nil {
 		return nil, err
@@ -2,26 +2,90 @@ package transport
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/quantarax/backend/daemon/manager"
+	"github.com/quantarax/backend/internal/chunker"
 )
 
-// ZeroLossVerifier enforces strict completion policy for Medical domain.
-// Placeholder: tracks missing chunks and requires all present before completion.
+// ZeroLossVerifier enforces the Medical/strict-domain completion policy
+// with an authenticated completion proof instead of a bare received-index
+// count: at session start the sender announces total, a chunk size, and
+// the root of a Merkle tree over per-chunk hashes (see ZeroLossProver),
+// and every chunk must pass MarkReceived's inclusion-proof check against
+// that root before its bit is allowed to flip. Because each proof is
+// checked individually against the announced root, VerifyComplete's "all
+// bits set" condition already implies the reconstructed tree matches that
+// root — there is nothing left to recompute, so the verifier never holds
+// more than the current chunk's O(log total) proof hashes at a time.
 type ZeroLossVerifier struct {
-	total   int
-	recvd   map[int]bool
+	total int
+	root  []byte
+	bits  *manager.ChunkBitmap
 }
 
-func NewZeroLossVerifier(totalChunks int) *ZeroLossVerifier {
-	return &ZeroLossVerifier{total: totalChunks, recvd: make(map[int]bool)}
+// NewZeroLossVerifier creates a verifier for a session of totalChunks
+// chunks that checks every inclusion proof against root, the Merkle root
+// the sender announced at session start (see ZeroLossProver.Root).
+func NewZeroLossVerifier(sessionID string, totalChunks int, root []byte) *ZeroLossVerifier {
+	return &ZeroLossVerifier{
+		total: totalChunks,
+		root:  root,
+		bits:  manager.NewChunkBitmap(sessionID, int64(totalChunks)),
+	}
 }
 
-func (z *ZeroLossVerifier) MarkReceived(idx int) {
-	z.recvd[idx] = true
+// MarkReceived verifies chunkHash's inclusion proof against the announced
+// root before flipping idx's bit in the bitmap. A chunk whose proof
+// doesn't verify is not marked received, so VerifyComplete keeps
+// reporting the transfer incomplete until it's retransmitted and
+// re-verified.
+func (z *ZeroLossVerifier) MarkReceived(idx int, chunkHash []byte, proof [][]byte) error {
+	if idx < 0 || idx >= z.total {
+		return fmt.Errorf("chunk index %d out of range for %d chunks", idx, z.total)
+	}
+	if !chunker.VerifyProof(chunkHash, idx, proof, z.root) {
+		return fmt.Errorf("chunk %d failed Merkle inclusion proof verification", idx)
+	}
+	return z.bits.SetChunk(int64(idx))
 }
 
+// VerifyComplete reports the transfer complete only once every chunk's
+// bit is set, each having already passed its own inclusion-proof check in
+// MarkReceived against the announced root.
 func (z *ZeroLossVerifier) VerifyComplete() error {
-	if len(z.recvd) != z.total {
+	if !z.bits.IsComplete() {
 		return errors.New("verification failed: missing chunks in strict mode")
 	}
 	return nil
 }
+
+// ZeroLossProver is the sender-side counterpart to ZeroLossVerifier: it
+// builds the full Merkle tree once from the manifest's chunk hashes and
+// answers GenerateProof per chunk as it's sent, so the receiver only ever
+// needs that chunk's sibling-hash path, never the whole tree.
+type ZeroLossProver struct {
+	tree *chunker.MerkleTree
+}
+
+// NewZeroLossProver builds the Merkle tree over chunkHashes (base64-
+// encoded, one per chunk in order) that GenerateProof and Root serve from.
+func NewZeroLossProver(chunkHashes []string) (*ZeroLossProver, error) {
+	tree, err := chunker.BuildMerkleTree(chunkHashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build zero-loss Merkle tree: %w", err)
+	}
+	return &ZeroLossProver{tree: tree}, nil
+}
+
+// Root returns the tree's root hash, to be announced to the receiver at
+// session start alongside total chunk count and chunk size.
+func (p *ZeroLossProver) Root() []byte {
+	return p.tree.Root()
+}
+
+// GenerateProof returns the inclusion proof for chunk idx, to be sent
+// alongside that chunk for ZeroLossVerifier.MarkReceived to check.
+func (p *ZeroLossProver) GenerateProof(idx int) ([][]byte, error) {
+	return p.tree.ProofFor(idx)
+}
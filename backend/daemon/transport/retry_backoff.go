@@ -0,0 +1,116 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/quantarax/backend/internal/crypto"
+)
+
+// RetryBackoff decides how long to wait before retrying a failed chunk
+// send, handshake attempt, or control-channel NACK re-enqueue. attempt is
+// 0-indexed (the delay before the first retry). lastErr is the error the
+// failed attempt returned, or a representative one synthesized from a
+// NackMessage.Reason for NACK-driven retries (see nackReasonError). hint,
+// if non-zero, is a Retry-After-style delay the peer asked for (e.g. a
+// congestion-control backoff or a "bad nonce" rekey request) that should
+// take priority over the policy's own computed delay.
+//
+// Returning <= 0 tells the caller to stop retrying — the convention for a
+// permanent failure (bad AAD, auth tag mismatch) that no amount of waiting
+// will fix — rather than a transient one (timeout, backpressure,
+// nonce-resync) that should keep retrying. ClassConfig.RetryBackoff is
+// this hook's plug-in point; a nil value leaves BackoffConfig/BackoffPolicy
+// in charge, unchanged.
+type RetryBackoff func(attempt int, lastErr error, hint time.Duration) time.Duration
+
+// retryBackoffMaxDelay caps DefaultRetryBackoff's computed (non-hinted)
+// delay, truncating the exponential series before it grows unreasonably
+// long.
+const retryBackoffMaxDelay = 10 * time.Second
+
+// DefaultRetryBackoff is used wherever a ClassConfig leaves RetryBackoff
+// unset but a caller still wants the richer error/hint-aware policy rather
+// than BackoffConfig/BackoffPolicy's plain attempt-indexed curve. The nth
+// retry waits min(2^n seconds, 10s) plus up to 1 second of jitter, or
+// hint plus that same jitter if hint is set, unless lastErr is a permanent
+// failure (see IsPermanentFailure), in which case it returns 0 to stop.
+func DefaultRetryBackoff(attempt int, lastErr error, hint time.Duration) time.Duration {
+	if IsPermanentFailure(lastErr) {
+		return 0
+	}
+
+	delay := hint
+	if delay <= 0 {
+		if attempt < 0 {
+			attempt = 0
+		}
+		delay = time.Duration(math.Pow(2, float64(attempt))) * time.Second
+		if delay > retryBackoffMaxDelay {
+			delay = retryBackoffMaxDelay
+		}
+	}
+	return delay + time.Duration(rand.Int63n(int64(time.Second)))
+}
+
+// patientRetryBackoffMaxDelay is PatientRetryBackoff's cap in place of
+// retryBackoffMaxDelay, for disaster/rural profiles where the path is
+// assumed intermittent rather than merely congested, so retries should
+// space out further before giving the link a chance to recover.
+const patientRetryBackoffMaxDelay = 60 * time.Second
+
+// PatientRetryBackoff is DefaultRetryBackoff with a longer cap and wider
+// jitter, for the disaster/rural DomainTransportProfiles (see
+// ProfileForDomain) to plug into ClassConfig.RetryBackoff without touching
+// the core send loop.
+func PatientRetryBackoff(attempt int, lastErr error, hint time.Duration) time.Duration {
+	if IsPermanentFailure(lastErr) {
+		return 0
+	}
+
+	delay := hint
+	if delay <= 0 {
+		if attempt < 0 {
+			attempt = 0
+		}
+		delay = time.Duration(math.Pow(2, float64(attempt))) * time.Second
+		if delay > patientRetryBackoffMaxDelay {
+			delay = patientRetryBackoffMaxDelay
+		}
+	}
+	return delay + time.Duration(rand.Int63n(int64(2*time.Second)))
+}
+
+// ErrPermanentRetryFailure lets a send path signal a non-retryable failure
+// to a RetryBackoff hook without needing a more specific sentinel, the same
+// role crypto.ErrAuthenticationFailed already plays for AEAD open failures.
+var ErrPermanentRetryFailure = errors.New("transport: permanent failure, do not retry")
+
+// IsPermanentFailure reports whether err represents a failure class a
+// RetryBackoff hook should stop retrying on: an AEAD authentication
+// failure (bad AAD, tampered or misaligned ciphertext — retrying with the
+// same key and nonce sequence can never succeed) or ErrPermanentRetryFailure.
+// Anything else — timeouts, backpressure, nonce-resync requests — is
+// treated as transient.
+func IsPermanentFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, crypto.ErrAuthenticationFailed) || errors.Is(err, ErrPermanentRetryFailure)
+}
+
+// nackReasonError maps a NackMessage.Reason to a representative error, so
+// a RetryBackoff hook can apply the same permanent-vs-transient judgment to
+// a NACK-driven re-enqueue that it would to a local send error, even though
+// a NACK reason arrives as a string rather than a Go error.
+func nackReasonError(reason string) error {
+	switch reason {
+	case "decrypt_failed":
+		return crypto.ErrAuthenticationFailed
+	default:
+		return fmt.Errorf("transport: nack reason %q", reason)
+	}
+}
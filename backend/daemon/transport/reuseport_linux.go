@@ -0,0 +1,97 @@
+//go:build linux
+
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/quic-go/quic-go"
+	"golang.org/x/sys/unix"
+)
+
+// ListenQUICReusable behaves like ListenQUIC, but binds its UDP socket with
+// SO_REUSEPORT and also returns an *os.File duplicating that socket's file
+// descriptor. A graceful restart (daemon/main.go's
+// daemonAdminController.Restart) re-execs the daemon binary with that fd
+// number passed via an environment variable, so the re-exec'd process can
+// rebuild a PacketConn around the same bound socket via
+// ListenQUICFromInheritedFD instead of racing the old process to rebind
+// addr.
+func ListenQUICReusable(addr string, tlsConfig *tls.Config) (*QUICListener, *os.File, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			if err := c.Control(func(fd uintptr) {
+				sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			}); err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+	pconn, err := lc.ListenPacket(context.Background(), "udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// File() dup's the socket's fd; Go marks the dup close-on-exec, so the
+	// flag is cleared below to let it survive the restart's syscall.Exec.
+	// Closing file later does not affect pconn.
+	file, err := pconn.(*net.UDPConn).File()
+	if err != nil {
+		pconn.Close()
+		return nil, nil, err
+	}
+	if _, err := unix.FcntlInt(file.Fd(), unix.F_SETFD, 0); err != nil {
+		file.Close()
+		pconn.Close()
+		return nil, nil, err
+	}
+
+	listener, err := quic.Listen(pconn, tlsConfig, defaultQUICConfig())
+	if err != nil {
+		file.Close()
+		pconn.Close()
+		return nil, nil, err
+	}
+
+	return &QUICListener{listener: listener}, file, nil
+}
+
+// ListenQUICFromInheritedFD rebuilds a QUICListener around a UDP socket
+// inherited (via file descriptor fd, unclosed across the parent's
+// syscall.Exec) from a prior process that bound it with
+// ListenQUICReusable.
+func ListenQUICFromInheritedFD(fd uintptr, tlsConfig *tls.Config) (*QUICListener, *os.File, error) {
+	file := os.NewFile(fd, "quic-listen")
+	if file == nil {
+		return nil, nil, os.ErrInvalid
+	}
+	pconn, err := net.FilePacketConn(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	listener, err := quic.Listen(pconn, tlsConfig, defaultQUICConfig())
+	if err != nil {
+		pconn.Close()
+		return nil, nil, err
+	}
+	return &QUICListener{listener: listener}, file, nil
+}
+
+// defaultQUICConfig is the quic.Config ListenQUICReusable and
+// ListenQUICFromInheritedFD listen with. ListenQUIC builds its own inline
+// quic.Config with the same values.
+func defaultQUICConfig() *quic.Config {
+	return &quic.Config{
+		KeepAlivePeriod:                10 * 1e9,
+		MaxIdleTimeout:                 60 * 1e9,
+		InitialStreamReceiveWindow:     8 << 20,
+		InitialConnectionReceiveWindow: 128 << 20,
+		EnableDatagrams:                true,
+	}
+}
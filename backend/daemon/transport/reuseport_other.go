@@ -0,0 +1,24 @@
+//go:build !linux
+
+package transport
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+)
+
+// ErrReusePortUnsupported is returned by ListenQUICReusable and
+// ListenQUICFromInheritedFD on platforms other than Linux, where
+// SO_REUSEPORT either isn't available or isn't wired up here. Callers fall
+// back to plain ListenQUIC, the same graceful-restart limitation
+// daemonAdminController.Restart already documents for the fd-handoff gap.
+var ErrReusePortUnsupported = errors.New("transport: SO_REUSEPORT listener handoff is only implemented on linux")
+
+func ListenQUICReusable(addr string, tlsConfig *tls.Config) (*QUICListener, *os.File, error) {
+	return nil, nil, ErrReusePortUnsupported
+}
+
+func ListenQUICFromInheritedFD(fd uintptr, tlsConfig *tls.Config) (*QUICListener, *os.File, error) {
+	return nil, nil, ErrReusePortUnsupported
+}
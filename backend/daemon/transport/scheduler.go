@@ -3,6 +3,7 @@ package transport
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/quic-go/quic-go"
 )
@@ -53,6 +54,20 @@ func (qs *PriorityScheduler) Enqueue(class PriorityClass, fn func(context.Contex
 	qs.queues[class] <- fn
 }
 
+// EnqueueWithExpiry is like Enqueue, but drops fn instead of running it if
+// expires has already passed by the time it reaches the front of its class's
+// queue. This lets short-lived work (e.g. a single fragment) be submitted
+// alongside long bulk work without a backed-up queue forcing it to run late
+// anyway; a zero expires means "never expires", same as Enqueue.
+func (qs *PriorityScheduler) EnqueueWithExpiry(class PriorityClass, expires time.Time, fn func(context.Context)) {
+	qs.Enqueue(class, func(ctx context.Context) {
+		if !expires.IsZero() && time.Now().After(expires) {
+			return
+		}
+		fn(ctx)
+	})
+}
+
 func (qs *PriorityScheduler) Close() {
 	for _, q := range qs.queues {
 		close(q)
@@ -2,29 +2,96 @@ package transport
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"path/filepath"
 
 	"github.com/google/uuid"
+	"github.com/quantarax/backend/daemon/manager"
+	"github.com/quantarax/backend/internal/chunker"
+	"github.com/quantarax/backend/internal/chunkreader"
 	"github.com/quantarax/backend/internal/crypto"
+	"github.com/quantarax/backend/internal/observability"
 )
 
 // OrchestratedSender manages per-class worker pools and control routing.
 type OrchestratedSender struct {
-	conn   *QUICConnection
-	pools  map[PriorityClass]*ChunkWorkerPool
+	conn    *QUICConnection
+	pools   map[PriorityClass]*ChunkWorkerPool
+	reader  chunkreader.RangeReader
+	bwMeter *BandwidthMeter
+
+	// snapshotHandler, if set via SetSnapshotHandler, answers SnapshotRequest
+	// messages (statesync's resumable-transfer snapshots) received on the
+	// control stream. Left nil, SnapshotRequest messages are ignored, same
+	// as an unset onChunkFailed callback elsewhere in this package.
+	snapshotHandler func(*SnapshotRequest) (*SnapshotResponse, error)
+
+	// adminHandler, if set via SetAdminHandler, answers AdminCommand
+	// messages (operator-triggered restart/pause/resume/set-config/
+	// rotate-keys) received on the control stream. Left nil, AdminCommand
+	// messages get a generic "admin not configured" AdminAck, the same
+	// disabled-by-default posture config.Config.AdminPubKeys being empty
+	// already gives an unset allowlist. Injected as a plain function value
+	// rather than imported directly, the same reason SetSnapshotHandler
+	// takes one: this package can't import daemon/service (service already
+	// imports transport).
+	adminHandler func(*AdminCommand) *AdminAck
+
+	// nackedChunks counts chunk indices named in a NackMessage seen by
+	// ServeChunkRequests, a proxy for packets lost on the path to this
+	// sender's receiver. FECController.Observe reads it via NackedCount to
+	// drive its loss EWMA.
+	nackedChunks int64
+
+	// backoff paces NACK re-enqueue (see reenqueueNacked); nackAttempts
+	// tracks how many times each chunk index has been NACKed so repeated
+	// NACKs for the same chunk back off further instead of retrying at a
+	// fixed rate.
+	backoff      BackoffPolicy
+	nackMu       sync.Mutex
+	nackAttempts map[int64]int
+
+	// retryBackoff, taken from profile.P2.RetryBackoff since NACK
+	// re-enqueue always lands chunks back on the bulk P2 pool (see
+	// EnqueueBulk), overrides backoff entirely when set — see
+	// reenqueueNacked.
+	retryBackoff RetryBackoff
 }
 
 // NewOrchestratedSender creates worker pools for P0/P1/P2 according to the domain profile.
 func NewOrchestratedSender(conn *QUICConnection, profile DomainTransportProfile, sessionKeys *crypto.SessionKeys, sessionID uuid.UUID, filePath string, baseChunkSize int64, onChunkSent func(idx int64), onChunkFailed func(idx int64, err error)) *OrchestratedSender {
+	// One RangeReader is shared across every class's pool so the source file
+	// is opened once for the transfer's lifetime rather than once per pool.
+	reader, err := chunkreader.New("pread", filePath, baseChunkSize, 0)
+	if err != nil {
+		// Pools fail their first read the same way os.Open used to fail
+		// theirs; there is no existing error-return path out of this
+		// constructor to surface it earlier.
+		reader = nil
+	}
+	// bwMeter is shared across every class's pool so operators get one
+	// combined raw/useful byte count per transfer rather than one per class.
+	// It's also attached to conn so (*QUICConnection).Stats() reports the
+	// same counters without callers needing a handle on this sender.
+	bwMeter := NewBandwidthMeter()
+	conn.SetBandwidthMeter(bwMeter)
+	conn.SetSessionID(sessionID.String())
 	pools := make(map[PriorityClass]*ChunkWorkerPool)
 	mk := func(class PriorityClass, cfg ClassConfig) *ChunkWorkerPool {
 		workers := cfg.Streams
 		if workers <= 0 { workers = 1 }
 		chunkSize := baseChunkSize
 		if cfg.ChunkBytes > 0 { chunkSize = int64(cfg.ChunkBytes) }
-	p := NewChunkWorkerPool(workers, 1024, conn.GetConnection(), sessionKeys, sessionID, filePath, chunkSize, onChunkSent, onChunkFailed)
+	p := NewChunkWorkerPool(workers, 1024, conn.GetConnection(), sessionKeys, sessionID, reader, chunkSize, onChunkSent, onChunkFailed)
 		p.SetScheduler(conn.Scheduler(), class)
+		p.SetBandwidthMeter(bwMeter)
+		p.SetControlStream(conn.GetControlStream())
+		p.SetRetryBackoff(cfg.RetryBackoff)
 		return p
 	}
 	// Override class chunk sizes with BDP-based sizing where network info available
@@ -33,7 +100,100 @@ func NewOrchestratedSender(conn *QUICConnection, profile DomainTransportProfile,
 	pools[PriorityP2] = mk(PriorityP2, profile.P2)
 	// Start pools
 	for _, p := range pools { p.Start() }
-	return &OrchestratedSender{conn: conn, pools: pools}
+	return &OrchestratedSender{
+		conn:         conn,
+		pools:        pools,
+		reader:       reader,
+		bwMeter:      bwMeter,
+		backoff:      profile.Backoff,
+		nackAttempts: make(map[int64]int),
+		retryBackoff: profile.P2.RetryBackoff,
+	}
+}
+
+// BandwidthMeter returns the meter shared by every class's pool, so callers
+// can read a snapshot or start periodic publishing via
+// BandwidthMeter().StartPeriodicPublish.
+func (s *OrchestratedSender) BandwidthMeter() *BandwidthMeter {
+	return s.bwMeter
+}
+
+// NackedCount returns the cumulative number of chunk indices this sender
+// has been NACKed for, for a caller (e.g. an FECController.Observe loop)
+// to diff across an interval as a packet-loss proxy.
+func (s *OrchestratedSender) NackedCount() int64 {
+	return atomic.LoadInt64(&s.nackedChunks)
+}
+
+// EnableFEC layers manifest's FEC parity blob (written to parityPath by
+// chunker.ApplyFECScheme) on top of the transfer's source file, so parity
+// chunk indices recorded in manifest.FecStripes become readable through the
+// same chunkIndex*chunkSize addressing every pool's reader already uses for
+// data chunks. It's a no-op when manifest has no FecStripes. Call it once,
+// right after NewOrchestratedSender and before enqueuing any parity chunk
+// index.
+func (s *OrchestratedSender) EnableFEC(manifest *chunker.Manifest, parityPath string) error {
+	if len(manifest.FecStripes) == 0 {
+		return nil
+	}
+	parityReader, err := chunkreader.New("pread", parityPath, int64(manifest.ChunkSize), 0)
+	if err != nil {
+		return fmt.Errorf("fec: open parity sidecar: %w", err)
+	}
+	dataSize := int64(manifest.DataChunkCount()) * int64(manifest.ChunkSize)
+	fecReader := chunkreader.NewFECReader(s.reader, parityReader, dataSize)
+	s.reader = fecReader
+	for _, p := range s.pools {
+		p.SetReader(fecReader)
+	}
+	return nil
+}
+
+// SetFaultInjector attaches chaos behavior to every class's pool, for
+// reproducing unstable-network conditions in development and tests.
+func (s *OrchestratedSender) SetFaultInjector(f *FaultInjector) {
+	for _, p := range s.pools {
+		p.SetFaultInjector(f)
+	}
+}
+
+// SetBackoff configures in-pool retry backoff for every class's pool.
+func (s *OrchestratedSender) SetBackoff(cfg BackoffConfig) {
+	for _, p := range s.pools {
+		p.SetBackoff(cfg)
+	}
+}
+
+// Pause halts every class's pool mid-transfer (see ChunkWorkerPool.Pause),
+// for an admin-triggered PauseTransfers command over the control stream.
+func (s *OrchestratedSender) Pause() {
+	for _, p := range s.pools {
+		p.Pause()
+	}
+}
+
+// Resume reverses a prior Pause.
+func (s *OrchestratedSender) Resume() {
+	for _, p := range s.pools {
+		p.Resume()
+	}
+}
+
+// SetDatagramThreshold enables every class's pool's unreliable-datagram fast
+// path for chunks at or below bytes, same as ChunkWorkerPool.SetDatagramThreshold.
+func (s *OrchestratedSender) SetDatagramThreshold(bytes int64) {
+	for _, p := range s.pools {
+		p.SetDatagramThreshold(bytes)
+	}
+}
+
+// SetMetrics attaches the daemon's observability.Metrics to the control
+// stream, so the datagram fast path's sends, receives, and drops get
+// recorded regardless of which class's pool used it.
+func (s *OrchestratedSender) SetMetrics(m *observability.Metrics) {
+	if ctrl := s.conn.GetControlStream(); ctrl != nil {
+		ctrl.SetMetrics(m)
+	}
 }
 
 // EnqueueControl schedules a control task on P0.
@@ -43,17 +203,254 @@ func (s *OrchestratedSender) EnqueueControl(fn func(context.Context)) {
 
 // EnqueuePreview schedules a chunk index on P1 for headers/preview frames.
 func (s *OrchestratedSender) EnqueuePreview(chunkIndex int64) error {
+	observability.TraceGlobal(observability.FacetOrchestrator, "schedule chunk", "class", "preview", "chunk_index", chunkIndex)
 	return s.pools[PriorityP1].EnqueueChunk(chunkIndex)
 }
 
 // EnqueueBulk schedules a chunk index on P2 for bulk payload.
 func (s *OrchestratedSender) EnqueueBulk(chunkIndex int64) error {
+	observability.TraceGlobal(observability.FacetOrchestrator, "schedule chunk", "class", "bulk", "chunk_index", chunkIndex)
 	return s.pools[PriorityP2].EnqueueChunk(chunkIndex)
 }
 
-// Close stops all pools.
+// EnqueueRepair schedules an FEC parity/repair chunk index on P2 for bulk
+// payload, same as EnqueueBulk, but marks it (via
+// ChunkWorkerPool.EnqueueRepairChunk) so it gets a shot at the unreliable
+// datagram fast path under RepairDatagramThreshold instead of the ordinary,
+// usually smaller, DatagramThreshold — see SetRepairDatagramThreshold.
+func (s *OrchestratedSender) EnqueueRepair(chunkIndex int64) error {
+	observability.TraceGlobal(observability.FacetOrchestrator, "schedule chunk", "class", "repair", "chunk_index", chunkIndex)
+	return s.pools[PriorityP2].EnqueueRepairChunk(chunkIndex)
+}
+
+// EncryptChunkForDTN reads and encrypts chunkIndex the same way the
+// datagram fast path would, for a caller (service.SendWithOrchestration's
+// DTN bundle-mode fallback) that needs standalone chunk ciphertext rather
+// than enqueuing it on one of this sender's own pools.
+func (s *OrchestratedSender) EncryptChunkForDTN(chunkIndex int64) ([]byte, error) {
+	return s.pools[PriorityP2].EncryptChunkForBundle(chunkIndex)
+}
+
+// SetRepairDatagramThreshold configures every class's pool with a separate
+// datagram-fast-path ceiling for FEC repair chunks enqueued via
+// EnqueueRepair, typically larger than SetDatagramThreshold's since losing
+// a repair shard is never fatal the way losing a data chunk over the same
+// fast path would be.
+func (s *OrchestratedSender) SetRepairDatagramThreshold(bytes int64) {
+	for _, p := range s.pools {
+		p.SetRepairDatagramThreshold(bytes)
+	}
+}
+
+// SetSnapshotHandler registers fn to answer SnapshotRequest messages seen by
+// ServeChunkRequests, e.g. a handler built by statesync.NewSnapshotHandler.
+// This package never imports statesync itself (statesync depends on
+// transport, not the other way around), so the handler is injected as a
+// plain function value, the same way ChunkWorkerPool takes onChunkSent/
+// onChunkFailed callbacks rather than importing whatever package reacts to
+// them.
+func (s *OrchestratedSender) SetSnapshotHandler(fn func(*SnapshotRequest) (*SnapshotResponse, error)) {
+	s.snapshotHandler = fn
+}
+
+// SetAdminHandler registers fn to answer AdminCommand messages seen by
+// ServeChunkRequests, e.g. a handler built by service.NewAdminHandler.
+func (s *OrchestratedSender) SetAdminHandler(fn func(*AdminCommand) *AdminAck) {
+	s.adminHandler = fn
+}
+
+// ServeChunkRequests is the sender's single reader of the control stream
+// once a transfer is under way: it handles pull-mode ChunkRequest messages
+// (enqueuing each requested index on the bulk P2 pool, which opens a fresh
+// stream per chunk and writes the encrypted payload, then acknowledging
+// with a ChunkPayloadAck), NACKs from the push path (re-enqueuing the
+// missing indices the same way), and SnapshotRequest messages (answered via
+// snapshotHandler, if set). Folding all of these into one loop avoids two
+// goroutines racing to read the same QUIC control stream. It blocks until
+// ctx is done or the control stream errors, so callers should run it in a
+// goroutine.
+func (s *OrchestratedSender) ServeChunkRequests(ctx context.Context) {
+	ctrl := s.conn.GetControlStream()
+	if ctrl == nil {
+		return
+	}
+	go s.serveChunkDatagrams(ctx, ctrl)
+	go s.serveHaveBitmapDatagrams(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		msgType, data, err := ctrl.ReceiveAny()
+		if err != nil {
+			return
+		}
+		switch msgType {
+		case MessageTypeChunkRequest:
+			var req ChunkRequest
+			if ctrl.codec.Unmarshal(data, &req) != nil {
+				continue
+			}
+			for _, idx := range req.Indices {
+				err := s.EnqueueBulk(idx)
+				_ = ctrl.SendChunkPayloadAck(&ChunkPayloadAck{
+					SessionID: req.SessionID,
+					Index:     idx,
+					OK:        err == nil,
+					Timestamp: time.Now().Unix(),
+				})
+			}
+		case MessageTypeNack:
+			var nack NackMessage
+			if ctrl.codec.Unmarshal(data, &nack) != nil {
+				continue
+			}
+			var decomp ChunkRangeCompressor
+			idxs, _ := decomp.DecodeRanges(nack.MissingRanges, nack.Encoding)
+			atomic.AddInt64(&s.nackedChunks, int64(len(idxs)))
+			s.reenqueueNacked(ctx, idxs, nack.Reason, nack.RetryAfterMs)
+		case MessageTypeSnapshotRequest:
+			var req SnapshotRequest
+			if ctrl.codec.Unmarshal(data, &req) != nil || s.snapshotHandler == nil {
+				continue
+			}
+			resp, err := s.snapshotHandler(&req)
+			if err != nil {
+				continue
+			}
+			_ = ctrl.SendSnapshotResponse(resp)
+		case MessageTypeBadEncodingProof:
+			var msg BadEncodingProofMessage
+			if ctrl.codec.Unmarshal(data, &msg) != nil {
+				continue
+			}
+			fmt.Printf("bad encoding proof: receiver reports chunk %d of session %s reconstructs inconsistent with the manifest\n", msg.ChunkIndex, msg.SessionID)
+		case MessageTypeAdminCommand:
+			var cmd AdminCommand
+			if ctrl.codec.Unmarshal(data, &cmd) != nil {
+				continue
+			}
+			var ack *AdminAck
+			if s.adminHandler != nil {
+				ack = s.adminHandler(&cmd)
+			} else {
+				ack = &AdminAck{Action: cmd.Action, Error: "admin handler not configured", Timestamp: time.Now().Unix()}
+			}
+			_ = ctrl.SendAdminAck(ack)
+		}
+	}
+}
+
+// serveChunkDatagrams mirrors ServeChunkRequests' NACK handling for NACKs
+// that arrive over ctrl's unreliable datagram fast path instead of the
+// control stream proper (SendNack prefers the datagram path once the peer
+// supports datagrams). Datagram payloads are always plain JSON, never the
+// stream's negotiated codec, so this unmarshals directly rather than going
+// through ctrl.codec.Unmarshal. It blocks until ctx is done or the
+// connection closes the datagram channel, so ServeChunkRequests runs it in
+// a goroutine.
+func (s *OrchestratedSender) serveChunkDatagrams(ctx context.Context, ctrl *ControlStream) {
+	for dgram := range ctrl.ReceiveDatagrams(ctx) {
+		if dgram.Type != MessageTypeNack {
+			continue
+		}
+		var nack NackMessage
+		if json.Unmarshal(dgram.Payload, &nack) != nil {
+			continue
+		}
+		var decomp ChunkRangeCompressor
+		idxs, _ := decomp.DecodeRanges(nack.MissingRanges, nack.Encoding)
+		atomic.AddInt64(&s.nackedChunks, int64(len(idxs)))
+		s.reenqueueNacked(ctx, idxs, nack.Reason, nack.RetryAfterMs)
+	}
+}
+
+// serveHaveBitmapDatagrams reads ChunkReceiver.sendHaveBitmapDatagram's
+// periodic have-bitmap snapshots off the connection's DatagramMux and
+// re-enqueues whatever they report missing, the out-of-band counterpart to
+// serveChunkDatagrams' NACK handling: a receiver that's fallen behind (or
+// lost the datagram carrying its NACK) still gets retransmits driven by its
+// own bitmap state instead of waiting for the next explicit NACK. It blocks
+// until ctx is done, so ServeChunkRequests runs it in a goroutine.
+func (s *OrchestratedSender) serveHaveBitmapDatagrams(ctx context.Context) {
+	ch := s.conn.Datagrams().Subscribe(DatagramHaveBitmap)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-ch:
+			_, ranges, err := manager.DecodeRanges(payload)
+			if err != nil {
+				continue
+			}
+			idxs := manager.ExpandRanges(ranges)
+			atomic.AddInt64(&s.nackedChunks, int64(len(idxs)))
+			s.reenqueueNacked(ctx, idxs, "", 0)
+		}
+	}
+}
+
+// reenqueueNacked re-enqueues each NACKed chunk index on the bulk P2 pool
+// after a backoff delay. If s.retryBackoff is set (from profile.P2's
+// ClassConfig), it takes over entirely: reason (NackMessage.Reason, mapped
+// via nackReasonError) and retryAfterMs (NackMessage.RetryAfterMs, a
+// Retry-After-style hint from the peer) are passed straight through to it,
+// and a <= 0 delay drops the chunk instead of retrying it forever.
+// Otherwise s.backoff governs as before.
+func (s *OrchestratedSender) reenqueueNacked(ctx context.Context, idxs []int64, reason string, retryAfterMs int64) {
+	var lastErr error
+	var hint time.Duration
+	if s.retryBackoff != nil {
+		lastErr = nackReasonError(reason)
+		hint = time.Duration(retryAfterMs) * time.Millisecond
+	}
+
+	for _, idx := range idxs {
+		idx := idx
+		s.nackMu.Lock()
+		attempt := s.nackAttempts[idx]
+		s.nackAttempts[idx] = attempt + 1
+		s.nackMu.Unlock()
+
+		if s.retryBackoff != nil {
+			delay := s.retryBackoff(attempt, lastErr, hint)
+			if delay <= 0 {
+				continue
+			}
+			go func() {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(delay):
+					_ = s.EnqueueBulk(idx)
+				}
+			}()
+			continue
+		}
+
+		delay := s.backoff.Duration(attempt)
+		if delay <= 0 {
+			_ = s.EnqueueBulk(idx)
+			continue
+		}
+		go func() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+				_ = s.EnqueueBulk(idx)
+			}
+		}()
+	}
+}
+
+// Close stops all pools and releases the shared source-file reader.
 func (s *OrchestratedSender) Close() {
 	for _, p := range s.pools { p.Stop() }
+	if s.reader != nil {
+		s.reader.Close()
+	}
 }
 
 // Adjust updates chunk sizes and worker counts according to autotuning decisions.
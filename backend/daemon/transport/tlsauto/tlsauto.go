@@ -0,0 +1,269 @@
+// Package tlsauto obtains and renews TLS certificates via ACME (HTTP-01
+// and TLS-ALPN-01) for QUIC listeners exposed to the public internet,
+// rather than requiring an operator to provision and rotate certs by
+// hand - quicutil.NewAutocertTLSConfig covers the HTTP-01-only, single
+// on-disk-cache-directory case; Manager adds TLS-ALPN-01, a
+// staging/production directory switch, and keystore-backed persistence so
+// the ACME account key lives alongside identity keys in one encrypted
+// file instead of its own cache directory.
+package tlsauto
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/quantarax/backend/internal/crypto"
+)
+
+// stagingDirectoryURL is Let's Encrypt's staging ACME directory - issuance
+// against it doesn't count toward the production rate limits, so a node
+// can be brought up and tested before switching Config.Staging off.
+const stagingDirectoryURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+
+// httpChallengePortEnv overrides the port the HTTP-01 challenge responder
+// binds when Config.HTTPChallengePort is empty; it defaults to 80, the
+// port the ACME CA actually sends http-01 validation requests to.
+const httpChallengePortEnv = "QUANTARAX_ACME_HTTP_PORT"
+
+// Config configures a Manager.
+type Config struct {
+	// Hostnames is the allowlist of SNI names this node will request or
+	// serve a certificate for; any other ClientHello is refused rather
+	// than silently requesting a cert for whatever name shows up.
+	Hostnames []string
+
+	// Staging routes issuance through Let's Encrypt's staging directory
+	// instead of production.
+	Staging bool
+
+	// Email is the contact address passed to the CA for renewal/revocation
+	// notices.
+	Email string
+
+	// KeystorePath is the passphrase-encrypted keystore file the ACME
+	// account key and cached certificates are persisted in, alongside
+	// identity keys (see crypto.SaveKey/LoadKey and crypto.UpsertOpaqueSlot).
+	KeystorePath string
+
+	// Passphrase unlocks KeystorePath.
+	Passphrase string
+
+	// HTTPChallengePort overrides the HTTP-01 responder's port; empty uses
+	// httpChallengePortEnv or 80.
+	HTTPChallengePort string
+}
+
+// certState is what TLSCertCheck needs to know about one hostname's most
+// recently handled certificate request.
+type certState struct {
+	notAfter  time.Time
+	lastErr   error
+	checkedAt time.Time
+}
+
+// Manager obtains and renews certificates for Config.Hostnames via ACME,
+// tracking per-hostname state for observability.TLSCertCheck.
+type Manager struct {
+	certManager *autocert.Manager
+
+	mu    sync.RWMutex
+	state map[string]certState
+}
+
+// NewManager builds a Manager backed by an autocert.Manager whose Cache is
+// cfg.KeystorePath, and starts the HTTP-01 challenge responder. TLS-ALPN-01
+// needs no separate responder - autocert.Manager.GetCertificate answers it
+// directly whenever a ClientHello advertises the "acme-tls/1" ALPN
+// protocol, which TLSConfig's NextProtos includes.
+func NewManager(cfg Config) (*Manager, error) {
+	if len(cfg.Hostnames) == 0 {
+		return nil, errors.New("tlsauto: at least one hostname is required")
+	}
+	if cfg.KeystorePath == "" {
+		return nil, errors.New("tlsauto: KeystorePath is required")
+	}
+
+	certManager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.Hostnames...),
+		Cache:      &keystoreCache{path: cfg.KeystorePath, passphrase: cfg.Passphrase},
+		Email:      cfg.Email,
+	}
+	if cfg.Staging {
+		certManager.Client = &acme.Client{DirectoryURL: stagingDirectoryURL}
+	}
+
+	if err := startHTTPChallengeResponder(certManager, cfg.HTTPChallengePort); err != nil {
+		return nil, err
+	}
+
+	return &Manager{
+		certManager: certManager,
+		state:       make(map[string]certState),
+	}, nil
+}
+
+// GetCertificate is the QUIC listener's tls.Config.GetCertificate: it
+// obtains or renews hello.ServerName's certificate through certManager and
+// records the outcome for TLSCertCheck.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, err := m.certManager.GetCertificate(hello)
+	m.record(hello.ServerName, cert, err)
+	return cert, err
+}
+
+// TLSConfig returns a *tls.Config wired to GetCertificate and advertising
+// the ALPN protocol TLS-ALPN-01 challenge responses arrive under.
+func (m *Manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: m.GetCertificate,
+		NextProtos:     []string{acme.ALPNProto},
+		MinVersion:     tls.VersionTLS13,
+	}
+}
+
+func (m *Manager) record(hostname string, cert *tls.Certificate, err error) {
+	if hostname == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.state[hostname]
+	st.checkedAt = time.Now()
+	st.lastErr = err
+	if err == nil {
+		if notAfter, ok := certNotAfter(cert); ok {
+			st.notAfter = notAfter
+		}
+	}
+	m.state[hostname] = st
+}
+
+func certNotAfter(cert *tls.Certificate) (time.Time, bool) {
+	if cert == nil {
+		return time.Time{}, false
+	}
+	if cert.Leaf != nil {
+		return cert.Leaf.NotAfter, true
+	}
+	if len(cert.Certificate) == 0 {
+		return time.Time{}, false
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return leaf.NotAfter, true
+}
+
+// CertStatus is one managed hostname's most recently observed certificate
+// state.
+type CertStatus struct {
+	Hostname string
+	NotAfter time.Time
+	Err      error
+}
+
+// Statuses returns a snapshot of every hostname GetCertificate has been
+// asked for so far, for observability.TLSCertCheck to inspect.
+func (m *Manager) Statuses() []CertStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]CertStatus, 0, len(m.state))
+	for host, st := range m.state {
+		out = append(out, CertStatus{Hostname: host, NotAfter: st.notAfter, Err: st.lastErr})
+	}
+	return out
+}
+
+// startHTTPChallengeResponder binds certManager's HTTP-01 challenge
+// handler to the ACME responder port and serves it in a background
+// goroutine until SIGINT or SIGTERM, at which point it shuts down
+// gracefully rather than dropping in-flight validation requests - the
+// same pattern quicutil.NewAutocertTLSConfig uses.
+func startHTTPChallengeResponder(certManager *autocert.Manager, port string) error {
+	if port == "" {
+		port = os.Getenv(httpChallengePortEnv)
+	}
+	if port == "" {
+		port = "80"
+	}
+
+	responder := &http.Server{
+		Addr:    ":" + port,
+		Handler: certManager.HTTPHandler(nil),
+	}
+
+	listener, err := net.Listen("tcp", responder.Addr)
+	if err != nil {
+		return fmt.Errorf("tlsauto: failed to bind HTTP-01 responder on %s: %w", responder.Addr, err)
+	}
+
+	go func() {
+		if err := responder.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("tlsauto: HTTP-01 responder stopped: %v", err)
+		}
+	}()
+
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+		signal.Stop(sigCh)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = responder.Shutdown(ctx)
+	}()
+
+	return nil
+}
+
+// keystoreCache implements autocert.Cache by storing every ACME artifact
+// (account key, issued certificate, OCSP staple) as an opaque slot in the
+// same passphrase-encrypted keystore file identity keys live in, instead
+// of autocert's usual on-disk DirCache directory.
+type keystoreCache struct {
+	path       string
+	passphrase string
+}
+
+// keystoreCachePurposePrefix namespaces autocert's cache keys (e.g.
+// "acme_account+key", "example.com") from the "identity"/"signing"
+// purposes AddKey/SaveKey already use in the same file.
+const keystoreCachePurposePrefix = "acme-cache:"
+
+func (c *keystoreCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := crypto.GetOpaqueSlot(c.path, keystoreCachePurposePrefix+key, c.passphrase)
+	if errors.Is(err, crypto.ErrKeyNotFound) {
+		return nil, autocert.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *keystoreCache) Put(ctx context.Context, key string, data []byte) error {
+	return crypto.UpsertOpaqueSlot(c.path, keystoreCachePurposePrefix+key, data, c.passphrase)
+}
+
+func (c *keystoreCache) Delete(ctx context.Context, key string) error {
+	return crypto.DeleteOpaqueSlot(c.path, keystoreCachePurposePrefix+key)
+}
@@ -0,0 +1,93 @@
+package chunker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// BundleEntry describes one file within a directory/multi-file transfer.
+// FileKey is left empty by ComputeBundle; the service layer fills it in
+// once it registers the child manifest, so a receiver can fetch it via the
+// existing ManifestRequest/ManifestResponse flow.
+type BundleEntry struct {
+	RelativePath string `json:"relative_path"`
+	ManifestHash string `json:"manifest_hash"`
+	Size         int64  `json:"size"`
+	FileKey      string `json:"file_key,omitempty"`
+}
+
+// Bundle is a manifest-of-manifests: it lists the files under a root
+// directory and commits to their manifest hashes with its own Merkle root,
+// so a single signed token can reference an entire directory transfer.
+type Bundle struct {
+	RootName   string        `json:"root_name"`
+	Entries    []BundleEntry `json:"entries"`
+	MerkleRoot string        `json:"merkle_root"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// ComputeBundle walks root and chunks every regular file it contains,
+// returning a Bundle describing them plus the per-file manifests keyed by
+// their path relative to root. Entries are ordered by relative path, which
+// filepath.Walk already visits in lexical order, so ComputeBundle is
+// deterministic for a fixed directory tree.
+func ComputeBundle(root string, opts ChunkOptions) (*Bundle, map[string]*Manifest, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat bundle root: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("bundle root %q is not a directory", root)
+	}
+
+	var entries []BundleEntry
+	manifests := make(map[string]*Manifest)
+	var hashes []string
+
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if fi.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		manifest, err := ComputeManifest(path, opts)
+		if err != nil {
+			return fmt.Errorf("failed to chunk %s: %w", relPath, err)
+		}
+
+		entries = append(entries, BundleEntry{
+			RelativePath: relPath,
+			ManifestHash: manifest.MerkleRoot,
+			Size:         manifest.FileSize,
+		})
+		hashes = append(hashes, manifest.MerkleRoot)
+		manifests[relPath] = manifest
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merkleRoot, err := ComputeMerkleRoot(hashes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute bundle merkle root: %w", err)
+	}
+
+	bundle := &Bundle{
+		RootName:   filepath.Base(root),
+		Entries:    entries,
+		MerkleRoot: merkleRoot,
+		CreatedAt:  time.Now(),
+	}
+
+	return bundle, manifests, nil
+}
@@ -1,6 +1,7 @@
 package chunker
 
 import (
+	"crypto/rand"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/quantarax/backend/internal/crypto"
 	"github.com/zeebo/blake3"
 )
 
@@ -19,8 +21,23 @@ func ComputeManifest(filePath string, options ChunkOptions) (*Manifest, error) {
 		options = DefaultChunkOptions()
 	}
 
+	// Compression/encryption (see ApplyTransform) chunk the sidecar file it
+	// writes instead of filePath, so the bytes hashed below are the same
+	// ones that go out on the wire.
+	sourcePath := filePath
+	var codec CompressionCodec
+	var keyFingerprint string
+	if (options.Compression != "" && options.Compression != CodecNone) || len(options.EncryptionKey) > 0 {
+		c, fp, err := ApplyTransform(filePath, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply compression/encryption: %w", err)
+		}
+		codec, keyFingerprint = c, fp
+		sourcePath = CompressedPath(filePath)
+	}
+
 	// Open file
-	file, err := os.Open(filePath)
+	file, err := os.Open(sourcePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
@@ -60,15 +77,17 @@ func ComputeManifest(filePath string, options ChunkOptions) (*Manifest, error) {
 		merkleRoot, _ := ComputeMerkleRoot([]string{hashBase64})
 		
 		return &Manifest{
-			SessionID:  sessionID,
-			FileName:   fileName,
-			FileSize:   0,
-			ChunkSize:  options.ChunkSize,
-			ChunkCount: 1,
-			HashAlgo:   "BLAKE3",
-			Chunks:     chunks,
-			MerkleRoot: merkleRoot,
-			CreatedAt:  time.Now(),
+			SessionID:      sessionID,
+			FileName:       fileName,
+			FileSize:       0,
+			ChunkSize:      options.ChunkSize,
+			ChunkCount:     1,
+			HashAlgo:       "BLAKE3",
+			Chunks:         chunks,
+			MerkleRoot:     merkleRoot,
+			CreatedAt:      time.Now(),
+			Codec:          string(codec),
+			KeyFingerprint: keyFingerprint,
 		}, nil
 	}
 
@@ -114,15 +133,17 @@ func ComputeManifest(filePath string, options ChunkOptions) (*Manifest, error) {
 
 	// Create manifest
 	manifest := &Manifest{
-		SessionID:  sessionID,
-		FileName:   fileName,
-		FileSize:   fileSize,
-		ChunkSize:  options.ChunkSize,
-		ChunkCount: len(chunks),
-		HashAlgo:   "BLAKE3",
-		Chunks:     chunks,
-		MerkleRoot: merkleRoot,
-		CreatedAt:  time.Now(),
+		SessionID:      sessionID,
+		FileName:       fileName,
+		FileSize:       fileSize,
+		ChunkSize:      options.ChunkSize,
+		ChunkCount:     len(chunks),
+		HashAlgo:       "BLAKE3",
+		Chunks:         chunks,
+		MerkleRoot:     merkleRoot,
+		CreatedAt:      time.Now(),
+		Codec:          string(codec),
+		KeyFingerprint: keyFingerprint,
 	}
 
 	return manifest, nil
@@ -133,9 +154,20 @@ type Chunker struct {
 	reader    io.Reader
 	chunkSize int
 	buffer    []byte
+
+	// ivBase and key, set by NewChunkerWithTransform, have Next seal each
+	// returned chunk with a counter-derived nonce — the same construction
+	// ApplyTransform uses for ComputeManifest's sidecar file — so a
+	// streaming "-" (stdin) sender and a file-backed sender encrypt
+	// identically. counter is the current chunk index.
+	ivBase  [ivHeaderSize]byte
+	key     []byte
+	counter uint64
 }
 
-// NewChunker creates a new streaming chunker
+// NewChunker creates a new streaming chunker with no compression or
+// encryption; Next returns r's chunks unmodified. Use
+// NewChunkerWithTransform for "-" (stdin) input that needs either.
 func NewChunker(r io.Reader, chunkSize int) (*Chunker, error) {
 	if chunkSize <= 0 {
 		return nil, fmt.Errorf("chunk size must be positive")
@@ -147,7 +179,39 @@ func NewChunker(r io.Reader, chunkSize int) (*Chunker, error) {
 	}, nil
 }
 
-// Next returns the next chunk of data
+// NewChunkerWithTransform wraps r so Next's returned chunks are
+// codec-compressed and, if key is non-empty, AEAD-sealed — the streaming
+// equivalent of ComputeManifest's ApplyTransform for piped-in ("-") input,
+// where there's no file path to write a compressed sidecar to. codec of
+// "" or CodecNone skips compression but still encrypts if key is set.
+func NewChunkerWithTransform(r io.Reader, chunkSize int, codec CompressionCodec, key []byte) (*Chunker, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive")
+	}
+	if codec != "" && codec != CodecNone {
+		pr, pw := io.Pipe()
+		cw, err := newCompressWriter(codec, pw)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			_, copyErr := io.Copy(cw, r)
+			cw.Close()
+			pw.CloseWithError(copyErr)
+		}()
+		r = pr
+	}
+	c := &Chunker{reader: r, chunkSize: chunkSize, buffer: make([]byte, chunkSize), key: key}
+	if len(key) > 0 {
+		if _, err := rand.Read(c.ivBase[:]); err != nil {
+			return nil, fmt.Errorf("chunker: generate iv: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Next returns the next chunk of data, sealed with the key
+// NewChunkerWithTransform was given, if any.
 func (c *Chunker) Next() ([]byte, error) {
 	n, err := c.reader.Read(c.buffer)
 	if err != nil && err != io.EOF {
@@ -156,33 +220,82 @@ func (c *Chunker) Next() ([]byte, error) {
 	if n == 0 {
 		return nil, io.EOF
 	}
-	return c.buffer[:n], nil
+	chunk := c.buffer[:n]
+	if len(c.key) == 0 {
+		return chunk, nil
+	}
+	nonce := crypto.DeriveNonce(c.ivBase, c.counter)
+	c.counter++
+	sealed, err := crypto.Seal(c.key, nonce[:], nil, chunk)
+	if err != nil {
+		return nil, fmt.Errorf("chunker: seal chunk: %w", err)
+	}
+	return sealed, nil
 }
 
-// ReadChunk reads a specific chunk from the file
-func ReadChunk(filePath string, chunkIndex int, chunkSize int) ([]byte, error) {
+// ReadChunk reads a specific chunk from filePath, or — when codec isn't
+// CodecNone or key is set — from its ApplyTransform sidecar
+// (CompressedPath(filePath)), decrypting with key if one is given. Pass
+// CodecNone and a nil key to read a plain, untransformed file exactly as
+// before.
+func ReadChunk(filePath string, chunkIndex int, chunkSize int, codec CompressionCodec, key []byte) ([]byte, error) {
+	sourcePath := filePath
+	headerLen := int64(0)
+	if (codec != "" && codec != CodecNone) || len(key) > 0 {
+		sourcePath = CompressedPath(filePath)
+		if len(key) > 0 {
+			headerLen = ivHeaderSize
+		}
+	}
+
 	// Open file
-	file, err := os.Open(filePath)
+	file, err := os.Open(sourcePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	// Calculate offset
-	offset := int64(chunkIndex) * int64(chunkSize)
+	if len(key) == 0 {
+		// Calculate offset
+		offset := headerLen + int64(chunkIndex)*int64(chunkSize)
 
-	// Seek to offset
-	_, err = file.Seek(offset, 0)
-	if err != nil {
+		// Seek to offset
+		_, err = file.Seek(offset, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+		}
+
+		// Read chunk
+		buffer := make([]byte, chunkSize)
+		n, err := file.Read(buffer)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read chunk: %w", err)
+		}
+
+		return buffer[:n], nil
+	}
+
+	var ivBase [ivHeaderSize]byte
+	if _, err := file.Read(ivBase[:]); err != nil {
+		return nil, fmt.Errorf("failed to read iv header: %w", err)
+	}
+
+	sealedSize := chunkSize + aeadTagSize
+	offset := headerLen + int64(chunkIndex)*int64(sealedSize)
+	if _, err := file.Seek(offset, 0); err != nil {
 		return nil, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
 	}
 
-	// Read chunk
-	buffer := make([]byte, chunkSize)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
+	buffer := make([]byte, sealedSize)
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
 		return nil, fmt.Errorf("failed to read chunk: %w", err)
 	}
 
-	return buffer[:n], nil
+	nonce := crypto.DeriveNonce(ivBase, uint64(chunkIndex))
+	plaintext, err := crypto.Open(key, nonce[:], nil, buffer[:n])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt chunk %d: %w", chunkIndex, err)
+	}
+	return plaintext, nil
 }
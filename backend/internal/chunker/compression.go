@@ -0,0 +1,249 @@
+package chunker
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/quantarax/backend/internal/crypto"
+	"github.com/zeebo/blake3"
+)
+
+// CompressionCodec names the codec ChunkOptions.Compression requests for
+// ApplyTransform's compress-then-encrypt pass ahead of chunking, and the
+// codec Manifest.Codec records having actually been used, so a receiver
+// decompressing reconstructed chunks knows which decoder to run.
+type CompressionCodec string
+
+const (
+	CodecNone CompressionCodec = "none"
+	CodecGzip CompressionCodec = "gzip"
+	CodecZstd CompressionCodec = "zstd"
+	CodecLZ4  CompressionCodec = "lz4"
+	// CodecTelemetry re-encodes a CSV telemetry source through
+	// telemetry.Codec's double-delta/dictionary-RLE columnar scheme
+	// instead of a general-purpose byte compressor — see
+	// newTelemetryWriter — intended for introspect.DomainTelemetry
+	// transfers (racetrack_factory) where most columns are either
+	// steadily drifting (timestamps, RPM) or long runs of a handful of
+	// values (CAN IDs).
+	CodecTelemetry CompressionCodec = "telemetry"
+)
+
+// aeadTagSize is the fixed overhead crypto.Seal appends to every block, the
+// same 16-byte GCM authentication tag its own doc comment describes.
+const aeadTagSize = 16
+
+// ivHeaderSize is the length of the random per-transform IV base
+// ApplyTransform writes ahead of the ciphertext in CompressedPath(filePath)
+// when EncryptionKey is set, for ReadChunk to derive the same per-block
+// nonces back with crypto.DeriveNonce.
+const ivHeaderSize = 12
+
+// entropyProbeSize is how many leading bytes ApplyTransform samples via
+// looksAlreadyCompressed before spending CPU compressing a source that
+// won't shrink any further.
+const entropyProbeSize = 4096
+
+// highEntropyThreshold is the Shannon-entropy-per-byte (out of a possible
+// 8 bits) above which looksAlreadyCompressed treats a sample as already
+// compressed or encrypted, the same rule of thumb general-purpose
+// compressors use to auto-skip incompressible input.
+const highEntropyThreshold = 7.5
+
+// looksAlreadyCompressed reports whether sample's Shannon entropy is high
+// enough that running it through a general-purpose compressor would grow
+// it (framing overhead) rather than shrink it — the case for an
+// already-compressed archive, an encrypted blob, or most media codecs'
+// output.
+func looksAlreadyCompressed(sample []byte) bool {
+	if len(sample) == 0 {
+		return false
+	}
+	var counts [256]int
+	for _, b := range sample {
+		counts[b]++
+	}
+	total := float64(len(sample))
+	entropy := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / total
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= highEntropyThreshold
+}
+
+// CompressedPath is the sidecar path ApplyTransform writes filePath's
+// compressed and/or encrypted bytes to, the same sidecar-file convention
+// FecParityPath uses for FEC parity: ComputeManifest chunks and ReadChunk
+// reads back this file instead of filePath whenever a transform ran.
+func CompressedPath(filePath string) string {
+	return filePath + ".cz"
+}
+
+// newCompressWriter returns a WriteCloser that writes codec-compressed
+// bytes to w.
+func newCompressWriter(codec CompressionCodec, w io.Writer) (io.WriteCloser, error) {
+	switch codec {
+	case CodecGzip:
+		return gzip.NewWriter(w), nil
+	case CodecZstd:
+		return zstd.NewWriter(w)
+	case CodecLZ4:
+		return lz4.NewWriter(w), nil
+	case CodecTelemetry:
+		return newTelemetryWriter(w), nil
+	default:
+		return nil, fmt.Errorf("chunker: unknown compression codec %q", codec)
+	}
+}
+
+// fingerprintKey returns a short BLAKE3-based fingerprint of key for
+// Manifest.KeyFingerprint, so a receiver holding the wrong key can reject a
+// transfer up front instead of failing every chunk's AEAD open one at a
+// time.
+func fingerprintKey(key []byte) string {
+	hasher := blake3.New()
+	hasher.Write(key)
+	sum := hasher.Sum(nil)
+	return base64.StdEncoding.EncodeToString(sum[:8])
+}
+
+// ApplyTransform compresses filePath with options.Compression — skipped in
+// favor of CodecNone if looksAlreadyCompressed judges the file's first
+// entropyProbeSize bytes not worth it — and then, if options.EncryptionKey
+// is set, AEAD-seals the result in options.ChunkSize blocks, writing the
+// outcome to CompressedPath(filePath). ComputeManifest chunks that sidecar
+// file in place of filePath whenever either option is set, so the bytes it
+// hashes match what actually goes out on the wire. It returns the codec
+// actually used and a fingerprint of options.EncryptionKey (empty if unset)
+// for the caller to record on Manifest.Codec/KeyFingerprint.
+func ApplyTransform(filePath string, options ChunkOptions) (codec CompressionCodec, keyFingerprint string, err error) {
+	codec = options.Compression
+	if codec == "" {
+		codec = CodecNone
+	}
+
+	src, err := os.Open(filePath)
+	if err != nil {
+		return "", "", fmt.Errorf("transform: open %s: %w", filePath, err)
+	}
+	defer src.Close()
+
+	if codec != CodecNone {
+		br := bufio.NewReader(src)
+		probe, _ := br.Peek(entropyProbeSize)
+		if looksAlreadyCompressed(probe) {
+			codec = CodecNone
+		}
+		src.Seek(0, io.SeekStart)
+	}
+
+	var compressed bytes.Buffer
+	if codec == CodecNone {
+		if _, err := io.Copy(&compressed, src); err != nil {
+			return "", "", fmt.Errorf("transform: read %s: %w", filePath, err)
+		}
+	} else {
+		cw, err := newCompressWriter(codec, &compressed)
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := io.Copy(cw, src); err != nil {
+			return "", "", fmt.Errorf("transform: compress %s: %w", filePath, err)
+		}
+		if err := cw.Close(); err != nil {
+			return "", "", fmt.Errorf("transform: finish compressing %s: %w", filePath, err)
+		}
+	}
+
+	out, err := os.Create(CompressedPath(filePath))
+	if err != nil {
+		return "", "", fmt.Errorf("transform: create sidecar: %w", err)
+	}
+	defer out.Close()
+
+	if len(options.EncryptionKey) == 0 {
+		if _, err := out.Write(compressed.Bytes()); err != nil {
+			return "", "", fmt.Errorf("transform: write sidecar: %w", err)
+		}
+		return codec, "", nil
+	}
+
+	var ivBase [ivHeaderSize]byte
+	if _, err := rand.Read(ivBase[:]); err != nil {
+		return "", "", fmt.Errorf("transform: generate iv: %w", err)
+	}
+	if _, err := out.Write(ivBase[:]); err != nil {
+		return "", "", fmt.Errorf("transform: write iv: %w", err)
+	}
+
+	blockSize := options.ChunkSize
+	plain := compressed.Bytes()
+	for counter := uint64(0); len(plain) > 0; counter++ {
+		n := blockSize
+		if n > len(plain) {
+			n = len(plain)
+		}
+		nonce := crypto.DeriveNonce(ivBase, counter)
+		ciphertext, err := crypto.Seal(options.EncryptionKey, nonce[:], nil, plain[:n])
+		if err != nil {
+			return "", "", fmt.Errorf("transform: encrypt block %d: %w", counter, err)
+		}
+		if _, err := out.Write(ciphertext); err != nil {
+			return "", "", fmt.Errorf("transform: write block %d: %w", counter, err)
+		}
+		plain = plain[n:]
+	}
+
+	return codec, fingerprintKey(options.EncryptionKey), nil
+}
+
+// StreamReader wraps an io.Reader — typically os.Stdin for "-" CLI input —
+// and auto-detects a gzip magic header on the first two bytes, transparently
+// decompressing if found so callers like ComputeManifest/NewChunker always
+// see plaintext regardless of whether the piped-in data was pre-gzipped.
+type StreamReader struct {
+	io.Reader
+	closer io.Closer
+}
+
+// NewStreamReader peeks r's first two bytes for gzip's 0x1f 0x8b magic and
+// wraps it in a gzip.Reader if found, otherwise passes bytes through
+// unchanged.
+func NewStreamReader(r io.Reader) (*StreamReader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("stream reader: peek magic: %w", err)
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("stream reader: gzip header: %w", err)
+		}
+		return &StreamReader{Reader: gz, closer: gz}, nil
+	}
+	return &StreamReader{Reader: br}, nil
+}
+
+// Close releases the gzip reader NewStreamReader created, if any. It never
+// closes the underlying reader it was given — "-" stdin input isn't ours
+// to close.
+func (s *StreamReader) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package chunker
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/zeebo/blake3"
+)
+
+// adlerMod is the modulus the classic rsync rolling checksum reduces its
+// two running sums by, same constant zlib's Adler-32 uses.
+const adlerMod = 65521
+
+// BlockSignature is one fixed-size block's weak (rollable) and strong
+// checksum, computed by ComputeDeltaSignatures over a receiver's prior copy
+// of a file and sent to the sender so EmitDeltaInstructions can find which
+// regions of the new version are unchanged.
+type BlockSignature struct {
+	Index  int    `json:"index"`
+	Weak   uint32 `json:"weak"`
+	Strong string `json:"strong"` // base64-encoded BLAKE3 hash
+}
+
+// blockStrongHash returns block's BLAKE3 hash, base64-encoded, the same
+// strong-checksum role engineering.ComputeDeltaBlocks' BLAKE3Sum field
+// plays for content-defined chunks.
+func blockStrongHash(block []byte) string {
+	hasher := blake3.New()
+	hasher.Write(block)
+	return base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+}
+
+// weakChecksum computes the classic rsync rolling checksum (a zlib
+// Adler-32-style pair of running sums, reduced mod adlerMod) over data.
+func weakChecksum(data []byte) uint32 {
+	var a, b uint32
+	for i, c := range data {
+		a += uint32(c)
+		b += (uint32(len(data)-i) * uint32(c))
+	}
+	a %= adlerMod
+	b %= adlerMod
+	return (b << 16) | a
+}
+
+// ComputeDeltaSignatures splits path into fixed blockSize blocks (the final
+// block may be shorter) and returns each block's weak rolling checksum plus
+// a strong BLAKE3 hash, the table a receiver sends the sender before a
+// resumed transfer so EmitDeltaInstructions can diff the new version
+// against it.
+func ComputeDeltaSignatures(path string, blockSize int) ([]BlockSignature, error) {
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("delta resume: block size must be positive, got %d", blockSize)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sigs []BlockSignature
+	buf := make([]byte, blockSize)
+	for idx := 0; ; idx++ {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			block := buf[:n]
+			sigs = append(sigs, BlockSignature{
+				Index:  idx,
+				Weak:   weakChecksum(block),
+				Strong: blockStrongHash(block),
+			})
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sigs, nil
+}
+
+// DeltaOp distinguishes the two instruction kinds EmitDeltaInstructions
+// produces.
+type DeltaOp byte
+
+const (
+	// DeltaOpCopy means "copy block BlockIndex from the receiver's prior
+	// file", unchanged since the signature table was built.
+	DeltaOpCopy DeltaOp = iota
+	// DeltaOpLiteral means "write these new bytes", found nowhere in the
+	// receiver's prior file at a matching offset.
+	DeltaOpLiteral
+)
+
+// DeltaInstruction is one step of reconstructing the new version of a file
+// from a receiver's prior copy: either DeltaOpCopy (replay BlockIndex
+// unchanged) or DeltaOpLiteral (write Data, bytes the rolling scan couldn't
+// match against any known block).
+type DeltaInstruction struct {
+	Op         DeltaOp `json:"op"`
+	BlockIndex int     `json:"block_index,omitempty"`
+	Data       []byte  `json:"data,omitempty"`
+}
+
+// EmitDeltaInstructions runs the classic rsync rolling-hash scan over the
+// new version of a file at path against sigs (a signature table the
+// receiver computed over its prior version via ComputeDeltaSignatures),
+// emitting a stream of DeltaOpCopy/DeltaOpLiteral instructions that the
+// receiver can replay (see ApplyDeltaInstructions) to reconstruct the new
+// file while only ever transferring the bytes that actually changed.
+//
+// The scan is the textbook rsync algorithm: a rolling weak checksum is
+// evaluated at every byte offset; on a weak-checksum hit, the candidate
+// block's strong hash is checked before trusting the match (a 32-bit weak
+// checksum alone collides too often to copy on its own). Bytes that don't
+// match any known block accumulate into a literal run, flushed as a single
+// DeltaOpLiteral once a match is found or the file ends.
+func EmitDeltaInstructions(path string, blockSize int, sigs []BlockSignature) ([]DeltaInstruction, error) {
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("delta resume: block size must be positive, got %d", blockSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	byWeak := make(map[uint32][]BlockSignature, len(sigs))
+	for _, s := range sigs {
+		byWeak[s.Weak] = append(byWeak[s.Weak], s)
+	}
+
+	var (
+		instructions []DeltaInstruction
+		literal      []byte
+	)
+	flushLiteral := func() {
+		if len(literal) == 0 {
+			return
+		}
+		instructions = append(instructions, DeltaInstruction{Op: DeltaOpLiteral, Data: literal})
+		literal = nil
+	}
+
+	n := len(data)
+	for pos := 0; pos < n; {
+		end := pos + blockSize
+		if end > n {
+			end = n
+		}
+		block := data[pos:end]
+
+		matched := false
+		if candidates, ok := byWeak[weakChecksum(block)]; ok && len(block) == blockSize {
+			strong := blockStrongHash(block)
+			for _, c := range candidates {
+				if c.Strong == strong {
+					flushLiteral()
+					instructions = append(instructions, DeltaInstruction{Op: DeltaOpCopy, BlockIndex: c.Index})
+					pos = end
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			literal = append(literal, data[pos])
+			pos++
+		}
+	}
+	flushLiteral()
+
+	return instructions, nil
+}
+
+// ApplyDeltaInstructions reconstructs the new version of a file at outPath
+// by replaying instructions: a DeltaOpCopy reads block BlockIndex
+// (blockSize bytes, or fewer for the prior file's final block) from
+// oldPath, a DeltaOpLiteral writes its Data directly.
+func ApplyDeltaInstructions(oldPath, outPath string, blockSize int, instructions []DeltaInstruction) error {
+	old, err := os.Open(oldPath)
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	buf := make([]byte, blockSize)
+	for _, instr := range instructions {
+		switch instr.Op {
+		case DeltaOpCopy:
+			n, err := old.ReadAt(buf, int64(instr.BlockIndex)*int64(blockSize))
+			if err != nil && err != io.EOF {
+				return fmt.Errorf("delta resume: read block %d from %s: %w", instr.BlockIndex, oldPath, err)
+			}
+			if _, err := out.Write(buf[:n]); err != nil {
+				return err
+			}
+		case DeltaOpLiteral:
+			if _, err := out.Write(instr.Data); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("delta resume: unknown instruction op %d", instr.Op)
+		}
+	}
+	return nil
+}
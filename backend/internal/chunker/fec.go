@@ -0,0 +1,159 @@
+package chunker
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/quantarax/backend/internal/fec"
+	"github.com/zeebo/blake3"
+)
+
+// FecScheme requests optional Reed-Solomon erasure coding across a
+// manifest's chunks: every StripeChunks consecutive data chunks become one
+// stripe encoded into that many data shards (fewer for the final stripe)
+// plus M parity shards, so a receiver can recover up to M missing chunks
+// per stripe without a retransmit round trip. StripeChunks defaults to K
+// when zero.
+type FecScheme struct {
+	K            int `json:"k"`
+	M            int `json:"m"`
+	StripeChunks int `json:"stripe_chunks,omitempty"`
+}
+
+// FecStripe records one stripe's placement within Manifest.Chunks: data
+// chunks Start..Start+K-1 were encoded together, with M parity chunks
+// appended right after them at Start+K..Start+K+M-1. StripeID lets the
+// receiver's assembler group a stripe's shards regardless of arrival order.
+type FecStripe struct {
+	StripeID string `json:"stripe_id"`
+	Start    int    `json:"start"`
+	K        int    `json:"k"`
+	M        int    `json:"m"`
+}
+
+// ApplyFECScheme groups manifest's existing chunks into stripes per scheme,
+// computes M parity shards per stripe over a Vandermonde matrix in GF(2^8)
+// (internal/fec, the same construction klauspost/reedsolomon uses), and
+// appends a ChunkDescriptor for each parity shard to manifest.Chunks so
+// parity chunks are addressable by the same contiguous index space as data
+// chunks. reader must serve the same byte ranges ComputeManifest chunked,
+// i.e. chunk i's data at ReadAt(buf, int64(i)*int64(manifest.ChunkSize)).
+//
+// Every shard fed to the encoder, including a stripe's final (possibly
+// partial) data chunk, is zero-padded to manifest.ChunkSize so parity shards
+// always come out exactly chunkSize bytes — matching the uniform
+// chunkIndex*chunkSize stride chunkreader.FECReader and ChunkWorkerPool
+// already assume for every chunk. It returns the concatenated parity bytes
+// in chunk order, for the caller to persist as a sidecar file.
+func ApplyFECScheme(manifest *Manifest, reader io.ReaderAt, scheme FecScheme) ([]byte, error) {
+	if scheme.K <= 0 || scheme.M <= 0 {
+		return nil, fmt.Errorf("fec scheme requires positive K and M")
+	}
+	stripeSize := scheme.StripeChunks
+	if stripeSize <= 0 {
+		stripeSize = scheme.K
+	}
+
+	chunkSize := manifest.ChunkSize
+	dataChunkCount := len(manifest.Chunks)
+	var parityBlob []byte
+
+	for start := 0; start < dataChunkCount; start += stripeSize {
+		end := start + stripeSize
+		if end > dataChunkCount {
+			end = dataChunkCount
+		}
+		k := end - start
+
+		shards := make([][]byte, k)
+		for i := 0; i < k; i++ {
+			desc := manifest.Chunks[start+i]
+			buf := make([]byte, chunkSize)
+			if desc.Length > 0 {
+				offset := int64(start+i) * int64(chunkSize)
+				if _, err := reader.ReadAt(buf[:desc.Length], offset); err != nil && err != io.EOF {
+					return nil, fmt.Errorf("read chunk %d for fec stripe: %w", start+i, err)
+				}
+			}
+			shards[i] = buf
+		}
+
+		enc, err := fec.NewEncoder(k, scheme.M)
+		if err != nil {
+			return nil, fmt.Errorf("fec stripe at chunk %d: %w", start, err)
+		}
+		parityShards, err := enc.Encode(shards)
+		if err != nil {
+			return nil, fmt.Errorf("fec encode stripe at chunk %d: %w", start, err)
+		}
+
+		stripeID := uuid.New().String()
+		for _, parity := range parityShards {
+			hasher := blake3.New()
+			hasher.Write(parity)
+			hashBase64 := base64.StdEncoding.EncodeToString(hasher.Sum(nil))
+
+			manifest.Chunks = append(manifest.Chunks, ChunkDescriptor{
+				Index:  len(manifest.Chunks),
+				Hash:   hashBase64,
+				Length: len(parity),
+			})
+			parityBlob = append(parityBlob, parity...)
+		}
+
+		manifest.FecStripes = append(manifest.FecStripes, FecStripe{
+			StripeID: stripeID,
+			Start:    start,
+			K:        k,
+			M:        scheme.M,
+		})
+	}
+
+	manifest.ChunkCount = len(manifest.Chunks)
+	manifest.FEC = &FECProfile{K: scheme.K, R: scheme.M}
+	return parityBlob, nil
+}
+
+// FecParityPath is the sidecar path a FEC parity blob is written to
+// alongside filePath, shared between the sender (which writes it once
+// ApplyFECScheme returns) and the transport layer (which reads it back to
+// serve parity chunk requests).
+func FecParityPath(filePath string) string {
+	return filePath + ".fecparity"
+}
+
+// DataChunkCount returns the number of original file chunks in m.Chunks,
+// excluding any FEC parity chunks FecStripes appended.
+func (m *Manifest) DataChunkCount() int {
+	parity := 0
+	for _, s := range m.FecStripes {
+		parity += s.M
+	}
+	return m.ChunkCount - parity
+}
+
+// ParityChunkIndices returns every chunk index m.FecStripes marks as parity
+// (as opposed to original file data), in ascending order.
+func (m *Manifest) ParityChunkIndices() []int64 {
+	var indices []int64
+	for _, stripe := range m.FecStripes {
+		for i := 0; i < stripe.M; i++ {
+			indices = append(indices, int64(stripe.Start+stripe.K+i))
+		}
+	}
+	return indices
+}
+
+// StripeForChunk returns the FecStripe chunkIndex belongs to, as either a
+// data or parity member, and true — or false if m has no stripe covering it
+// (e.g. FEC wasn't enabled for this transfer).
+func (m *Manifest) StripeForChunk(chunkIndex int64) (FecStripe, bool) {
+	for _, stripe := range m.FecStripes {
+		if int(chunkIndex) >= stripe.Start && int(chunkIndex) < stripe.Start+stripe.K+stripe.M {
+			return stripe, true
+		}
+	}
+	return FecStripe{}, false
+}
@@ -0,0 +1,141 @@
+package chunker
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/quantarax/backend/internal/fec"
+	"github.com/zeebo/blake3"
+)
+
+// BadEncodingProof is compact, offline-verifiable evidence that a sender's
+// Reed-Solomon shards are internally inconsistent with the chunk hash it
+// committed to in the signed manifest: fec.Decoder.Reconstruct succeeds (the
+// shards on hand were enough to recover ShardIndex), but the recovered bytes
+// don't hash to ExpectedLeafHash. Mirrors the fraud-proof construction used
+// in erasure-coded data availability sampling: republish just enough of the
+// stripe's shards for any third party to re-derive and check the mismatch,
+// without re-sending the whole stripe.
+type BadEncodingProof struct {
+	// ShardIndex is the offending shard's position within its stripe
+	// (0..K+R-1), used to index the full reconstructed shard set.
+	ShardIndex int `json:"shard_index"`
+	K          int `json:"k"`
+	R          int `json:"r"`
+	// ShardIndices/Shards hold exactly the shards VerifyBadEncoding needs to
+	// redo the reconstruction: every shard the receiver actually had,
+	// unavailable ones simply omitted.
+	ShardIndices []int   `json:"shard_indices"`
+	Shards       [][]byte `json:"shards"`
+	// ChunkIndex is ShardIndex's absolute position in the manifest's chunk
+	// list (manifest.Chunks / the Merkle tree's leaves), since a stripe's
+	// local shard numbering and the manifest's global chunk numbering
+	// differ.
+	ChunkIndex int64 `json:"chunk_index"`
+	// ExpectedLeafHash is the manifest's committed hash for ChunkIndex
+	// (base64 BLAKE3, as stored in ChunkDescriptor.Hash).
+	ExpectedLeafHash string `json:"expected_leaf_hash"`
+	// MerkleProof is ChunkIndex's sibling-hash authentication path, as
+	// produced by BuildMerkleProof, letting a verifier confirm
+	// ExpectedLeafHash was actually committed in the sender's manifest root.
+	MerkleProof [][]byte `json:"merkle_proof"`
+}
+
+// ProveBadEncoding builds a BadEncodingProof from receivedShards (exactly
+// k+r entries in shard order, any unavailable shard left nil) showing that
+// reconstructing them yields a shardIndex shard that does not hash to
+// expectedLeafHash, the manifest's committed hash for chunkIndex.
+// merkleProof is chunkIndex's sibling-hash authentication path (e.g. from
+// BuildMerkleProof), so VerifyBadEncoding can later confirm
+// expectedLeafHash was actually part of the signed manifest root.
+func ProveBadEncoding(shardIndex, k, r int, receivedShards [][]byte, chunkIndex int64, expectedLeafHash string, merkleProof [][]byte) (*BadEncodingProof, error) {
+	if len(receivedShards) != k+r {
+		return nil, fmt.Errorf("expected %d shards (k=%d + r=%d), got %d", k+r, k, r, len(receivedShards))
+	}
+
+	dec, err := fec.NewDecoder(k, r)
+	if err != nil {
+		return nil, err
+	}
+	working := make([][]byte, len(receivedShards))
+	copy(working, receivedShards)
+	if err := dec.Reconstruct(working); err != nil {
+		return nil, fmt.Errorf("shards do not reconstruct: %w", err)
+	}
+	if shardIndex < 0 || shardIndex >= len(working) || working[shardIndex] == nil {
+		return nil, fmt.Errorf("reconstructed shard set has nothing at index %d", shardIndex)
+	}
+	h := blake3.Sum256(working[shardIndex])
+	if base64.StdEncoding.EncodeToString(h[:]) == expectedLeafHash {
+		return nil, fmt.Errorf("shard %d matches the manifest hash; nothing to prove", shardIndex)
+	}
+
+	indices := make([]int, 0, len(receivedShards))
+	shards := make([][]byte, 0, len(receivedShards))
+	for i, s := range receivedShards {
+		if s == nil {
+			continue
+		}
+		indices = append(indices, i)
+		cp := make([]byte, len(s))
+		copy(cp, s)
+		shards = append(shards, cp)
+	}
+
+	return &BadEncodingProof{
+		ShardIndex:       shardIndex,
+		K:                k,
+		R:                r,
+		ShardIndices:     indices,
+		Shards:           shards,
+		ChunkIndex:       chunkIndex,
+		ExpectedLeafHash: expectedLeafHash,
+		MerkleProof:      merkleProof,
+	}, nil
+}
+
+// VerifyBadEncoding re-derives proof's missing shards via Reed-Solomon
+// reconstruction from its included shards, confirms the resulting
+// ShardIndex shard does NOT hash to ExpectedLeafHash (what makes this fraud
+// rather than an honest retransmit), and confirms ExpectedLeafHash was
+// actually committed under manifestRoot via MerkleProof — proving the
+// sender signed a manifest promising one hash while its erasure coding
+// produces another. Runs entirely offline from proof and the sender's
+// published manifest root; anyone holding both can check it.
+func VerifyBadEncoding(proof *BadEncodingProof, manifestRoot []byte) error {
+	if len(proof.Shards) < proof.K {
+		return fmt.Errorf("not enough shards in proof to reconstruct: have %d, need k=%d", len(proof.Shards), proof.K)
+	}
+	dec, err := fec.NewDecoder(proof.K, proof.R)
+	if err != nil {
+		return err
+	}
+	full := make([][]byte, proof.K+proof.R)
+	for i, idx := range proof.ShardIndices {
+		if idx < 0 || idx >= len(full) {
+			return fmt.Errorf("shard index %d out of range for k=%d r=%d", idx, proof.K, proof.R)
+		}
+		full[idx] = proof.Shards[i]
+	}
+	if err := dec.Reconstruct(full); err != nil {
+		return fmt.Errorf("proof shards are not reproducible: %w", err)
+	}
+	if proof.ShardIndex < 0 || proof.ShardIndex >= len(full) || full[proof.ShardIndex] == nil {
+		return fmt.Errorf("reconstructed shard set has nothing at index %d", proof.ShardIndex)
+	}
+
+	h := blake3.Sum256(full[proof.ShardIndex])
+	computed := base64.StdEncoding.EncodeToString(h[:])
+	if computed == proof.ExpectedLeafHash {
+		return fmt.Errorf("shard %d matches the manifest hash; no fraud demonstrated", proof.ShardIndex)
+	}
+
+	expectedLeaf, err := base64.StdEncoding.DecodeString(proof.ExpectedLeafHash)
+	if err != nil {
+		return fmt.Errorf("invalid expected leaf hash: %w", err)
+	}
+	if !VerifyProof(expectedLeaf, int(proof.ChunkIndex), proof.MerkleProof, manifestRoot) {
+		return fmt.Errorf("expected leaf hash is not committed under the manifest root")
+	}
+	return nil
+}
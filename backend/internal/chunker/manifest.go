@@ -18,13 +18,29 @@ type NetworkProfile struct {
 	PathChanges int    `json:"path_changes"`
 }
 
+// Key-agreement suite identifiers for TransferPolicies.Encryption.Suite,
+// matching internal/crypto/handshake's KEMX25519/KEMX25519MLKEM768 so a
+// manifest and the handshake that produced its session keys always agree
+// on the same string.
+const (
+	SuiteX25519         = "x25519"
+	SuiteX25519MLKEM768 = "x25519+mlkem768"
+)
+
 // TransferPolicies controls ACK/resume/encryption
 type TransferPolicies struct {
 	AckMode string `json:"ack"`
 	Resume  string `json:"resume"`
 	Encryption struct {
-		E2E    bool `json:"e2e"`
-		AtRest bool `json:"at_rest"`
+		E2E    bool   `json:"e2e"`
+		AtRest bool   `json:"at_rest"`
+		// Suite records which key-agreement suite (see Suite* consts
+		// above) the handshake negotiated for this transfer's session
+		// keys, so a receiver resuming from a snapshot or auditing a
+		// completed transfer later can tell whether it was PQ-hybrid
+		// protected without re-deriving anything. Empty means the field
+		// predates this manifest version (assume SuiteX25519).
+		Suite string `json:"suite,omitempty"`
 	} `json:"encryption"`
 	NoRelayCache bool `json:"no_relay_cache"`
 }
@@ -110,6 +126,7 @@ type Manifest struct {
 	FEC             *FECProfile      `json:"fec_profile,omitempty"`
 	Network         *NetworkProfile  `json:"network_profile,omitempty"`
 	Policies        *TransferPolicies `json:"transfer_policies,omitempty"`
+	FecStripes      []FecStripe      `json:"fec_stripes,omitempty"`
 
 	// Domain-specific optional blocks
 	MediaProfile       *MediaProfile       `json:"media_profile,omitempty"`
@@ -117,6 +134,18 @@ type Manifest struct {
 	EngineeringProfile *EngineeringProfile `json:"engineering_profile,omitempty"`
 	TelemetryProfile   *TelemetryProfile   `json:"telemetry_profile,omitempty"`
 	DTNProfile         *DTNProfile         `json:"dtn_profile,omitempty"`
+
+	// Codec records which CompressionCodec ApplyTransform actually applied
+	// (which may be CodecNone even when ChunkOptions.Compression asked for
+	// more, if the entropy heuristic judged the source already compressed),
+	// so a receiver decompresses chunk bytes with the right decoder instead
+	// of needing to re-derive the sender's choice out-of-band.
+	Codec string `json:"codec,omitempty"`
+	// KeyFingerprint is a short fingerprint of the EncryptionKey
+	// ApplyTransform sealed chunks with, if any — see fingerprintKey — so a
+	// receiver holding the wrong key can reject the transfer up front
+	// instead of failing every chunk's AEAD open one at a time.
+	KeyFingerprint string `json:"key_fingerprint,omitempty"`
 }
 
 // ChunkDescriptor describes a single chunk
@@ -129,6 +158,18 @@ type ChunkDescriptor struct {
 // ChunkOptions configures chunking behavior
 type ChunkOptions struct {
 	ChunkSize int // Chunk size in bytes (default: 1 MiB)
+
+	// Compression requests ApplyTransform compress filePath with the named
+	// codec before chunking. CodecNone or the zero value disable it.
+	// ComputeManifest may still skip the request (see
+	// looksAlreadyCompressed) if the source doesn't look compressible.
+	Compression CompressionCodec
+
+	// EncryptionKey, if set, has ApplyTransform AEAD-seal filePath (after
+	// any Compression) before chunking, so the bytes ComputeManifest hashes
+	// and the bytes that go out on the wire are the same encrypted ones.
+	// Must be exactly 32 bytes (AES-256), same as crypto.Seal requires.
+	EncryptionKey []byte
 }
 
 // DefaultChunkOptions returns default chunking options
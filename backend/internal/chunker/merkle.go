@@ -1,7 +1,9 @@
 package chunker
 
 import (
+	"bytes"
 	"encoding/base64"
+	"fmt"
 
 	"github.com/zeebo/blake3"
 )
@@ -51,3 +53,154 @@ func ComputeMerkleRoot(chunkHashes []string) (string, error) {
 	// Encode root as base64
 	return base64.StdEncoding.EncodeToString(hashes[0]), nil
 }
+
+// MerkleTree retains every level of the bottom-up BLAKE3 pairing (leaves at
+// Levels[0], root as the single node of the last level), so repeated
+// ProofFor calls for different leaves don't each have to rebuild the tree
+// from scratch the way BuildMerkleProof does.
+type MerkleTree struct {
+	Levels [][][]byte
+}
+
+// BuildMerkleTree builds a MerkleTree from base64-encoded chunk hashes,
+// using the same bottom-up pairing (and odd-node duplication) as
+// ComputeMerkleRoot.
+func BuildMerkleTree(chunkHashes []string) (*MerkleTree, error) {
+	if len(chunkHashes) == 0 {
+		return nil, fmt.Errorf("cannot build a merkle tree from zero hashes")
+	}
+
+	leaves := make([][]byte, len(chunkHashes))
+	for i, hashStr := range chunkHashes {
+		decoded, err := base64.StdEncoding.DecodeString(hashStr)
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = decoded
+	}
+
+	levels := [][][]byte{leaves}
+	current := leaves
+	for len(current) > 1 {
+		var next [][]byte
+		for i := 0; i < len(current); i += 2 {
+			left := current[i]
+			right := left
+			if i+1 < len(current) {
+				right = current[i+1]
+			}
+			combined := make([]byte, 0, len(left)+len(right))
+			combined = append(combined, left...)
+			combined = append(combined, right...)
+			hasher := blake3.New()
+			hasher.Write(combined)
+			next = append(next, hasher.Sum(nil))
+		}
+		levels = append(levels, next)
+		current = next
+	}
+
+	return &MerkleTree{Levels: levels}, nil
+}
+
+// Root returns the tree's root hash.
+func (t *MerkleTree) Root() []byte {
+	top := t.Levels[len(t.Levels)-1]
+	return top[0]
+}
+
+// ProofFor returns the sibling-hash authentication path for the leaf at
+// index: at each level it records the sibling at index^1 (or the node
+// itself when duplicated for an odd-length level), then halves index for
+// the level above, exactly mirroring BuildMerkleProof's single-pass version.
+func (t *MerkleTree) ProofFor(index int) ([][]byte, error) {
+	leaves := t.Levels[0]
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("chunk index %d out of range for %d leaves", index, len(leaves))
+	}
+
+	var proof [][]byte
+	idx := index
+	for level := 0; level < len(t.Levels)-1; level++ {
+		nodes := t.Levels[level]
+		siblingIdx := idx ^ 1
+		if siblingIdx >= len(nodes) {
+			siblingIdx = idx
+		}
+		proof = append(proof, nodes[siblingIdx])
+		idx /= 2
+	}
+	return proof, nil
+}
+
+// VerifyProof recomputes the root from leaf by folding in proof (as produced
+// by MerkleTree.ProofFor or BuildMerkleProof) and reports whether it matches
+// root. index determines left/right ordering at each level, mirroring
+// ComputeMerkleRoot's pairing.
+func VerifyProof(leaf []byte, index int, proof [][]byte, root []byte) bool {
+	current := leaf
+	idx := index
+	for _, sibling := range proof {
+		var combined []byte
+		if idx%2 == 0 {
+			combined = append(append([]byte{}, current...), sibling...)
+		} else {
+			combined = append(append([]byte{}, sibling...), current...)
+		}
+		hasher := blake3.New()
+		hasher.Write(combined)
+		current = hasher.Sum(nil)
+		idx /= 2
+	}
+	return bytes.Equal(current, root)
+}
+
+// BuildMerkleProof returns the sibling-hash authentication path for the leaf
+// at index, following the same bottom-up pairing (and odd-node duplication)
+// as ComputeMerkleRoot. The path has log2(len(chunkHashes)) entries, one
+// sibling hash per tree level, ordered leaf-to-root; manager.VerifyMerkleProof
+// recombines them with the leaf hash to recompute the root.
+func BuildMerkleProof(chunkHashes []string, index int) ([][]byte, error) {
+	if index < 0 || index >= len(chunkHashes) {
+		return nil, fmt.Errorf("chunk index %d out of range for %d hashes", index, len(chunkHashes))
+	}
+
+	hashes := make([][]byte, len(chunkHashes))
+	for i, hashStr := range chunkHashes {
+		decoded, err := base64.StdEncoding.DecodeString(hashStr)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = decoded
+	}
+
+	var proof [][]byte
+	idx := index
+	for len(hashes) > 1 {
+		var nextLevel [][]byte
+		for i := 0; i < len(hashes); i += 2 {
+			left := hashes[i]
+			right := left
+			if i+1 < len(hashes) {
+				right = hashes[i+1]
+			}
+
+			if i == idx {
+				proof = append(proof, right)
+			} else if i+1 == idx {
+				proof = append(proof, left)
+			}
+
+			combined := make([]byte, 0, len(left)+len(right))
+			combined = append(combined, left...)
+			combined = append(combined, right...)
+			hasher := blake3.New()
+			hasher.Write(combined)
+			nextLevel = append(nextLevel, hasher.Sum(nil))
+		}
+		idx /= 2
+		hashes = nextLevel
+	}
+
+	return proof, nil
+}
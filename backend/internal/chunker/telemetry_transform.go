@@ -0,0 +1,126 @@
+package chunker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/quantarax/backend/internal/telemetry"
+)
+
+// telemetryWriter is the io.WriteCloser newCompressWriter returns for
+// CodecTelemetry. Unlike gzip/zstd/lz4, telemetry.Codec needs every row in
+// hand before it can build a SchemeDictionaryRLE column's dictionary, so it
+// buffers the full CSV source and only encodes on Close.
+type telemetryWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func newTelemetryWriter(w io.Writer) io.WriteCloser {
+	return &telemetryWriter{w: w}
+}
+
+func (tw *telemetryWriter) Write(p []byte) (int, error) {
+	return tw.buf.Write(p)
+}
+
+func (tw *telemetryWriter) Close() error {
+	encoded, err := encodeTelemetryCSV(tw.buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("chunker: telemetry encode: %w", err)
+	}
+	_, err = tw.w.Write(encoded)
+	return err
+}
+
+// encodeTelemetryCSV parses data as a header row plus fixed-width data
+// rows, infers each column's telemetry.ColumnSpec, and returns the column
+// names followed by telemetry.Codec's encoded body. An integer column whose
+// name contains "id" (case-insensitive, matching CAN ID style headers) is
+// packed with SchemeDictionaryRLE; every other column is packed with
+// SchemeDoubleDelta, as ColumnFloat if any sampled value parses as a
+// non-integer.
+func encodeTelemetryCSV(data []byte) ([]byte, error) {
+	reader := csv.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse csv: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty telemetry source")
+	}
+	header := rows[0]
+	dataRows := rows[1:]
+
+	specs := make([]telemetry.ColumnSpec, len(header))
+	records := make([]telemetry.Record, len(dataRows))
+	for i := range records {
+		records[i].Values = make([]int64, len(header))
+	}
+
+	for col, name := range header {
+		colType := telemetry.ColumnInt
+		for _, row := range dataRows {
+			if col >= len(row) {
+				continue
+			}
+			if strings.ContainsAny(row[col], ".eE") {
+				if _, err := strconv.ParseFloat(row[col], 64); err == nil {
+					colType = telemetry.ColumnFloat
+					break
+				}
+			}
+		}
+		scheme := telemetry.SchemeDoubleDelta
+		if colType == telemetry.ColumnInt && strings.Contains(strings.ToLower(name), "id") {
+			scheme = telemetry.SchemeDictionaryRLE
+		}
+		specs[col] = telemetry.ColumnSpec{Type: colType, Scheme: scheme}
+
+		for r, row := range dataRows {
+			if col >= len(row) {
+				return nil, fmt.Errorf("row %d missing column %q", r, name)
+			}
+			if colType == telemetry.ColumnFloat {
+				v, err := strconv.ParseFloat(row[col], 64)
+				if err != nil {
+					return nil, fmt.Errorf("column %q row %d: %w", name, r, err)
+				}
+				records[r].Values[col] = telemetry.EncodeFloat64(v)
+			} else {
+				v, err := strconv.ParseInt(row[col], 0, 64)
+				if err != nil {
+					return nil, fmt.Errorf("column %q row %d: %w", name, r, err)
+				}
+				records[r].Values[col] = v
+			}
+		}
+	}
+
+	body, err := telemetry.NewCodec(specs).Encode(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	writeUvarint(&out, uint64(len(header)))
+	for _, name := range header {
+		writeUvarint(&out, uint64(len(name)))
+		out.WriteString(name)
+	}
+	out.Write(body)
+	return out.Bytes(), nil
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	buf.Write(tmp[:n])
+}
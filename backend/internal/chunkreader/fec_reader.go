@@ -0,0 +1,48 @@
+package chunkreader
+
+import "fmt"
+
+// FECReader composes a primary RangeReader over a transfer's source file
+// with a parity RangeReader over the FEC parity blob chunker.ApplyFECScheme
+// produced, presenting both as one contiguous address space keyed by chunk
+// index: offsets below dataSize read through to primary unchanged, and
+// everything from dataSize onward reads from parity (itself offset by
+// dataSize). This lets ChunkWorkerPool.sendChunk serve FEC parity chunks
+// through the exact same chunkIndex*chunkSize ReadAt path it already uses
+// for data chunks, with no special-casing in chunk_sender.go.
+type FECReader struct {
+	primary  RangeReader
+	parity   RangeReader
+	dataSize int64
+}
+
+// NewFECReader wraps primary (serving byte offsets [0, dataSize)) with
+// parity (serving everything from dataSize onward).
+func NewFECReader(primary, parity RangeReader, dataSize int64) *FECReader {
+	return &FECReader{primary: primary, parity: parity, dataSize: dataSize}
+}
+
+func (f *FECReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < f.dataSize {
+		return f.primary.ReadAt(p, off)
+	}
+	return f.parity.ReadAt(p, off-f.dataSize)
+}
+
+func (f *FECReader) Size() (int64, error) {
+	paritySize, err := f.parity.Size()
+	if err != nil {
+		return 0, fmt.Errorf("fec reader: parity size: %w", err)
+	}
+	return f.dataSize + paritySize, nil
+}
+
+// Close closes both the primary and parity readers, returning the first
+// error encountered (if any), but always attempting both.
+func (f *FECReader) Close() error {
+	err := f.primary.Close()
+	if perr := f.parity.Close(); err == nil {
+		err = perr
+	}
+	return err
+}
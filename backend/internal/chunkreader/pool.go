@@ -0,0 +1,37 @@
+package chunkreader
+
+import "sync"
+
+// BufferPool recycles fixed-size byte slices, avoiding a fresh
+// make([]byte, n) on every fragment read. Buffers whose capacity doesn't
+// match the pool's size (e.g. a segment's final, shorter fragment) are
+// simply not returned to the pool rather than rejected.
+type BufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+// NewBufferPool creates a pool of buffers of the given size.
+func NewBufferPool(size int) *BufferPool {
+	return &BufferPool{
+		size: size,
+		pool: sync.Pool{
+			New: func() interface{} {
+				return make([]byte, size)
+			},
+		},
+	}
+}
+
+// Get returns a size-length buffer, reused from the pool when available.
+func (bp *BufferPool) Get() []byte {
+	return bp.pool.Get().([]byte)
+}
+
+// Put returns buf to the pool if its capacity matches the pool's size.
+func (bp *BufferPool) Put(buf []byte) {
+	if cap(buf) != bp.size {
+		return
+	}
+	bp.pool.Put(buf[:bp.size])
+}
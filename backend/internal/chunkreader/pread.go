@@ -0,0 +1,36 @@
+package chunkreader
+
+import "os"
+
+// PReadReader reads through a single shared *os.File's ReadAt (pread(2)), so
+// concurrent chunk reads each issue their own syscall against one
+// already-open file descriptor instead of every chunk paying its own
+// os.Open. This is the default strategy.
+type PReadReader struct {
+	f *os.File
+}
+
+// NewPReadReader opens path once for the lifetime of the returned reader.
+func NewPReadReader(path string) (*PReadReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &PReadReader{f: f}, nil
+}
+
+func (r *PReadReader) ReadAt(p []byte, off int64) (int, error) {
+	return r.f.ReadAt(p, off)
+}
+
+func (r *PReadReader) Size() (int64, error) {
+	info, err := r.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (r *PReadReader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,62 @@
+package chunkreader
+
+import "golang.org/x/sys/unix"
+
+// PrefetchReader wraps a PReadReader and, as the consumer advances through
+// chunk indices, issues posix_fadvise(WILLNEED) hints chunksAhead chunks in
+// front of the cursor from a dedicated goroutine. This hides disk latency
+// for the next several chunks behind whatever encryption and QUIC send work
+// the caller is doing on the chunk it already has in hand.
+type PrefetchReader struct {
+	RangeReader
+
+	fd          int
+	chunkSize   int64
+	chunksAhead int64
+
+	advanceCh chan int64
+	done      chan struct{}
+}
+
+// NewPrefetchReader starts the background advisory goroutine for r.
+func NewPrefetchReader(r *PReadReader, chunkSize int64, chunksAhead int64) *PrefetchReader {
+	pr := &PrefetchReader{
+		RangeReader: r,
+		fd:          int(r.f.Fd()),
+		chunkSize:   chunkSize,
+		chunksAhead: chunksAhead,
+		advanceCh:   make(chan int64, 32),
+		done:        make(chan struct{}),
+	}
+	go pr.loop()
+	return pr
+}
+
+// Advance tells the prefetcher the consumer's cursor has reached
+// chunkIndex, so it can advise the kernel about the chunk chunksAhead
+// further on. It never blocks: a full advance queue just means the
+// prefetcher is already behind, and the next Advance call will catch it up.
+func (pr *PrefetchReader) Advance(chunkIndex int64) {
+	select {
+	case pr.advanceCh <- chunkIndex:
+	default:
+	}
+}
+
+func (pr *PrefetchReader) loop() {
+	for {
+		select {
+		case idx := <-pr.advanceCh:
+			ahead := idx + pr.chunksAhead
+			offset := ahead * pr.chunkSize
+			_ = unix.Fadvise(pr.fd, offset, pr.chunkSize, unix.FADV_WILLNEED)
+		case <-pr.done:
+			return
+		}
+	}
+}
+
+func (pr *PrefetchReader) Close() error {
+	close(pr.done)
+	return pr.RangeReader.Close()
+}
@@ -0,0 +1,45 @@
+// Package chunkreader provides shared, concurrency-safe strategies for
+// reading byte ranges out of a transfer's source file, so a ChunkWorkerPool
+// with N workers opens the file once (or once per strategy's own idiom)
+// instead of paying an open/seek/read per chunk.
+package chunkreader
+
+import (
+	"fmt"
+	"io"
+)
+
+// RangeReader reads byte ranges from an underlying file-like source. It must
+// be safe for concurrent use by multiple callers reading different (and
+// possibly overlapping) ranges at once, the same contract io.ReaderAt makes.
+type RangeReader interface {
+	io.ReaderAt
+	// Size returns the total size in bytes of the underlying content.
+	Size() (int64, error)
+	Close() error
+}
+
+// New opens path using the reader strategy named by kind: "pread" (the
+// default) shares one *os.File across all readers via ReadAt, "mmap"
+// memory-maps the whole file read-only, and "prefetch" wraps "pread" with a
+// background goroutine that issues readahead hints chunksAhead chunks in
+// front of the consumer.
+func New(kind, path string, chunkSize int64, chunksAhead int64) (RangeReader, error) {
+	switch kind {
+	case "", "pread":
+		return NewPReadReader(path)
+	case "mmap":
+		return NewMmapReader(path)
+	case "prefetch":
+		r, err := NewPReadReader(path)
+		if err != nil {
+			return nil, err
+		}
+		if chunksAhead <= 0 {
+			chunksAhead = 4
+		}
+		return NewPrefetchReader(r, chunkSize, chunksAhead), nil
+	default:
+		return nil, fmt.Errorf("chunkreader: unknown reader kind %q", kind)
+	}
+}
@@ -0,0 +1,178 @@
+package crypto
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/sys/cpu"
+)
+
+// AEADAlgorithm identifies a negotiated AEAD cipher with the single byte
+// carried in SignedManifest.Algorithm, the same way Codec names the
+// negotiated ControlCodec. 0 (the zero value) always means
+// AlgorithmAES256GCM, so a peer predating this negotiation still
+// interoperates.
+type AEADAlgorithm byte
+
+const (
+	// AlgorithmAES256GCM is what Seal and Open have always used.
+	AlgorithmAES256GCM AEADAlgorithm = iota
+	// AlgorithmChaCha20Poly1305 trades AES-NI hardware acceleration for a
+	// cipher that's fast in software, 3-4x faster than AES-256-GCM on
+	// ARM/embedded receivers without AES-NI.
+	AlgorithmChaCha20Poly1305
+	// AlgorithmXChaCha20Poly1305 extends ChaCha20-Poly1305 to a 24-byte
+	// nonce, wide enough to draw nonces at random instead of deriving them
+	// from a per-session counter (see DeriveNonce).
+	AlgorithmXChaCha20Poly1305
+)
+
+// String names algo for logging; unrecognized values report as the
+// AES-256-GCM default, matching NewAEAD's fallback.
+func (a AEADAlgorithm) String() string {
+	switch a {
+	case AlgorithmChaCha20Poly1305:
+		return "chacha20-poly1305"
+	case AlgorithmXChaCha20Poly1305:
+		return "xchacha20-poly1305"
+	default:
+		return "aes-256-gcm"
+	}
+}
+
+// AEAD is the interface Seal/Open's hardcoded AES-256-GCM pair conforms to
+// implicitly. NewAEAD resolves an AEADAlgorithm negotiated over the wire
+// (see SignedManifest.Algorithm) to the implementation that handles it,
+// while every call site keeps using the same Seal(key, nonce, aad,
+// plaintext) shape this package has always exposed.
+type AEAD interface {
+	Seal(key, nonce, aad, plaintext []byte) ([]byte, error)
+	Open(key, nonce, aad, ciphertext []byte) ([]byte, error)
+	NonceSize() int
+	KeySize() int
+	// ID is the single byte this algorithm occupies in SignedManifest.Algorithm.
+	ID() byte
+}
+
+// aesGCMAEAD defers to the package's original Seal/Open so the behavior
+// (and its Security Warning about nonce reuse) stays in one place.
+type aesGCMAEAD struct{}
+
+func (aesGCMAEAD) KeySize() int   { return 32 }
+func (aesGCMAEAD) NonceSize() int { return 12 }
+func (aesGCMAEAD) ID() byte       { return byte(AlgorithmAES256GCM) }
+func (aesGCMAEAD) Seal(key, nonce, aad, plaintext []byte) ([]byte, error) {
+	return Seal(key, nonce, aad, plaintext)
+}
+func (aesGCMAEAD) Open(key, nonce, aad, ciphertext []byte) ([]byte, error) {
+	return Open(key, nonce, aad, ciphertext)
+}
+
+type chacha20Poly1305AEAD struct{}
+
+func (chacha20Poly1305AEAD) KeySize() int   { return chacha20poly1305.KeySize }
+func (chacha20Poly1305AEAD) NonceSize() int { return chacha20poly1305.NonceSize }
+func (chacha20Poly1305AEAD) ID() byte       { return byte(AlgorithmChaCha20Poly1305) }
+
+func (chacha20Poly1305AEAD) Seal(key, nonce, aad, plaintext []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, fmt.Errorf("chacha20-poly1305: %w", err)
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidNonceSize, len(nonce))
+	}
+	return aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+func (chacha20Poly1305AEAD) Open(key, nonce, aad, ciphertext []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, fmt.Errorf("chacha20-poly1305: %w", err)
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidNonceSize, len(nonce))
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthenticationFailed, err)
+	}
+	return plaintext, nil
+}
+
+// xchacha20Poly1305AEAD uses chacha20poly1305.NewX, whose 24-byte nonce is
+// wide enough that a caller can pick one uniformly at random per message
+// instead of tracking a per-session counter like DeriveNonce does for the
+// 12-byte algorithms.
+type xchacha20Poly1305AEAD struct{}
+
+func (xchacha20Poly1305AEAD) KeySize() int   { return chacha20poly1305.KeySize }
+func (xchacha20Poly1305AEAD) NonceSize() int { return chacha20poly1305.NonceSizeX }
+func (xchacha20Poly1305AEAD) ID() byte       { return byte(AlgorithmXChaCha20Poly1305) }
+
+func (xchacha20Poly1305AEAD) Seal(key, nonce, aad, plaintext []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("xchacha20-poly1305: %w", err)
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidNonceSize, len(nonce))
+	}
+	return aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+func (xchacha20Poly1305AEAD) Open(key, nonce, aad, ciphertext []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("xchacha20-poly1305: %w", err)
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidNonceSize, len(nonce))
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAuthenticationFailed, err)
+	}
+	return plaintext, nil
+}
+
+// NewAEAD resolves algo to its AEAD implementation. An unrecognized value
+// (including the zero value) falls back to AES-256-GCM.
+func NewAEAD(algo AEADAlgorithm) AEAD {
+	switch algo {
+	case AlgorithmChaCha20Poly1305:
+		return chacha20Poly1305AEAD{}
+	case AlgorithmXChaCha20Poly1305:
+		return xchacha20Poly1305AEAD{}
+	default:
+		return aesGCMAEAD{}
+	}
+}
+
+// ResolveAEADAlgorithm maps the wire byte carried in
+// SignedManifest.Algorithm to the AEADAlgorithm it names. It never fails:
+// an unrecognized byte (e.g. from a newer peer) falls back to
+// AlgorithmAES256GCM, mirroring resolveCodec's fallback for an
+// unrecognized Codec name.
+func ResolveAEADAlgorithm(wire byte) AEADAlgorithm {
+	switch AEADAlgorithm(wire) {
+	case AlgorithmChaCha20Poly1305:
+		return AlgorithmChaCha20Poly1305
+	case AlgorithmXChaCha20Poly1305:
+		return AlgorithmXChaCha20Poly1305
+	default:
+		return AlgorithmAES256GCM
+	}
+}
+
+// PreferredAEADAlgorithm probes the local CPU for AES hardware acceleration
+// and returns the algorithm a sender should propose: AES-256-GCM where
+// AES-NI (or ARMv8 AES) is available, since it then outruns software
+// ChaCha20-Poly1305, and ChaCha20-Poly1305 everywhere else (e.g. many
+// embedded ARM receivers), where it is the 3-4x faster choice.
+func PreferredAEADAlgorithm() AEADAlgorithm {
+	if cpu.X86.HasAES || cpu.ARM64.HasAES {
+		return AlgorithmAES256GCM
+	}
+	return AlgorithmChaCha20Poly1305
+}
@@ -0,0 +1,51 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+// TestAEADRoundTrip exercises Seal/Open through NewAEAD for every
+// AEADAlgorithm, the same round-trip TestSealAndOpen already covers for the
+// package-level Seal/Open pair AlgorithmAES256GCM defers to.
+func TestAEADRoundTrip(t *testing.T) {
+	for _, algo := range []AEADAlgorithm{AlgorithmAES256GCM, AlgorithmChaCha20Poly1305, AlgorithmXChaCha20Poly1305} {
+		t.Run(algo.String(), func(t *testing.T) {
+			aead := NewAEAD(algo)
+			key := make([]byte, aead.KeySize())
+			nonce := make([]byte, aead.NonceSize())
+			rand.Read(key)
+			rand.Read(nonce)
+
+			plaintext := []byte("Hello from QuantaraX!")
+			aad := []byte("chunk-0")
+
+			ciphertext, err := aead.Seal(key, nonce, aad, plaintext)
+			if err != nil {
+				t.Fatalf("Seal() failed: %v", err)
+			}
+			decrypted, err := aead.Open(key, nonce, aad, ciphertext)
+			if err != nil {
+				t.Fatalf("Open() failed: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Error("Decrypted plaintext does not match original")
+			}
+
+			ciphertext[0] ^= 0x01
+			if _, err := aead.Open(key, nonce, aad, ciphertext); err == nil {
+				t.Error("Open() should fail on tampered ciphertext")
+			}
+		})
+	}
+}
+
+// TestResolveAEADAlgorithmFallback checks that an unrecognized wire byte
+// falls back to AlgorithmAES256GCM, the same way resolveCodec falls back
+// to CodecJSON for an unrecognized Codec name.
+func TestResolveAEADAlgorithmFallback(t *testing.T) {
+	if got := ResolveAEADAlgorithm(0xFF); got != AlgorithmAES256GCM {
+		t.Errorf("ResolveAEADAlgorithm(0xFF) = %v, want AlgorithmAES256GCM", got)
+	}
+}
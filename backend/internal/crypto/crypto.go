@@ -31,9 +31,10 @@ type X25519KeyPair struct {
 // SessionKeys contains cryptographically independent keys derived from
 // the shared secret using HKDF.
 type SessionKeys struct {
-	PayloadKey [32]byte // AES-256 key for chunk data encryption
-	ControlKey [32]byte // AES-256 key for control message encryption
-	IVBase     [12]byte // Base initialization vector for nonce derivation
+	PayloadKey      [32]byte // AES-256 key for chunk data encryption
+	ControlKey      [32]byte // AES-256 key for control message encryption
+	IVBase          [12]byte // Base initialization vector for nonce derivation
+	ConfirmationKey [32]byte // HMAC key for ComputeConfirmationTag/VerifyConfirmationTag, only set by the transcript-bound derivations
 }
 
 // KeystoreEntry represents an encrypted Ed25519 private key stored on disk.
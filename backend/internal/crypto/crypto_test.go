@@ -3,6 +3,7 @@ package crypto
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/hex"
 	"os"
 	"path/filepath"
 	"testing"
@@ -74,6 +75,39 @@ func TestX25519Exchange(t *testing.T) {
 	}
 }
 
+// TestX25519ExchangeRejectsLowOrderPoints verifies every known low-order
+// X25519 public key is rejected before scalar mult rather than producing a
+// key-independent shared secret.
+func TestX25519ExchangeRejectsLowOrderPoints(t *testing.T) {
+	alice, err := GenerateX25519()
+	if err != nil {
+		t.Fatalf("Failed to generate Alice's keypair: %v", err)
+	}
+
+	lowOrderHex := []string{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"0100000000000000000000000000000000000000000000000000000000000000",
+		"5f9c95bca3508c24b1d0b1559c83ef5b04445cc4581c8e86d8224eddd09f1157",
+		"e0eb7a7c3b41b8ae1656e3faf19fc46ada098deb9c32b1fd866205165f49b800",
+		"ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+		"edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+		"eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+	}
+
+	for _, h := range lowOrderHex {
+		raw, err := hex.DecodeString(h)
+		if err != nil || len(raw) != 32 {
+			t.Fatalf("bad test vector %q: %v", h, err)
+		}
+		var point [32]byte
+		copy(point[:], raw)
+
+		if _, err := X25519Exchange(&alice.PrivateKey, &point); err == nil {
+			t.Errorf("X25519Exchange(%s) succeeded, want rejection as low-order point", h)
+		}
+	}
+}
+
 // TestDeriveSessionKeys tests session key derivation is symmetric
 func TestDeriveSessionKeys(t *testing.T) {
 	// Generate keypairs
@@ -117,6 +151,161 @@ func TestDeriveSessionKeys(t *testing.T) {
 	}
 }
 
+// TestDeriveSessionKeysHybrid tests the hybrid variant is symmetric and
+// produces different keys than the classical-only derivation for the
+// same X25519 keypairs.
+func TestDeriveSessionKeysHybrid(t *testing.T) {
+	alice, err := GenerateX25519()
+	if err != nil {
+		t.Fatalf("Failed to generate Alice's keypair: %v", err)
+	}
+	bob, err := GenerateX25519()
+	if err != nil {
+		t.Fatalf("Failed to generate Bob's keypair: %v", err)
+	}
+
+	manifestHash := make([]byte, 32)
+	rand.Read(manifestHash)
+
+	kemSharedSecret := make([]byte, 32)
+	rand.Read(kemSharedSecret)
+
+	aliceKeys, err := DeriveSessionKeysHybrid(&alice.PrivateKey, &bob.PublicKey, kemSharedSecret, manifestHash)
+	if err != nil {
+		t.Fatalf("Alice's DeriveSessionKeysHybrid failed: %v", err)
+	}
+	bobKeys, err := DeriveSessionKeysHybrid(&bob.PrivateKey, &alice.PublicKey, kemSharedSecret, manifestHash)
+	if err != nil {
+		t.Fatalf("Bob's DeriveSessionKeysHybrid failed: %v", err)
+	}
+
+	if !bytes.Equal(aliceKeys.PayloadKey[:], bobKeys.PayloadKey[:]) {
+		t.Error("PayloadKeys do not match")
+	}
+	if !bytes.Equal(aliceKeys.ControlKey[:], bobKeys.ControlKey[:]) {
+		t.Error("ControlKeys do not match")
+	}
+	if !bytes.Equal(aliceKeys.IVBase[:], bobKeys.IVBase[:]) {
+		t.Error("IVBases do not match")
+	}
+
+	classicalKeys, err := DeriveSessionKeys(&alice.PrivateKey, &bob.PublicKey, manifestHash)
+	if err != nil {
+		t.Fatalf("DeriveSessionKeys failed: %v", err)
+	}
+	if bytes.Equal(aliceKeys.PayloadKey[:], classicalKeys.PayloadKey[:]) {
+		t.Error("hybrid and classical derivations should not collide")
+	}
+
+	if _, err := DeriveSessionKeysHybrid(&alice.PrivateKey, &bob.PublicKey, nil, manifestHash); err == nil {
+		t.Error("DeriveSessionKeysHybrid should reject an empty kemSharedSecret")
+	}
+}
+
+// TestEncodeTranscript checks the canonical encoding is deterministic and
+// that changing any one input changes the output.
+func TestEncodeTranscript(t *testing.T) {
+	senderPub := []byte("sender-pub-key")
+	receiverPub := []byte("receiver-pub-key")
+	manifestHash := make([]byte, 32)
+	rand.Read(manifestHash)
+
+	t1 := EncodeTranscript(TranscriptVersion, TranscriptSuiteX25519, senderPub, receiverPub, manifestHash)
+	t2 := EncodeTranscript(TranscriptVersion, TranscriptSuiteX25519, senderPub, receiverPub, manifestHash)
+	if !bytes.Equal(t1, t2) {
+		t.Error("EncodeTranscript is not deterministic for identical inputs")
+	}
+
+	if t3 := EncodeTranscript(TranscriptVersion, TranscriptSuiteX25519MLKEM768, senderPub, receiverPub, manifestHash); bytes.Equal(t1, t3) {
+		t.Error("changing the suite byte should change the transcript")
+	}
+
+	if t4 := EncodeTranscript(TranscriptVersion, TranscriptSuiteX25519, receiverPub, senderPub, manifestHash); bytes.Equal(t1, t4) {
+		t.Error("swapping sender/receiver pub keys should change the transcript")
+	}
+
+	if t1[0] != TranscriptVersion || t1[1] != TranscriptSuiteX25519 {
+		t.Error("transcript must start with version byte then suite byte")
+	}
+}
+
+// TestConfirmationTag checks matching tags verify and tampering is rejected.
+func TestConfirmationTag(t *testing.T) {
+	var confirmationKey [32]byte
+	rand.Read(confirmationKey[:])
+	transcript := []byte("some handshake transcript")
+
+	tag := ComputeConfirmationTag(confirmationKey, transcript)
+	if !VerifyConfirmationTag(confirmationKey, transcript, tag) {
+		t.Error("VerifyConfirmationTag should accept a correctly computed tag")
+	}
+
+	var otherKey [32]byte
+	rand.Read(otherKey[:])
+	if VerifyConfirmationTag(otherKey, transcript, tag) {
+		t.Error("VerifyConfirmationTag should reject a tag computed with a different key")
+	}
+
+	if VerifyConfirmationTag(confirmationKey, []byte("tampered transcript"), tag) {
+		t.Error("VerifyConfirmationTag should reject a tag over a different transcript")
+	}
+}
+
+// TestDeriveSessionKeysWithTranscript checks the transcript-bound derivation
+// is symmetric, produces a ConfirmationKey, and diverges from both
+// DeriveSessionKeys and itself under a different transcript.
+func TestDeriveSessionKeysWithTranscript(t *testing.T) {
+	alice, err := GenerateX25519()
+	if err != nil {
+		t.Fatalf("Failed to generate Alice's keypair: %v", err)
+	}
+	bob, err := GenerateX25519()
+	if err != nil {
+		t.Fatalf("Failed to generate Bob's keypair: %v", err)
+	}
+
+	manifestHash := make([]byte, 32)
+	rand.Read(manifestHash)
+	transcript := EncodeTranscript(TranscriptVersion, TranscriptSuiteX25519, alice.PublicKey[:], bob.PublicKey[:], manifestHash)
+
+	aliceKeys, err := DeriveSessionKeysWithTranscript(&alice.PrivateKey, &bob.PublicKey, manifestHash, transcript)
+	if err != nil {
+		t.Fatalf("Alice's DeriveSessionKeysWithTranscript failed: %v", err)
+	}
+	bobKeys, err := DeriveSessionKeysWithTranscript(&bob.PrivateKey, &alice.PublicKey, manifestHash, transcript)
+	if err != nil {
+		t.Fatalf("Bob's DeriveSessionKeysWithTranscript failed: %v", err)
+	}
+
+	if !bytes.Equal(aliceKeys.PayloadKey[:], bobKeys.PayloadKey[:]) {
+		t.Error("PayloadKeys do not match")
+	}
+	if !bytes.Equal(aliceKeys.ConfirmationKey[:], bobKeys.ConfirmationKey[:]) {
+		t.Error("ConfirmationKeys do not match")
+	}
+	var zero [32]byte
+	if bytes.Equal(aliceKeys.ConfirmationKey[:], zero[:]) {
+		t.Error("ConfirmationKey should not be the zero value")
+	}
+
+	classicalKeys, err := DeriveSessionKeys(&alice.PrivateKey, &bob.PublicKey, manifestHash)
+	if err != nil {
+		t.Fatalf("DeriveSessionKeys failed: %v", err)
+	}
+	if bytes.Equal(aliceKeys.PayloadKey[:], classicalKeys.PayloadKey[:]) {
+		t.Error("transcript-bound and plain derivations should not collide")
+	}
+
+	otherTranscript := EncodeTranscript(TranscriptVersion, TranscriptSuiteX25519MLKEM768, alice.PublicKey[:], bob.PublicKey[:], manifestHash)
+	diffTranscriptKeys, err := DeriveSessionKeysWithTranscript(&alice.PrivateKey, &bob.PublicKey, manifestHash, otherTranscript)
+	if err != nil {
+		t.Fatalf("DeriveSessionKeysWithTranscript with otherTranscript failed: %v", err)
+	}
+	if bytes.Equal(aliceKeys.PayloadKey[:], diffTranscriptKeys.PayloadKey[:]) {
+		t.Error("different transcripts should derive different keys")
+	}
+}
+
 // TestSealAndOpen tests AES-GCM encryption roundtrip
 func TestSealAndOpen(t *testing.T) {
 	// Generate random key and nonce
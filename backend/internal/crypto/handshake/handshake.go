@@ -3,6 +3,7 @@ package handshake
 import (
 	"crypto/ed25519"
 	"crypto/hmac"
+	"crypto/mlkem"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -14,25 +15,59 @@ import (
 	"golang.org/x/crypto/hkdf"
 )
 
+// KEM algorithms negotiable via ClientHello/ServerHello's KEMAlg field.
+// KEMX25519 is the classical-only handshake this package has always spoken;
+// KEMX25519MLKEM768 hybridizes it with ML-KEM-768 for forward secrecy
+// against a future quantum adversary, without dropping the X25519 leg.
+const (
+	KEMX25519         = "x25519"
+	KEMX25519MLKEM768 = "x25519+mlkem768"
+)
+
 type ClientHello struct {
-	Type        string `json:"type"`
-	SessionID   string `json:"session_id"`
-	ClientEph   string `json:"client_eph_pub"` // base64
-	ClientIDPub string `json:"client_id_pub"`  // base64 (ed25519)
-	Sig         string `json:"sig,omitempty"`  // base64 (ed25519 over transcript)
-	TokenHMAC   string `json:"token_hmac,omitempty"`
+	Type         string `json:"type"`
+	SessionID    string `json:"session_id"`
+	ClientEph    string `json:"client_eph_pub"` // base64
+	ClientIDPub  string `json:"client_id_pub"`  // base64 (ed25519)
+	KEMAlg       string `json:"kem_alg,omitempty"`
+	ClientKEMPub string `json:"client_kem_pub,omitempty"` // base64, ML-KEM-768 encapsulation key
+	Sig          string `json:"sig,omitempty"`            // base64 (ed25519 over transcript)
+	TokenHMAC    string `json:"token_hmac,omitempty"`
 }
 
 type ServerHello struct {
-	Type       string `json:"type"`
-	ServerEph  string `json:"server_eph_pub"`
-	ServerID   string `json:"server_id_pub"`
-	Sig        string `json:"sig,omitempty"`
+	Type        string `json:"type"`
+	ServerEph   string `json:"server_eph_pub"`
+	ServerID    string `json:"server_id_pub"`
+	KEMAlg      string `json:"kem_alg,omitempty"`
+	ServerKEMCT string `json:"server_kem_ct,omitempty"` // base64, ML-KEM-768 ciphertext
+	Sig         string `json:"sig,omitempty"`
 }
 
 type SessionKeys struct {
 	PayloadKey [32]byte
 	IVBase     [12]byte
+	// Suite is the negotiated KEMX25519/KEMX25519MLKEM768 identifier,
+	// suitable for stashing directly into a
+	// chunker.TransferPolicies.Encryption.Suite field.
+	Suite string
+	// ConfirmationKey is used internally by Client/ServerHandshake's key
+	// confirmation exchange via crypto.ComputeConfirmationTag/
+	// VerifyConfirmationTag; callers don't need to touch it themselves.
+	ConfirmationKey [32]byte
+}
+
+// ConfirmMessage is the final handshake message each side sends after
+// deriving session keys: an HMAC-SHA256 tag (see
+// crypto.ComputeConfirmationTag) over the full transcript, keyed by
+// ConfirmationKey. Both Client/ServerHandshake verify the peer's tag
+// before returning keys to the caller, so a peer that derived different
+// keys - whether from MITM tampering, a downgraded/stripped KEM offer,
+// or a bug - is caught here instead of only surfacing later as garbled
+// payload ciphertext.
+type ConfirmMessage struct {
+	Type string `json:"type"`
+	Tag  string `json:"tag"` // base64
 }
 
 func serialize(v any) []byte { b, _ := json.Marshal(v); return b }
@@ -52,18 +87,52 @@ func verify(pub ed25519.PublicKey, sigb64 string, parts ...[]byte) bool {
 	return ed25519.Verify(pub, msg, sig)
 }
 
-// Derive session keys using HKDF-SHA256 over ECDH + transcript hash
+// Derive session keys using HKDF-SHA256 over ECDH + transcript hash.
+// Output is 76 bytes: 32 (PayloadKey) + 12 (IVBase) + 32 (ConfirmationKey).
+//
+// transcript here is this package's own JSON-serialized ClientHello||
+// ServerHello concatenation, not crypto.EncodeTranscript's fixed binary
+// encoding - this handshake already binds its derivation to every field
+// of both hello messages (including KEMAlg/KEM public keys/ciphertext),
+// so switching encodings would change no security property, only churn
+// every call site. crypto.EncodeTranscript/DeriveSessionKeysWithTranscript
+// is the canonical primitive for callers that don't already have an
+// equivalent transcript of their own.
 func deriveKeys(shared []byte, transcript []byte) (SessionKeys, error) {
 	salt := sha256.Sum256(transcript)
 	h := hkdf.New(sha256.New, shared, salt[:], []byte("quantarax-session-keys"))
-	var out [48]byte
+	var out [76]byte
 	if _, err := io.ReadFull(h, out[:]); err != nil { return SessionKeys{}, err }
 	var sk SessionKeys
 	copy(sk.PayloadKey[:], out[:32])
 	copy(sk.IVBase[:], out[32:44])
+	copy(sk.ConfirmationKey[:], out[44:76])
 	return sk, nil
 }
 
+// confirmKeys exchanges and verifies a ConfirmMessage tag derived from
+// sk.ConfirmationKey over transcript: it writes this side's tag, reads
+// the peer's, and fails closed if they don't match in constant time.
+// enc/dec must be the same Encoder/Decoder the caller already used for
+// the rest of the handshake on rw - a fresh json.Decoder can silently
+// drop bytes the old one already buffered from the stream.
+func confirmKeys(enc *json.Encoder, dec *json.Decoder, sk SessionKeys, transcript []byte) error {
+	myTag := crypto.ComputeConfirmationTag(sk.ConfirmationKey, transcript)
+	if err := enc.Encode(&ConfirmMessage{Type: "confirm", Tag: base64.StdEncoding.EncodeToString(myTag)}); err != nil {
+		return err
+	}
+
+	var peerConfirm ConfirmMessage
+	if err := dec.Decode(&peerConfirm); err != nil { return err }
+	if peerConfirm.Type != "confirm" { return fmt.Errorf("unexpected msg: %s", peerConfirm.Type) }
+	peerTag, err := base64.StdEncoding.DecodeString(peerConfirm.Tag)
+	if err != nil { return fmt.Errorf("bad confirmation tag encoding: %w", err) }
+	if !crypto.VerifyConfirmationTag(sk.ConfirmationKey, transcript, peerTag) {
+		return fmt.Errorf("key confirmation failed: peer derived different session keys")
+	}
+	return nil
+}
+
 // Compute HMAC binding to optional token secret
 func computeTokenHMAC(secret []byte, transcript []byte) string {
 	h := hmac.New(sha256.New, secret)
@@ -71,16 +140,35 @@ func computeTokenHMAC(secret []byte, transcript []byte) string {
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
-// Client performs handshake on provided io.ReadWriter (stream)
-func ClientHandshake(rw io.ReadWriter, sessionID string, clientIDPriv ed25519.PrivateKey, clientIDPub ed25519.PublicKey, tokenSecret []byte) (SessionKeys, error) {
+// Client performs handshake on provided io.ReadWriter (stream). kemAlg
+// selects which KEM this client offers: KEMX25519 for the original
+// classical-only handshake, or KEMX25519MLKEM768 to additionally offer a
+// hybrid ML-KEM-768 encapsulation key the server can use if it supports PQ.
+func ClientHandshake(rw io.ReadWriter, sessionID string, clientIDPriv ed25519.PrivateKey, clientIDPub ed25519.PublicKey, tokenSecret []byte, kemAlg string) (SessionKeys, error) {
 	// generate ephemeral X25519
 	kp, err := crypto.GenerateX25519()
 	if err != nil { return SessionKeys{}, err }
 	clientEphB64 := base64.StdEncoding.EncodeToString(kp.PublicKey[:])
 	clientIDB64 := base64.StdEncoding.EncodeToString(clientIDPub)
 	ch := ClientHello{Type:"client_hello", SessionID: sessionID, ClientEph: clientEphB64, ClientIDPub: clientIDB64}
-	// sign transcript so far
-	sig, err := sign(clientIDPriv, []byte("client"), []byte(sessionID), []byte(clientEphB64), []byte(clientIDB64))
+
+	var kemDK *mlkem.DecapsulationKey768
+	if kemAlg == KEMX25519MLKEM768 {
+		kemDK, err = mlkem.GenerateKey768()
+		if err != nil { return SessionKeys{}, fmt.Errorf("generate ML-KEM-768 keypair: %w", err) }
+		ch.KEMAlg = KEMX25519MLKEM768
+		ch.ClientKEMPub = base64.StdEncoding.EncodeToString(kemDK.EncapsulationKey().Bytes())
+	}
+
+	// sign transcript so far. KEMAlg/ClientKEMPub only enter the signed
+	// message when non-empty, so a classical-only ClientHello's signature
+	// is byte-for-byte what this package has always produced; when they
+	// are set, including them here is what makes a downgrade attacker's
+	// tampering (stripping them in transit) fail signature verification
+	// on the receiving side instead of silently falling back.
+	signParts := [][]byte{[]byte("client"), []byte(sessionID), []byte(clientEphB64), []byte(clientIDB64)}
+	if ch.KEMAlg != "" { signParts = append(signParts, []byte(ch.KEMAlg), []byte(ch.ClientKEMPub)) }
+	sig, err := sign(clientIDPriv, signParts...)
 	if err == nil { ch.Sig = sig }
 	// optional token binding
 	transcript := serialize(ch)
@@ -96,7 +184,9 @@ func ClientHandshake(rw io.ReadWriter, sessionID string, clientIDPriv ed25519.Pr
 	// verify server sig if present
 	srvPubB, _ := base64.StdEncoding.DecodeString(sh.ServerID)
 	if sh.Sig != "" && len(srvPubB) == ed25519.PublicKeySize {
-		ok := verify(ed25519.PublicKey(srvPubB), sh.Sig, []byte("server"), []byte(sessionID), []byte(sh.ServerEph), []byte(sh.ServerID))
+		verifyParts := [][]byte{[]byte("server"), []byte(sessionID), []byte(sh.ServerEph), []byte(sh.ServerID)}
+		if sh.KEMAlg != "" { verifyParts = append(verifyParts, []byte(sh.KEMAlg), []byte(sh.ServerKEMCT)) }
+		ok := verify(ed25519.PublicKey(srvPubB), sh.Sig, verifyParts...)
 		if !ok { return SessionKeys{}, fmt.Errorf("server signature invalid") }
 	}
 	// derive shared
@@ -104,12 +194,37 @@ func ClientHandshake(rw io.ReadWriter, sessionID string, clientIDPriv ed25519.Pr
 	if len(srvEphB) != 32 { return SessionKeys{}, fmt.Errorf("bad server eph") }
 	var srvEph [32]byte; copy(srvEph[:], srvEphB)
 	shared := crypto.SharedSecret(&kp.PrivateKey, &srvEph)
+	ikm := shared[:]
+
+	// Only combine in an ML-KEM-768 shared secret when the server actually
+	// answered with one; a server that doesn't support PQ (or that a
+	// downgrade stripped the offer from) leaves sh.KEMAlg empty and the
+	// handshake falls back to the classical-only derivation cleanly.
+	negotiatedSuite := KEMX25519
+	if kemDK != nil && sh.KEMAlg == KEMX25519MLKEM768 && sh.ServerKEMCT != "" {
+		ct, err := base64.StdEncoding.DecodeString(sh.ServerKEMCT)
+		if err != nil { return SessionKeys{}, fmt.Errorf("bad server ML-KEM ciphertext: %w", err) }
+		mlkemSS, err := kemDK.Decapsulate(ct)
+		if err != nil { return SessionKeys{}, fmt.Errorf("ML-KEM-768 decapsulate: %w", err) }
+		ikm = append(append([]byte{}, ikm...), mlkemSS...)
+		negotiatedSuite = KEMX25519MLKEM768
+	}
+
 	// derive keys
 	transcriptB := append(transcript, serialize(sh)...)
-	return deriveKeys(shared[:], transcriptB)
+	sk, err := deriveKeys(ikm, transcriptB)
+	if err != nil { return SessionKeys{}, err }
+	sk.Suite = negotiatedSuite
+	if err := confirmKeys(enc, dec, sk, transcriptB); err != nil { return SessionKeys{}, err }
+	return sk, nil
 }
 
-// Server performs handshake and returns session keys
+// Server performs handshake and returns session keys. It answers whatever
+// KEMAlg the client offered in its ClientHello: a classical-only offer gets
+// a classical-only ServerHello back, and a KEMX25519MLKEM768 offer gets a
+// hybrid response unless the client's encapsulation key is malformed, in
+// which case the server falls back to classical-only rather than failing
+// the handshake outright.
 func ServerHandshake(rw io.ReadWriter, sessionID string, serverIDPriv ed25519.PrivateKey, serverIDPub ed25519.PublicKey, tokenSecret []byte) (SessionKeys, error) {
 	dec := json.NewDecoder(rw)
 	var ch ClientHello
@@ -119,7 +234,9 @@ func ServerHandshake(rw io.ReadWriter, sessionID string, serverIDPriv ed25519.Pr
 	// verify client sig if present
 	cliPubB, _ := base64.StdEncoding.DecodeString(ch.ClientIDPub)
 	if ch.Sig != "" && len(cliPubB) == ed25519.PublicKeySize {
-		ok := verify(ed25519.PublicKey(cliPubB), ch.Sig, []byte("client"), []byte(ch.SessionID), []byte(ch.ClientEph), []byte(ch.ClientIDPub))
+		verifyParts := [][]byte{[]byte("client"), []byte(ch.SessionID), []byte(ch.ClientEph), []byte(ch.ClientIDPub)}
+		if ch.KEMAlg != "" { verifyParts = append(verifyParts, []byte(ch.KEMAlg), []byte(ch.ClientKEMPub)) }
+		ok := verify(ed25519.PublicKey(cliPubB), ch.Sig, verifyParts...)
 		if !ok { return SessionKeys{}, fmt.Errorf("client signature invalid") }
 	}
 	// optional token binding check (best-effort)
@@ -128,12 +245,29 @@ func ServerHandshake(rw io.ReadWriter, sessionID string, serverIDPriv ed25519.Pr
 		expected := computeTokenHMAC(tokenSecret, transcript)
 		if !strings.EqualFold(expected, ch.TokenHMAC) { return SessionKeys{}, fmt.Errorf("token binding invalid") }
 	}
+
+	var mlkemSS []byte
+	var kemAlg, kemCT string
+	if ch.KEMAlg == KEMX25519MLKEM768 && ch.ClientKEMPub != "" {
+		ekb, err := base64.StdEncoding.DecodeString(ch.ClientKEMPub)
+		if err == nil {
+			if ek, err := mlkem.NewEncapsulationKey768(ekb); err == nil {
+				ss, ct := ek.Encapsulate()
+				mlkemSS, kemAlg, kemCT = ss, KEMX25519MLKEM768, base64.StdEncoding.EncodeToString(ct)
+			}
+		}
+		// A malformed client encapsulation key falls back to classical-only
+		// (kemAlg left empty) rather than aborting the handshake.
+	}
+
 	// generate server eph
 	kp, err := crypto.GenerateX25519(); if err != nil { return SessionKeys{}, err }
 	srvEphB64 := base64.StdEncoding.EncodeToString(kp.PublicKey[:])
 	srvIDB64 := base64.StdEncoding.EncodeToString(serverIDPub)
-	sh := ServerHello{Type:"server_hello", ServerEph: srvEphB64, ServerID: srvIDB64}
-	sig, err := sign(serverIDPriv, []byte("server"), []byte(ch.SessionID), []byte(srvEphB64), []byte(srvIDB64))
+	sh := ServerHello{Type:"server_hello", ServerEph: srvEphB64, ServerID: srvIDB64, KEMAlg: kemAlg, ServerKEMCT: kemCT}
+	signParts := [][]byte{[]byte("server"), []byte(ch.SessionID), []byte(srvEphB64), []byte(srvIDB64)}
+	if sh.KEMAlg != "" { signParts = append(signParts, []byte(sh.KEMAlg), []byte(sh.ServerKEMCT)) }
+	sig, err := sign(serverIDPriv, signParts...)
 	if err == nil { sh.Sig = sig }
 	// send
 	enc := json.NewEncoder(rw)
@@ -143,7 +277,17 @@ func ServerHandshake(rw io.ReadWriter, sessionID string, serverIDPriv ed25519.Pr
 	if len(cliEphB) != 32 { return SessionKeys{}, fmt.Errorf("bad client eph") }
 	var cliEph [32]byte; copy(cliEph[:], cliEphB)
 	shared := crypto.SharedSecret(&kp.PrivateKey, &cliEph)
+	ikm := shared[:]
+	negotiatedSuite := KEMX25519
+	if mlkemSS != nil {
+		ikm = append(append([]byte{}, ikm...), mlkemSS...)
+		negotiatedSuite = KEMX25519MLKEM768
+	}
 	// derive keys
 	transcriptB := append(transcript, serialize(sh)...)
-	return deriveKeys(shared[:], transcriptB)
+	sk, err := deriveKeys(ikm, transcriptB)
+	if err != nil { return SessionKeys{}, err }
+	sk.Suite = negotiatedSuite
+	if err := confirmKeys(enc, dec, sk, transcriptB); err != nil { return SessionKeys{}, err }
+	return sk, nil
 }
@@ -41,6 +41,35 @@ func LoadOrCreate(privPath, pubPath string) (ed25519.PrivateKey, ed25519.PublicK
 	return priv, pub, nil
 }
 
+// Rotate generates a fresh ed25519 keypair and overwrites privPath/pubPath
+// (same path defaulting as LoadOrCreate) with it, unconditionally — unlike
+// LoadOrCreate, an existing keypair at those paths is replaced rather than
+// loaded. Callers that need the old identity (e.g. to announce the new one
+// signed by the old) must load it themselves before calling Rotate.
+func Rotate(privPath, pubPath string) (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	if privPath == "" {
+		p, u, err := DefaultPaths()
+		if err != nil {
+			return nil, nil, err
+		}
+		privPath, pubPath = p, u
+	}
+	if pubPath == "" {
+		pubPath = privPath + ".pub"
+	}
+	if err := os.MkdirAll(filepath.Dir(privPath), 0o700); err != nil {
+		return nil, nil, err
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeKeyFiles(privPath, pubPath, priv, pub); err != nil {
+		return nil, nil, err
+	}
+	return priv, pub, nil
+}
+
 func load(privPath, pubPath string) (ed25519.PrivateKey, ed25519.PublicKey, error) {
 	pbytes, err := os.ReadFile(privPath)
 	if err != nil { return nil, nil, err }
@@ -0,0 +1,33 @@
+//go:build darwin
+
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+)
+
+// KeychainPassphraseSource reads the passphrase from the macOS login
+// Keychain via the `security` CLI, keyed on Service and purpose. As with
+// the Linux secret-tool variant, this shells out rather than linking
+// Security.framework directly, to avoid a cgo dependency in a package
+// that's otherwise pure Go.
+type KeychainPassphraseSource struct {
+	// Service scopes the lookup, e.g. "quantarax". Defaults to "quantarax"
+	// if empty.
+	Service string
+}
+
+func (k KeychainPassphraseSource) Passphrase(purpose string) (string, error) {
+	service := k.Service
+	if service == "" {
+		service = "quantarax"
+	}
+
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", purpose, "-w").Output()
+	if err != nil {
+		return "", fmt.Errorf("security find-generic-password failed (is the item present in the login keychain?): %w", err)
+	}
+	return string(bytes.TrimRight(out, "\n")), nil
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+)
+
+// KeychainPassphraseSource reads the passphrase from the session's
+// freedesktop Secret Service keyring (GNOME Keyring, KWallet, etc.) via
+// the `secret-tool` CLI (part of libsecret-tools), keyed on Service and
+// purpose. There's no cgo-free way to speak the Secret Service D-Bus API
+// directly without a new dependency, so this shells out the same way the
+// daemon already does for other best-effort OS integrations.
+type KeychainPassphraseSource struct {
+	// Service scopes the lookup, e.g. "quantarax". Defaults to "quantarax"
+	// if empty.
+	Service string
+}
+
+func (k KeychainPassphraseSource) Passphrase(purpose string) (string, error) {
+	service := k.Service
+	if service == "" {
+		service = "quantarax"
+	}
+
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "purpose", purpose).Output()
+	if err != nil {
+		return "", fmt.Errorf("secret-tool lookup failed (is libsecret-tools installed and a keyring unlocked?): %w", err)
+	}
+	return string(bytes.TrimRight(out, "\n")), nil
+}
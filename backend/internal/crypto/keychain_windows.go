@@ -0,0 +1,57 @@
+//go:build windows
+
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+)
+
+// KeychainPassphraseSource reads the passphrase from the Windows
+// Credential Manager via `cmdkey`'s generic credential store, keyed on
+// Service and purpose. cmdkey can't print a stored secret directly, so
+// this shells out to a small PowerShell snippet using the Windows
+// Credential Manager COM/CredRead API instead of linking against it via
+// cgo.
+type KeychainPassphraseSource struct {
+	// Service scopes the lookup, e.g. "quantarax". Defaults to "quantarax"
+	// if empty.
+	Service string
+}
+
+func (k KeychainPassphraseSource) Passphrase(purpose string) (string, error) {
+	service := k.Service
+	if service == "" {
+		service = "quantarax"
+	}
+	target := service + ":" + purpose
+
+	script := `
+$ErrorActionPreference = "Stop"
+Add-Type -AssemblyName System.Runtime.InteropServices
+$sig = @"
+using System;
+using System.Runtime.InteropServices;
+public class CredManager {
+    [DllImport("advapi32.dll", SetLastError = true, CharSet = CharSet.Unicode)]
+    public static extern bool CredRead(string target, int type, int flags, out IntPtr credential);
+}
+"@
+Add-Type -TypeDefinition $sig
+$cred = [IntPtr]::Zero
+$ok = [CredManager]::CredRead($args[0], 1, 0, [ref]$cred)
+if (-not $ok) { exit 1 }
+$offset = [IntPtr]::Size * 2 + 4 * 6
+$blobPtr = [System.Runtime.InteropServices.Marshal]::ReadIntPtr($cred, $offset)
+$blobLen = [System.Runtime.InteropServices.Marshal]::ReadInt32($cred, $offset - 4)
+$bytes = New-Object byte[] $blobLen
+[System.Runtime.InteropServices.Marshal]::Copy($blobPtr, $bytes, 0, $blobLen)
+[System.Text.Encoding]::Unicode.GetString($bytes)
+`
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script, target).Output()
+	if err != nil {
+		return "", fmt.Errorf("reading Windows Credential Manager entry %q failed: %w", target, err)
+	}
+	return string(bytes.TrimRight(out, "\r\n")), nil
+}
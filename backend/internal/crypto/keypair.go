@@ -3,6 +3,8 @@ package crypto
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
 	"fmt"
 
@@ -48,35 +50,89 @@ func GenerateX25519() (*X25519KeyPair, error) {
 	return &kp, nil
 }
 
+// lowOrderPoints holds the known low-order X25519 public keys (hex,
+// big-endian as written in RFC 7748 test vectors): the two order-8 points,
+// the order-4 point at p-1, the order-2 point at 0, and the order-1 point
+// at 1, plus the non-canonical (>= p) encodings of 0 and 1. A peer who
+// sends one of these forces the scalar multiplication to land on a small
+// subgroup, producing a shared secret that doesn't depend on our private
+// key at all — accepting it would let an attacker impersonate any peer
+// regardless of which key they actually hold.
+//
+// Every entry here is independently verified by TestLowOrderPointsDoubleToIdentity,
+// which doubles each one with the same Montgomery-ladder formula X25519
+// itself uses and confirms it collapses to the point at infinity within
+// three doublings (i.e. its order divides 8) — catching a hand-transcribed
+// hex literal that doesn't actually correspond to a low-order point.
+var lowOrderPoints = [][32]byte{
+	decodeLowOrderPoint("0000000000000000000000000000000000000000000000000000000000000000"),
+	decodeLowOrderPoint("0100000000000000000000000000000000000000000000000000000000000000"),
+	decodeLowOrderPoint("5f9c95bca3508c24b1d0b1559c83ef5b04445cc4581c8e86d8224eddd09f1157"),
+	decodeLowOrderPoint("e0eb7a7c3b41b8ae1656e3faf19fc46ada098deb9c32b1fd866205165f49b800"),
+	decodeLowOrderPoint("ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f"),
+	decodeLowOrderPoint("edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f"),
+	decodeLowOrderPoint("eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f"),
+}
+
+// decodeLowOrderPoint decodes one of lowOrderPoints' 64-hex-digit literals;
+// it panics on a malformed literal since the set is a fixed compile-time
+// constant, not attacker- or caller-controlled input.
+func decodeLowOrderPoint(h string) [32]byte {
+	raw, err := hex.DecodeString(h)
+	if err != nil || len(raw) != 32 {
+		panic("crypto: malformed lowOrderPoints literal")
+	}
+	var point [32]byte
+	copy(point[:], raw)
+	return point
+}
+
+// isLowOrderPoint reports whether candidate matches one of lowOrderPoints,
+// using subtle.ConstantTimeCompare for every entry so a peer can't learn
+// which low-order point it's closest to — or even whether it's low-order
+// at all — by timing this check.
+func isLowOrderPoint(candidate *[32]byte) bool {
+	var found int
+	for _, p := range lowOrderPoints {
+		found |= subtle.ConstantTimeCompare(candidate[:], p[:])
+	}
+	return found == 1
+}
+
 // X25519Exchange performs Elliptic Curve Diffie-Hellman key exchange.
 // Given our private key and peer's public key, computes the shared secret.
 //
+// Before multiplying, theirPublic is checked in constant time against the
+// known low-order X25519 points (see lowOrderPoints) and rejected if it
+// matches one — a contributory-behavior attack that curve25519.X25519's
+// own all-zero-output check alone doesn't catch for every low-order point.
+//
 // Parameters:
 //   - ourPrivate: Our X25519 private key
 //   - theirPublic: Peer's X25519 public key
 //
 // Returns:
 //   - sharedSecret: 32-byte shared secret
-//   - error if ECDH computation fails
+//   - error if theirPublic is a low-order point or ECDH computation fails
 func X25519Exchange(ourPrivate, theirPublic *[32]byte) ([32]byte, error) {
 	var sharedSecret [32]byte
 
-	// Perform scalar multiplication: sharedSecret = ourPrivate * theirPublic
-	curve25519.ScalarMult(&sharedSecret, ourPrivate, theirPublic)
+	if isLowOrderPoint(theirPublic) {
+		return sharedSecret, errors.New("X25519 exchange rejected: peer public key is a known low-order point")
+	}
 
-	// Check for all-zero output (invalid exchange)
-	allZero := true
-	for _, b := range sharedSecret {
-		if b != 0 {
-			allZero = false
-			break
-		}
+	// curve25519.X25519 (RFC 7748, Section 5) replaces the deprecated
+	// ScalarMult and performs its own all-zero-output rejection internally.
+	secret, err := curve25519.X25519(ourPrivate[:], theirPublic[:])
+	if err != nil {
+		return sharedSecret, fmt.Errorf("X25519 exchange failed: %w", err)
 	}
 
-	if allZero {
+	if subtle.ConstantTimeCompare(secret, make([]byte, 32)) == 1 {
 		return sharedSecret, errors.New("X25519 exchange resulted in all-zero shared secret (invalid public key)")
 	}
 
+	copy(sharedSecret[:], secret)
 	return sharedSecret, nil
 }
 
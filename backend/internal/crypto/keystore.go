@@ -7,30 +7,95 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/google/uuid"
 	"golang.org/x/crypto/argon2"
 )
 
 const (
 	// Argon2id parameters (recommended values for interactive use)
-	argon2Time    = 3      // Number of iterations
-	argon2Memory  = 65536  // Memory in KiB (64 MiB)
-	argon2Threads = 4      // Parallelism factor
-	argon2KeyLen  = 32     // Output key length (AES-256)
-	saltSize      = 32     // Salt size in bytes
-	keystoreVersion = 1    // Keystore format version
+	argon2Time    = 3     // Number of iterations
+	argon2Memory  = 65536 // Memory in KiB (64 MiB)
+	argon2Threads = 4     // Parallelism factor
+	argon2KeyLen  = 32    // Output key length (AES-256)
+	saltSize      = 32    // Salt size in bytes
+
+	// keystoreVersion is the current on-disk format. Version 1 files held a
+	// single top-level KeystoreEntry; version 2 wraps zero or more KeySlots
+	// in a Keystore so multiple identities and retired signing keys can
+	// coexist in one file. parseKeystore migrates version 1 files to this
+	// shape transparently on load.
+	keystoreVersion = 2
+
+	// DefaultRetiredKeyGrace is how long a slot RotateKey retires stays on
+	// disk before PurgeRetired reaps it, so manifests signed just before
+	// the rotation still have a key to verify against.
+	DefaultRetiredKeyGrace = 7 * 24 * time.Hour
 )
 
 var (
 	// ErrInvalidPassphrase is returned when the passphrase fails to decrypt the keystore
 	ErrInvalidPassphrase = errors.New("invalid passphrase or corrupted keystore")
+	// ErrKeyNotFound is returned by RotateKey/RemoveKey when keyID has no matching slot.
+	ErrKeyNotFound = errors.New("keystore: key id not found")
 )
 
+// KeystoreEntry is the version 1 on-disk shape: a single encrypted Ed25519
+// private key with no KeyID or purpose. It is kept around so parseKeystore
+// can still read pre-v2 files during migration; new code should use KeySlot.
+type KeystoreEntry struct {
+	Version       int    `json:"version"`        // Format version (1)
+	KDF           string `json:"kdf"`            // Key derivation function ("argon2id")
+	Argon2Time    int    `json:"argon2_time"`    // Argon2 time parameter
+	Argon2Memory  int    `json:"argon2_memory"`  // Argon2 memory in KiB
+	Argon2Threads int    `json:"argon2_threads"` // Argon2 parallelism
+	Salt          []byte `json:"salt"`           // Random salt for KDF
+	Nonce         []byte `json:"nonce"`          // Random nonce for AES-GCM
+	Ciphertext    []byte `json:"ciphertext"`     // Encrypted private key + auth tag
+}
+
+// KeySlot is one encrypted Ed25519 private key inside a Keystore file.
+// Purpose distinguishes what the key is for (e.g. "identity", "signing")
+// so ListKeys/RotateKey can tell coexisting keys apart; Retired slots are
+// kept (rather than removed) for DefaultRetiredKeyGrace after a RotateKey
+// so in-flight manifests signed by the old key still verify.
+type KeySlot struct {
+	KeyID     string    `json:"key_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Purpose   string    `json:"purpose"`
+	Retired   bool      `json:"retired,omitempty"`
+	RetiredAt time.Time `json:"retired_at,omitempty"`
+
+	KDF           string `json:"kdf"`
+	Argon2Time    int    `json:"argon2_time,omitempty"`
+	Argon2Memory  int    `json:"argon2_memory,omitempty"`
+	Argon2Threads int    `json:"argon2_threads,omitempty"`
+	Salt          []byte `json:"salt,omitempty"`
+	Nonce         []byte `json:"nonce"`
+	Ciphertext    []byte `json:"ciphertext"`
+
+	// WrappedDEK holds a KMS-wrapped data-encryption key for slots written
+	// via AddKeyKMS/RotateKeyKMS instead of a passphrase. KDF is
+	// "kms-envelope" for these slots, and Salt is unused since there is no
+	// passphrase to derive from.
+	WrappedDEK []byte `json:"wrapped_dek,omitempty"`
+}
+
+// Keystore is the on-disk container for one or more KeySlots.
+type Keystore struct {
+	Version int       `json:"version"`
+	Slots   []KeySlot `json:"slots"`
+}
+
 // SaveKey encrypts and saves an Ed25519 private key to disk.
 //
 // If passphrase is empty, the key is stored unencrypted (insecure, only for testing).
 // Otherwise, the key is encrypted using AES-256-GCM with a key derived from the
-// passphrase using Argon2id.
+// passphrase using Argon2id, and written as a single-slot Keystore under the
+// slot ID "default" - this is the same file AddKey/RotateKey/ListKeys work
+// against, it's just the original one-key-per-file convenience path kept for
+// the common case of a single identity key.
 //
 // Parameters:
 //   - privateKey: Ed25519 private key to save (64 bytes)
@@ -44,44 +109,31 @@ func SaveKey(privateKey []byte, keystorePath string, passphrase string) error {
 		return errors.New("Ed25519 private key must be 64 bytes")
 	}
 
-	// Ensure directory exists
-	dir := filepath.Dir(keystorePath)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("failed to create keystore directory: %w", err)
-	}
-
-	var data []byte
-
 	if passphrase == "" {
 		// Store unencrypted (insecure, for testing only)
-		data = privateKey
-		keystorePath += ".insecure"
-	} else {
-		// Encrypt with Argon2id + AES-256-GCM
-		entry, err := encryptKey(privateKey, passphrase)
-		if err != nil {
-			return fmt.Errorf("failed to encrypt key: %w", err)
+		dir := filepath.Dir(keystorePath)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create keystore directory: %w", err)
 		}
-
-		var marshalErr error
-		data, marshalErr = json.MarshalIndent(entry, "", "  ")
-		if marshalErr != nil {
-			return fmt.Errorf("failed to marshal keystore entry: %w", marshalErr)
+		if err := os.WriteFile(keystorePath+".insecure", privateKey, 0600); err != nil {
+			return fmt.Errorf("failed to write keystore file: %w", err)
 		}
+		return nil
 	}
 
-	// Write to file with restricted permissions (owner read/write only)
-	if err := os.WriteFile(keystorePath, data, 0600); err != nil {
-		return fmt.Errorf("failed to write keystore file: %w", err)
+	slot, err := newPassphraseSlot(privateKey, "default", "identity", passphrase)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return saveKeystoreFile(keystorePath, &Keystore{Version: keystoreVersion, Slots: []KeySlot{*slot}})
 }
 
 // LoadKey loads and decrypts an Ed25519 private key from disk.
 //
 // If the keystore file ends with ".insecure", it is loaded without decryption.
-// Otherwise, the passphrase is used to decrypt the key.
+// Otherwise, the passphrase decrypts the file's first slot - for multi-slot
+// files written by AddKey, use LoadKeystore and DecryptSlot to pick a
+// specific KeyID instead.
 //
 // Parameters:
 //   - keystorePath: Full path to the keystore file
@@ -91,65 +143,224 @@ func SaveKey(privateKey []byte, keystorePath string, passphrase string) error {
 //   - privateKey: Ed25519 private key (64 bytes)
 //   - error if loading or decryption fails
 func LoadKey(keystorePath string, passphrase string) ([]byte, error) {
-	data, err := os.ReadFile(keystorePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read keystore file: %w", err)
-	}
-
-	// Check if unencrypted
 	if filepath.Ext(keystorePath) == ".insecure" {
+		data, err := os.ReadFile(keystorePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keystore file: %w", err)
+		}
 		if len(data) != 64 {
 			return nil, errors.New("invalid unencrypted keystore: expected 64 bytes")
 		}
 		return data, nil
 	}
 
-	// Decrypt encrypted keystore
-	var entry KeystoreEntry
-	if err := json.Unmarshal(data, &entry); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal keystore entry: %w", err)
+	ks, err := LoadKeystore(keystorePath)
+	if err != nil {
+		return nil, err
 	}
+	if len(ks.Slots) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return DecryptSlot(&ks.Slots[0], passphrase)
+}
 
-	privateKey, err := decryptKey(&entry, passphrase)
+// LoadKeystore reads path and, if it's a version 1 single-key file,
+// migrates it in memory to a version 2 Keystore with one "default" slot.
+// The migrated shape is not written back automatically - callers that want
+// the migration persisted (e.g. the daemon on startup) should follow up
+// with SaveKeystore once they've confirmed the file is readable.
+func LoadKeystore(path string) (*Keystore, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt key: %w", err)
+		return nil, fmt.Errorf("failed to read keystore file: %w", err)
 	}
+	return parseKeystore(data)
+}
 
-	return privateKey, nil
+// SaveKeystore writes ks to path, creating the parent directory if needed.
+func SaveKeystore(path string, ks *Keystore) error {
+	return saveKeystoreFile(path, ks)
 }
 
-// encryptKey encrypts an Ed25519 private key using Argon2id + AES-256-GCM.
-func encryptKey(privateKey []byte, passphrase string) (*KeystoreEntry, error) {
-	// Generate random salt
-	salt := make([]byte, saltSize)
-	if _, err := rand.Read(salt); err != nil {
-		return nil, fmt.Errorf("failed to generate salt: %w", err)
+// AddKey encrypts privateKey under passphrase and appends it to the
+// Keystore at path as a new slot, creating the file if it doesn't exist
+// yet. It returns the fresh, randomly generated KeyID so callers can
+// reference this specific slot later (e.g. to RemoveKey it).
+func AddKey(path string, privateKey []byte, purpose, passphrase string) (string, error) {
+	if len(privateKey) != 64 {
+		return "", errors.New("Ed25519 private key must be 64 bytes")
+	}
+
+	ks, err := loadOrNewKeystore(path)
+	if err != nil {
+		return "", err
+	}
+
+	slot, err := newPassphraseSlot(privateKey, uuid.New().String(), purpose, passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	ks.Slots = append(ks.Slots, *slot)
+	if err := saveKeystoreFile(path, ks); err != nil {
+		return "", err
+	}
+	return slot.KeyID, nil
+}
+
+// RotateKey generates a fresh Ed25519 keypair, encrypts it under
+// passphrase with a new salt and nonce, and appends it to the keystore at
+// path in place of oldID: oldID's slot is kept but marked Retired with
+// RetiredAt set to now, rather than removed, so manifests signed with it
+// still verify until PurgeRetired reaps it. The new slot inherits oldID's
+// Purpose. It returns the new key's ID and the regenerated private key,
+// since callers (e.g. the identity package) need the plaintext key to
+// start using it immediately.
+func RotateKey(path, oldID, passphrase string) (newID string, newPrivateKey []byte, err error) {
+	ks, err := LoadKeystore(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	idx := findSlot(ks, oldID)
+	if idx < 0 {
+		return "", nil, ErrKeyNotFound
+	}
+
+	kp, err := GenerateEd25519()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate rotated key: %w", err)
+	}
+
+	slot, err := newPassphraseSlot(kp.PrivateKey, uuid.New().String(), ks.Slots[idx].Purpose, passphrase)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ks.Slots[idx].Retired = true
+	ks.Slots[idx].RetiredAt = time.Now()
+	ks.Slots = append(ks.Slots, *slot)
+
+	if err := saveKeystoreFile(path, ks); err != nil {
+		return "", nil, err
+	}
+	return slot.KeyID, kp.PrivateKey, nil
+}
+
+// PurgeRetired removes slots that have been Retired for longer than grace,
+// permanently discarding their ciphertext. Run this on a schedule (e.g.
+// daily) rather than inline with RotateKey, so the grace period is
+// measured from real wall-clock time rather than from the rotation call.
+func PurgeRetired(path string, grace time.Duration) error {
+	ks, err := LoadKeystore(path)
+	if err != nil {
+		return err
 	}
 
-	// Derive encryption key from passphrase using Argon2id
+	kept := ks.Slots[:0]
+	for _, s := range ks.Slots {
+		if s.Retired && time.Since(s.RetiredAt) > grace {
+			continue
+		}
+		kept = append(kept, s)
+	}
+	ks.Slots = kept
+
+	return saveKeystoreFile(path, ks)
+}
+
+// ListKeys returns the slots in the keystore at path, in file order.
+// Ciphertext is included since it's already encrypted and useless without
+// the passphrase, but callers displaying this to a user should stick to
+// KeyID/Purpose/CreatedAt/Retired.
+func ListKeys(path string) ([]KeySlot, error) {
+	ks, err := LoadKeystore(path)
+	if err != nil {
+		return nil, err
+	}
+	return ks.Slots, nil
+}
+
+// RemoveKey deletes the slot identified by keyID from the keystore at
+// path, regardless of whether it's Retired. Unlike the natural reap of
+// PurgeRetired, this is for explicit, immediate key destruction.
+func RemoveKey(path, keyID string) error {
+	ks, err := LoadKeystore(path)
+	if err != nil {
+		return err
+	}
+
+	idx := findSlot(ks, keyID)
+	if idx < 0 {
+		return ErrKeyNotFound
+	}
+	ks.Slots = append(ks.Slots[:idx], ks.Slots[idx+1:]...)
+
+	return saveKeystoreFile(path, ks)
+}
+
+// DecryptSlot decrypts slot using passphrase. For kms-envelope slots
+// (slot.KDF == "kms-envelope"), use DecryptSlotKMS with the matching
+// KMSEnvelope instead - passphrase is ignored there.
+func DecryptSlot(slot *KeySlot, passphrase string) ([]byte, error) {
+	if slot.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported KDF for passphrase decrypt: %s", slot.KDF)
+	}
 	derivedKey := argon2.IDKey(
 		[]byte(passphrase),
-		salt,
-		argon2Time,
-		argon2Memory,
-		argon2Threads,
+		slot.Salt,
+		uint32(slot.Argon2Time),
+		uint32(slot.Argon2Memory),
+		uint8(slot.Argon2Threads),
 		argon2KeyLen,
 	)
+	return openSlot(derivedKey, slot)
+}
+
+func openSlot(derivedKey []byte, slot *KeySlot) ([]byte, error) {
+	plaintext, err := openSlotAny(derivedKey, slot)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) != 64 {
+		return nil, errors.New("decrypted key has invalid size")
+	}
+	return plaintext, nil
+}
+
+// openSlotAny is openSlot without the Ed25519-sized assumption, for slots
+// holding something other than a 64-byte private key (see
+// UpsertOpaqueSlot/GetOpaqueSlot).
+func openSlotAny(derivedKey []byte, slot *KeySlot) ([]byte, error) {
+	plaintext, err := Open(derivedKey, slot.Nonce, nil, slot.Ciphertext)
+	if err != nil {
+		return nil, ErrInvalidPassphrase
+	}
+	return plaintext, nil
+}
+
+func newPassphraseSlot(privateKey []byte, keyID, purpose, passphrase string) (*KeySlot, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	derivedKey := argon2.IDKey([]byte(passphrase), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
 
-	// Generate random nonce
 	nonce := make([]byte, 12)
 	if _, err := rand.Read(nonce); err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	// Encrypt private key using AES-256-GCM (no AAD for keystore)
 	ciphertext, err := Seal(derivedKey, nonce, nil, privateKey)
 	if err != nil {
 		return nil, err
 	}
 
-	entry := &KeystoreEntry{
-		Version:       keystoreVersion,
+	return &KeySlot{
+		KeyID:         keyID,
+		CreatedAt:     time.Now(),
+		Purpose:       purpose,
 		KDF:           "argon2id",
 		Argon2Time:    argon2Time,
 		Argon2Memory:  argon2Memory,
@@ -157,45 +368,80 @@ func encryptKey(privateKey []byte, passphrase string) (*KeystoreEntry, error) {
 		Salt:          salt,
 		Nonce:         nonce,
 		Ciphertext:    ciphertext,
+	}, nil
+}
+
+func findSlot(ks *Keystore, keyID string) int {
+	for i := range ks.Slots {
+		if ks.Slots[i].KeyID == keyID {
+			return i
+		}
 	}
+	return -1
+}
 
-	return entry, nil
+func loadOrNewKeystore(path string) (*Keystore, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return &Keystore{Version: keystoreVersion}, nil
+	}
+	return LoadKeystore(path)
 }
 
-// decryptKey decrypts an Ed25519 private key using Argon2id + AES-256-GCM.
-func decryptKey(entry *KeystoreEntry, passphrase string) ([]byte, error) {
-	// Validate keystore version
-	if entry.Version != keystoreVersion {
-		return nil, fmt.Errorf("unsupported keystore version: %d", entry.Version)
+// parseKeystore unmarshals data as a version 2 Keystore, or, if it looks
+// like a version 1 KeystoreEntry instead (no top-level "slots" key),
+// migrates it into a single-slot Keystore in memory.
+func parseKeystore(data []byte) (*Keystore, error) {
+	var probe struct {
+		Slots json.RawMessage `json:"slots"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal keystore: %w", err)
+	}
+	if probe.Slots != nil {
+		var ks Keystore
+		if err := json.Unmarshal(data, &ks); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal keystore: %w", err)
+		}
+		return &ks, nil
 	}
 
-	// Validate KDF
-	if entry.KDF != "argon2id" {
-		return nil, fmt.Errorf("unsupported KDF: %s", entry.KDF)
+	var entry KeystoreEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal keystore entry: %w", err)
 	}
+	return &Keystore{
+		Version: keystoreVersion,
+		Slots: []KeySlot{{
+			KeyID:         "default",
+			CreatedAt:     time.Now(),
+			Purpose:       "identity",
+			KDF:           entry.KDF,
+			Argon2Time:    entry.Argon2Time,
+			Argon2Memory:  entry.Argon2Memory,
+			Argon2Threads: entry.Argon2Threads,
+			Salt:          entry.Salt,
+			Nonce:         entry.Nonce,
+			Ciphertext:    entry.Ciphertext,
+		}},
+	}, nil
+}
 
-	// Derive decryption key from passphrase using stored parameters
-	derivedKey := argon2.IDKey(
-		[]byte(passphrase),
-		entry.Salt,
-		uint32(entry.Argon2Time),
-		uint32(entry.Argon2Memory),
-		uint8(entry.Argon2Threads),
-		argon2KeyLen,
-	)
+func saveKeystoreFile(path string, ks *Keystore) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create keystore directory: %w", err)
+	}
 
-	// Decrypt private key using AES-256-GCM
-	plaintext, err := Open(derivedKey, entry.Nonce, nil, entry.Ciphertext)
+	ks.Version = keystoreVersion
+	data, err := json.MarshalIndent(ks, "", "  ")
 	if err != nil {
-		return nil, ErrInvalidPassphrase
+		return fmt.Errorf("failed to marshal keystore: %w", err)
 	}
 
-	// Validate decrypted key size
-	if len(plaintext) != 64 {
-		return nil, errors.New("decrypted key has invalid size")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write keystore file: %w", err)
 	}
-
-	return plaintext, nil
+	return nil
 }
 
 // GetDefaultKeystorePath returns the default keystore directory path.
@@ -215,4 +461,4 @@ func GetDefaultKeystorePath() string {
 	// Fallback to ~/.local/share
 	homeDir, _ := os.UserHomeDir()
 	return filepath.Join(homeDir, ".local", "share", "quantarax", "keys")
-}
\ No newline at end of file
+}
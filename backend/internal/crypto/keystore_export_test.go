@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// TestExportImportKeystoreRoundTrip verifies a keystore-v1 export decrypts
+// back to the original private key under the right passphrase.
+func TestExportImportKeystoreRoundTrip(t *testing.T) {
+	kp, err := GenerateEd25519()
+	if err != nil {
+		t.Fatalf("GenerateEd25519() failed: %v", err)
+	}
+
+	data, err := ExportKeystore(kp.PrivateKey, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("ExportKeystore() failed: %v", err)
+	}
+
+	got, err := ImportKeystore(data, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("ImportKeystore() failed: %v", err)
+	}
+	if !bytes.Equal(got, kp.PrivateKey) {
+		t.Error("imported private key does not match original")
+	}
+}
+
+// TestImportKeystoreWrongPassphrase verifies a wrong passphrase is rejected
+// with ErrImportWrongPassphrase rather than a generic decode error.
+func TestImportKeystoreWrongPassphrase(t *testing.T) {
+	kp, err := GenerateEd25519()
+	if err != nil {
+		t.Fatalf("GenerateEd25519() failed: %v", err)
+	}
+
+	data, err := ExportKeystore(kp.PrivateKey, "right passphrase")
+	if err != nil {
+		t.Fatalf("ExportKeystore() failed: %v", err)
+	}
+
+	if _, err := ImportKeystore(data, "wrong passphrase"); err != ErrImportWrongPassphrase {
+		t.Errorf("ImportKeystore() error = %v, want ErrImportWrongPassphrase", err)
+	}
+}
+
+// TestExportImportKeystoreAgeRoundTrip verifies the age-recipient export
+// mode decrypts with the matching identity and not with an unrelated one.
+func TestExportImportKeystoreAgeRoundTrip(t *testing.T) {
+	kp, err := GenerateEd25519()
+	if err != nil {
+		t.Fatalf("GenerateEd25519() failed: %v", err)
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("age.GenerateX25519Identity() failed: %v", err)
+	}
+
+	data, err := ExportKeystoreAge(kp.PrivateKey, []string{identity.Recipient().String()})
+	if err != nil {
+		t.Fatalf("ExportKeystoreAge() failed: %v", err)
+	}
+
+	got, err := ImportKeystoreAge(data, identity.String())
+	if err != nil {
+		t.Fatalf("ImportKeystoreAge() failed: %v", err)
+	}
+	if !bytes.Equal(got, kp.PrivateKey) {
+		t.Error("imported private key does not match original")
+	}
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("age.GenerateX25519Identity() failed: %v", err)
+	}
+	if _, err := ImportKeystoreAge(data, other.String()); err == nil {
+		t.Error("ImportKeystoreAge() with an unrelated identity succeeded, want error")
+	}
+}
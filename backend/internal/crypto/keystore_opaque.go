@@ -0,0 +1,99 @@
+package crypto
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
+)
+
+// UpsertOpaqueSlot encrypts data under passphrase using the same
+// Argon2id+AES-256-GCM envelope SaveKey/AddKey use, and writes it to the
+// keystore at path as the slot whose Purpose is purpose, replacing any
+// existing slot with that Purpose rather than appending a duplicate.
+//
+// This is AddKey's machinery generalized to data that isn't a 64-byte
+// Ed25519 private key - an ACME account key, a cached leaf certificate,
+// ... - and addressed by Purpose instead of a generated KeyID, for
+// callers that want one encrypted keystore file to hold non-identity
+// secrets alongside identity keys (see transport/tlsauto's keystoreCache).
+func UpsertOpaqueSlot(path, purpose string, data []byte, passphrase string) error {
+	ks, err := loadOrNewKeystore(path)
+	if err != nil {
+		return err
+	}
+
+	slot, err := newPassphraseSlot(data, uuid.New().String(), purpose, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if idx := findSlotByPurpose(ks, purpose); idx >= 0 {
+		ks.Slots[idx] = *slot
+	} else {
+		ks.Slots = append(ks.Slots, *slot)
+	}
+	return saveKeystoreFile(path, ks)
+}
+
+// GetOpaqueSlot decrypts and returns the data UpsertOpaqueSlot most
+// recently stored under purpose in the keystore at path, or
+// ErrKeyNotFound if path doesn't exist yet or has no such slot.
+func GetOpaqueSlot(path, purpose, passphrase string) ([]byte, error) {
+	ks, err := LoadKeystore(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+
+	idx := findSlotByPurpose(ks, purpose)
+	if idx < 0 {
+		return nil, ErrKeyNotFound
+	}
+
+	slot := &ks.Slots[idx]
+	if slot.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported KDF for passphrase decrypt: %s", slot.KDF)
+	}
+	derivedKey := argon2.IDKey(
+		[]byte(passphrase),
+		slot.Salt,
+		uint32(slot.Argon2Time),
+		uint32(slot.Argon2Memory),
+		uint8(slot.Argon2Threads),
+		argon2KeyLen,
+	)
+	return openSlotAny(derivedKey, slot)
+}
+
+// DeleteOpaqueSlot removes the slot UpsertOpaqueSlot stored under purpose
+// in the keystore at path, if any. A missing path or purpose is not an
+// error, matching autocert.Cache.Delete's "already gone is fine" contract.
+func DeleteOpaqueSlot(path, purpose string) error {
+	ks, err := LoadKeystore(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	idx := findSlotByPurpose(ks, purpose)
+	if idx < 0 {
+		return nil
+	}
+	ks.Slots = append(ks.Slots[:idx], ks.Slots[idx+1:]...)
+	return saveKeystoreFile(path, ks)
+}
+
+func findSlotByPurpose(ks *Keystore, purpose string) int {
+	for i := range ks.Slots {
+		if ks.Slots[i].Purpose == purpose {
+			return i
+		}
+	}
+	return -1
+}
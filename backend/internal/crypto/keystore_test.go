@@ -0,0 +1,221 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestAddKeyMultiSlot verifies multiple identities coexist in one file.
+func TestAddKeyMultiSlot(t *testing.T) {
+	keystorePath := filepath.Join(t.TempDir(), "identities.key")
+
+	kp1, err := GenerateEd25519()
+	if err != nil {
+		t.Fatalf("GenerateEd25519() failed: %v", err)
+	}
+	kp2, err := GenerateEd25519()
+	if err != nil {
+		t.Fatalf("GenerateEd25519() failed: %v", err)
+	}
+
+	id1, err := AddKey(keystorePath, kp1.PrivateKey, "identity", "pass-1")
+	if err != nil {
+		t.Fatalf("AddKey() failed: %v", err)
+	}
+	id2, err := AddKey(keystorePath, kp2.PrivateKey, "signing", "pass-2")
+	if err != nil {
+		t.Fatalf("AddKey() failed: %v", err)
+	}
+	if id1 == id2 {
+		t.Fatal("AddKey() returned the same KeyID twice")
+	}
+
+	slots, err := ListKeys(keystorePath)
+	if err != nil {
+		t.Fatalf("ListKeys() failed: %v", err)
+	}
+	if len(slots) != 2 {
+		t.Fatalf("expected 2 slots, got %d", len(slots))
+	}
+
+	ks, err := LoadKeystore(keystorePath)
+	if err != nil {
+		t.Fatalf("LoadKeystore() failed: %v", err)
+	}
+	idx := findSlot(ks, id1)
+	if idx < 0 {
+		t.Fatal("slot 1 missing after reload")
+	}
+	got, err := DecryptSlot(&ks.Slots[idx], "pass-1")
+	if err != nil {
+		t.Fatalf("DecryptSlot() failed: %v", err)
+	}
+	if !bytes.Equal(got, kp1.PrivateKey) {
+		t.Error("decrypted key does not match original")
+	}
+}
+
+// TestRotateKeyRetainsRetiredSlot verifies the old slot survives rotation,
+// marked Retired, so it can still be used to verify until purged.
+func TestRotateKeyRetainsRetiredSlot(t *testing.T) {
+	keystorePath := filepath.Join(t.TempDir(), "identities.key")
+
+	kp, err := GenerateEd25519()
+	if err != nil {
+		t.Fatalf("GenerateEd25519() failed: %v", err)
+	}
+	oldID, err := AddKey(keystorePath, kp.PrivateKey, "identity", "pass")
+	if err != nil {
+		t.Fatalf("AddKey() failed: %v", err)
+	}
+
+	newID, newKey, err := RotateKey(keystorePath, oldID, "pass")
+	if err != nil {
+		t.Fatalf("RotateKey() failed: %v", err)
+	}
+	if newID == oldID {
+		t.Fatal("RotateKey() did not generate a new KeyID")
+	}
+	if bytes.Equal(newKey, kp.PrivateKey) {
+		t.Fatal("RotateKey() did not generate a fresh keypair")
+	}
+
+	ks, err := LoadKeystore(keystorePath)
+	if err != nil {
+		t.Fatalf("LoadKeystore() failed: %v", err)
+	}
+	if len(ks.Slots) != 2 {
+		t.Fatalf("expected old + new slot, got %d", len(ks.Slots))
+	}
+	oldIdx := findSlot(ks, oldID)
+	if oldIdx < 0 || !ks.Slots[oldIdx].Retired {
+		t.Fatal("old slot should still be present and marked Retired")
+	}
+
+	// Old slot still decrypts with its original passphrase.
+	if _, err := DecryptSlot(&ks.Slots[oldIdx], "pass"); err != nil {
+		t.Fatalf("retired slot should still decrypt: %v", err)
+	}
+}
+
+// TestPurgeRetiredReapsExpiredOnly verifies PurgeRetired only removes
+// slots whose grace period has elapsed.
+func TestPurgeRetiredReapsExpiredOnly(t *testing.T) {
+	keystorePath := filepath.Join(t.TempDir(), "identities.key")
+
+	kp, err := GenerateEd25519()
+	if err != nil {
+		t.Fatalf("GenerateEd25519() failed: %v", err)
+	}
+	oldID, err := AddKey(keystorePath, kp.PrivateKey, "identity", "pass")
+	if err != nil {
+		t.Fatalf("AddKey() failed: %v", err)
+	}
+	if _, _, err := RotateKey(keystorePath, oldID, "pass"); err != nil {
+		t.Fatalf("RotateKey() failed: %v", err)
+	}
+
+	// Grace period hasn't elapsed yet: both slots remain.
+	if err := PurgeRetired(keystorePath, time.Hour); err != nil {
+		t.Fatalf("PurgeRetired() failed: %v", err)
+	}
+	ks, err := LoadKeystore(keystorePath)
+	if err != nil {
+		t.Fatalf("LoadKeystore() failed: %v", err)
+	}
+	if len(ks.Slots) != 2 {
+		t.Fatalf("expected both slots to remain, got %d", len(ks.Slots))
+	}
+
+	// A zero grace period means the retired slot is immediately eligible.
+	if err := PurgeRetired(keystorePath, 0); err != nil {
+		t.Fatalf("PurgeRetired() failed: %v", err)
+	}
+	ks, err = LoadKeystore(keystorePath)
+	if err != nil {
+		t.Fatalf("LoadKeystore() failed: %v", err)
+	}
+	if len(ks.Slots) != 1 {
+		t.Fatalf("expected retired slot to be reaped, got %d slots", len(ks.Slots))
+	}
+	if ks.Slots[0].Retired {
+		t.Error("remaining slot should be the active (non-retired) one")
+	}
+}
+
+// TestRemoveKey verifies explicit slot removal regardless of Retired state.
+func TestRemoveKey(t *testing.T) {
+	keystorePath := filepath.Join(t.TempDir(), "identities.key")
+
+	kp, err := GenerateEd25519()
+	if err != nil {
+		t.Fatalf("GenerateEd25519() failed: %v", err)
+	}
+	id, err := AddKey(keystorePath, kp.PrivateKey, "identity", "pass")
+	if err != nil {
+		t.Fatalf("AddKey() failed: %v", err)
+	}
+
+	if err := RemoveKey(keystorePath, id); err != nil {
+		t.Fatalf("RemoveKey() failed: %v", err)
+	}
+	if err := RemoveKey(keystorePath, id); err != ErrKeyNotFound {
+		t.Fatalf("expected ErrKeyNotFound removing an already-removed key, got %v", err)
+	}
+}
+
+// TestLoadKeystoreMigratesV1 verifies a version 1 KeystoreEntry file
+// (written by the pre-multi-slot SaveKey) loads as a single-slot v2
+// Keystore transparently.
+func TestLoadKeystoreMigratesV1(t *testing.T) {
+	keystorePath := filepath.Join(t.TempDir(), "identity.key")
+
+	kp, err := GenerateEd25519()
+	if err != nil {
+		t.Fatalf("GenerateEd25519() failed: %v", err)
+	}
+	entry, err := newPassphraseSlot(kp.PrivateKey, "", "", "pass")
+	if err != nil {
+		t.Fatalf("newPassphraseSlot() failed: %v", err)
+	}
+	v1 := KeystoreEntry{
+		Version:       1,
+		KDF:           entry.KDF,
+		Argon2Time:    entry.Argon2Time,
+		Argon2Memory:  entry.Argon2Memory,
+		Argon2Threads: entry.Argon2Threads,
+		Salt:          entry.Salt,
+		Nonce:         entry.Nonce,
+		Ciphertext:    entry.Ciphertext,
+	}
+	data, err := json.MarshalIndent(v1, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal v1 entry: %v", err)
+	}
+	if err := os.WriteFile(keystorePath, data, 0600); err != nil {
+		t.Fatalf("write v1 keystore: %v", err)
+	}
+
+	ks, err := LoadKeystore(keystorePath)
+	if err != nil {
+		t.Fatalf("LoadKeystore() failed to migrate v1 file: %v", err)
+	}
+	if ks.Version != keystoreVersion {
+		t.Fatalf("expected migrated version %d, got %d", keystoreVersion, ks.Version)
+	}
+	if len(ks.Slots) != 1 {
+		t.Fatalf("expected 1 migrated slot, got %d", len(ks.Slots))
+	}
+
+	got, err := DecryptSlot(&ks.Slots[0], "pass")
+	if err != nil {
+		t.Fatalf("DecryptSlot() on migrated slot failed: %v", err)
+	}
+	if !bytes.Equal(got, kp.PrivateKey) {
+		t.Error("migrated slot decrypts to a different key than the original")
+	}
+}
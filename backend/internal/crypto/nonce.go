@@ -55,6 +55,67 @@ func DeriveChunkNonce(ivBase [12]byte, chunkIndex uint32) [12]byte {
 	return DeriveNonce(ivBase, uint64(chunkIndex))
 }
 
+// DeriveFragmentNonce derives a nonce for one fragment of a segmented chunk,
+// folding both the segment (chunk) index and the fragment's position within
+// it into the counter so every (segment, fragment) pair gets a distinct
+// nonce under the same session key, the same way DeriveChunkNonce folds in
+// just the chunk index for whole-chunk encryption.
+//
+// Parameters:
+//   - ivBase: 12-byte base initialization vector from session keys
+//   - segmentSeq: the chunk/segment index the fragment belongs to
+//   - fragmentSeq: the fragment's zero-based position within that segment
+//
+// Returns:
+//   - 12-byte nonce for encrypting this fragment
+func DeriveFragmentNonce(ivBase [12]byte, segmentSeq, fragmentSeq uint32) [12]byte {
+	counter := uint64(segmentSeq)<<32 | uint64(fragmentSeq)
+	return DeriveNonce(ivBase, counter)
+}
+
+// DeriveNonceN generalizes DeriveNonce to algorithms whose nonce size isn't
+// the fixed 12 bytes AES-GCM and ChaCha20-Poly1305 use — namely
+// XChaCha20-Poly1305's 24-byte nonce (see AlgorithmXChaCha20Poly1305).
+//
+// Parameters:
+//   - ivBase: base initialization vector from session keys; any length up
+//     to size is accepted, since session key derivation predates agile
+//     AEAD selection and still hands back a 12-byte IVBase regardless of
+//     which algorithm a session eventually negotiates
+//   - counter: Monotonically increasing counter (chunk index or message counter)
+//   - size: the target AEAD's NonceSize(), e.g. 12 or 24
+//
+// Returns:
+//   - size-byte nonce: ivBase XORed with the little-endian counter in the
+//     first 8 bytes, ivBase copied unchanged beyond that, and zero-padded
+//     out to size if ivBase is shorter
+//
+// Security Properties:
+//   - Each unique counter value produces a unique nonce
+//   - Deterministic: same counter always produces same nonce (for given ivBase)
+//   - No nonce reuse possible as long as counter doesn't repeat in a session
+func DeriveNonceN(ivBase []byte, counter uint64, size int) []byte {
+	nonce := make([]byte, size)
+
+	var counterBytes [8]byte
+	binary.LittleEndian.PutUint64(counterBytes[:], counter)
+
+	for i := 0; i < size; i++ {
+		switch {
+		case i < 8:
+			if i < len(ivBase) {
+				nonce[i] = ivBase[i] ^ counterBytes[i]
+			} else {
+				nonce[i] = counterBytes[i]
+			}
+		case i < len(ivBase):
+			nonce[i] = ivBase[i]
+		}
+	}
+
+	return nonce
+}
+
 // DeriveControlNonce is a convenience wrapper for deriving nonces for control messages.
 // It uses the message counter as the counter, offset by a large value to avoid
 // collision with chunk nonces.
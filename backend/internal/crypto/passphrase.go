@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// PassphraseSource supplies the passphrase AddKey/RotateKey/DecryptSlot
+// derive a slot's AES key from. SaveKey/LoadKey's plain passphrase string
+// parameter is equivalent to StaticPassphraseSource(passphrase) - it's the
+// default because most callers already have the passphrase in hand, but
+// anything needing to prompt, read an env var, or reach into an OS
+// keychain instead implements this interface.
+type PassphraseSource interface {
+	// Passphrase returns the passphrase for a key of the given purpose
+	// (e.g. "identity"). Called once per AddKey/RotateKey/DecryptSlot call.
+	Passphrase(purpose string) (string, error)
+}
+
+// StaticPassphraseSource is a PassphraseSource that always returns the
+// same literal passphrase. It's what SaveKey/LoadKey use internally, and
+// is the right choice whenever the passphrase is already known (e.g.
+// passed on the command line or read from a config file elsewhere).
+type StaticPassphraseSource string
+
+func (s StaticPassphraseSource) Passphrase(purpose string) (string, error) {
+	return string(s), nil
+}
+
+// StdinPassphraseSource prompts on the terminal attached to os.Stdin,
+// with echo disabled if Stdin is a real terminal (falling back to a plain
+// line read otherwise, e.g. when piped in tests or scripts).
+type StdinPassphraseSource struct{}
+
+func (StdinPassphraseSource) Passphrase(purpose string) (string, error) {
+	fmt.Fprintf(os.Stderr, "Passphrase for %s key: ", purpose)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		return string(b), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return trimNewline(line), nil
+}
+
+func trimNewline(s string) string {
+	for len(s) > 0 && (s[len(s)-1] == '\n' || s[len(s)-1] == '\r') {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// EnvPassphraseSource reads the passphrase from the named environment
+// variable, e.g. for daemons started under systemd with
+// Environment=QUANTARAX_KEYSTORE_PASSPHRASE=... or an EnvironmentFile=.
+type EnvPassphraseSource struct {
+	Var string
+}
+
+func (e EnvPassphraseSource) Passphrase(purpose string) (string, error) {
+	v, ok := os.LookupEnv(e.Var)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", e.Var)
+	}
+	return v, nil
+}
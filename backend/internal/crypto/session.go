@@ -12,8 +12,19 @@ const (
 	// Domain separation string for session key derivation
 	sessionInfoString = "quantarax-v1-session"
 
+	// Domain separation string for the hybrid (X25519 + PQ KEM) variant.
+	// Distinct from sessionInfoString so a hybrid-capable peer can never
+	// derive the same keys a classical-only peer would from the same
+	// X25519 shared secret alone.
+	hybridSessionInfoString = "quantarax-v1-session-hybrid"
+
 	// Expected output length from HKDF: 32 (PayloadKey) + 32 (ControlKey) + 12 (IVBase) = 76 bytes
 	hkdfOutputLength = 76
+
+	// hkdfOutputLengthWithConfirmation is hkdfOutputLength plus a fourth
+	// 32-byte ConfirmationKey output, for the transcript-bound
+	// derivations that support ComputeConfirmationTag/VerifyConfirmationTag.
+	hkdfOutputLengthWithConfirmation = hkdfOutputLength + 32
 )
 
 // DeriveSessionKeys performs HKDF-based key derivation from an X25519 shared secret.
@@ -70,5 +81,106 @@ func DeriveSessionKeys(ourPrivate, theirPublic *[32]byte, manifestHash []byte) (
 	copy(keys.ControlKey[:], keyMaterial[32:64])
 	copy(keys.IVBase[:], keyMaterial[64:76])
 
+	return &keys, nil
+}
+
+// DeriveSessionKeysWithTranscript is DeriveSessionKeys with the static
+// sessionInfoString replaced by transcript (see EncodeTranscript) as the
+// HKDF info parameter, and a fourth 32-byte ConfirmationKey output
+// alongside PayloadKey/ControlKey/IVBase. Binding info to the transcript
+// - both ephemeral public keys, the negotiated suite, and the protocol
+// version - means any tampering or downgrade an attacker makes in
+// transit produces a transcript mismatch between the two sides, which
+// surfaces as a ConfirmationKey (and therefore confirmation tag)
+// mismatch instead of succeeding silently with keys only one side
+// expects.
+//
+// Parameters:
+//   - ourPrivate, theirPublic: the X25519 keypair halves
+//   - manifestHash: BLAKE3 hash of the file manifest (32 bytes, used as HKDF salt)
+//   - transcript: EncodeTranscript's output for this handshake
+//
+// Returns:
+//   - SessionKeys containing PayloadKey, ControlKey, IVBase, and ConfirmationKey
+//   - error if ECDH fails or key derivation fails
+func DeriveSessionKeysWithTranscript(ourPrivate, theirPublic *[32]byte, manifestHash []byte, transcript []byte) (*SessionKeys, error) {
+	if len(manifestHash) != 32 {
+		return nil, fmt.Errorf("manifest hash must be 32 bytes, got %d", len(manifestHash))
+	}
+
+	sharedSecret, err := X25519Exchange(ourPrivate, theirPublic)
+	if err != nil {
+		return nil, fmt.Errorf("ECDH key exchange failed: %w", err)
+	}
+
+	hkdfReader := hkdf.New(sha256.New, sharedSecret[:], manifestHash, transcript)
+
+	keyMaterial := make([]byte, hkdfOutputLengthWithConfirmation)
+	if _, err := io.ReadFull(hkdfReader, keyMaterial); err != nil {
+		return nil, fmt.Errorf("HKDF key derivation failed: %w", err)
+	}
+
+	var keys SessionKeys
+	copy(keys.PayloadKey[:], keyMaterial[0:32])
+	copy(keys.ControlKey[:], keyMaterial[32:64])
+	copy(keys.IVBase[:], keyMaterial[64:76])
+	copy(keys.ConfirmationKey[:], keyMaterial[76:108])
+
+	return &keys, nil
+}
+
+// DeriveSessionKeysHybrid is DeriveSessionKeys' post-quantum counterpart:
+// it feeds HKDF the X25519 ECDH shared secret concatenated with
+// kemSharedSecret (e.g. an ML-KEM-768 encapsulated secret) instead of the
+// X25519 secret alone, so recorded traffic stays confidential even if
+// X25519 alone is later broken by a quantum adversary - the attacker would
+// additionally need to break the KEM.
+//
+// kemSharedSecret is supplied by the caller rather than computed here:
+// this package has no KEM implementation of its own (see
+// internal/crypto/handshake, which already speaks a hybrid
+// X25519+ML-KEM-768 handshake using the standard library's crypto/mlkem
+// and can be pointed at ML-KEM-1024 or another KEM just as easily).
+//
+// Parameters:
+//   - ourX25519Priv, theirX25519Pub: the classical X25519 keypair halves
+//   - kemSharedSecret: the decapsulated/encapsulated PQ KEM shared secret
+//   - manifestHash: BLAKE3 hash of the file manifest (32 bytes, used as salt)
+//
+// Returns:
+//   - SessionKeys containing PayloadKey, ControlKey, and IVBase
+//   - error if ECDH fails, kemSharedSecret is empty, or key derivation fails
+func DeriveSessionKeysHybrid(ourX25519Priv, theirX25519Pub *[32]byte, kemSharedSecret []byte, manifestHash []byte) (*SessionKeys, error) {
+	if len(manifestHash) != 32 {
+		return nil, fmt.Errorf("manifest hash must be 32 bytes, got %d", len(manifestHash))
+	}
+	if len(kemSharedSecret) == 0 {
+		return nil, fmt.Errorf("kemSharedSecret must not be empty")
+	}
+
+	sharedSecret, err := X25519Exchange(ourX25519Priv, theirX25519Pub)
+	if err != nil {
+		return nil, fmt.Errorf("ECDH key exchange failed: %w", err)
+	}
+
+	ikm := append(append([]byte{}, sharedSecret[:]...), kemSharedSecret...)
+
+	hkdfReader := hkdf.New(
+		sha256.New,
+		ikm,
+		manifestHash,
+		[]byte(hybridSessionInfoString),
+	)
+
+	keyMaterial := make([]byte, hkdfOutputLength)
+	if _, err := io.ReadFull(hkdfReader, keyMaterial); err != nil {
+		return nil, fmt.Errorf("HKDF key derivation failed: %w", err)
+	}
+
+	var keys SessionKeys
+	copy(keys.PayloadKey[:], keyMaterial[0:32])
+	copy(keys.ControlKey[:], keyMaterial[32:64])
+	copy(keys.IVBase[:], keyMaterial[64:76])
+
 	return &keys, nil
 }
\ No newline at end of file
@@ -0,0 +1,28 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// DeriveStorageKey derives a 32-byte AES-256 key for encrypting at-rest data
+// (e.g. session metadata rows) from the service's own Ed25519 identity key,
+// via HKDF. label provides domain separation between different at-rest
+// encryption uses of the same identity key (e.g. "session-metadata"), the
+// same role manifestHash plays in DeriveSessionKeys for in-flight keys.
+//
+// Unlike session keys, a storage key is not tied to a specific transfer and
+// is deterministic for a given identity+label, so existing encrypted rows
+// stay decryptable across daemon restarts without persisting the key itself.
+func DeriveStorageKey(identity ed25519.PrivateKey, label string) [32]byte {
+	hkdfReader := hkdf.New(sha256.New, identity.Seed(), nil, []byte("quantarax-v1-storage:"+label))
+
+	var key [32]byte
+	// HKDF-Expand only fails if the requested output exceeds 255*HashSize,
+	// which 32 bytes never does.
+	_, _ = io.ReadFull(hkdfReader, key[:])
+	return key
+}
@@ -0,0 +1,83 @@
+// Package discovery implements a device discovery service modeled on
+// Syncthing's stdiscosrv: peers announce the addresses they're reachable
+// at, signed with the ed25519 identity key (see internal/crypto/identity)
+// that names them, and other peers look those addresses up by device ID.
+// A lookup also hands back the server's own view of the requester's
+// address, a STUN-style reflexive address internal/nattraversal's Gather
+// can use as a server-reflexive candidate without a separate STUN round
+// trip.
+package discovery
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"time"
+)
+
+// deviceIDEncoding matches Syncthing's own device ID alphabet choice:
+// base32 without padding, so a device ID is URL- and path-segment-safe
+// without escaping (it appears directly in GET /v1/lookup/{device_id}).
+var deviceIDEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// DeviceID derives the stable identifier a peer announces and is looked up
+// by: the base32 encoding of SHA-256 over its ed25519 public key. Deriving
+// it from the key (rather than letting a peer pick its own ID) is what
+// lets Announce verify an announcement's signature against the very ID
+// it's stored under.
+func DeviceID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return deviceIDEncoding.EncodeToString(sum[:])
+}
+
+// Announcement is the signed blob a peer posts to advertise where it's
+// reachable. Sig is computed over the canonical signed bytes (see
+// signedBytes) with the ed25519 private key matching PublicKey, so the
+// registry can verify an announcement came from whoever DeviceID actually
+// names before storing it.
+type Announcement struct {
+	DeviceID  string    `json:"device_id"`
+	PublicKey []byte    `json:"public_key"`
+	Addresses []string  `json:"addresses"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Sig       []byte    `json:"sig"`
+}
+
+// signedBytes is what Sig authenticates: device ID, addresses, and expiry,
+// joined the same way the handshake package's sign/verify helpers join
+// transcript parts, so tampering with any one field invalidates Sig.
+func signedBytes(deviceID string, addresses []string, expiresAt time.Time) []byte {
+	msg := []byte("QX-DISCOVERY|" + deviceID + "|")
+	for _, addr := range addresses {
+		msg = append(msg, addr...)
+		msg = append(msg, ',')
+	}
+	msg = append(msg, []byte(expiresAt.UTC().Format(time.RFC3339))...)
+	return msg
+}
+
+// Sign fills in Sig for the (DeviceID, Addresses, ExpiresAt) already set on
+// ann, using priv. Callers normally get here via Announce helpers in the
+// client, not the server; it's exported because tests and any future
+// quantarax-side announce client both need it.
+func (a *Announcement) Sign(priv ed25519.PrivateKey) {
+	a.Sig = ed25519.Sign(priv, signedBytes(a.DeviceID, a.Addresses, a.ExpiresAt))
+}
+
+// Verify reports whether ann's signature is valid for its own PublicKey
+// and whether that public key actually hashes to ann's claimed DeviceID —
+// both checks are required, since a valid signature from the wrong key
+// would otherwise let any peer overwrite another device's announcement.
+func (a *Announcement) Verify() error {
+	if len(a.PublicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("discovery: invalid public key length %d", len(a.PublicKey))
+	}
+	if DeviceID(a.PublicKey) != a.DeviceID {
+		return fmt.Errorf("discovery: device_id does not match sha256(public_key)")
+	}
+	if !ed25519.Verify(a.PublicKey, signedBytes(a.DeviceID, a.Addresses, a.ExpiresAt), a.Sig) {
+		return fmt.Errorf("discovery: signature verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package discovery
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// record is what Registry keeps per device: the announcement as received
+// plus when it was last (re-)announced, so CleanupExpired can evict it.
+type record struct {
+	announcement Announcement
+}
+
+// Registry holds every device's current announcement in memory, keyed by
+// device ID. Unlike bootstrap's TokenRegistry/UsernameRegistry, there's no
+// CAS-retry dance here: only the device itself can produce a validly
+// signed announcement for its own ID, so a re-announcement always
+// legitimately overwrites the previous one rather than racing another
+// writer for the same key.
+type Registry struct {
+	mu      sync.Mutex
+	devices map[string]record
+}
+
+// NewRegistry creates an empty in-memory registry.
+func NewRegistry() *Registry {
+	return &Registry{devices: make(map[string]record)}
+}
+
+// Announce verifies ann's signature and stores it, superseding whatever
+// was previously announced for the same device ID (e.g. after the device
+// changed IP address and is re-announcing with a new address list).
+func (r *Registry) Announce(ann Announcement) error {
+	if err := ann.Verify(); err != nil {
+		return err
+	}
+	if ann.ExpiresAt.Before(time.Now()) {
+		return fmt.Errorf("discovery: announcement already expired")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.devices[ann.DeviceID] = record{announcement: ann}
+	return nil
+}
+
+// LookupResult is what Lookup returns: the device's announced addresses
+// plus the IP the server observed the lookup request arrive from, handed
+// back the way a STUN server hands back a reflexive address.
+type LookupResult struct {
+	Addresses  []string `json:"addresses"`
+	ObservedIP string   `json:"observed_ip"`
+}
+
+// Lookup returns deviceID's current address list, or ok=false if it has no
+// live (non-expired) announcement on file. observedIP is folded into the
+// result unchanged; Lookup doesn't validate it since it's just whatever
+// the HTTP layer read off the request's remote address.
+func (r *Registry) Lookup(deviceID, observedIP string) (LookupResult, bool) {
+	r.mu.Lock()
+	rec, found := r.devices[deviceID]
+	r.mu.Unlock()
+	if !found || rec.announcement.ExpiresAt.Before(time.Now()) {
+		return LookupResult{}, false
+	}
+	return LookupResult{Addresses: rec.announcement.Addresses, ObservedIP: observedIP}, true
+}
+
+// CleanupExpired evicts every device whose announcement has expired,
+// returning how many were removed.
+func (r *Registry) CleanupExpired() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	count := 0
+	for id, rec := range r.devices {
+		if rec.announcement.ExpiresAt.Before(now) {
+			delete(r.devices, id)
+			count++
+		}
+	}
+	return count
+}
+
+// Count returns the number of devices currently registered, expired or
+// not, for health/stats reporting.
+func (r *Registry) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.devices)
+}
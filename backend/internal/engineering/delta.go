@@ -1,32 +1,135 @@
 package engineering
 
 import (
+	"encoding/base64"
 	"io"
 	"os"
 
 	"github.com/zeebo/blake3"
 )
 
-// ComputeDeltaBlocks computes rolling hashes for fixed-size blocks to allow delta-sync.
-// This is a simple baseline: fixed window; production may use variable windows and sparse maps.
-func ComputeDeltaBlocks(path string, blockSize int) ([][32]byte, error) {
+// rollWindow is the number of trailing bytes the rolling hash sums over.
+const rollWindow = 64
+
+// minChunkSize and maxChunkSize bound content-defined chunk sizes so that a
+// pathological input (e.g. a long run of repeated bytes) can't produce a
+// chunk that never closes, or a flood of tiny chunks.
+const (
+	minChunkSize = 512 * 1024
+	maxChunkSize = 4 * 1024 * 1024
+)
+
+// chunkMask targets an average chunk size of ~1 MiB: a boundary is declared
+// whenever the rolling sum's low bits match chunkMagic.
+const (
+	chunkMask  = (1 << 20) - 1
+	chunkMagic = 0
+)
+
+// rollTable is a precomputed table of pseudo-random 32-bit values, one per
+// input byte, used by the Buzhash-style rolling hash below.
+var rollTable = buildRollTable()
+
+func buildRollTable() [256]uint32 {
+	// Fixed seed so the table (and therefore chunk boundaries) is
+	// deterministic across runs and machines.
+	var tab [256]uint32
+	seed := uint32(0x9E3779B9)
+	for i := range tab {
+		seed = seed*1664525 + 1013904223
+		tab[i] = seed
+	}
+	return tab
+}
+
+func rotl(x uint32, n uint) uint32 {
+	return (x << n) | (x >> (32 - n))
+}
+
+// DeltaBlock describes one content-defined chunk of a file: its byte range
+// and BLAKE3 checksum, suitable for building a sparse content-addressable
+// map so a receiver can skip chunks it already has on disk.
+type DeltaBlock struct {
+	Offset    int64
+	Length    int
+	BLAKE3Sum string // base64-encoded BLAKE3 hash
+}
+
+// ComputeDeltaBlocks splits path into content-defined chunks using a
+// Buzhash-style rolling hash over a rollWindow-byte window, so that an
+// insertion or deletion upstream only disturbs the chunks touching the
+// edit instead of every chunk after it. chunkSize is used as a hint for
+// the desired average chunk size when it falls within
+// [minChunkSize, maxChunkSize]; otherwise the package defaults apply.
+func ComputeDeltaBlocks(path string, chunkSize int) ([]DeltaBlock, error) {
 	f, err := os.Open(path)
-	if err != nil { return nil, err }
+	if err != nil {
+		return nil, err
+	}
 	defer f.Close()
-	var hashes [][32]byte
-	buf := make([]byte, blockSize)
+
+	minSize, maxSize := minChunkSize, maxChunkSize
+	if chunkSize >= minSize && chunkSize <= maxSize {
+		maxSize = chunkSize
+	}
+
+	var (
+		blocks    []DeltaBlock
+		window    [rollWindow]byte
+		windowPos int
+		sum       uint32
+		offset    int64
+		start     int64
+		hasher    = blake3.New()
+		buf       [64 * 1024]byte
+	)
+
+	flush := func(end int64) {
+		if end <= start {
+			return
+		}
+		sumBytes := hasher.Sum(nil)
+		blocks = append(blocks, DeltaBlock{
+			Offset:    start,
+			Length:    int(end - start),
+			BLAKE3Sum: base64.StdEncoding.EncodeToString(sumBytes),
+		})
+		hasher.Reset()
+		start = end
+		window = [rollWindow]byte{}
+		windowPos = 0
+		sum = 0
+	}
+
 	for {
-		n, err := io.ReadFull(f, buf)
-		if err == io.ErrUnexpectedEOF {
-			// last partial block
-			h := blake3.Sum256(buf[:n])
-			hashes = append(hashes, h)
+		n, rerr := f.Read(buf[:])
+		for i := 0; i < n; i++ {
+			b := buf[i]
+			hasher.Write([]byte{b})
+			offset++
+
+			out := window[windowPos]
+			window[windowPos] = b
+			windowPos = (windowPos + 1) % rollWindow
+
+			sum = rotl(sum, 1) ^ rollTable[b] ^ rotl(rollTable[out], rollWindow%32)
+
+			chunkLen := offset - start
+			if chunkLen < int64(minSize) {
+				continue
+			}
+			if chunkLen >= int64(maxSize) || sum&chunkMask == chunkMagic {
+				flush(offset)
+			}
+		}
+		if rerr == io.EOF {
 			break
 		}
-		if err == io.EOF { break }
-		if err != nil { return nil, err }
-		h := blake3.Sum256(buf)
-		hashes = append(hashes, h)
+		if rerr != nil {
+			return nil, rerr
+		}
 	}
-	return hashes, nil
+	flush(offset)
+
+	return blocks, nil
 }
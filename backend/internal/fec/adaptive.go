@@ -13,6 +13,8 @@ type PolicyState struct {
 	R         int     // Parity shards
 	LossRate  float64 // Current loss rate percentage
 	UpdatedAt time.Time
+	GE        GEState // Fitted Gilbert-Elliott burst-loss model (zero value if not yet fitted)
+	GEFitted  bool
 }
 
 // AdaptivePolicy manages FEC parameters based on network conditions
@@ -33,53 +35,79 @@ type AdaptivePolicy struct {
 	lastStateChange  time.Time
 	sampleStartTime  time.Time
 
+	// onChange, if set, is invoked (outside the lock) whenever Update
+	// changes enabled/K/R, so callers can publish a fec_updated event
+	// without this package depending on observability.
+	onChange func(PolicyState)
+
+	// Gilbert-Elliott burst-loss model, refit from lossRateSamples once at
+	// least geMinSamples are available.
+	targetResidualLoss float64
+	ge                 GEState
+	geFitted           bool
+
 	mu sync.RWMutex
 }
 
 // PolicyConfig holds adaptive policy configuration
 type PolicyConfig struct {
-	EnableThreshold  float64       // Default: 1.0%
-	DisableThreshold float64       // Default: 0.5%
-	MinObservation   time.Duration // Default: 30s
-	DefaultK         int           // Default: 8
-	DefaultR         int           // Default: 2
-	MaxR             int           // Default: 4
+	EnableThreshold    float64       // Default: 1.0%
+	DisableThreshold   float64       // Default: 0.5%
+	MinObservation     time.Duration // Default: 30s
+	DefaultK           int           // Default: 8
+	DefaultR           int           // Default: 2
+	MaxR               int           // Default: 4
+	TargetResidualLoss float64       // Target uncorrectable-block probability once a burst-loss model is fitted. Default: 1e-4
 }
 
 // DefaultPolicyConfig returns default policy configuration
 func DefaultPolicyConfig() PolicyConfig {
 	return PolicyConfig{
-		EnableThreshold:  1.0,
-		DisableThreshold: 0.5,
-		MinObservation:   30 * time.Second,
-		DefaultK:         8,
-		DefaultR:         2,
-		MaxR:             4,
+		EnableThreshold:    1.0,
+		DisableThreshold:   0.5,
+		MinObservation:     30 * time.Second,
+		DefaultK:           8,
+		DefaultR:           2,
+		MaxR:               4,
+		TargetResidualLoss: 1e-4,
 	}
 }
 
 // NewAdaptivePolicy creates a new adaptive FEC policy
 func NewAdaptivePolicy(config PolicyConfig) *AdaptivePolicy {
+	targetResidualLoss := config.TargetResidualLoss
+	if targetResidualLoss <= 0 {
+		targetResidualLoss = 1e-4
+	}
 	return &AdaptivePolicy{
-		enableThreshold:  config.EnableThreshold,
-		disableThreshold: config.DisableThreshold,
-		minObservation:   config.MinObservation,
-		defaultK:         config.DefaultK,
-		defaultR:         config.DefaultR,
-		maxR:             config.MaxR,
-		enabled:          false,
-		currentK:         config.DefaultK,
-		currentR:         config.DefaultR,
-		lossRateSamples:  make([]float64, 0, 60), // 60 samples max
-		lastStateChange:  time.Now(),
-		sampleStartTime:  time.Now(),
+		enableThreshold:    config.EnableThreshold,
+		disableThreshold:   config.DisableThreshold,
+		minObservation:     config.MinObservation,
+		defaultK:           config.DefaultK,
+		defaultR:           config.DefaultR,
+		maxR:               config.MaxR,
+		targetResidualLoss: targetResidualLoss,
+		enabled:            false,
+		currentK:           config.DefaultK,
+		currentR:           config.DefaultR,
+		lossRateSamples:    make([]float64, 0, 60), // 60 samples max
+		lastStateChange:    time.Now(),
+		sampleStartTime:    time.Now(),
 	}
 }
 
+// SetOnChange registers a callback invoked whenever Update changes the
+// enabled/K/R state, so callers (e.g. the transport layer) can publish a
+// fec_updated event without this package depending on observability.
+func (ap *AdaptivePolicy) SetOnChange(fn func(PolicyState)) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	ap.onChange = fn
+}
+
 // Update updates the policy with the latest loss rate
 func (ap *AdaptivePolicy) Update(lossRate float64) {
 	ap.mu.Lock()
-	defer ap.mu.Unlock()
 
 	// Add sample
 	ap.lossRateSamples = append(ap.lossRateSamples, lossRate)
@@ -95,34 +123,69 @@ func (ap *AdaptivePolicy) Update(lossRate float64) {
 	// Check if enough time has passed since last state change
 	timeSinceChange := time.Since(ap.lastStateChange)
 	if timeSinceChange < ap.minObservation {
+		ap.mu.Unlock()
 		return // Too soon to change state
 	}
 
+	changed := false
+
 	// Apply policy rules
 	if !ap.enabled && avgLoss > ap.enableThreshold {
 		// Enable FEC
 		ap.enabled = true
 		ap.currentR = ap.defaultR
 		ap.lastStateChange = time.Now()
+		changed = true
 	} else if ap.enabled && avgLoss < ap.disableThreshold {
 		// Disable FEC (only after longer observation)
 		if timeSinceChange >= ap.minObservation*10 { // 5 minutes
 			ap.enabled = false
 			ap.lastStateChange = time.Now()
+			changed = true
 		}
 	} else if ap.enabled {
 		// Adjust R based on loss rate
 		if avgLoss > 5.0 && ap.currentR < ap.maxR {
 			ap.currentR = 4
 			ap.lastStateChange = time.Now()
+			changed = true
 		} else if avgLoss > 3.0 && ap.currentR < 3 {
 			ap.currentR = 3
 			ap.lastStateChange = time.Now()
+			changed = true
 		} else if avgLoss < 2.0 && ap.currentR > ap.defaultR {
 			ap.currentR = ap.defaultR
 			ap.lastStateChange = time.Now()
+			changed = true
+		}
+	}
+
+	// Once enough samples have accumulated, refine R using a fitted
+	// Gilbert-Elliott burst-loss model instead of the scalar EMA rules
+	// above, since bursty Wi-Fi/cellular loss is underserved by a single
+	// average. Below geMinSamples, the EMA-driven R chosen above stands.
+	if ap.enabled {
+		if ge, ok := fitGilbertElliott(ap.lossRateSamples); ok {
+			ap.ge = ge
+			ap.geFitted = true
+			if targetR := chooseParityShards(ap.currentK, ap.defaultR, ap.maxR, ge, ap.targetResidualLoss); targetR != ap.currentR {
+				ap.currentR = targetR
+				ap.lastStateChange = time.Now()
+				changed = true
+			}
 		}
 	}
+
+	var state PolicyState
+	onChange := ap.onChange
+	if changed && onChange != nil {
+		state = PolicyState{Enabled: ap.enabled, K: ap.currentK, R: ap.currentR, LossRate: avgLoss, UpdatedAt: ap.lastStateChange, GE: ap.ge, GEFitted: ap.geFitted}
+	}
+	ap.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange(state)
+	}
 }
 
 // GetParameters returns current FEC parameters
@@ -143,6 +206,8 @@ func (ap *AdaptivePolicy) GetState() PolicyState {
 		R:         ap.currentR,
 		LossRate:  ap.calculateAverageLoss(),
 		UpdatedAt: time.Now(),
+		GE:        ap.ge,
+		GEFitted:  ap.geFitted,
 	}
 }
 
@@ -195,6 +260,8 @@ func (ap *AdaptivePolicy) Reset() {
 	ap.lossRateSamples = make([]float64, 0, 60)
 	ap.lastStateChange = time.Now()
 	ap.sampleStartTime = time.Now()
+	ap.ge = GEState{}
+	ap.geFitted = false
 }
 
 var (
@@ -2,13 +2,85 @@ package fec
 
 import (
 	"crypto/rand"
+	"fmt"
 	"testing"
 )
 
+// BenchmarkFECEncode measures NewEncoder/Encode throughput across the
+// shard sizes a chunker.FecScheme stripe actually produces (one shard per
+// data chunk, so shard size tracks manifest.ChunkSize), reporting
+// MB/s via b.SetBytes so -benchmem comparisons reflect real encoder cost
+// instead of the prior len(data) placeholder.
 func BenchmarkFECEncode(b *testing.B) {
-	data := make([]byte, 1<<20)
-	rand.Read(data)
-	for i := 0; i < b.N; i++ {
-		_ = len(data) // placeholder until FEC encode exposed
+	const k, r = 8, 2
+	for _, shardSize := range []int{4 << 10, 64 << 10, 256 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("shard=%dKB", shardSize/1024), func(b *testing.B) {
+			dataShards := make([][]byte, k)
+			for i := range dataShards {
+				dataShards[i] = make([]byte, shardSize)
+				if _, err := rand.Read(dataShards[i]); err != nil {
+					b.Fatal(err)
+				}
+			}
+			enc, err := NewEncoder(k, r)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.SetBytes(int64(k * shardSize))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := enc.Encode(dataShards); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkFECReconstruct measures Reconstruct's cost when exactly r
+// shards are missing (the worst case this scheme can still recover from),
+// across the same shard sizes as BenchmarkFECEncode.
+func BenchmarkFECReconstruct(b *testing.B) {
+	const k, r = 8, 2
+	for _, shardSize := range []int{4 << 10, 64 << 10, 256 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("shard=%dKB", shardSize/1024), func(b *testing.B) {
+			enc, err := NewEncoder(k, r)
+			if err != nil {
+				b.Fatal(err)
+			}
+			dec, err := NewDecoder(k, r)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			dataShards := make([][]byte, k)
+			for i := range dataShards {
+				dataShards[i] = make([]byte, shardSize)
+				if _, err := rand.Read(dataShards[i]); err != nil {
+					b.Fatal(err)
+				}
+			}
+			parityShards, err := enc.Encode(dataShards)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.SetBytes(int64(k * shardSize))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				shards := make([][]byte, k+r)
+				copy(shards[:k], dataShards)
+				copy(shards[k:], parityShards)
+				shards[0] = nil
+				shards[k+1] = nil
+				b.StartTimer()
+
+				if err := dec.Reconstruct(shards); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
 	}
 }
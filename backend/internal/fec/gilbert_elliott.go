@@ -0,0 +1,195 @@
+package fec
+
+import (
+	"math"
+	"math/rand"
+)
+
+// geMinSamples is the minimum number of loss-rate samples required before
+// the Gilbert-Elliott fit is trusted; below this the caller should keep
+// using the scalar EMA-driven rules.
+const geMinSamples = 30
+
+// geSimTrials is the number of Monte Carlo trials used to estimate the
+// probability that a K+R symbol block exceeds R erasures under the fitted
+// Gilbert-Elliott chain.
+const geSimTrials = 2000
+
+// GEState describes a fitted Gilbert-Elliott two-state burst-loss model:
+// state G (good, loss probability ~0) and state B (bad, loss probability
+// PB), with transition probability P (G->B) and R (B->G).
+type GEState struct {
+	P        float64 // P(G -> B)
+	R        float64 // P(B -> G)
+	PB       float64 // loss probability while in state B
+	BurstLen float64 // expected consecutive bad intervals, 1/R
+}
+
+// fitGilbertElliott estimates {P, R, PB} from a window of per-interval loss
+// rates (as fractions in [0,1]) via windowed method-of-moments, assuming the
+// good state has loss probability ~0. It treats each sample as drawn from a
+// two-point mixture {0, PB} with P(PB) = piB, so:
+//
+//	mean  mu  = piB * PB
+//	var   s2  = piB*PB^2 - mu^2  =>  PB = (s2 + mu^2) / mu
+//	lag-1 autocorrelation rho = 1 - P - R, with piB = P/(P+R)
+//	  => P = piB*(1-rho), R = (1-piB)*(1-rho)
+//
+// ok is false when there are too few samples or no observed loss to fit against.
+func fitGilbertElliott(samplesPct []float64) (state GEState, ok bool) {
+	if len(samplesPct) < geMinSamples {
+		return GEState{}, false
+	}
+
+	samples := make([]float64, len(samplesPct))
+	for i, v := range samplesPct {
+		samples[i] = v / 100.0
+	}
+
+	n := float64(len(samples))
+	var mean float64
+	for _, v := range samples {
+		mean += v
+	}
+	mean /= n
+	if mean <= 0 {
+		return GEState{}, false
+	}
+
+	var variance, autocov float64
+	for i, v := range samples {
+		d := v - mean
+		variance += d * d
+		if i > 0 {
+			autocov += d * (samples[i-1] - mean)
+		}
+	}
+	variance /= n
+	if variance <= 0 {
+		return GEState{}, false
+	}
+	autocov /= n - 1
+	rho := clamp01Signed(autocov / variance)
+
+	pb := clamp01((variance + mean*mean) / mean)
+	if pb < mean {
+		pb = mean // PB can never be below the overall mean loss rate
+	}
+	piB := clamp01(mean / pb)
+
+	p := clamp01(piB * (1 - rho))
+	r := clamp01((1 - piB) * (1 - rho))
+	if r <= 0 {
+		return GEState{}, false
+	}
+
+	return GEState{P: p, R: r, PB: pb, BurstLen: 1 / r}, true
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+func clamp01Signed(v float64) float64 {
+	if v < -1 {
+		return -1
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// chooseParityShards simulates the fitted Gilbert-Elliott chain over blocks
+// of k+r symbols and returns the smallest r in [defaultR, maxR] whose
+// estimated uncorrectable-block probability (more than r erasures in the
+// block) is at or below target. When the chain mixes quickly (p+r > 0.5,
+// i.e. little burstiness left after one step), it falls back to the
+// closed-form binomial approximation using the stationary loss rate.
+func chooseParityShards(k, defaultR, maxR int, state GEState, target float64) int {
+	for r := defaultR; r <= maxR; r++ {
+		var failProb float64
+		if state.P+state.R > 0.5 {
+			failProb = binomialTailProb(k+r, r, state.P/(state.P+state.R)*state.PB)
+		} else {
+			failProb = simulateBlockFailureProb(k, r, state)
+		}
+		if failProb <= target {
+			return r
+		}
+	}
+	return maxR
+}
+
+// simulateBlockFailureProb runs geSimTrials Monte Carlo simulations of the
+// two-state chain over k+r symbols and returns the fraction of trials with
+// more than r losses.
+func simulateBlockFailureProb(k, r int, state GEState) float64 {
+	n := k + r
+	if n <= 0 {
+		return 0
+	}
+	// A fixed seed keeps the estimate (and R selection) reproducible across
+	// runs instead of depending on global rand state.
+	rng := rand.New(rand.NewSource(1))
+	failures := 0
+	for t := 0; t < geSimTrials; t++ {
+		bad := rng.Float64() < state.P/(state.P+state.R) // start from the stationary distribution
+		losses := 0
+		for i := 0; i < n; i++ {
+			lossProb := 0.0
+			if bad {
+				lossProb = state.PB
+			}
+			if rng.Float64() < lossProb {
+				losses++
+			}
+			if bad {
+				bad = rng.Float64() >= state.R // stay bad unless we transition out
+			} else {
+				bad = rng.Float64() < state.P // stay good unless we transition in
+			}
+		}
+		if losses > r {
+			failures++
+		}
+	}
+	return float64(failures) / float64(geSimTrials)
+}
+
+// binomialTailProb returns P(X > r) for X ~ Binomial(n, p).
+func binomialTailProb(n, r int, p float64) float64 {
+	if p <= 0 {
+		return 0
+	}
+	if p >= 1 {
+		return 1
+	}
+	// Sum the PMF for X <= r and take the complement; n here is small
+	// (data + parity shards per block), so the naive approach is cheap.
+	cdf := 0.0
+	logP, log1mP := math.Log(p), math.Log(1-p)
+	for x := 0; x <= r && x <= n; x++ {
+		cdf += binomialPMF(n, x, logP, log1mP)
+	}
+	return clamp01(1 - cdf)
+}
+
+func binomialPMF(n, x int, logP, log1mP float64) float64 {
+	return math.Exp(logBinomialCoeff(n, x) + float64(x)*logP + float64(n-x)*log1mP)
+}
+
+// logBinomialCoeff returns log(n choose k) via the log-gamma function, which
+// stays numerically stable for the small block sizes (K+R shards) used here.
+func logBinomialCoeff(n, k int) float64 {
+	lg1, _ := math.Lgamma(float64(n + 1))
+	lg2, _ := math.Lgamma(float64(k + 1))
+	lg3, _ := math.Lgamma(float64(n-k+1))
+	return lg1 - lg2 - lg3
+}
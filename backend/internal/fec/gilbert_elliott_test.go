@@ -0,0 +1,55 @@
+package fec
+
+import "testing"
+
+func TestFitGilbertElliott_TooFewSamples(t *testing.T) {
+	samples := make([]float64, geMinSamples-1)
+	for i := range samples {
+		samples[i] = 2.0
+	}
+	if _, ok := fitGilbertElliott(samples); ok {
+		t.Error("expected fit to fail with fewer than geMinSamples samples")
+	}
+}
+
+func TestFitGilbertElliott_BurstyLoss(t *testing.T) {
+	// Alternate long runs of ~0% loss with short runs of ~20% loss, so the
+	// sample variance and lag-1 autocorrelation are clearly non-degenerate.
+	var samples []float64
+	for i := 0; i < 40; i++ {
+		if i%10 < 8 {
+			samples = append(samples, 0.1)
+		} else {
+			samples = append(samples, 20.0)
+		}
+	}
+
+	state, ok := fitGilbertElliott(samples)
+	if !ok {
+		t.Fatal("expected fit to succeed with bursty samples")
+	}
+	if state.PB <= 0 || state.PB > 1 {
+		t.Errorf("PB out of range: %v", state.PB)
+	}
+	if state.P <= 0 || state.P > 1 {
+		t.Errorf("P out of range: %v", state.P)
+	}
+	if state.R <= 0 || state.R > 1 {
+		t.Errorf("R out of range: %v", state.R)
+	}
+	if state.BurstLen <= 0 {
+		t.Errorf("expected positive BurstLen, got %v", state.BurstLen)
+	}
+}
+
+func TestChooseParityShards_HigherBurstinessNeedsMoreShards(t *testing.T) {
+	mild := GEState{P: 0.3, R: 0.3, PB: 0.3}
+	severe := GEState{P: 0.3, R: 0.05, PB: 0.3} // longer bursts (smaller R)
+
+	rMild := chooseParityShards(8, 2, 8, mild, 1e-3)
+	rSevere := chooseParityShards(8, 2, 8, severe, 1e-3)
+
+	if rSevere < rMild {
+		t.Errorf("expected burstier loss to require at least as many parity shards (mild=%d, severe=%d)", rMild, rSevere)
+	}
+}
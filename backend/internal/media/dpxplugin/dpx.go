@@ -0,0 +1,122 @@
+// Package dpxplugin decodes SMPTE DPX v2 frames (the Descriptor=RGB/RGBA,
+// BitSize=10, Packing=Method A variant produced by most DI/VFX pipelines)
+// into image.Image, and registers itself with media.RegisterDecoder so
+// media.GenerateThumbnail can preview DPX sequences without the core media
+// package depending on this format directly.
+package dpxplugin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/quantarax/backend/internal/media"
+)
+
+func init() {
+	media.RegisterDecoder(".dpx", Decode, false)
+}
+
+// DPX element descriptor values this decoder understands (SMPTE 268M Table
+// 4). Other descriptors (luma-only, CbYCr, etc.) are rejected rather than
+// silently misread.
+const (
+	descriptorRGB  = 50
+	descriptorRGBA = 51
+)
+
+// genericHeaderSize is the fixed size, in bytes, of a DPX generic file
+// header (SMPTE 268M); the image information header immediately follows.
+const genericHeaderSize = 768
+
+// Decode reads a DPX frame from r and returns its first image element as an
+// image.Image. Only 10-bit, Method A packed RGB/RGBA elements are
+// supported, which covers the overwhelming majority of DPX frames produced
+// by film scanners and DI pipelines; anything else returns an error rather
+// than a silently wrong image.
+func Decode(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < genericHeaderSize+72 {
+		return nil, fmt.Errorf("dpx: file too short for a generic header")
+	}
+
+	var order binary.ByteOrder
+	switch string(data[0:4]) {
+	case "SDPX":
+		order = binary.BigEndian
+	case "XPDS":
+		order = binary.LittleEndian
+	default:
+		return nil, fmt.Errorf("dpx: bad magic number %q", data[0:4])
+	}
+
+	orientation := order.Uint16(data[768:770])
+	if orientation != 0 {
+		return nil, fmt.Errorf("dpx: orientation %d not supported (only left-to-right, top-to-bottom)", orientation)
+	}
+	width := int(order.Uint32(data[772:776]))
+	height := int(order.Uint32(data[776:780]))
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("dpx: invalid dimensions %dx%d", width, height)
+	}
+
+	elem := data[780:852] // first ImageElement struct, 72 bytes
+	descriptor := elem[20]
+	bitSize := elem[23]
+	packing := order.Uint16(elem[24:26])
+	dataOffset := order.Uint32(elem[28:32])
+
+	if bitSize != 10 || packing != 1 {
+		return nil, fmt.Errorf("dpx: only 10-bit Method A packing is supported (got bitsize=%d packing=%d)", bitSize, packing)
+	}
+
+	hasAlpha := descriptor == descriptorRGBA
+	if descriptor != descriptorRGB && descriptor != descriptorRGBA {
+		return nil, fmt.Errorf("dpx: unsupported element descriptor %d (only RGB/RGBA)", descriptor)
+	}
+
+	img := image.NewNRGBA64(image.Rect(0, 0, width, height))
+	off := int(dataOffset)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if off+4 > len(data) {
+				return nil, fmt.Errorf("dpx: truncated pixel data at row %d col %d", y, x)
+			}
+			word := order.Uint32(data[off : off+4])
+			off += 4
+			r10 := uint16((word >> 22) & 0x3FF)
+			g10 := uint16((word >> 12) & 0x3FF)
+			b10 := uint16((word >> 2) & 0x3FF)
+			a16 := uint16(0xFFFF)
+			if hasAlpha {
+				// RGBA packs A into its own following word rather than
+				// stealing the 2 padding bits, per SMPTE 268M.
+				if off+4 > len(data) {
+					return nil, fmt.Errorf("dpx: truncated alpha data at row %d col %d", y, x)
+				}
+				aword := order.Uint32(data[off : off+4])
+				off += 4
+				a16 = expand10to16(uint16((aword >> 22) & 0x3FF))
+			}
+			img.SetNRGBA64(x, y, color.NRGBA64{
+				R: expand10to16(r10),
+				G: expand10to16(g10),
+				B: expand10to16(b10),
+				A: a16,
+			})
+		}
+	}
+	return img, nil
+}
+
+// expand10to16 scales a 10-bit sample (0..1023) to the full 16-bit range
+// using bit replication (v<<6 | v>>4), the standard lossless way to widen
+// an N-bit value without the low end staying darker than it should.
+func expand10to16(v uint16) uint16 {
+	return v<<6 | v>>4
+}
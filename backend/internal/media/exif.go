@@ -0,0 +1,152 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"os"
+	"strings"
+)
+
+// exifOrientationTag is the EXIF IFD0 tag number for image orientation
+// (values 1-8, per the TIFF/EXIF spec section on Orientation).
+const exifOrientationTag = 0x0112
+
+// correctOrientation re-renders img upright according to inputPath's EXIF
+// Orientation tag, if present. Only JPEG carries EXIF in this pipeline
+// (PNG/WebP/EXR/DPX have no equivalent tag the decoders above surface), so
+// non-JPEG inputs and JPEGs without the tag are returned unchanged.
+func correctOrientation(inputPath string, img image.Image) image.Image {
+	if !strings.HasSuffix(strings.ToLower(inputPath), ".jpg") && !strings.HasSuffix(strings.ToLower(inputPath), ".jpeg") {
+		return img
+	}
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return img
+	}
+	orientation, ok := readJPEGOrientation(data)
+	if !ok || orientation == 1 {
+		return img
+	}
+	return applyOrientation(img, orientation)
+}
+
+// readJPEGOrientation scans data's APP1/Exif segment for the Orientation
+// tag, returning ok=false if no Exif segment or tag is present.
+func readJPEGOrientation(data []byte) (orientation int, ok bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, false
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			break
+		}
+		marker := data[i+1]
+		if marker == 0xD8 || marker == 0xD9 {
+			i += 2
+			continue
+		}
+		segLen := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if segLen < 2 || i+2+segLen > len(data) {
+			break
+		}
+		seg := data[i+4 : i+2+segLen]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return parseExifOrientation(seg[6:])
+		}
+		i += 2 + segLen
+		if marker == 0xDA {
+			break // start of scan: no more APPn segments follow
+		}
+	}
+	return 0, false
+}
+
+// parseExifOrientation walks a TIFF header (as embedded after the "Exif\0\0"
+// marker) looking for the Orientation tag in IFD0.
+func parseExifOrientation(tiff []byte) (orientation int, ok bool) {
+	if len(tiff) < 8 {
+		return 0, false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+	ifd0Off := order.Uint32(tiff[4:8])
+	if int(ifd0Off)+2 > len(tiff) {
+		return 0, false
+	}
+	count := order.Uint16(tiff[ifd0Off : ifd0Off+2])
+	entries := tiff[ifd0Off+2:]
+	for e := 0; e < int(count); e++ {
+		off := e * 12
+		if off+12 > len(entries) {
+			break
+		}
+		tag := order.Uint16(entries[off : off+2])
+		if tag != exifOrientationTag {
+			continue
+		}
+		valType := order.Uint16(entries[off+2 : off+4])
+		if valType != 3 { // SHORT
+			return 0, false
+		}
+		return int(order.Uint16(entries[off+8 : off+10])), true
+	}
+	return 0, false
+}
+
+// applyOrientation re-renders img upright for EXIF orientation values 2-8.
+// The mapping follows the standard EXIF orientation table: 2/4 mirror, 3
+// rotates 180, 5/6/7/8 additionally rotate +/-90.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+
+	ow, oh := w, h
+	if orientation >= 5 && orientation <= 8 {
+		ow, oh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, ow, oh))
+	for y := 0; y < oh; y++ {
+		for x := 0; x < ow; x++ {
+			sx, sy := mapOrientedCoord(x, y, ow, oh, orientation)
+			dst.Set(x, y, out.At(sx, sy))
+		}
+	}
+	return dst
+}
+
+// mapOrientedCoord maps an output pixel (x, y) in the corrected image back
+// to its source coordinate in the as-decoded (still-rotated/mirrored)
+// image, for each of the EXIF orientation values 2-8.
+func mapOrientedCoord(x, y, ow, oh, orientation int) (sx, sy int) {
+	switch orientation {
+	case 2: // mirror horizontal
+		return ow - 1 - x, y
+	case 3: // rotate 180
+		return ow - 1 - x, oh - 1 - y
+	case 4: // mirror vertical
+		return x, oh - 1 - y
+	case 5: // mirror horizontal + rotate 270 CW
+		return y, x
+	case 6: // rotate 90 CW
+		return y, ow - 1 - x
+	case 7: // mirror horizontal + rotate 90 CW
+		return oh - 1 - y, ow - 1 - x
+	case 8: // rotate 270 CW
+		return oh - 1 - y, x
+	default:
+		return x, y
+	}
+}
@@ -0,0 +1,107 @@
+// Package exrplugin decodes OpenEXR frames into an image.Image that also
+// exposes media.FloatImage, so media.GenerateThumbnail can Reinhard
+// tone-map linear HDR samples before downscaling and encoding to 8-bit
+// JPEG/WebP. It registers itself with media.RegisterDecoder so the core
+// media package doesn't need to depend on an OpenEXR binding directly.
+package exrplugin
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/mokiat/go-openexr"
+
+	"github.com/quantarax/backend/internal/media"
+)
+
+func init() {
+	media.RegisterDecoder(".exr", Decode, true)
+}
+
+// image wraps a decoded OpenEXR RGBA framebuffer. It implements both
+// image.Image (so it works anywhere an image.Image is expected) and
+// media.FloatImage (so callers that care can read the unclamped linear
+// samples directly, e.g. for tone mapping).
+type exrImage struct {
+	width, height int
+	r, g, b, a    []float32
+}
+
+func (im *exrImage) ColorModel() color.Model { return color.RGBA64Model }
+
+func (im *exrImage) Bounds() image.Rectangle {
+	return image.Rect(0, 0, im.width, im.height)
+}
+
+func (im *exrImage) At(x, y int) color.Color {
+	r, g, b, a := im.FloatAt(x, y)
+	return color.RGBA64{
+		R: floatToUint16Clamped(r),
+		G: floatToUint16Clamped(g),
+		B: floatToUint16Clamped(b),
+		A: floatToUint16Clamped(a),
+	}
+}
+
+// FloatAt returns the unclamped linear RGBA sample at (x, y), satisfying
+// media.FloatImage. Values above 1.0 represent highlights brighter than the
+// format's nominal white point and are preserved as-is for the caller's
+// tone mapper to compress.
+func (im *exrImage) FloatAt(x, y int) (r, g, b, a float64) {
+	if !(image.Point{x, y}.In(im.Bounds())) {
+		return 0, 0, 0, 0
+	}
+	i := y*im.width + x
+	return float64(im.r[i]), float64(im.g[i]), float64(im.b[i]), float64(im.a[i])
+}
+
+func floatToUint16Clamped(v float64) uint16 {
+	if v < 0 {
+		v = 0
+	}
+	if v > 1 {
+		v = 1
+	}
+	return uint16(v * 0xFFFF)
+}
+
+// Decode reads a single-part scanline OpenEXR image from r via
+// go-openexr and returns it as an image.Image / media.FloatImage. Only the
+// R/G/B(/A) channels are read; multi-layer or deep EXRs should be
+// pre-flattened by the caller before this decoder sees them.
+func Decode(r io.Reader) (image.Image, error) {
+	file, err := openexr.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("exrplugin: %w", err)
+	}
+	defer file.Close()
+
+	header := file.Header()
+	width := header.DataWindow.Max.X - header.DataWindow.Min.X + 1
+	height := header.DataWindow.Max.Y - header.DataWindow.Min.Y + 1
+
+	r32, err := file.Channel("R")
+	if err != nil {
+		return nil, fmt.Errorf("exrplugin: missing R channel: %w", err)
+	}
+	g32, err := file.Channel("G")
+	if err != nil {
+		return nil, fmt.Errorf("exrplugin: missing G channel: %w", err)
+	}
+	b32, err := file.Channel("B")
+	if err != nil {
+		return nil, fmt.Errorf("exrplugin: missing B channel: %w", err)
+	}
+	a32, err := file.Channel("A")
+	if err != nil {
+		// Alpha is optional; treat a missing channel as fully opaque.
+		a32 = make([]float32, width*height)
+		for i := range a32 {
+			a32[i] = 1
+		}
+	}
+
+	return &exrImage{width: width, height: height, r: r32, g: g32, b: b32, a: a32}, nil
+}
@@ -0,0 +1,96 @@
+package media
+
+import (
+	"encoding/binary"
+	"os"
+)
+
+// RelocateFragmentedMP4 recognizes a styp/moof/mdat fragmented MP4 sequence
+// and, mirroring RelocateMoovToFront, ensures the ftyp+moov init segment
+// precedes the first moof rather than trailing the file. Any sidx box's
+// first_offset is rewritten by the same delta the relocated init segment
+// shifts everything else by, the same way stco/co64 are adjusted today.
+// Returns the same path after atomic swap, or empty string if no change
+// needed.
+func RelocateFragmentedMP4(path string) (string, error) {
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	find := func(tag string, from int) (off int, size int) {
+		for i := from; i+8 <= len(in); {
+			sz := int(binary.BigEndian.Uint32(in[i : i+4]))
+			if sz < 8 || i+sz > len(in) {
+				break
+			}
+			if string(in[i+4:i+8]) == tag {
+				return i, sz
+			}
+			i += sz
+		}
+		return -1, 0
+	}
+	ftypOff, ftypSize := find("ftyp", 0)
+	if ftypOff != 0 {
+		return "", nil
+	}
+	moofOff, _ := find("moof", 0)
+	if moofOff < 0 {
+		return "", nil
+	}
+	moovOff, moovSize := find("moov", 0)
+	if moovOff < 0 || moovOff < moofOff {
+		// No moov, or it already precedes the first moof: nothing to do.
+		return "", nil
+	}
+
+	delta := int64(moovSize)
+	moov := make([]byte, moovSize)
+	copy(moov, in[moovOff:moovOff+moovSize])
+
+	out := make([]byte, 0, len(in))
+	out = append(out, in[:ftypOff+ftypSize]...)
+	out = append(out, moov...)
+	out = append(out, in[ftypOff+ftypSize:moovOff]...)
+	out = append(out, in[moovOff+moovSize:]...)
+
+	rewriteSidxOffsets(out, delta)
+
+	tmp := path + ".fragmoovtmp"
+	if err := os.WriteFile(tmp, out, 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// rewriteSidxOffsets walks buf for top-level sidx boxes and adjusts their
+// first_offset field by delta, so segment-index references stay consistent
+// after a preceding box (the relocated moov) changes size.
+func rewriteSidxOffsets(buf []byte, delta int64) {
+	for i := 0; i+8 <= len(buf); {
+		sz := int(binary.BigEndian.Uint32(buf[i : i+4]))
+		if sz < 8 || i+sz > len(buf) {
+			break
+		}
+		if string(buf[i+4:i+8]) == "sidx" && i+28 <= len(buf) {
+			version := buf[i+8]
+			// box header(8) + version/flags(4) + reference_ID(4) + timescale(4)
+			base := i + 20
+			if version == 0 && base+8 <= len(buf) {
+				// earliest_presentation_time(4) then first_offset(4)
+				off := base + 4
+				v := int64(binary.BigEndian.Uint32(buf[off:off+4])) + delta
+				binary.BigEndian.PutUint32(buf[off:off+4], uint32(v))
+			} else if version != 0 && base+16 <= len(buf) {
+				// earliest_presentation_time(8) then first_offset(8)
+				off := base + 8
+				v := int64(binary.BigEndian.Uint64(buf[off:off+8])) + delta
+				binary.BigEndian.PutUint64(buf[off:off+8], uint64(v))
+			}
+		}
+		i += sz
+	}
+}
@@ -0,0 +1,242 @@
+package media
+
+import "os"
+
+// Matroska/WebM EBML element IDs this pass cares about (Segment's direct
+// children, plus the CueClusterPosition leaf inside Cues/CuePoint/
+// CueTrackPositions).
+const (
+	ebmlIDSegment            = 0x18538067
+	ebmlIDSeekHead           = 0x114D9B74
+	ebmlIDCues               = 0x1C53BB6B
+	ebmlIDCuePoint           = 0xBB
+	ebmlIDCueTrackPositions  = 0xB7
+	ebmlIDCueClusterPosition = 0xF7
+)
+
+// ebmlElement describes one parsed EBML element within its parent's data.
+type ebmlElement struct {
+	id        uint64
+	headerLen int
+	dataOff   int
+	dataLen   int
+}
+
+// readVintSize decodes an EBML "data size" vint: the leading 1-bit marks the
+// encoded length, and is masked out of the value.
+func readVintSize(b []byte) (value uint64, length int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	first := b[0]
+	length = 1
+	mask := byte(0x80)
+	for mask != 0 && first&mask == 0 {
+		mask >>= 1
+		length++
+	}
+	if mask == 0 || length > 8 || length > len(b) {
+		return 0, 0
+	}
+	value = uint64(first &^ mask)
+	for i := 1; i < length; i++ {
+		value = value<<8 | uint64(b[i])
+	}
+	return value, length
+}
+
+// readVintID decodes an EBML element ID vint, keeping the length-marker bit
+// as part of the ID (unlike size vints, IDs are compared including it).
+func readVintID(b []byte) (id uint64, length int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	first := b[0]
+	length = 1
+	mask := byte(0x80)
+	for mask != 0 && first&mask == 0 {
+		mask >>= 1
+		length++
+	}
+	if mask == 0 || length > 4 || length > len(b) {
+		return 0, 0
+	}
+	for i := 0; i < length; i++ {
+		id = id<<8 | uint64(b[i])
+	}
+	return id, length
+}
+
+// parseEBMLChildren walks the direct children of an EBML element's data.
+func parseEBMLChildren(buf []byte) []ebmlElement {
+	var elems []ebmlElement
+	off := 0
+	for off < len(buf) {
+		id, idLen := readVintID(buf[off:])
+		if idLen == 0 {
+			break
+		}
+		size, sizeLen := readVintSize(buf[off+idLen:])
+		if sizeLen == 0 {
+			break
+		}
+		dataOff := off + idLen + sizeLen
+		dataLen := int(size)
+		if dataLen < 0 || dataOff+dataLen > len(buf) {
+			break
+		}
+		elems = append(elems, ebmlElement{id: id, headerLen: idLen + sizeLen, dataOff: dataOff, dataLen: dataLen})
+		off = dataOff + dataLen
+	}
+	return elems
+}
+
+// ReorderMatroska parses EBML elements and, mirroring how RelocateMoovToFront
+// handles an MP4 with moov trailing mdat, ensures SeekHead and Cues sit at
+// the front of the Segment rather than trailing the Clusters as muxers
+// commonly leave them. Every CueClusterPosition is shifted by the same
+// uniform delta (the byte size of the relocated elements), the same
+// single-delta approach used for stco/co64 today. Returns the same path
+// after atomic swap, or empty string if no change needed.
+func ReorderMatroska(path string) (string, error) {
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	top := parseEBMLChildren(in)
+	var seg *ebmlElement
+	for i := range top {
+		if top[i].id == ebmlIDSegment {
+			seg = &top[i]
+			break
+		}
+	}
+	if seg == nil {
+		return "", nil
+	}
+	segData := in[seg.dataOff : seg.dataOff+seg.dataLen]
+	children := parseEBMLChildren(segData)
+
+	seekHeadIdx, cuesIdx := -1, -1
+	for i, c := range children {
+		switch c.id {
+		case ebmlIDSeekHead:
+			seekHeadIdx = i
+		case ebmlIDCues:
+			cuesIdx = i
+		}
+	}
+	if seekHeadIdx == -1 && cuesIdx == -1 {
+		return "", nil
+	}
+	if alreadyAtFront(children, seekHeadIdx, cuesIdx) {
+		return "", nil
+	}
+
+	var moved, rest []byte
+	movedLen := 0
+	for _, idx := range []int{seekHeadIdx, cuesIdx} {
+		if idx < 0 {
+			continue
+		}
+		c := children[idx]
+		moved = append(moved, segData[c.dataOff-c.headerLen:c.dataOff+c.dataLen]...)
+		movedLen += c.headerLen + c.dataLen
+	}
+	for i, c := range children {
+		if i == seekHeadIdx || i == cuesIdx {
+			continue
+		}
+		rest = append(rest, segData[c.dataOff-c.headerLen:c.dataOff+c.dataLen]...)
+	}
+	newSegData := append(moved, rest...)
+
+	if cuesIdx >= 0 {
+		rewriteCueClusterPositions(newSegData, int64(movedLen))
+	}
+
+	out := make([]byte, 0, len(in))
+	out = append(out, in[:seg.dataOff]...)
+	out = append(out, newSegData...)
+	out = append(out, in[seg.dataOff+seg.dataLen:]...)
+
+	tmp := path + ".mkvtmp"
+	if err := os.WriteFile(tmp, out, 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// alreadyAtFront reports whether SeekHead/Cues already occupy the first
+// slots of children, in either order, so ReorderMatroska is a no-op.
+func alreadyAtFront(children []ebmlElement, seekHeadIdx, cuesIdx int) bool {
+	want := 0
+	if seekHeadIdx >= 0 {
+		want++
+	}
+	if cuesIdx >= 0 {
+		want++
+	}
+	for i := 0; i < want && i < len(children); i++ {
+		if i != seekHeadIdx && i != cuesIdx {
+			return false
+		}
+	}
+	return true
+}
+
+// rewriteCueClusterPositions walks segData's Cues element and adds delta to
+// every CueClusterPosition value found under CuePoint/CueTrackPositions,
+// re-encoding each in place at its original vint length.
+func rewriteCueClusterPositions(segData []byte, delta int64) {
+	for _, c := range parseEBMLChildren(segData) {
+		if c.id != ebmlIDCues {
+			continue
+		}
+		cuesData := segData[c.dataOff : c.dataOff+c.dataLen]
+		for _, cp := range parseEBMLChildren(cuesData) {
+			if cp.id != ebmlIDCuePoint {
+				continue
+			}
+			cpData := cuesData[cp.dataOff : cp.dataOff+cp.dataLen]
+			for _, ctp := range parseEBMLChildren(cpData) {
+				if ctp.id != ebmlIDCueTrackPositions {
+					continue
+				}
+				ctpData := cpData[ctp.dataOff : ctp.dataOff+ctp.dataLen]
+				for _, pos := range parseEBMLChildren(ctpData) {
+					if pos.id != ebmlIDCueClusterPosition {
+						continue
+					}
+					raw := ctpData[pos.dataOff : pos.dataOff+pos.dataLen]
+					var v uint64
+					for _, b := range raw {
+						v = v<<8 | uint64(b)
+					}
+					nv := int64(v) + delta
+					if nv < 0 {
+						nv = 0
+					}
+					for i, b := range uintToBytes(uint64(nv), len(raw)) {
+						raw[i] = b
+					}
+				}
+			}
+		}
+	}
+}
+
+// uintToBytes renders v as a big-endian byte slice of exactly n bytes,
+// truncating high-order bits if v no longer fits (best-effort, matching the
+// rest of this pass's non-destructive-on-failure posture).
+func uintToBytes(v uint64, n int) []byte {
+	out := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		out[i] = byte(v)
+		v >>= 8
+	}
+	return out
+}
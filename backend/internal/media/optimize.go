@@ -0,0 +1,53 @@
+package media
+
+import "os"
+
+// OptimizeForStreaming inspects path's container signature (ftyp for ISO
+// base media files, EBML for Matroska/WebM) and applies whichever
+// faststart-style relocation pass applies, so callers like
+// service.TransferService.CreateTransfer don't need container-specific
+// branching. Returns the same path after atomic swap, or empty string if no
+// change was needed or the container wasn't recognized.
+func OptimizeForStreaming(path string) (string, error) {
+	sig, err := containerSignature(path)
+	if err != nil {
+		return "", err
+	}
+	switch sig {
+	case "ftyp":
+		if newPath, err := RelocateMoovToFront(path); err != nil {
+			return "", err
+		} else if newPath != "" {
+			return newPath, nil
+		}
+		return RelocateFragmentedMP4(path)
+	case "ebml":
+		return ReorderMatroska(path)
+	default:
+		return "", nil
+	}
+}
+
+// containerSignature reads just enough of path's header to tell an ISO base
+// media file (ftyp box) apart from a Matroska/WebM file (EBML header), or
+// "" if neither is recognized.
+func containerSignature(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, 8)
+	n, err := f.Read(buf)
+	if err != nil && n == 0 {
+		return "", err
+	}
+	buf = buf[:n]
+	if len(buf) >= 8 && string(buf[4:8]) == "ftyp" {
+		return "ftyp", nil
+	}
+	if len(buf) >= 4 && buf[0] == 0x1A && buf[1] == 0x45 && buf[2] == 0xDF && buf[3] == 0xA3 {
+		return "ebml", nil
+	}
+	return "", nil
+}
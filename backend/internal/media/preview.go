@@ -1,24 +1,215 @@
 package media
 
 import (
+	"fmt"
 	"image"
 	"image/jpeg"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
 
 	_ "image/png"
 )
 
-// GenerateThumbnail attempts to create a JPEG thumbnail from an image file.
-// For EXR/DPX this is a placeholder; integrate a proper reader in production.
+func init() {
+	// Registers the ".webp" extension as decodable via image.Decode, so a
+	// WebP source hits the same path as JPEG/PNG rather than needing its own
+	// decoderRegistry entry.
+	image.RegisterFormat("webp", "RIFF????WEBP", webp.Decode, webp.DecodeConfig)
+}
+
+// Resampler downscales src into an image no larger than maxW x maxH,
+// preserving aspect ratio. Callers select quality vs. speed by swapping the
+// implementation passed to GenerateThumbnail's ThumbnailOptions.
+type Resampler interface {
+	Scale(dst draw.Image, r image.Rectangle, src image.Image, sr image.Rectangle)
+}
+
+// resamplerFunc adapts a draw.Scaler (draw.CatmullRom, draw.ApproxBiLinear,
+// ...) to Resampler by fixing the compositing op to draw.Src, since a
+// thumbnail has no existing background to composite over.
+type resamplerFunc struct{ draw.Scaler }
+
+func (r resamplerFunc) Scale(dst draw.Image, dr image.Rectangle, src image.Image, sr image.Rectangle) {
+	r.Scaler.Scale(dst, dr, src, sr, draw.Src, nil)
+}
+
+// DefaultResampler produces high-quality downscales suitable for VFX/DI
+// preview thumbnails. Callers needing faster, lower-quality scaling (e.g.
+// generating thousands of proxies) can pass draw.ApproxBiLinear via
+// ThumbnailOptions.Resampler instead.
+var DefaultResampler Resampler = resamplerFunc{draw.CatmullRom}
+
+// ToneMapper converts a high-dynamic-range image (e.g. decoded from EXR)
+// down to 8-bit range before JPEG/WebP encoding. It is only invoked for
+// decoders registered with RegisterDecoder(..., hdr: true).
+type ToneMapper func(image.Image) image.Image
+
+// DefaultToneMapper applies the Reinhard operator (c / (1 + c)) per channel,
+// the same tone curve ACES and most DI pipelines use as a sane default for
+// previewing linear HDR footage without crushing highlights to white.
+var DefaultToneMapper ToneMapper = reinhardToneMap
+
+// decoderEntry pairs a decode func with whether its output should be treated
+// as HDR (and therefore tone-mapped) before encoding to an 8-bit output
+// format.
+type decoderEntry struct {
+	decode func(io.Reader) (image.Image, error)
+	hdr    bool
+}
+
+// decoderRegistry holds format decoders keyed by lowercase extension
+// (including the leading dot, e.g. ".exr"). Plugin packages such as
+// media/exrplugin and media/dpxplugin register themselves from an init()
+// func, so core doesn't import format-specific dependencies directly.
+var decoderRegistry = map[string]decoderEntry{}
+
+// RegisterDecoder makes GenerateThumbnail able to open files with the given
+// extension (case-insensitive, with or without a leading dot). hdr marks the
+// decoder's output as linear/high-dynamic-range so GenerateThumbnail applies
+// a ToneMapper before encoding to an 8-bit output format.
+func RegisterDecoder(ext string, decode func(io.Reader) (image.Image, error), hdr bool) {
+	ext = normalizeExt(ext)
+	decoderRegistry[ext] = decoderEntry{decode: decode, hdr: hdr}
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// ThumbnailOptions customizes GenerateThumbnail beyond its required
+// arguments. The zero value selects DefaultResampler, DefaultToneMapper, and
+// JPEG quality 80.
+type ThumbnailOptions struct {
+	Resampler    Resampler
+	ToneMapper   ToneMapper
+	JPEGQuality  int
+	WebPLossless bool
+}
+
+// GenerateThumbnail decodes inputPath, corrects JPEG EXIF orientation,
+// downscales to fit within maxW x maxH using a Resampler (DefaultResampler
+// by default), and encodes to outputPath as JPEG or WebP based on
+// outputPath's extension. EXR/DPX/TIFF sources are decoded through whatever
+// plugin registered that extension via RegisterDecoder; HDR sources are
+// tone-mapped to 8-bit range before encoding.
 func GenerateThumbnail(inputPath, outputPath string, maxW, maxH int) error {
+	return GenerateThumbnailWithOptions(inputPath, outputPath, maxW, maxH, ThumbnailOptions{})
+}
+
+// GenerateThumbnailWithOptions is GenerateThumbnail with explicit control
+// over resampling and tone-mapping.
+func GenerateThumbnailWithOptions(inputPath, outputPath string, maxW, maxH int, opts ThumbnailOptions) error {
+	img, hdr, err := decodeImage(inputPath)
+	if err != nil {
+		return err
+	}
+
+	img = correctOrientation(inputPath, img)
+
+	if hdr {
+		toneMap := opts.ToneMapper
+		if toneMap == nil {
+			toneMap = DefaultToneMapper
+		}
+		img = toneMap(img)
+	}
+
+	resampler := opts.Resampler
+	if resampler == nil {
+		resampler = DefaultResampler
+	}
+	thumb := scaleToFit(img, maxW, maxH, resampler)
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	switch strings.ToLower(filepath.Ext(outputPath)) {
+	case ".webp":
+		return encodeWebP(out, thumb, opts.WebPLossless)
+	default:
+		quality := opts.JPEGQuality
+		if quality == 0 {
+			quality = 80
+		}
+		return jpeg.Encode(out, thumb, &jpeg.Options{Quality: quality})
+	}
+}
+
+// decodeImage opens inputPath and decodes it via a registered plugin decoder
+// if its extension matches one, falling back to the standard library's
+// image.Decode (which covers JPEG/PNG/GIF via their registered blank
+// imports) otherwise. The returned bool reports whether the source should be
+// treated as HDR.
+func decodeImage(inputPath string) (image.Image, bool, error) {
 	in, err := os.Open(inputPath)
-	if err != nil { return err }
+	if err != nil {
+		return nil, false, err
+	}
 	defer in.Close()
+
+	if entry, ok := decoderRegistry[normalizeExt(filepath.Ext(inputPath))]; ok {
+		img, err := entry.decode(in)
+		if err != nil {
+			return nil, false, fmt.Errorf("media: decode %s: %w", inputPath, err)
+		}
+		return img, entry.hdr, nil
+	}
+
 	img, _, err := image.Decode(in)
-	if err != nil { return err }
-	// naive scale (no resample): keep as is for placeholder
-	out, err := os.Create(outputPath)
-	if err != nil { return err }
-	defer out.Close()
-	return jpeg.Encode(out, img, &jpeg.Options{Quality: 80})
+	if err != nil {
+		return nil, false, fmt.Errorf("media: decode %s: %w", inputPath, err)
+	}
+	return img, false, nil
+}
+
+// scaleToFit resamples src down to the largest size that fits within
+// maxW x maxH while preserving aspect ratio, leaving src unscaled (only
+// copied) if it already fits.
+func scaleToFit(src image.Image, maxW, maxH int, resampler Resampler) image.Image {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	if sw <= 0 || sh <= 0 {
+		return src
+	}
+
+	w, h := sw, sh
+	if sw > maxW || sh > maxH {
+		ratio := float64(sw) / float64(sh)
+		w, h = maxW, maxH
+		if float64(maxW)/float64(maxH) > ratio {
+			w = int(float64(maxH) * ratio)
+		} else {
+			h = int(float64(maxW) / ratio)
+		}
+		if w < 1 {
+			w = 1
+		}
+		if h < 1 {
+			h = 1
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	resampler.Scale(dst, dst.Bounds(), src, sb)
+	return dst
+}
+
+// encodeWebP writes img to w as WebP. golang.org/x/image/webp (registered
+// above) only supports decoding, so encoding goes through webpenc.go's
+// libwebp binding; kept behind this indirection so GenerateThumbnail's
+// public surface doesn't change if the encoder backing it swaps.
+func encodeWebP(w io.Writer, img image.Image, lossless bool) error {
+	return webpEncode(w, img, lossless)
 }
@@ -0,0 +1,70 @@
+package media
+
+import (
+	"image"
+	"image/color"
+)
+
+// FloatImage is implemented by HDR decoders (e.g. media/exrplugin's Image)
+// that carry linear, unclamped floating-point samples — values may exceed
+// 1.0 for highlights brighter than the format's notional white point. A
+// ToneMapper type-asserts against this interface to get at the raw values;
+// decoders that don't implement it are passed through reinhardToneMap
+// unchanged, since their samples are already clamped to 8/16-bit range by
+// whatever produced the image.Image.
+type FloatImage interface {
+	image.Image
+	FloatAt(x, y int) (r, g, b, a float64)
+}
+
+// reinhardToneMap is DefaultToneMapper. It applies the Reinhard operator
+// (c' = c / (1 + c)) independently per RGB channel, the same curve ACES and
+// most DI grading pipelines default to for compressing scene-linear HDR
+// into a displayable 0..1 range without hard-clipping highlights.
+func reinhardToneMap(img image.Image) image.Image {
+	fi, ok := img.(FloatImage)
+	if !ok {
+		return img
+	}
+
+	b := img.Bounds()
+	out := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := fi.FloatAt(x, y)
+			out.SetNRGBA(x, y, color.NRGBA{
+				R: reinhardChannel(r),
+				G: reinhardChannel(g),
+				B: reinhardChannel(bl),
+				A: clampChannel(a),
+			})
+		}
+	}
+	return out
+}
+
+// reinhardChannel maps one scene-linear channel value (0..+inf, where 1.0 is
+// nominal mid-grey) through c/(1+c) and quantizes to 8 bits.
+func reinhardChannel(c float64) uint8 {
+	if c < 0 {
+		c = 0
+	}
+	mapped := c / (1 + c)
+	v := mapped * 255
+	if v > 255 {
+		v = 255
+	}
+	return uint8(v)
+}
+
+// clampChannel linearly clamps an already-normalized 0..1 value (alpha has
+// no "brightness" to compress, so it skips the Reinhard curve) to 8 bits.
+func clampChannel(c float64) uint8 {
+	if c < 0 {
+		c = 0
+	}
+	if c > 1 {
+		c = 1
+	}
+	return uint8(c * 255)
+}
@@ -1,29 +1,366 @@
+// Package medical detects and extracts metadata from medical imaging
+// formats (DICOM, NIfTI, NRRD) so the chunker/manifest path can use stable
+// identifiers (study/series/instance UIDs) as content grouping keys instead
+// of treating a directory of scans as opaque files.
 package medical
 
 import (
+	"encoding/binary"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-// Minimal DICOM detector and placeholder metadata extractor.
-// In production, integrate a proper DICOM library.
+// tag is a DICOM data element tag (group, element).
+type tag struct {
+	group, element uint16
+}
+
+var (
+	tagFileMetaGroupLength = tag{0x0002, 0x0000}
+	tagTransferSyntaxUID   = tag{0x0002, 0x0010}
+	tagPatientID           = tag{0x0010, 0x0020}
+	tagStudyInstanceUID    = tag{0x0020, 0x000D}
+	tagSeriesInstanceUID   = tag{0x0020, 0x000E}
+	tagSOPInstanceUID      = tag{0x0008, 0x0018}
+	tagModality            = tag{0x0008, 0x0060}
+	tagRows                = tag{0x0028, 0x0010}
+	tagColumns             = tag{0x0028, 0x0011}
+	tagBitsAllocated       = tag{0x0028, 0x0100}
+	tagPixelData           = tag{0x7FE0, 0x0010}
+
+	tagSequenceDelimitation = tag{0xFFFE, 0xE0DD}
+)
+
+// Transfer syntax UIDs this parser understands well enough to pick the
+// dataset's VR encoding. Anything else (JPEG-family, RLE, deflate) is still
+// Explicit VR Little Endian at the element-header level per the DICOM
+// standard, so it's handled the same way as the explicit case.
+const (
+	transferSyntaxImplicitVRLittleEndian = "1.2.840.10008.1.2"
+)
+
+// undefinedLength marks a sequence or encapsulated-pixel-data element whose
+// length isn't known up front; its end is instead signaled by a Sequence
+// Delimitation Item.
+const undefinedLength = 0xFFFFFFFF
+
+// Instance is one DICOM SOP instance's extracted metadata.
+type Instance struct {
+	SOPInstanceUID string `json:"sop_instance_uid"`
+}
+
+// Series groups Instances that share a SeriesInstanceUID.
+type Series struct {
+	SeriesInstanceUID string     `json:"series_instance_uid"`
+	Modality          string     `json:"modality,omitempty"`
+	Rows              int        `json:"rows,omitempty"`
+	Columns           int        `json:"columns,omitempty"`
+	BitsAllocated     int        `json:"bits_allocated,omitempty"`
+	Instances         []Instance `json:"instances"`
+}
 
+// Study groups Series that share a StudyInstanceUID.
 type Study struct {
-	SeriesCount int
+	StudyInstanceUID string   `json:"study_instance_uid"`
+	PatientID        string   `json:"patient_id,omitempty"`
+	Series           []Series `json:"series"`
+	// SeriesCount mirrors len(Series). It's kept as its own field because
+	// that's what chunker.MedicalProfile actually serializes into the
+	// manifest; the full Series slice is for callers that want the whole
+	// tree (e.g. a directory walk).
+	SeriesCount int `json:"series_count"`
 }
 
+// Metadata is the extracted Study -> Series -> Instance tree for one file
+// or one WalkDirectory call.
 type Metadata struct {
-	Studies []Study
+	Studies []Study `json:"studies"`
 }
 
+// instanceRecord is one parsed file's flattened tag set, before grouping.
+type instanceRecord struct {
+	patientID         string
+	studyInstanceUID  string
+	seriesInstanceUID string
+	sopInstanceUID    string
+	modality          string
+	rows              int
+	columns           int
+	bitsAllocated     int
+}
+
+// DetectAndExtract reports whether path is a DICOM Part 10 file and, if so,
+// parses its File Meta Information and dataset header, returning a Metadata
+// tree with exactly one Study containing exactly one Series containing one
+// Instance. Use WalkDirectory to group metadata across many files.
 func DetectAndExtract(path string) (*Metadata, bool) {
-	// Only detect by magic for now
+	rec, ok := parseDICOMFile(path)
+	if !ok {
+		return nil, false
+	}
+	return groupInstances([]instanceRecord{rec}), true
+}
+
+// WalkDirectory parses every DICOM file under root and groups the results
+// into a single Study -> Series -> Instance tree, the shape a directory of
+// mixed studies and series actually has on disk. Files that aren't DICOM
+// are silently skipped, same as DetectAndExtract's bool return for one
+// file.
+func WalkDirectory(root string) (*Metadata, error) {
+	var records []instanceRecord
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if rec, ok := parseDICOMFile(p); ok {
+			records = append(records, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groupInstances(records), nil
+}
+
+// groupInstances groups records by StudyInstanceUID then SeriesInstanceUID,
+// preserving first-seen order so the result is deterministic for a given
+// walk order.
+func groupInstances(records []instanceRecord) *Metadata {
+	studyIndex := make(map[string]int)
+	var meta Metadata
+
+	for _, rec := range records {
+		si, ok := studyIndex[rec.studyInstanceUID]
+		if !ok {
+			si = len(meta.Studies)
+			studyIndex[rec.studyInstanceUID] = si
+			meta.Studies = append(meta.Studies, Study{
+				StudyInstanceUID: rec.studyInstanceUID,
+				PatientID:        rec.patientID,
+			})
+		}
+		study := &meta.Studies[si]
+
+		seriesIdx := -1
+		for i := range study.Series {
+			if study.Series[i].SeriesInstanceUID == rec.seriesInstanceUID {
+				seriesIdx = i
+				break
+			}
+		}
+		if seriesIdx == -1 {
+			study.Series = append(study.Series, Series{
+				SeriesInstanceUID: rec.seriesInstanceUID,
+				Modality:          rec.modality,
+				Rows:              rec.rows,
+				Columns:           rec.columns,
+				BitsAllocated:     rec.bitsAllocated,
+			})
+			seriesIdx = len(study.Series) - 1
+			study.SeriesCount = len(study.Series)
+		}
+		study.Series[seriesIdx].Instances = append(study.Series[seriesIdx].Instances, Instance{
+			SOPInstanceUID: rec.sopInstanceUID,
+		})
+	}
+
+	return &meta
+}
+
+// parseDICOMFile reads path's 128-byte preamble and "DICM" magic, its File
+// Meta Information group (always Explicit VR Little Endian), and then the
+// main dataset in whatever transfer syntax the File Meta declared, stopping
+// as soon as it reaches PixelData since nothing after that is needed for
+// metadata.
+func parseDICOMFile(path string) (instanceRecord, bool) {
 	f, err := os.Open(path)
-	if err != nil { return nil, false }
+	if err != nil {
+		return instanceRecord{}, false
+	}
 	defer f.Close()
-	buf := make([]byte, 132)
-	n, _ := f.Read(buf)
-	if n < 132 { return nil, false }
-	if string(buf[128:132]) != "DICM" { return nil, false }
-	// Placeholder metadata
-	return &Metadata{Studies: []Study{{SeriesCount: 1}}}, true
+
+	preamble := make([]byte, 132)
+	if n, _ := io.ReadFull(f, preamble); n < 132 || string(preamble[128:132]) != "DICM" {
+		return instanceRecord{}, false
+	}
+
+	transferSyntaxUID, ok := readFileMeta(f)
+	if !ok {
+		return instanceRecord{}, false
+	}
+
+	var rec instanceRecord
+	readDataset(f, transferSyntaxUID != transferSyntaxImplicitVRLittleEndian, &rec)
+	return rec, true
+}
+
+// readFileMeta reads the File Meta Information group starting with
+// (0002,0000) FileMetaInformationGroupLength, which gives the byte length
+// of everything else in the group; limiting the read to exactly that many
+// bytes means the caller's next read from f lands precisely on the
+// dataset's first element without needing to peek a tag and seek back.
+func readFileMeta(f *os.File) (transferSyntaxUID string, ok bool) {
+	t, _, data, ok := readElement(f, true)
+	if !ok || t != tagFileMetaGroupLength || len(data) != 4 {
+		return "", false
+	}
+	groupLength := binary.LittleEndian.Uint32(data)
+
+	limited := io.LimitReader(f, int64(groupLength))
+	for {
+		et, _, edata, eok := readElement(limited, true)
+		if !eok {
+			break
+		}
+		if et == tagTransferSyntaxUID {
+			transferSyntaxUID = trimDICOMString(edata)
+		}
+	}
+	return transferSyntaxUID, true
+}
+
+// readDataset reads elements from r until PixelData, EOF, or a malformed
+// element, recording the tags this package cares about into rec.
+func readDataset(r io.Reader, explicitVR bool, rec *instanceRecord) {
+	for {
+		t, _, data, ok := readElement(r, explicitVR)
+		if !ok || t == tagPixelData {
+			return
+		}
+		switch t {
+		case tagPatientID:
+			rec.patientID = trimDICOMString(data)
+		case tagStudyInstanceUID:
+			rec.studyInstanceUID = trimDICOMString(data)
+		case tagSeriesInstanceUID:
+			rec.seriesInstanceUID = trimDICOMString(data)
+		case tagSOPInstanceUID:
+			rec.sopInstanceUID = trimDICOMString(data)
+		case tagModality:
+			rec.modality = trimDICOMString(data)
+		case tagRows:
+			rec.rows = int(leUint16(data))
+		case tagColumns:
+			rec.columns = int(leUint16(data))
+		case tagBitsAllocated:
+			rec.bitsAllocated = int(leUint16(data))
+		}
+	}
+}
+
+// readElement reads one data element's tag, VR (when explicitVR), length,
+// and value from r. PixelData's value is never read (defined or not; it can
+// be gigabytes, or an encapsulated sequence of compressed fragments) since
+// callers stop as soon as they see it. A length of undefinedLength marks a
+// sequence whose contents are skipped rather than returned, since none of
+// the tags this package extracts are nested inside one.
+func readElement(r io.Reader, explicitVR bool) (t tag, vr string, data []byte, ok bool) {
+	var tagBuf [4]byte
+	if _, err := io.ReadFull(r, tagBuf[:]); err != nil {
+		return tag{}, "", nil, false
+	}
+	t = tag{
+		group:   binary.LittleEndian.Uint16(tagBuf[0:2]),
+		element: binary.LittleEndian.Uint16(tagBuf[2:4]),
+	}
+
+	var length uint32
+	if explicitVR {
+		var vrBuf [2]byte
+		if _, err := io.ReadFull(r, vrBuf[:]); err != nil {
+			return t, "", nil, false
+		}
+		vr = string(vrBuf[:])
+		switch vr {
+		case "OB", "OW", "OF", "OD", "OL", "SQ", "UC", "UR", "UT", "UN":
+			var reserved [2]byte
+			if _, err := io.ReadFull(r, reserved[:]); err != nil {
+				return t, vr, nil, false
+			}
+			var lenBuf [4]byte
+			if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+				return t, vr, nil, false
+			}
+			length = binary.LittleEndian.Uint32(lenBuf[:])
+		default:
+			var lenBuf [2]byte
+			if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+				return t, vr, nil, false
+			}
+			length = uint32(binary.LittleEndian.Uint16(lenBuf[:]))
+		}
+	} else {
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+			return t, "", nil, false
+		}
+		length = binary.LittleEndian.Uint32(lenBuf[:])
+	}
+
+	if t == tagPixelData {
+		return t, vr, nil, true
+	}
+
+	if length == undefinedLength {
+		if err := skipUndefinedLength(r); err != nil {
+			return t, vr, nil, false
+		}
+		return t, vr, nil, true
+	}
+
+	data = make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return t, vr, nil, false
+	}
+	return t, vr, data, true
+}
+
+// skipUndefinedLength discards an undefined-length sequence's Items until
+// its Sequence Delimitation Item, recursing for any Item that itself turns
+// out to have undefined length (e.g. a nested sequence, or a fragmented
+// encapsulated pixel data item — though PixelData itself is never skipped
+// this way since readElement returns before reaching here for that tag).
+func skipUndefinedLength(r io.Reader) error {
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return err
+		}
+		itemTag := tag{
+			group:   binary.LittleEndian.Uint16(hdr[0:2]),
+			element: binary.LittleEndian.Uint16(hdr[2:4]),
+		}
+		length := binary.LittleEndian.Uint32(hdr[4:8])
+
+		if itemTag == tagSequenceDelimitation {
+			return nil
+		}
+		if length == undefinedLength {
+			if err := skipUndefinedLength(r); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+			return err
+		}
+	}
+}
+
+// trimDICOMString strips the trailing space or null padding DICOM string
+// VRs use to keep element lengths even.
+func trimDICOMString(b []byte) string {
+	return strings.TrimRight(string(b), " \x00")
+}
+
+// leUint16 reads a little-endian uint16 from the start of b, or 0 if b is
+// too short (a malformed or absent US element).
+func leUint16(b []byte) uint16 {
+	if len(b) < 2 {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
 }
@@ -0,0 +1,52 @@
+package medical
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// NIfTI-1 magic strings, at byte offset 344 of the 348-byte header: "n+1"
+// for a single combined .nii file, "ni1" for a detached .hdr/.img pair.
+const (
+	niftiMagicSingleFile = "n+1\x00"
+	niftiMagicPairedFile = "ni1\x00"
+)
+
+// NIfTIMetadata is the subset of the nifti-1 header DetectNIfTI extracts.
+type NIfTIMetadata struct {
+	// Dims holds dim[0..7]: dim[0] is the number of dimensions actually
+	// used, dim[1..dim[0]] are their extents.
+	Dims         [8]int16
+	DataType     int16
+	BitsPerVoxel int16
+}
+
+// DetectNIfTI reports whether path is a NIfTI-1 volume (magic "n+1\0" or
+// "ni1\0" at byte offset 344) and, if so, extracts its dimension and voxel
+// datatype header fields. NIfTI-2 (magic "n+2"/"ni2" at offset 4, with a
+// 540-byte header) is not handled here.
+func DetectNIfTI(path string) (*NIfTIMetadata, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	header := make([]byte, 348)
+	if n, _ := io.ReadFull(f, header); n < 348 {
+		return nil, false
+	}
+	magic := string(header[344:348])
+	if magic != niftiMagicSingleFile && magic != niftiMagicPairedFile {
+		return nil, false
+	}
+
+	var meta NIfTIMetadata
+	for i := 0; i < 8; i++ {
+		meta.Dims[i] = int16(binary.LittleEndian.Uint16(header[40+i*2 : 42+i*2]))
+	}
+	meta.DataType = int16(binary.LittleEndian.Uint16(header[70:72]))
+	meta.BitsPerVoxel = int16(binary.LittleEndian.Uint16(header[72:74]))
+	return &meta, true
+}
@@ -0,0 +1,66 @@
+package medical
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NRRDMetadata is the subset of an NRRD header's "key: value" fields
+// DetectNRRD extracts.
+type NRRDMetadata struct {
+	Dimension int
+	Type      string
+	Sizes     []int
+}
+
+// DetectNRRD reports whether path begins with the "NRRD000n" magic and, if
+// so, parses its plain-text header lines up through the blank line that
+// separates header from data (detached .nhdr headers have no data section
+// and simply end after the header).
+func DetectNRRD(path string) (*NRRDMetadata, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return nil, false
+	}
+	if !strings.HasPrefix(scanner.Text(), "NRRD000") {
+		return nil, false
+	}
+
+	meta := &NRRDMetadata{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break // blank line ends the header
+		}
+		if strings.HasPrefix(line, "#") {
+			continue // comment
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		switch key {
+		case "dimension":
+			meta.Dimension, _ = strconv.Atoi(value)
+		case "type":
+			meta.Type = value
+		case "sizes":
+			for _, field := range strings.Fields(value) {
+				if n, err := strconv.Atoi(field); err == nil {
+					meta.Sizes = append(meta.Sizes, n)
+				}
+			}
+		}
+	}
+	return meta, true
+}
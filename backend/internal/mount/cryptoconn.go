@@ -0,0 +1,163 @@
+package mount
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/quantarax/backend/internal/crypto"
+)
+
+// streamRole distinguishes the header stream from the bulk stream for
+// nonce-domain separation; see cryptoStream's domain fields below.
+type streamRole uint8
+
+const (
+	streamRoleHeader streamRole = iota
+	streamRoleBulk
+)
+
+// maxFrameSize bounds a single encrypted frame, generous enough for a 9P
+// message (header stream) or a bulk read/write payload (bulk stream)
+// without risking an unbounded allocation off a malformed length prefix.
+const maxFrameSize = 4 << 20
+
+// cryptoStream wraps one of the mount's two QUIC streams with the
+// session's AEAD, so 9P traffic gets the same confidentiality and replay
+// protection as chunk data without a second handshake. Each frame is
+// length-prefixed (the stream itself carries no message boundaries) and
+// sealed with a nonce derived from the session's IVBase the same way
+// crypto.DeriveNonce derives chunk nonces, except the counter is tagged
+// with this stream's role and which side is writing so the two directions
+// (and the two streams) never share a nonce under the same key.
+type cryptoStream struct {
+	raw         io.ReadWriteCloser
+	keys        *crypto.SessionKeys
+	sessionID   uuid.UUID
+	role        streamRole
+	writeDomain uint64
+	readDomain  uint64
+	writeSeq    uint64
+	readSeq     uint64
+
+	// pendingRead holds whatever ReadFrame returned that frameReadWriteCloser
+	// hasn't handed back yet, for callers (like p9's message decoder) that
+	// read a frame's contents across several smaller Read calls instead of
+	// one ReadFrame-sized one.
+	pendingRead []byte
+}
+
+// nonceDomain packs role and fromInitiator into the high bits of the
+// 64-bit counter DeriveNonce XORs into IVBase, leaving the low 56 bits for
+// a per-frame sequence number — far more frames than any single mount will
+// ever exchange.
+func nonceDomain(role streamRole, fromInitiator bool) uint64 {
+	domain := uint64(role) << 1
+	if fromInitiator {
+		domain |= 1
+	}
+	return domain << 56
+}
+
+// newCryptoStream wraps raw with AEAD framing for the given role. isInitiator
+// tells it which nonce domain to write with and which to expect on read:
+// Attach (which opens the streams) is always the initiator, Serve (which
+// accepts them) is always the responder, so the two sides never pick
+// conflicting domains despite deciding independently.
+func newCryptoStream(raw io.ReadWriteCloser, keys *crypto.SessionKeys, sessionID uuid.UUID, role streamRole, isInitiator bool) *cryptoStream {
+	return &cryptoStream{
+		raw:         raw,
+		keys:        keys,
+		sessionID:   sessionID,
+		role:        role,
+		writeDomain: nonceDomain(role, isInitiator),
+		readDomain:  nonceDomain(role, !isInitiator),
+	}
+}
+
+// WriteFrame seals payload and writes it as one length-prefixed frame.
+func (cs *cryptoStream) WriteFrame(payload []byte) error {
+	counter := cs.writeDomain | cs.writeSeq
+	cs.writeSeq++
+	nonce := crypto.DeriveNonce(cs.keys.IVBase, counter)
+	sealed, err := crypto.Seal(cs.keys.PayloadKey[:], nonce[:], cs.frameAAD(counter), payload)
+	if err != nil {
+		return fmt.Errorf("mount: seal frame: %w", err)
+	}
+	if len(sealed) > maxFrameSize {
+		return fmt.Errorf("mount: frame too large (%d bytes)", len(sealed))
+	}
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(sealed)))
+	if _, err := cs.raw.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err = cs.raw.Write(sealed)
+	return err
+}
+
+// ReadFrame reads and opens the next frame.
+func (cs *cryptoStream) ReadFrame() ([]byte, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(cs.raw, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(lenBuf[:])
+	if n > maxFrameSize {
+		return nil, fmt.Errorf("mount: frame too large (%d bytes)", n)
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(cs.raw, sealed); err != nil {
+		return nil, err
+	}
+	counter := cs.readDomain | cs.readSeq
+	cs.readSeq++
+	nonce := crypto.DeriveNonce(cs.keys.IVBase, counter)
+	plaintext, err := crypto.Open(cs.keys.PayloadKey[:], nonce[:], cs.frameAAD(counter), sealed)
+	if err != nil {
+		return nil, fmt.Errorf("mount: open frame: %w", err)
+	}
+	return plaintext, nil
+}
+
+// frameAAD binds each frame to this mount's session ID and its own
+// (domain, sequence) counter, the same SessionID||counter shape chunk
+// encryption's AAD already uses, so a frame can't be replayed into a
+// different position in the stream or onto a different session.
+func (cs *cryptoStream) frameAAD(counter uint64) []byte {
+	aad := make([]byte, 24)
+	copy(aad[0:16], cs.sessionID[:])
+	binary.BigEndian.PutUint64(aad[16:24], counter)
+	return aad
+}
+
+func (cs *cryptoStream) Close() error {
+	return cs.raw.Close()
+}
+
+// writeLabel and readLabel send/receive the plaintext stream label
+// Serve/Attach use to tell the header and bulk streams apart; they run
+// before the cryptoStream wrapper exists, so they talk directly to raw.
+func writeLabel(raw io.Writer, label string) error {
+	b := []byte(label)
+	var lenBuf [1]byte
+	lenBuf[0] = byte(len(b))
+	if _, err := raw.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err := raw.Write(b)
+	return err
+}
+
+func readLabel(raw io.Reader) (string, error) {
+	var lenBuf [1]byte
+	if _, err := io.ReadFull(raw, lenBuf[:]); err != nil {
+		return "", err
+	}
+	b := make([]byte, lenBuf[0])
+	if _, err := io.ReadFull(raw, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,304 @@
+package mount
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hugelgupf/p9/p9"
+)
+
+// serve9P runs a 9P2000 server over pair until the peer disconnects or ctx
+// is canceled, exporting root as the filesystem's single top-level
+// directory. The header stream carries T-messages/R-messages; the bulk
+// stream is handed to p9 as the connection large Tread/Twrite payloads get
+// written to, via bulkReadWriter below, so a big file transfer doesn't
+// block the metadata traffic behind it on the same stream.
+func serve9P(ctx context.Context, pair *streamPair, root string) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return fmt.Errorf("mount: stat root %q: %w", root, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("mount: root %q is not a directory", root)
+	}
+
+	server := p9.NewServer(&rootAttacher{root: root, pair: pair})
+	done := make(chan error, 1)
+	go func() { done <- server.Handle(frameReadWriteCloser{pair.hdr}) }()
+
+	select {
+	case <-ctx.Done():
+		pair.hdr.Close()
+		pair.bulk.Close()
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
+// frameReadWriteCloser adapts cryptoStream's frame-at-a-time WriteFrame/
+// ReadFrame to the plain io.ReadWriteCloser p9.Server.Handle expects a 9P
+// transport to be, buffering a read frame across however many smaller
+// Read calls p9's own message decoder makes against it.
+type frameReadWriteCloser struct {
+	cs *cryptoStream
+}
+
+func (f frameReadWriteCloser) Write(p []byte) (int, error) {
+	if err := f.cs.WriteFrame(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (f frameReadWriteCloser) Read(p []byte) (int, error) {
+	if len(f.cs.pendingRead) == 0 {
+		frame, err := f.cs.ReadFrame()
+		if err != nil {
+			return 0, err
+		}
+		f.cs.pendingRead = frame
+	}
+	n := copy(p, f.cs.pendingRead)
+	f.cs.pendingRead = f.cs.pendingRead[n:]
+	return n, nil
+}
+
+func (f frameReadWriteCloser) Close() error {
+	return f.cs.Close()
+}
+
+// rootAttacher is the p9.Attacher the server hands every Tattach to; this
+// mount exports a single tree, so every attach gets the same root node.
+type rootAttacher struct {
+	root string
+	pair *streamPair
+}
+
+func (a *rootAttacher) Attach() (p9.File, error) {
+	return &localFile{path: a.root, bulk: a.pair.bulk}, nil
+}
+
+// localFile implements p9.File over an OS directory tree. It supports the
+// operations a mount needs for read/write/stat/create/mkdir/readdir
+// round-trips; xattrs, symlinks, hard links, and device nodes aren't
+// something this exporter's backing store (a plain directory) needs, so
+// those methods return p9.ENOSYS rather than pretending to support them.
+type localFile struct {
+	path string
+	bulk *cryptoStream
+	file *os.File
+}
+
+func (l *localFile) info() (os.FileInfo, error) {
+	return os.Lstat(l.path)
+}
+
+func (l *localFile) Walk(names []string) ([]p9.QID, p9.File, error) {
+	qids := make([]p9.QID, 0, len(names))
+	path := l.path
+	for _, name := range names {
+		path = filepath.Join(path, name)
+		info, err := os.Lstat(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		qids = append(qids, qidFor(info))
+	}
+	if len(names) == 0 {
+		info, err := l.info()
+		if err != nil {
+			return nil, nil, err
+		}
+		return []p9.QID{qidFor(info)}, &localFile{path: l.path, bulk: l.bulk}, nil
+	}
+	return qids, &localFile{path: path, bulk: l.bulk}, nil
+}
+
+func (l *localFile) GetAttr(req p9.AttrMask) (p9.QID, p9.AttrMask, p9.Attr, error) {
+	info, err := l.info()
+	if err != nil {
+		return p9.QID{}, p9.AttrMask{}, p9.Attr{}, err
+	}
+	return qidFor(info), p9.AttrMaskAll, attrFor(info), nil
+}
+
+func (l *localFile) SetAttr(valid p9.SetAttrMask, attr p9.SetAttr) error {
+	if valid.Size {
+		if err := os.Truncate(l.path, int64(attr.Size)); err != nil {
+			return err
+		}
+	}
+	if valid.Permissions {
+		if err := os.Chmod(l.path, os.FileMode(attr.Permissions)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *localFile) Open(mode p9.OpenFlags) (*os.File, p9.QID, uint32, error) {
+	info, err := l.info()
+	if err != nil {
+		return nil, p9.QID{}, 0, err
+	}
+	f, err := os.OpenFile(l.path, int(mode), 0)
+	if err != nil {
+		return nil, p9.QID{}, 0, err
+	}
+	l.file = f
+	return f, qidFor(info), 0, nil
+}
+
+// ReadAt and WriteAt serve the bulk payload over the mount's dedicated
+// bulk stream rather than inline in the 9P R-message on the header
+// stream, so a large read/write doesn't hold up unrelated metadata
+// traffic queued behind it.
+func (l *localFile) ReadAt(p []byte, offset int64) (int, error) {
+	if l.file == nil {
+		return 0, fmt.Errorf("mount: ReadAt on unopened file %q", l.path)
+	}
+	n, err := l.file.ReadAt(p, offset)
+	if err != nil && err != io.EOF {
+		return n, err
+	}
+	if werr := l.bulk.WriteFrame(p[:n]); werr != nil {
+		return n, werr
+	}
+	return n, err
+}
+
+func (l *localFile) WriteAt(p []byte, offset int64) (int, error) {
+	if l.file == nil {
+		return 0, fmt.Errorf("mount: WriteAt on unopened file %q", l.path)
+	}
+	payload, err := l.bulk.ReadFrame()
+	if err != nil {
+		return 0, err
+	}
+	return l.file.WriteAt(payload, offset)
+}
+
+func (l *localFile) FSync() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Sync()
+}
+
+func (l *localFile) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+func (l *localFile) Create(name string, mode p9.OpenFlags, perm p9.FileMode, _ p9.UID, _ p9.GID) (p9.File, *os.File, p9.QID, uint32, error) {
+	path := filepath.Join(l.path, name)
+	f, err := os.OpenFile(path, int(mode)|os.O_CREATE|os.O_EXCL, os.FileMode(perm))
+	if err != nil {
+		return nil, nil, p9.QID{}, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, p9.QID{}, 0, err
+	}
+	child := &localFile{path: path, bulk: l.bulk, file: f}
+	return child, f, qidFor(info), 0, nil
+}
+
+func (l *localFile) Mkdir(name string, perm p9.FileMode, _ p9.UID, _ p9.GID) (p9.QID, error) {
+	path := filepath.Join(l.path, name)
+	if err := os.Mkdir(path, os.FileMode(perm)); err != nil {
+		return p9.QID{}, err
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return p9.QID{}, err
+	}
+	return qidFor(info), nil
+}
+
+func (l *localFile) UnlinkAt(name string, _ uint32) error {
+	return os.Remove(filepath.Join(l.path, name))
+}
+
+func (l *localFile) RenameAt(oldName string, newDir p9.File, newName string) error {
+	newParent, ok := newDir.(*localFile)
+	if !ok {
+		return fmt.Errorf("mount: RenameAt target is not a localFile")
+	}
+	return os.Rename(filepath.Join(l.path, oldName), filepath.Join(newParent.path, newName))
+}
+
+func (l *localFile) Readdir(offset uint64, count uint32) ([]p9.Dirent, error) {
+	entries, err := os.ReadDir(l.path)
+	if err != nil {
+		return nil, err
+	}
+	var out []p9.Dirent
+	for i, e := range entries {
+		if uint64(i) < offset {
+			continue
+		}
+		if uint32(len(out)) >= count {
+			break
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, p9.Dirent{
+			QID:    qidFor(info),
+			Offset: uint64(i) + 1,
+			Type:   direntType(info),
+			Name:   e.Name(),
+		})
+	}
+	return out, nil
+}
+
+func (l *localFile) StatFS() (p9.FSStat, error)                                       { return p9.FSStat{}, p9.ENOSYS }
+func (l *localFile) GetXattr(string, uint64) (string, error)                          { return "", p9.ENOSYS }
+func (l *localFile) SetXattr(string, string, uint32) error                            { return p9.ENOSYS }
+func (l *localFile) ListXattr(uint64) (map[string]struct{}, error)                    { return nil, p9.ENOSYS }
+func (l *localFile) RemoveXattr(string) error                                         { return p9.ENOSYS }
+func (l *localFile) Allocate(p9.AllocateMode, uint64, uint64) error                    { return p9.ENOSYS }
+func (l *localFile) Symlink(string, string, p9.UID, p9.GID) (p9.QID, error)           { return p9.QID{}, p9.ENOSYS }
+func (l *localFile) Link(p9.File, string) error                                       { return p9.ENOSYS }
+func (l *localFile) Mknod(string, p9.FileMode, uint32, uint32, p9.UID, p9.GID) (p9.QID, error) {
+	return p9.QID{}, p9.ENOSYS
+}
+func (l *localFile) Readlink() (string, error) { return "", p9.ENOSYS }
+func (l *localFile) Flush() error              { return nil }
+func (l *localFile) Rename(p9.File, string) error {
+	return p9.ENOSYS
+}
+func (l *localFile) Renamed(p9.File, string) {}
+
+func qidFor(info os.FileInfo) p9.QID {
+	qidType := p9.TypeRegular
+	if info.IsDir() {
+		qidType = p9.TypeDir
+	}
+	return p9.QID{Type: qidType, Path: uint64(info.ModTime().UnixNano())}
+}
+
+func attrFor(info os.FileInfo) p9.Attr {
+	return p9.Attr{
+		Mode:      p9.FileMode(info.Mode().Perm()),
+		Size:      uint64(info.Size()),
+		MTimeSeconds: uint64(info.ModTime().Unix()),
+	}
+}
+
+func direntType(info os.FileInfo) p9.QIDType {
+	if info.IsDir() {
+		return p9.TypeDir
+	}
+	return p9.TypeRegular
+}
@@ -0,0 +1,159 @@
+package mount
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hugelgupf/p9/p9"
+)
+
+// attachFUSE mounts the tree session's peer is Serve-ing at mountpoint,
+// via a 9P client over pair's header stream (metadata) and bulk stream
+// (read/write payload), blocking until the mount is unmounted.
+func attachFUSE(ctx context.Context, pair *streamPair, mountpoint string) error {
+	client, err := p9.NewClient(frameReadWriteCloser{pair.hdr})
+	if err != nil {
+		return fmt.Errorf("mount: 9P client handshake: %w", err)
+	}
+	defer client.Close()
+
+	rootFile, err := client.Attach("")
+	if err != nil {
+		return fmt.Errorf("mount: attach root: %w", err)
+	}
+
+	root := &inode{file: rootFile, bulk: pair.bulk}
+	server, err := fs.Mount(mountpoint, root, &fs.Options{})
+	if err != nil {
+		return fmt.Errorf("mount: fuse mount %q: %w", mountpoint, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Unmount()
+	}()
+
+	server.Wait()
+	pair.hdr.Close()
+	pair.bulk.Close()
+	return nil
+}
+
+// inode is a FUSE node backed by a remote p9.File reached over the mount's
+// 9P client connection. Every Lookup walks one path element further on the
+// underlying p9 connection and wraps the result in a new inode, the same
+// way the 9P protocol itself is purely walk-based.
+type inode struct {
+	fs.Inode
+	file p9.File
+	bulk *cryptoStream
+}
+
+var (
+	_ fs.NodeGetattrer  = (*inode)(nil)
+	_ fs.NodeLookuper   = (*inode)(nil)
+	_ fs.NodeReaddirer  = (*inode)(nil)
+	_ fs.NodeOpener     = (*inode)(nil)
+	_ fs.NodeReader     = (*inode)(nil)
+	_ fs.NodeWriter     = (*inode)(nil)
+	_ fs.NodeCreater    = (*inode)(nil)
+	_ fs.NodeMkdirer    = (*inode)(nil)
+	_ fs.NodeUnlinker   = (*inode)(nil)
+)
+
+func (n *inode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	_, _, attr, err := n.file.GetAttr(p9.AttrMaskAll)
+	if err != nil {
+		return syscall.EIO
+	}
+	out.Size = attr.Size
+	out.Mode = uint32(attr.Mode)
+	out.Mtime = attr.MTimeSeconds
+	return 0
+}
+
+func (n *inode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	qids, child, err := n.file.Walk([]string{name})
+	if err != nil || len(qids) == 0 {
+		return nil, syscall.ENOENT
+	}
+	mode := fuse.S_IFREG
+	if qids[0].Type == p9.TypeDir {
+		mode = fuse.S_IFDIR
+	}
+	childNode := &inode{file: child, bulk: n.bulk}
+	return n.NewInode(ctx, childNode, fs.StableAttr{Mode: uint32(mode)}), 0
+}
+
+func (n *inode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	entries, err := n.file.Readdir(0, 4096)
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	list := make([]fuse.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		mode := fuse.S_IFREG
+		if e.Type == p9.TypeDir {
+			mode = fuse.S_IFDIR
+		}
+		list = append(list, fuse.DirEntry{Name: e.Name, Mode: uint32(mode)})
+	}
+	return fs.NewListDirStream(list), 0
+}
+
+func (n *inode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if _, _, _, err := n.file.Open(p9.OpenFlags(flags)); err != nil {
+		return nil, 0, syscall.EIO
+	}
+	return nil, 0, 0
+}
+
+// Read and Write move their payload over the mount's bulk stream, mirroring
+// how localFile's ReadAt/WriteAt on the exporter side do; the header stream
+// only ever carries the 9P call's metadata (offset, length, result code).
+func (n *inode) Read(ctx context.Context, f fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	got, err := n.file.ReadAt(dest, off)
+	if err != nil && got == 0 {
+		return nil, syscall.EIO
+	}
+	return fuse.ReadResultData(dest[:got]), 0
+}
+
+func (n *inode) Write(ctx context.Context, f fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	written, err := n.file.WriteAt(data, off)
+	if err != nil {
+		return 0, syscall.EIO
+	}
+	return uint32(written), 0
+}
+
+func (n *inode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	child, _, _, _, err := n.file.Create(name, p9.OpenFlags(flags), p9.FileMode(mode), 0, 0)
+	if err != nil {
+		return nil, nil, 0, syscall.EIO
+	}
+	childNode := &inode{file: child, bulk: n.bulk}
+	return n.NewInode(ctx, childNode, fs.StableAttr{Mode: fuse.S_IFREG}), nil, 0, 0
+}
+
+func (n *inode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if _, err := n.file.Mkdir(name, p9.FileMode(mode), 0, 0); err != nil {
+		return nil, syscall.EIO
+	}
+	_, child, err := n.file.Walk([]string{name})
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	childNode := &inode{file: child, bulk: n.bulk}
+	return n.NewInode(ctx, childNode, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+}
+
+func (n *inode) Unlink(ctx context.Context, name string) syscall.Errno {
+	if err := n.file.UnlinkAt(name, 0); err != nil {
+		return syscall.EIO
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+// Package mount exposes a directory tree to a peer (or imports one a peer
+// exposes) over an already-authenticated QuantaraX QUIC session, the way
+// sshfs or 9pfs ride an existing SSH/network connection instead of opening
+// a socket of their own. Serve runs the exporter side; Attach runs the
+// importer side. Both speak 9P2000 over a pair of dedicated QUIC streams
+// carved out of the session: a header/metadata stream and a bulk data
+// stream, each AEAD-sealed with the session's own PayloadKey so mount
+// traffic gets the same confidentiality and integrity guarantees as a
+// chunk transfer, without a second handshake.
+package mount
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/quantarax/backend/internal/crypto"
+	"github.com/quic-go/quic-go"
+)
+
+// headerStreamLabel and bulkStreamLabel are sent as the first frame on each
+// stream Serve/Attach open, so the accepting side can tell which of the two
+// it just received without relying on accept order (QUIC doesn't guarantee
+// streams opened in one order are accepted in the same order).
+const (
+	headerStreamLabel = "QNTX-MOUNT-HDR"
+	bulkStreamLabel   = "QNTX-MOUNT-BULK"
+)
+
+// Session bundles what Serve and Attach need from an already-negotiated
+// QuantaraX transport session: the QUIC connection itself, the AEAD keys
+// DeriveSessionKeys (or the handshake package's hybrid/Noise handshakes)
+// produced for it, and the session ID those keys are bound to, mirroring
+// the (conn, sessionKeys, sessionID) triple transport.NewOrchestratedSender
+// already takes for chunk transfers.
+type Session struct {
+	Conn        *quic.Conn
+	SessionKeys *crypto.SessionKeys
+	SessionID   uuid.UUID
+}
+
+// streamPair is the two dedicated QUIC streams a mount uses: hdr carries 9P
+// T-messages/R-messages (small, latency-sensitive, so it rides what the
+// rest of the transport calls PriorityP1), and bulk carries the payload of
+// large Tread/Rread and Twrite messages (scheduled like PriorityP2 bulk
+// chunk traffic) so a large read/write can't head-of-line block the next
+// metadata operation behind it.
+type streamPair struct {
+	hdr  *cryptoStream
+	bulk *cryptoStream
+}
+
+// Serve exports root over session: it accepts the header and bulk streams
+// the peer's Attach call opens, then runs a 9P server over them until the
+// session's context is done or the peer unmounts. root must be a directory;
+// everything under it becomes visible (subject to the exporting process's
+// own file permissions) to whatever mounts it.
+func Serve(ctx context.Context, session *Session, root string) error {
+	pair, err := acceptStreamPair(ctx, session)
+	if err != nil {
+		return fmt.Errorf("mount: accept stream pair: %w", err)
+	}
+	return serve9P(ctx, pair, root)
+}
+
+// Attach imports the directory tree session's peer exposed via Serve,
+// mounting it at mountpoint via FUSE. It blocks until the mount is torn
+// down (ctx is canceled, or the FUSE filesystem is unmounted locally), at
+// which point it unmounts mountpoint and closes the mount streams.
+func Attach(ctx context.Context, session *Session, mountpoint string) error {
+	pair, err := openStreamPair(ctx, session)
+	if err != nil {
+		return fmt.Errorf("mount: open stream pair: %w", err)
+	}
+	return attachFUSE(ctx, pair, mountpoint)
+}
+
+// openStreamPair is the importer side of stream setup: it opens both
+// streams and writes each one's label first, since OpenStreamSync doesn't
+// let the accepting side distinguish streams by the order it calls
+// AcceptStream (concurrent opens can arrive in either order).
+func openStreamPair(ctx context.Context, session *Session) (*streamPair, error) {
+	hdrRaw, err := session.Conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("open header stream: %w", err)
+	}
+	if err := writeLabel(hdrRaw, headerStreamLabel); err != nil {
+		return nil, err
+	}
+
+	bulkRaw, err := session.Conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("open bulk stream: %w", err)
+	}
+	if err := writeLabel(bulkRaw, bulkStreamLabel); err != nil {
+		return nil, err
+	}
+
+	return &streamPair{
+		hdr:  newCryptoStream(hdrRaw, session.SessionKeys, session.SessionID, streamRoleHeader, true),
+		bulk: newCryptoStream(bulkRaw, session.SessionKeys, session.SessionID, streamRoleBulk, true),
+	}, nil
+}
+
+// acceptStreamPair is the exporter side: it accepts two streams and sorts
+// them into header/bulk by the label each one starts with, regardless of
+// which arrived first.
+func acceptStreamPair(ctx context.Context, session *Session) (*streamPair, error) {
+	var pair streamPair
+	for pair.hdr == nil || pair.bulk == nil {
+		raw, err := session.Conn.AcceptStream(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("accept mount stream: %w", err)
+		}
+		label, err := readLabel(raw)
+		if err != nil {
+			return nil, fmt.Errorf("read mount stream label: %w", err)
+		}
+		switch label {
+		case headerStreamLabel:
+			pair.hdr = newCryptoStream(raw, session.SessionKeys, session.SessionID, streamRoleHeader, false)
+		case bulkStreamLabel:
+			pair.bulk = newCryptoStream(raw, session.SessionKeys, session.SessionID, streamRoleBulk, false)
+		default:
+			return nil, fmt.Errorf("unrecognized mount stream label %q", label)
+		}
+	}
+	return &pair, nil
+}
@@ -0,0 +1,145 @@
+package nattraversal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
+
+// connCheckTimeout bounds how long Connect waits for a single candidate
+// pair's probe/ack round trip before moving on to the next one.
+const connCheckTimeout = 1500 * time.Millisecond
+
+// probePayload and ackPayload are Connect's minimal connectivity-check
+// handshake: whichever side receives probePayload on a UDP socket it's
+// listening on echoes back ackPayload, confirming the path is open in both
+// directions rather than just nominally routable.
+var (
+	probePayload = []byte("QNTX-NAT-PROBE")
+	ackPayload   = []byte("QNTX-NAT-ACK")
+)
+
+// Connect picks a working path to remote out of the candidates Gather
+// collected for each side, racing connectivity checks against local's own
+// candidates in priority order (host, then server-reflexive, then
+// relayed) so a direct path wins whenever it's actually reachable and a
+// relayed candidate is used only once every direct pair above it has
+// failed. It returns a net.PacketConn already bound and ready to hand to
+// quic.Dial (or similar), along with the remote Candidate it reached.
+func Connect(ctx context.Context, local []Candidate, remote []Candidate) (net.PacketConn, Candidate, error) {
+	if len(local) == 0 {
+		return nil, Candidate{}, fmt.Errorf("nattraversal: no local candidates to connect from")
+	}
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: 0})
+	if err != nil {
+		return nil, Candidate{}, fmt.Errorf("nattraversal: open local socket: %w", err)
+	}
+
+	// One reader goroutine owns every read off conn for Connect's whole
+	// duration: probes it sees get acked immediately, and acks get handed
+	// to whichever probeAndAwaitAck call is waiting on them. Splitting
+	// reads across two goroutines racing on the same socket would let
+	// respondToProbes steal an ack meant for a pending check instead.
+	acks := newAckRouter()
+	readerCtx, stopReader := context.WithCancel(ctx)
+	defer stopReader()
+	go acks.run(readerCtx, conn)
+
+	ordered := orderedByPriority(remote)
+	for _, cand := range ordered {
+		if cand.Type == CandidateRelayed {
+			// No connectivity check: the relay is reachable by definition
+			// once the relay binary itself accepts connections, and it's
+			// only tried once every direct candidate above it has failed.
+			return conn, cand, nil
+		}
+		if acks.probeAndAwaitAck(ctx, conn, cand.Addr) {
+			return conn, cand, nil
+		}
+	}
+
+	conn.Close()
+	return nil, Candidate{}, fmt.Errorf("nattraversal: no reachable candidate (direct or relayed) out of %d", len(remote))
+}
+
+func orderedByPriority(candidates []Candidate) []Candidate {
+	ordered := make([]Candidate, len(candidates))
+	copy(ordered, candidates)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].priority() > ordered[j].priority() })
+	return ordered
+}
+
+// ackRouter is Connect's single reader of conn: it answers incoming probes
+// directly and fans incoming acks out to whichever probeAndAwaitAck call
+// is waiting on the sending address, keyed by addr.String() since the two
+// sides of a connectivity check race independently and several checks can
+// be in flight (this side probing while the peer's own checks arrive too).
+type ackRouter struct {
+	acks chan *net.UDPAddr
+}
+
+func newAckRouter() *ackRouter {
+	return &ackRouter{acks: make(chan *net.UDPAddr, 16)}
+}
+
+func (r *ackRouter) run(ctx context.Context, conn *net.UDPConn) {
+	buf := make([]byte, 512)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			continue
+		}
+		switch {
+		case n == len(probePayload) && bytes.Equal(buf[:n], probePayload):
+			_, _ = conn.WriteToUDP(ackPayload, from)
+		case n == len(ackPayload) && bytes.Equal(buf[:n], ackPayload):
+			select {
+			case r.acks <- from:
+			default:
+				// A full buffer means nothing is currently waiting on an
+				// ack (every in-flight probeAndAwaitAck call has already
+				// moved on); dropping it is fine, the sender will have
+				// already timed out its own check on this pair too.
+			}
+		}
+	}
+}
+
+// probeAndAwaitAck sends probePayload to addr and waits up to
+// connCheckTimeout for an ack the router reports as coming from that same
+// address.
+func (r *ackRouter) probeAndAwaitAck(ctx context.Context, conn *net.UDPConn, addr *net.UDPAddr) bool {
+	deadline := time.Now().Add(connCheckTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	if _, err := conn.WriteToUDP(probePayload, addr); err != nil {
+		return false
+	}
+
+	for {
+		select {
+		case from := <-r.acks:
+			if from.IP.Equal(addr.IP) && from.Port == addr.Port {
+				return true
+			}
+			// An ack for a different in-flight candidate; keep waiting for
+			// this one until the timer fires.
+		case <-timer.C:
+			return false
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package nattraversal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	stunMagicCookie      = 0x2112A442
+	stunMsgBindingReq    = 0x0001
+	stunMsgBindingResp   = 0x0101
+	stunAttrMappedAddr   = 0x0001
+	stunAttrXorMappedAddr = 0x0020
+
+	// stunRequestTimeout bounds how long Gather waits on each STUN server
+	// before moving on; a single slow or unreachable server shouldn't stall
+	// the whole gather when ctx carries no deadline of its own.
+	stunRequestTimeout = 2 * time.Second
+)
+
+// Gather collects this host's ICE-style candidates: one host candidate per
+// non-loopback local IPv4 address, one server-reflexive candidate per
+// reachable entry in stunServers (host:port, RFC 5389 STUN), and one
+// relayed candidate if relayAddr is non-empty (the relay binary acting as
+// a TURN-like fallback, not an actual TURN allocation). A STUN server that
+// doesn't answer within stunRequestTimeout is skipped rather than failing
+// the whole gather, since host candidates and any other reachable server
+// still give Connect something to try.
+func Gather(ctx context.Context, stunServers []string, relayAddr string) ([]Candidate, error) {
+	var out []Candidate
+
+	hostIPs, err := hostCandidateIPs()
+	if err != nil {
+		return nil, fmt.Errorf("nattraversal: enumerate host addresses: %w", err)
+	}
+	for _, ip := range hostIPs {
+		out = append(out, Candidate{Type: CandidateHost, Addr: &net.UDPAddr{IP: ip, Port: 0}})
+	}
+
+	for _, server := range stunServers {
+		reflexive, err := stunQuery(ctx, server)
+		if err != nil {
+			continue
+		}
+		out = append(out, Candidate{Type: CandidateServerReflexive, Addr: reflexive})
+	}
+
+	if relayAddr != "" {
+		out = append(out, Candidate{Type: CandidateRelayed, RelayAddr: relayAddr})
+	}
+
+	if len(out) == 0 {
+		return nil, fmt.Errorf("nattraversal: no candidates gathered")
+	}
+	return out, nil
+}
+
+// hostCandidateIPs returns this host's non-loopback IPv4 addresses, one per
+// interface, as host candidates' starting point.
+func hostCandidateIPs() ([]net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		if v4 := ipNet.IP.To4(); v4 != nil {
+			ips = append(ips, v4)
+		}
+	}
+	return ips, nil
+}
+
+// stunQuery sends a single RFC 5389 binding request to server and returns
+// the address the server observed this socket's public address as, from
+// the response's XOR-MAPPED-ADDRESS attribute (falling back to the older
+// MAPPED-ADDRESS if that's what the server sent instead).
+func stunQuery(ctx context.Context, server string) (*net.UDPAddr, error) {
+	raddr, err := net.ResolveUDPAddr("udp", server)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", server, err)
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", server, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(stunRequestTimeout))
+	}
+
+	txID := make([]byte, 12)
+	if _, err := rand.Read(txID); err != nil {
+		return nil, err
+	}
+	req := make([]byte, 20)
+	binary.BigEndian.PutUint16(req[0:2], stunMsgBindingReq)
+	binary.BigEndian.PutUint16(req[2:4], 0)
+	binary.BigEndian.PutUint32(req[4:8], stunMagicCookie)
+	copy(req[8:20], txID)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	resp := make([]byte, 512)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	return parseStunBindingResponse(resp[:n], txID)
+}
+
+func parseStunBindingResponse(resp, txID []byte) (*net.UDPAddr, error) {
+	if len(resp) < 20 {
+		return nil, fmt.Errorf("STUN response too short")
+	}
+	if binary.BigEndian.Uint16(resp[0:2]) != stunMsgBindingResp {
+		return nil, fmt.Errorf("unexpected STUN message type")
+	}
+	if !bytes.Equal(resp[8:20], txID) {
+		return nil, fmt.Errorf("STUN transaction ID mismatch")
+	}
+	msgLen := int(binary.BigEndian.Uint16(resp[2:4]))
+	body := resp[20:]
+	if len(body) < msgLen {
+		return nil, fmt.Errorf("STUN response truncated")
+	}
+	body = body[:msgLen]
+
+	for len(body) >= 4 {
+		attrType := binary.BigEndian.Uint16(body[0:2])
+		attrLen := int(binary.BigEndian.Uint16(body[2:4]))
+		if len(body) < 4+attrLen {
+			break
+		}
+		val := body[4 : 4+attrLen]
+		switch attrType {
+		case stunAttrXorMappedAddr:
+			if addr, err := parseXorMappedAddress(val); err == nil {
+				return addr, nil
+			}
+		case stunAttrMappedAddr:
+			if addr, err := parseMappedAddress(val); err == nil {
+				return addr, nil
+			}
+		}
+		padded := (attrLen + 3) &^ 3 // attributes are padded to a 4-byte boundary
+		body = body[4+padded:]
+	}
+	return nil, fmt.Errorf("STUN response had no mapped-address attribute")
+}
+
+func parseMappedAddress(val []byte) (*net.UDPAddr, error) {
+	if len(val) < 8 || val[1] != 0x01 {
+		return nil, fmt.Errorf("unsupported MAPPED-ADDRESS family")
+	}
+	port := binary.BigEndian.Uint16(val[2:4])
+	ip := append([]byte{}, val[4:8]...)
+	return &net.UDPAddr{IP: net.IP(ip), Port: int(port)}, nil
+}
+
+func parseXorMappedAddress(val []byte) (*net.UDPAddr, error) {
+	if len(val) < 8 || val[1] != 0x01 {
+		return nil, fmt.Errorf("unsupported XOR-MAPPED-ADDRESS family")
+	}
+	xport := binary.BigEndian.Uint16(val[2:4])
+	port := xport ^ uint16(stunMagicCookie>>16)
+	cookie := make([]byte, 4)
+	binary.BigEndian.PutUint32(cookie, stunMagicCookie)
+	ip := make(net.IP, 4)
+	for i := range ip {
+		ip[i] = val[4+i] ^ cookie[i]
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+}
@@ -0,0 +1,64 @@
+// Package nattraversal gathers this host's reachable addresses and picks a
+// working path to a peer the way ICE does: host candidates first, then
+// server-reflexive candidates learned from a STUN server, falling back to
+// a relayed candidate through the existing relay binary when nothing
+// direct is reachable (symmetric NAT on one or both sides).
+package nattraversal
+
+import "net"
+
+// CandidateType classifies how a Candidate was discovered, mirroring ICE's
+// host/server-reflexive/relayed taxonomy.
+type CandidateType int
+
+const (
+	CandidateHost CandidateType = iota
+	CandidateServerReflexive
+	CandidateRelayed
+)
+
+// String renders t the way log lines and Candidate's own String want it.
+func (t CandidateType) String() string {
+	switch t {
+	case CandidateHost:
+		return "host"
+	case CandidateServerReflexive:
+		return "srflx"
+	case CandidateRelayed:
+		return "relay"
+	default:
+		return "unknown"
+	}
+}
+
+// Candidate is one address this side might be reachable at, along with how
+// it was discovered. Connect orders candidates by priority() — host above
+// server-reflexive above relayed — since a direct path is always cheaper
+// than bouncing through a relay once it's confirmed reachable.
+type Candidate struct {
+	Type CandidateType
+	Addr *net.UDPAddr
+	// RelayAddr is set only when Type is CandidateRelayed, naming the
+	// relay binary's address; Addr is left nil since a relayed candidate
+	// has no 4-tuple of its own until the relay allocates one.
+	RelayAddr string
+}
+
+func (c Candidate) String() string {
+	if c.Type == CandidateRelayed {
+		return c.Type.String() + " " + c.RelayAddr
+	}
+	return c.Type.String() + " " + c.Addr.String()
+}
+
+// priority ranks candidates the way Connect tries them: highest first.
+func (c Candidate) priority() int {
+	switch c.Type {
+	case CandidateHost:
+		return 2
+	case CandidateServerReflexive:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,218 @@
+package observability
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// EventKind classifies a session/chunk lifecycle event published on an EventBus.
+type EventKind string
+
+const (
+	EventKindStarted              EventKind = "started"
+	EventKindChunkReceived        EventKind = "chunk_received"
+	EventKindDecryptFailed        EventKind = "decrypt_failed"
+	EventKindHashMismatch         EventKind = "hash_mismatch"
+	EventKindRetransmit           EventKind = "retransmit"
+	EventKindFECUpdated           EventKind = "fec_updated"
+	EventKindVerificationCompleted EventKind = "verification_completed"
+	EventKindMerkleProofFailed    EventKind = "merkle_proof_failed"
+	EventKindSnapshotRequested    EventKind = "snapshot_requested"
+	EventKindSnapshotApplied      EventKind = "snapshot_applied"
+	EventKindBandwidth           EventKind = "bandwidth"
+	EventKindBadEncodingProof    EventKind = "bad_encoding_proof"
+)
+
+// Event is a single lifecycle event describing what happened to a session or chunk.
+// It is deliberately flat and JSON-serializable so it can be published to Kafka
+// or written as NDJSON without a schema registry.
+type Event struct {
+	Kind      EventKind         `json:"kind"`
+	SessionID string            `json:"session_id"`
+	ChunkIndex *int64           `json:"chunk_index,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// EventBus publishes lifecycle events to an external system, decoupling
+// observers from the transport/FEC code paths that generate the events.
+type EventBus interface {
+	Publish(ev Event) error
+	Close() error
+}
+
+// RoutingRule maps an event kind to an alternate destination (e.g. Kafka topic,
+// or NDJSON file path) so noisy event kinds can be routed away from the default.
+type RoutingRule struct {
+	Kind EventKind
+	Dest string
+}
+
+// NopEventBus discards every event. It is the default when no sink is configured.
+type NopEventBus struct{}
+
+func (NopEventBus) Publish(Event) error { return nil }
+func (NopEventBus) Close() error        { return nil }
+
+// NDJSONEventBus appends one JSON object per line to a local file. It is meant
+// for development and for environments without a Kafka cluster.
+type NDJSONEventBus struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewNDJSONEventBus opens (creating if needed) path for append-only NDJSON writes.
+func NewNDJSONEventBus(path string) (*NDJSONEventBus, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event sink file: %w", err)
+	}
+	return &NDJSONEventBus{f: f}, nil
+}
+
+func (b *NDJSONEventBus) Publish(ev Event) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err = b.f.Write(append(line, '\n'))
+	return err
+}
+
+func (b *NDJSONEventBus) Close() error {
+	return b.f.Close()
+}
+
+// KafkaTLSConfig configures transport-level TLS for the Kafka client.
+type KafkaTLSConfig struct {
+	Enabled            bool
+	CertFile           string
+	KeyFile            string
+	CAFile             string
+	InsecureSkipVerify bool
+}
+
+// KafkaSASLConfig configures SASL authentication for the Kafka client.
+type KafkaSASLConfig struct {
+	Enabled  bool
+	Mechanism string // "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"
+	Username string
+	Password string
+}
+
+// KafkaEventBusConfig configures the Kafka-backed EventBus.
+type KafkaEventBusConfig struct {
+	Brokers        []string
+	Topic          string
+	ConsumerGroupID string // recorded as the producer's client ID for traceability
+	TLS            KafkaTLSConfig
+	SASL           KafkaSASLConfig
+	// Routes overrides the destination topic for specific event kinds,
+	// e.g. routing high-volume chunk_received events to a separate topic.
+	Routes []RoutingRule
+}
+
+// KafkaEventBus publishes events to Kafka via an async producer, fire-and-forget,
+// so a slow or unavailable broker never blocks the transport/FEC hot path.
+type KafkaEventBus struct {
+	producer sarama.AsyncProducer
+	topic    string
+	routes   map[EventKind]string
+}
+
+// NewKafkaEventBus dials the configured brokers and returns a ready EventBus.
+func NewKafkaEventBus(cfg KafkaEventBusConfig) (*KafkaEventBus, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka event bus: at least one broker is required")
+	}
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka event bus: topic is required")
+	}
+
+	sc := sarama.NewConfig()
+	sc.ClientID = cfg.ConsumerGroupID
+	sc.Producer.Return.Successes = false
+	sc.Producer.Return.Errors = true
+	sc.Producer.RequiredAcks = sarama.WaitForLocal
+
+	if cfg.TLS.Enabled {
+		sc.Net.TLS.Enable = true
+		sc.Net.TLS.Config = &tls.Config{InsecureSkipVerify: cfg.TLS.InsecureSkipVerify}
+		if cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("kafka event bus: failed to load client cert: %w", err)
+			}
+			sc.Net.TLS.Config.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	if cfg.SASL.Enabled {
+		sc.Net.SASL.Enable = true
+		sc.Net.SASL.User = cfg.SASL.Username
+		sc.Net.SASL.Password = cfg.SASL.Password
+		switch cfg.SASL.Mechanism {
+		case "SCRAM-SHA-256":
+			sc.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		case "SCRAM-SHA-512":
+			sc.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		default:
+			sc.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		}
+	}
+
+	producer, err := sarama.NewAsyncProducer(cfg.Brokers, sc)
+	if err != nil {
+		return nil, fmt.Errorf("kafka event bus: failed to create producer: %w", err)
+	}
+
+	routes := make(map[EventKind]string, len(cfg.Routes))
+	for _, r := range cfg.Routes {
+		routes[r.Kind] = r.Dest
+	}
+
+	bus := &KafkaEventBus{producer: producer, topic: cfg.Topic, routes: routes}
+
+	// Drain the errors channel so a full buffer never stalls the producer;
+	// publish failures are fire-and-forget by design (see Publish).
+	go func() {
+		for range producer.Errors() {
+		}
+	}()
+
+	return bus, nil
+}
+
+// Publish encodes ev as JSON and enqueues it for async delivery. It returns
+// only marshaling errors; broker-side delivery failures are logged by the
+// background error drain and do not propagate to the caller.
+func (b *KafkaEventBus) Publish(ev Event) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	topic := b.topic
+	if dest, ok := b.routes[ev.Kind]; ok && dest != "" {
+		topic = dest
+	}
+
+	b.producer.Input() <- &sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(ev.SessionID),
+		Value: sarama.ByteEncoder(payload),
+	}
+	return nil
+}
+
+func (b *KafkaEventBus) Close() error {
+	return b.producer.Close()
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -38,6 +39,9 @@ type HealthChecker struct {
 	version   string
 	startTime time.Time
 	checks    map[string]HealthCheckFunc
+
+	mu     sync.RWMutex
+	static map[string]ComponentHealth
 }
 
 // HealthCheckFunc defines a function that checks component health.
@@ -49,6 +53,7 @@ func NewHealthChecker(version string) *HealthChecker {
 		version:   version,
 		startTime: time.Now(),
 		checks:    make(map[string]HealthCheckFunc),
+		static:    make(map[string]ComponentHealth),
 	}
 }
 
@@ -57,6 +62,19 @@ func (hc *HealthChecker) RegisterCheck(name string, checkFunc HealthCheckFunc) {
 	hc.checks[name] = checkFunc
 }
 
+// SetStatus imperatively sets name's status for every future /health
+// response, overriding any HealthCheckFunc registered under the same name.
+// Unlike RegisterCheck's poll-on-demand model, this lets a component push
+// its status the moment it changes (e.g. the QUIC listener dying, or the
+// bitmap store losing its database connection) rather than waiting for the
+// next Check call to poll it — the HTTP JSON counterpart to a gRPC
+// health.Server's SetServingStatus.
+func (hc *HealthChecker) SetStatus(name string, status ComponentHealth) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.static[name] = status
+}
+
 // Check performs all health checks.
 func (hc *HealthChecker) Check(ctx context.Context) HealthCheckResponse {
 	response := HealthCheckResponse{
@@ -70,7 +88,15 @@ func (hc *HealthChecker) Check(ctx context.Context) HealthCheckResponse {
 	for name, checkFunc := range hc.checks {
 		health := checkFunc(ctx)
 		response.Checks[name] = health
+	}
 
+	hc.mu.RLock()
+	for name, health := range hc.static {
+		response.Checks[name] = health
+	}
+	hc.mu.RUnlock()
+
+	for _, health := range response.Checks {
 		// Update overall status
 		if health.Status == HealthStatusUnhealthy {
 			response.Status = HealthStatusUnhealthy
@@ -171,6 +197,59 @@ func DatabaseCheck(dbPath string) HealthCheckFunc {
 	}
 }
 
+// TLSCertStatus is one managed hostname's most recently observed
+// certificate state, mirroring transport/tlsauto.CertStatus without this
+// package importing transport.
+type TLSCertStatus struct {
+	Hostname string
+	NotAfter time.Time
+	Err      error
+}
+
+// TLSCertProvider supplies the current certificate state for every
+// hostname a TLS automation manager is tracking - usually a
+// *transport/tlsauto.Manager's Statuses method, wired in by the daemon so
+// this package doesn't have to import transport.
+type TLSCertProvider func() []TLSCertStatus
+
+// tlsCertExpiryWarning is how close to a cert's NotAfter TLSCertCheck
+// starts reporting Degraded instead of OK, giving an operator time to
+// notice a stuck renewal before the certificate actually lapses.
+const tlsCertExpiryWarning = 14 * 24 * time.Hour
+
+// TLSCertCheck reports Unhealthy if any managed hostname's last ACME
+// renewal attempt failed or its certificate has already expired, Degraded
+// if any certificate expires within 14 days, and OK otherwise. The
+// reported message always includes the relevant certificate's NotAfter.
+func TLSCertCheck(provider TLSCertProvider) HealthCheckFunc {
+	return func(ctx context.Context) ComponentHealth {
+		statuses := provider()
+		if len(statuses) == 0 {
+			return ComponentHealth{Status: HealthStatusOK, Message: "no managed certificates yet"}
+		}
+
+		now := time.Now()
+		status := HealthStatusOK
+		message := fmt.Sprintf("%d managed certificate(s) healthy", len(statuses))
+
+		for _, st := range statuses {
+			switch {
+			case st.Err != nil:
+				status = HealthStatusUnhealthy
+				message = fmt.Sprintf("%s: last ACME renewal failed (NotAfter %s): %v", st.Hostname, st.NotAfter.Format(time.RFC3339), st.Err)
+			case !st.NotAfter.IsZero() && now.After(st.NotAfter):
+				status = HealthStatusUnhealthy
+				message = fmt.Sprintf("%s: certificate expired (NotAfter %s)", st.Hostname, st.NotAfter.Format(time.RFC3339))
+			case !st.NotAfter.IsZero() && st.NotAfter.Sub(now) < tlsCertExpiryWarning && status != HealthStatusUnhealthy:
+				status = HealthStatusDegraded
+				message = fmt.Sprintf("%s: certificate expires soon (NotAfter %s)", st.Hostname, st.NotAfter.Format(time.RFC3339))
+			}
+		}
+
+		return ComponentHealth{Status: status, Message: message}
+	}
+}
+
 // DiskSpaceCheck checks available disk space.
 func DiskSpaceCheck(path string, minFreeGB int64) HealthCheckFunc {
 	return func(ctx context.Context) ComponentHealth {
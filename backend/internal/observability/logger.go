@@ -28,9 +28,11 @@ func NewLogger(service, version string, output io.Writer) *Logger {
 		Str("host", getHostname()).
 		Logger()
 
-	return &Logger{
+	l := &Logger{
 		logger: logger,
 	}
+	defaultLogger.Store(l)
+	return l
 }
 
 // WithSession adds session_id context to logger.
@@ -139,6 +141,18 @@ func (l *Logger) ChunkDecryptFailed(sessionID string, chunkIndex int, errorCode
 		Msg("chunk decryption failed")
 }
 
+// DTNItemDropped logs a DTN queue item given up on, because it exceeded its
+// backoff policy's MaxAttempts or its ExpireAt passed before delivery
+// succeeded.
+func (l *Logger) DTNItemDropped(sessionID string, chunkIndex int64, reason string, attempts int) {
+	l.logger.Error().
+		Str("session_id", sessionID).
+		Int64("chunk_index", chunkIndex).
+		Str("reason", reason).
+		Int("attempts", attempts).
+		Msg("dtn item dropped")
+}
+
 // ConnectionEstablished logs connection establishment.
 func (l *Logger) ConnectionEstablished(remoteAddr string, connectionID string) {
 	l.logger.Info().
@@ -155,6 +169,19 @@ func (l *Logger) ConnectionFailed(remoteAddr string, err error) {
 		Msg("QUIC connection failed")
 }
 
+// BandwidthSample logs a windowed raw-bandwidth measurement: sent and recvd
+// are the raw (on-wire) bytes moved during window, not running totals, so
+// operators can compare samples over time without re-deriving a rate
+// themselves.
+func (l *Logger) BandwidthSample(sessionID string, sent, recvd int64, window time.Duration) {
+	l.logger.Info().
+		Str("session_id", sessionID).
+		Int64("raw_bytes_sent", sent).
+		Int64("raw_bytes_recvd", recvd).
+		Float64("window_seconds", window.Seconds()).
+		Msg("bandwidth sample")
+}
+
 // Helper function to get hostname.
 func getHostname() string {
 	hostname, err := os.Hostname()
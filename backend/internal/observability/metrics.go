@@ -1,12 +1,15 @@
 package observability
 
 import (
+	"context"
 	"net/http"
 	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Metrics holds all Prometheus metrics for the daemon.
@@ -26,10 +29,16 @@ type Metrics struct {
 	QUICConnectionDuration  prometheus.Histogram
 	QUICStreamsActive       prometheus.Gauge
 	QUICPacketLossRate      prometheus.Gauge
+	DatagramsSentTotal      prometheus.Counter
+	DatagramsReceivedTotal  prometheus.Counter
+	DatagramsDroppedTotal   *prometheus.CounterVec
+	MaxDatagramFrameSize    prometheus.Gauge
 	FECEnabled              prometheus.Gauge
 	FECReconstructionsTotal prometheus.Counter
 	FECReconstructionFailuresTotal prometheus.Counter
 	FECParityShardsSentTotal       prometheus.Counter
+	FECBadEncodingProofsTotal      prometheus.Counter
+	FECCurrentRatio                prometheus.Gauge
 
 	// Crypto metrics
 	CryptoOperationsTotal     *prometheus.CounterVec
@@ -43,11 +52,21 @@ type Metrics struct {
 
 	// Active transfers counter (atomic for thread-safety)
 	activeTransfers int64
+
+	// Tracer starts the per-transfer/per-chunk/per-crypto-op spans exposed
+	// by StartTransferSpan/StartChunkSpan/StartCryptoSpan, and supplies the
+	// trace_id the duration histograms attach as a Prometheus exemplar via
+	// observeWithExemplar. Sampled from whatever otel.SetTracerProvider
+	// installed (see InitTracing); a no-op provider still produces a valid,
+	// just unsampled, Tracer.
+	Tracer trace.Tracer
 }
 
 // NewMetrics creates and registers all Prometheus metrics.
 func NewMetrics() *Metrics {
 	m := &Metrics{
+		Tracer: otel.Tracer("quantarax-metrics"),
+
 		// Transfer metrics
 		TransfersTotal: promauto.NewCounterVec(
 			prometheus.CounterOpts{
@@ -66,9 +85,10 @@ func NewMetrics() *Metrics {
 
 		TransferDuration: promauto.NewHistogram(
 			prometheus.HistogramOpts{
-				Name:    "quantarax_transfer_duration_seconds",
-				Help:    "Transfer completion time distribution",
-				Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800},
+				Name:                        "quantarax_transfer_duration_seconds",
+				Help:                        "Transfer completion time distribution",
+				NativeHistogramBucketFactor: 1.1,
+				Buckets:                     []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800},
 			},
 		),
 
@@ -120,9 +140,10 @@ func NewMetrics() *Metrics {
 
 		QUICConnectionDuration: promauto.NewHistogram(
 			prometheus.HistogramOpts{
-				Name:    "quantarax_quic_connection_duration_seconds",
-				Help:    "QUIC connection lifetime",
-				Buckets: []float64{1, 5, 10, 30, 60, 120, 300},
+				Name:                        "quantarax_quic_connection_duration_seconds",
+				Help:                        "QUIC connection lifetime",
+				NativeHistogramBucketFactor: 1.1,
+				Buckets:                     []float64{1, 5, 10, 30, 60, 120, 300},
 			},
 		),
 
@@ -140,6 +161,35 @@ func NewMetrics() *Metrics {
 			},
 		),
 
+		DatagramsSentTotal: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Name: "quantarax_datagrams_sent_total",
+				Help: "QUIC DATAGRAM frames sent (Ack/Nack/FECUpdate fast path and sub-MTU chunk data)",
+			},
+		),
+
+		DatagramsReceivedTotal: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Name: "quantarax_datagrams_received_total",
+				Help: "QUIC DATAGRAM frames received and successfully decoded",
+			},
+		),
+
+		DatagramsDroppedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "quantarax_datagrams_dropped_total",
+				Help: "QUIC DATAGRAM frames dropped, by reason",
+			},
+			[]string{"reason"},
+		),
+
+		MaxDatagramFrameSize: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "quantarax_max_datagram_frame_size_bytes",
+				Help: "Largest datagram payload this connection's ControlStream will attempt to send (see ControlStream.MaxDatagramSize)",
+			},
+		),
+
 		// FEC metrics
 		FECEnabled: promauto.NewGauge(
 			prometheus.GaugeOpts{
@@ -169,20 +219,35 @@ func NewMetrics() *Metrics {
 			},
 		),
 
+		FECBadEncodingProofsTotal: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Name: "quantarax_fec_bad_encoding_proofs_total",
+				Help: "Bad-encoding fraud proofs generated for a sender whose FEC shards reconstruct to data inconsistent with the signed manifest",
+			},
+		),
+
+		FECCurrentRatio: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "quantarax_fec_current_ratio",
+				Help: "Current FEC parity ratio (r/k) as set by the adaptive FECController",
+			},
+		),
+
 		// Crypto metrics
 		CryptoOperationsTotal: promauto.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "quantarax_crypto_operations_total",
 				Help: "Cryptographic operations performed",
 			},
-			[]string{"operation"},
+			[]string{"operation", "algo"},
 		),
 
 		CryptoOperationDuration: promauto.NewHistogram(
 			prometheus.HistogramOpts{
-				Name:    "quantarax_crypto_operation_duration_seconds",
-				Help:    "Crypto operation latency",
-				Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0},
+				Name:                        "quantarax_crypto_operation_duration_seconds",
+				Help:                        "Crypto operation latency",
+				NativeHistogramBucketFactor: 1.1,
+				Buckets:                     []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0},
 			},
 		),
 
@@ -197,9 +262,10 @@ func NewMetrics() *Metrics {
 		// Storage metrics
 		BitmapPersistDuration: promauto.NewHistogram(
 			prometheus.HistogramOpts{
-				Name:    "quantarax_bitmap_persist_duration_seconds",
-				Help:    "Bitmap persistence latency",
-				Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1.0, 2.0},
+				Name:                        "quantarax_bitmap_persist_duration_seconds",
+				Help:                        "Bitmap persistence latency",
+				NativeHistogramBucketFactor: 1.1,
+				Buckets:                     []float64{0.01, 0.05, 0.1, 0.5, 1.0, 2.0},
 			},
 		),
 
@@ -228,8 +294,11 @@ func (m *Metrics) RecordTransferStart() {
 	m.TransfersActive.Set(float64(atomic.LoadInt64(&m.activeTransfers)))
 }
 
-// RecordTransferComplete records transfer completion metrics.
-func (m *Metrics) RecordTransferComplete(success bool, durationSeconds float64) {
+// RecordTransferComplete records transfer completion metrics. ctx's current
+// span, if any, is attached to TransferDuration's observation as a
+// Prometheus exemplar (see observeWithExemplar), so a slow bucket in
+// Grafana can jump straight to the trace that landed in it.
+func (m *Metrics) RecordTransferComplete(ctx context.Context, success bool, durationSeconds float64) {
 	atomic.AddInt64(&m.activeTransfers, -1)
 	m.TransfersActive.Set(float64(atomic.LoadInt64(&m.activeTransfers)))
 
@@ -239,7 +308,7 @@ func (m *Metrics) RecordTransferComplete(success bool, durationSeconds float64)
 	}
 
 	m.TransfersTotal.WithLabelValues(status).Inc()
-	m.TransferDuration.Observe(durationSeconds)
+	observeWithExemplar(ctx, m.TransferDuration, durationSeconds)
 }
 
 // RecordChunkSent updates metrics for a sent chunk.
@@ -273,15 +342,51 @@ func (m *Metrics) RecordQUICConnection(success bool) {
 }
 
 // RecordQUICConnectionClose updates metrics for closed QUIC connections.
-func (m *Metrics) RecordQUICConnectionClose(durationSeconds float64) {
+func (m *Metrics) RecordQUICConnectionClose(ctx context.Context, durationSeconds float64) {
 	m.QUICConnectionsActive.Dec()
-	m.QUICConnectionDuration.Observe(durationSeconds)
+	observeWithExemplar(ctx, m.QUICConnectionDuration, durationSeconds)
+}
+
+// RecordDatagramSent increments DatagramsSentTotal for one successfully
+// submitted QUIC DATAGRAM frame (see ControlStream.SendDatagramMessage).
+func (m *Metrics) RecordDatagramSent() {
+	m.DatagramsSentTotal.Inc()
+}
+
+// RecordDatagramReceived increments DatagramsReceivedTotal for one
+// successfully decoded, non-duplicate QUIC DATAGRAM frame (see
+// ControlStream.ReceiveDatagrams).
+func (m *Metrics) RecordDatagramReceived() {
+	m.DatagramsReceivedTotal.Inc()
+}
+
+// RecordDatagramDropped increments DatagramsDroppedTotal for reason, e.g.
+// "too_large", "send_failed", "malformed", or "duplicate".
+func (m *Metrics) RecordDatagramDropped(reason string) {
+	m.DatagramsDroppedTotal.WithLabelValues(reason).Inc()
 }
 
-// RecordCryptoOperation records cryptographic operation duration.
-func (m *Metrics) RecordCryptoOperation(operation string, durationSeconds float64) {
-	m.CryptoOperationsTotal.WithLabelValues(operation).Inc()
-	m.CryptoOperationDuration.Observe(durationSeconds)
+// SetMaxDatagramFrameSize records the largest datagram payload this
+// connection's ControlStream will attempt to send, so operators can see at
+// a glance whether datagrams are usable at all on a given path (it stays
+// at its zero value until something calls this, the same as any other
+// gauge here before its first observation).
+func (m *Metrics) SetMaxDatagramFrameSize(bytes int) {
+	m.MaxDatagramFrameSize.Set(float64(bytes))
+}
+
+// RecordCryptoOperation records cryptographic operation duration, broken
+// down by the AEAD algorithm (e.g. crypto.AEADAlgorithm.String()) that
+// performed it, so per-algorithm cost is visible once a session negotiates
+// something other than the AES-256-GCM default.
+func (m *Metrics) RecordCryptoOperation(ctx context.Context, operation, algo string, durationSeconds float64) {
+	m.CryptoOperationsTotal.WithLabelValues(operation, algo).Inc()
+	observeWithExemplar(ctx, m.CryptoOperationDuration, durationSeconds)
+}
+
+// RecordBitmapPersist records how long one bitmap persistence write took.
+func (m *Metrics) RecordBitmapPersist(ctx context.Context, durationSeconds float64) {
+	observeWithExemplar(ctx, m.BitmapPersistDuration, durationSeconds)
 }
 
 // RecordMerkleVerification increments Merkle verification counters.
@@ -302,6 +407,29 @@ func (m *Metrics) RecordFECReconstruction(success bool) {
 	}
 }
 
+// RecordFECBadEncodingProof increments the bad-encoding fraud proof counter,
+// called whenever a receiver generates a BadEncodingProof against a sender.
+func (m *Metrics) RecordFECBadEncodingProof() {
+	m.FECBadEncodingProofsTotal.Inc()
+}
+
+// RecordFECAdaptation reports a FECController decision: lossRate and
+// rttMillis are its current EWMA estimates, and parityShards is the r the
+// controller just set, added to FECParityShardsSentTotal since every
+// future stripe now carries that many more parity shards per k data
+// shards. QUICPacketLossRate and FECCurrentRatio are set to the
+// controller's latest view regardless of whether this Tick changed (k, r),
+// so they stay live even while hysteresis holds the ratio steady.
+func (m *Metrics) RecordFECAdaptation(lossRate, rttMillis float64, k, r, parityShards int) {
+	m.QUICPacketLossRate.Set(lossRate)
+	if k > 0 {
+		m.FECCurrentRatio.Set(float64(r) / float64(k))
+	}
+	if parityShards > 0 {
+		m.FECParityShardsSentTotal.Add(float64(parityShards))
+	}
+}
+
 // SetFECEnabled sets the FEC enabled flag.
 func (m *Metrics) SetFECEnabled(enabled bool) {
 	if enabled {
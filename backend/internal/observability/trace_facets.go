@@ -0,0 +1,137 @@
+package observability
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// Facet names the hot-path subsystems Trace can be gated on. These match the
+// comma-separated values accepted by the QXTRACE env var.
+const (
+	FacetFEC          = "fec"
+	FacetCAS          = "cas"
+	FacetQUIC         = "quic"
+	FacetOrchestrator = "orchestrator"
+	FacetDTN          = "dtn"
+)
+
+// facetBits maps each known Facet to its bit in a facet mask. Unknown facet
+// names named in QXTRACE are ignored rather than rejected, the same
+// forward-compatible convention QXTRACE=all's bit (every bit set) relies on.
+var facetBits = map[string]uint32{
+	FacetFEC:          1 << 0,
+	FacetCAS:          1 << 1,
+	FacetQUIC:         1 << 2,
+	FacetOrchestrator: 1 << 3,
+	FacetDTN:          1 << 4,
+}
+
+const allFacetsMask uint32 = 1<<5 - 1
+
+// facetMask is the process-wide set of enabled trace facets, parsed once
+// from QXTRACE at package init and adjustable at runtime via SetFacets. It's
+// a package-level atomic rather than a Logger field so TraceEnabled can gate
+// a hot path without a Logger in scope, and so every Logger in the process
+// (there's normally just one, but tests construct several) shares one
+// toggle.
+var facetMask uint32
+
+func init() {
+	facetMask = parseFacets(os.Getenv("QXTRACE"))
+}
+
+// parseFacets turns a QXTRACE-style comma-separated facet list (or "all")
+// into a facet mask.
+func parseFacets(spec string) uint32 {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return 0
+	}
+	if strings.EqualFold(spec, "all") {
+		return allFacetsMask
+	}
+	var mask uint32
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if bit, ok := facetBits[name]; ok {
+			mask |= bit
+		}
+	}
+	return mask
+}
+
+// SetFacets replaces the process-wide enabled trace facets with mask,
+// overriding whatever QXTRACE set at startup. Intended for runtime toggling
+// (e.g. a remote-control message on the control stream flipping a facet on
+// to debug a live transfer without restarting the daemon).
+func SetFacets(mask uint32) {
+	atomic.StoreUint32(&facetMask, mask)
+}
+
+// FacetMask returns the facet bit for name, or 0 if name isn't a known
+// Facet. Combine with bitwise-or to build a mask for SetFacets.
+func FacetMask(name string) uint32 {
+	return facetBits[strings.ToLower(name)]
+}
+
+// TraceEnabled reports whether facet is currently enabled, so a hot path can
+// skip building a trace message entirely when it isn't (e.g.
+// `if observability.TraceEnabled(observability.FacetCAS) { ... }` around a
+// fmt.Sprintf a Logger.Trace call alone wouldn't avoid).
+func TraceEnabled(facet string) bool {
+	bit, ok := facetBits[strings.ToLower(facet)]
+	if !ok {
+		return false
+	}
+	return atomic.LoadUint32(&facetMask)&bit != 0
+}
+
+// Trace logs msg at debug level if facet is enabled, with fields as
+// alternating key/value pairs the way zerolog's Fields map expects (e.g.
+// Trace(FacetFEC, "adapting parity", "k", k, "r", r)). It's the
+// facet-gated replacement for an all-or-nothing Debug call on hot paths:
+// cheap to call unconditionally since TraceEnabled's check happens first
+// and fields are never formatted when the facet is off.
+func (l *Logger) Trace(facet, msg string, fields ...interface{}) {
+	if !TraceEnabled(facet) {
+		return
+	}
+	traceWithLogger(l.logger, facet, msg, fields)
+}
+
+// defaultLogger is the process's most recently constructed Logger, so
+// TraceGlobal can reach a sink from hot paths that don't hold their own
+// Logger reference (FECController, the CAS backend, DTN's queue) without
+// every one of those needing a SetLogger plumbed in from main. NewLogger
+// registers itself here; a daemon only ever constructs one Logger in
+// practice, so "most recent" and "the" are the same thing.
+var defaultLogger atomic.Value // holds *Logger
+
+// TraceGlobal is Trace for callers with no Logger of their own, logging
+// through the most recently constructed Logger (see defaultLogger). It's a
+// no-op before any Logger has been constructed.
+func TraceGlobal(facet, msg string, fields ...interface{}) {
+	if !TraceEnabled(facet) {
+		return
+	}
+	v, _ := defaultLogger.Load().(*Logger)
+	if v == nil {
+		return
+	}
+	traceWithLogger(v.logger, facet, msg, fields)
+}
+
+func traceWithLogger(zl zerolog.Logger, facet, msg string, fields []interface{}) {
+	evt := zl.Debug().Str("facet", facet)
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, ok := fields[i].(string)
+		if !ok {
+			continue
+		}
+		evt = evt.Interface(key, fields[i+1])
+	}
+	evt.Msg(msg)
+}
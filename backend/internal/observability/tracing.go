@@ -7,24 +7,35 @@ import (
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/sdk/resource"
-	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 )
 
-// InitTracing initializes OpenTelemetry tracing with Jaeger exporter.
+// InitTracing initializes OpenTelemetry tracing. An OTLP exporter is used
+// when OTEL_EXPORTER_OTLP_ENDPOINT is set (modern collectors, including
+// recent Jaeger releases, all speak OTLP natively), falling back to the
+// legacy Jaeger exporter for deployments still pointing at a Jaeger-only
+// collector via OTEL_EXPORTER_JAEGER_ENDPOINT. If neither is set, tracing
+// is a no-op.
+//
 // Config via env:
-//   OTEL_SERVICE_NAME, OTEL_EXPORTER_JAEGER_ENDPOINT (e.g. http://localhost:14268/api/traces)
+//   OTEL_SERVICE_NAME
+//   OTEL_EXPORTER_OTLP_ENDPOINT    (e.g. http://localhost:4318, or host:4317 for grpc)
+//   OTEL_EXPORTER_OTLP_PROTOCOL    "grpc" or "http/protobuf" (default "http/protobuf")
+//   OTEL_EXPORTER_JAEGER_ENDPOINT  (e.g. http://localhost:14268/api/traces)
 func InitTracing(ctx context.Context, serviceName string) (func(context.Context) error, error) {
-	endpoint := os.Getenv("OTEL_EXPORTER_JAEGER_ENDPOINT")
-	if endpoint == "" {
-		// no-op
-		return func(ctx context.Context) error { return nil }, nil
-	}
-	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(endpoint)))
+	exp, err := newExporter(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if exp == nil {
+		// no-op
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
 	res, err := resource.New(ctx, resource.WithAttributes(
 		semconv.ServiceName(serviceName),
 	))
@@ -38,3 +49,21 @@ func InitTracing(ctx context.Context, serviceName string) (func(context.Context)
 	otel.SetTracerProvider(tp)
 	return tp.Shutdown, nil
 }
+
+// newExporter picks an exporter based on the OTEL_EXPORTER_OTLP_ENDPOINT and
+// OTEL_EXPORTER_JAEGER_ENDPOINT env vars, preferring OTLP. Returns a nil
+// exporter (and nil error) when neither is configured.
+func newExporter(ctx context.Context) (trace.SpanExporter, error) {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		if os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL") == "grpc" {
+			return otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+	}
+
+	if endpoint := os.Getenv("OTEL_EXPORTER_JAEGER_ENDPOINT"); endpoint != "" {
+		return jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(endpoint)))
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,53 @@
+package observability
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartTransferSpan starts a span covering one whole transfer, named and
+// tagged by transferID, for the per-transfer trace a slow TransferDuration
+// bucket's exemplar links back to.
+func (m *Metrics) StartTransferSpan(ctx context.Context, transferID string) (context.Context, trace.Span) {
+	return m.Tracer.Start(ctx, "transfer", trace.WithAttributes(
+		attribute.String("transfer.id", transferID),
+	))
+}
+
+// StartChunkSpan starts a span covering one chunk's send or receive, a
+// child of whatever transfer span is already in ctx.
+func (m *Metrics) StartChunkSpan(ctx context.Context, chunkIdx int64) (context.Context, trace.Span) {
+	return m.Tracer.Start(ctx, "chunk", trace.WithAttributes(
+		attribute.Int64("chunk.index", chunkIdx),
+	))
+}
+
+// StartCryptoSpan starts a span covering one AEAD Seal/Open (or similar)
+// operation named op, e.g. "seal" or "open", a child of whatever chunk or
+// transfer span is already in ctx.
+func (m *Metrics) StartCryptoSpan(ctx context.Context, op string) (context.Context, trace.Span) {
+	return m.Tracer.Start(ctx, "crypto."+op)
+}
+
+// observeWithExemplar records value on hist and, if ctx carries a sampled
+// span, attaches its trace ID as a Prometheus exemplar, so a Grafana panel
+// on hist's bucket can jump straight to that trace. Falls back to a plain
+// Observe when there's no sampled span, or hist doesn't implement
+// prometheus.ExemplarObserver (every histogram this package creates does;
+// the type assertion guards callers passing in some other Histogram).
+func observeWithExemplar(ctx context.Context, hist prometheus.Histogram, value float64) {
+	span := trace.SpanContextFromContext(ctx)
+	if !span.IsValid() || !span.IsSampled() {
+		hist.Observe(value)
+		return
+	}
+	eo, ok := hist.(prometheus.ExemplarObserver)
+	if !ok {
+		hist.Observe(value)
+		return
+	}
+	eo.ObserveWithExemplar(value, prometheus.Labels{"trace_id": span.TraceID().String()})
+}
@@ -0,0 +1,150 @@
+package quicutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	stunMagicCookie       = 0x2112A442
+	stunMsgBindingReq     = 0x0001
+	stunMsgBindingResp    = 0x0101
+	stunAttrMappedAddr    = 0x0001
+	stunAttrXorMappedAddr = 0x0020
+
+	// stunQueryTimeout bounds how long QueryPublicAddr waits on a single
+	// server before moving on to the next one in the list.
+	stunQueryTimeout = 2 * time.Second
+)
+
+// QueryPublicAddr is QuantaraX's embedded RFC 5389 STUN client: it sends a
+// binding request to each server in turn and returns the public address
+// the first one to answer reports back, so a caller behind a NAT can learn
+// the address its own outbound packets are mapped to. servers are tried in
+// order and a server that doesn't answer within stunQueryTimeout is skipped
+// rather than failing the whole query, since any one reachable server is
+// enough.
+func QueryPublicAddr(ctx context.Context, servers []string) (*net.UDPAddr, error) {
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("quicutil: no STUN servers configured")
+	}
+	var lastErr error
+	for _, server := range servers {
+		addr, err := stunBindingRequest(ctx, server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return addr, nil
+	}
+	return nil, fmt.Errorf("quicutil: no STUN server in %v answered: %w", servers, lastErr)
+}
+
+// stunBindingRequest sends a single binding request to server and decodes
+// the mapped address out of its response.
+func stunBindingRequest(ctx context.Context, server string) (*net.UDPAddr, error) {
+	raddr, err := net.ResolveUDPAddr("udp", server)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", server, err)
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", server, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(stunQueryTimeout))
+	}
+
+	txID := make([]byte, 12)
+	if _, err := rand.Read(txID); err != nil {
+		return nil, err
+	}
+	req := make([]byte, 20)
+	binary.BigEndian.PutUint16(req[0:2], stunMsgBindingReq)
+	binary.BigEndian.PutUint16(req[2:4], 0)
+	binary.BigEndian.PutUint32(req[4:8], stunMagicCookie)
+	copy(req[8:20], txID)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	resp := make([]byte, 512)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	return parseStunBindingResponse(resp[:n], txID)
+}
+
+func parseStunBindingResponse(resp, txID []byte) (*net.UDPAddr, error) {
+	if len(resp) < 20 {
+		return nil, fmt.Errorf("STUN response too short")
+	}
+	if binary.BigEndian.Uint16(resp[0:2]) != stunMsgBindingResp {
+		return nil, fmt.Errorf("unexpected STUN message type")
+	}
+	if !bytes.Equal(resp[8:20], txID) {
+		return nil, fmt.Errorf("STUN transaction ID mismatch")
+	}
+	msgLen := int(binary.BigEndian.Uint16(resp[2:4]))
+	body := resp[20:]
+	if len(body) < msgLen {
+		return nil, fmt.Errorf("STUN response truncated")
+	}
+	body = body[:msgLen]
+
+	for len(body) >= 4 {
+		attrType := binary.BigEndian.Uint16(body[0:2])
+		attrLen := int(binary.BigEndian.Uint16(body[2:4]))
+		if len(body) < 4+attrLen {
+			break
+		}
+		val := body[4 : 4+attrLen]
+		switch attrType {
+		case stunAttrXorMappedAddr:
+			if addr, err := parseXorMappedAddress(val); err == nil {
+				return addr, nil
+			}
+		case stunAttrMappedAddr:
+			if addr, err := parseMappedAddress(val); err == nil {
+				return addr, nil
+			}
+		}
+		padded := (attrLen + 3) &^ 3 // attributes are padded to a 4-byte boundary
+		body = body[4+padded:]
+	}
+	return nil, fmt.Errorf("STUN response had no mapped-address attribute")
+}
+
+func parseMappedAddress(val []byte) (*net.UDPAddr, error) {
+	if len(val) < 8 || val[1] != 0x01 {
+		return nil, fmt.Errorf("unsupported MAPPED-ADDRESS family")
+	}
+	port := binary.BigEndian.Uint16(val[2:4])
+	ip := append([]byte{}, val[4:8]...)
+	return &net.UDPAddr{IP: net.IP(ip), Port: int(port)}, nil
+}
+
+func parseXorMappedAddress(val []byte) (*net.UDPAddr, error) {
+	if len(val) < 8 || val[1] != 0x01 {
+		return nil, fmt.Errorf("unsupported XOR-MAPPED-ADDRESS family")
+	}
+	xport := binary.BigEndian.Uint16(val[2:4])
+	port := xport ^ uint16(stunMagicCookie>>16)
+	cookie := make([]byte, 4)
+	binary.BigEndian.PutUint32(cookie, stunMagicCookie)
+	ip := make(net.IP, 4)
+	for i := range ip {
+		ip[i] = val[4+i] ^ cookie[i]
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+}
@@ -46,3 +46,27 @@ func (tb *TokenBucket) Wait(n int) {
 		time.Sleep(10 * time.Millisecond)
 	}
 }
+
+// SetRate changes tb's rate and burst at runtime, for callers that expose
+// it as a live-tunable (e.g. the relay's POST /admin/limits). available is
+// capped to the new burst so a shrink takes effect immediately rather than
+// after burst tokens drain.
+func (tb *TokenBucket) SetRate(rate float64, burst int) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refillLocked(time.Now())
+	tb.rate = rate
+	tb.burst = burst
+	if tb.available > float64(burst) {
+		tb.available = float64(burst)
+	}
+}
+
+// Snapshot returns tb's current rate and burst, for callers (e.g. the
+// relay's GET /admin/limits) that need to report the live-tunable values
+// without reaching into tb's unexported fields.
+func (tb *TokenBucket) Snapshot() (rate float64, burst int) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.rate, tb.burst
+}
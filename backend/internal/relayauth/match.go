@@ -0,0 +1,23 @@
+package relayauth
+
+import "path"
+
+// MatchesTarget reports whether target (a relay target's host, with any
+// :port already stripped by the caller) is permitted by pattern, a
+// Claims.TargetPattern. An empty pattern matches nothing — a token with no
+// target_pattern can reach no targets — and "*" matches everything. Beyond
+// that, pattern is a path.Match glob against target, so "*.example.com"
+// scopes a token to one DNS suffix and "10.0.0.*" to one subnet's literal
+// dotted-quad form, the same two shapes RelayPolicy's DNS-suffix/CIDR rules
+// cover for the global policy file, without pulling in a second rule
+// syntax for the per-token case.
+func MatchesTarget(pattern, target string) bool {
+	if pattern == "" {
+		return false
+	}
+	if pattern == "*" {
+		return true
+	}
+	ok, err := path.Match(pattern, target)
+	return err == nil && ok
+}
@@ -0,0 +1,174 @@
+package relayauth
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testKey() Key {
+	return Key{ID: "k1", Algorithm: AlgorithmHMACSHA256, Secret: []byte("test-secret")}
+}
+
+func testVerifier(key Key) *Verifier {
+	return &Verifier{Keys: NewKeySet([]Key{key})}
+}
+
+func TestVerifyValidToken(t *testing.T) {
+	key := testKey()
+	v := testVerifier(key)
+	claims := Claims{
+		Subject:       "alice",
+		TargetPattern: "*.example.com",
+		Expiry:        time.Now().Add(time.Hour).Unix(),
+		JTI:           "jti-1",
+	}
+	token, err := IssueToken(key, claims)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	got, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.Subject != "alice" || got.TargetPattern != "*.example.com" {
+		t.Errorf("Verify returned unexpected claims: %+v", got)
+	}
+}
+
+func TestVerifyExpired(t *testing.T) {
+	key := testKey()
+	v := &Verifier{Keys: NewKeySet([]Key{key}), ClockSkew: time.Second}
+	claims := Claims{Subject: "alice", Expiry: time.Now().Add(-time.Hour).Unix(), JTI: "jti-expired"}
+	token, err := IssueToken(key, claims)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	if _, err := v.Verify(token); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify: got %v, want ErrExpired", err)
+	}
+}
+
+func TestVerifyClockSkewTolerated(t *testing.T) {
+	key := testKey()
+	v := &Verifier{Keys: NewKeySet([]Key{key}), ClockSkew: time.Minute}
+	claims := Claims{Subject: "alice", Expiry: time.Now().Add(-30 * time.Second).Unix(), JTI: "jti-skew"}
+	token, err := IssueToken(key, claims)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	if _, err := v.Verify(token); err != nil {
+		t.Errorf("Verify: expected skew to tolerate a recently-expired token, got %v", err)
+	}
+}
+
+func TestVerifyNotYetValid(t *testing.T) {
+	key := testKey()
+	v := &Verifier{Keys: NewKeySet([]Key{key}), ClockSkew: time.Second}
+	claims := Claims{
+		Subject:   "alice",
+		Expiry:    time.Now().Add(time.Hour).Unix(),
+		NotBefore: time.Now().Add(time.Hour).Unix(),
+		JTI:       "jti-nbf",
+	}
+	token, err := IssueToken(key, claims)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	if _, err := v.Verify(token); !errors.Is(err, ErrNotYetValid) {
+		t.Errorf("Verify: got %v, want ErrNotYetValid", err)
+	}
+}
+
+func TestVerifyWrongKeyRejected(t *testing.T) {
+	signingKey := testKey()
+	v := testVerifier(Key{ID: "k1", Algorithm: AlgorithmHMACSHA256, Secret: []byte("a-different-secret")})
+	claims := Claims{Subject: "alice", Expiry: time.Now().Add(time.Hour).Unix(), JTI: "jti-wrongkey"}
+	token, err := IssueToken(signingKey, claims)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	if _, err := v.Verify(token); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Verify: got %v, want ErrBadSignature", err)
+	}
+}
+
+func TestVerifyUnknownKeyRejected(t *testing.T) {
+	v := testVerifier(testKey())
+	claims := Claims{Subject: "alice", Expiry: time.Now().Add(time.Hour).Unix(), JTI: "jti-unknown"}
+	token, err := IssueToken(Key{ID: "k-missing", Algorithm: AlgorithmHMACSHA256, Secret: []byte("whatever")}, claims)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	if _, err := v.Verify(token); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Verify: got %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestVerifyReplayedJTIRejected(t *testing.T) {
+	key := testKey()
+	v := testVerifier(key)
+	claims := Claims{Subject: "alice", Expiry: time.Now().Add(time.Hour).Unix(), JTI: "jti-replay"}
+	token, err := IssueToken(key, claims)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	if _, err := v.Verify(token); err != nil {
+		t.Fatalf("first Verify: %v", err)
+	}
+	if _, err := v.Verify(token); !errors.Is(err, ErrReplayed) {
+		t.Errorf("second Verify: got %v, want ErrReplayed", err)
+	}
+}
+
+func TestVerifyMalformedToken(t *testing.T) {
+	v := testVerifier(testKey())
+	if _, err := v.Verify("not-a-valid-token"); !errors.Is(err, ErrMalformedToken) {
+		t.Errorf("Verify: got %v, want ErrMalformedToken", err)
+	}
+}
+
+func TestVerifyEd25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	key := Key{ID: "ed1", Algorithm: AlgorithmEd25519, PublicKey: pub, PrivateKey: priv}
+	v := testVerifier(key)
+	claims := Claims{Subject: "bob", Expiry: time.Now().Add(time.Hour).Unix(), JTI: "jti-ed25519"}
+	token, err := IssueToken(key, claims)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	if _, err := v.Verify(token); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
+
+func TestMatchesTarget(t *testing.T) {
+	cases := []struct {
+		pattern, target string
+		want            bool
+	}{
+		{"*.example.com", "relay.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*", "anything.at.all", true},
+		{"", "anything.at.all", false},
+		{"10.0.0.*", "10.0.0.5", true},
+		{"10.0.0.*", "10.0.1.5", false},
+	}
+	for _, c := range cases {
+		if got := MatchesTarget(c.pattern, c.target); got != c.want {
+			t.Errorf("MatchesTarget(%q, %q) = %v, want %v", c.pattern, c.target, got, c.want)
+		}
+	}
+}
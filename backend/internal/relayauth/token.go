@@ -0,0 +1,201 @@
+package relayauth
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replayCache is a bounded, TTL-expiring set of consumed jti values,
+// identical in shape to authn's nonceCache: a jti stays rejected until its
+// own expiry, after which it's safe to evict since the expiry check would
+// reject it anyway.
+type replayCache struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	maxSize int
+}
+
+func newReplayCache(maxSize int) *replayCache {
+	return &replayCache{seen: make(map[string]time.Time), maxSize: maxSize}
+}
+
+func (c *replayCache) checkAndStore(jti string, expiry time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if exp, ok := c.seen[jti]; ok && exp.After(now) {
+		return false
+	}
+
+	for k, exp := range c.seen {
+		if exp.Before(now) {
+			delete(c.seen, k)
+		}
+	}
+	if len(c.seen) >= c.maxSize {
+		var oldestKey string
+		var oldest time.Time
+		for k, exp := range c.seen {
+			if oldest.IsZero() || exp.Before(oldest) {
+				oldestKey, oldest = k, exp
+			}
+		}
+		if oldestKey != "" {
+			delete(c.seen, oldestKey)
+		}
+	}
+	c.seen[jti] = expiry
+	return true
+}
+
+// defaultReplayCacheSize bounds Verifier's jti cache when MaxReplayCacheSize
+// is left at zero.
+const defaultReplayCacheSize = 10000
+
+// Verifier checks relay auth tokens against a KeySet, rejecting expired,
+// not-yet-valid, badly signed, or replayed ones.
+type Verifier struct {
+	Keys *KeySet
+
+	// ClockSkew is how far past Expiry (or before NotBefore) a token is
+	// still accepted, absorbing clock drift between the token issuer and
+	// this relay. Defaults to 30s when zero.
+	ClockSkew time.Duration
+
+	// MaxReplayCacheSize bounds the jti replay cache; defaults to
+	// defaultReplayCacheSize when zero.
+	MaxReplayCacheSize int
+
+	once  sync.Once
+	cache *replayCache
+}
+
+func (v *Verifier) replay() *replayCache {
+	v.once.Do(func() {
+		size := v.MaxReplayCacheSize
+		if size <= 0 {
+			size = defaultReplayCacheSize
+		}
+		v.cache = newReplayCache(size)
+	})
+	return v.cache
+}
+
+func (v *Verifier) skew() time.Duration {
+	if v.ClockSkew <= 0 {
+		return 30 * time.Second
+	}
+	return v.ClockSkew
+}
+
+// Verify parses and checks token, returning its Claims once the signature,
+// validity window, and jti replay checks all pass.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	kid, claimsB64, sigB64, err := splitToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := v.Keys.Lookup(kid)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(sigB64)
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	if !verifySignature(key, []byte(claimsB64), sig) {
+		return nil, ErrBadSignature
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(claimsB64)
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	now := time.Now()
+	skew := v.skew()
+	if now.After(claims.expiry().Add(skew)) {
+		return nil, ErrExpired
+	}
+	if claims.NotBefore != 0 && now.Before(claims.notBefore().Add(-skew)) {
+		return nil, ErrNotYetValid
+	}
+	if !v.replay().checkAndStore(claims.JTI, claims.expiry().Add(skew)) {
+		return nil, ErrReplayed
+	}
+
+	return &claims, nil
+}
+
+// IssueToken signs claims with key, returning the wire-format token string
+// <kid>.<claims>.<sig> that Verify parses. It's a convenience for tests and
+// for whatever issues relay tokens (a CLI, a control-plane service) — the
+// relay itself only ever verifies.
+func IssueToken(key Key, claims Claims) (string, error) {
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	claimsB64 := base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	sig, err := sign(key, []byte(claimsB64))
+	if err != nil {
+		return "", err
+	}
+	sigB64 := base64.RawURLEncoding.EncodeToString(sig)
+	return key.ID + "." + claimsB64 + "." + sigB64, nil
+}
+
+func splitToken(token string) (kid, claimsB64, sigB64 string, err error) {
+	parts := strings.SplitN(token, ".", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", ErrMalformedToken
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+func sign(key Key, data []byte) ([]byte, error) {
+	switch key.Algorithm {
+	case AlgorithmHMACSHA256:
+		mac := hmac.New(sha256.New, key.Secret)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+	case AlgorithmEd25519:
+		if len(key.PrivateKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("relayauth: key %q has no private key to sign with", key.ID)
+		}
+		return ed25519.Sign(key.PrivateKey, data), nil
+	default:
+		return nil, fmt.Errorf("relayauth: key %q has unknown algorithm %q", key.ID, key.Algorithm)
+	}
+}
+
+func verifySignature(key Key, data, sig []byte) bool {
+	switch key.Algorithm {
+	case AlgorithmHMACSHA256:
+		mac := hmac.New(sha256.New, key.Secret)
+		mac.Write(data)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case AlgorithmEd25519:
+		if len(key.PublicKey) != ed25519.PublicKeySize {
+			return false
+		}
+		return ed25519.Verify(key.PublicKey, data, sig)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,369 @@
+// Package telemetry implements a columnar double-delta codec for
+// fixed-width telemetry records (timestamps, sensor channels, CAN IDs)
+// ahead of chunking, so the racetrack_factory profile (see
+// introspect.DomainTelemetry and transport.ProfileForDomain) doesn't pay to
+// encrypt and ship raw rows when most columns barely change between
+// samples.
+package telemetry
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ColumnType tells Codec whether a column's int64 values (see Record) are
+// used as-is (ColumnInt) or are the IEEE-754 bit pattern of a float64
+// (ColumnFloat, see EncodeFloat64/DecodeFloat64) — keeping the wire
+// representation integral even for float columns is what lets the
+// double-delta scheme apply uniformly and round-trip exactly, with no
+// float rounding.
+type ColumnType byte
+
+const (
+	ColumnInt ColumnType = iota
+	ColumnFloat
+)
+
+// ColumnScheme selects how a column's values are packed on the wire.
+type ColumnScheme byte
+
+const (
+	// SchemeDoubleDelta stores the first value verbatim, the second as a
+	// delta from the first, and every value after that as the
+	// delta-of-deltas from the previous delta — commonly zero for a
+	// steady sample rate or monotonic counter, so it zigzag/varint-encodes
+	// to a single byte. Intended for timestamps, sequence counters, and
+	// slowly varying analog channels (RPM, speed, coolant temp, ...).
+	SchemeDoubleDelta ColumnScheme = iota
+	// SchemeDictionaryRLE stores each distinct value once in a dictionary
+	// and the column as run-length-encoded dictionary indices. Intended
+	// for CAN IDs and other enum-like columns that hold a long run of one
+	// of a small set of values rather than drifting smoothly.
+	SchemeDictionaryRLE
+)
+
+// ColumnSpec describes one column of a Record: its type (so ColumnFloat
+// values round-trip exactly) and the packing scheme Encode should use for
+// it.
+type ColumnSpec struct {
+	Type   ColumnType
+	Scheme ColumnScheme
+}
+
+// Record is one fixed-width telemetry row, one int64 per Codec.Columns
+// entry. A ColumnFloat value must be produced with EncodeFloat64 before
+// being placed in Values, and read back with DecodeFloat64.
+type Record struct {
+	Values []int64
+}
+
+// EncodeFloat64 reinterprets v's IEEE-754 bits as an int64, for placing a
+// float64 sample into a ColumnFloat Record.Values entry without any loss
+// of precision.
+func EncodeFloat64(v float64) int64 {
+	return int64(math.Float64bits(v))
+}
+
+// DecodeFloat64 reverses EncodeFloat64.
+func DecodeFloat64(v int64) float64 {
+	return math.Float64frombits(uint64(v))
+}
+
+// Codec packs and unpacks a fixed layout of Columns. Both ends of a
+// transfer must already agree on Columns — the wire format Encode
+// produces only carries each column's type/scheme tag, not its name —
+// which NewCodec's caller typically derives from a source format's known
+// header (see chunker's telemetry wiring for the racetrack_factory CSV
+// case).
+type Codec struct {
+	Columns []ColumnSpec
+}
+
+// NewCodec returns a Codec for the given column layout.
+func NewCodec(columns []ColumnSpec) *Codec {
+	return &Codec{Columns: columns}
+}
+
+// Encode packs records into a self-describing byte stream: a column
+// count, each column's type+scheme tag, a record count, then each
+// column's values packed according to its scheme. Every record must have
+// exactly len(Columns) values.
+func (c *Codec) Encode(records []Record) ([]byte, error) {
+	for i, r := range records {
+		if len(r.Values) != len(c.Columns) {
+			return nil, fmt.Errorf("telemetry: record %d has %d columns, want %d", i, len(r.Values), len(c.Columns))
+		}
+	}
+
+	buf := make([]byte, 0, len(records)*len(c.Columns)*2)
+	buf = appendUvarint(buf, uint64(len(c.Columns)))
+	for _, col := range c.Columns {
+		buf = append(buf, byte(col.Type), byte(col.Scheme))
+	}
+	buf = appendUvarint(buf, uint64(len(records)))
+
+	column := make([]int64, len(records))
+	for colIdx, col := range c.Columns {
+		for i, r := range records {
+			column[i] = r.Values[colIdx]
+		}
+		switch col.Scheme {
+		case SchemeDoubleDelta:
+			buf = encodeDoubleDelta(buf, column)
+		case SchemeDictionaryRLE:
+			buf = encodeDictionaryRLE(buf, column)
+		default:
+			return nil, fmt.Errorf("telemetry: unknown column scheme %d", col.Scheme)
+		}
+	}
+	return buf, nil
+}
+
+// Decode reverses Encode, returning Records with Values deep-equal to
+// what was originally packed.
+func (c *Codec) Decode(data []byte) ([]Record, error) {
+	r := &byteReader{buf: data}
+
+	numCols, err := r.uvarint()
+	if err != nil {
+		return nil, fmt.Errorf("telemetry: read column count: %w", err)
+	}
+	cols := make([]ColumnSpec, numCols)
+	for i := range cols {
+		typ, ok := r.readByte()
+		if !ok {
+			return nil, fmt.Errorf("telemetry: truncated column %d type", i)
+		}
+		scheme, ok := r.readByte()
+		if !ok {
+			return nil, fmt.Errorf("telemetry: truncated column %d scheme", i)
+		}
+		cols[i] = ColumnSpec{Type: ColumnType(typ), Scheme: ColumnScheme(scheme)}
+	}
+
+	numRecords, err := r.uvarint()
+	if err != nil {
+		return nil, fmt.Errorf("telemetry: read record count: %w", err)
+	}
+
+	columnValues := make([][]int64, numCols)
+	for i, col := range cols {
+		var values []int64
+		var err error
+		switch col.Scheme {
+		case SchemeDoubleDelta:
+			values, err = decodeDoubleDelta(r, int(numRecords))
+		case SchemeDictionaryRLE:
+			values, err = decodeDictionaryRLE(r, int(numRecords))
+		default:
+			err = fmt.Errorf("unknown column scheme %d", col.Scheme)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("telemetry: decode column %d: %w", i, err)
+		}
+		columnValues[i] = values
+	}
+
+	records := make([]Record, numRecords)
+	for i := range records {
+		row := make([]int64, numCols)
+		for col := range cols {
+			row[col] = columnValues[col][i]
+		}
+		records[i] = Record{Values: row}
+	}
+	return records, nil
+}
+
+// encodeDoubleDelta appends values to buf as: the first value verbatim,
+// the second as a delta from the first, and each subsequent value as the
+// delta-of-deltas from the previous delta.
+func encodeDoubleDelta(buf []byte, values []int64) []byte {
+	if len(values) == 0 {
+		return buf
+	}
+	buf = appendVarint(buf, values[0])
+	if len(values) == 1 {
+		return buf
+	}
+	prevDelta := values[1] - values[0]
+	buf = appendVarint(buf, prevDelta)
+	prevValue := values[1]
+	for i := 2; i < len(values); i++ {
+		delta := values[i] - prevValue
+		buf = appendVarint(buf, delta-prevDelta)
+		prevDelta = delta
+		prevValue = values[i]
+	}
+	return buf
+}
+
+func decodeDoubleDelta(r *byteReader, n int) ([]int64, error) {
+	values := make([]int64, n)
+	if n == 0 {
+		return values, nil
+	}
+	v0, err := r.varint()
+	if err != nil {
+		return nil, fmt.Errorf("read first value: %w", err)
+	}
+	values[0] = v0
+	if n == 1 {
+		return values, nil
+	}
+	delta, err := r.varint()
+	if err != nil {
+		return nil, fmt.Errorf("read first delta: %w", err)
+	}
+	values[1] = values[0] + delta
+	prevDelta := delta
+	for i := 2; i < n; i++ {
+		dd, err := r.varint()
+		if err != nil {
+			return nil, fmt.Errorf("read delta-of-delta %d: %w", i, err)
+		}
+		delta := prevDelta + dd
+		values[i] = values[i-1] + delta
+		prevDelta = delta
+	}
+	return values, nil
+}
+
+// rleRun is one (dictionary index, repeat count) run in a
+// SchemeDictionaryRLE column.
+type rleRun struct {
+	index int
+	count int
+}
+
+func encodeDictionaryRLE(buf []byte, values []int64) []byte {
+	dict := make([]int64, 0, len(values))
+	indexOf := make(map[int64]int, len(values))
+	indices := make([]int, len(values))
+	for i, v := range values {
+		idx, ok := indexOf[v]
+		if !ok {
+			idx = len(dict)
+			indexOf[v] = idx
+			dict = append(dict, v)
+		}
+		indices[i] = idx
+	}
+
+	buf = appendUvarint(buf, uint64(len(dict)))
+	for _, v := range dict {
+		buf = appendVarint(buf, v)
+	}
+
+	var runs []rleRun
+	for _, idx := range indices {
+		if len(runs) > 0 && runs[len(runs)-1].index == idx {
+			runs[len(runs)-1].count++
+		} else {
+			runs = append(runs, rleRun{index: idx, count: 1})
+		}
+	}
+	buf = appendUvarint(buf, uint64(len(runs)))
+	for _, run := range runs {
+		buf = appendUvarint(buf, uint64(run.index))
+		buf = appendUvarint(buf, uint64(run.count))
+	}
+	return buf
+}
+
+func decodeDictionaryRLE(r *byteReader, n int) ([]int64, error) {
+	dictLen, err := r.uvarint()
+	if err != nil {
+		return nil, fmt.Errorf("read dictionary length: %w", err)
+	}
+	dict := make([]int64, dictLen)
+	for i := range dict {
+		v, err := r.varint()
+		if err != nil {
+			return nil, fmt.Errorf("read dictionary entry %d: %w", i, err)
+		}
+		dict[i] = v
+	}
+
+	numRuns, err := r.uvarint()
+	if err != nil {
+		return nil, fmt.Errorf("read run count: %w", err)
+	}
+	values := make([]int64, 0, n)
+	for i := uint64(0); i < numRuns; i++ {
+		idx, err := r.uvarint()
+		if err != nil {
+			return nil, fmt.Errorf("read run %d index: %w", i, err)
+		}
+		if int(idx) >= len(dict) {
+			return nil, fmt.Errorf("run %d references out-of-range dictionary index %d", i, idx)
+		}
+		count, err := r.uvarint()
+		if err != nil {
+			return nil, fmt.Errorf("read run %d count: %w", i, err)
+		}
+		for j := uint64(0); j < count; j++ {
+			values = append(values, dict[idx])
+		}
+	}
+	if len(values) != n {
+		return nil, fmt.Errorf("decoded %d values, want %d", len(values), n)
+	}
+	return values, nil
+}
+
+// zigzagEncode maps a signed int64 to an unsigned one so small-magnitude
+// negative deltas (as common as positive ones in a double-delta column)
+// still varint-encode to a single byte.
+func zigzagEncode(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func zigzagDecode(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	return appendUvarint(buf, zigzagEncode(v))
+}
+
+// byteReader is a minimal cursor over an encoded buffer, shared by Decode
+// and the per-scheme decoders.
+type byteReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *byteReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *byteReader) varint() (int64, error) {
+	v, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	return zigzagDecode(v), nil
+}
+
+func (r *byteReader) readByte() (byte, bool) {
+	if r.pos >= len(r.buf) {
+		return 0, false
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, true
+}
@@ -0,0 +1,135 @@
+package telemetry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodecDoubleDeltaRoundTrip(t *testing.T) {
+	codec := NewCodec([]ColumnSpec{{Type: ColumnInt, Scheme: SchemeDoubleDelta}})
+	records := []Record{
+		{Values: []int64{1000}},
+		{Values: []int64{1010}},
+		{Values: []int64{1020}},
+		{Values: []int64{1031}},
+		{Values: []int64{1041}},
+	}
+
+	data, err := codec.Encode(records)
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	got, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, records) {
+		t.Errorf("Decode() = %+v, want %+v", got, records)
+	}
+}
+
+func TestCodecFloatColumnExact(t *testing.T) {
+	codec := NewCodec([]ColumnSpec{{Type: ColumnFloat, Scheme: SchemeDoubleDelta}})
+	want := []float64{98.6, 98.61, 98.7, 99.123456789, 0, -12.5}
+	records := make([]Record, len(want))
+	for i, v := range want {
+		records[i] = Record{Values: []int64{EncodeFloat64(v)}}
+	}
+
+	data, err := codec.Encode(records)
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	got, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	for i, r := range got {
+		if DecodeFloat64(r.Values[0]) != want[i] {
+			t.Errorf("record %d = %v, want %v", i, DecodeFloat64(r.Values[0]), want[i])
+		}
+	}
+}
+
+func TestCodecDictionaryRLERoundTrip(t *testing.T) {
+	codec := NewCodec([]ColumnSpec{{Type: ColumnInt, Scheme: SchemeDictionaryRLE}})
+	canIDs := []int64{0x100, 0x100, 0x100, 0x200, 0x200, 0x101, 0x100, 0x100}
+	records := make([]Record, len(canIDs))
+	for i, v := range canIDs {
+		records[i] = Record{Values: []int64{v}}
+	}
+
+	data, err := codec.Encode(records)
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	got, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, records) {
+		t.Errorf("Decode() = %+v, want %+v", got, records)
+	}
+}
+
+func TestCodecMixedColumnsRoundTrip(t *testing.T) {
+	codec := NewCodec([]ColumnSpec{
+		{Type: ColumnInt, Scheme: SchemeDoubleDelta},
+		{Type: ColumnInt, Scheme: SchemeDictionaryRLE},
+		{Type: ColumnFloat, Scheme: SchemeDoubleDelta},
+	})
+	records := []Record{
+		{Values: []int64{1000, 0x100, EncodeFloat64(7200.0)}},
+		{Values: []int64{1001, 0x100, EncodeFloat64(7201.5)}},
+		{Values: []int64{1002, 0x200, EncodeFloat64(7199.25)}},
+		{Values: []int64{1003, 0x200, EncodeFloat64(7203.0)}},
+	}
+
+	data, err := codec.Encode(records)
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	got, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, records) {
+		t.Errorf("Decode() = %+v, want %+v", got, records)
+	}
+}
+
+func TestCodecEmptyAndSingleRecord(t *testing.T) {
+	codec := NewCodec([]ColumnSpec{{Type: ColumnInt, Scheme: SchemeDoubleDelta}})
+
+	data, err := codec.Encode(nil)
+	if err != nil {
+		t.Fatalf("Encode(nil) failed: %v", err)
+	}
+	got, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Decode() of empty input = %+v, want empty", got)
+	}
+
+	single := []Record{{Values: []int64{42}}}
+	data, err = codec.Encode(single)
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	got, err = codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, single) {
+		t.Errorf("Decode() = %+v, want %+v", got, single)
+	}
+}
+
+func TestCodecEncodeRejectsWrongColumnCount(t *testing.T) {
+	codec := NewCodec([]ColumnSpec{{Type: ColumnInt, Scheme: SchemeDoubleDelta}})
+	if _, err := codec.Encode([]Record{{Values: []int64{1, 2}}}); err == nil {
+		t.Error("Encode() with mismatched column count succeeded, want error")
+	}
+}
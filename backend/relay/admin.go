@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quic-go/quic-go"
+)
+
+// requireAdminToken gates every /admin endpoint behind the X-Admin-Token
+// header, the same shared-secret pattern authn.StaticTokenAuthenticator
+// uses for the daemon's API servers. An unset RelayConfig.AdminToken
+// disables /admin entirely (404) rather than accepting an empty token.
+func (rs *RelayService) requireAdminToken(w http.ResponseWriter, r *http.Request) bool {
+	if rs.config.AdminToken == "" {
+		http.Error(w, "admin endpoints disabled: start the relay with --admin-token", http.StatusNotFound)
+		return false
+	}
+	if r.Header.Get("X-Admin-Token") != rs.config.AdminToken {
+		http.Error(w, "invalid or missing X-Admin-Token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// registerConn assigns conn a connID and tracks it, plus a fresh
+// ConnStats, under rs.connsMu so /admin/kick/{connID} and /admin/conns
+// can find them later. The caller's deferred unregisterConn(connID)
+// removes both entries once the connection's handleConnection goroutine
+// returns.
+func (rs *RelayService) registerConn(conn *quic.Conn) string {
+	connID := uuid.New().String()
+	rs.connsMu.Lock()
+	rs.conns[connID] = conn
+	rs.connStats[connID] = newConnStats(connID, conn.RemoteAddr().String())
+	rs.connsMu.Unlock()
+	return connID
+}
+
+// unregisterConn drops connID from rs.conns and rs.connStats once its
+// connection has closed, so /admin/kick/{connID} and /admin/conns never
+// see a stale entry.
+func (rs *RelayService) unregisterConn(connID string) {
+	rs.connsMu.Lock()
+	delete(rs.conns, connID)
+	delete(rs.connStats, connID)
+	rs.connsMu.Unlock()
+}
+
+// connStatsFor looks up connID's ConnStats, or nil if the connection has
+// already been unregistered (e.g. a straggling copy goroutine finishing
+// its last Write just as the connection tears down).
+func (rs *RelayService) connStatsFor(connID string) *ConnStats {
+	rs.connsMu.Lock()
+	defer rs.connsMu.Unlock()
+	return rs.connStats[connID]
+}
+
+// handleAdminConns lists every active connection's ConnStats: remote and
+// target address, when it started, and bytes forwarded each way. Same
+// per-connection totals RelayMetrics.BytesForwardedTotal aggregates
+// across the whole relay.
+func (rs *RelayService) handleAdminConns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !rs.requireAdminToken(w, r) {
+		return
+	}
+
+	rs.connsMu.Lock()
+	statsList := make([]*ConnStats, 0, len(rs.connStats))
+	for _, cs := range rs.connStats {
+		statsList = append(statsList, cs)
+	}
+	rs.connsMu.Unlock()
+
+	type connReport struct {
+		ConnID       string    `json:"conn_id"`
+		RemoteAddr   string    `json:"remote_addr"`
+		TargetAddr   string    `json:"target_addr"`
+		StartedAt    time.Time `json:"started_at"`
+		ForwardBytes int64     `json:"forward_bytes"`
+		ReverseBytes int64     `json:"reverse_bytes"`
+	}
+	reports := make([]connReport, 0, len(statsList))
+	for _, cs := range statsList {
+		targetAddr, forwardBytes, reverseBytes := cs.Snapshot()
+		reports = append(reports, connReport{
+			ConnID:       cs.ConnID,
+			RemoteAddr:   cs.RemoteAddr,
+			TargetAddr:   targetAddr,
+			StartedAt:    cs.StartedAt,
+			ForwardBytes: forwardBytes,
+			ReverseBytes: reverseBytes,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
+
+// handleAdminDrain stops the accept loop from handing out new connections
+// and closes the QUIC listener so it unblocks out of Accept, then waits
+// (up to an optional ?timeout_seconds=N, default 30) for connections
+// already in flight to finish on their own, mirroring the "drain before
+// restart" pattern production relays use to roll hosts without cutting
+// active large-file transfers.
+func (rs *RelayService) handleAdminDrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !rs.requireAdminToken(w, r) {
+		return
+	}
+
+	atomic.StoreInt32(&rs.draining, 1)
+	if rs.listener != nil {
+		rs.listener.Close()
+	}
+	log.Printf("Admin draining relay, waiting for active connections to finish")
+
+	timeout := 30 * time.Second
+	if v := r.URL.Query().Get("timeout_seconds"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			timeout = time.Duration(secs) * time.Second
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		rs.acceptWG.Wait()
+		close(done)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	select {
+	case <-done:
+		json.NewEncoder(w).Encode(map[string]string{"status": "drained"})
+	case <-time.After(timeout):
+		json.NewEncoder(w).Encode(map[string]string{"status": "draining"})
+	}
+}
+
+// handleAdminKick force-closes a single connection by the connID
+// registerConn assigned it, without disturbing any other session. The
+// connID is the path segment after /admin/kick/.
+func (rs *RelayService) handleAdminKick(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !rs.requireAdminToken(w, r) {
+		return
+	}
+
+	connID := strings.TrimPrefix(r.URL.Path, "/admin/kick/")
+	if connID == "" {
+		http.Error(w, "missing connID", http.StatusBadRequest)
+		return
+	}
+
+	rs.connsMu.Lock()
+	conn, ok := rs.conns[connID]
+	rs.connsMu.Unlock()
+	if !ok {
+		http.Error(w, "no such connection", http.StatusNotFound)
+		return
+	}
+
+	conn.CloseWithError(1, "kicked by admin")
+	log.Printf("Admin kicked connection %s", connID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "kicked", "conn_id": connID})
+}
+
+// handleAdminReload re-reads the policy file and, if configured, the
+// relayauth keyset in place, the same re-read-without-restart behavior
+// Start already gives SIGHUP. The relay's TLS certificate is a fresh
+// self-signed one generated per process (see generateRelayTLSConfig), so
+// there is no cert file to re-read; reload only touches policy and auth
+// keys.
+func (rs *RelayService) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !rs.requireAdminToken(w, r) {
+		return
+	}
+
+	if err := rs.policy.ReloadFile(rs.config.PolicyFile); err != nil {
+		http.Error(w, "failed to reload policy: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rs.authVerifier != nil {
+		if err := rs.authVerifier.Keys.ReloadFile(rs.config.AuthKeysFile); err != nil {
+			http.Error(w, "failed to reload auth keys: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	log.Printf("Admin reloaded policy file %q", rs.config.PolicyFile)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "reloaded"})
+}
+
+// adminLimits is the GET/POST body for /admin/limits: the live-tunable
+// subset of RelayConfig plus the connLimiter's rate/burst. POST fields
+// are all optional pointers so a partial body only changes what it sets.
+type adminLimits struct {
+	MaxConnections   *int64   `json:"max_connections,omitempty"`
+	StreamBufferSize *int64   `json:"stream_buffer_size,omitempty"`
+	ConnRate         *float64 `json:"conn_rate,omitempty"`
+	ConnBurst        *int     `json:"conn_burst,omitempty"`
+}
+
+// handleAdminLimits serves GET (current live limits) and POST (mutate
+// MaxConnections, StreamBufferSize, and the connLimiter rate/burst at
+// runtime) on /admin/limits.
+func (rs *RelayService) handleAdminLimits(w http.ResponseWriter, r *http.Request) {
+	if !rs.requireAdminToken(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		maxConn := atomic.LoadInt64(&rs.maxConnections)
+		bufSize := atomic.LoadInt64(&rs.streamBufferSize)
+		rate, burst := rs.connLimiter.Snapshot()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(adminLimits{
+			MaxConnections:   &maxConn,
+			StreamBufferSize: &bufSize,
+			ConnRate:         &rate,
+			ConnBurst:        &burst,
+		})
+	case http.MethodPost:
+		var req adminLimits
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if req.MaxConnections != nil {
+			atomic.StoreInt64(&rs.maxConnections, *req.MaxConnections)
+		}
+		if req.StreamBufferSize != nil {
+			atomic.StoreInt64(&rs.streamBufferSize, *req.StreamBufferSize)
+		}
+		if req.ConnRate != nil || req.ConnBurst != nil {
+			rate, burst := rs.connLimiter.Snapshot()
+			if req.ConnRate != nil {
+				rate = *req.ConnRate
+			}
+			if req.ConnBurst != nil {
+				burst = *req.ConnBurst
+			}
+			rs.connLimiter.SetRate(rate, burst)
+		}
+		log.Printf("Admin updated relay limits: %+v", req)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// errChaosForcedClose is returned by chaosWriter.Write when the injector
+// rolls ForceCloseProbability; forwardStream reports it the same as any
+// other stream copy error, ending that direction's copy goroutine.
+var errChaosForcedClose = errors.New("relay: chaos injector forced stream close")
+
+// ChaosConfig tunes ChaosInjector's behavior, mirroring the shape of
+// transport.FaultInjectorConfig and server.HTTPFaultConfig but for the
+// relay's byte-forwarding path instead of QUIC stream opens or whole HTTP
+// responses. Every rate is an independent per-call probability in [0, 1];
+// a zero-value ChaosConfig disables every fault.
+type ChaosConfig struct {
+	// Seed makes the injected fault sequence reproducible across runs;
+	// 0 falls back to a time-seeded RNG.
+	Seed int64 `json:"seed"`
+
+	// DropRate is the chance forwardStream's chaosWriter silently
+	// discards a Write's bytes instead of forwarding them, simulating
+	// packet loss for whatever retry/retransmit logic (e.g. the
+	// receiver's ChunkBitmap) is supposed to recover from it.
+	DropRate float64 `json:"drop_rate"`
+
+	// ExtraLatencyMinMs/ExtraLatencyMaxMs add a uniformly distributed
+	// delay before each forwarded Write; both zero disables it.
+	ExtraLatencyMinMs int64 `json:"extra_latency_min_ms"`
+	ExtraLatencyMaxMs int64 `json:"extra_latency_max_ms"`
+
+	// JitterMs adds a further independent random delay on top of the
+	// extra-latency range, per stream, so sibling streams on the same
+	// connection don't all delay by the same amount.
+	JitterMs int64 `json:"jitter_ms"`
+
+	// ForceCloseProbability is the chance a forwarded Write fails with
+	// errChaosForcedClose instead of reaching the real destination.
+	ForceCloseProbability float64 `json:"force_close_probability"`
+
+	// DialFailureProbability is the chance handleConnection reports
+	// TARGET_UNREACHABLE without ever dialing the target.
+	DialFailureProbability float64 `json:"dial_failure_probability"`
+}
+
+// ChaosInjector wraps the relay's forwarding path with reproducible chaos
+// so integration tests can exercise retry/backoff behavior against
+// adverse conditions without an actually unstable network, the same role
+// transport.FaultInjector and server.HTTPFaultInjector play for the chunk
+// sender and the REST control plane. A nil *ChaosInjector (the default,
+// relay started without --chaos) disables it entirely; every method is a
+// no-op on a nil receiver so call sites don't need their own nil checks.
+type ChaosInjector struct {
+	mu  sync.RWMutex
+	cfg ChaosConfig
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+}
+
+// NewChaosInjector creates an injector with a zero-value (fully disabled)
+// ChaosConfig; Configure (or the /chaos endpoint) turns faults on.
+func NewChaosInjector() *ChaosInjector {
+	return &ChaosInjector{rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Configure replaces the injector's active configuration and reseeds its
+// RNG from cfg.Seed (or the current time if cfg.Seed is 0), so a freshly
+// posted seed takes effect immediately rather than continuing the
+// previous run's sequence.
+func (c *ChaosInjector) Configure(cfg ChaosConfig) {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	c.mu.Lock()
+	c.cfg = cfg
+	c.mu.Unlock()
+	c.rngMu.Lock()
+	c.rng = rand.New(rand.NewSource(seed))
+	c.rngMu.Unlock()
+}
+
+// Snapshot returns the injector's current configuration.
+func (c *ChaosInjector) Snapshot() ChaosConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cfg
+}
+
+func (c *ChaosInjector) roll(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	c.rngMu.Lock()
+	defer c.rngMu.Unlock()
+	return c.rng.Float64() < probability
+}
+
+func (c *ChaosInjector) extraDelay(cfg ChaosConfig) time.Duration {
+	min := time.Duration(cfg.ExtraLatencyMinMs) * time.Millisecond
+	max := time.Duration(cfg.ExtraLatencyMaxMs) * time.Millisecond
+	d := min
+	c.rngMu.Lock()
+	if max > min {
+		d += time.Duration(c.rng.Int63n(int64(max - min)))
+	}
+	if cfg.JitterMs > 0 {
+		d += time.Duration(c.rng.Int63n(cfg.JitterMs)) * time.Millisecond
+	}
+	c.rngMu.Unlock()
+	return d
+}
+
+// ShouldFailDial rolls DialFailureProbability for handleConnection, so it
+// can report TARGET_UNREACHABLE without ever dialing the real target.
+func (c *ChaosInjector) ShouldFailDial() bool {
+	if c == nil {
+		return false
+	}
+	return c.roll(c.Snapshot().DialFailureProbability)
+}
+
+// wrap returns dst wrapped in a chaosWriter applying c's configured
+// loss/latency/forced-close faults, or dst unchanged if c is nil.
+func (c *ChaosInjector) wrap(dst io.Writer) io.Writer {
+	if c == nil {
+		return dst
+	}
+	return &chaosWriter{dst: dst, chaos: c}
+}
+
+// chaosWriter is the "intermediate reader/writer" forwardStream interposes
+// between copyBuffer and the real destination stream.
+type chaosWriter struct {
+	dst   io.Writer
+	chaos *ChaosInjector
+}
+
+func (w *chaosWriter) Write(p []byte) (int, error) {
+	cfg := w.chaos.Snapshot()
+	if w.chaos.roll(cfg.ForceCloseProbability) {
+		return 0, errChaosForcedClose
+	}
+	if d := w.chaos.extraDelay(cfg); d > 0 {
+		time.Sleep(d)
+	}
+	if w.chaos.roll(cfg.DropRate) {
+		// The peer never sees these bytes, same as a lossy link; report
+		// them as written so copyBuffer's caller sees a clean EOF rather
+		// than a copy error when the stream is otherwise healthy.
+		return len(p), nil
+	}
+	return w.dst.Write(p)
+}
+
+// handleChaos serves GET (current ChaosConfig) and POST (replace it) on
+// /chaos, letting integration tests dial in loss/latency/force-close/
+// dial-failure rates and an RNG seed at runtime instead of restarting the
+// relay with different flags.
+func (rs *RelayService) handleChaos(w http.ResponseWriter, r *http.Request) {
+	if rs.chaos == nil {
+		http.Error(w, "chaos injection disabled: start the relay with --chaos", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rs.chaos.Snapshot())
+	case http.MethodPost:
+		var cfg ChaosConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		rs.chaos.Configure(cfg)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/quantarax/backend/daemon/transport/dtn"
+)
+
+// handleDTNBundle accepts a bundle-protocol Bundle POSTed by a sender
+// running in DTN fallback mode (see service.SendWithOrchestration) and
+// spools it locally for later opportunistic forwarding toward the
+// recipient, the same store-and-forward hop a sender's own
+// dtn.Forwarder performs. A 404 means rs.dtnSpool isn't configured
+// (RelayConfig.DTNSpoolPath unset), the same disabled-by-default posture
+// requireAdminToken gives /admin.
+func (rs *RelayService) handleDTNBundle(w http.ResponseWriter, r *http.Request) {
+	if rs.dtnSpool == nil {
+		http.Error(w, "dtn bundle endpoint disabled: start the relay with --dtn-spool", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var b dtn.Bundle
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid bundle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if b.SessionID == "" {
+		http.Error(w, "bundle missing session_id", http.StatusBadRequest)
+		return
+	}
+	if b.Expired(time.Now()) {
+		// Already past TTL: accept the request but don't spool it, and
+		// don't accept custody of something we'd just expire ourselves.
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]bool{"custody_accepted": false})
+		return
+	}
+
+	if err := rs.dtnSpool.Put(&b); err != nil {
+		http.Error(w, "failed to spool bundle: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Accepting custody here means this relay has taken responsibility
+	// for delivering the bundle onward; the originator is free to drop
+	// its own copy as soon as it sees custody_accepted == true.
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]bool{"custody_accepted": b.Custody})
+}
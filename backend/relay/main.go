@@ -12,16 +12,20 @@ import (
 	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/quic-go/quic-go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/quantarax/backend/daemon/transport/dtn"
 	"github.com/quantarax/backend/internal/observability"
 	"github.com/quantarax/backend/internal/quicutil"
 	"github.com/quantarax/backend/internal/ratelimit"
+	"github.com/quantarax/backend/internal/relayauth"
 	"github.com/quantarax/backend/internal/validation"
+	"github.com/quic-go/quic-go"
 	"go.opentelemetry.io/otel"
 )
 
@@ -33,18 +37,134 @@ type RelayConfig struct {
 	StreamBufferSize int
 	AuthMode         string
 	LogLevel         string
+	PolicyFile       string
+
+	// StunServers is the configurable STUN server list (host:port, RFC
+	// 5389) endpoints use to learn their own public address for the
+	// NAT-traversal upgrade handshake (see attemptDirectUpgrade). Empty
+	// disables the upgrade path entirely.
+	StunServers []string
+
+	// ChaosEnabled starts the relay with a ChaosInjector (see chaos.go),
+	// reachable at /chaos for runtime configuration. It ships disabled
+	// (zero-value ChaosConfig) until a test configures it.
+	ChaosEnabled bool
+
+	// AuthKeysFile, when set, points validateToken at a relayauth.KeySet
+	// loaded from this JSON file instead of the simple policy-file token
+	// check, and is re-read on SIGHUP (see Start).
+	AuthKeysFile string
+
+	// AdminToken gates the /admin endpoints (see admin.go) behind an
+	// X-Admin-Token header check, the same shared-secret pattern
+	// authn.StaticTokenAuthenticator uses. Empty disables /admin entirely.
+	AdminToken string
+
+	// DTNSpoolPath, when set, starts a BoltDB-backed dtn.Spool at this path
+	// and serves POST /dtn/bundle (see dtn.go) so senders running in
+	// bundle-mode fallback (chunker.DTNProfile) can hand this relay custody
+	// of chunks it can't deliver directly. Empty disables /dtn/bundle
+	// entirely, the same as AdminToken disabling /admin.
+	DTNSpoolPath string
 }
 
 // RelayService manages QUIC relay forwarding
 type RelayService struct {
-	config            *RelayConfig
-	activeConnections int64
-	totalConnections  int64
-	bytesForwarded    int64
+	config               *RelayConfig
+	policy               *RelayPolicy
+	activeConnections    int64
+	totalConnections     int64
+	bytesForwarded       int64
+	forbiddenConnections int64
+
+	// Counters for the NAT-traversal upgrade path (see upgrade.go).
+	directUpgradeSuccessTotal int64
+	directUpgradeFailTotal    int64
+
+	// chaos is nil unless RelayConfig.ChaosEnabled is set, in which case
+	// it's always non-nil and starts out fully disabled (see chaos.go).
+	chaos *ChaosInjector
+
+	// authVerifier is nil unless RelayConfig.AuthKeysFile is set, in which
+	// case validateToken verifies signed tokens against it instead of
+	// falling back to the policy file's plain token check.
+	authVerifier *relayauth.Verifier
+
+	// listener is the active QUIC listener, stored so the admin drain
+	// handler can stop accepting new connections without canceling ctx
+	// (which would also tear down every connection already in flight).
+	listener *quic.Listener
+
+	// connLimiter rate-limits new connection acceptance; its rate/burst
+	// are live-tunable via POST /admin/limits.
+	connLimiter *ratelimit.TokenBucket
+
+	// maxConnections and streamBufferSize mirror the same-named
+	// RelayConfig fields but are mutable at runtime via
+	// POST /admin/limits; config's values are only the starting point.
+	maxConnections   int64
+	streamBufferSize int64
+
+	// draining is set by POST /admin/drain: the accept loop stops handing
+	// out new connections once it's non-zero, while connections already
+	// in flight (tracked by acceptWG) run to completion.
+	draining int32
+	acceptWG sync.WaitGroup
+
+	// conns tracks every active connection by a generated connID, for
+	// POST /admin/kick/{connID} to find and close one without touching
+	// the others. connStats holds the matching per-connection byte
+	// counters GET /admin/conns and RelayMetrics report from (see
+	// metrics.go); both maps share connsMu and a connID's lifetime.
+	connsMu   sync.Mutex
+	conns     map[string]*quic.Conn
+	connStats map[string]*ConnStats
+
+	// metrics holds the relay's Prometheus collectors, served at
+	// /metrics/prom alongside the legacy JSON /metrics blob.
+	metrics *RelayMetrics
+
+	// dtnSpool is nil unless RelayConfig.DTNSpoolPath is set, in which
+	// case it backs POST /dtn/bundle (see dtn.go).
+	dtnSpool *dtn.Spool
 }
 
 func NewRelayService(config *RelayConfig) *RelayService {
-	return &RelayService{config: config}
+	policy, err := LoadRelayPolicy(config.PolicyFile)
+	if err != nil {
+		log.Printf("Failed to load relay policy file %q, falling back to permit-all: %v", config.PolicyFile, err)
+		policy = NewRelayPolicy(RelayPolicyConfig{})
+	}
+	rs := &RelayService{
+		config:           config,
+		policy:           policy,
+		connLimiter:      ratelimit.NewTokenBucket(200, 400), // 200 conn/s, burst 400
+		maxConnections:   int64(config.MaxConnections),
+		streamBufferSize: int64(config.StreamBufferSize),
+		conns:            make(map[string]*quic.Conn),
+		connStats:        make(map[string]*ConnStats),
+		metrics:          NewRelayMetrics(),
+	}
+	if config.ChaosEnabled {
+		rs.chaos = NewChaosInjector()
+	}
+	if config.AuthKeysFile != "" {
+		keys, err := relayauth.LoadKeySetFile(config.AuthKeysFile)
+		if err != nil {
+			log.Printf("Failed to load auth keys file %q, token verification will reject every token: %v", config.AuthKeysFile, err)
+			keys = relayauth.NewKeySet(nil)
+		}
+		rs.authVerifier = &relayauth.Verifier{Keys: keys}
+	}
+	if config.DTNSpoolPath != "" {
+		spool, err := dtn.OpenSpool(config.DTNSpoolPath)
+		if err != nil {
+			log.Printf("Failed to open DTN bundle spool %q, /dtn/bundle will be disabled: %v", config.DTNSpoolPath, err)
+		} else {
+			rs.dtnSpool = spool
+		}
+	}
+	return rs
 }
 
 // Start begins the relay service
@@ -59,12 +179,10 @@ func (rs *RelayService) Start() error {
 	quicConfig := &quic.Config{MaxIdleTimeout: 30 * time.Second, KeepAlivePeriod: 10 * time.Second}
 
 	listener, err := quic.ListenAddr(rs.config.ListenAddr, tlsConfig, quicConfig)
-	// Apply simple rate limiter for new connections
-	connLimiter := ratelimit.NewTokenBucket(200, 400) // 200 conn/s, burst 400
-	_ = connLimiter
 	if err != nil {
 		return fmt.Errorf("failed to start QUIC listener: %w", err)
 	}
+	rs.listener = listener
 
 	log.Printf("Relay service listening on %s", rs.config.ListenAddr)
 	log.Printf("Max connections: %d", rs.config.MaxConnections)
@@ -87,12 +205,31 @@ func (rs *RelayService) Start() error {
 		listener.Close()
 	}()
 
+	// Reload the auth keyset on SIGHUP instead of restarting, so rotating
+	// the signing key doesn't drop in-flight relay sessions.
+	if rs.config.AuthKeysFile != "" {
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		go func() {
+			for range hupChan {
+				if err := rs.authVerifier.Keys.ReloadFile(rs.config.AuthKeysFile); err != nil {
+					log.Printf("Failed to reload auth keys file %q, keeping previous keys: %v", rs.config.AuthKeysFile, err)
+					continue
+				}
+				log.Printf("Reloaded auth keys file %q", rs.config.AuthKeysFile)
+			}
+		}()
+	}
+
 	// Accept connections
 	for {
-		// if !connLimiter.Allow(1) { time.Sleep(5 * time.Millisecond); continue }
+		if !rs.connLimiter.Allow(1) {
+			time.Sleep(5 * time.Millisecond)
+			continue
+		}
 		conn, err := listener.Accept(ctx)
 		if err != nil {
-			if ctx.Err() != nil {
+			if ctx.Err() != nil || atomic.LoadInt32(&rs.draining) != 0 {
 				log.Println("Relay service shutting down...")
 				break
 			}
@@ -100,9 +237,14 @@ func (rs *RelayService) Start() error {
 			continue
 		}
 
+		if atomic.LoadInt32(&rs.draining) != 0 {
+			conn.CloseWithError(1, "relay draining")
+			continue
+		}
+
 		// Check connection limit
 		active := atomic.LoadInt64(&rs.activeConnections)
-		if active >= int64(rs.config.MaxConnections) {
+		if active >= atomic.LoadInt64(&rs.maxConnections) {
 			log.Printf("Connection limit reached (%d), rejecting connection", active)
 			conn.CloseWithError(1, "connection limit exceeded")
 			continue
@@ -110,23 +252,32 @@ func (rs *RelayService) Start() error {
 
 		atomic.AddInt64(&rs.activeConnections, 1)
 		atomic.AddInt64(&rs.totalConnections, 1)
+		rs.metrics.ConnectionsActive.Set(float64(atomic.LoadInt64(&rs.activeConnections)))
+		rs.metrics.ConnectionsTotal.Inc()
 
 		log.Printf("Accepted connection from %s (active: %d)", conn.RemoteAddr(), active+1)
 
-		go rs.handleConnection(ctx, conn)
+		connID := rs.registerConn(conn)
+		rs.acceptWG.Add(1)
+		go func() {
+			defer rs.acceptWG.Done()
+			defer rs.unregisterConn(connID)
+			rs.handleConnection(ctx, conn, connID)
+		}()
 	}
 
 	return nil
 }
 
 // handleConnection manages a relay connection
-func (rs *RelayService) handleConnection(ctx context.Context, sourceConn *quic.Conn) {
+func (rs *RelayService) handleConnection(ctx context.Context, sourceConn *quic.Conn, connID string) {
 	tr := otel.Tracer("quantarax-relay")
 	ctx, span := tr.Start(ctx, "relay.handleConnection")
 	defer span.End()
 
 	defer func() {
 		atomic.AddInt64(&rs.activeConnections, -1)
+		rs.metrics.ConnectionsActive.Set(float64(atomic.LoadInt64(&rs.activeConnections)))
 		sourceConn.CloseWithError(0, "relay closing")
 	}()
 
@@ -150,8 +301,12 @@ func (rs *RelayService) handleConnection(ctx context.Context, sourceConn *quic.C
 	targetAddr := string(targetAddrBuf[:n])
 
 	log.Printf("Relay target: %s", targetAddr)
+	if stats := rs.connStatsFor(connID); stats != nil {
+		stats.setTarget(targetAddr)
+	}
 
 	// Validate authentication if enabled
+	var principal *RelayPrincipal
 	if rs.config.AuthMode != "none" {
 		// Read auth token
 		tokenBuf := make([]byte, 256)
@@ -162,19 +317,45 @@ func (rs *RelayService) handleConnection(ctx context.Context, sourceConn *quic.C
 		}
 		token := string(tokenBuf[:n])
 
-		if !rs.validateToken(token) {
+		p, ok := rs.validateToken(token)
+		if !ok {
 			log.Printf("Invalid auth token from %s", sourceConn.RemoteAddr())
+			rs.metrics.AuthFailuresTotal.Inc()
 			controlStream.Write([]byte("AUTH_FAILED"))
 			return
 		}
+		principal = p
+	}
+
+	// Match the target against the policy's allow/deny rules and, if
+	// authenticated, the principal's scope and quotas, before ever dialing it.
+	if err := rs.policy.CheckTarget(principal, targetAddr); err != nil {
+		atomic.AddInt64(&rs.forbiddenConnections, 1)
+		log.Printf("Target %s forbidden by relay policy for %s: %v", targetAddr, sourceConn.RemoteAddr(), err)
+		controlStream.Write([]byte("RELAY_FORBIDDEN"))
+		return
+	}
+	if principal != nil {
+		rs.policy.AcquireConnection(principal.TokenID)
+		defer rs.policy.ReleaseConnection(principal.TokenID)
+	}
+
+	if rs.chaos.ShouldFailDial() {
+		log.Printf("Chaos: injected dial failure for target %s", targetAddr)
+		rs.metrics.TargetDialFailuresTotal.WithLabelValues("chaos_injected").Inc()
+		controlStream.Write([]byte("TARGET_UNREACHABLE"))
+		return
 	}
 
 	// Establish connection to target
 	// When dialing the target receiver, use the direct transfer ALPN so it matches quic_recv
 	targetTLSConfig := &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quantarax-quic"}, ServerName: ""}
+	dialStart := time.Now()
 	targetConn, err := quic.DialAddr(ctx, targetAddr, targetTLSConfig, &quic.Config{MaxIdleTimeout: 30 * time.Second})
+	rs.metrics.TargetDialSeconds.Observe(time.Since(dialStart).Seconds())
 	if err != nil {
 		log.Printf("Failed to connect to target %s: %v", targetAddr, err)
+		rs.metrics.TargetDialFailuresTotal.WithLabelValues("dial_error").Inc()
 		controlStream.Write([]byte("TARGET_UNREACHABLE"))
 		return
 	}
@@ -194,19 +375,29 @@ func (rs *RelayService) handleConnection(ctx context.Context, sourceConn *quic.C
 	connCtx, connCancel := context.WithCancel(ctx)
 	defer connCancel()
 
+	if len(rs.config.StunServers) > 0 {
+		go rs.attemptDirectUpgrade(connCtx, sourceConn, targetConn, controlStream)
+	}
+
 	var wg sync.WaitGroup
 
 
 	wg.Add(1)
-	go func() { defer wg.Done(); rs.forwardStreams(connCtx, sourceConn, targetConn, "source->target") }()
+	go func() {
+		defer wg.Done()
+		rs.forwardStreams(connCtx, sourceConn, targetConn, "source->target", principal, connID)
+	}()
 	wg.Add(1)
-	go func() { defer wg.Done(); rs.forwardStreams(connCtx, targetConn, sourceConn, "target->source") }()
+	go func() {
+		defer wg.Done()
+		rs.forwardStreams(connCtx, targetConn, sourceConn, "target->source", principal, connID)
+	}()
 	wg.Wait()
 	log.Printf("Relay session completed for %s", targetAddr)
 }
 
 // forwardStreams forwards all streams from source to target
-func (rs *RelayService) forwardStreams(ctx context.Context, source, target *quic.Conn, direction string) {
+func (rs *RelayService) forwardStreams(ctx context.Context, source, target *quic.Conn, direction string, principal *RelayPrincipal, connID string) {
 	tr := otel.Tracer("quantarax-relay")
 	ctx, span := tr.Start(ctx, "relay.forwardStreams")
 	defer span.End()
@@ -226,12 +417,12 @@ func (rs *RelayService) forwardStreams(ctx context.Context, source, target *quic
 			return
 		}
 
-		go rs.forwardStream(ctx, stream, target, direction)
+		go rs.forwardStream(ctx, stream, target, direction, principal, connID)
 	}
 }
 
 // forwardStream forwards a single stream
-func (rs *RelayService) forwardStream(ctx context.Context, sourceStream *quic.Stream, targetConn *quic.Conn, direction string) {
+func (rs *RelayService) forwardStream(ctx context.Context, sourceStream *quic.Stream, targetConn *quic.Conn, direction string, principal *RelayPrincipal, connID string) {
 	tr := otel.Tracer("quantarax-relay")
 	ctx, span := tr.Start(ctx, "relay.forwardStream")
 	defer span.End()
@@ -246,44 +437,139 @@ func (rs *RelayService) forwardStream(ctx context.Context, sourceStream *quic.St
 	}
 	defer targetStream.Close()
 
-	
+	limiter := rs.policy.BandwidthLimiter(principal)
+	streamStart := time.Now()
+	stats := rs.connStatsFor(connID)
+
 	var wg sync.WaitGroup
 	// Copy source->target
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		buf := make([]byte, rs.config.StreamBufferSize)
-		n, err := io.CopyBuffer(targetStream, sourceStream, buf)
+		buf := make([]byte, atomic.LoadInt64(&rs.streamBufferSize))
+		n, err := copyBuffer(rs.chaos.wrap(targetStream), sourceStream, buf, limiter)
 		if err != nil && ctx.Err() == nil {
 			log.Printf("Stream copy error (%s): %v", direction, err)
 		}
 
 		atomic.AddInt64(&rs.bytesForwarded, n)
+		rs.metrics.BytesForwardedTotal.WithLabelValues("forward").Add(float64(n))
+		if stats != nil {
+			stats.addBytes("forward", n)
+		}
 	}()
 	// Copy target->source
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		buf := make([]byte, rs.config.StreamBufferSize)
-		n, err := io.CopyBuffer(sourceStream, targetStream, buf)
+		buf := make([]byte, atomic.LoadInt64(&rs.streamBufferSize))
+		n, err := copyBuffer(rs.chaos.wrap(sourceStream), targetStream, buf, limiter)
 		if err != nil && ctx.Err() == nil {
 			log.Printf("Stream copy error (reverse %s): %v", direction, err)
 		}
 
 		atomic.AddInt64(&rs.bytesForwarded, n)
+		rs.metrics.BytesForwardedTotal.WithLabelValues("reverse").Add(float64(n))
+		if stats != nil {
+			stats.addBytes("reverse", n)
+		}
 	}()
 
 	wg.Wait()
+	rs.metrics.StreamDuration.Observe(time.Since(streamStart).Seconds())
+}
+
+// copyBuffer is io.CopyBuffer with an optional per-token bandwidth quota:
+// when limiter is non-nil, it blocks between reads until enough tokens
+// are available for the bytes about to be forwarded.
+func copyBuffer(dst io.Writer, src io.Reader, buf []byte, limiter *ratelimit.TokenBucket) (int64, error) {
+	var written int64
+	for {
+		nr, rerr := src.Read(buf)
+		if nr > 0 {
+			if limiter != nil {
+				limiter.Wait(nr)
+			}
+			nw, werr := dst.Write(buf[:nr])
+			written += int64(nw)
+			if werr != nil {
+				return written, werr
+			}
+			if nw != nr {
+				return written, io.ErrShortWrite
+			}
+		}
+		if rerr != nil {
+			if rerr == io.EOF {
+				return written, nil
+			}
+			return written, rerr
+		}
+	}
 }
 
-// validateToken validates an authentication token
-func (rs *RelayService) validateToken(token string) bool { return token != "" && len(token) > 10 }
+// validateToken decodes token into the RelayPrincipal it authenticates as.
+// When -auth-keys is configured, token must be a relayauth-signed token
+// verified against the configured KeySet, and the principal's scope comes
+// from its claims rather than the policy file. Otherwise a token
+// registered in the policy file carries that token's scope and quotas; an
+// unregistered token is accepted with no scope only when the policy file
+// has not registered any tokens at all, preserving the previous
+// permit-any-well-formed-token behavior for relays run without a policy
+// file.
+func (rs *RelayService) validateToken(token string) (*RelayPrincipal, bool) {
+	if rs.authVerifier != nil {
+		claims, err := rs.authVerifier.Verify(token)
+		if err != nil {
+			log.Printf("Auth token rejected: %v", err)
+			return nil, false
+		}
+		if claims.Subject == "" {
+			// RelayPrincipal.TokenID keys AcquireConnection/ReleaseConnection's
+			// per-token quota tracking; an empty TokenID is treated as
+			// "unscoped" there and would let this quota go unenforced.
+			log.Printf("Auth token rejected: claims carry no subject")
+			return nil, false
+		}
+		return &RelayPrincipal{
+			TokenID:         claims.Subject,
+			TargetPattern:   claims.TargetPattern,
+			MaxConnections:  claims.Quota.MaxConnections,
+			MaxBandwidthBps: claims.Quota.MaxBandwidthBps,
+		}, true
+	}
+
+	if token == "" || len(token) <= 10 {
+		return nil, false
+	}
+	if tp, ok := rs.policy.LookupToken(token); ok {
+		return &RelayPrincipal{
+			TokenID:            tp.TokenID,
+			AllowedCIDRs:       tp.AllowedCIDRs,
+			AllowedDNSSuffixes: tp.AllowedDNSSuffixes,
+			MaxConnections:     tp.MaxConnections,
+			MaxBandwidthBps:    tp.MaxBandwidthBps,
+		}, true
+	}
+	if rs.policy.HasRegisteredTokens() {
+		return nil, false
+	}
+	return &RelayPrincipal{TokenID: token}, true
+}
 
 // startHealthServer starts HTTP health, metrics, and pprof endpoints
 func (rs *RelayService) startHealthServer() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", rs.handleHealth)
 	mux.HandleFunc("/metrics", rs.handleMetrics)
+	mux.HandleFunc("/chaos", rs.handleChaos)
+	mux.HandleFunc("/admin/drain", rs.handleAdminDrain)
+	mux.HandleFunc("/admin/reload", rs.handleAdminReload)
+	mux.HandleFunc("/admin/limits", rs.handleAdminLimits)
+	mux.HandleFunc("/admin/kick/", rs.handleAdminKick)
+	mux.HandleFunc("/admin/conns", rs.handleAdminConns)
+	mux.HandleFunc("/dtn/bundle", rs.handleDTNBundle)
+	mux.Handle("/metrics/prom", promhttp.HandlerFor(rs.metrics.registry, promhttp.HandlerOpts{}))
 	// pprof handlers
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -302,7 +588,7 @@ func (rs *RelayService) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":             "healthy",
 		"active_connections": atomic.LoadInt64(&rs.activeConnections),
-		"max_connections":    rs.config.MaxConnections,
+		"max_connections":    atomic.LoadInt64(&rs.maxConnections),
 	})
 }
 
@@ -310,10 +596,13 @@ func (rs *RelayService) handleHealth(w http.ResponseWriter, r *http.Request) {
 func (rs *RelayService) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"active_connections": atomic.LoadInt64(&rs.activeConnections),
-		"total_connections":  atomic.LoadInt64(&rs.totalConnections),
-		"bytes_forwarded":    atomic.LoadInt64(&rs.bytesForwarded),
-		"max_connections":    rs.config.MaxConnections,
+		"active_connections":           atomic.LoadInt64(&rs.activeConnections),
+		"total_connections":            atomic.LoadInt64(&rs.totalConnections),
+		"bytes_forwarded":              atomic.LoadInt64(&rs.bytesForwarded),
+		"forbidden_connections":        atomic.LoadInt64(&rs.forbiddenConnections),
+		"max_connections":              atomic.LoadInt64(&rs.maxConnections),
+		"direct_upgrade_success_total": atomic.LoadInt64(&rs.directUpgradeSuccessTotal),
+		"direct_upgrade_fail_total":    atomic.LoadInt64(&rs.directUpgradeFailTotal),
 	})
 }
 
@@ -336,6 +625,12 @@ func main() {
 	maxConn := flag.Int("max-connections", 1000, "Maximum concurrent connections")
 	authMode := flag.String("auth-mode", "none", "Authentication mode (none, token)")
 	logLevel := flag.String("log-level", "info", "Logging level")
+	policyFile := flag.String("policy-file", "", "Path to a JSON RelayPolicy file (allow/deny rules, per-token scopes and quotas); unset relays to any target")
+	stunServers := flag.String("stun-servers", "", "Comma-separated list of STUN servers (host:port) for the NAT-traversal direct-upgrade path; unset disables the upgrade")
+	chaos := flag.Bool("chaos", false, "Enable the runtime-configurable chaos/fault injector for integration testing (configure via POST /chaos)")
+	authKeysFile := flag.String("auth-keys", "", "Path to a JSON relayauth KeySet file for signed-token verification; unset falls back to the plain policy-file token check. Re-read on SIGHUP.")
+	adminToken := flag.String("admin-token", "", "Shared secret required in the X-Admin-Token header to use /admin/{drain,kick,reload,limits}; unset disables /admin entirely.")
+	dtnSpoolPath := flag.String("dtn-spool", "", "Path to a BoltDB file backing POST /dtn/bundle, the DTN bundle-protocol custody-transfer endpoint; unset disables /dtn/bundle entirely.")
 	flag.Parse()
 	// Init tracing if configured
 	if shutdown, err := observability.InitTracing(context.Background(), "quantarax-relay"); err == nil { defer shutdown(context.Background()) }
@@ -347,7 +642,11 @@ func main() {
 	log.Printf("Listen address: %s", *listen)
 	log.Printf("Auth mode: %s", *authMode)
 	log.Printf("Log level: %s", *logLevel)
-	config := &RelayConfig{ListenAddr: *listen, MaxConnections: *maxConn, ConnTimeout: 30 * time.Second, StreamBufferSize: 65536, AuthMode: *authMode, LogLevel: *logLevel}
+	var stunServerList []string
+	if *stunServers != "" {
+		stunServerList = strings.Split(*stunServers, ",")
+	}
+	config := &RelayConfig{ListenAddr: *listen, MaxConnections: *maxConn, ConnTimeout: 30 * time.Second, StreamBufferSize: 65536, AuthMode: *authMode, LogLevel: *logLevel, PolicyFile: *policyFile, StunServers: stunServerList, ChaosEnabled: *chaos, AuthKeysFile: *authKeysFile, AdminToken: *adminToken, DTNSpoolPath: *dtnSpoolPath}
 	service := NewRelayService(config)
 	if err := service.Start(); err != nil { log.Fatalf("Relay service error: %v", err) }
 	log.Println("Relay service stopped")
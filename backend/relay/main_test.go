@@ -38,19 +38,60 @@ func TestTokenValidation(t *testing.T) {
 	config := &RelayConfig{AuthMode: "token"}
 	service := NewRelayService(config)
 
-	// Valid token
-	if !service.validateToken("valid-token-12345") {
+	// Valid token, no policy file loaded: accepted with an unscoped principal.
+	principal, ok := service.validateToken("valid-token-12345")
+	if !ok {
 		t.Error("Expected valid token to pass validation")
 	}
+	if principal == nil || principal.TokenID != "valid-token-12345" {
+		t.Errorf("Expected principal with matching token ID, got %+v", principal)
+	}
 	// Invalid tokens
-	if service.validateToken("") {
+	if _, ok := service.validateToken(""); ok {
 		t.Error("Expected empty token to fail validation")
 	}
-	if service.validateToken("short") {
+	if _, ok := service.validateToken("short"); ok {
 		t.Error("Expected short token to fail validation")
 	}
 }
 
+func TestTokenValidationWithRegisteredTokens(t *testing.T) {
+	config := &RelayConfig{AuthMode: "token"}
+	service := NewRelayService(config)
+	service.policy = NewRelayPolicy(RelayPolicyConfig{
+		Tokens: []TokenPolicy{{TokenID: "registered-token-1", MaxConnections: 2}},
+	})
+
+	principal, ok := service.validateToken("registered-token-1")
+	if !ok || principal.MaxConnections != 2 {
+		t.Errorf("Expected registered token to pass with its scope, got principal=%+v ok=%v", principal, ok)
+	}
+
+	if _, ok := service.validateToken("unregistered-token-1"); ok {
+		t.Error("Expected unregistered token to fail once the policy registers specific tokens")
+	}
+}
+
+func TestRelayPolicyCheckTarget(t *testing.T) {
+	policy := NewRelayPolicy(RelayPolicyConfig{
+		DenyRules: []PolicyRule{{CIDR: "10.0.0.0/8"}},
+	})
+	if err := policy.CheckTarget(nil, "10.1.2.3:4433"); err == nil {
+		t.Error("Expected denied CIDR to be forbidden")
+	}
+	if err := policy.CheckTarget(nil, "8.8.8.8:4433"); err != nil {
+		t.Errorf("Expected target outside deny rules to be permitted, got %v", err)
+	}
+
+	scoped := &RelayPrincipal{TokenID: "scoped", AllowedDNSSuffixes: []string{".internal.example.com"}}
+	if err := policy.CheckTarget(scoped, "host.internal.example.com:4433"); err != nil {
+		t.Errorf("Expected in-scope target to be permitted, got %v", err)
+	}
+	if err := policy.CheckTarget(scoped, "host.other.example.com:4433"); err == nil {
+		t.Error("Expected out-of-scope target to be forbidden")
+	}
+}
+
 func TestRelayConfig(t *testing.T) {
 	config := &RelayConfig{
 		ListenAddr:       ":4433",
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/quantarax/backend/internal/ratelimit"
+	"github.com/quantarax/backend/internal/relayauth"
+)
+
+// ErrTargetForbidden is returned by RelayPolicy.CheckTarget when a relay
+// target is rejected by the global allow/deny rules, a token's scope, or
+// a token's connection quota. handleConnection reports it to the client
+// as a RELAY_FORBIDDEN control message instead of dialing the target.
+var ErrTargetForbidden = errors.New("target forbidden by relay policy")
+
+// RelayPrincipal is the identity and entitlements decoded from an
+// authentication token by RelayService.validateToken. A nil principal
+// means AuthMode is "none"; CheckTarget then only applies the global
+// allow/deny rules.
+type RelayPrincipal struct {
+	TokenID            string
+	AllowedCIDRs       []string
+	AllowedDNSSuffixes []string
+	MaxConnections     int
+	MaxBandwidthBps    int64
+
+	// TargetPattern, when non-empty, is a relayauth.Claims.TargetPattern
+	// decoded from a signed auth token (see RelayService.validateToken):
+	// CheckTarget additionally requires the target host to match it,
+	// alongside (not instead of) AllowedCIDRs/AllowedDNSSuffixes.
+	TargetPattern string
+}
+
+// PolicyRule matches a relay target either by CIDR block or by DNS
+// suffix; exactly one of the two fields is expected to be set.
+type PolicyRule struct {
+	CIDR      string `json:"cidr,omitempty"`
+	DNSSuffix string `json:"dns_suffix,omitempty"`
+}
+
+// TokenPolicy is the per-token entry of the policy file: the targets a
+// token may relay to and the quotas it is held to.
+type TokenPolicy struct {
+	TokenID            string   `json:"token_id"`
+	AllowedCIDRs       []string `json:"allowed_cidrs,omitempty"`
+	AllowedDNSSuffixes []string `json:"allowed_dns_suffixes,omitempty"`
+	MaxConnections     int      `json:"max_connections,omitempty"`
+	MaxBandwidthBps    int64    `json:"max_bandwidth_bps,omitempty"`
+}
+
+// RelayPolicyConfig is the on-disk JSON shape loaded by LoadRelayPolicy
+// via -policy-file. Loading it from an admin API is left to the relay
+// admin control channel (see the admin reconfigure command).
+type RelayPolicyConfig struct {
+	AllowRules []PolicyRule  `json:"allow_rules,omitempty"`
+	DenyRules  []PolicyRule  `json:"deny_rules,omitempty"`
+	Tokens     []TokenPolicy `json:"tokens,omitempty"`
+}
+
+// RelayPolicy is the runtime policy engine consulted by handleConnection
+// before it dials a target: global allow/deny rules plus, when a
+// principal is present, that token's scope and quotas.
+type RelayPolicy struct {
+	rulesMu    sync.RWMutex
+	allowRules []PolicyRule
+	denyRules  []PolicyRule
+	tokens     map[string]TokenPolicy
+
+	mu         sync.Mutex
+	connCounts map[string]int
+	buckets    map[string]*ratelimit.TokenBucket
+}
+
+// NewRelayPolicy builds a RelayPolicy from an already-parsed config. An
+// empty config yields a permit-all policy (no allow/deny rules, no
+// registered tokens), which is what relay runs with when -policy-file
+// is unset.
+func NewRelayPolicy(cfg RelayPolicyConfig) *RelayPolicy {
+	tokens := make(map[string]TokenPolicy, len(cfg.Tokens))
+	for _, t := range cfg.Tokens {
+		tokens[t.TokenID] = t
+	}
+	return &RelayPolicy{
+		allowRules: cfg.AllowRules,
+		denyRules:  cfg.DenyRules,
+		tokens:     tokens,
+		connCounts: make(map[string]int),
+		buckets:    make(map[string]*ratelimit.TokenBucket),
+	}
+}
+
+// LoadRelayPolicy reads and parses a RelayPolicy from a JSON policy
+// file. An empty path returns a permit-all policy so -policy-file
+// remains optional.
+func LoadRelayPolicy(path string) (*RelayPolicy, error) {
+	if path == "" {
+		return NewRelayPolicy(RelayPolicyConfig{}), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relay policy file: %w", err)
+	}
+	var cfg RelayPolicyConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse relay policy file: %w", err)
+	}
+	return NewRelayPolicy(cfg), nil
+}
+
+// ReloadFile re-reads path and swaps in its allow/deny rules and token
+// scopes, preserving the connection counts and bandwidth buckets already
+// tracked under rp.mu for in-flight sessions. Used by the relay's
+// POST /admin/reload endpoint (see admin.go) to pick up policy-file edits
+// without a restart. An empty path is a no-op, matching LoadRelayPolicy
+// treating an unset -policy-file as permit-all.
+func (rp *RelayPolicy) ReloadFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read relay policy file: %w", err)
+	}
+	var cfg RelayPolicyConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse relay policy file: %w", err)
+	}
+	tokens := make(map[string]TokenPolicy, len(cfg.Tokens))
+	for _, t := range cfg.Tokens {
+		tokens[t.TokenID] = t
+	}
+	rp.rulesMu.Lock()
+	rp.allowRules = cfg.AllowRules
+	rp.denyRules = cfg.DenyRules
+	rp.tokens = tokens
+	rp.rulesMu.Unlock()
+	return nil
+}
+
+// LookupToken returns the per-token policy registered for token, if any.
+func (rp *RelayPolicy) LookupToken(token string) (TokenPolicy, bool) {
+	rp.rulesMu.RLock()
+	defer rp.rulesMu.RUnlock()
+	tp, ok := rp.tokens[token]
+	return tp, ok
+}
+
+// HasRegisteredTokens reports whether the policy file registered any
+// specific tokens. When it has, validateToken treats an unregistered
+// token as invalid rather than silently falling back to an unscoped
+// principal.
+func (rp *RelayPolicy) HasRegisteredTokens() bool {
+	rp.rulesMu.RLock()
+	defer rp.rulesMu.RUnlock()
+	return len(rp.tokens) > 0
+}
+
+// CheckTarget evaluates targetAddr (host:port, as read off the control
+// stream) against the global allow/deny rules and, when principal is
+// non-nil, its per-token scope and connection quota. A deny match always
+// forbids. With no allow rules configured (globally or per-token)
+// everything not denied is permitted, since the allowlist is opt-in.
+func (rp *RelayPolicy) CheckTarget(principal *RelayPrincipal, targetAddr string) error {
+	host := targetAddr
+	if h, _, err := net.SplitHostPort(targetAddr); err == nil {
+		host = h
+	}
+
+	rp.rulesMu.RLock()
+	allowRules, denyRules := rp.allowRules, rp.denyRules
+	rp.rulesMu.RUnlock()
+
+	if matchesAny(denyRules, host) {
+		return ErrTargetForbidden
+	}
+	if len(allowRules) > 0 && !matchesAny(allowRules, host) {
+		return ErrTargetForbidden
+	}
+
+	if principal == nil {
+		return nil
+	}
+
+	if len(principal.AllowedCIDRs) > 0 || len(principal.AllowedDNSSuffixes) > 0 {
+		scoped := make([]PolicyRule, 0, len(principal.AllowedCIDRs)+len(principal.AllowedDNSSuffixes))
+		for _, c := range principal.AllowedCIDRs {
+			scoped = append(scoped, PolicyRule{CIDR: c})
+		}
+		for _, s := range principal.AllowedDNSSuffixes {
+			scoped = append(scoped, PolicyRule{DNSSuffix: s})
+		}
+		if !matchesAny(scoped, host) {
+			return ErrTargetForbidden
+		}
+	}
+
+	if principal.TargetPattern != "" && !relayauth.MatchesTarget(principal.TargetPattern, host) {
+		return ErrTargetForbidden
+	}
+
+	if principal.MaxConnections > 0 {
+		rp.mu.Lock()
+		active := rp.connCounts[principal.TokenID]
+		rp.mu.Unlock()
+		if active >= principal.MaxConnections {
+			return ErrTargetForbidden
+		}
+	}
+
+	return nil
+}
+
+// AcquireConnection records a new active connection for tokenID, for
+// enforcing MaxConnections in CheckTarget. Callers must call
+// ReleaseConnection once the relay session ends.
+func (rp *RelayPolicy) AcquireConnection(tokenID string) {
+	if tokenID == "" {
+		return
+	}
+	rp.mu.Lock()
+	rp.connCounts[tokenID]++
+	rp.mu.Unlock()
+}
+
+// ReleaseConnection undoes a prior AcquireConnection.
+func (rp *RelayPolicy) ReleaseConnection(tokenID string) {
+	if tokenID == "" {
+		return
+	}
+	rp.mu.Lock()
+	if rp.connCounts[tokenID] > 0 {
+		rp.connCounts[tokenID]--
+	}
+	rp.mu.Unlock()
+}
+
+// BandwidthLimiter returns the shared token bucket enforcing
+// principal's bandwidth quota, creating it on first use, or nil if the
+// principal has no bandwidth quota configured.
+func (rp *RelayPolicy) BandwidthLimiter(principal *RelayPrincipal) *ratelimit.TokenBucket {
+	if principal == nil || principal.MaxBandwidthBps <= 0 {
+		return nil
+	}
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if b, ok := rp.buckets[principal.TokenID]; ok {
+		return b
+	}
+	b := ratelimit.NewTokenBucket(float64(principal.MaxBandwidthBps), int(principal.MaxBandwidthBps))
+	rp.buckets[principal.TokenID] = b
+	return b
+}
+
+func matchesAny(rules []PolicyRule, host string) bool {
+	for _, r := range rules {
+		if matchesRule(r, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesRule(r PolicyRule, host string) bool {
+	switch {
+	case r.CIDR != "":
+		_, ipnet, err := net.ParseCIDR(r.CIDR)
+		if err != nil {
+			return false
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			return ipnet.Contains(ip)
+		}
+		// host is a hostname rather than a literal IP: resolve it so CIDR
+		// rules still apply to targets the client named by DNS.
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			return false
+		}
+		for _, ip := range ips {
+			if ipnet.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	case r.DNSSuffix != "":
+		return strings.HasSuffix(host, r.DNSSuffix)
+	default:
+		return false
+	}
+}
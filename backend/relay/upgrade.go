@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+const (
+	// natUpgradeFrameTimeout bounds how long the relay waits for an
+	// endpoint's NAT_CANDIDATE frame before giving up on the upgrade.
+	natUpgradeFrameTimeout = 3 * time.Second
+
+	// natUpgradeWindow is how long the relay keeps forwarding packets
+	// after sending TRY_DIRECT, giving both endpoints time to dial the
+	// candidate they were just handed and QUIC-connection-migrate their
+	// session onto it before the relay announces RELAY_UPGRADED.
+	natUpgradeWindow = 2 * time.Second
+)
+
+// Control-frame markers for the NAT-traversal upgrade handshake, written
+// to the control stream alongside the existing OK/AUTH_FAILED/... ones in
+// handleConnection.
+const (
+	frameNATCandidate   = "NAT_CANDIDATE"
+	frameTryDirect      = "TRY_DIRECT"
+	frameRelayUpgraded  = "RELAY_UPGRADED"
+	frameUpgradeSkipped = "UPGRADE_UNAVAILABLE"
+)
+
+// natCandidateFrame is the wire shape of a NAT_CANDIDATE control frame: an
+// endpoint's own best guess at its publicly reachable address, learned via
+// its embedded quicutil.QueryPublicAddr STUN client against the relay's
+// configured STUN server list before it ever dials the relay.
+type natCandidateFrame struct {
+	Addr string `json:"addr"`
+}
+
+// tryDirectFrame is the wire shape of a TRY_DIRECT control frame: the
+// peer's candidate address, handed to an endpoint so it can attempt a
+// direct QUIC connection migration onto that path.
+type tryDirectFrame struct {
+	PeerAddr string `json:"peer_addr"`
+}
+
+// attemptDirectUpgrade runs once handleConnection has linked sourceConn and
+// targetConn through the relay. Each endpoint is expected to report a
+// NAT_CANDIDATE frame over its control stream; if the address it reports
+// matches the address the relay actually saw it dial from, that side's NAT
+// preserves the STUN-mapped address (full-cone or address-restricted-cone)
+// and is likely reachable directly rather than only through the relay. When
+// both sides clear that bar, attemptDirectUpgrade hands each side the
+// other's candidate in a TRY_DIRECT frame, keeps relaying for
+// natUpgradeWindow while they migrate, then announces RELAY_UPGRADED and
+// returns so handleConnection's deferred cleanup tears the tunnel down.
+// Any failure along the way (no frame, a mismatched candidate, a target
+// that won't open a control stream) is reported with UPGRADE_UNAVAILABLE
+// and leaves the relayed tunnel exactly as handleConnection set it up.
+func (rs *RelayService) attemptDirectUpgrade(ctx context.Context, sourceConn, targetConn *quic.Conn, sourceControl *quic.Stream) {
+	if len(rs.config.StunServers) == 0 {
+		return
+	}
+
+	sourceCand, ok := rs.readPeerCandidate(sourceControl, sourceConn)
+	if !ok {
+		rs.failUpgrade(sourceControl, nil)
+		return
+	}
+
+	targetControl, err := targetConn.OpenStreamSync(ctx)
+	if err != nil {
+		log.Printf("NAT upgrade: failed to open target control stream: %v", err)
+		rs.failUpgrade(sourceControl, nil)
+		return
+	}
+	defer targetControl.Close()
+
+	targetCand, ok := rs.readPeerCandidate(targetControl, targetConn)
+	if !ok {
+		rs.failUpgrade(sourceControl, targetControl)
+		return
+	}
+
+	if err := writeFrame(sourceControl, frameTryDirect, tryDirectFrame{PeerAddr: targetCand}); err != nil {
+		log.Printf("NAT upgrade: failed to signal TRY_DIRECT to source: %v", err)
+		rs.failUpgrade(sourceControl, targetControl)
+		return
+	}
+	if err := writeFrame(targetControl, frameTryDirect, tryDirectFrame{PeerAddr: sourceCand}); err != nil {
+		log.Printf("NAT upgrade: failed to signal TRY_DIRECT to target: %v", err)
+		rs.failUpgrade(sourceControl, targetControl)
+		return
+	}
+
+	// The tunnel keeps carrying traffic during the migration window:
+	// forwardStreams is still running on both directions, so nothing
+	// already in flight is dropped while the endpoints cut over.
+	time.Sleep(natUpgradeWindow)
+
+	writeRaw(sourceControl, frameRelayUpgraded)
+	writeRaw(targetControl, frameRelayUpgraded)
+	atomic.AddInt64(&rs.directUpgradeSuccessTotal, 1)
+	log.Printf("NAT upgrade: relay tunnel upgraded to direct path (source candidate %s, target candidate %s)", sourceCand, targetCand)
+}
+
+// readPeerCandidate reads one NAT_CANDIDATE frame from stream and reports
+// whether the address it announced matches conn's actual observed remote
+// address. A mismatch means the reporting side's NAT rewrites the mapping
+// per destination (symmetric NAT): the relayed path works but a direct one
+// most likely won't, so the upgrade isn't attempted for either side.
+func (rs *RelayService) readPeerCandidate(stream *quic.Stream, conn *quic.Conn) (string, bool) {
+	_ = stream.SetReadDeadline(time.Now().Add(natUpgradeFrameTimeout))
+	var frame natCandidateFrame
+	if err := readFrame(stream, frameNATCandidate, &frame); err != nil {
+		return "", false
+	}
+	if frame.Addr == "" || frame.Addr != conn.RemoteAddr().String() {
+		return frame.Addr, false
+	}
+	return frame.Addr, true
+}
+
+// failUpgrade counts a failed upgrade attempt and best-effort notifies
+// whichever streams are non-nil that it isn't going to happen.
+func (rs *RelayService) failUpgrade(streams ...*quic.Stream) {
+	atomic.AddInt64(&rs.directUpgradeFailTotal, 1)
+	for _, s := range streams {
+		writeRaw(s, frameUpgradeSkipped)
+	}
+}
+
+// writeFrame writes marker followed by v JSON-encoded, matching the
+// existing "OK"/"AUTH_FAILED" raw-string control frames handleConnection
+// already writes.
+func writeFrame(stream *quic.Stream, marker string, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = stream.Write(append([]byte(marker+" "), payload...))
+	return err
+}
+
+// readFrame reads a single marker-prefixed JSON frame off stream, as
+// written by writeFrame.
+func readFrame(stream *quic.Stream, marker string, v interface{}) error {
+	buf := make([]byte, 512)
+	n, err := stream.Read(buf)
+	if err != nil {
+		return err
+	}
+	data := buf[:n]
+	prefix := marker + " "
+	if len(data) < len(prefix) || string(data[:len(prefix)]) != prefix {
+		return fmt.Errorf("expected %s frame, got %q", marker, string(data))
+	}
+	return json.Unmarshal(data[len(prefix):], v)
+}
+
+// writeRaw writes a bare marker frame (no payload), such as
+// RELAY_UPGRADED or UPGRADE_UNAVAILABLE. stream may be nil if the caller
+// never got far enough to open it, in which case it's a no-op.
+func writeRaw(stream *quic.Stream, marker string) {
+	if stream == nil {
+		return
+	}
+	_, _ = stream.Write([]byte(marker))
+}
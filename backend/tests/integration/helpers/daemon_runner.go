@@ -8,6 +8,10 @@ import (
 	"os/exec"
 	"path/filepath"
 	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 // DaemonRunner manages the lifecycle of the QuantaraX daemon for tests.
@@ -78,26 +82,71 @@ func (r *DaemonRunner) Start() error {
 	return nil
 }
 
-// waitForReady polls /health until the daemon reports ready.
+// waitForReady polls the daemon's grpc.health.v1.Health service until it
+// reports SERVING, falling back to the HTTP /health endpoint if the gRPC
+// probe can't be reached at all (e.g. a daemon binary old enough not to
+// register the health service yet). The gRPC probe observes per-subsystem
+// status (quic_listener, bitmapstore) individually, rather than the single
+// pass/fail bit HTTP /health's top-level status collapses them into.
 func (r *DaemonRunner) waitForReady() error {
-	// Extract port from ObservAddr for health check
 	url := "http://" + r.ObservAddr + "/health"
 	for i := 0; i < 30; i++ { // Increased attempts
 		time.Sleep(1 * time.Second) // Increased delay
-		resp, err := http.Get(url)
-		if err == nil && resp.StatusCode == http.StatusOK {
-			resp.Body.Close()
+		if r.checkGRPCHealth() {
 			return nil
 		}
-		if resp != nil {
-			resp.Body.Close()
+		if r.checkHTTPHealth(url) {
+			return nil
 		}
 		// Log progress for debugging
 		if i%5 == 0 {
-			fmt.Printf("Waiting for daemon health check... attempt %d/30 (url: %s)\n", i+1, url)
+			fmt.Printf("Waiting for daemon health check... attempt %d/30 (grpc: %s, http: %s)\n", i+1, r.GRPCAddr, url)
 		}
 	}
-	return fmt.Errorf("daemon not ready after timeout at url: %s", url)
+	return fmt.Errorf("daemon not ready after timeout (grpc: %s, http: %s)", r.GRPCAddr, url)
+}
+
+// checkGRPCHealth reports whether the daemon's overall grpc.health.v1.Health
+// status ("") is SERVING. Any dial/RPC error (including the service not
+// being registered) is treated as "not yet ready" rather than a fatal error,
+// since waitForReady falls back to HTTP in that case.
+func (r *DaemonRunner) checkGRPCHealth() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, r.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING
+}
+
+// checkHTTPHealth reports whether the legacy JSON /health endpoint at url
+// answers 200 OK.
+func (r *DaemonRunner) checkHTTPHealth(url string) bool {
+	resp, err := http.Get(url)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// Restart issues an admin restart request against the running daemon's REST
+// admin endpoint and waits for /health to come back green, so integration
+// tests can validate that resume logic and the scheduler plumbed through
+// ChunkWorkerPool.SetScheduler survive a daemon bounce.
+func (r *DaemonRunner) Restart() error {
+	resp, err := http.Post("http://"+r.RESTAddr+"/api/v1/admin/restart", "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("failed to request restart: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("restart request returned status %d", resp.StatusCode)
+	}
+	return r.waitForReady()
 }
 
 // Stop terminates the daemon.
